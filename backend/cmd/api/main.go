@@ -12,7 +12,9 @@ import (
 	"payment-service/internal/config"
 	"payment-service/internal/database"
 	"payment-service/internal/handlers"
+	"payment-service/internal/jobs"
 	"payment-service/internal/middleware"
+	"payment-service/internal/models"
 	"payment-service/internal/providers"
 	"payment-service/internal/repository"
 	"payment-service/internal/services"
@@ -78,6 +80,10 @@ func main() {
 
 	// Initialize provider factory
 	providerFactory := providers.NewFactory(cfg.StripeAPIKey, cfg.StripeWebhookSecret)
+	if cfg.UseFakeProvider {
+		log.Println("Using fake payment provider; no charges will reach Stripe")
+		providerFactory = providers.NewFakeFactory(providers.NewFakeProvider(cfg.FakeProviderDeclineAttempts))
+	}
 
 	// Initialize repositories
 	customerRepo := repository.NewCustomerRepository(db.DB)
@@ -85,21 +91,102 @@ func main() {
 	subscriptionRepo := repository.NewSubscriptionRepository(db.DB)
 	refundRepo := repository.NewRefundRepository(db.DB)
 	webhookRepo := repository.NewWebhookRepository(db.DB)
+	auditRepo := repository.NewAuditRepository(db.DB)
+	couponRepo := repository.NewCouponRepository(db.DB)
+	promotionCodeRepo := repository.NewPromotionCodeRepository(db.DB)
+	eventRepo := repository.NewEventRepository(db.DB)
+	invoiceRepo := repository.NewInvoiceRepository(db.DB)
+	receiptRepo := repository.NewReceiptRepository(db.DB)
+	ledgerRepo := repository.NewLedgerRepository(db.DB)
+	reconciliationRepo := repository.NewReconciliationRepository(db.DB)
+	payoutRepo := repository.NewPayoutRepository(db.DB)
+	disputeRepo := repository.NewDisputeRepository(db.DB)
+	balanceRepo := repository.NewCustomerBalanceRepository(db.DB)
+	walletRepo := repository.NewWalletRepository(db.DB)
+	usageRepo := repository.NewUsageRepository(db.DB)
+	scheduleRepo := repository.NewSubscriptionScheduleRepository(db.DB)
 
 	// Initialize services
-	paymentService := services.NewPaymentService(paymentRepo, customerRepo, providerFactory)
-	subscriptionService := services.NewSubscriptionService(subscriptionRepo, customerRepo, providerFactory)
-	refundService := services.NewRefundService(refundRepo, paymentRepo, customerRepo, providerFactory)
-	// TODO: Wire webhookService into webhookHandler for async event processing
-	_ = services.NewWebhookService(webhookRepo, paymentRepo, subscriptionRepo, refundRepo)
+	couponService := services.NewCouponService(couponRepo, promotionCodeRepo, customerRepo, providerFactory)
+	taxService := services.NewTaxService(services.TaxConfig{
+		HomeCountry:     cfg.TaxHomeCountry,
+		DefaultBehavior: models.TaxBehavior(cfg.TaxDefaultBehavior),
+		Rates:           cfg.TaxRates,
+	})
+	ledgerService := services.NewLedgerService(ledgerRepo, services.LedgerConfig{Tenant: cfg.TenantID})
+	settlementService := services.NewSettlementService(paymentRepo, refundRepo, ledgerService, providerFactory)
+
+	// Subscriptions on providers without a subscription API are billed by us;
+	// services that manage subscriptions get their providers through it
+	nativeBillingProviders := make([]models.Provider, len(cfg.NativeBillingProviders))
+	for i, provider := range cfg.NativeBillingProviders {
+		nativeBillingProviders[i] = models.Provider(provider)
+	}
+	nativeBilling := services.NewNativeBilling(subscriptionRepo, invoiceRepo, paymentRepo, customerRepo, ledgerService, settlementService, providerFactory, services.NativeBillingConfig{
+		Providers:      nativeBillingProviders,
+		PaymentTimeout: cfg.NativeBillingPaymentTimeout,
+	})
+
+	customerService := services.NewCustomerService(customerRepo, paymentRepo, subscriptionRepo, refundRepo, auditRepo, nativeBilling)
+	balanceService := services.NewCustomerBalanceService(balanceRepo, customerRepo, ledgerService, nativeBilling)
+	paymentService := services.NewPaymentService(paymentRepo, customerRepo, couponService, taxService, ledgerService, settlementService, balanceService, providerFactory)
+	walletService := services.NewWalletService(walletRepo, customerRepo, auditRepo, paymentService, ledgerService)
+	subscriptionService := services.NewSubscriptionService(subscriptionRepo, customerRepo, auditRepo, couponService, taxService, nativeBilling)
+	usageService := services.NewUsageService(usageRepo, subscriptionRepo, customerRepo, invoiceRepo, nativeBilling)
+	scheduleService := services.NewSubscriptionScheduleService(scheduleRepo, subscriptionRepo, customerRepo, auditRepo, subscriptionService, taxService, nativeBilling)
+	refundService := services.NewRefundService(refundRepo, paymentRepo, customerRepo, auditRepo, ledgerService, settlementService, balanceService, providerFactory, services.RefundApprovalPolicy{
+		AmountThreshold: cfg.RefundApprovalAmountThreshold,
+		PaymentAge:      cfg.RefundApprovalPaymentAge,
+		Roles:           cfg.RefundApprovalRoles,
+	})
+	dunningService := services.NewDunningService(subscriptionRepo, eventRepo, nativeBilling, services.DunningConfig{
+		RetrySchedule: cfg.DunningRetrySchedule,
+		GracePeriod:   cfg.DunningGracePeriod,
+		FinalAction:   models.DunningFinalAction(cfg.DunningFinalAction),
+	})
+	billingService := services.NewBillingService(subscriptionRepo, invoiceRepo, nativeBilling, dunningService)
+	disputeService := services.NewDisputeService(disputeRepo, paymentRepo, eventRepo, ledgerService, providerFactory)
+	webhookService := services.NewWebhookService(webhookRepo, paymentRepo, subscriptionRepo, refundRepo, eventRepo, invoiceRepo, dunningService, disputeService, ledgerService, settlementService, balanceService, walletService)
+	reportService := services.NewReportService(paymentRepo)
+	reconciliationService := services.NewReconciliationService(reconciliationRepo, paymentRepo, subscriptionRepo, refundRepo, ledgerService, settlementService, balanceService, walletService, nativeBilling, services.ReconciliationConfig{
+		Window: cfg.ReconciliationWindow,
+	})
+	payoutService := services.NewPayoutService(payoutRepo, ledgerService, providerFactory, services.PayoutConfig{
+		ImportWindow: cfg.PayoutImportWindow,
+	})
+	eventService := services.NewEventService(eventRepo, customerRepo)
+	invoiceService := services.NewInvoiceService(invoiceRepo, subscriptionRepo, customerRepo)
+	receiptService := services.NewReceiptService(receiptRepo, paymentRepo, invoiceRepo, customerRepo, services.ReceiptConfig{
+		Tenant:       cfg.TenantID,
+		NumberPrefix: cfg.ReceiptNumberPrefix,
+		Seller: services.SellerDetails{
+			Name:      cfg.SellerName,
+			Address:   cfg.SellerAddress,
+			OrgNumber: cfg.SellerOrgNumber,
+			VATNumber: cfg.SellerVATNumber,
+			Email:     cfg.SellerEmail,
+		},
+	})
 
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler()
-	customerHandler := handlers.NewCustomerHandler(customerRepo)
+	customerHandler := handlers.NewCustomerHandler(customerService, balanceService)
 	paymentHandler := handlers.NewPaymentHandler(paymentService)
-	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService, usageService)
+	scheduleHandler := handlers.NewSubscriptionScheduleHandler(scheduleService)
 	refundHandler := handlers.NewRefundHandler(refundService)
-	webhookHandler := handlers.NewWebhookHandler(providerFactory)
+	webhookHandler := handlers.NewWebhookHandler(providerFactory, webhookService)
+	couponHandler := handlers.NewCouponHandler(couponService)
+	eventHandler := handlers.NewEventHandler(eventService)
+	dunningHandler := handlers.NewDunningHandler(dunningService)
+	invoiceHandler := handlers.NewInvoiceHandler(invoiceService)
+	receiptHandler := handlers.NewReceiptHandler(receiptService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	reportHandler := handlers.NewReportHandler(reportService)
+	reconciliationHandler := handlers.NewReconciliationHandler(reconciliationService)
+	payoutHandler := handlers.NewPayoutHandler(payoutService)
+	disputeHandler := handlers.NewDisputeHandler(disputeService)
+	walletHandler := handlers.NewWalletHandler(walletService)
 
 	// Initialize router
 	r := chi.NewRouter()
@@ -131,11 +218,16 @@ func main() {
 
 		// Customer endpoints
 		r.Get("/customers/me", customerHandler.GetMe)
+		r.Patch("/customers/me", customerHandler.UpdateMe)
+		r.Delete("/customers/me", customerHandler.EraseMe)
+		r.Get("/customers/me/export", customerHandler.ExportMe)
+		r.Get("/customers/me/balance", customerHandler.GetBalance)
 
 		// Payment endpoints
 		r.Post("/payments", paymentHandler.CreatePayment)
 		r.Get("/payments/{id}", paymentHandler.GetPayment)
 		r.Get("/payments/{id}/refunds", refundHandler.ListRefundsByPayment)
+		r.Get("/payments/{id}/receipt.pdf", receiptHandler.GetPaymentReceipt)
 		r.Get("/payments", paymentHandler.ListPayments)
 
 		// Subscription endpoints
@@ -143,12 +235,100 @@ func main() {
 		r.Get("/subscriptions/{id}", subscriptionHandler.GetSubscription)
 		r.Patch("/subscriptions/{id}", subscriptionHandler.UpdateSubscription)
 		r.Delete("/subscriptions/{id}", subscriptionHandler.CancelSubscription)
+		r.Post("/subscriptions/{id}/reactivate", subscriptionHandler.ReactivateSubscription)
+		r.Post("/subscriptions/{id}/pause", subscriptionHandler.PauseSubscription)
+		r.Post("/subscriptions/{id}/resume", subscriptionHandler.ResumeSubscription)
+		r.Post("/subscriptions/{id}/items", subscriptionHandler.AddItem)
+		r.Patch("/subscriptions/{id}/items/{itemId}", subscriptionHandler.UpdateItem)
+		r.Delete("/subscriptions/{id}/items/{itemId}", subscriptionHandler.RemoveItem)
+		r.Post("/subscriptions/{id}/usage", subscriptionHandler.ReportUsage)
+		r.Get("/subscriptions/{id}/usage", subscriptionHandler.GetUsage)
+		r.Get("/subscriptions/{id}/invoices", invoiceHandler.ListSubscriptionInvoices)
 		r.Get("/subscriptions", subscriptionHandler.ListSubscriptions)
 
+		// Subscription schedule routes
+		r.Post("/subscription-schedules", scheduleHandler.CreateSchedule)
+		r.Get("/subscription-schedules", scheduleHandler.ListSchedules)
+		r.Get("/subscription-schedules/{id}", scheduleHandler.GetSchedule)
+		r.Post("/subscription-schedules/{id}/cancel", scheduleHandler.CancelSchedule)
+
 		// Refund endpoints
 		r.Post("/refunds", refundHandler.CreateRefund)
 		r.Get("/refunds/{id}", refundHandler.GetRefund)
 		r.Get("/refunds", refundHandler.ListRefunds)
+
+		// Wallet endpoints
+		r.Get("/wallet", walletHandler.ListWallets)
+		r.Get("/wallet/transactions", walletHandler.ListTransactions)
+		r.Post("/wallet/top-ups", walletHandler.TopUp)
+		r.Post("/wallet/debits", walletHandler.Debit)
+		r.Post("/wallet/holds", walletHandler.CreateHold)
+		r.Post("/wallet/holds/{id}/capture", walletHandler.CaptureHold)
+		r.Post("/wallet/holds/{id}/release", walletHandler.ReleaseHold)
+
+		// Invoice endpoints
+		r.Get("/invoices", invoiceHandler.ListInvoices)
+		r.Get("/invoices/{id}/receipt.pdf", receiptHandler.GetInvoiceReceipt)
+
+		// Event endpoints
+		r.Get("/events", eventHandler.ListEvents)
+
+		// Support endpoints
+		r.Route("/support", func(r chi.Router) {
+			r.Use(middleware.RequireRole("support", "admin"))
+
+			r.Post("/refunds", refundHandler.CreateSupportRefund)
+		})
+
+		// Admin endpoints
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.RequireRole("admin"))
+
+			// Coupon endpoints
+			r.Post("/coupons", couponHandler.CreateCoupon)
+			r.Get("/coupons", couponHandler.ListCoupons)
+			r.Get("/coupons/{id}", couponHandler.GetCoupon)
+			r.Delete("/coupons/{id}", couponHandler.DeleteCoupon)
+			r.Post("/coupons/{id}/promotion-codes", couponHandler.CreatePromotionCode)
+			r.Get("/coupons/{id}/promotion-codes", couponHandler.ListPromotionCodes)
+			r.Patch("/promotion-codes/{id}", couponHandler.UpdatePromotionCode)
+
+			// Subscription endpoints
+			r.Post("/subscriptions/{id}/extend-trial", subscriptionHandler.ExtendTrial)
+
+			// Dunning endpoints
+			r.Post("/dunning/run", dunningHandler.RunDunning)
+
+			// Ledger endpoints
+			r.Get("/ledger/balances", ledgerHandler.GetBalances)
+
+			// Report endpoints
+			r.Get("/reports/product-margins", reportHandler.GetProductMargins)
+
+			// Reconciliation endpoints
+			r.Post("/reconciliation/run", reconciliationHandler.RunReconciliation)
+			r.Get("/reconciliation/discrepancies", reconciliationHandler.ListDiscrepancies)
+			r.Post("/reconciliation/discrepancies/{id}/resolve", reconciliationHandler.ResolveDiscrepancy)
+
+			// Payout endpoints
+			r.Post("/payouts/import", payoutHandler.ImportPayouts)
+			r.Get("/payouts", payoutHandler.ListPayouts)
+			r.Get("/payouts/{id}", payoutHandler.GetPayout)
+			r.Get("/payouts/{id}/export.csv", payoutHandler.ExportPayout)
+
+			// Refund approval endpoints
+			r.Get("/refunds/pending-approval", refundHandler.ListPendingApprovals)
+			r.Post("/refunds/{id}/approve", refundHandler.ApproveRefund)
+			r.Post("/refunds/{id}/reject", refundHandler.RejectRefund)
+
+			// Dispute endpoints
+			r.Get("/disputes", disputeHandler.ListDisputes)
+			r.Get("/disputes/{id}", disputeHandler.GetDispute)
+			r.Post("/disputes/{id}/evidence", disputeHandler.SubmitEvidence)
+
+			// Wallet endpoints
+			r.Post("/wallets/grants", walletHandler.GrantCredit)
+		})
 	})
 
 	// Webhook endpoints (no auth, verified by signature)
@@ -164,6 +344,44 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Start background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go jobs.Run(jobsCtx, "dunning", cfg.DunningJobInterval, func(ctx context.Context) error {
+		_, err := dunningService.ProcessDueRetries(ctx)
+		return err
+	})
+	go jobs.Run(jobsCtx, "reconciliation", cfg.ReconciliationJobInterval, func(ctx context.Context) error {
+		result, err := reconciliationService.RunScheduled(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Reconciliation checked %d objects: %d discrepancies, %d repaired, %d failed",
+			result.Checked, result.Discrepancies, result.Repaired, result.Failed)
+		return nil
+	})
+	go jobs.Run(jobsCtx, "payouts", cfg.PayoutJobInterval, func(ctx context.Context) error {
+		_, err := payoutService.ImportScheduled(ctx)
+		return err
+	})
+	go jobs.Run(jobsCtx, "wallet-expiry", cfg.WalletExpiryJobInterval, func(ctx context.Context) error {
+		_, err := walletService.ExpireGrants(ctx)
+		return err
+	})
+	go jobs.Run(jobsCtx, "usage", cfg.UsageJobInterval, func(ctx context.Context) error {
+		_, err := usageService.ProcessUsage(ctx)
+		return err
+	})
+	go jobs.Run(jobsCtx, "billing", cfg.BillingJobInterval, func(ctx context.Context) error {
+		_, err := billingService.ProcessRenewals(ctx)
+		return err
+	})
+	go jobs.Run(jobsCtx, "subscription-schedules", cfg.SubscriptionScheduleJobInterval, func(ctx context.Context) error {
+		_, err := scheduleService.ProcessSchedules(ctx)
+		return err
+	})
+
 	// Start server in goroutine
 	go func() {
 		log.Printf("Server listening on port %s", cfg.Port)
@@ -178,6 +396,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	// Graceful shutdown
 	if err := server.Close(); err != nil {
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -23,6 +25,10 @@ type Config struct {
 	StripeAPIKey        string
 	StripeWebhookSecret string
 
+	// Fake provider (local development without Stripe)
+	UseFakeProvider             bool
+	FakeProviderDeclineAttempts int
+
 	// Swish
 	SwishAPIURL        string
 	SwishCertPath      string
@@ -34,6 +40,53 @@ type Config struct {
 
 	// CORS
 	AllowedOrigins []string
+
+	// Seller details printed on receipts
+	TenantID            string
+	SellerName          string
+	SellerAddress       string
+	SellerOrgNumber     string
+	SellerVATNumber     string
+	SellerEmail         string
+	ReceiptNumberPrefix string
+
+	// Tax
+	TaxHomeCountry     string
+	TaxDefaultBehavior string
+	TaxRates           map[string]map[string]float64
+
+	// Dunning
+	DunningRetrySchedule []time.Duration
+	DunningGracePeriod   time.Duration
+	DunningFinalAction   string
+	DunningJobInterval   time.Duration
+
+	// Reconciliation
+	ReconciliationJobInterval time.Duration
+	ReconciliationWindow      time.Duration
+
+	// Payouts
+	PayoutJobInterval  time.Duration
+	PayoutImportWindow time.Duration
+
+	// Wallets
+	WalletExpiryJobInterval time.Duration
+
+	// Metered usage
+	UsageJobInterval time.Duration
+
+	// Subscription schedules
+	SubscriptionScheduleJobInterval time.Duration
+
+	// Native billing for providers without a subscription API
+	NativeBillingProviders      []string
+	NativeBillingPaymentTimeout time.Duration
+	BillingJobInterval          time.Duration
+
+	// Refund approval
+	RefundApprovalAmountThreshold int64
+	RefundApprovalPaymentAge      time.Duration
+	RefundApprovalRoles           []string
 }
 
 func Load() (*Config, error) {
@@ -54,12 +107,91 @@ func Load() (*Config, error) {
 		SwishWebhookSecret:  getEnv("SWISH_WEBHOOK_SECRET", ""),
 		AuthServiceURL:      getEnv("AUTH_SERVICE_URL", "https://auth.vibeoholic.com"),
 		AllowedOrigins:      parseCSV(getEnv("ALLOWED_ORIGINS", "http://localhost:3000")),
+		UseFakeProvider:     getEnv("USE_FAKE_PROVIDER", "false") == "true",
+		DunningFinalAction:  getEnv("DUNNING_FINAL_ACTION", "cancel"),
+		TenantID:            getEnv("TENANT_ID", "default"),
+		SellerName:          getEnv("SELLER_NAME", ""),
+		SellerAddress:       getEnv("SELLER_ADDRESS", ""),
+		SellerOrgNumber:     getEnv("SELLER_ORG_NUMBER", ""),
+		SellerVATNumber:     getEnv("SELLER_VAT_NUMBER", ""),
+		SellerEmail:         getEnv("SELLER_EMAIL", ""),
+		ReceiptNumberPrefix: getEnv("RECEIPT_NUMBER_PREFIX", "R"),
+		TaxHomeCountry:      strings.ToUpper(getEnv("TAX_HOME_COUNTRY", "SE")),
+		TaxDefaultBehavior:  getEnv("TAX_DEFAULT_BEHAVIOR", "inclusive"),
+		RefundApprovalRoles: parseCSV(getEnv("REFUND_APPROVAL_ROLES", "support")),
+
+		NativeBillingProviders: parseCSV(getEnv("NATIVE_BILLING_PROVIDERS", "swish")),
+	}
+
+	var err error
+	if cfg.FakeProviderDeclineAttempts, err = strconv.Atoi(getEnv("FAKE_PROVIDER_DECLINE_ATTEMPTS", "2")); err != nil {
+		return nil, fmt.Errorf("invalid FAKE_PROVIDER_DECLINE_ATTEMPTS: %w", err)
+	}
+	if cfg.DunningRetrySchedule, err = parseDurations(getEnv("DUNNING_RETRY_SCHEDULE", "72h,120h,168h")); err != nil {
+		return nil, fmt.Errorf("invalid DUNNING_RETRY_SCHEDULE: %w", err)
+	}
+	if cfg.DunningGracePeriod, err = time.ParseDuration(getEnv("DUNNING_GRACE_PERIOD", "336h")); err != nil {
+		return nil, fmt.Errorf("invalid DUNNING_GRACE_PERIOD: %w", err)
+	}
+	if cfg.DunningJobInterval, err = time.ParseDuration(getEnv("DUNNING_JOB_INTERVAL", "1h")); err != nil {
+		return nil, fmt.Errorf("invalid DUNNING_JOB_INTERVAL: %w", err)
+	}
+	if cfg.ReconciliationJobInterval, err = time.ParseDuration(getEnv("RECONCILIATION_JOB_INTERVAL", "24h")); err != nil {
+		return nil, fmt.Errorf("invalid RECONCILIATION_JOB_INTERVAL: %w", err)
+	}
+	if cfg.ReconciliationWindow, err = time.ParseDuration(getEnv("RECONCILIATION_WINDOW", "48h")); err != nil {
+		return nil, fmt.Errorf("invalid RECONCILIATION_WINDOW: %w", err)
+	}
+	if cfg.PayoutJobInterval, err = time.ParseDuration(getEnv("PAYOUT_JOB_INTERVAL", "6h")); err != nil {
+		return nil, fmt.Errorf("invalid PAYOUT_JOB_INTERVAL: %w", err)
+	}
+	if cfg.PayoutImportWindow, err = time.ParseDuration(getEnv("PAYOUT_IMPORT_WINDOW", "336h")); err != nil {
+		return nil, fmt.Errorf("invalid PAYOUT_IMPORT_WINDOW: %w", err)
+	}
+	if cfg.WalletExpiryJobInterval, err = time.ParseDuration(getEnv("WALLET_EXPIRY_JOB_INTERVAL", "1h")); err != nil {
+		return nil, fmt.Errorf("invalid WALLET_EXPIRY_JOB_INTERVAL: %w", err)
+	}
+	if cfg.UsageJobInterval, err = time.ParseDuration(getEnv("USAGE_JOB_INTERVAL", "15m")); err != nil {
+		return nil, fmt.Errorf("invalid USAGE_JOB_INTERVAL: %w", err)
+	}
+	if cfg.SubscriptionScheduleJobInterval, err = time.ParseDuration(getEnv("SUBSCRIPTION_SCHEDULE_JOB_INTERVAL", "5m")); err != nil {
+		return nil, fmt.Errorf("invalid SUBSCRIPTION_SCHEDULE_JOB_INTERVAL: %w", err)
+	}
+	if cfg.NativeBillingPaymentTimeout, err = time.ParseDuration(getEnv("NATIVE_BILLING_PAYMENT_TIMEOUT", "24h")); err != nil {
+		return nil, fmt.Errorf("invalid NATIVE_BILLING_PAYMENT_TIMEOUT: %w", err)
+	}
+	if cfg.BillingJobInterval, err = time.ParseDuration(getEnv("BILLING_JOB_INTERVAL", "1m")); err != nil {
+		return nil, fmt.Errorf("invalid BILLING_JOB_INTERVAL: %w", err)
+	}
+	if cfg.RefundApprovalAmountThreshold, err = strconv.ParseInt(getEnv("REFUND_APPROVAL_AMOUNT_THRESHOLD", "0"), 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid REFUND_APPROVAL_AMOUNT_THRESHOLD: %w", err)
+	}
+	if cfg.RefundApprovalPaymentAge, err = time.ParseDuration(getEnv("REFUND_APPROVAL_PAYMENT_AGE", "0")); err != nil {
+		return nil, fmt.Errorf("invalid REFUND_APPROVAL_PAYMENT_AGE: %w", err)
+	}
+	if cfg.DunningFinalAction != "cancel" && cfg.DunningFinalAction != "mark_unpaid" {
+		return nil, fmt.Errorf("DUNNING_FINAL_ACTION must be cancel or mark_unpaid")
+	}
+	for _, provider := range cfg.NativeBillingProviders {
+		if provider != "stripe" && provider != "swish" {
+			return nil, fmt.Errorf("NATIVE_BILLING_PROVIDERS must list stripe or swish, got %q", provider)
+		}
+	}
+
+	if cfg.TaxRates, err = parseTaxRates(getEnv("TAX_RATES", "")); err != nil {
+		return nil, fmt.Errorf("invalid TAX_RATES: %w", err)
+	}
+	if cfg.TaxDefaultBehavior != "inclusive" && cfg.TaxDefaultBehavior != "exclusive" {
+		return nil, fmt.Errorf("TAX_DEFAULT_BEHAVIOR must be inclusive or exclusive")
 	}
 
 	// Validate required fields
 	if cfg.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL is required")
 	}
+	if cfg.UseFakeProvider {
+		return cfg, nil
+	}
 	if cfg.StripeAPIKey == "" {
 		return nil, fmt.Errorf("STRIPE_API_KEY is required")
 	}
@@ -90,3 +222,43 @@ func parseCSV(s string) []string {
 	}
 	return result
 }
+
+// parseDurations parses a comma-separated list of Go durations, e.g. "72h,120h"
+func parseDurations(s string) ([]time.Duration, error) {
+	parts := parseCSV(s)
+	result := make([]time.Duration, 0, len(parts))
+	for _, p := range parts {
+		d, err := time.ParseDuration(p)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+// parseTaxRates parses comma-separated COUNTRY:category=percent entries,
+// e.g. "DE:reduced=7,FR:reduced=5.5"
+func parseTaxRates(s string) (map[string]map[string]float64, error) {
+	rates := map[string]map[string]float64{}
+	for _, p := range parseCSV(s) {
+		country, rest, ok := strings.Cut(p, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected COUNTRY:category=percent, got %q", p)
+		}
+		category, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected COUNTRY:category=percent, got %q", p)
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if rates[country] == nil {
+			rates[country] = map[string]float64{}
+		}
+		rates[country][strings.TrimSpace(category)] = rate
+	}
+	return rates, nil
+}
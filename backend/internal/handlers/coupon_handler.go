@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type CouponHandler struct {
+	couponService *services.CouponService
+}
+
+func NewCouponHandler(couponService *services.CouponService) *CouponHandler {
+	return &CouponHandler{
+		couponService: couponService,
+	}
+}
+
+// CreateCoupon handles POST /api/admin/coupons
+func (h *CouponHandler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	// Decode request
+	var req models.CreateCouponRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid request body",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Create coupon
+	coupon, err := h.couponService.CreateCoupon(r.Context(), &req)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to create coupon",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, coupon)
+}
+
+// GetCoupon handles GET /api/admin/coupons/:id
+func (h *CouponHandler) GetCoupon(w http.ResponseWriter, r *http.Request) {
+	// Parse coupon ID
+	couponID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid coupon ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Get coupon
+	coupon, err := h.couponService.GetCoupon(r.Context(), couponID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve coupon",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, coupon)
+}
+
+// ListCoupons handles GET /api/admin/coupons
+func (h *CouponHandler) ListCoupons(w http.ResponseWriter, r *http.Request) {
+	// Parse pagination params
+	limit := 20
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	// List coupons
+	response, err := h.couponService.ListCoupons(r.Context(), limit, offset)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list coupons",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// DeleteCoupon handles DELETE /api/admin/coupons/:id
+func (h *CouponHandler) DeleteCoupon(w http.ResponseWriter, r *http.Request) {
+	// Parse coupon ID
+	couponID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid coupon ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Invalidate coupon
+	coupon, err := h.couponService.DeleteCoupon(r.Context(), couponID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to delete coupon",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, coupon)
+}
+
+// CreatePromotionCode handles POST /api/admin/coupons/:id/promotion-codes
+func (h *CouponHandler) CreatePromotionCode(w http.ResponseWriter, r *http.Request) {
+	// Parse coupon ID
+	couponID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid coupon ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Decode request
+	var req models.CreatePromotionCodeRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid request body",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Create promotion code
+	promotionCode, err := h.couponService.CreatePromotionCode(r.Context(), couponID, &req)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to create promotion code",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, promotionCode)
+}
+
+// ListPromotionCodes handles GET /api/admin/coupons/:id/promotion-codes
+func (h *CouponHandler) ListPromotionCodes(w http.ResponseWriter, r *http.Request) {
+	// Parse coupon ID
+	couponID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid coupon ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// List promotion codes
+	promotionCodes, err := h.couponService.ListPromotionCodes(r.Context(), couponID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list promotion codes",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, promotionCodes)
+}
+
+// UpdatePromotionCode handles PATCH /api/admin/promotion-codes/:id
+func (h *CouponHandler) UpdatePromotionCode(w http.ResponseWriter, r *http.Request) {
+	// Parse promotion code ID
+	promotionCodeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid promotion code ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Decode request
+	var req models.UpdatePromotionCodeRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid request body",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Update promotion code
+	promotionCode, err := h.couponService.UpdatePromotionCode(r.Context(), promotionCodeID, &req)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update promotion code",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, promotionCode)
+}
@@ -1,19 +1,22 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
 	"payment-service/internal/middleware"
 	"payment-service/internal/models"
-	"payment-service/internal/repository"
+	"payment-service/internal/services"
 )
 
 type CustomerHandler struct {
-	customerRepo *repository.CustomerRepository
+	customerService *services.CustomerService
+	balanceService  *services.CustomerBalanceService
 }
 
-func NewCustomerHandler(customerRepo *repository.CustomerRepository) *CustomerHandler {
+func NewCustomerHandler(customerService *services.CustomerService, balanceService *services.CustomerBalanceService) *CustomerHandler {
 	return &CustomerHandler{
-		customerRepo: customerRepo,
+		customerService: customerService,
+		balanceService:  balanceService,
 	}
 }
 
@@ -31,24 +34,153 @@ func (h *CustomerHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get customer
-	customer, err := h.customerRepo.GetByUserID(r.Context(), userID)
+	customer, err := h.customerService.GetCustomer(r.Context(), userID)
 	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+		} else {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeProviderError,
+				err.Error(),
+				http.StatusInternalServerError,
+			))
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, customer)
+}
+
+// UpdateMe handles PATCH /api/customers/me
+func (h *CustomerHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
 		WriteError(w, models.NewAPIError(
-			models.ErrCodeProviderError,
-			"Failed to retrieve customer",
-			http.StatusInternalServerError,
+			models.ErrCodeAuthenticationFailed,
+			"User ID not found in context",
+			http.StatusUnauthorized,
 		))
 		return
 	}
 
-	if customer == nil {
+	// Parse request
+	var req models.UpdateCustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteError(w, models.NewAPIError(
-			models.ErrCodeNotFound,
-			"Customer not found",
-			http.StatusNotFound,
+			models.ErrCodeInvalidRequest,
+			"Invalid request body",
+			http.StatusBadRequest,
 		))
 		return
 	}
 
+	// Update billing profile
+	customer, err := h.customerService.UpdateCustomer(r.Context(), userID, &req)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+		} else {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeProviderError,
+				err.Error(),
+				http.StatusInternalServerError,
+			))
+		}
+		return
+	}
+
 	WriteJSON(w, http.StatusOK, customer)
 }
+
+// ExportMe handles GET /api/customers/me/export
+func (h *CustomerHandler) ExportMe(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeAuthenticationFailed,
+			"User ID not found in context",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	export, err := h.customerService.ExportCustomer(r.Context(), userID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+		} else {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeProviderError,
+				err.Error(),
+				http.StatusInternalServerError,
+			))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="customer-export.json"`)
+	WriteJSON(w, http.StatusOK, export)
+}
+
+// GetBalance handles GET /api/customers/me/balance
+func (h *CustomerHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeAuthenticationFailed,
+			"User ID not found in context",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	limit, offset := parsePagination(r)
+
+	balance, err := h.balanceService.GetBalance(r.Context(), userID, limit, offset)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+		} else {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeProviderError,
+				err.Error(),
+				http.StatusInternalServerError,
+			))
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, balance)
+}
+
+// EraseMe handles DELETE /api/customers/me
+func (h *CustomerHandler) EraseMe(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeAuthenticationFailed,
+			"User ID not found in context",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	if err := h.customerService.EraseCustomer(r.Context(), userID); err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+		} else {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeProviderError,
+				err.Error(),
+				http.StatusInternalServerError,
+			))
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
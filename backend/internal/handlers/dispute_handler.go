@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// maxDisputeEvidenceRequestSize leaves room for text fields and multipart
+// overhead on top of the evidence files
+const maxDisputeEvidenceRequestSize = models.MaxDisputeEvidenceSize + 1<<20
+
+type DisputeHandler struct {
+	disputeService *services.DisputeService
+}
+
+func NewDisputeHandler(disputeService *services.DisputeService) *DisputeHandler {
+	return &DisputeHandler{
+		disputeService: disputeService,
+	}
+}
+
+// ListDisputes handles GET /api/admin/disputes
+// Optional filter: status
+func (h *DisputeHandler) ListDisputes(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+
+	response, err := h.disputeService.ListDisputes(r.Context(), models.DisputeFilter{
+		Status: models.DisputeStatus(r.URL.Query().Get("status")),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list disputes",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// GetDispute handles GET /api/admin/disputes/{id}
+func (h *DisputeHandler) GetDispute(w http.ResponseWriter, r *http.Request) {
+	disputeID, ok := parseDisputeID(w, r)
+	if !ok {
+		return
+	}
+
+	dispute, err := h.disputeService.GetDispute(r.Context(), disputeID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to get dispute",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, dispute)
+}
+
+// SubmitEvidence handles POST /api/admin/disputes/{id}/evidence
+// Takes a multipart form: text evidence as form values and PDF, JPEG or PNG
+// files as file parts, both named after the evidence field. The evidence is
+// submitted to the bank unless submit=false, which only stages it.
+func (h *DisputeHandler) SubmitEvidence(w http.ResponseWriter, r *http.Request) {
+	disputeID, ok := parseDisputeID(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxDisputeEvidenceRequestSize)
+	if err := r.ParseMultipartForm(maxDisputeEvidenceRequestSize); err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid multipart form or evidence too large",
+			http.StatusBadRequest,
+		))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	req := &models.SubmitDisputeEvidenceRequest{
+		Text:   map[string]string{},
+		Submit: true,
+	}
+	for field, values := range r.MultipartForm.Value {
+		if field == "submit" {
+			submit, err := strconv.ParseBool(values[0])
+			if err != nil {
+				WriteError(w, models.NewAPIError(
+					models.ErrCodeInvalidRequest,
+					"submit must be true or false",
+					http.StatusBadRequest,
+				))
+				return
+			}
+			req.Submit = submit
+			continue
+		}
+		req.Text[field] = values[0]
+	}
+
+	for field, headers := range r.MultipartForm.File {
+		if len(headers) != 1 {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				fmt.Sprintf("Only one file can be uploaded for %s", field),
+				http.StatusBadRequest,
+			))
+			return
+		}
+
+		file, err := headers[0].Open()
+		if err != nil {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Failed to read evidence file",
+				http.StatusBadRequest,
+			))
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Failed to read evidence file",
+				http.StatusBadRequest,
+			))
+			return
+		}
+
+		req.Files = append(req.Files, models.DisputeEvidenceFile{
+			Field:    field,
+			Filename: headers[0].Filename,
+			Data:     data,
+		})
+	}
+
+	dispute, err := h.disputeService.SubmitEvidence(r.Context(), disputeID, req)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to submit evidence",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, dispute)
+}
+
+func parseDisputeID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	disputeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid dispute ID",
+			http.StatusBadRequest,
+		))
+		return uuid.Nil, false
+	}
+	return disputeID, true
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+)
+
+type DunningHandler struct {
+	dunningService *services.DunningService
+}
+
+func NewDunningHandler(dunningService *services.DunningService) *DunningHandler {
+	return &DunningHandler{
+		dunningService: dunningService,
+	}
+}
+
+// RunDunning handles POST /api/admin/dunning/run
+// Runs one dunning pass immediately instead of waiting for the background job
+func (h *DunningHandler) RunDunning(w http.ResponseWriter, r *http.Request) {
+	result, err := h.dunningService.ProcessDueRetries(r.Context())
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to run dunning",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"payment-service/internal/middleware"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+	"strconv"
+)
+
+type EventHandler struct {
+	eventService *services.EventService
+}
+
+func NewEventHandler(eventService *services.EventService) *EventHandler {
+	return &EventHandler{
+		eventService: eventService,
+	}
+}
+
+// ListEvents handles GET /api/events
+func (h *EventHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	// Parse pagination params
+	limit := 20
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	// List events
+	response, err := h.eventService.ListEvents(r.Context(), userID, limit, offset)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list events",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"payment-service/internal/middleware"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type InvoiceHandler struct {
+	invoiceService *services.InvoiceService
+}
+
+func NewInvoiceHandler(invoiceService *services.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: invoiceService,
+	}
+}
+
+// ListInvoices handles GET /api/invoices
+func (h *InvoiceHandler) ListInvoices(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	limit, offset := parsePagination(r)
+
+	// List invoices
+	response, err := h.invoiceService.ListInvoices(r.Context(), userID, limit, offset)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list invoices",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// ListSubscriptionInvoices handles GET /api/subscriptions/{id}/invoices
+func (h *InvoiceHandler) ListSubscriptionInvoices(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	// Parse subscription ID
+	subscriptionIDStr := chi.URLParam(r, "id")
+	subscriptionID, err := uuid.Parse(subscriptionIDStr)
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid subscription ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	limit, offset := parsePagination(r)
+
+	// List invoices
+	response, err := h.invoiceService.ListSubscriptionInvoices(r.Context(), subscriptionID, userID, limit, offset)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list invoices",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
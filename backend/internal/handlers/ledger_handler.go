@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+
+	"github.com/google/uuid"
+)
+
+type LedgerHandler struct {
+	ledgerService *services.LedgerService
+}
+
+func NewLedgerHandler(ledgerService *services.LedgerService) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerService: ledgerService,
+	}
+}
+
+// GetBalances handles GET /api/admin/ledger/balances
+// Optional filters: tenant, customer_id, account and currency
+func (h *LedgerHandler) GetBalances(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.LedgerBalanceFilter{
+		Tenant:      query.Get("tenant"),
+		AccountCode: models.LedgerAccountCode(query.Get("account")),
+		Currency:    models.Currency(query.Get("currency")),
+	}
+
+	if customerID := query.Get("customer_id"); customerID != "" {
+		id, err := uuid.Parse(customerID)
+		if err != nil {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Invalid customer ID",
+				http.StatusBadRequest,
+			))
+			return
+		}
+		filter.CustomerID = &id
+	}
+
+	balances, err := h.ledgerService.GetBalances(r.Context(), filter)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve ledger balances",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, balances)
+}
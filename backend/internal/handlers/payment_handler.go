@@ -45,19 +45,15 @@ func (h *PaymentHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate request
-	if req.Amount <= 0 {
-		WriteError(w, models.NewAPIError(
-			models.ErrCodeInvalidRequest,
-			"Amount must be greater than 0",
-			http.StatusBadRequest,
-		))
-		return
-	}
-
 	if req.Currency == "" {
 		req.Currency = models.CurrencySEK // Default
 	}
 
+	if err := validateAmount(req.Amount, req.Currency); err != nil {
+		WriteError(w, err)
+		return
+	}
+
 	if req.Provider == "" {
 		req.Provider = models.ProviderStripe // Default
 	}
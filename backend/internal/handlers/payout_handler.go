@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type PayoutHandler struct {
+	payoutService *services.PayoutService
+}
+
+func NewPayoutHandler(payoutService *services.PayoutService) *PayoutHandler {
+	return &PayoutHandler{
+		payoutService: payoutService,
+	}
+}
+
+// ImportPayouts handles POST /api/admin/payouts/import
+// Imports payouts created between the optional from and to query params
+// (YYYY-MM-DD, to is exclusive) instead of waiting for the background job
+func (h *PayoutHandler) ImportPayouts(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	result, err := h.payoutService.ImportPayouts(r.Context(), from, to)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to import payouts",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, result)
+}
+
+// ListPayouts handles GET /api/admin/payouts
+func (h *PayoutHandler) ListPayouts(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+
+	response, err := h.payoutService.ListPayouts(r.Context(), limit, offset)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list payouts",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// GetPayout handles GET /api/admin/payouts/{id}
+// Returns the payout with its charges, refunds and fees
+func (h *PayoutHandler) GetPayout(w http.ResponseWriter, r *http.Request) {
+	payoutID, ok := parsePayoutID(w, r)
+	if !ok {
+		return
+	}
+
+	breakdown, err := h.payoutService.GetPayoutBreakdown(r.Context(), payoutID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to get payout",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, breakdown)
+}
+
+// ExportPayout handles GET /api/admin/payouts/{id}/export.csv
+func (h *PayoutHandler) ExportPayout(w http.ResponseWriter, r *http.Request) {
+	payoutID, ok := parsePayoutID(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.payoutService.ExportPayoutCSV(r.Context(), payoutID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to export payout",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"payout-%s.csv\"", payoutID))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func parsePayoutID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	payoutID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid payout ID",
+			http.StatusBadRequest,
+		))
+		return uuid.Nil, false
+	}
+	return payoutID, true
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"payment-service/internal/middleware"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ReceiptHandler struct {
+	receiptService *services.ReceiptService
+}
+
+func NewReceiptHandler(receiptService *services.ReceiptService) *ReceiptHandler {
+	return &ReceiptHandler{
+		receiptService: receiptService,
+	}
+}
+
+// GetPaymentReceipt handles GET /api/payments/{id}/receipt.pdf
+func (h *ReceiptHandler) GetPaymentReceipt(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	// Parse payment ID
+	paymentIDStr := chi.URLParam(r, "id")
+	paymentID, err := uuid.Parse(paymentIDStr)
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid payment ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Get receipt
+	receipt, err := h.receiptService.GetPaymentReceipt(r.Context(), paymentID, userID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to get receipt",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	writePDF(w, receipt)
+}
+
+// GetInvoiceReceipt handles GET /api/invoices/{id}/receipt.pdf
+func (h *ReceiptHandler) GetInvoiceReceipt(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	// Parse invoice ID
+	invoiceIDStr := chi.URLParam(r, "id")
+	invoiceID, err := uuid.Parse(invoiceIDStr)
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid invoice ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Get receipt
+	receipt, err := h.receiptService.GetInvoiceReceipt(r.Context(), invoiceID, userID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to get receipt",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	writePDF(w, receipt)
+}
+
+// writePDF writes a receipt as a PDF download
+func writePDF(w http.ResponseWriter, receipt *models.Receipt) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"receipt-%s.pdf\"", receipt.ReceiptNumber))
+	w.Header().Set("Content-Length", strconv.Itoa(len(receipt.PDF)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(receipt.PDF)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ReconciliationHandler struct {
+	reconciliationService *services.ReconciliationService
+}
+
+func NewReconciliationHandler(reconciliationService *services.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		reconciliationService: reconciliationService,
+	}
+}
+
+// RunReconciliation handles POST /api/admin/reconciliation/run
+// Reconciles objects created between the optional from and to query params
+// (YYYY-MM-DD, to is exclusive) instead of waiting for the daily job
+func (h *ReconciliationHandler) RunReconciliation(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	result, err := h.reconciliationService.Reconcile(r.Context(), &models.ReconciliationRequest{From: from, To: to})
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to run reconciliation",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, result)
+}
+
+// ListDiscrepancies handles GET /api/admin/reconciliation/discrepancies
+// Optional filters: status (default open; "all" for every status) and object_type
+func (h *ReconciliationHandler) ListDiscrepancies(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, offset := parsePagination(r)
+
+	filter := models.DiscrepancyFilter{
+		Status:     models.DiscrepancyStatus(query.Get("status")),
+		ObjectType: models.ReconciliationObjectType(query.Get("object_type")),
+		Limit:      limit,
+		Offset:     offset,
+	}
+	switch filter.Status {
+	case "":
+		filter.Status = models.DiscrepancyStatusOpen
+	case "all":
+		filter.Status = ""
+	}
+
+	response, err := h.reconciliationService.ListDiscrepancies(r.Context(), filter)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list discrepancies",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// ResolveDiscrepancy handles POST /api/admin/reconciliation/discrepancies/{id}/resolve
+func (h *ReconciliationHandler) ResolveDiscrepancy(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid discrepancy ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	if err := h.reconciliationService.ResolveDiscrepancy(r.Context(), id); err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to resolve discrepancy",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"payment-service/internal/middleware"
 	"payment-service/internal/models"
@@ -22,7 +23,22 @@ func NewRefundHandler(refundService *services.RefundService) *RefundHandler {
 }
 
 // CreateRefund handles POST /api/refunds
+// Refunds that need approval are returned with 202 in pending_approval.
 func (h *RefundHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
+	h.createRefund(w, r, h.refundService.CreateRefund)
+}
+
+// CreateSupportRefund handles POST /api/support/refunds
+// Refunds any customer's payment; same request and response as CreateRefund.
+func (h *RefundHandler) CreateSupportRefund(w http.ResponseWriter, r *http.Request) {
+	h.createRefund(w, r, h.refundService.CreateSupportRefund)
+}
+
+func (h *RefundHandler) createRefund(
+	w http.ResponseWriter,
+	r *http.Request,
+	create func(context.Context, uuid.UUID, string, *models.CreateRefundRequest) (*models.Refund, error),
+) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
 		WriteError(w, models.NewAPIError(
@@ -32,6 +48,7 @@ func (h *RefundHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
 		))
 		return
 	}
+	role, _ := middleware.GetRoleFromContext(r.Context())
 
 	// Decode request
 	var req models.CreateRefundRequest
@@ -64,7 +81,7 @@ func (h *RefundHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create refund
-	refund, err := h.refundService.CreateRefund(r.Context(), userID, &req)
+	refund, err := create(r.Context(), userID, role, &req)
 	if err != nil {
 		if apiErr, ok := err.(*models.APIError); ok {
 			WriteError(w, apiErr)
@@ -78,6 +95,10 @@ func (h *RefundHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if refund.Status == models.RefundStatusPendingApproval {
+		WriteJSON(w, http.StatusAccepted, refund)
+		return
+	}
 	WriteJSON(w, http.StatusCreated, refund)
 }
 
@@ -210,3 +231,90 @@ func (h *RefundHandler) ListRefundsByPayment(w http.ResponseWriter, r *http.Requ
 
 	WriteJSON(w, http.StatusOK, refunds)
 }
+
+// ListPendingApprovals handles GET /api/admin/refunds/pending-approval
+func (h *RefundHandler) ListPendingApprovals(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r)
+
+	response, err := h.refundService.ListPendingApprovals(r.Context(), limit, offset)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list refunds",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// ApproveRefund handles POST /api/admin/refunds/{id}/approve
+// Takes an optional {"note": "..."} body.
+func (h *RefundHandler) ApproveRefund(w http.ResponseWriter, r *http.Request) {
+	h.reviewRefund(w, r, h.refundService.ApproveRefund)
+}
+
+// RejectRefund handles POST /api/admin/refunds/{id}/reject
+// Takes an optional {"note": "..."} body.
+func (h *RefundHandler) RejectRefund(w http.ResponseWriter, r *http.Request) {
+	h.reviewRefund(w, r, h.refundService.RejectRefund)
+}
+
+func (h *RefundHandler) reviewRefund(
+	w http.ResponseWriter,
+	r *http.Request,
+	review func(context.Context, uuid.UUID, uuid.UUID, *models.ReviewRefundRequest) (*models.Refund, error),
+) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	refundID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid refund ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	var req models.ReviewRefundRequest
+	if r.ContentLength != 0 {
+		if err := DecodeJSON(r, &req); err != nil {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Invalid request body",
+				http.StatusBadRequest,
+			))
+			return
+		}
+	}
+
+	refund, err := review(r.Context(), refundID, userID, &req)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to review refund",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, refund)
+}
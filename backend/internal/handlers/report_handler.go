@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+)
+
+type ReportHandler struct {
+	reportService *services.ReportService
+}
+
+func NewReportHandler(reportService *services.ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// GetProductMargins handles GET /api/admin/reports/product-margins
+// Optional query params: from and to (YYYY-MM-DD, to is exclusive)
+func (h *ReportHandler) GetProductMargins(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	report, err := h.reportService.GetProductMargins(r.Context(), from, to)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve product margins",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, report)
+}
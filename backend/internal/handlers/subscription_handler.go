@@ -6,6 +6,7 @@ import (
 	"payment-service/internal/models"
 	"payment-service/internal/services"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -13,11 +14,13 @@ import (
 
 type SubscriptionHandler struct {
 	subscriptionService *services.SubscriptionService
+	usageService        *services.UsageService
 }
 
-func NewSubscriptionHandler(subscriptionService *services.SubscriptionService) *SubscriptionHandler {
+func NewSubscriptionHandler(subscriptionService *services.SubscriptionService, usageService *services.UsageService) *SubscriptionHandler {
 	return &SubscriptionHandler{
 		subscriptionService: subscriptionService,
+		usageService:        usageService,
 	}
 }
 
@@ -47,8 +50,9 @@ func (h *SubscriptionHandler) CreateSubscription(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// Validate request
-	if req.Amount <= 0 {
+	// Validate request; items replace the amount, and a purely usage-based
+	// subscription has no fixed amount
+	if req.Amount < 0 || (req.Amount == 0 && len(req.Items) == 0 && len(req.MeteredPrices) == 0) {
 		WriteError(w, models.NewAPIError(
 			models.ErrCodeInvalidRequest,
 			"Amount must be greater than zero",
@@ -66,6 +70,18 @@ func (h *SubscriptionHandler) CreateSubscription(w http.ResponseWriter, r *http.
 		return
 	}
 
+	if err := validateCurrency(req.Currency); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if req.Amount > 0 {
+		if err := validateAmount(req.Amount, req.Currency); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
 	if req.Interval == "" {
 		WriteError(w, models.NewAPIError(
 			models.ErrCodeInvalidRequest,
@@ -88,6 +104,35 @@ func (h *SubscriptionHandler) CreateSubscription(w http.ResponseWriter, r *http.
 		return
 	}
 
+	if req.TrialPeriodDays < 0 {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Trial period days cannot be negative",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	switch req.TrialEndBehavior {
+	case "":
+	case models.TrialEndBehaviorCancel, models.TrialEndBehaviorPause:
+		if req.TrialPeriodDays == 0 {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Trial end behavior requires a trial period",
+				http.StatusBadRequest,
+			))
+			return
+		}
+	default:
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid trial end behavior",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
 	// Create subscription
 	subscription, err := h.subscriptionService.CreateSubscription(
 		r.Context(),
@@ -285,12 +330,40 @@ func (h *SubscriptionHandler) CancelSubscription(w http.ResponseWriter, r *http.
 	// Parse query param for immediate cancellation
 	immediate := r.URL.Query().Get("immediate") == "true"
 
+	// Decode optional churn feedback
+	var req models.CancelSubscriptionRequest
+	if r.ContentLength != 0 {
+		if err := DecodeJSON(r, &req); err != nil {
+			WriteError(w, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Invalid request body",
+				http.StatusBadRequest,
+			))
+			return
+		}
+	}
+
+	switch req.Reason {
+	case "", models.CancellationReasonTooExpensive, models.CancellationReasonMissingFeatures,
+		models.CancellationReasonSwitchedService, models.CancellationReasonUnused,
+		models.CancellationReasonCustomerService, models.CancellationReasonTooComplex,
+		models.CancellationReasonLowQuality, models.CancellationReasonOther:
+	default:
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid cancellation reason",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
 	// Cancel subscription
 	subscription, err := h.subscriptionService.CancelSubscription(
 		r.Context(),
 		subscriptionID,
 		userID,
 		immediate,
+		&req,
 	)
 	if err != nil {
 		if apiErr, ok := err.(*models.APIError); ok {
@@ -307,3 +380,420 @@ func (h *SubscriptionHandler) CancelSubscription(w http.ResponseWriter, r *http.
 
 	WriteJSON(w, http.StatusOK, subscription)
 }
+
+// ReactivateSubscription handles POST /api/subscriptions/:id/reactivate
+func (h *SubscriptionHandler) ReactivateSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	// Parse subscription ID
+	subscriptionIDStr := chi.URLParam(r, "id")
+	subscriptionID, err := uuid.Parse(subscriptionIDStr)
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid subscription ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Reactivate subscription
+	subscription, err := h.subscriptionService.ReactivateSubscription(r.Context(), subscriptionID, userID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to reactivate subscription",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, subscription)
+}
+
+// PauseSubscription handles POST /api/subscriptions/:id/pause
+func (h *SubscriptionHandler) PauseSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	// Parse subscription ID
+	subscriptionIDStr := chi.URLParam(r, "id")
+	subscriptionID, err := uuid.Parse(subscriptionIDStr)
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid subscription ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Decode request
+	var req models.PauseSubscriptionRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid request body",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Validate request
+	switch req.Behavior {
+	case "":
+		req.Behavior = models.PauseBehaviorVoid // Default
+	case models.PauseBehaviorVoid, models.PauseBehaviorKeepAsDraft, models.PauseBehaviorMarkUncollectible:
+	default:
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Behavior must be one of void, keep_as_draft or mark_uncollectible",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	if req.ResumesAt != nil && !req.ResumesAt.After(time.Now()) {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Resume date must be in the future",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Pause subscription
+	subscription, err := h.subscriptionService.PauseSubscription(
+		r.Context(),
+		subscriptionID,
+		userID,
+		&req,
+	)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to pause subscription",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, subscription)
+}
+
+// ResumeSubscription handles POST /api/subscriptions/:id/resume
+func (h *SubscriptionHandler) ResumeSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	// Parse subscription ID
+	subscriptionIDStr := chi.URLParam(r, "id")
+	subscriptionID, err := uuid.Parse(subscriptionIDStr)
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid subscription ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Resume subscription
+	subscription, err := h.subscriptionService.ResumeSubscription(r.Context(), subscriptionID, userID)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to resume subscription",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, subscription)
+}
+
+// ExtendTrial handles POST /api/admin/subscriptions/:id/extend-trial
+func (h *SubscriptionHandler) ExtendTrial(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	// Parse subscription ID
+	subscriptionIDStr := chi.URLParam(r, "id")
+	subscriptionID, err := uuid.Parse(subscriptionIDStr)
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid subscription ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Decode request
+	var req models.ExtendTrialRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid request body",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	if !req.TrialEnd.After(time.Now()) {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"trial_end must be in the future",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	// Extend trial
+	subscription, err := h.subscriptionService.ExtendTrial(r.Context(), subscriptionID, userID, &req)
+	if err != nil {
+		if apiErr, ok := err.(*models.APIError); ok {
+			WriteError(w, apiErr)
+			return
+		}
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to extend trial",
+			http.StatusInternalServerError,
+		))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, subscription)
+}
+
+// ReportUsage handles POST /api/subscriptions/:id/usage
+func (h *SubscriptionHandler) ReportUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	subscriptionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid subscription ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	var req models.ReportUsageRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	record, err := h.usageService.ReportUsage(r.Context(), subscriptionID, userID, &req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, record)
+}
+
+// GetUsage handles GET /api/subscriptions/:id/usage
+func (h *SubscriptionHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	subscriptionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid subscription ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	usage, err := h.usageService.GetUsage(r.Context(), subscriptionID, userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, usage)
+}
+
+// AddItem handles POST /api/subscriptions/:id/items
+func (h *SubscriptionHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	subscriptionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid subscription ID",
+			http.StatusBadRequest,
+		))
+		return
+	}
+
+	var req models.AddSubscriptionItemRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	subscription, err := h.subscriptionService.AddItem(r.Context(), subscriptionID, userID, &req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, subscription)
+}
+
+// UpdateItem handles PATCH /api/subscriptions/:id/items/:itemId
+func (h *SubscriptionHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	subscriptionID, itemID, ok := parseSubscriptionItemID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateSubscriptionItemRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	subscription, err := h.subscriptionService.UpdateItem(r.Context(), subscriptionID, itemID, userID, &req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, subscription)
+}
+
+// RemoveItem handles DELETE /api/subscriptions/:id/items/:itemId
+// Takes an optional proration_behavior query parameter.
+func (h *SubscriptionHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"User not authenticated",
+			http.StatusUnauthorized,
+		))
+		return
+	}
+
+	subscriptionID, itemID, ok := parseSubscriptionItemID(w, r)
+	if !ok {
+		return
+	}
+
+	proration := models.ProrationBehavior(r.URL.Query().Get("proration_behavior"))
+
+	subscription, err := h.subscriptionService.RemoveItem(r.Context(), subscriptionID, itemID, userID, proration)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, subscription)
+}
+
+func parseSubscriptionItemID(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	subscriptionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid subscription ID",
+			http.StatusBadRequest,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	itemID, err := uuid.Parse(chi.URLParam(r, "itemId"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid item ID",
+			http.StatusBadRequest,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return subscriptionID, itemID, true
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"payment-service/internal/middleware"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type SubscriptionScheduleHandler struct {
+	scheduleService *services.SubscriptionScheduleService
+}
+
+func NewSubscriptionScheduleHandler(scheduleService *services.SubscriptionScheduleService) *SubscriptionScheduleHandler {
+	return &SubscriptionScheduleHandler{
+		scheduleService: scheduleService,
+	}
+}
+
+// CreateSchedule handles POST /api/subscription-schedules
+func (h *SubscriptionScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getScheduleUserID(w, r)
+	if !ok {
+		return
+	}
+
+	email, _ := middleware.GetEmailFromContext(r.Context())
+	name, _ := middleware.GetNameFromContext(r.Context())
+
+	var req models.CreateSubscriptionScheduleRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if req.SubscriptionID == nil && req.Provider == "" {
+		req.Provider = models.ProviderStripe // Default
+	}
+
+	if req.Currency != "" {
+		if err := validateCurrency(req.Currency); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	schedule, err := h.scheduleService.CreateSchedule(r.Context(), userID, email, name, &req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, schedule)
+}
+
+// GetSchedule handles GET /api/subscription-schedules/{id}
+func (h *SubscriptionScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getScheduleUserID(w, r)
+	if !ok {
+		return
+	}
+
+	scheduleID, ok := parseScheduleID(w, r)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.scheduleService.GetSchedule(r.Context(), scheduleID, userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, schedule)
+}
+
+// ListSchedules handles GET /api/subscription-schedules
+func (h *SubscriptionScheduleHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getScheduleUserID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := parsePagination(r)
+
+	response, err := h.scheduleService.ListSchedules(r.Context(), userID, limit, offset)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// CancelSchedule handles POST /api/subscription-schedules/{id}/cancel
+func (h *SubscriptionScheduleHandler) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getScheduleUserID(w, r)
+	if !ok {
+		return
+	}
+
+	scheduleID, ok := parseScheduleID(w, r)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.scheduleService.CancelSchedule(r.Context(), scheduleID, userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, schedule)
+}
+
+func getScheduleUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeAuthenticationFailed,
+			"User ID not found in context",
+			http.StatusUnauthorized,
+		))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func parseScheduleID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	scheduleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid subscription schedule ID",
+			http.StatusBadRequest,
+		))
+		return uuid.Nil, false
+	}
+	return scheduleID, true
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"payment-service/internal/models"
+	"payment-service/pkg/currency"
+	"strconv"
+	"time"
 )
 
 // WriteJSON writes a JSON response
@@ -48,3 +51,85 @@ func DecodeJSON(r *http.Request, v any) error {
 	}
 	return nil
 }
+
+// validateCurrency checks that a requested currency is in the currency registry
+func validateCurrency(c models.Currency) error {
+	if !c.IsValid() {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Unsupported currency: "+string(c),
+			http.StatusBadRequest,
+		)
+	}
+	return nil
+}
+
+// validateAmount checks that a requested amount, in the currency's minor unit,
+// is greater than zero and within the currency's limit
+func validateAmount(amount int64, c models.Currency) error {
+	if err := validateCurrency(c); err != nil {
+		return err
+	}
+
+	info, _ := currency.Lookup(string(c))
+	switch {
+	case amount <= 0:
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Amount must be greater than 0",
+			http.StatusBadRequest,
+		)
+	case amount > info.MaxAmount():
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Amount must be at most "+info.FormatAmount(info.MaxAmount())+" "+info.Code,
+			http.StatusBadRequest,
+		)
+	}
+	return nil
+}
+
+// parsePagination reads limit and offset query params (default 20, max 100)
+func parsePagination(r *http.Request) (int, int) {
+	limit := 20
+	offset := 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	return limit, offset
+}
+
+// parseDateRange reads the optional from and to query params (YYYY-MM-DD).
+// Missing dates are returned as zero times.
+func parseDateRange(r *http.Request) (from, to time.Time, err error) {
+	for _, param := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		raw := r.URL.Query().Get(param.name)
+		if raw == "" {
+			continue
+		}
+		parsed, parseErr := time.Parse(time.DateOnly, raw)
+		if parseErr != nil {
+			return time.Time{}, time.Time{}, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Invalid "+param.name+" date, expected YYYY-MM-DD",
+				http.StatusBadRequest,
+			)
+		}
+		*param.value = parsed
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"net/http"
+	"payment-service/internal/middleware"
+	"payment-service/internal/models"
+	"payment-service/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type WalletHandler struct {
+	walletService *services.WalletService
+}
+
+func NewWalletHandler(walletService *services.WalletService) *WalletHandler {
+	return &WalletHandler{
+		walletService: walletService,
+	}
+}
+
+// ListWallets handles GET /api/wallet
+func (h *WalletHandler) ListWallets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getWalletUserID(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := h.walletService.ListWallets(r.Context(), userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// ListTransactions handles GET /api/wallet/transactions
+func (h *WalletHandler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getWalletUserID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := parsePagination(r)
+
+	response, err := h.walletService.ListTransactions(r.Context(), userID, limit, offset)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// TopUp handles POST /api/wallet/top-ups
+func (h *WalletHandler) TopUp(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getWalletUserID(w, r)
+	if !ok {
+		return
+	}
+
+	email, _ := middleware.GetEmailFromContext(r.Context())
+	name, _ := middleware.GetNameFromContext(r.Context())
+
+	var req models.WalletTopUpRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if req.Currency == "" {
+		req.Currency = models.CurrencySEK // Default
+	}
+
+	if err := validateAmount(req.Amount, req.Currency); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if req.Provider == "" {
+		req.Provider = models.ProviderStripe // Default
+	}
+
+	response, err := h.walletService.TopUp(r.Context(), userID, email, name, &req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, response)
+}
+
+// Debit handles POST /api/wallet/debits
+func (h *WalletHandler) Debit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getWalletUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.WalletDebitRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if req.Currency == "" {
+		req.Currency = models.CurrencySEK // Default
+	}
+
+	if err := validateAmount(req.Amount, req.Currency); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	txn, err := h.walletService.Debit(r.Context(), userID, &req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, txn)
+}
+
+// CreateHold handles POST /api/wallet/holds
+func (h *WalletHandler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getWalletUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.WalletHoldRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if req.Currency == "" {
+		req.Currency = models.CurrencySEK // Default
+	}
+
+	if err := validateAmount(req.Amount, req.Currency); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	hold, err := h.walletService.CreateHold(r.Context(), userID, &req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, hold)
+}
+
+// CaptureHold handles POST /api/wallet/holds/{id}/capture
+// Takes an optional {"amount": ...} body; the full hold is captured by default.
+func (h *WalletHandler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getWalletUserID(w, r)
+	if !ok {
+		return
+	}
+
+	holdID, ok := parseWalletHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CaptureWalletHoldRequest
+	if r.ContentLength != 0 {
+		if err := DecodeJSON(r, &req); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	hold, err := h.walletService.CaptureHold(r.Context(), userID, holdID, &req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, hold)
+}
+
+// ReleaseHold handles POST /api/wallet/holds/{id}/release
+func (h *WalletHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	userID, ok := getWalletUserID(w, r)
+	if !ok {
+		return
+	}
+
+	holdID, ok := parseWalletHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.walletService.ReleaseHold(r.Context(), userID, holdID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, hold)
+}
+
+// GrantCredit handles POST /api/admin/wallets/grants
+func (h *WalletHandler) GrantCredit(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := getWalletUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.WalletGrantRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if req.Currency == "" {
+		req.Currency = models.CurrencySEK // Default
+	}
+
+	if err := validateAmount(req.Amount, req.Currency); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	txn, err := h.walletService.GrantCredit(r.Context(), adminID, &req)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, txn)
+}
+
+func getWalletUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeAuthenticationFailed,
+			"User ID not found in context",
+			http.StatusUnauthorized,
+		))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func parseWalletHoldID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	holdID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Invalid hold ID",
+			http.StatusBadRequest,
+		))
+		return uuid.Nil, false
+	}
+	return holdID, true
+}
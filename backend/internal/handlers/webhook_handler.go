@@ -2,18 +2,22 @@ package handlers
 
 import (
 	"io"
+	"log"
 	"net/http"
 	"payment-service/internal/models"
 	"payment-service/internal/providers"
+	"payment-service/internal/services"
 )
 
 type WebhookHandler struct {
 	providerFactory *providers.Factory
+	webhookService  *services.WebhookService
 }
 
-func NewWebhookHandler(providerFactory *providers.Factory) *WebhookHandler {
+func NewWebhookHandler(providerFactory *providers.Factory, webhookService *services.WebhookService) *WebhookHandler {
 	return &WebhookHandler{
 		providerFactory: providerFactory,
+		webhookService:  webhookService,
 	}
 }
 
@@ -73,16 +77,16 @@ func (h *WebhookHandler) HandleStripeWebhook(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// TODO: Process webhook event with webhook service
-	// This would involve:
-	// 1. Checking for duplicate events using webhook_events table
-	// 2. Processing the event based on type (payment.succeeded, subscription.updated, etc.)
-	// 3. Updating database records accordingly
-	// 4. Storing the event in webhook_events table for deduplication
-
-	// For now, just log that we received the webhook
-	// In production, this should be processed asynchronously
-	_ = webhookEvent
+	// Process webhook event (deduplicated by provider event ID)
+	if err := h.webhookService.ProcessWebhookEvent(r.Context(), webhookEvent, "stripe"); err != nil {
+		log.Printf("Failed to process Stripe webhook %s (%s): %v", webhookEvent.ID, webhookEvent.Type, err)
+		WriteError(w, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to process webhook event",
+			http.StatusInternalServerError,
+		))
+		return
+	}
 
 	// Return 200 OK to acknowledge receipt
 	w.WriteHeader(http.StatusOK)
@@ -106,3 +106,33 @@ func IsSuperAdmin(ctx context.Context) bool {
 	isSuperAdmin, ok := ctx.Value(IsSuperAdminKey).(bool)
 	return ok && isSuperAdmin
 }
+
+// RequireRole only lets through users with one of the given roles. Super
+// admins are always allowed.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if IsSuperAdmin(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			role, _ := GetRoleFromContext(r.Context())
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]interface{}{
+					"code":    "forbidden",
+					"message": "Insufficient permissions",
+				},
+			})
+		})
+	}
+}
@@ -107,6 +107,39 @@ var (
 		},
 		[]string{"provider", "event_type"},
 	)
+
+	// Reconciliation metrics
+	reconciliationDiscrepancies = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payment_service_reconciliation_discrepancies_total",
+			Help: "Total number of discrepancies found by reconciliation",
+		},
+		[]string{"object_type", "kind", "status"},
+	)
+
+	reconciliationOpenDiscrepancies = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "payment_service_reconciliation_open_discrepancies",
+			Help: "Number of open reconciliation discrepancies",
+		},
+		[]string{"object_type"},
+	)
+
+	reconciliationLastRun = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "payment_service_reconciliation_last_run_timestamp_seconds",
+			Help: "Unix time of the last completed reconciliation run",
+		},
+	)
+
+	// Dispute metrics
+	disputesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payment_service_disputes_total",
+			Help: "Total number of disputes opened and closed",
+		},
+		[]string{"provider", "status", "reason"},
+	)
 )
 
 // MetricsMiddleware records HTTP request metrics
@@ -182,3 +215,23 @@ func RecordWebhookError(provider, eventType string) {
 func RecordWebhookDuration(provider, eventType string, duration time.Duration) {
 	webhookProcessingDuration.WithLabelValues(provider, eventType).Observe(duration.Seconds())
 }
+
+// RecordDiscrepancy records a discrepancy found by reconciliation
+func RecordDiscrepancy(objectType, kind, status string) {
+	reconciliationDiscrepancies.WithLabelValues(objectType, kind, status).Inc()
+}
+
+// UpdateOpenDiscrepancies updates the open discrepancies gauge
+func UpdateOpenDiscrepancies(objectType string, count int) {
+	reconciliationOpenDiscrepancies.WithLabelValues(objectType).Set(float64(count))
+}
+
+// RecordReconciliationRun records when a reconciliation run completed
+func RecordReconciliationRun(completedAt time.Time) {
+	reconciliationLastRun.Set(float64(completedAt.Unix()))
+}
+
+// RecordDispute records a dispute being opened or closed
+func RecordDispute(provider, status, reason string) {
+	disputesTotal.WithLabelValues(provider, status, reason).Inc()
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction identifies the kind of change recorded in the audit log
+type AuditAction string
+
+const (
+	AuditActionSubscriptionCanceled        AuditAction = "subscription.canceled"
+	AuditActionSubscriptionReactivated     AuditAction = "subscription.reactivated"
+	AuditActionSubscriptionTrialExtended   AuditAction = "subscription.trial_extended"
+	AuditActionSubscriptionItemsChanged    AuditAction = "subscription.items_changed"
+	AuditActionSubscriptionScheduleChanged AuditAction = "subscription.schedule_changed"
+	AuditActionCustomerErased              AuditAction = "customer.erased"
+	AuditActionRefundApprovalRequested     AuditAction = "refund.approval_requested"
+	AuditActionRefundApproved              AuditAction = "refund.approved"
+	AuditActionRefundRejected              AuditAction = "refund.rejected"
+	AuditActionWalletCreditGranted         AuditAction = "wallet.credit_granted"
+)
+
+// AuditEvent records a change made to a resource and who made it
+type AuditEvent struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	CustomerID *uuid.UUID `json:"customer_id,omitempty" db:"customer_id"`
+
+	// Who made the change (NULL for system and webhook driven changes)
+	ActorUserID *uuid.UUID `json:"actor_user_id,omitempty" db:"actor_user_id"`
+
+	// What changed
+	Action       AuditAction `json:"action" db:"action"`
+	ResourceType string      `json:"resource_type" db:"resource_type"`
+	ResourceID   uuid.UUID   `json:"resource_id" db:"resource_id"`
+	Details      JSONBMap    `json:"details,omitempty" db:"details"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"payment-service/pkg/currency"
 )
 
 // Provider represents a payment provider
@@ -14,16 +15,52 @@ const (
 	ProviderSwish  Provider = "swish"
 )
 
-// Currency represents a currency code
+// Currency represents an ISO 4217 currency code. Any currency in the
+// currency registry is accepted; amounts are in its minor unit (see Exponent).
 type Currency string
 
 const (
 	CurrencySEK Currency = "SEK"
-	CurrencyUSD Currency = "USD"
+	CurrencyNOK Currency = "NOK"
+	CurrencyDKK Currency = "DKK"
 	CurrencyEUR Currency = "EUR"
 	CurrencyGBP Currency = "GBP"
+	CurrencyCHF Currency = "CHF"
+	CurrencyPLN Currency = "PLN"
+	CurrencyUSD Currency = "USD"
+	CurrencyCAD Currency = "CAD"
+	CurrencyAUD Currency = "AUD"
+	CurrencyJPY Currency = "JPY"
+	CurrencyISK Currency = "ISK"
 )
 
+// IsValid reports whether the currency is in the currency registry
+func (c Currency) IsValid() bool {
+	return currency.IsValid(string(c))
+}
+
+// Exponent returns the number of decimals of the currency's minor unit, e.g.
+// 2 for SEK and 0 for JPY
+func (c Currency) Exponent() int {
+	return c.info().Exponent
+}
+
+// IsZeroDecimal reports whether amounts in the currency are in whole units
+func (c Currency) IsZeroDecimal() bool {
+	return c.info().IsZeroDecimal()
+}
+
+// FormatAmount formats an amount in the currency's minor unit as a decimal
+// number, e.g. 12345 is "123.45" in SEK and "12345" in JPY
+func (c Currency) FormatAmount(amount int64) string {
+	return c.info().FormatAmount(amount)
+}
+
+// info returns the currency's registry entry
+func (c Currency) info() currency.Currency {
+	return currency.ForCode(string(c))
+}
+
 // PaymentStatus represents the status of a payment
 type PaymentStatus string
 
@@ -50,17 +87,139 @@ const (
 	SubscriptionStatusPaused            SubscriptionStatus = "paused"
 )
 
+// PauseBehavior represents how invoices are handled while a subscription is paused
+type PauseBehavior string
+
+const (
+	PauseBehaviorVoid              PauseBehavior = "void"
+	PauseBehaviorKeepAsDraft       PauseBehavior = "keep_as_draft"
+	PauseBehaviorMarkUncollectible PauseBehavior = "mark_uncollectible"
+)
+
+// CancellationReason represents why a customer canceled a subscription
+type CancellationReason string
+
+const (
+	CancellationReasonTooExpensive    CancellationReason = "too_expensive"
+	CancellationReasonMissingFeatures CancellationReason = "missing_features"
+	CancellationReasonSwitchedService CancellationReason = "switched_service"
+	CancellationReasonUnused          CancellationReason = "unused"
+	CancellationReasonCustomerService CancellationReason = "customer_service"
+	CancellationReasonTooComplex      CancellationReason = "too_complex"
+	CancellationReasonLowQuality      CancellationReason = "low_quality"
+	CancellationReasonOther           CancellationReason = "other"
+)
+
+// TrialEndBehavior represents what happens when a trial ends without a payment method
+type TrialEndBehavior string
+
+const (
+	TrialEndBehaviorCancel TrialEndBehavior = "cancel"
+	TrialEndBehaviorPause  TrialEndBehavior = "pause"
+)
+
+// DunningStatus represents where a subscription is in the failed-payment retry cycle
+type DunningStatus string
+
+const (
+	DunningStatusRetrying  DunningStatus = "retrying"
+	DunningStatusRecovered DunningStatus = "recovered"
+	DunningStatusExhausted DunningStatus = "exhausted"
+)
+
+// ProrationBehavior represents how a mid-period change to subscription items is billed
+type ProrationBehavior string
+
+const (
+	ProrationBehaviorCreateProrations ProrationBehavior = "create_prorations" // Settled on the next invoice
+	ProrationBehaviorAlwaysInvoice    ProrationBehavior = "always_invoice"    // Invoiced right away
+	ProrationBehaviorNone             ProrationBehavior = "none"              // New price from the next period
+)
+
+// SubscriptionScheduleStatus represents where a subscription schedule is in its phases
+type SubscriptionScheduleStatus string
+
+const (
+	SubscriptionScheduleStatusNotStarted SubscriptionScheduleStatus = "not_started" // Waiting for its first phase
+	SubscriptionScheduleStatusActive     SubscriptionScheduleStatus = "active"
+	SubscriptionScheduleStatusCompleted  SubscriptionScheduleStatus = "completed" // Its last phase ended
+	SubscriptionScheduleStatusReleased   SubscriptionScheduleStatus = "released"  // Stopped early; the subscription continues as it is
+	SubscriptionScheduleStatusCanceled   SubscriptionScheduleStatus = "canceled"  // Stopped before it started a subscription
+)
+
+// ScheduleEndBehavior represents what happens to a subscription when its schedule's last phase ends
+type ScheduleEndBehavior string
+
+const (
+	ScheduleEndBehaviorRelease ScheduleEndBehavior = "release" // The subscription continues as in the last phase
+	ScheduleEndBehaviorCancel  ScheduleEndBehavior = "cancel"
+)
+
+// DunningFinalAction represents what happens to a subscription once dunning is exhausted
+type DunningFinalAction string
+
+const (
+	DunningFinalActionCancel     DunningFinalAction = "cancel"
+	DunningFinalActionMarkUnpaid DunningFinalAction = "mark_unpaid"
+)
+
+// CouponDuration represents how long a coupon's discount applies to a subscription
+type CouponDuration string
+
+const (
+	CouponDurationOnce      CouponDuration = "once"
+	CouponDurationRepeating CouponDuration = "repeating"
+	CouponDurationForever   CouponDuration = "forever"
+)
+
+// InvoiceStatus represents the status of an invoice
+type InvoiceStatus string
+
+const (
+	InvoiceStatusDraft         InvoiceStatus = "draft"
+	InvoiceStatusOpen          InvoiceStatus = "open"
+	InvoiceStatusPaid          InvoiceStatus = "paid"
+	InvoiceStatusVoid          InvoiceStatus = "void"
+	InvoiceStatusUncollectible InvoiceStatus = "uncollectible"
+)
+
+// TaxCategory represents the VAT category a product is sold under
+type TaxCategory string
+
+const (
+	TaxCategoryStandard     TaxCategory = "standard"
+	TaxCategoryReduced      TaxCategory = "reduced"       // e.g. food and hotels, 12% in Sweden
+	TaxCategorySuperReduced TaxCategory = "super_reduced" // e.g. books and transport, 6% in Sweden
+	TaxCategoryExempt       TaxCategory = "exempt"
+)
+
+// TaxBehavior represents whether a price includes tax
+type TaxBehavior string
+
+const (
+	TaxBehaviorInclusive TaxBehavior = "inclusive"
+	TaxBehaviorExclusive TaxBehavior = "exclusive"
+)
+
 // RefundStatus represents the status of a refund
 type RefundStatus string
 
 const (
-	RefundStatusPending    RefundStatus = "pending"
-	RefundStatusProcessing RefundStatus = "processing"
-	RefundStatusSucceeded  RefundStatus = "succeeded"
-	RefundStatusFailed     RefundStatus = "failed"
-	RefundStatusCanceled   RefundStatus = "canceled"
+	RefundStatusPendingApproval RefundStatus = "pending_approval"
+	RefundStatusPending         RefundStatus = "pending"
+	RefundStatusProcessing      RefundStatus = "processing"
+	RefundStatusSucceeded       RefundStatus = "succeeded"
+	RefundStatusFailed          RefundStatus = "failed"
+	RefundStatusCanceled        RefundStatus = "canceled"
+	RefundStatusRejected        RefundStatus = "rejected"
 )
 
+// Open reports whether a refund can still succeed or fail. Open refunds keep
+// their amount reserved on the payment.
+func (s RefundStatus) Open() bool {
+	return s == RefundStatusPendingApproval || s == RefundStatusPending || s == RefundStatusProcessing
+}
+
 // JSONBMap is a map[string]any that implements sql.Scanner and driver.Valuer
 // for PostgreSQL JSONB columns.
 type JSONBMap map[string]any
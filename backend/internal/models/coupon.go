@@ -0,0 +1,136 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Coupon represents a reusable discount, either a percentage or a fixed amount
+type Coupon struct {
+	ID   uuid.UUID `json:"id" db:"id"`
+	Name string    `json:"name" db:"name"`
+
+	// Discount (exactly one of PercentOff and AmountOff is set)
+	PercentOff *float64  `json:"percent_off,omitempty" db:"percent_off"`
+	AmountOff  *int64    `json:"amount_off,omitempty" db:"amount_off"`
+	Currency   *Currency `json:"currency,omitempty" db:"currency"`
+
+	// How long the discount applies to a subscription
+	Duration         CouponDuration `json:"duration" db:"duration"`
+	DurationInMonths *int           `json:"duration_in_months,omitempty" db:"duration_in_months"`
+
+	// Redemption limits
+	MaxRedemptions *int       `json:"max_redemptions,omitempty" db:"max_redemptions"`
+	TimesRedeemed  int        `json:"times_redeemed" db:"times_redeemed"`
+	RedeemBy       *time.Time `json:"redeem_by,omitempty" db:"redeem_by"`
+	Valid          bool       `json:"valid" db:"valid"`
+
+	// Provider-specific coupon IDs
+	StripeCouponID *string `json:"stripe_coupon_id,omitempty" db:"stripe_coupon_id"`
+
+	// Metadata
+	Metadata JSONBMap `json:"metadata,omitempty" db:"metadata"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DiscountFor returns the discount the coupon gives on amount. The result
+// never exceeds amount.
+func (c *Coupon) DiscountFor(amount int64) int64 {
+	var discount int64
+	if c.PercentOff != nil {
+		discount = int64(math.Round(float64(amount) * *c.PercentOff / 100))
+	} else if c.AmountOff != nil {
+		discount = *c.AmountOff
+	}
+
+	if discount > amount {
+		return amount
+	}
+	return discount
+}
+
+// PromotionCode represents a customer-facing code that redeems a coupon
+type PromotionCode struct {
+	ID       uuid.UUID `json:"id" db:"id"`
+	CouponID uuid.UUID `json:"coupon_id" db:"coupon_id"`
+	Code     string    `json:"code" db:"code"`
+	Active   bool      `json:"active" db:"active"`
+
+	// Restrictions
+	MaxRedemptions       *int       `json:"max_redemptions,omitempty" db:"max_redemptions"`
+	TimesRedeemed        int        `json:"times_redeemed" db:"times_redeemed"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	FirstTimeTransaction bool       `json:"first_time_transaction" db:"first_time_transaction"`
+
+	// Metadata
+	Metadata JSONBMap `json:"metadata,omitempty" db:"metadata"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AppliedDiscount is a coupon redemption reserved for a payment or subscription
+type AppliedDiscount struct {
+	Coupon        *Coupon
+	PromotionCode *PromotionCode
+	Amount        int64
+	Currency      Currency
+}
+
+// Money returns the discount in the currency of the payment it applies to
+func (d *AppliedDiscount) Money() Money {
+	return NewMoney(d.Amount, d.Currency)
+}
+
+// DiscountRedemption records that a customer redeemed a coupon
+type DiscountRedemption struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	CouponID        uuid.UUID  `json:"coupon_id" db:"coupon_id"`
+	PromotionCodeID *uuid.UUID `json:"promotion_code_id,omitempty" db:"promotion_code_id"`
+	CustomerID      uuid.UUID  `json:"customer_id" db:"customer_id"`
+	PaymentID       *uuid.UUID `json:"payment_id,omitempty" db:"payment_id"`
+	SubscriptionID  *uuid.UUID `json:"subscription_id,omitempty" db:"subscription_id"`
+	AmountOff       int64      `json:"amount_off" db:"amount_off"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CreateCouponRequest represents a request to create a coupon
+type CreateCouponRequest struct {
+	Name             string         `json:"name"`
+	PercentOff       *float64       `json:"percent_off,omitempty"`
+	AmountOff        *int64         `json:"amount_off,omitempty"`
+	Currency         *Currency      `json:"currency,omitempty"`
+	Duration         CouponDuration `json:"duration"`
+	DurationInMonths *int           `json:"duration_in_months,omitempty"`
+	MaxRedemptions   *int           `json:"max_redemptions,omitempty"`
+	RedeemBy         *time.Time     `json:"redeem_by,omitempty"`
+	Metadata         map[string]any `json:"metadata,omitempty"`
+}
+
+// CreatePromotionCodeRequest represents a request to create a promotion code
+type CreatePromotionCodeRequest struct {
+	Code                 string         `json:"code"`
+	MaxRedemptions       *int           `json:"max_redemptions,omitempty"`
+	ExpiresAt            *time.Time     `json:"expires_at,omitempty"`
+	FirstTimeTransaction bool           `json:"first_time_transaction"`
+	Metadata             map[string]any `json:"metadata,omitempty"`
+}
+
+// UpdatePromotionCodeRequest represents a request to update a promotion code
+type UpdatePromotionCodeRequest struct {
+	Active *bool `json:"active,omitempty"`
+}
+
+// CouponListResponse represents a list of coupons
+type CouponListResponse struct {
+	Data   []Coupon `json:"data"`
+	Total  int      `json:"total"`
+	Limit  int      `json:"limit"`
+	Offset int      `json:"offset"`
+}
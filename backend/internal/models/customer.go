@@ -13,6 +13,18 @@ type Customer struct {
 	Email     string    `json:"email" db:"email"`
 	Name      string    `json:"name" db:"name"`
 
+	// Billing profile
+	AddressLine1 *string `json:"address_line1,omitempty" db:"address_line1"`
+	AddressLine2 *string `json:"address_line2,omitempty" db:"address_line2"`
+	PostalCode   *string `json:"postal_code,omitempty" db:"postal_code"`
+	City         *string `json:"city,omitempty" db:"city"`
+	Country      *string `json:"country,omitempty" db:"country"` // ISO 3166-1 alpha-2
+	CompanyName  *string `json:"company_name,omitempty" db:"company_name"`
+	OrgNumber    *string `json:"org_number,omitempty" db:"org_number"`
+	VATID        *string `json:"vat_id,omitempty" db:"vat_id"`
+	Locale       *string `json:"locale,omitempty" db:"locale"` // e.g. sv-SE
+	InvoiceEmail *string `json:"invoice_email,omitempty" db:"invoice_email"`
+
 	// Provider-specific customer IDs
 	StripeCustomerID *string `json:"stripe_customer_id,omitempty" db:"stripe_customer_id"`
 	SwishCustomerID  *string `json:"swish_customer_id,omitempty" db:"swish_customer_id"`
@@ -34,3 +46,30 @@ type CreateCustomerRequest struct {
 	Provider Provider       `json:"provider"`
 	Metadata map[string]any `json:"metadata,omitempty"`
 }
+
+// UpdateCustomerRequest represents a request to update a customer's billing
+// profile. Omitted fields are left unchanged and empty strings clear a field.
+type UpdateCustomerRequest struct {
+	Name         *string `json:"name,omitempty"`
+	AddressLine1 *string `json:"address_line1,omitempty"`
+	AddressLine2 *string `json:"address_line2,omitempty"`
+	PostalCode   *string `json:"postal_code,omitempty"`
+	City         *string `json:"city,omitempty"`
+	Country      *string `json:"country,omitempty"`
+	CompanyName  *string `json:"company_name,omitempty"`
+	OrgNumber    *string `json:"org_number,omitempty"`
+	VATID        *string `json:"vat_id,omitempty"`
+	Locale       *string `json:"locale,omitempty"`
+	InvoiceEmail *string `json:"invoice_email,omitempty"`
+}
+
+// CustomerExport is a copy of everything stored about a customer, returned
+// for data access requests
+type CustomerExport struct {
+	ExportedAt    time.Time      `json:"exported_at"`
+	Customer      *Customer      `json:"customer"`
+	Payments      []Payment      `json:"payments"`
+	Subscriptions []Subscription `json:"subscriptions"`
+	Refunds       []Refund       `json:"refunds"`
+	AuditEvents   []AuditEvent   `json:"audit_events"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomerBalanceTransactionType identifies what changed a customer balance
+type CustomerBalanceTransactionType string
+
+const (
+	// Credits
+	CustomerBalanceTransactionRefundCredit CustomerBalanceTransactionType = "refund_credit"
+	CustomerBalanceTransactionReversal     CustomerBalanceTransactionType = "reversal"
+
+	// Debits
+	CustomerBalanceTransactionPaymentApplied CustomerBalanceTransactionType = "payment_applied"
+	CustomerBalanceTransactionInvoiceApplied CustomerBalanceTransactionType = "invoice_applied"
+)
+
+// CustomerBalance is a customer's store credit in one currency
+type CustomerBalance struct {
+	Currency Currency `json:"currency" db:"currency"`
+	Balance  int64    `json:"balance" db:"balance"`
+}
+
+// CustomerBalanceTransaction is one change to a customer balance. Positive
+// amounts credit the customer and negative amounts use credit.
+type CustomerBalanceTransaction struct {
+	ID           uuid.UUID                      `json:"id" db:"id"`
+	CustomerID   uuid.UUID                      `json:"customer_id" db:"customer_id"`
+	Currency     Currency                       `json:"currency" db:"currency"`
+	Amount       int64                          `json:"amount" db:"amount"`
+	BalanceAfter int64                          `json:"balance_after" db:"balance_after"`
+	Type         CustomerBalanceTransactionType `json:"type" db:"type"`
+	Description  *string                        `json:"description,omitempty" db:"description"`
+
+	// What caused the change, e.g. a refund or an invoice
+	ReferenceType string     `json:"reference_type" db:"reference_type"`
+	ReferenceID   *uuid.UUID `json:"reference_id,omitempty" db:"reference_id"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Money returns the transaction's amount in its currency
+func (t *CustomerBalanceTransaction) Money() Money {
+	return NewMoney(t.Amount, t.Currency)
+}
+
+// CustomerBalanceResponse is a customer's balances with a page of their
+// transaction history, newest first
+type CustomerBalanceResponse struct {
+	Balances     []CustomerBalance            `json:"balances"`
+	Transactions []CustomerBalanceTransaction `json:"transactions"`
+	Total        int                          `json:"total"`
+	Limit        int                          `json:"limit"`
+	Offset       int                          `json:"offset"`
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DisputeStatus represents the status of a dispute (chargeback). Warning
+// statuses are inquiries that have not withdrawn funds yet.
+type DisputeStatus string
+
+const (
+	DisputeStatusWarningNeedsResponse DisputeStatus = "warning_needs_response"
+	DisputeStatusWarningUnderReview   DisputeStatus = "warning_under_review"
+	DisputeStatusWarningClosed        DisputeStatus = "warning_closed"
+	DisputeStatusNeedsResponse        DisputeStatus = "needs_response"
+	DisputeStatusUnderReview          DisputeStatus = "under_review"
+	DisputeStatusWon                  DisputeStatus = "won"
+	DisputeStatusLost                 DisputeStatus = "lost"
+)
+
+// NeedsResponse reports whether evidence can still be submitted
+func (s DisputeStatus) NeedsResponse() bool {
+	return s == DisputeStatusNeedsResponse || s == DisputeStatusWarningNeedsResponse
+}
+
+// Closed reports whether the dispute has been decided
+func (s DisputeStatus) Closed() bool {
+	return s == DisputeStatusWon || s == DisputeStatusLost || s == DisputeStatusWarningClosed
+}
+
+// Dispute is a chargeback or inquiry raised by the cardholder's bank against
+// a payment
+type Dispute struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	PaymentID  uuid.UUID `json:"payment_id" db:"payment_id"`
+	CustomerID uuid.UUID `json:"customer_id" db:"customer_id"`
+
+	// Dispute details
+	Provider          Provider      `json:"provider" db:"provider"`
+	ProviderDisputeID string        `json:"provider_dispute_id" db:"provider_dispute_id"`
+	Amount            int64         `json:"amount" db:"amount"`
+	Currency          Currency      `json:"currency" db:"currency"`
+	Status            DisputeStatus `json:"status" db:"status"`
+	Reason            string        `json:"reason" db:"reason"`
+
+	// Evidence; file fields hold the provider's file ID
+	EvidenceDueBy       *time.Time `json:"evidence_due_by,omitempty" db:"evidence_due_by"`
+	Evidence            JSONBMap   `json:"evidence,omitempty" db:"evidence"`
+	EvidenceSubmittedAt *time.Time `json:"evidence_submitted_at,omitempty" db:"evidence_submitted_at"`
+
+	// Timestamps
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+}
+
+// DisputeEvidenceTextFields are the text evidence fields that can be submitted
+var DisputeEvidenceTextFields = map[string]bool{
+	"access_activity_log":            true,
+	"billing_address":                true,
+	"cancellation_policy_disclosure": true,
+	"cancellation_rebuttal":          true,
+	"customer_email_address":         true,
+	"customer_name":                  true,
+	"customer_purchase_ip":           true,
+	"duplicate_charge_explanation":   true,
+	"duplicate_charge_id":            true,
+	"product_description":            true,
+	"refund_policy_disclosure":       true,
+	"refund_refusal_explanation":     true,
+	"service_date":                   true,
+	"shipping_address":               true,
+	"shipping_carrier":               true,
+	"shipping_date":                  true,
+	"shipping_tracking_number":       true,
+	"uncategorized_text":             true,
+}
+
+// DisputeEvidenceFileFields are the evidence fields that take a file upload
+var DisputeEvidenceFileFields = map[string]bool{
+	"cancellation_policy":            true,
+	"customer_communication":         true,
+	"customer_signature":             true,
+	"duplicate_charge_documentation": true,
+	"receipt":                        true,
+	"refund_policy":                  true,
+	"service_documentation":          true,
+	"shipping_documentation":         true,
+	"uncategorized_file":             true,
+}
+
+// MaxDisputeEvidenceSize is the provider's limit on the combined size of a
+// dispute's evidence files
+const MaxDisputeEvidenceSize = 4608 << 10 // 4.5 MB
+
+// DisputeEvidenceFile is an uploaded evidence file
+type DisputeEvidenceFile struct {
+	Field    string
+	Filename string
+	Data     []byte
+}
+
+// SubmitDisputeEvidenceRequest represents evidence for a dispute. Evidence is
+// staged with the provider and only sent to the bank when Submit is set.
+type SubmitDisputeEvidenceRequest struct {
+	Text   map[string]string
+	Files  []DisputeEvidenceFile
+	Submit bool
+}
+
+// DisputeFilter narrows a dispute listing
+type DisputeFilter struct {
+	Status DisputeStatus
+	Limit  int
+	Offset int
+}
+
+// DisputeListResponse represents a paginated list of disputes
+type DisputeListResponse struct {
+	Data   []Dispute `json:"data"`
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType identifies an event exposed to client applications
+type EventType string
+
+const (
+	EventTypeSubscriptionTrialWillEnd   EventType = "subscription.trial_will_end"
+	EventTypeSubscriptionTrialConverted EventType = "subscription.trial_converted"
+	EventTypeSubscriptionTrialExpired   EventType = "subscription.trial_expired"
+
+	EventTypeSubscriptionPaymentFailed    EventType = "subscription.payment_failed"
+	EventTypeSubscriptionPaymentRecovered EventType = "subscription.payment_recovered"
+	EventTypeSubscriptionDunningExhausted EventType = "subscription.dunning_exhausted"
+
+	EventTypePaymentDisputed      EventType = "payment.disputed"
+	EventTypePaymentDisputeClosed EventType = "payment.dispute_closed"
+)
+
+// Event is a notification about a customer's resources that applications can
+// poll for and act on (e.g. reminding a user to add a card before a trial ends)
+type Event struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CustomerID uuid.UUID `json:"customer_id" db:"customer_id"`
+
+	// What happened
+	Type         EventType `json:"type" db:"type"`
+	ResourceType string    `json:"resource_type" db:"resource_type"`
+	ResourceID   uuid.UUID `json:"resource_id" db:"resource_id"`
+	Data         JSONBMap  `json:"data,omitempty" db:"data"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// EventListResponse represents a list of events
+type EventListResponse struct {
+	Data   []Event `json:"data"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invoice is a local mirror of a provider invoice, e.g. a subscription renewal
+type Invoice struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	CustomerID     uuid.UUID  `json:"customer_id" db:"customer_id"`
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty" db:"subscription_id"`
+	PaymentID      *uuid.UUID `json:"payment_id,omitempty" db:"payment_id"`
+
+	// Invoice details
+	Provider          Provider      `json:"provider" db:"provider"`
+	ProviderInvoiceID string        `json:"provider_invoice_id" db:"provider_invoice_id"`
+	Number            *string       `json:"number,omitempty" db:"number"`
+	Status            InvoiceStatus `json:"status" db:"status"`
+	BillingReason     *string       `json:"billing_reason,omitempty" db:"billing_reason"`
+
+	// Amounts in smallest currency unit
+	Currency   Currency `json:"currency" db:"currency"`
+	Subtotal   int64    `json:"subtotal" db:"subtotal"`
+	Tax        int64    `json:"tax" db:"tax"`
+	Total      int64    `json:"total" db:"total"`
+	AmountDue  int64    `json:"amount_due" db:"amount_due"`
+	AmountPaid int64    `json:"amount_paid" db:"amount_paid"`
+
+	// Tax per rate, summing to Tax
+	TaxBreakdown *TaxBreakdown `json:"tax_breakdown,omitempty" db:"tax_breakdown"`
+
+	// Line items
+	LineItems InvoiceLineItems `json:"line_items" db:"line_items"`
+
+	// Billing period covered by the invoice
+	PeriodStart time.Time `json:"period_start" db:"period_start"`
+	PeriodEnd   time.Time `json:"period_end" db:"period_end"`
+
+	// Timestamps
+	DueDate   *time.Time `json:"due_date,omitempty" db:"due_date"`
+	PaidAt    *time.Time `json:"paid_at,omitempty" db:"paid_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// InvoiceLineItem is a single charge on an invoice
+type InvoiceLineItem struct {
+	Description string     `json:"description"`
+	Quantity    int64      `json:"quantity"`
+	UnitAmount  int64      `json:"unit_amount"`
+	Amount      int64      `json:"amount"`
+	PeriodStart *time.Time `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time `json:"period_end,omitempty"`
+}
+
+// InvoiceLineItems is a list of line items stored as a JSONB column
+type InvoiceLineItems []InvoiceLineItem
+
+// Scan implements sql.Scanner for reading JSONB from PostgreSQL.
+func (l *InvoiceLineItems) Scan(value any) error {
+	if value == nil {
+		*l = InvoiceLineItems{}
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("InvoiceLineItems.Scan: expected []byte, got %T", value)
+	}
+	return json.Unmarshal(b, l)
+}
+
+// Value implements driver.Valuer for writing JSONB to PostgreSQL.
+func (l InvoiceLineItems) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l)
+}
+
+// InvoiceListResponse represents a list of invoices
+type InvoiceListResponse struct {
+	Data   []Invoice `json:"data"`
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
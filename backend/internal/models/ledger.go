@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LedgerAccountType is the accounting class of a ledger account
+type LedgerAccountType string
+
+const (
+	LedgerAccountTypeAsset     LedgerAccountType = "asset"
+	LedgerAccountTypeLiability LedgerAccountType = "liability"
+	LedgerAccountTypeRevenue   LedgerAccountType = "revenue"
+	LedgerAccountTypeExpense   LedgerAccountType = "expense"
+)
+
+// LedgerAccountCode identifies an account in the chart of accounts
+type LedgerAccountCode string
+
+const (
+	// Funds held by the provider that have not been paid out yet
+	LedgerAccountProviderBalance LedgerAccountCode = "provider_balance"
+	// Funds paid out to our bank account
+	LedgerAccountBank LedgerAccountCode = "bank"
+
+	LedgerAccountRevenue      LedgerAccountCode = "revenue"
+	LedgerAccountTaxPayable   LedgerAccountCode = "tax_payable"
+	LedgerAccountRefunds      LedgerAccountCode = "refunds"
+	LedgerAccountProviderFees LedgerAccountCode = "provider_fees"
+	LedgerAccountDisputes     LedgerAccountCode = "disputes"
+
+	// Store credit we owe customers
+	LedgerAccountCustomerCredit LedgerAccountCode = "customer_credit"
+	// Prepaid wallet funds we owe customers
+	LedgerAccountWallet LedgerAccountCode = "wallet"
+	// Promotional wallet credit we gave away
+	LedgerAccountPromotions LedgerAccountCode = "promotions"
+)
+
+// ledgerAccountTypes maps each account in the chart of accounts to its type
+var ledgerAccountTypes = map[LedgerAccountCode]LedgerAccountType{
+	LedgerAccountProviderBalance: LedgerAccountTypeAsset,
+	LedgerAccountBank:            LedgerAccountTypeAsset,
+	LedgerAccountRevenue:         LedgerAccountTypeRevenue,
+	LedgerAccountTaxPayable:      LedgerAccountTypeLiability,
+	LedgerAccountRefunds:         LedgerAccountTypeExpense,
+	LedgerAccountProviderFees:    LedgerAccountTypeExpense,
+	LedgerAccountDisputes:        LedgerAccountTypeExpense,
+	LedgerAccountCustomerCredit:  LedgerAccountTypeLiability,
+	LedgerAccountWallet:          LedgerAccountTypeLiability,
+	LedgerAccountPromotions:      LedgerAccountTypeExpense,
+}
+
+// Type returns the account's type, or "" if the code is not in the chart of
+// accounts
+func (c LedgerAccountCode) Type() LedgerAccountType {
+	return ledgerAccountTypes[c]
+}
+
+// LedgerEntryType identifies the kind of money movement an entry records
+type LedgerEntryType string
+
+const (
+	LedgerEntryCharge          LedgerEntryType = "charge"
+	LedgerEntryRefund          LedgerEntryType = "refund"
+	LedgerEntryFee             LedgerEntryType = "fee"
+	LedgerEntryDispute         LedgerEntryType = "dispute"
+	LedgerEntryDisputeReversal LedgerEntryType = "dispute_reversal"
+	LedgerEntryPayout          LedgerEntryType = "payout"
+	LedgerEntryCreditIssued    LedgerEntryType = "credit_issued"
+	LedgerEntryCreditApplied   LedgerEntryType = "credit_applied"
+	LedgerEntryWalletTopUp     LedgerEntryType = "wallet_top_up"
+	LedgerEntryWalletGrant     LedgerEntryType = "wallet_grant"
+	LedgerEntryWalletDebit     LedgerEntryType = "wallet_debit"
+	LedgerEntryWalletExpiry    LedgerEntryType = "wallet_expiry"
+)
+
+// LedgerEntry is a balanced journal entry. Entries are append-only and each
+// money movement is recorded once per reference.
+type LedgerEntry struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	Tenant     string          `json:"tenant" db:"tenant"`
+	Type       LedgerEntryType `json:"type" db:"type"`
+	Currency   Currency        `json:"currency" db:"currency"`
+	CustomerID *uuid.UUID      `json:"customer_id,omitempty" db:"customer_id"`
+
+	// What the entry records, e.g. a payment or refund
+	ReferenceType string    `json:"reference_type" db:"reference_type"`
+	ReferenceID   uuid.UUID `json:"reference_id" db:"reference_id"`
+	Description   *string   `json:"description,omitempty" db:"description"`
+
+	Postings []LedgerPosting `json:"postings"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// LedgerPosting moves an amount in or out of one account. Debits are
+// positive and credits negative, in minor units of the entry's currency.
+type LedgerPosting struct {
+	ID          uuid.UUID         `json:"id" db:"id"`
+	EntryID     uuid.UUID         `json:"entry_id" db:"entry_id"`
+	AccountCode LedgerAccountCode `json:"account_code" db:"account_code"`
+	Amount      int64             `json:"amount" db:"amount"`
+}
+
+// LedgerBalance is the balance of one account in one currency. Positive
+// balances are debit balances.
+type LedgerBalance struct {
+	AccountCode LedgerAccountCode `json:"account_code"`
+	AccountType LedgerAccountType `json:"account_type"`
+	Currency    Currency          `json:"currency"`
+	Balance     int64             `json:"balance"`
+}
+
+// LedgerBalanceFilter narrows a balance query
+type LedgerBalanceFilter struct {
+	Tenant      string
+	CustomerID  *uuid.UUID
+	AccountCode LedgerAccountCode
+	Currency    Currency
+}
@@ -0,0 +1,16 @@
+package models
+
+import "payment-service/pkg/currency"
+
+// Money is an amount in a currency's minor unit, e.g. 12345 SEK is 123.45 kr.
+// Arithmetic refuses to mix currencies; see currency.Money.
+type Money = currency.Money[Currency]
+
+// ErrCurrencyMismatch is returned when amounts in different currencies are
+// combined
+var ErrCurrencyMismatch = currency.ErrCurrencyMismatch
+
+// NewMoney returns amount minor units of currency
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
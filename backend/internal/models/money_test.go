@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoney_Arithmetic(t *testing.T) {
+	total, err := Money{}.Add(NewMoney(10000, CurrencySEK))
+	assert.NoError(t, err)
+	total, err = total.Sub(NewMoney(2550, CurrencySEK))
+	assert.NoError(t, err)
+	assert.Equal(t, NewMoney(7450, CurrencySEK), total)
+
+	_, err = total.Add(NewMoney(100, CurrencyEUR))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = total.Cmp(NewMoney(100, CurrencyNOK))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestMoney_AllocateKeepsEveryOre(t *testing.T) {
+	parts, err := NewMoney(10000, CurrencySEK).Allocate(1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []Money{NewMoney(3333, CurrencySEK), NewMoney(6667, CurrencySEK)}, parts)
+
+	parts, err = NewMoney(-100, CurrencySEK).Split(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []Money{
+		NewMoney(-34, CurrencySEK),
+		NewMoney(-33, CurrencySEK),
+		NewMoney(-33, CurrencySEK),
+	}, parts)
+
+	_, err = NewMoney(100, CurrencySEK).Split(0)
+	assert.Error(t, err)
+}
+
+func TestMoney_Format(t *testing.T) {
+	assert.Equal(t, "1\u00a0234,56\u00a0kr", NewMoney(123456, CurrencySEK).Format("sv-SE"))
+	assert.Equal(t, "SEK\u00a01,234.56", NewMoney(123456, CurrencySEK).Format("en-US"))
+	assert.Equal(t, "-€1,234.56", NewMoney(-123456, CurrencyEUR).Format("en"))
+	assert.Equal(t, "1.234,56\u00a0€", NewMoney(123456, CurrencyEUR).Format("de_DE"))
+	assert.Equal(t, "¥123,456", NewMoney(123456, CurrencyJPY).Format("ja-JP"))
+	assert.Equal(t, "1234.56 SEK", NewMoney(123456, CurrencySEK).String())
+}
+
+func TestMoney_JSON(t *testing.T) {
+	b, err := json.Marshal(NewMoney(4000, CurrencyNOK))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":4000,"currency":"NOK"}`, string(b))
+
+	var m Money
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, NewMoney(4000, CurrencyNOK), m)
+}
+
+func TestMoney_ScanValueRoundTrip(t *testing.T) {
+	v, err := NewMoney(123456, CurrencyEUR).Value()
+	assert.NoError(t, err)
+
+	var m Money
+	assert.NoError(t, m.Scan(v))
+	assert.Equal(t, NewMoney(123456, CurrencyEUR), m)
+
+	assert.NoError(t, m.Scan(nil))
+	assert.Equal(t, Money{}, m)
+}
+
+func TestMoney_ScanRejectsUnknownCurrency(t *testing.T) {
+	var m Money
+	assert.Error(t, m.Scan([]byte(`{"amount":100,"currency":"XXX"}`)))
+	assert.Error(t, m.Scan([]byte(`{"amount":100,"currency":""}`)))
+	assert.Error(t, m.Scan(int64(100)))
+	assert.Equal(t, Money{}, m)
+}
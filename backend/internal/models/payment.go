@@ -18,6 +18,38 @@ type Payment struct {
 	Currency          Currency      `json:"currency" db:"currency"`
 	Status            PaymentStatus `json:"status" db:"status"`
 
+	// Discount (Amount is the price after the discount)
+	DiscountAmount  int64      `json:"discount_amount" db:"discount_amount"`
+	CouponID        *uuid.UUID `json:"coupon_id,omitempty" db:"coupon_id"`
+	PromotionCodeID *uuid.UUID `json:"promotion_code_id,omitempty" db:"promotion_code_id"`
+
+	// Tax (included in Amount)
+	TaxAmount    int64         `json:"tax_amount" db:"tax_amount"`
+	TaxBreakdown *TaxBreakdown `json:"tax_breakdown,omitempty" db:"tax_breakdown"`
+
+	// Customer balance used towards Amount; the rest is charged through the
+	// provider. A payment fully covered by credit has no provider payment.
+	CreditApplied int64 `json:"credit_applied" db:"credit_applied"`
+
+	// Settlement, from the provider's balance transaction. Amounts are in
+	// the settlement currency; set once the provider has settled.
+	ProviderFee          *int64     `json:"provider_fee,omitempty" db:"provider_fee"`
+	NetAmount            *int64     `json:"net_amount,omitempty" db:"net_amount"`
+	SettlementCurrency   *Currency  `json:"settlement_currency,omitempty" db:"settlement_currency"`
+	ExchangeRate         *float64   `json:"exchange_rate,omitempty" db:"exchange_rate"`
+	AvailableOn          *time.Time `json:"available_on,omitempty" db:"available_on"`
+	BalanceTransactionID *string    `json:"balance_transaction_id,omitempty" db:"balance_transaction_id"`
+
+	// Refunds. Open refunds reserve their amount in AmountRefundPending until
+	// they succeed or fail, so concurrent refunds cannot exceed the charged
+	// amount.
+	AmountRefunded      int64                `json:"amount_refunded" db:"amount_refunded"`
+	AmountRefundPending int64                `json:"amount_refund_pending" db:"amount_refund_pending"`
+	RefundStatus        *PaymentRefundStatus `json:"refund_status,omitempty" db:"refund_status"`
+
+	// Set when the cardholder's bank first disputes the payment
+	DisputedAt *time.Time `json:"disputed_at,omitempty" db:"disputed_at"`
+
 	// Payment method
 	PaymentMethodType    *string        `json:"payment_method_type,omitempty" db:"payment_method_type"`
 	PaymentMethodDetails JSONBMap `json:"payment_method_details,omitempty" db:"payment_method_details"`
@@ -49,6 +81,31 @@ type Payment struct {
 	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
 }
 
+// PaymentRefundStatus reports whether a payment has been partly or fully refunded
+type PaymentRefundStatus string
+
+const (
+	PaymentRefundStatusPartiallyRefunded PaymentRefundStatus = "partially_refunded"
+	PaymentRefundStatusRefunded          PaymentRefundStatus = "refunded"
+)
+
+// Money returns the payment's amount in its currency
+func (p *Payment) Money() Money {
+	return NewMoney(p.Amount, p.Currency)
+}
+
+// ChargedAmount is the part of the amount charged through the provider, i.e.
+// not covered by store credit
+func (p *Payment) ChargedAmount() Money {
+	return NewMoney(p.Amount-p.CreditApplied, p.Currency)
+}
+
+// RefundableAmount is what is left to refund of the charged amount after
+// succeeded and open refunds
+func (p *Payment) RefundableAmount() Money {
+	return NewMoney(p.Amount-p.CreditApplied-p.AmountRefunded-p.AmountRefundPending, p.Currency)
+}
+
 // CreatePaymentRequest represents a request to create a payment
 type CreatePaymentRequest struct {
 	Provider            Provider       `json:"provider"`
@@ -56,6 +113,11 @@ type CreatePaymentRequest struct {
 	Currency            Currency       `json:"currency"`
 	Description         string         `json:"description,omitempty"`
 	StatementDescriptor string         `json:"statement_descriptor,omitempty"`
+	PromotionCode       string         `json:"promotion_code,omitempty"`
+	TaxCategory         TaxCategory    `json:"tax_category,omitempty"`
+	TaxBehavior         TaxBehavior    `json:"tax_behavior,omitempty"`
+	BillingCountry      string         `json:"billing_country,omitempty"`
+	VATID               string         `json:"vat_id,omitempty"`
 	Metadata            map[string]any `json:"metadata,omitempty"`
 }
 
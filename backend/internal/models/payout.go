@@ -0,0 +1,121 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PayoutStatus represents the status of a payout to our bank account
+type PayoutStatus string
+
+const (
+	PayoutStatusPending   PayoutStatus = "pending"
+	PayoutStatusInTransit PayoutStatus = "in_transit"
+	PayoutStatusPaid      PayoutStatus = "paid"
+	PayoutStatusFailed    PayoutStatus = "failed"
+	PayoutStatusCanceled  PayoutStatus = "canceled"
+)
+
+// Payout is a transfer of funds from the provider balance to our bank account
+type Payout struct {
+	ID               uuid.UUID    `json:"id" db:"id"`
+	Provider         Provider     `json:"provider" db:"provider"`
+	ProviderPayoutID string       `json:"provider_payout_id" db:"provider_payout_id"`
+	Amount           int64        `json:"amount" db:"amount"`
+	Currency         Currency     `json:"currency" db:"currency"`
+	Status           PayoutStatus `json:"status" db:"status"`
+	ArrivalDate      time.Time    `json:"arrival_date" db:"arrival_date"`
+	Description      *string      `json:"description,omitempty" db:"description"`
+
+	// Error handling
+	FailureCode    *string `json:"failure_code,omitempty" db:"failure_code"`
+	FailureMessage *string `json:"failure_message,omitempty" db:"failure_message"`
+
+	// Reconciliation; set when every transaction is matched and they sum to Amount
+	ReconciledAt *time.Time `json:"reconciled_at,omitempty" db:"reconciled_at"`
+
+	// Timestamps
+	ProviderCreatedAt time.Time `json:"provider_created_at" db:"provider_created_at"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PayoutTransactionType is the kind of balance transaction paid out
+type PayoutTransactionType string
+
+const (
+	PayoutTransactionCharge PayoutTransactionType = "charge"
+	PayoutTransactionRefund PayoutTransactionType = "refund"
+)
+
+// PayoutTransaction is a balance transaction included in a payout, linked to
+// the payment or refund it settled when we have one
+type PayoutTransaction struct {
+	ID                   uuid.UUID             `json:"id" db:"id"`
+	PayoutID             uuid.UUID             `json:"payout_id" db:"payout_id"`
+	BalanceTransactionID string                `json:"balance_transaction_id" db:"balance_transaction_id"`
+	Type                 PayoutTransactionType `json:"type" db:"type"`
+	SourceID             *string               `json:"source_id,omitempty" db:"source_id"`
+	Amount               int64                 `json:"amount" db:"amount"`
+	Fee                  int64                 `json:"fee" db:"fee"`
+	Net                  int64                 `json:"net" db:"net"`
+	Currency             Currency              `json:"currency" db:"currency"`
+	Description          *string               `json:"description,omitempty" db:"description"`
+	PaymentID            *uuid.UUID            `json:"payment_id,omitempty" db:"payment_id"`
+	RefundID             *uuid.UUID            `json:"refund_id,omitempty" db:"refund_id"`
+	CreatedAt            time.Time             `json:"created_at" db:"created_at"`
+}
+
+// NetMoney returns the transaction's net amount in its currency
+func (t *PayoutTransaction) NetMoney() Money {
+	return NewMoney(t.Net, t.Currency)
+}
+
+// Matched reports whether a charge or refund is linked to our record of it.
+// Other transaction types have nothing to match.
+func (t *PayoutTransaction) Matched() bool {
+	switch t.Type {
+	case PayoutTransactionCharge:
+		return t.PaymentID != nil
+	case PayoutTransactionRefund:
+		return t.RefundID != nil
+	}
+	return true
+}
+
+// PayoutSummaryLine totals one kind of transaction in a payout
+type PayoutSummaryLine struct {
+	Count  int   `json:"count"`
+	Amount int64 `json:"amount"`
+	Fees   int64 `json:"fees"`
+	Net    int64 `json:"net"`
+}
+
+// PayoutBreakdown is a payout with the transactions that make it up
+type PayoutBreakdown struct {
+	Payout       Payout              `json:"payout"`
+	Charges      PayoutSummaryLine   `json:"charges"`
+	Refunds      PayoutSummaryLine   `json:"refunds"`
+	Other        PayoutSummaryLine   `json:"other"`
+	Net          int64               `json:"net"`
+	Difference   int64               `json:"difference"` // Payout amount less the transactions' net
+	Unmatched    int                 `json:"unmatched"`
+	Reconciled   bool                `json:"reconciled"`
+	Transactions []PayoutTransaction `json:"transactions"`
+}
+
+// PayoutListResponse represents a paginated list of payouts
+type PayoutListResponse struct {
+	Data   []Payout `json:"data"`
+	Total  int      `json:"total"`
+	Limit  int      `json:"limit"`
+	Offset int      `json:"offset"`
+}
+
+// PayoutImportResult summarizes a payout import
+type PayoutImportResult struct {
+	Imported   int `json:"imported"`
+	Reconciled int `json:"reconciled"`
+	Failed     int `json:"failed"`
+}
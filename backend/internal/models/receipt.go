@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Receipt is a rendered PDF receipt for a succeeded payment. Receipt numbers
+// are sequential and unique per tenant.
+type Receipt struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	Tenant        string     `json:"tenant" db:"tenant"`
+	Number        int64      `json:"number" db:"number"`
+	ReceiptNumber string     `json:"receipt_number" db:"receipt_number"`
+	PaymentID     uuid.UUID  `json:"payment_id" db:"payment_id"`
+	InvoiceID     *uuid.UUID `json:"invoice_id,omitempty" db:"invoice_id"`
+	CustomerID    uuid.UUID  `json:"customer_id" db:"customer_id"`
+
+	// Amount covered by the receipt
+	Amount   int64    `json:"amount" db:"amount"`
+	Currency Currency `json:"currency" db:"currency"`
+
+	// Rendered document, kept so re-downloads are identical
+	PDF []byte `json:"-" db:"pdf"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReconciliationObjectType is the kind of provider object being reconciled
+type ReconciliationObjectType string
+
+const (
+	ReconciliationObjectPayment      ReconciliationObjectType = "payment"
+	ReconciliationObjectSubscription ReconciliationObjectType = "subscription"
+	ReconciliationObjectRefund       ReconciliationObjectType = "refund"
+)
+
+// DiscrepancyKind describes how a local row differs from the provider
+type DiscrepancyKind string
+
+const (
+	DiscrepancyMissingLocal      DiscrepancyKind = "missing_local"
+	DiscrepancyStatusMismatch    DiscrepancyKind = "status_mismatch"
+	DiscrepancyAmountMismatch    DiscrepancyKind = "amount_mismatch"
+	DiscrepancyCurrencyMismatch  DiscrepancyKind = "currency_mismatch"
+	DiscrepancyPeriodMismatch    DiscrepancyKind = "period_mismatch"
+	DiscrepancyMissingSettlement DiscrepancyKind = "missing_settlement"
+)
+
+// DiscrepancyStatus tracks whether a discrepancy still needs attention
+type DiscrepancyStatus string
+
+const (
+	// Found and not yet fixed; needs a human
+	DiscrepancyStatusOpen DiscrepancyStatus = "open"
+	// Fixed automatically when it was found
+	DiscrepancyStatusRepaired DiscrepancyStatus = "repaired"
+	// No longer seen by a later run, or closed by an admin
+	DiscrepancyStatusResolved DiscrepancyStatus = "resolved"
+)
+
+// ReconciliationDiscrepancy is a difference between a local row and the
+// provider's object
+type ReconciliationDiscrepancy struct {
+	ID               uuid.UUID                `json:"id" db:"id"`
+	Provider         Provider                 `json:"provider" db:"provider"`
+	ObjectType       ReconciliationObjectType `json:"object_type" db:"object_type"`
+	ProviderObjectID string                   `json:"provider_object_id" db:"provider_object_id"`
+	LocalID          *uuid.UUID               `json:"local_id,omitempty" db:"local_id"`
+	Kind             DiscrepancyKind          `json:"kind" db:"kind"`
+	LocalValue       *string                  `json:"local_value,omitempty" db:"local_value"`
+	ProviderValue    *string                  `json:"provider_value,omitempty" db:"provider_value"`
+	Status           DiscrepancyStatus        `json:"status" db:"status"`
+
+	// Timestamps
+	FirstSeenAt time.Time  `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+}
+
+// DiscrepancyFilter narrows a discrepancy listing
+type DiscrepancyFilter struct {
+	Status     DiscrepancyStatus
+	ObjectType ReconciliationObjectType
+	Limit      int
+	Offset     int
+}
+
+// DiscrepancyListResponse represents a paginated list of discrepancies
+type DiscrepancyListResponse struct {
+	Data   []ReconciliationDiscrepancy `json:"data"`
+	Total  int                         `json:"total"`
+	Limit  int                         `json:"limit"`
+	Offset int                         `json:"offset"`
+}
+
+// ReconciliationRequest selects the creation window to reconcile
+type ReconciliationRequest struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// ReconciliationResult summarizes a reconciliation run
+type ReconciliationResult struct {
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	Checked       int       `json:"checked"`
+	Discrepancies int       `json:"discrepancies"`
+	Repaired      int       `json:"repaired"`
+	Failed        int       `json:"failed"`
+}
@@ -18,10 +18,30 @@ type Refund struct {
 	Currency        Currency     `json:"currency" db:"currency"`
 	Status          RefundStatus `json:"status" db:"status"`
 
+	// Where the money goes; customer balance refunds never reach the provider
+	Destination RefundDestination `json:"destination" db:"destination"`
+
+	// Settlement, from the provider's balance transaction. Amounts are in
+	// the settlement currency; set once the provider has settled.
+	ProviderFee          *int64     `json:"provider_fee,omitempty" db:"provider_fee"`
+	NetAmount            *int64     `json:"net_amount,omitempty" db:"net_amount"`
+	SettlementCurrency   *Currency  `json:"settlement_currency,omitempty" db:"settlement_currency"`
+	ExchangeRate         *float64   `json:"exchange_rate,omitempty" db:"exchange_rate"`
+	AvailableOn          *time.Time `json:"available_on,omitempty" db:"available_on"`
+	BalanceTransactionID *string    `json:"balance_transaction_id,omitempty" db:"balance_transaction_id"`
+
 	// Reason
 	Reason *string `json:"reason,omitempty" db:"reason"`
 	Notes  *string `json:"notes,omitempty" db:"notes"`
 
+	// Approval. Refunds matching an approval policy wait in pending_approval
+	// until someone other than the requester approves or rejects them.
+	RequestedBy    *uuid.UUID `json:"requested_by,omitempty" db:"requested_by"`
+	ApprovalReason *string    `json:"approval_reason,omitempty" db:"approval_reason"`
+	ReviewedBy     *uuid.UUID `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt     *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ReviewNote     *string    `json:"review_note,omitempty" db:"review_note"`
+
 	// Error handling
 	FailureCode    *string `json:"failure_code,omitempty" db:"failure_code"`
 	FailureMessage *string `json:"failure_message,omitempty" db:"failure_message"`
@@ -35,13 +55,32 @@ type Refund struct {
 	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
 }
 
+// Money returns the refund's amount in its currency
+func (r *Refund) Money() Money {
+	return NewMoney(r.Amount, r.Currency)
+}
+
+// RefundDestination is where a refund sends the money
+type RefundDestination string
+
+const (
+	RefundDestinationPaymentMethod   RefundDestination = "payment_method"
+	RefundDestinationCustomerBalance RefundDestination = "customer_balance"
+)
+
 // CreateRefundRequest represents a request to create a refund
 type CreateRefundRequest struct {
-	PaymentID uuid.UUID      `json:"payment_id"`
-	Amount    int64          `json:"amount"`
-	Reason    string         `json:"reason,omitempty"`
-	Notes     string         `json:"notes,omitempty"`
-	Metadata  map[string]any `json:"metadata,omitempty"`
+	PaymentID   uuid.UUID         `json:"payment_id"`
+	Amount      int64             `json:"amount"`
+	Destination RefundDestination `json:"destination,omitempty"`
+	Reason      string            `json:"reason,omitempty"`
+	Notes       string            `json:"notes,omitempty"`
+	Metadata    map[string]any    `json:"metadata,omitempty"`
+}
+
+// ReviewRefundRequest represents an approval or rejection of a refund
+type ReviewRefundRequest struct {
+	Note string `json:"note,omitempty"`
 }
 
 // RefundListResponse represents a list of refunds
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ProductMargin summarizes succeeded payments for one product. Gross, tax and
+// refunded amounts are in Currency; fees and net are in SettlementCurrency.
+type ProductMargin struct {
+	Product            string    `json:"product"`
+	Currency           Currency  `json:"currency"`
+	SettlementCurrency *Currency `json:"settlement_currency,omitempty"`
+	Payments           int       `json:"payments"`
+	Unsettled          int       `json:"unsettled"`
+	GrossAmount        int64     `json:"gross_amount"`
+	TaxAmount          int64     `json:"tax_amount"`
+	RefundedAmount     int64     `json:"refunded_amount"`
+	ProviderFees       int64     `json:"provider_fees"`
+	NetAmount          int64     `json:"net_amount"`
+
+	// Margin is gross less tax, refunds and fees. Only set when payments
+	// settled in the currency they were charged in.
+	Margin *int64 `json:"margin,omitempty"`
+}
+
+// ProductMarginReport is the margin report for a period
+type ProductMarginReport struct {
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Products []ProductMargin `json:"products"`
+}
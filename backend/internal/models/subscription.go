@@ -16,26 +16,69 @@ type Subscription struct {
 	ProviderSubscriptionID string             `json:"provider_subscription_id" db:"provider_subscription_id"`
 	Status                 SubscriptionStatus `json:"status" db:"status"`
 
-	// Billing
+	// Billing; Amount is the total of Items per period
 	Amount        int64    `json:"amount" db:"amount"`
 	Currency      Currency `json:"currency" db:"currency"`
 	Interval      string   `json:"interval" db:"interval"`
 	IntervalCount int      `json:"interval_count" db:"interval_count"`
 
+	// Priced items, e.g. seats and add-ons
+	Items []SubscriptionItem `json:"items,omitempty" db:"-"`
+
+	// Per-unit prices billed on reported usage
+	MeteredPrices []MeteredPrice `json:"metered_prices,omitempty" db:"-"`
+
+	// Changes scheduled for later phases, if any
+	Schedule *SubscriptionSchedule `json:"schedule,omitempty" db:"-"`
+
+	// Discount per period while it applies (Amount is the undiscounted price)
+	DiscountAmount  int64      `json:"discount_amount" db:"discount_amount"`
+	CouponID        *uuid.UUID `json:"coupon_id,omitempty" db:"coupon_id"`
+	PromotionCodeID *uuid.UUID `json:"promotion_code_id,omitempty" db:"promotion_code_id"`
+	DiscountEndsAt  *time.Time `json:"discount_ends_at,omitempty" db:"discount_ends_at"`
+
+	// Tax per period on the undiscounted price; invoices record what was charged
+	TaxAmount    int64         `json:"tax_amount" db:"tax_amount"`
+	TaxBreakdown *TaxBreakdown `json:"tax_breakdown,omitempty" db:"tax_breakdown"`
+
 	// Billing dates
 	CurrentPeriodStart time.Time  `json:"current_period_start" db:"current_period_start"`
 	CurrentPeriodEnd   time.Time  `json:"current_period_end" db:"current_period_end"`
 	TrialStart         *time.Time `json:"trial_start,omitempty" db:"trial_start"`
 	TrialEnd           *time.Time `json:"trial_end,omitempty" db:"trial_end"`
 
+	// What happens when the trial ends without a payment method
+	TrialEndBehavior *TrialEndBehavior `json:"trial_end_behavior,omitempty" db:"trial_end_behavior"`
+
 	// Cancellation
 	CancelAt           *time.Time `json:"cancel_at,omitempty" db:"cancel_at"`
 	CanceledAt         *time.Time `json:"canceled_at,omitempty" db:"canceled_at"`
 	CancelAtPeriodEnd  bool       `json:"cancel_at_period_end" db:"cancel_at_period_end"`
 
+	// Churn feedback given when canceling
+	CancellationReason   *CancellationReason `json:"cancellation_reason,omitempty" db:"cancellation_reason"`
+	CancellationFeedback *string             `json:"cancellation_feedback,omitempty" db:"cancellation_feedback"`
+
+	// Pause
+	PauseBehavior *PauseBehavior `json:"pause_behavior,omitempty" db:"pause_behavior"`
+	PausedAt      *time.Time     `json:"paused_at,omitempty" db:"paused_at"`
+	ResumesAt     *time.Time     `json:"resumes_at,omitempty" db:"resumes_at"`
+
+	// Dunning (retrying a failed renewal payment)
+	DunningStatus     *DunningStatus `json:"dunning_status,omitempty" db:"dunning_status"`
+	DunningInvoiceID  *string        `json:"dunning_invoice_id,omitempty" db:"dunning_invoice_id"`
+	DunningAttempts   int            `json:"dunning_attempts" db:"dunning_attempts"`
+	DunningStartedAt  *time.Time     `json:"dunning_started_at,omitempty" db:"dunning_started_at"`
+	NextRetryAt       *time.Time     `json:"next_retry_at,omitempty" db:"next_retry_at"`
+	GracePeriodEndsAt *time.Time     `json:"grace_period_ends_at,omitempty" db:"grace_period_ends_at"`
+
 	// Latest payment
 	LatestPaymentID *uuid.UUID `json:"latest_payment_id,omitempty" db:"latest_payment_id"`
 
+	// When the native billing engine next renews the subscription or checks
+	// on its payment; nil for subscriptions the provider bills
+	NextBillingAt *time.Time `json:"next_billing_at,omitempty" db:"next_billing_at"`
+
 	// Product info
 	ProductName        string  `json:"product_name" db:"product_name"`
 	ProductDescription *string `json:"product_description,omitempty" db:"product_description"`
@@ -50,15 +93,27 @@ type Subscription struct {
 
 // CreateSubscriptionRequest represents a request to create a subscription
 type CreateSubscriptionRequest struct {
-	Provider           Provider       `json:"provider"`
-	Amount             int64          `json:"amount"`
-	Currency           Currency       `json:"currency"`
-	Interval           string         `json:"interval"`
-	IntervalCount      int            `json:"interval_count"`
-	ProductName        string         `json:"product_name"`
-	ProductDescription string         `json:"product_description,omitempty"`
-	TrialPeriodDays    int            `json:"trial_period_days,omitempty"`
-	Metadata           map[string]any `json:"metadata,omitempty"`
+	Provider           Provider         `json:"provider"`
+	Amount             int64            `json:"amount"`
+	Currency           Currency         `json:"currency"`
+	Interval           string           `json:"interval"`
+	IntervalCount      int              `json:"interval_count"`
+	ProductName        string           `json:"product_name"`
+	ProductDescription string           `json:"product_description,omitempty"`
+	TrialPeriodDays    int              `json:"trial_period_days,omitempty"`
+	TrialEndBehavior   TrialEndBehavior `json:"trial_end_behavior,omitempty"`
+	PromotionCode      string           `json:"promotion_code,omitempty"`
+	TaxCategory        TaxCategory      `json:"tax_category,omitempty"`
+	TaxBehavior        TaxBehavior      `json:"tax_behavior,omitempty"`
+	BillingCountry     string           `json:"billing_country,omitempty"`
+	VATID              string           `json:"vat_id,omitempty"`
+	Metadata           map[string]any   `json:"metadata,omitempty"`
+
+	// Items to bill instead of a single Amount, e.g. seats plus add-ons
+	Items []SubscriptionItemRequest `json:"items,omitempty"`
+
+	// Optional usage-based prices billed on top of Amount
+	MeteredPrices []MeteredPriceRequest `json:"metered_prices,omitempty"`
 }
 
 // UpdateSubscriptionRequest represents a request to update a subscription
@@ -67,6 +122,23 @@ type UpdateSubscriptionRequest struct {
 	Metadata          map[string]any `json:"metadata,omitempty"`
 }
 
+// CancelSubscriptionRequest represents optional churn feedback given when canceling
+type CancelSubscriptionRequest struct {
+	Reason   CancellationReason `json:"reason,omitempty"`
+	Feedback string             `json:"feedback,omitempty"`
+}
+
+// PauseSubscriptionRequest represents a request to pause collection on a subscription
+type PauseSubscriptionRequest struct {
+	Behavior  PauseBehavior `json:"behavior"`
+	ResumesAt *time.Time    `json:"resumes_at,omitempty"`
+}
+
+// ExtendTrialRequest represents a request to move the end of a trial
+type ExtendTrialRequest struct {
+	TrialEnd time.Time `json:"trial_end"`
+}
+
 // SubscriptionListResponse represents a list of subscriptions
 type SubscriptionListResponse struct {
 	Data   []Subscription `json:"data"`
@@ -74,3 +146,39 @@ type SubscriptionListResponse struct {
 	Limit  int            `json:"limit"`
 	Offset int            `json:"offset"`
 }
+
+// DunningRunResult summarizes one pass over subscriptions due for a payment retry
+type DunningRunResult struct {
+	Retried   int `json:"retried"`
+	Recovered int `json:"recovered"`
+	Exhausted int `json:"exhausted"`
+	Failed    int `json:"failed"`
+}
+
+// BillingRunResult summarizes one pass over natively billed subscriptions
+type BillingRunResult struct {
+	Renewed  int `json:"renewed"`
+	Paid     int `json:"paid"`
+	Pending  int `json:"pending"`
+	Declined int `json:"declined"`
+	Canceled int `json:"canceled"`
+	Failed   int `json:"failed"`
+}
+
+// AddInterval advances t by count billing intervals
+func AddInterval(t time.Time, interval string, count int) time.Time {
+	if count <= 0 {
+		count = 1
+	}
+
+	switch interval {
+	case "day":
+		return t.AddDate(0, 0, count)
+	case "week":
+		return t.AddDate(0, 0, 7*count)
+	case "year":
+		return t.AddDate(count, 0, 0)
+	default:
+		return t.AddDate(0, count, 0)
+	}
+}
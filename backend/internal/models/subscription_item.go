@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubscriptionItem is a price billed every period of a subscription, once per
+// unit of Quantity (e.g. per seat)
+type SubscriptionItem struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	SubscriptionID uuid.UUID `json:"subscription_id" db:"subscription_id"`
+	ProductName    string    `json:"product_name" db:"product_name"`
+	UnitAmount     int64     `json:"unit_amount" db:"unit_amount"`
+	Quantity       int64     `json:"quantity" db:"quantity"`
+
+	// Provider subscription item; nil for items created before items were
+	// tracked until they are synced from the provider
+	ProviderItemID *string `json:"provider_item_id,omitempty" db:"provider_item_id"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SubscriptionItemRequest represents an item on a new subscription. Quantity
+// defaults to 1 and ProductName to the subscription's.
+type SubscriptionItemRequest struct {
+	ProductName string `json:"product_name,omitempty"`
+	UnitAmount  int64  `json:"unit_amount"`
+	Quantity    int64  `json:"quantity,omitempty"`
+}
+
+// AddSubscriptionItemRequest represents a request to add an item to a
+// subscription. ProrationBehavior defaults to create_prorations.
+type AddSubscriptionItemRequest struct {
+	ProductName       string            `json:"product_name"`
+	UnitAmount        int64             `json:"unit_amount"`
+	Quantity          int64             `json:"quantity,omitempty"`
+	ProrationBehavior ProrationBehavior `json:"proration_behavior,omitempty"`
+}
+
+// UpdateSubscriptionItemRequest represents a request to change the quantity
+// of a subscription item. ProrationBehavior defaults to create_prorations.
+type UpdateSubscriptionItemRequest struct {
+	Quantity          int64             `json:"quantity"`
+	ProrationBehavior ProrationBehavior `json:"proration_behavior,omitempty"`
+}
+
+// ItemsAmount returns the total of items per period
+func ItemsAmount(items []SubscriptionItem) int64 {
+	var total int64
+	for _, item := range items {
+		total += item.UnitAmount * item.Quantity
+	}
+	return total
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubscriptionSchedule changes a subscription in phases, e.g. starting it on a
+// future date, switching its plan at the next renewal or ending it after a
+// number of billing cycles
+type SubscriptionSchedule struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CustomerID uuid.UUID `json:"customer_id" db:"customer_id"`
+
+	// Subscription the schedule changes; nil until a new subscription starts
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty" db:"subscription_id"`
+
+	// Set when the provider runs the schedule; otherwise the scheduler job
+	// applies each phase
+	Provider           Provider `json:"provider" db:"provider"`
+	ProviderScheduleID *string  `json:"provider_schedule_id,omitempty" db:"provider_schedule_id"`
+
+	Status      SubscriptionScheduleStatus `json:"status" db:"status"`
+	EndBehavior ScheduleEndBehavior        `json:"end_behavior" db:"end_behavior"`
+
+	// Phases in order; CurrentPhase is nil until the first one starts
+	Phases       SchedulePhases `json:"phases" db:"phases"`
+	CurrentPhase *int           `json:"current_phase,omitempty" db:"current_phase"`
+
+	// When the next phase starts or the last one ends; nil once the schedule has ended
+	NextTransitionAt *time.Time `json:"next_transition_at,omitempty" db:"next_transition_at"`
+
+	// Settings of the subscription the schedule starts
+	Currency       Currency    `json:"currency" db:"currency"`
+	ProductName    string      `json:"product_name" db:"product_name"`
+	TaxCategory    TaxCategory `json:"tax_category,omitempty" db:"tax_category"`
+	TaxBehavior    TaxBehavior `json:"tax_behavior,omitempty" db:"tax_behavior"`
+	BillingCountry string      `json:"billing_country,omitempty" db:"billing_country"`
+	VATID          string      `json:"vat_id,omitempty" db:"vat_id"`
+	Metadata       JSONBMap    `json:"metadata,omitempty" db:"metadata"`
+
+	// Timestamps; EndedAt is set once completed, released or canceled
+	EndedAt   *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SchedulePhase bills Items every interval for Iterations billing cycles
+type SchedulePhase struct {
+	Items         []SubscriptionItemRequest `json:"items"`
+	Interval      string                    `json:"interval"`
+	IntervalCount int                       `json:"interval_count"`
+	Iterations    int                       `json:"iterations"`
+	StartDate     time.Time                 `json:"start_date"`
+	EndDate       time.Time                 `json:"end_date"`
+}
+
+// SchedulePhases is stored as a JSONB array
+type SchedulePhases []SchedulePhase
+
+// Scan implements sql.Scanner for reading JSONB from PostgreSQL.
+func (p *SchedulePhases) Scan(value any) error {
+	if value == nil {
+		*p = SchedulePhases{}
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("SchedulePhases.Scan: expected []byte, got %T", value)
+	}
+	return json.Unmarshal(b, p)
+}
+
+// Value implements driver.Valuer for writing JSONB to PostgreSQL.
+func (p SchedulePhases) Value() (driver.Value, error) {
+	if p == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(p)
+}
+
+// CreateSubscriptionScheduleRequest represents a request to schedule changes
+// to a subscription. With a SubscriptionID the first phase starts when the
+// subscription's current period ends; otherwise a new subscription with the
+// settings given here starts on StartDate.
+type CreateSubscriptionScheduleRequest struct {
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty"`
+	StartDate      *time.Time `json:"start_date,omitempty"`
+
+	// Settings of a new subscription
+	Provider       Provider       `json:"provider,omitempty"`
+	Currency       Currency       `json:"currency,omitempty"`
+	ProductName    string         `json:"product_name,omitempty"`
+	TaxCategory    TaxCategory    `json:"tax_category,omitempty"`
+	TaxBehavior    TaxBehavior    `json:"tax_behavior,omitempty"`
+	BillingCountry string         `json:"billing_country,omitempty"`
+	VATID          string         `json:"vat_id,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+
+	EndBehavior ScheduleEndBehavior    `json:"end_behavior,omitempty"`
+	Phases      []SchedulePhaseRequest `json:"phases"`
+}
+
+// SchedulePhaseRequest represents a phase of a new schedule. Items and the
+// interval default to the previous phase's, or to the subscription's for the
+// first phase of a schedule for an existing subscription. Iterations defaults
+// to 1.
+type SchedulePhaseRequest struct {
+	Items         []SubscriptionItemRequest `json:"items,omitempty"`
+	Interval      string                    `json:"interval,omitempty"`
+	IntervalCount int                       `json:"interval_count,omitempty"`
+	Iterations    int                       `json:"iterations,omitempty"`
+}
+
+// SubscriptionScheduleListResponse represents a list of subscription schedules
+type SubscriptionScheduleListResponse struct {
+	Data   []SubscriptionSchedule `json:"data"`
+	Total  int                    `json:"total"`
+	Limit  int                    `json:"limit"`
+	Offset int                    `json:"offset"`
+}
+
+// ScheduleRunResult summarizes one pass over subscription schedules due for a transition
+type ScheduleRunResult struct {
+	Started int `json:"started"`
+	Phased  int `json:"phased"`
+	Ended   int `json:"ended"`
+	Failed  int `json:"failed"`
+}
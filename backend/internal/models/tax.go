@@ -0,0 +1,58 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// TaxBreakdown records how tax was calculated on a payment, subscription or
+// invoice
+type TaxBreakdown struct {
+	// Country the tax was charged for (ISO 3166-1 alpha-2)
+	Country  string      `json:"country,omitempty"`
+	Behavior TaxBehavior `json:"behavior"`
+
+	// Reverse charge: the buyer accounts for VAT, none is charged
+	ReverseCharge bool   `json:"reverse_charge"`
+	CustomerVATID string `json:"customer_vat_id,omitempty"`
+
+	// One line per rate applied
+	Lines []TaxLine `json:"lines"`
+}
+
+// TaxLine is the tax charged at a single rate
+type TaxLine struct {
+	Category TaxCategory `json:"category,omitempty"`
+	Rate     float64     `json:"rate"` // Percent, e.g. 25
+	Net      int64       `json:"net"`
+	Tax      int64       `json:"tax"`
+}
+
+// TotalTax returns the sum of tax over all lines. Lines are in the currency
+// of the payment, subscription or invoice the breakdown belongs to.
+func (b *TaxBreakdown) TotalTax(currency Currency) Money {
+	total := NewMoney(0, currency)
+	for _, line := range b.Lines {
+		total.Amount += line.Tax
+	}
+	return total
+}
+
+// Scan implements sql.Scanner for reading JSONB from PostgreSQL.
+func (b *TaxBreakdown) Scan(value any) error {
+	if value == nil {
+		*b = TaxBreakdown{}
+		return nil
+	}
+	data, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("TaxBreakdown.Scan: expected []byte, got %T", value)
+	}
+	return json.Unmarshal(data, b)
+}
+
+// Value implements driver.Valuer for writing JSONB to PostgreSQL.
+func (b TaxBreakdown) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
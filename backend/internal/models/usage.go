@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageAggregation decides how the usage reported in a billing period becomes
+// the quantity billed for it
+type UsageAggregation string
+
+const (
+	UsageAggregationSum  UsageAggregation = "sum"  // Total of all records, e.g. API calls
+	UsageAggregationMax  UsageAggregation = "max"  // Highest record, e.g. peak storage
+	UsageAggregationLast UsageAggregation = "last" // Latest record, e.g. storage at period end
+)
+
+// MeteredPrice is a per-unit price on a subscription billed on reported usage
+// at the end of each period, on top of the fixed Amount
+type MeteredPrice struct {
+	ID             uuid.UUID        `json:"id" db:"id"`
+	SubscriptionID uuid.UUID        `json:"subscription_id" db:"subscription_id"`
+	Meter          string           `json:"meter" db:"meter"`
+	UnitAmount     int64            `json:"unit_amount" db:"unit_amount"`
+	Aggregation    UsageAggregation `json:"aggregation" db:"aggregation"`
+
+	// Provider subscription item the usage is reported to; nil when usage is
+	// invoiced locally instead
+	ProviderItemID *string `json:"provider_item_id,omitempty" db:"provider_item_id"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// UsageRecord is a quantity reported for a metered price. RecordID is chosen
+// by the reporter so that retries are not counted twice.
+type UsageRecord struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	SubscriptionID uuid.UUID `json:"subscription_id" db:"subscription_id"`
+	MeteredPriceID uuid.UUID `json:"metered_price_id" db:"metered_price_id"`
+	RecordID       string    `json:"record_id" db:"record_id"`
+	Quantity       int64     `json:"quantity" db:"quantity"`
+	Timestamp      time.Time `json:"timestamp" db:"timestamp"`
+
+	// Billing period the usage belongs to
+	PeriodStart time.Time `json:"period_start" db:"period_start"`
+	PeriodEnd   time.Time `json:"period_end" db:"period_end"`
+
+	// Set once the usage has been sent to the provider or invoiced locally
+	ReportedAt *time.Time `json:"reported_at,omitempty" db:"reported_at"`
+	InvoiceID  *uuid.UUID `json:"invoice_id,omitempty" db:"invoice_id"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// UsageSummary is the usage of one metered price in a billing period
+type UsageSummary struct {
+	MeteredPriceID uuid.UUID        `json:"metered_price_id"`
+	Meter          string           `json:"meter"`
+	Aggregation    UsageAggregation `json:"aggregation"`
+	Quantity       int64            `json:"quantity"`
+	UnitAmount     int64            `json:"unit_amount"`
+	Amount         int64            `json:"amount"`
+}
+
+// UsagePeriod is a billing period of a subscription with usage to invoice
+type UsagePeriod struct {
+	SubscriptionID uuid.UUID
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+}
+
+// MeteredPriceRequest represents a metered price on a new subscription
+type MeteredPriceRequest struct {
+	Meter       string           `json:"meter"`
+	UnitAmount  int64            `json:"unit_amount"`
+	Aggregation UsageAggregation `json:"aggregation,omitempty"`
+}
+
+// ReportUsageRequest represents usage reported for a subscription. Timestamp
+// defaults to now and must fall within the current billing period.
+type ReportUsageRequest struct {
+	Meter     string     `json:"meter"`
+	Quantity  int64      `json:"quantity"`
+	RecordID  string     `json:"record_id"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// SubscriptionUsageResponse represents the usage of a subscription's current
+// billing period so far
+type SubscriptionUsageResponse struct {
+	SubscriptionID uuid.UUID      `json:"subscription_id"`
+	PeriodStart    time.Time      `json:"period_start"`
+	PeriodEnd      time.Time      `json:"period_end"`
+	Data           []UsageSummary `json:"data"`
+}
+
+// UsageRunResult summarizes one pass of the usage job
+type UsageRunResult struct {
+	Reported int `json:"reported"`
+	Invoiced int `json:"invoiced"`
+	Failed   int `json:"failed"`
+}
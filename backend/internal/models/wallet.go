@@ -0,0 +1,166 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WalletTransactionType identifies what changed a wallet balance
+type WalletTransactionType string
+
+const (
+	// Credits
+	WalletTransactionTopUp WalletTransactionType = "top_up"
+	WalletTransactionGrant WalletTransactionType = "grant"
+
+	// Debits
+	WalletTransactionDebit   WalletTransactionType = "debit"
+	WalletTransactionCapture WalletTransactionType = "capture"
+	WalletTransactionExpiry  WalletTransactionType = "expiry"
+)
+
+// WalletHoldStatus represents the status of a hold on wallet funds
+type WalletHoldStatus string
+
+const (
+	WalletHoldStatusActive   WalletHoldStatus = "active"
+	WalletHoldStatusCaptured WalletHoldStatus = "captured"
+	WalletHoldStatusReleased WalletHoldStatus = "released"
+)
+
+// Wallet is a customer's prepaid balance in one currency. Held funds are part
+// of Balance but cannot be spent until their hold is captured or released.
+type Wallet struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CustomerID uuid.UUID `json:"customer_id" db:"customer_id"`
+	Currency   Currency  `json:"currency" db:"currency"`
+	Balance    int64     `json:"balance" db:"balance"`
+	Held       int64     `json:"held" db:"held"`
+
+	// Promotional credit not spent or expired yet, included in Balance
+	Promotional int64 `json:"promotional" db:"promotional"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Available returns the funds that can be debited or held
+func (w *Wallet) Available() int64 {
+	return w.Balance - w.Held
+}
+
+// WalletTransaction is one change to a wallet balance. Positive amounts add
+// funds and negative amounts spend them.
+type WalletTransaction struct {
+	ID             uuid.UUID             `json:"id" db:"id"`
+	WalletID       uuid.UUID             `json:"wallet_id" db:"wallet_id"`
+	Type           WalletTransactionType `json:"type" db:"type"`
+	Amount         int64                 `json:"amount" db:"amount"`
+	BalanceAfter   int64                 `json:"balance_after" db:"balance_after"`
+	IdempotencyKey string                `json:"idempotency_key" db:"idempotency_key"`
+	Description    *string               `json:"description,omitempty" db:"description"`
+
+	// What caused the change, when it came from a payment, hold or grant
+	PaymentID *uuid.UUID `json:"payment_id,omitempty" db:"payment_id"`
+	HoldID    *uuid.UUID `json:"hold_id,omitempty" db:"hold_id"`
+	GrantID   *uuid.UUID `json:"grant_id,omitempty" db:"grant_id"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// WalletHold reserves wallet funds for a charge whose final amount is not
+// known yet, e.g. a metered session
+type WalletHold struct {
+	ID             uuid.UUID        `json:"id" db:"id"`
+	WalletID       uuid.UUID        `json:"wallet_id" db:"wallet_id"`
+	Amount         int64            `json:"amount" db:"amount"`
+	CapturedAmount int64            `json:"captured_amount" db:"captured_amount"`
+	Status         WalletHoldStatus `json:"status" db:"status"`
+	IdempotencyKey string           `json:"idempotency_key" db:"idempotency_key"`
+	Description    *string          `json:"description,omitempty" db:"description"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WalletGrant is promotional credit added to a wallet. Debits spend the grant
+// expiring first before paid funds, and whatever is left expires at ExpiresAt.
+type WalletGrant struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	WalletID  uuid.UUID `json:"wallet_id" db:"wallet_id"`
+	Amount    int64     `json:"amount" db:"amount"`
+	Remaining int64     `json:"remaining" db:"remaining"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// WalletTopUpRequest represents a request to add funds to a wallet through a
+// payment
+type WalletTopUpRequest struct {
+	Provider       Provider       `json:"provider"`
+	Amount         int64          `json:"amount"`
+	Currency       Currency       `json:"currency"`
+	BillingCountry string         `json:"billing_country,omitempty"`
+	VATID          string         `json:"vat_id,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+}
+
+// WalletTopUpResponse is the payment behind a top-up and the wallet after it.
+// The wallet is only credited once the payment succeeds.
+type WalletTopUpResponse struct {
+	Payment *Payment `json:"payment"`
+	Wallet  *Wallet  `json:"wallet"`
+}
+
+// WalletDebitRequest represents a request to spend wallet funds. Repeating a
+// request with the same idempotency key returns the original transaction.
+type WalletDebitRequest struct {
+	Amount         int64    `json:"amount"`
+	Currency       Currency `json:"currency"`
+	IdempotencyKey string   `json:"idempotency_key"`
+	Description    string   `json:"description,omitempty"`
+}
+
+// WalletHoldRequest represents a request to hold wallet funds. Repeating a
+// request with the same idempotency key returns the original hold.
+type WalletHoldRequest struct {
+	Amount         int64    `json:"amount"`
+	Currency       Currency `json:"currency"`
+	IdempotencyKey string   `json:"idempotency_key"`
+	Description    string   `json:"description,omitempty"`
+}
+
+// CaptureWalletHoldRequest represents a request to spend held funds. Amount
+// defaults to the full hold; the rest of the hold is released.
+type CaptureWalletHoldRequest struct {
+	Amount *int64 `json:"amount,omitempty"`
+}
+
+// WalletGrantRequest represents promotional credit given to a customer
+type WalletGrantRequest struct {
+	CustomerID     uuid.UUID `json:"customer_id"`
+	Amount         int64     `json:"amount"`
+	Currency       Currency  `json:"currency"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	Description    string    `json:"description,omitempty"`
+}
+
+// WalletListResponse represents a customer's wallets, one per currency
+type WalletListResponse struct {
+	Data []Wallet `json:"data"`
+}
+
+// WalletTransactionListResponse represents a list of wallet transactions
+type WalletTransactionListResponse struct {
+	Data   []WalletTransaction `json:"data"`
+	Total  int                 `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
@@ -17,6 +17,14 @@ func NewFactory(stripeAPIKey, stripeWebhookSecret string) *Factory {
 	}
 }
 
+// NewFakeFactory creates a factory that serves the in-memory fake provider in
+// place of Stripe, for running locally without Stripe credentials
+func NewFakeFactory(fakeProvider *FakeProvider) *Factory {
+	return &Factory{
+		stripeProvider: fakeProvider,
+	}
+}
+
 // GetProvider returns a provider by name
 func (f *Factory) GetProvider(provider models.Provider) (PaymentProvider, error) {
 	switch provider {
@@ -0,0 +1,619 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"payment-service/internal/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FakeProvider is an in-memory stand-in for Stripe used to run the service
+// locally without Stripe credentials. Each invoice is declined for its first
+// declineAttempts payment attempts so dunning can be exercised end to end.
+type FakeProvider struct {
+	mu              sync.Mutex
+	declineAttempts int
+	payments        map[string]*models.Payment
+	subscriptions   map[string]*models.Subscription
+	refunds         map[string]*models.Refund
+	invoiceAttempts map[string]int
+}
+
+// NewFakeProvider creates a new fake provider
+func NewFakeProvider(declineAttempts int) *FakeProvider {
+	return &FakeProvider{
+		declineAttempts: declineAttempts,
+		payments:        make(map[string]*models.Payment),
+		subscriptions:   make(map[string]*models.Subscription),
+		refunds:         make(map[string]*models.Refund),
+		invoiceAttempts: make(map[string]int),
+	}
+}
+
+// Name returns the provider name
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// CreateCustomer creates a fake customer
+func (p *FakeProvider) CreateCustomer(ctx context.Context, req *CreateCustomerRequest) (*models.Customer, error) {
+	customerID := fakeID("cus")
+	return &models.Customer{
+		UserID:           req.UserID,
+		Email:            req.Email,
+		Name:             req.Name,
+		StripeCustomerID: &customerID,
+	}, nil
+}
+
+// GetCustomer returns a fake customer
+func (p *FakeProvider) GetCustomer(ctx context.Context, providerCustomerID string) (*models.Customer, error) {
+	return &models.Customer{
+		StripeCustomerID: &providerCustomerID,
+	}, nil
+}
+
+// UpdateCustomer accepts any billing details
+func (p *FakeProvider) UpdateCustomer(ctx context.Context, providerCustomerID string, req *UpdateCustomerRequest) error {
+	return nil
+}
+
+// DeleteCustomer accepts any customer
+func (p *FakeProvider) DeleteCustomer(ctx context.Context, providerCustomerID string) error {
+	return nil
+}
+
+// CreatePayment creates a payment that succeeds immediately
+func (p *FakeProvider) CreatePayment(ctx context.Context, req *CreatePaymentRequest) (*models.Payment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	clientSecret := fakeID("pi_secret")
+	payment := &models.Payment{
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: fakeID("pi"),
+		Amount:            req.Amount,
+		Currency:          models.Currency(strings.ToUpper(req.Currency)),
+		Status:            models.PaymentStatusSucceeded,
+		ClientSecret:      &clientSecret,
+		CreatedAt:         now,
+		CompletedAt:       &now,
+	}
+	if req.Description != "" {
+		payment.Description = &req.Description
+	}
+
+	p.payments[payment.ProviderPaymentID] = payment
+	copied := *payment
+	return &copied, nil
+}
+
+// GetPayment retrieves a fake payment
+func (p *FakeProvider) GetPayment(ctx context.Context, providerPaymentID string) (*models.Payment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[providerPaymentID]
+	if !ok {
+		return nil, fmt.Errorf("fake: payment %s not found", providerPaymentID)
+	}
+
+	copied := *payment
+	return &copied, nil
+}
+
+// CancelPayment cancels a fake payment
+func (p *FakeProvider) CancelPayment(ctx context.Context, providerPaymentID string) (*models.Payment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[providerPaymentID]
+	if !ok {
+		return nil, fmt.Errorf("fake: payment %s not found", providerPaymentID)
+	}
+	payment.Status = models.PaymentStatusCanceled
+
+	copied := *payment
+	return &copied, nil
+}
+
+// CreateSubscription creates a fake subscription, starting its trial if requested
+func (p *FakeProvider) CreateSubscription(ctx context.Context, req *CreateSubscriptionRequest) (*models.Subscription, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	subscription := &models.Subscription{
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: fakeID("sub"),
+		Currency:               models.Currency(strings.ToUpper(req.Currency)),
+		Interval:               req.Interval,
+		IntervalCount:          req.IntervalCount,
+		Status:                 models.SubscriptionStatusActive,
+		CurrentPeriodStart:     now,
+		CurrentPeriodEnd:       models.AddInterval(now, req.Interval, req.IntervalCount),
+		CreatedAt:              now,
+	}
+
+	for _, item := range req.Items {
+		itemID := fakeID("si")
+		subscription.Items = append(subscription.Items, models.SubscriptionItem{
+			ProductName:    item.ProductName,
+			UnitAmount:     item.UnitAmount,
+			Quantity:       item.Quantity,
+			ProviderItemID: &itemID,
+		})
+	}
+	subscription.Amount = models.ItemsAmount(subscription.Items)
+
+	if req.TrialPeriodDays > 0 {
+		trialEnd := now.AddDate(0, 0, req.TrialPeriodDays)
+		subscription.Status = models.SubscriptionStatusTrialing
+		subscription.TrialStart = &now
+		subscription.TrialEnd = &trialEnd
+		subscription.CurrentPeriodEnd = trialEnd
+	}
+
+	if req.TrialEndBehavior != "" {
+		behavior := models.TrialEndBehavior(req.TrialEndBehavior)
+		subscription.TrialEndBehavior = &behavior
+	}
+
+	p.subscriptions[subscription.ProviderSubscriptionID] = subscription
+	return cloneFakeSubscription(subscription), nil
+}
+
+// GetSubscription retrieves a fake subscription
+func (p *FakeProvider) GetSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	subscription, ok := p.subscriptions[providerSubscriptionID]
+	if !ok {
+		return nil, fmt.Errorf("fake: subscription %s not found", providerSubscriptionID)
+	}
+
+	return cloneFakeSubscription(subscription), nil
+}
+
+// UpdateSubscription updates a fake subscription
+func (p *FakeProvider) UpdateSubscription(ctx context.Context, providerSubscriptionID string, req *UpdateSubscriptionRequest) (*models.Subscription, error) {
+	return p.mutateSubscription(providerSubscriptionID, func(subscription *models.Subscription) {
+		if req.CancelAtPeriodEnd != nil {
+			subscription.CancelAtPeriodEnd = *req.CancelAtPeriodEnd
+			subscription.CancelAt = nil
+			if *req.CancelAtPeriodEnd {
+				cancelAt := subscription.CurrentPeriodEnd
+				subscription.CancelAt = &cancelAt
+			}
+		}
+
+		if req.TrialEnd != nil {
+			trialEnd := *req.TrialEnd
+			subscription.TrialEnd = &trialEnd
+			subscription.CurrentPeriodEnd = trialEnd
+		}
+	})
+}
+
+// CancelSubscription cancels a fake subscription
+func (p *FakeProvider) CancelSubscription(ctx context.Context, providerSubscriptionID string, immediate bool) (*models.Subscription, error) {
+	return p.mutateSubscription(providerSubscriptionID, func(subscription *models.Subscription) {
+		if immediate {
+			now := time.Now()
+			subscription.Status = models.SubscriptionStatusCanceled
+			subscription.CanceledAt = &now
+			return
+		}
+
+		cancelAt := subscription.CurrentPeriodEnd
+		subscription.CancelAtPeriodEnd = true
+		subscription.CancelAt = &cancelAt
+	})
+}
+
+// ReactivateSubscription clears a scheduled cancellation on a fake subscription
+func (p *FakeProvider) ReactivateSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	return p.mutateSubscription(providerSubscriptionID, func(subscription *models.Subscription) {
+		subscription.CancelAtPeriodEnd = false
+		subscription.CancelAt = nil
+	})
+}
+
+// PauseSubscription pauses a fake subscription
+func (p *FakeProvider) PauseSubscription(ctx context.Context, providerSubscriptionID string, req *PauseSubscriptionRequest) (*models.Subscription, error) {
+	return p.mutateSubscription(providerSubscriptionID, func(subscription *models.Subscription) {
+		behavior := models.PauseBehavior(req.Behavior)
+		subscription.Status = models.SubscriptionStatusPaused
+		subscription.PauseBehavior = &behavior
+		subscription.ResumesAt = req.ResumesAt
+	})
+}
+
+// ResumeSubscription resumes a fake subscription
+func (p *FakeProvider) ResumeSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	return p.mutateSubscription(providerSubscriptionID, func(subscription *models.Subscription) {
+		subscription.Status = models.SubscriptionStatusActive
+		subscription.PauseBehavior = nil
+		subscription.ResumesAt = nil
+	})
+}
+
+// AddSubscriptionItem adds an item to a fake subscription; nothing is prorated
+func (p *FakeProvider) AddSubscriptionItem(ctx context.Context, providerSubscriptionID string, req *AddSubscriptionItemRequest) (*models.SubscriptionItem, error) {
+	itemID := fakeID("si")
+	item := models.SubscriptionItem{
+		ProductName:    req.ProductName,
+		UnitAmount:     req.UnitAmount,
+		Quantity:       req.Quantity,
+		ProviderItemID: &itemID,
+	}
+
+	_, err := p.mutateSubscription(providerSubscriptionID, func(subscription *models.Subscription) {
+		subscription.Items = append(subscription.Items, item)
+		subscription.Amount = models.ItemsAmount(subscription.Items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// UpdateSubscriptionItem changes the quantity of a fake subscription item
+func (p *FakeProvider) UpdateSubscriptionItem(ctx context.Context, providerSubscriptionID, providerItemID string, req *UpdateSubscriptionItemRequest) (*models.SubscriptionItem, error) {
+	var updated *models.SubscriptionItem
+	_, err := p.mutateSubscription(providerSubscriptionID, func(subscription *models.Subscription) {
+		for i := range subscription.Items {
+			if item := &subscription.Items[i]; item.ProviderItemID != nil && *item.ProviderItemID == providerItemID {
+				item.Quantity = req.Quantity
+				copied := *item
+				updated = &copied
+			}
+		}
+		subscription.Amount = models.ItemsAmount(subscription.Items)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, fmt.Errorf("fake: subscription item %s not found", providerItemID)
+	}
+
+	return updated, nil
+}
+
+// DeleteSubscriptionItem removes an item from a fake subscription
+func (p *FakeProvider) DeleteSubscriptionItem(ctx context.Context, providerSubscriptionID, providerItemID string, prorationBehavior string) error {
+	_, err := p.mutateSubscription(providerSubscriptionID, func(subscription *models.Subscription) {
+		items := subscription.Items[:0]
+		for _, item := range subscription.Items {
+			if item.ProviderItemID == nil || *item.ProviderItemID != providerItemID {
+				items = append(items, item)
+			}
+		}
+		subscription.Items = items
+		subscription.Amount = models.ItemsAmount(subscription.Items)
+	})
+	return err
+}
+
+// ReportUsage is not supported; the fake creates no metered subscription
+// items, so usage is invoiced locally
+func (p *FakeProvider) ReportUsage(ctx context.Context, req *ReportUsageRequest) error {
+	return fmt.Errorf("fake: metered subscription items are not supported")
+}
+
+// CreateSubscriptionSchedule is not supported; schedules are run by the
+// scheduler job, which changes the plan when each phase starts
+func (p *FakeProvider) CreateSubscriptionSchedule(ctx context.Context, req *CreateSubscriptionScheduleRequest) (string, error) {
+	return "", ErrNotSupported
+}
+
+// GetSubscriptionSchedule is not supported
+func (p *FakeProvider) GetSubscriptionSchedule(ctx context.Context, providerScheduleID string) (*SubscriptionSchedule, error) {
+	return nil, ErrNotSupported
+}
+
+// CancelSubscriptionSchedule is not supported
+func (p *FakeProvider) CancelSubscriptionSchedule(ctx context.Context, providerScheduleID string) error {
+	return ErrNotSupported
+}
+
+// ReleaseSubscriptionSchedule is not supported
+func (p *FakeProvider) ReleaseSubscriptionSchedule(ctx context.Context, providerScheduleID string) error {
+	return ErrNotSupported
+}
+
+// ChangeSubscriptionPlan replaces the items of a fake subscription, starting a
+// new period if the interval changes
+func (p *FakeProvider) ChangeSubscriptionPlan(ctx context.Context, providerSubscriptionID string, req *ChangeSubscriptionPlanRequest) (*models.Subscription, error) {
+	return p.mutateSubscription(providerSubscriptionID, func(subscription *models.Subscription) {
+		subscription.Items = nil
+		for _, item := range req.Items {
+			itemID := fakeID("si")
+			subscription.Items = append(subscription.Items, models.SubscriptionItem{
+				ProductName:    item.ProductName,
+				UnitAmount:     item.UnitAmount,
+				Quantity:       item.Quantity,
+				ProviderItemID: &itemID,
+			})
+		}
+		subscription.Amount = models.ItemsAmount(subscription.Items)
+
+		if subscription.Interval != req.Interval || subscription.IntervalCount != req.IntervalCount {
+			now := time.Now()
+			subscription.Interval = req.Interval
+			subscription.IntervalCount = req.IntervalCount
+			subscription.CurrentPeriodStart = now
+			subscription.CurrentPeriodEnd = models.AddInterval(now, req.Interval, req.IntervalCount)
+		}
+	})
+}
+
+// cloneFakeSubscription copies a stored subscription so callers cannot change it
+func cloneFakeSubscription(subscription *models.Subscription) *models.Subscription {
+	copied := *subscription
+	copied.Items = append([]models.SubscriptionItem(nil), subscription.Items...)
+	return &copied
+}
+
+// mutateSubscription applies fn to a stored subscription and returns a copy
+func (p *FakeProvider) mutateSubscription(providerSubscriptionID string, fn func(*models.Subscription)) (*models.Subscription, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	subscription, ok := p.subscriptions[providerSubscriptionID]
+	if !ok {
+		return nil, fmt.Errorf("fake: subscription %s not found", providerSubscriptionID)
+	}
+	fn(subscription)
+
+	return cloneFakeSubscription(subscription), nil
+}
+
+// PayInvoice declines the first declineAttempts attempts on each invoice and
+// succeeds after that
+func (p *FakeProvider) PayInvoice(ctx context.Context, providerInvoiceID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.invoiceAttempts[providerInvoiceID]++
+	if p.invoiceAttempts[providerInvoiceID] <= p.declineAttempts {
+		return fmt.Errorf("fake: %w: attempt %d on invoice %s", ErrPaymentDeclined, p.invoiceAttempts[providerInvoiceID], providerInvoiceID)
+	}
+
+	return nil
+}
+
+// CreateInvoiceCredit always succeeds
+func (p *FakeProvider) CreateInvoiceCredit(ctx context.Context, req *InvoiceCreditRequest) error {
+	return nil
+}
+
+// CreateRefund creates a refund that succeeds immediately
+func (p *FakeProvider) CreateRefund(ctx context.Context, req *CreateRefundRequest) (*models.Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[req.PaymentID]
+	if !ok {
+		return nil, fmt.Errorf("fake: payment %s not found", req.PaymentID)
+	}
+
+	amount := req.Amount
+	if amount == 0 {
+		amount = payment.Amount
+	}
+
+	refund := &models.Refund{
+		Provider:         models.ProviderStripe,
+		ProviderRefundID: fakeID("re"),
+		Amount:           amount,
+		Currency:         payment.Currency,
+		Status:           models.RefundStatusSucceeded,
+		CreatedAt:        time.Now(),
+	}
+	if req.Reason != "" {
+		refund.Reason = &req.Reason
+	}
+
+	p.refunds[refund.ProviderRefundID] = refund
+	copied := *refund
+	return &copied, nil
+}
+
+// GetRefund retrieves a fake refund
+func (p *FakeProvider) GetRefund(ctx context.Context, providerRefundID string) (*models.Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	refund, ok := p.refunds[providerRefundID]
+	if !ok {
+		return nil, fmt.Errorf("fake: refund %s not found", providerRefundID)
+	}
+
+	copied := *refund
+	return &copied, nil
+}
+
+// GetPaymentBalanceTransaction settles a succeeded fake payment at a fee of
+// 1.5% plus 1.80 and makes the funds available after two days
+func (p *FakeProvider) GetPaymentBalanceTransaction(ctx context.Context, providerPaymentID string) (*BalanceTransaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[providerPaymentID]
+	if !ok {
+		return nil, fmt.Errorf("fake: payment %s not found", providerPaymentID)
+	}
+	if payment.Status != models.PaymentStatusSucceeded {
+		return nil, nil
+	}
+
+	settledAt := time.Now()
+	if payment.CompletedAt != nil {
+		settledAt = *payment.CompletedAt
+	}
+
+	fee := payment.Amount*15/1000 + 180
+	return &BalanceTransaction{
+		ID:          "txn_" + providerPaymentID,
+		Type:        "charge",
+		SourceID:    providerPaymentID,
+		Amount:      payment.Amount,
+		Fee:         fee,
+		Net:         payment.Amount - fee,
+		Currency:    payment.Currency,
+		AvailableOn: settledAt.AddDate(0, 0, 2),
+	}, nil
+}
+
+// GetRefundBalanceTransaction settles a fake refund without a fee
+func (p *FakeProvider) GetRefundBalanceTransaction(ctx context.Context, providerRefundID string) (*BalanceTransaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	refund, ok := p.refunds[providerRefundID]
+	if !ok {
+		return nil, fmt.Errorf("fake: refund %s not found", providerRefundID)
+	}
+	if refund.Status != models.RefundStatusSucceeded {
+		return nil, nil
+	}
+
+	return &BalanceTransaction{
+		ID:          "txn_" + providerRefundID,
+		Type:        "refund",
+		SourceID:    providerRefundID,
+		Amount:      -refund.Amount,
+		Net:         -refund.Amount,
+		Currency:    refund.Currency,
+		AvailableOn: time.Now(),
+	}, nil
+}
+
+// ListPayments lists fake payments created in the given range
+func (p *FakeProvider) ListPayments(ctx context.Context, params *ListParams) (*ListPage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	created := make(map[string]time.Time, len(p.payments))
+	for id, payment := range p.payments {
+		created[id] = payment.CreatedAt
+	}
+	return fakeListPage(created, params), nil
+}
+
+// ListSubscriptions lists fake subscriptions created in the given range
+func (p *FakeProvider) ListSubscriptions(ctx context.Context, params *ListParams) (*ListPage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	created := make(map[string]time.Time, len(p.subscriptions))
+	for id, subscription := range p.subscriptions {
+		created[id] = subscription.CreatedAt
+	}
+	return fakeListPage(created, params), nil
+}
+
+// ListRefunds lists fake refunds created in the given range
+func (p *FakeProvider) ListRefunds(ctx context.Context, params *ListParams) (*ListPage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	created := make(map[string]time.Time, len(p.refunds))
+	for id, refund := range p.refunds {
+		created[id] = refund.CreatedAt
+	}
+	return fakeListPage(created, params), nil
+}
+
+// ListPayouts lists no payouts; the fake provider never pays out
+func (p *FakeProvider) ListPayouts(ctx context.Context, params *ListParams) (*ListPage, error) {
+	return &ListPage{}, nil
+}
+
+// GetPayout fails for every payout, since none exist
+func (p *FakeProvider) GetPayout(ctx context.Context, providerPayoutID string) (*models.Payout, error) {
+	return nil, fmt.Errorf("fake: payout %s not found", providerPayoutID)
+}
+
+// ListPayoutTransactions fails for every payout, since none exist
+func (p *FakeProvider) ListPayoutTransactions(ctx context.Context, providerPayoutID string) ([]BalanceTransaction, error) {
+	return nil, fmt.Errorf("fake: payout %s not found", providerPayoutID)
+}
+
+// UpdateDisputeEvidence accepts any evidence. Fake disputes only arrive
+// through webhooks, so the dispute is not looked up.
+func (p *FakeProvider) UpdateDisputeEvidence(ctx context.Context, providerDisputeID string, req *DisputeEvidenceRequest) (*models.Dispute, error) {
+	staged := models.JSONBMap{}
+	for field, value := range req.Text {
+		staged[field] = value
+	}
+	for _, f := range req.Files {
+		staged[f.Field] = fakeID("file")
+	}
+
+	status := models.DisputeStatusNeedsResponse
+	if req.Submit {
+		status = models.DisputeStatusUnderReview
+	}
+
+	return &models.Dispute{
+		Provider:          models.ProviderStripe,
+		ProviderDisputeID: providerDisputeID,
+		Status:            status,
+		Evidence:          staged,
+	}, nil
+}
+
+// fakeListPage pages through IDs in order, keeping those created in range
+func fakeListPage(created map[string]time.Time, params *ListParams) *ListPage {
+	var ids []string
+	for id, createdAt := range created {
+		if !createdAt.Before(params.CreatedFrom) && createdAt.Before(params.CreatedTo) && id > params.StartingAfter {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	page := &ListPage{IDs: ids}
+	if params.Limit > 0 && len(ids) > params.Limit {
+		page.IDs = ids[:params.Limit]
+		page.NextCursor = page.IDs[len(page.IDs)-1]
+	}
+	return page
+}
+
+// CreateCoupon returns a fake coupon ID
+func (p *FakeProvider) CreateCoupon(ctx context.Context, req *CreateCouponRequest) (string, error) {
+	return fakeID("coupon"), nil
+}
+
+// DeleteCoupon is a no-op for the fake provider
+func (p *FakeProvider) DeleteCoupon(ctx context.Context, providerCouponID string) error {
+	return nil
+}
+
+// VerifyWebhookSignature accepts every payload; the fake provider has no secret
+func (p *FakeProvider) VerifyWebhookSignature(payload []byte, signature string) error {
+	return nil
+}
+
+// ParseWebhookEvent parses Stripe-formatted events so Stripe fixtures can be
+// posted to the local webhook endpoint
+func (p *FakeProvider) ParseWebhookEvent(payload []byte) (*WebhookEvent, error) {
+	return parseStripeWebhookEvent(payload)
+}
+
+// fakeID returns a unique, Stripe-looking object ID
+func fakeID(prefix string) string {
+	return prefix + "_fake_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+}
@@ -2,11 +2,23 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"payment-service/internal/models"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrPaymentDeclined is returned when the customer's payment method is declined
+var ErrPaymentDeclined = errors.New("payment declined")
+
+// ErrPaymentPending is returned when a payment is waiting on the customer,
+// e.g. to approve a payment request in their app
+var ErrPaymentPending = errors.New("payment pending")
+
+// ErrNotSupported is returned for optional features a provider does not offer
+var ErrNotSupported = errors.New("not supported by provider")
+
 // PaymentProvider defines the interface all payment providers must implement
 type PaymentProvider interface {
 	// Provider identification
@@ -15,6 +27,8 @@ type PaymentProvider interface {
 	// Customer management
 	CreateCustomer(ctx context.Context, req *CreateCustomerRequest) (*models.Customer, error)
 	GetCustomer(ctx context.Context, providerCustomerID string) (*models.Customer, error)
+	UpdateCustomer(ctx context.Context, providerCustomerID string, req *UpdateCustomerRequest) error
+	DeleteCustomer(ctx context.Context, providerCustomerID string) error
 
 	// One-time payments
 	CreatePayment(ctx context.Context, req *CreatePaymentRequest) (*models.Payment, error)
@@ -26,11 +40,57 @@ type PaymentProvider interface {
 	GetSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error)
 	UpdateSubscription(ctx context.Context, providerSubscriptionID string, req *UpdateSubscriptionRequest) (*models.Subscription, error)
 	CancelSubscription(ctx context.Context, providerSubscriptionID string, immediate bool) (*models.Subscription, error)
+	ReactivateSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error)
+	PauseSubscription(ctx context.Context, providerSubscriptionID string, req *PauseSubscriptionRequest) (*models.Subscription, error)
+	ResumeSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error)
+
+	// Subscription items; changes within a period are prorated as requested
+	AddSubscriptionItem(ctx context.Context, providerSubscriptionID string, req *AddSubscriptionItemRequest) (*models.SubscriptionItem, error)
+	UpdateSubscriptionItem(ctx context.Context, providerSubscriptionID, providerItemID string, req *UpdateSubscriptionItemRequest) (*models.SubscriptionItem, error)
+	DeleteSubscriptionItem(ctx context.Context, providerSubscriptionID, providerItemID string, prorationBehavior string) error
+
+	// Subscription schedules run by the provider, which returns
+	// ErrNotSupported if it has none; phases are then applied by changing
+	// the subscription's plan when each one starts
+	CreateSubscriptionSchedule(ctx context.Context, req *CreateSubscriptionScheduleRequest) (string, error)
+	GetSubscriptionSchedule(ctx context.Context, providerScheduleID string) (*SubscriptionSchedule, error)
+	CancelSubscriptionSchedule(ctx context.Context, providerScheduleID string) error
+	ReleaseSubscriptionSchedule(ctx context.Context, providerScheduleID string) error
+	ChangeSubscriptionPlan(ctx context.Context, providerSubscriptionID string, req *ChangeSubscriptionPlanRequest) (*models.Subscription, error)
+
+	// Usage for metered prices; the provider aggregates it and bills it on
+	// the next invoice
+	ReportUsage(ctx context.Context, req *ReportUsageRequest) error
+
+	// Invoices
+	PayInvoice(ctx context.Context, providerInvoiceID string) error
+	CreateInvoiceCredit(ctx context.Context, req *InvoiceCreditRequest) error
 
 	// Refunds
 	CreateRefund(ctx context.Context, req *CreateRefundRequest) (*models.Refund, error)
 	GetRefund(ctx context.Context, providerRefundID string) (*models.Refund, error)
 
+	// Settlement; nil until the provider has settled the charge or refund
+	GetPaymentBalanceTransaction(ctx context.Context, providerPaymentID string) (*BalanceTransaction, error)
+	GetRefundBalanceTransaction(ctx context.Context, providerRefundID string) (*BalanceTransaction, error)
+
+	// Reconciliation; list IDs of objects created in a date range, a page at a time
+	ListPayments(ctx context.Context, params *ListParams) (*ListPage, error)
+	ListSubscriptions(ctx context.Context, params *ListParams) (*ListPage, error)
+	ListRefunds(ctx context.Context, params *ListParams) (*ListPage, error)
+
+	// Payouts; transactions exclude the payout's own balance transaction
+	ListPayouts(ctx context.Context, params *ListParams) (*ListPage, error)
+	GetPayout(ctx context.Context, providerPayoutID string) (*models.Payout, error)
+	ListPayoutTransactions(ctx context.Context, providerPayoutID string) ([]BalanceTransaction, error)
+
+	// Disputes; evidence files are uploaded to the provider first
+	UpdateDisputeEvidence(ctx context.Context, providerDisputeID string, req *DisputeEvidenceRequest) (*models.Dispute, error)
+
+	// Discounts
+	CreateCoupon(ctx context.Context, req *CreateCouponRequest) (string, error)
+	DeleteCoupon(ctx context.Context, providerCouponID string) error
+
 	// Webhooks
 	VerifyWebhookSignature(payload []byte, signature string) error
 	ParseWebhookEvent(payload []byte) (*WebhookEvent, error)
@@ -44,6 +104,25 @@ type CreateCustomerRequest struct {
 	Metadata map[string]string
 }
 
+// UpdateCustomerRequest represents a customer's billing details. It replaces
+// what the provider has; empty fields are cleared.
+type UpdateCustomerRequest struct {
+	Name    string // Company name if set, otherwise the customer's name
+	Email   string // Invoice email if set, otherwise the customer's email
+	Address *Address
+	Locale  string
+	VATID   string
+}
+
+// Address represents a billing address
+type Address struct {
+	Line1      string
+	Line2      string
+	PostalCode string
+	City       string
+	Country    string
+}
+
 // CreatePaymentRequest represents a request to create a payment
 type CreatePaymentRequest struct {
 	CustomerID          string
@@ -55,25 +134,141 @@ type CreatePaymentRequest struct {
 	IdempotencyKey      string
 }
 
-// CreateSubscriptionRequest represents a request to create a subscription
+// CreateSubscriptionRequest represents a request to create a subscription. The
+// created subscription's Items carry the provider item IDs.
 type CreateSubscriptionRequest struct {
 	CustomerID         string
-	Amount             int64
+	Items              []SubscriptionItem
 	Currency           string
 	Interval           string
 	IntervalCount      int
 	ProductName        string
 	ProductDescription string
 	TrialPeriodDays    int
+	TrialEndBehavior   string
+	CouponID           string
+	TaxRate            *TaxRate
+	MeteredPrices      []MeteredPrice
 	Metadata           map[string]string
 }
 
+// SubscriptionItem is a price billed every period, once per unit of Quantity
+type SubscriptionItem struct {
+	ProductName string
+	UnitAmount  int64
+	Quantity    int64
+}
+
+// MeteredPrice is a per-unit price billed on reported usage. The created
+// subscription's MeteredPrices carry the item IDs to report usage to.
+type MeteredPrice struct {
+	Meter       string
+	UnitAmount  int64
+	Aggregation models.UsageAggregation
+}
+
+// TaxRate is a tax rate applied to every invoice of a subscription
+type TaxRate struct {
+	DisplayName string
+	Country     string
+	Percentage  float64
+	Inclusive   bool
+}
+
 // UpdateSubscriptionRequest represents a request to update a subscription
 type UpdateSubscriptionRequest struct {
 	CancelAtPeriodEnd *bool
+	TrialEnd          *time.Time
 	Metadata          map[string]string
 }
 
+// AddSubscriptionItemRequest represents an item added to a subscription. The
+// price recurs like the subscription's other items.
+type AddSubscriptionItemRequest struct {
+	ProductName       string
+	UnitAmount        int64
+	Quantity          int64
+	Currency          string
+	Interval          string
+	IntervalCount     int
+	ProrationBehavior string
+}
+
+// UpdateSubscriptionItemRequest represents a quantity change of a subscription item
+type UpdateSubscriptionItemRequest struct {
+	Quantity          int64
+	ProrationBehavior string
+}
+
+// CreateSubscriptionScheduleRequest represents phases run by the provider. A
+// schedule for an existing subscription keeps its current plan until the
+// first phase starts; otherwise a subscription is created for CustomerID when
+// it starts. Each phase starts when the previous one has run its iterations.
+type CreateSubscriptionScheduleRequest struct {
+	CustomerID     string
+	SubscriptionID string // Empty to start a new subscription
+	Currency       string
+	EndBehavior    string
+	TaxRate        *TaxRate
+	Metadata       map[string]string
+	Phases         []SchedulePhase
+}
+
+// SchedulePhase bills Items every interval for Iterations billing cycles
+type SchedulePhase struct {
+	Items         []SubscriptionItem
+	Interval      string
+	IntervalCount int
+	Iterations    int
+	StartDate     time.Time
+}
+
+// SubscriptionSchedule is the provider's progress through a schedule
+type SubscriptionSchedule struct {
+	Status         string // not_started, active, completed, released or canceled
+	SubscriptionID string // Empty until the schedule has started a subscription
+}
+
+// ChangeSubscriptionPlanRequest replaces the licensed items and billing
+// interval of a subscription without prorating. A new interval starts a new
+// period right away; otherwise the new items are billed from the next period.
+type ChangeSubscriptionPlanRequest struct {
+	Items         []SubscriptionItem
+	Currency      string
+	Interval      string
+	IntervalCount int
+}
+
+// PauseSubscriptionRequest represents a request to pause collection on a subscription
+type PauseSubscriptionRequest struct {
+	Behavior  string
+	ResumesAt *time.Time
+}
+
+// InvoiceCreditRequest represents store credit taken off a draft invoice
+type InvoiceCreditRequest struct {
+	CustomerID  string
+	InvoiceID   string
+	Amount      int64
+	Currency    string
+	Description string
+}
+
+// Usage report actions
+const (
+	UsageActionIncrement = "increment" // Add to the usage so far
+	UsageActionSet       = "set"       // Replace the usage at the timestamp
+)
+
+// ReportUsageRequest represents usage reported for a metered subscription item
+type ReportUsageRequest struct {
+	SubscriptionItemID string
+	Quantity           int64
+	Timestamp          time.Time
+	Action             string
+	IdempotencyKey     string
+}
+
 // CreateRefundRequest represents a request to create a refund
 type CreateRefundRequest struct {
 	PaymentID string
@@ -82,13 +277,91 @@ type CreateRefundRequest struct {
 	Metadata  map[string]string
 }
 
+// CreateCouponRequest represents a request to create a coupon
+type CreateCouponRequest struct {
+	Name             string
+	PercentOff       *float64
+	AmountOff        *int64
+	Currency         string
+	Duration         string
+	DurationInMonths *int
+	MaxRedemptions   *int
+	RedeemBy         *time.Time
+	Metadata         map[string]string
+}
+
+// DisputeEvidenceRequest represents evidence for a dispute, keyed by evidence
+// field. The evidence is only sent to the bank when Submit is set.
+type DisputeEvidenceRequest struct {
+	Text   map[string]string
+	Files  []models.DisputeEvidenceFile
+	Submit bool
+}
+
+// ListParams selects a page of provider objects created in [CreatedFrom, CreatedTo)
+type ListParams struct {
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	StartingAfter string // Cursor from the previous page's NextCursor
+	Limit         int
+}
+
+// ListPage is a page of provider object IDs
+type ListPage struct {
+	IDs        []string
+	NextCursor string // Empty on the last page
+}
+
 // WebhookEvent represents a parsed webhook event
 type WebhookEvent struct {
 	ID           string
 	Type         string
 	Provider     string
-	ResourceType string // payment, subscription, refund
+	ResourceType string // payment, subscription, refund, invoice, dispute
 	ResourceID   string
 	Status       string
 	Payload      map[string]any
+
+	// Provider object mapped to our models, when the event carries one
+	Subscription *models.Subscription
+	Refund       *models.Refund
+	Invoice      *WebhookInvoice
+	Dispute      *WebhookDispute
+}
+
+// WebhookInvoice carries a provider invoice and the provider IDs it refers to
+type WebhookInvoice struct {
+	ProviderInvoiceID      string
+	ProviderSubscriptionID string
+	ProviderPaymentID      string
+	BillingReason          string
+	AmountDue              int64
+	Currency               string
+	AttemptCount           int
+
+	// Invoice mapped to our model; local IDs are left unset
+	Invoice *models.Invoice
+}
+
+// WebhookDispute carries a provider dispute and the payment it disputes
+type WebhookDispute struct {
+	ProviderPaymentID string
+
+	// Dispute mapped to our model; local IDs are left unset
+	Dispute *models.Dispute
+}
+
+// BalanceTransaction is how a charge or refund moved our provider balance.
+// Amounts are in minor units of the settlement currency; refunds are negative.
+type BalanceTransaction struct {
+	ID           string
+	Type         string // charge, refund, adjustment, ...
+	SourceID     string // Provider ID of the charge, refund, etc. that caused it
+	Description  string
+	Amount       int64
+	Fee          int64
+	Net          int64
+	Currency     models.Currency
+	ExchangeRate *float64 // Set when the settlement currency differs from the charge's
+	AvailableOn  time.Time
 }
@@ -1,25 +1,47 @@
 package providers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"payment-service/internal/models"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/balancetransaction"
+	"github.com/stripe/stripe-go/v78/coupon"
 	"github.com/stripe/stripe-go/v78/customer"
+	"github.com/stripe/stripe-go/v78/dispute"
+	"github.com/stripe/stripe-go/v78/file"
+	"github.com/stripe/stripe-go/v78/invoice"
+	"github.com/stripe/stripe-go/v78/invoiceitem"
 	"github.com/stripe/stripe-go/v78/paymentintent"
+	"github.com/stripe/stripe-go/v78/payout"
 	"github.com/stripe/stripe-go/v78/price"
 	"github.com/stripe/stripe-go/v78/refund"
 	"github.com/stripe/stripe-go/v78/subscription"
+	"github.com/stripe/stripe-go/v78/subscriptionitem"
+	"github.com/stripe/stripe-go/v78/subscriptionschedule"
+	"github.com/stripe/stripe-go/v78/taxid"
+	"github.com/stripe/stripe-go/v78/taxrate"
+	"github.com/stripe/stripe-go/v78/usagerecord"
 	"github.com/stripe/stripe-go/v78/webhook"
 )
 
+// stripeMeterMetadataKey names the meter of a metered Stripe price
+const stripeMeterMetadataKey = "meter"
+
 type StripeProvider struct {
 	apiKey        string
 	webhookSecret string
+
+	// Tax rates created in Stripe, keyed by country, percentage and inclusiveness
+	mu       sync.Mutex
+	taxRates map[string]string
 }
 
 // NewStripeProvider creates a new Stripe provider
@@ -28,6 +50,7 @@ func NewStripeProvider(apiKey, webhookSecret string) *StripeProvider {
 	return &StripeProvider{
 		apiKey:        apiKey,
 		webhookSecret: webhookSecret,
+		taxRates:      map[string]string{},
 	}
 }
 
@@ -82,6 +105,84 @@ func (p *StripeProvider) GetCustomer(ctx context.Context, providerCustomerID str
 	}, nil
 }
 
+// UpdateCustomer syncs a customer's billing details to Stripe
+func (p *StripeProvider) UpdateCustomer(ctx context.Context, providerCustomerID string, req *UpdateCustomerRequest) error {
+	params := &stripe.CustomerParams{
+		Name:  stripe.String(req.Name),
+		Email: stripe.String(req.Email),
+	}
+
+	if req.Address != nil {
+		params.Address = &stripe.AddressParams{
+			Line1:      stripe.String(req.Address.Line1),
+			Line2:      stripe.String(req.Address.Line2),
+			PostalCode: stripe.String(req.Address.PostalCode),
+			City:       stripe.String(req.Address.City),
+			Country:    stripe.String(req.Address.Country),
+		}
+	}
+
+	if req.Locale != "" {
+		params.PreferredLocales = []*string{stripe.String(req.Locale)}
+	}
+
+	if _, err := customer.Update(providerCustomerID, params); err != nil {
+		return fmt.Errorf("stripe: failed to update customer: %w", err)
+	}
+
+	return p.syncVATID(providerCustomerID, req.VATID)
+}
+
+// DeleteCustomer deletes a customer and its payment methods in Stripe. A
+// customer that is already gone counts as deleted.
+func (p *StripeProvider) DeleteCustomer(ctx context.Context, providerCustomerID string) error {
+	if _, err := customer.Del(providerCustomerID, nil); err != nil {
+		if stripeErr, ok := err.(*stripe.Error); ok && stripeErr.Code == stripe.ErrorCodeResourceMissing {
+			return nil
+		}
+		return fmt.Errorf("stripe: failed to delete customer: %w", err)
+	}
+
+	return nil
+}
+
+// syncVATID makes vatID the customer's only EU VAT ID in Stripe
+func (p *StripeProvider) syncVATID(providerCustomerID, vatID string) error {
+	found := false
+	iter := taxid.List(&stripe.TaxIDListParams{Customer: stripe.String(providerCustomerID)})
+	for iter.Next() {
+		existing := iter.TaxID()
+		if existing.Type != stripe.TaxIDTypeEUVAT {
+			continue
+		}
+		if existing.Value == vatID {
+			found = true
+			continue
+		}
+		if _, err := taxid.Del(existing.ID, &stripe.TaxIDParams{Customer: stripe.String(providerCustomerID)}); err != nil {
+			return fmt.Errorf("stripe: failed to delete tax id: %w", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("stripe: failed to list tax ids: %w", err)
+	}
+
+	if vatID == "" || found {
+		return nil
+	}
+
+	_, err := taxid.New(&stripe.TaxIDParams{
+		Customer: stripe.String(providerCustomerID),
+		Type:     stripe.String(string(stripe.TaxIDTypeEUVAT)),
+		Value:    stripe.String(vatID),
+	})
+	if err != nil {
+		return fmt.Errorf("stripe: failed to create tax id: %w", err)
+	}
+
+	return nil
+}
+
 // CreatePayment creates a payment intent in Stripe
 func (p *StripeProvider) CreatePayment(ctx context.Context, req *CreatePaymentRequest) (*models.Payment, error) {
 	params := &stripe.PaymentIntentParams{
@@ -141,10 +242,16 @@ func (p *StripeProvider) VerifyWebhookSignature(payload []byte, signature string
 	return nil
 }
 
-// ParseWebhookEvent parses a Stripe webhook event
+// ParseWebhookEvent parses a Stripe webhook event. The signature must already
+// have been checked with VerifyWebhookSignature.
 func (p *StripeProvider) ParseWebhookEvent(payload []byte) (*WebhookEvent, error) {
-	event, err := webhook.ConstructEvent(payload, "", p.webhookSecret)
-	if err != nil {
+	return parseStripeWebhookEvent(payload)
+}
+
+// parseStripeWebhookEvent maps a Stripe event payload to a WebhookEvent
+func parseStripeWebhookEvent(payload []byte) (*WebhookEvent, error) {
+	var event stripe.Event
+	if err := json.Unmarshal(payload, &event); err != nil {
 		return nil, fmt.Errorf("stripe: failed to parse webhook event: %w", err)
 	}
 
@@ -155,13 +262,85 @@ func (p *StripeProvider) ParseWebhookEvent(payload []byte) (*WebhookEvent, error
 		Payload:  make(map[string]any),
 	}
 
+	if event.Data == nil {
+		return webhookEvent, nil
+	}
+	if event.Data.Object != nil {
+		webhookEvent.Payload = event.Data.Object
+	}
+
 	// Determine resource type based on event type
 	switch event.Type {
 	case "payment_intent.succeeded", "payment_intent.payment_failed",
 		"payment_intent.canceled", "payment_intent.processing":
+		var pi stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
+			return nil, fmt.Errorf("stripe: failed to parse payment intent: %w", err)
+		}
 		webhookEvent.ResourceType = "payment"
+		webhookEvent.ResourceID = pi.ID
+		webhookEvent.Status = string(pi.Status)
+	case "customer.subscription.created", "customer.subscription.updated",
+		"customer.subscription.deleted", "customer.subscription.paused",
+		"customer.subscription.resumed", "customer.subscription.trial_will_end":
+		var sub stripe.Subscription
+		if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
+			return nil, fmt.Errorf("stripe: failed to parse subscription: %w", err)
+		}
+		webhookEvent.ResourceType = "subscription"
+		webhookEvent.ResourceID = sub.ID
+		webhookEvent.Status = string(sub.Status)
+		webhookEvent.Subscription = mapStripeSubscription(&sub)
+	case "invoice.created", "invoice.finalized", "invoice.updated", "invoice.paid",
+		"invoice.payment_failed", "invoice.voided", "invoice.marked_uncollectible":
+		var inv stripe.Invoice
+		if err := json.Unmarshal(event.Data.Raw, &inv); err != nil {
+			return nil, fmt.Errorf("stripe: failed to parse invoice: %w", err)
+		}
+		webhookEvent.ResourceType = "invoice"
+		webhookEvent.ResourceID = inv.ID
+		webhookEvent.Status = string(inv.Status)
+		webhookEvent.Invoice = &WebhookInvoice{
+			ProviderInvoiceID: inv.ID,
+			BillingReason:     string(inv.BillingReason),
+			AmountDue:         inv.AmountDue,
+			Currency:          strings.ToUpper(string(inv.Currency)),
+			AttemptCount:      int(inv.AttemptCount),
+			Invoice:           mapStripeInvoice(&inv),
+		}
+		if inv.Subscription != nil {
+			webhookEvent.Invoice.ProviderSubscriptionID = inv.Subscription.ID
+		}
+		if inv.PaymentIntent != nil {
+			webhookEvent.Invoice.ProviderPaymentID = inv.PaymentIntent.ID
+		}
+	case "charge.refund.updated", "refund.created", "refund.updated", "refund.failed":
+		var ref stripe.Refund
+		if err := json.Unmarshal(event.Data.Raw, &ref); err != nil {
+			return nil, fmt.Errorf("stripe: failed to parse refund: %w", err)
+		}
+		webhookEvent.ResourceType = "refund"
+		webhookEvent.ResourceID = ref.ID
+		webhookEvent.Status = string(ref.Status)
+		webhookEvent.Refund = mapStripeRefund(&ref)
+	case "charge.dispute.created", "charge.dispute.updated", "charge.dispute.closed",
+		"charge.dispute.funds_withdrawn", "charge.dispute.funds_reinstated":
+		var d stripe.Dispute
+		if err := json.Unmarshal(event.Data.Raw, &d); err != nil {
+			return nil, fmt.Errorf("stripe: failed to parse dispute: %w", err)
+		}
+		webhookEvent.ResourceType = "dispute"
+		webhookEvent.ResourceID = d.ID
+		webhookEvent.Status = string(d.Status)
+		webhookEvent.Dispute = &WebhookDispute{Dispute: mapStripeDispute(&d)}
+		if d.PaymentIntent != nil {
+			webhookEvent.Dispute.ProviderPaymentID = d.PaymentIntent.ID
+		}
 	case "customer.created", "customer.updated", "customer.deleted":
 		webhookEvent.ResourceType = "customer"
+		if id, ok := event.Data.Object["id"].(string); ok {
+			webhookEvent.ResourceID = id
+		}
 	}
 
 	return webhookEvent, nil
@@ -191,34 +370,72 @@ func mapPaymentIntentToPayment(pi *stripe.PaymentIntent) *models.Payment {
 
 // CreateSubscription creates a subscription in Stripe
 func (p *StripeProvider) CreateSubscription(ctx context.Context, req *CreateSubscriptionRequest) (*models.Subscription, error) {
-	// Create price for the subscription
-	priceParams := &stripe.PriceParams{
-		Currency:   stripe.String(req.Currency),
-		UnitAmount: stripe.Int64(req.Amount),
-		Recurring: &stripe.PriceRecurringParams{
-			Interval:      stripe.String(req.Interval),
-			IntervalCount: stripe.Int64(int64(req.IntervalCount)),
-		},
-		Product: stripe.String("prod_payment_service"), // Use a generic product or create dynamically
+	// Create a price for each item, billed per unit of its quantity
+	subParams := &stripe.SubscriptionParams{
+		Customer: stripe.String(req.CustomerID),
 	}
-
-	priceObj, err := price.New(priceParams)
-	if err != nil {
-		return nil, fmt.Errorf("stripe: failed to create price: %w", err)
+	for _, item := range req.Items {
+		priceID, err := createStripeLicensedPrice(item.ProductName, item.UnitAmount, req.Currency, req.Interval, req.IntervalCount)
+		if err != nil {
+			return nil, err
+		}
+		subParams.Items = append(subParams.Items, &stripe.SubscriptionItemsParams{
+			Price:    stripe.String(priceID),
+			Quantity: stripe.Int64(item.Quantity),
+		})
 	}
 
-	// Create subscription
-	subParams := &stripe.SubscriptionParams{
-		Customer: stripe.String(req.CustomerID),
-		Items: []*stripe.SubscriptionItemsParams{
-			{Price: stripe.String(priceObj.ID)},
-		},
+	// Metered prices are billed in arrears on the usage reported for them
+	for _, metered := range req.MeteredPrices {
+		meteredParams := &stripe.PriceParams{
+			Currency:   stripe.String(req.Currency),
+			UnitAmount: stripe.Int64(metered.UnitAmount),
+			Recurring: &stripe.PriceRecurringParams{
+				Interval:       stripe.String(req.Interval),
+				IntervalCount:  stripe.Int64(int64(req.IntervalCount)),
+				UsageType:      stripe.String(string(stripe.PriceRecurringUsageTypeMetered)),
+				AggregateUsage: stripe.String(string(stripeAggregateUsage(metered.Aggregation))),
+			},
+			Product: stripe.String("prod_payment_service"),
+		}
+		meteredParams.AddMetadata(stripeMeterMetadataKey, metered.Meter)
+
+		meteredPrice, err := price.New(meteredParams)
+		if err != nil {
+			return nil, fmt.Errorf("stripe: failed to create metered price: %w", err)
+		}
+		subParams.Items = append(subParams.Items, &stripe.SubscriptionItemsParams{
+			Price: stripe.String(meteredPrice.ID),
+		})
 	}
 
 	if req.TrialPeriodDays > 0 {
 		subParams.TrialPeriodDays = stripe.Int64(int64(req.TrialPeriodDays))
 	}
 
+	// Trials can start without a card; decide what happens if none was added
+	if req.TrialEndBehavior != "" {
+		subParams.TrialSettings = &stripe.SubscriptionTrialSettingsParams{
+			EndBehavior: &stripe.SubscriptionTrialSettingsEndBehaviorParams{
+				MissingPaymentMethod: stripe.String(req.TrialEndBehavior),
+			},
+		}
+	}
+
+	if req.CouponID != "" {
+		subParams.Discounts = []*stripe.SubscriptionDiscountParams{
+			{Coupon: stripe.String(req.CouponID)},
+		}
+	}
+
+	if req.TaxRate != nil {
+		taxRateID, err := p.taxRateID(req.TaxRate)
+		if err != nil {
+			return nil, err
+		}
+		subParams.DefaultTaxRates = []*string{stripe.String(taxRateID)}
+	}
+
 	for k, v := range req.Metadata {
 		subParams.AddMetadata(k, v)
 	}
@@ -231,6 +448,70 @@ func (p *StripeProvider) CreateSubscription(ctx context.Context, req *CreateSubs
 	return mapStripeSubscription(sub), nil
 }
 
+// createStripeLicensedPrice creates a recurring price for a subscription item.
+// The product name is kept as the price nickname so items can be told apart.
+func createStripeLicensedPrice(productName string, unitAmount int64, currency, interval string, intervalCount int) (string, error) {
+	params := &stripe.PriceParams{
+		Currency:   stripe.String(currency),
+		UnitAmount: stripe.Int64(unitAmount),
+		Nickname:   stripe.String(productName),
+		Recurring: &stripe.PriceRecurringParams{
+			Interval:      stripe.String(interval),
+			IntervalCount: stripe.Int64(int64(intervalCount)),
+		},
+		Product: stripe.String("prod_payment_service"), // Use a generic product or create dynamically
+	}
+
+	priceObj, err := price.New(params)
+	if err != nil {
+		return "", fmt.Errorf("stripe: failed to create price: %w", err)
+	}
+
+	return priceObj.ID, nil
+}
+
+// stripeAggregateUsage maps our usage aggregation to Stripe's
+func stripeAggregateUsage(aggregation models.UsageAggregation) stripe.PriceRecurringAggregateUsage {
+	switch aggregation {
+	case models.UsageAggregationMax:
+		return stripe.PriceRecurringAggregateUsageMax
+	case models.UsageAggregationLast:
+		return stripe.PriceRecurringAggregateUsageLastDuringPeriod
+	default:
+		return stripe.PriceRecurringAggregateUsageSum
+	}
+}
+
+// taxRateID returns a Stripe tax rate matching rate, creating one if needed.
+// Stripe tax rates are immutable, so created rates are reused.
+func (p *StripeProvider) taxRateID(rate *TaxRate) (string, error) {
+	key := fmt.Sprintf("%s:%g:%t", rate.Country, rate.Percentage, rate.Inclusive)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if id, ok := p.taxRates[key]; ok {
+		return id, nil
+	}
+
+	params := &stripe.TaxRateParams{
+		DisplayName:  stripe.String(rate.DisplayName),
+		Country:      stripe.String(rate.Country),
+		Jurisdiction: stripe.String(rate.Country),
+		Percentage:   stripe.Float64(rate.Percentage),
+		Inclusive:    stripe.Bool(rate.Inclusive),
+		TaxType:      stripe.String("vat"),
+	}
+
+	taxRate, err := taxrate.New(params)
+	if err != nil {
+		return "", fmt.Errorf("stripe: failed to create tax rate: %w", err)
+	}
+
+	p.taxRates[key] = taxRate.ID
+	return taxRate.ID, nil
+}
+
 // GetSubscription retrieves a subscription from Stripe
 func (p *StripeProvider) GetSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
 	sub, err := subscription.Get(providerSubscriptionID, nil)
@@ -249,6 +530,11 @@ func (p *StripeProvider) UpdateSubscription(ctx context.Context, providerSubscri
 		params.CancelAtPeriodEnd = stripe.Bool(*req.CancelAtPeriodEnd)
 	}
 
+	if req.TrialEnd != nil {
+		params.TrialEnd = stripe.Int64(req.TrialEnd.Unix())
+		params.ProrationBehavior = stripe.String("none")
+	}
+
 	for k, v := range req.Metadata {
 		params.AddMetadata(k, v)
 	}
@@ -284,6 +570,339 @@ func (p *StripeProvider) CancelSubscription(ctx context.Context, providerSubscri
 	return mapStripeSubscription(sub), nil
 }
 
+// ReactivateSubscription clears a scheduled cancellation in Stripe
+func (p *StripeProvider) ReactivateSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	params := &stripe.SubscriptionParams{
+		CancelAtPeriodEnd: stripe.Bool(false),
+	}
+	// An empty cancel_at clears an explicit cancellation date
+	params.AddExtra("cancel_at", "")
+
+	sub, err := subscription.Update(providerSubscriptionID, params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to reactivate subscription: %w", err)
+	}
+
+	return mapStripeSubscription(sub), nil
+}
+
+// PauseSubscription pauses payment collection on a subscription in Stripe
+func (p *StripeProvider) PauseSubscription(ctx context.Context, providerSubscriptionID string, req *PauseSubscriptionRequest) (*models.Subscription, error) {
+	pauseParams := &stripe.SubscriptionPauseCollectionParams{
+		Behavior: stripe.String(req.Behavior),
+	}
+	if req.ResumesAt != nil {
+		pauseParams.ResumesAt = stripe.Int64(req.ResumesAt.Unix())
+	}
+
+	params := &stripe.SubscriptionParams{
+		PauseCollection: pauseParams,
+	}
+	sub, err := subscription.Update(providerSubscriptionID, params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to pause subscription: %w", err)
+	}
+
+	return mapStripeSubscription(sub), nil
+}
+
+// ResumeSubscription resumes payment collection on a paused subscription in Stripe
+func (p *StripeProvider) ResumeSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	current, err := subscription.Get(providerSubscriptionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to get subscription: %w", err)
+	}
+
+	// A trial that ended without a payment method pauses the subscription
+	// itself rather than its collection, and has to be resumed explicitly
+	if current.Status == stripe.SubscriptionStatusPaused {
+		sub, err := subscription.Resume(providerSubscriptionID, &stripe.SubscriptionResumeParams{
+			BillingCycleAnchor: stripe.String("now"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("stripe: failed to resume subscription: %w", err)
+		}
+		return mapStripeSubscription(sub), nil
+	}
+
+	// An empty pause_collection clears the pause
+	params := &stripe.SubscriptionParams{}
+	params.AddExtra("pause_collection", "")
+
+	sub, err := subscription.Update(providerSubscriptionID, params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to resume subscription: %w", err)
+	}
+
+	return mapStripeSubscription(sub), nil
+}
+
+// AddSubscriptionItem adds an item with a new price to a Stripe subscription
+func (p *StripeProvider) AddSubscriptionItem(ctx context.Context, providerSubscriptionID string, req *AddSubscriptionItemRequest) (*models.SubscriptionItem, error) {
+	priceID, err := createStripeLicensedPrice(req.ProductName, req.UnitAmount, req.Currency, req.Interval, req.IntervalCount)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := subscriptionitem.New(&stripe.SubscriptionItemParams{
+		Subscription:      stripe.String(providerSubscriptionID),
+		Price:             stripe.String(priceID),
+		Quantity:          stripe.Int64(req.Quantity),
+		ProrationBehavior: stripe.String(req.ProrationBehavior),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to add subscription item: %w", err)
+	}
+
+	return mapStripeSubscriptionItem(item), nil
+}
+
+// UpdateSubscriptionItem changes the quantity of a Stripe subscription item
+func (p *StripeProvider) UpdateSubscriptionItem(ctx context.Context, providerSubscriptionID, providerItemID string, req *UpdateSubscriptionItemRequest) (*models.SubscriptionItem, error) {
+	item, err := subscriptionitem.Update(providerItemID, &stripe.SubscriptionItemParams{
+		Quantity:          stripe.Int64(req.Quantity),
+		ProrationBehavior: stripe.String(req.ProrationBehavior),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to update subscription item: %w", err)
+	}
+
+	return mapStripeSubscriptionItem(item), nil
+}
+
+// DeleteSubscriptionItem removes an item from a Stripe subscription
+func (p *StripeProvider) DeleteSubscriptionItem(ctx context.Context, providerSubscriptionID, providerItemID string, prorationBehavior string) error {
+	_, err := subscriptionitem.Del(providerItemID, &stripe.SubscriptionItemParams{
+		ProrationBehavior: stripe.String(prorationBehavior),
+	})
+	if err != nil {
+		return fmt.Errorf("stripe: failed to delete subscription item: %w", err)
+	}
+
+	return nil
+}
+
+// CreateSubscriptionSchedule creates a Stripe subscription schedule. For an
+// existing subscription, its current plan becomes the first Stripe phase and
+// its metered prices carry over to every later phase.
+func (p *StripeProvider) CreateSubscriptionSchedule(ctx context.Context, req *CreateSubscriptionScheduleRequest) (string, error) {
+	var taxRates []*string
+	if req.TaxRate != nil {
+		taxRateID, err := p.taxRateID(req.TaxRate)
+		if err != nil {
+			return "", err
+		}
+		taxRates = []*string{stripe.String(taxRateID)}
+	}
+
+	var current *stripe.SubscriptionSchedulePhaseParams
+	var meteredItems []*stripe.SubscriptionSchedulePhaseItemParams
+	if req.SubscriptionID != "" {
+		sub, err := subscription.Get(req.SubscriptionID, nil)
+		if err != nil {
+			return "", fmt.Errorf("stripe: failed to get subscription: %w", err)
+		}
+
+		current = &stripe.SubscriptionSchedulePhaseParams{
+			StartDate: stripe.Int64(sub.CurrentPeriodStart),
+			EndDate:   stripe.Int64(req.Phases[0].StartDate.Unix()),
+		}
+		for _, rate := range sub.DefaultTaxRates {
+			current.DefaultTaxRates = append(current.DefaultTaxRates, stripe.String(rate.ID))
+		}
+		for _, it := range sub.Items.Data {
+			item := &stripe.SubscriptionSchedulePhaseItemParams{Price: stripe.String(it.Price.ID)}
+			if it.Price.Recurring.UsageType == stripe.PriceRecurringUsageTypeMetered {
+				meteredItems = append(meteredItems, item)
+			} else {
+				item.Quantity = stripe.Int64(it.Quantity)
+			}
+			current.Items = append(current.Items, item)
+		}
+	}
+
+	phases := make([]*stripe.SubscriptionSchedulePhaseParams, 0, len(req.Phases)+1)
+	if current != nil {
+		phases = append(phases, current)
+	}
+	for _, phase := range req.Phases {
+		phaseParams := &stripe.SubscriptionSchedulePhaseParams{
+			Iterations:        stripe.Int64(int64(phase.Iterations)),
+			DefaultTaxRates:   taxRates,
+			ProrationBehavior: stripe.String(string(stripe.SubscriptionSchedulePhaseProrationBehaviorNone)),
+			Metadata:          req.Metadata,
+		}
+		for _, item := range phase.Items {
+			priceID, err := createStripeLicensedPrice(item.ProductName, item.UnitAmount, req.Currency, phase.Interval, phase.IntervalCount)
+			if err != nil {
+				return "", err
+			}
+			phaseParams.Items = append(phaseParams.Items, &stripe.SubscriptionSchedulePhaseItemParams{
+				Price:    stripe.String(priceID),
+				Quantity: stripe.Int64(item.Quantity),
+			})
+		}
+		phaseParams.Items = append(phaseParams.Items, meteredItems...)
+		phases = append(phases, phaseParams)
+	}
+
+	params := &stripe.SubscriptionScheduleParams{
+		EndBehavior: stripe.String(req.EndBehavior),
+		Phases:      phases,
+	}
+
+	if req.SubscriptionID == "" {
+		params.Customer = stripe.String(req.CustomerID)
+		params.StartDate = stripe.Int64(req.Phases[0].StartDate.Unix())
+		sched, err := subscriptionschedule.New(params)
+		if err != nil {
+			return "", fmt.Errorf("stripe: failed to create subscription schedule: %w", err)
+		}
+		return sched.ID, nil
+	}
+
+	// Phases cannot be given when creating a schedule from a subscription
+	sched, err := subscriptionschedule.New(&stripe.SubscriptionScheduleParams{
+		FromSubscription: stripe.String(req.SubscriptionID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("stripe: failed to create subscription schedule: %w", err)
+	}
+	if _, err := subscriptionschedule.Update(sched.ID, params); err != nil {
+		// Leave the subscription as it was rather than on a half-set schedule
+		if _, releaseErr := subscriptionschedule.Release(sched.ID, nil); releaseErr != nil {
+			return "", fmt.Errorf("stripe: failed to set schedule phases: %w (release failed: %v)", err, releaseErr)
+		}
+		return "", fmt.Errorf("stripe: failed to set schedule phases: %w", err)
+	}
+
+	return sched.ID, nil
+}
+
+// GetSubscriptionSchedule retrieves the progress of a Stripe subscription schedule
+func (p *StripeProvider) GetSubscriptionSchedule(ctx context.Context, providerScheduleID string) (*SubscriptionSchedule, error) {
+	sched, err := subscriptionschedule.Get(providerScheduleID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to get subscription schedule: %w", err)
+	}
+
+	schedule := &SubscriptionSchedule{Status: string(sched.Status)}
+	if sched.Subscription != nil {
+		schedule.SubscriptionID = sched.Subscription.ID
+	} else if sched.ReleasedSubscription != nil {
+		schedule.SubscriptionID = sched.ReleasedSubscription.ID
+	}
+
+	return schedule, nil
+}
+
+// CancelSubscriptionSchedule cancels a Stripe subscription schedule that has
+// not started a subscription yet
+func (p *StripeProvider) CancelSubscriptionSchedule(ctx context.Context, providerScheduleID string) error {
+	if _, err := subscriptionschedule.Cancel(providerScheduleID, nil); err != nil {
+		return fmt.Errorf("stripe: failed to cancel subscription schedule: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseSubscriptionSchedule stops a Stripe subscription schedule, leaving
+// its subscription as it is
+func (p *StripeProvider) ReleaseSubscriptionSchedule(ctx context.Context, providerScheduleID string) error {
+	if _, err := subscriptionschedule.Release(providerScheduleID, nil); err != nil {
+		return fmt.Errorf("stripe: failed to release subscription schedule: %w", err)
+	}
+
+	return nil
+}
+
+// ChangeSubscriptionPlan replaces the licensed items of a Stripe subscription
+// with new prices. Stripe starts a new period itself when the interval changes.
+func (p *StripeProvider) ChangeSubscriptionPlan(ctx context.Context, providerSubscriptionID string, req *ChangeSubscriptionPlanRequest) (*models.Subscription, error) {
+	current, err := subscription.Get(providerSubscriptionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to get subscription: %w", err)
+	}
+
+	params := &stripe.SubscriptionParams{
+		ProrationBehavior: stripe.String("none"),
+	}
+	for _, it := range current.Items.Data {
+		if it.Price.Recurring.UsageType != stripe.PriceRecurringUsageTypeMetered {
+			params.Items = append(params.Items, &stripe.SubscriptionItemsParams{
+				ID:      stripe.String(it.ID),
+				Deleted: stripe.Bool(true),
+			})
+		}
+	}
+	for _, item := range req.Items {
+		priceID, err := createStripeLicensedPrice(item.ProductName, item.UnitAmount, req.Currency, req.Interval, req.IntervalCount)
+		if err != nil {
+			return nil, err
+		}
+		params.Items = append(params.Items, &stripe.SubscriptionItemsParams{
+			Price:    stripe.String(priceID),
+			Quantity: stripe.Int64(item.Quantity),
+		})
+	}
+
+	sub, err := subscription.Update(providerSubscriptionID, params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to change subscription plan: %w", err)
+	}
+
+	return mapStripeSubscription(sub), nil
+}
+
+// ReportUsage creates a usage record on a metered subscription item
+func (p *StripeProvider) ReportUsage(ctx context.Context, req *ReportUsageRequest) error {
+	params := &stripe.UsageRecordParams{
+		SubscriptionItem: stripe.String(req.SubscriptionItemID),
+		Quantity:         stripe.Int64(req.Quantity),
+		Timestamp:        stripe.Int64(req.Timestamp.Unix()),
+		Action:           stripe.String(req.Action),
+	}
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+
+	if _, err := usagerecord.New(params); err != nil {
+		return fmt.Errorf("stripe: failed to report usage: %w", err)
+	}
+
+	return nil
+}
+
+// PayInvoice attempts to collect an open invoice in Stripe
+func (p *StripeProvider) PayInvoice(ctx context.Context, providerInvoiceID string) error {
+	_, err := invoice.Pay(providerInvoiceID, nil)
+	if err != nil {
+		if stripeErr, ok := err.(*stripe.Error); ok && stripeErr.Type == stripe.ErrorTypeCard {
+			return fmt.Errorf("stripe: %w: %s", ErrPaymentDeclined, stripeErr.Msg)
+		}
+		return fmt.Errorf("stripe: failed to pay invoice: %w", err)
+	}
+
+	return nil
+}
+
+// CreateInvoiceCredit adds a negative line item to a draft Stripe invoice
+func (p *StripeProvider) CreateInvoiceCredit(ctx context.Context, req *InvoiceCreditRequest) error {
+	params := &stripe.InvoiceItemParams{
+		Customer:    stripe.String(req.CustomerID),
+		Invoice:     stripe.String(req.InvoiceID),
+		Amount:      stripe.Int64(-req.Amount),
+		Currency:    stripe.String(req.Currency),
+		Description: stripe.String(req.Description),
+	}
+
+	if _, err := invoiceitem.New(params); err != nil {
+		return fmt.Errorf("stripe: failed to credit invoice: %w", err)
+	}
+
+	return nil
+}
+
 // CreateRefund creates a refund in Stripe
 func (p *StripeProvider) CreateRefund(ctx context.Context, req *CreateRefundRequest) (*models.Refund, error) {
 	params := &stripe.RefundParams{
@@ -320,15 +939,404 @@ func (p *StripeProvider) GetRefund(ctx context.Context, providerRefundID string)
 	return mapStripeRefund(ref), nil
 }
 
+// GetPaymentBalanceTransaction retrieves the balance transaction of a payment
+// intent's latest charge
+func (p *StripeProvider) GetPaymentBalanceTransaction(ctx context.Context, providerPaymentID string) (*BalanceTransaction, error) {
+	params := &stripe.PaymentIntentParams{}
+	params.AddExpand("latest_charge.balance_transaction")
+
+	pi, err := paymentintent.Get(providerPaymentID, params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to get payment intent: %w", err)
+	}
+
+	if pi.LatestCharge == nil {
+		return nil, nil
+	}
+	return mapStripeBalanceTransaction(pi.LatestCharge.BalanceTransaction), nil
+}
+
+// GetRefundBalanceTransaction retrieves the balance transaction of a refund
+func (p *StripeProvider) GetRefundBalanceTransaction(ctx context.Context, providerRefundID string) (*BalanceTransaction, error) {
+	params := &stripe.RefundParams{}
+	params.AddExpand("balance_transaction")
+
+	ref, err := refund.Get(providerRefundID, params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to get refund: %w", err)
+	}
+
+	return mapStripeBalanceTransaction(ref.BalanceTransaction), nil
+}
+
+// mapStripeBalanceTransaction maps an expanded Stripe balance transaction
+func mapStripeBalanceTransaction(bt *stripe.BalanceTransaction) *BalanceTransaction {
+	if bt == nil || bt.ID == "" {
+		return nil
+	}
+
+	transaction := &BalanceTransaction{
+		ID:          bt.ID,
+		Type:        string(bt.Type),
+		Description: bt.Description,
+		Amount:      bt.Amount,
+		Fee:         bt.Fee,
+		Net:         bt.Net,
+		Currency:    models.Currency(strings.ToUpper(string(bt.Currency))),
+		AvailableOn: time.Unix(bt.AvailableOn, 0),
+	}
+	if bt.Source != nil {
+		transaction.SourceID = bt.Source.ID
+	}
+	if bt.ExchangeRate != 0 {
+		rate := bt.ExchangeRate
+		transaction.ExchangeRate = &rate
+	}
+
+	return transaction
+}
+
+// ListPayments lists payment intents created in the given range
+func (p *StripeProvider) ListPayments(ctx context.Context, params *ListParams) (*ListPage, error) {
+	listParams := &stripe.PaymentIntentListParams{
+		ListParams:   stripeListParams(params),
+		CreatedRange: stripeCreatedRange(params),
+	}
+
+	return stripeListPage(paymentintent.List(listParams).Iter, "payment intents", func(v interface{}) string {
+		return v.(*stripe.PaymentIntent).ID
+	})
+}
+
+// ListSubscriptions lists subscriptions in any status created in the given range
+func (p *StripeProvider) ListSubscriptions(ctx context.Context, params *ListParams) (*ListPage, error) {
+	listParams := &stripe.SubscriptionListParams{
+		ListParams:   stripeListParams(params),
+		CreatedRange: stripeCreatedRange(params),
+		Status:       stripe.String("all"),
+	}
+
+	return stripeListPage(subscription.List(listParams).Iter, "subscriptions", func(v interface{}) string {
+		return v.(*stripe.Subscription).ID
+	})
+}
+
+// ListRefunds lists refunds created in the given range
+func (p *StripeProvider) ListRefunds(ctx context.Context, params *ListParams) (*ListPage, error) {
+	listParams := &stripe.RefundListParams{
+		ListParams:   stripeListParams(params),
+		CreatedRange: stripeCreatedRange(params),
+	}
+
+	return stripeListPage(refund.List(listParams).Iter, "refunds", func(v interface{}) string {
+		return v.(*stripe.Refund).ID
+	})
+}
+
+// ListPayouts lists payouts created in the given range
+func (p *StripeProvider) ListPayouts(ctx context.Context, params *ListParams) (*ListPage, error) {
+	listParams := &stripe.PayoutListParams{
+		ListParams:   stripeListParams(params),
+		CreatedRange: stripeCreatedRange(params),
+	}
+
+	return stripeListPage(payout.List(listParams).Iter, "payouts", func(v interface{}) string {
+		return v.(*stripe.Payout).ID
+	})
+}
+
+// GetPayout retrieves a payout from Stripe
+func (p *StripeProvider) GetPayout(ctx context.Context, providerPayoutID string) (*models.Payout, error) {
+	po, err := payout.Get(providerPayoutID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to get payout: %w", err)
+	}
+
+	return mapStripePayout(po), nil
+}
+
+// ListPayoutTransactions lists every balance transaction paid out by a payout
+func (p *StripeProvider) ListPayoutTransactions(ctx context.Context, providerPayoutID string) ([]BalanceTransaction, error) {
+	params := &stripe.BalanceTransactionListParams{
+		Payout: stripe.String(providerPayoutID),
+	}
+	params.Limit = stripe.Int64(100)
+
+	var transactions []BalanceTransaction
+	it := balancetransaction.List(params)
+	for it.Next() {
+		bt := it.BalanceTransaction()
+		if bt.Type == stripe.BalanceTransactionTypePayout {
+			continue
+		}
+		transactions = append(transactions, *mapStripeBalanceTransaction(bt))
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("stripe: failed to list payout transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+func mapStripePayout(po *stripe.Payout) *models.Payout {
+	payout := &models.Payout{
+		Provider:          models.ProviderStripe,
+		ProviderPayoutID:  po.ID,
+		Amount:            po.Amount,
+		Currency:          models.Currency(strings.ToUpper(string(po.Currency))),
+		Status:            models.PayoutStatus(po.Status),
+		ArrivalDate:       time.Unix(po.ArrivalDate, 0),
+		ProviderCreatedAt: time.Unix(po.Created, 0),
+	}
+
+	if po.Description != "" {
+		payout.Description = &po.Description
+	}
+	if po.FailureCode != "" {
+		failureCode := string(po.FailureCode)
+		payout.FailureCode = &failureCode
+		payout.FailureMessage = &po.FailureMessage
+	}
+
+	return payout
+}
+
+// UpdateDisputeEvidence uploads evidence files and stages the evidence on a
+// dispute, submitting it to the bank when requested
+func (p *StripeProvider) UpdateDisputeEvidence(ctx context.Context, providerDisputeID string, req *DisputeEvidenceRequest) (*models.Dispute, error) {
+	evidence := &stripe.DisputeEvidenceParams{}
+	staged := models.JSONBMap{}
+
+	for field, value := range req.Text {
+		target := stripeDisputeEvidenceField(evidence, field)
+		if target == nil {
+			return nil, fmt.Errorf("stripe: unknown dispute evidence field %s", field)
+		}
+		*target = stripe.String(value)
+		staged[field] = value
+	}
+
+	for _, f := range req.Files {
+		target := stripeDisputeEvidenceField(evidence, f.Field)
+		if target == nil {
+			return nil, fmt.Errorf("stripe: unknown dispute evidence field %s", f.Field)
+		}
+		uploaded, err := file.New(&stripe.FileParams{
+			Purpose:    stripe.String(string(stripe.FilePurposeDisputeEvidence)),
+			FileReader: bytes.NewReader(f.Data),
+			Filename:   stripe.String(f.Filename),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("stripe: failed to upload evidence file %s: %w", f.Filename, err)
+		}
+		*target = stripe.String(uploaded.ID)
+		staged[f.Field] = uploaded.ID
+	}
+
+	d, err := dispute.Update(providerDisputeID, &stripe.DisputeParams{
+		Evidence: evidence,
+		Submit:   stripe.Bool(req.Submit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("stripe: failed to update dispute evidence: %w", err)
+	}
+
+	mapped := mapStripeDispute(d)
+	mapped.Evidence = staged
+	return mapped, nil
+}
+
+// stripeDisputeEvidenceField returns the evidence parameter for a field name,
+// or nil if Stripe has no such field
+func stripeDisputeEvidenceField(evidence *stripe.DisputeEvidenceParams, field string) **string {
+	switch field {
+	case "access_activity_log":
+		return &evidence.AccessActivityLog
+	case "billing_address":
+		return &evidence.BillingAddress
+	case "cancellation_policy":
+		return &evidence.CancellationPolicy
+	case "cancellation_policy_disclosure":
+		return &evidence.CancellationPolicyDisclosure
+	case "cancellation_rebuttal":
+		return &evidence.CancellationRebuttal
+	case "customer_communication":
+		return &evidence.CustomerCommunication
+	case "customer_email_address":
+		return &evidence.CustomerEmailAddress
+	case "customer_name":
+		return &evidence.CustomerName
+	case "customer_purchase_ip":
+		return &evidence.CustomerPurchaseIP
+	case "customer_signature":
+		return &evidence.CustomerSignature
+	case "duplicate_charge_documentation":
+		return &evidence.DuplicateChargeDocumentation
+	case "duplicate_charge_explanation":
+		return &evidence.DuplicateChargeExplanation
+	case "duplicate_charge_id":
+		return &evidence.DuplicateChargeID
+	case "product_description":
+		return &evidence.ProductDescription
+	case "receipt":
+		return &evidence.Receipt
+	case "refund_policy":
+		return &evidence.RefundPolicy
+	case "refund_policy_disclosure":
+		return &evidence.RefundPolicyDisclosure
+	case "refund_refusal_explanation":
+		return &evidence.RefundRefusalExplanation
+	case "service_date":
+		return &evidence.ServiceDate
+	case "service_documentation":
+		return &evidence.ServiceDocumentation
+	case "shipping_address":
+		return &evidence.ShippingAddress
+	case "shipping_carrier":
+		return &evidence.ShippingCarrier
+	case "shipping_date":
+		return &evidence.ShippingDate
+	case "shipping_documentation":
+		return &evidence.ShippingDocumentation
+	case "shipping_tracking_number":
+		return &evidence.ShippingTrackingNumber
+	case "uncategorized_file":
+		return &evidence.UncategorizedFile
+	case "uncategorized_text":
+		return &evidence.UncategorizedText
+	}
+	return nil
+}
+
+// mapStripeDispute converts a Stripe Dispute to our Dispute model
+func mapStripeDispute(d *stripe.Dispute) *models.Dispute {
+	mapped := &models.Dispute{
+		Provider:          models.ProviderStripe,
+		ProviderDisputeID: d.ID,
+		Amount:            d.Amount,
+		Currency:          models.Currency(strings.ToUpper(string(d.Currency))),
+		Status:            models.DisputeStatus(d.Status),
+		Reason:            string(d.Reason),
+	}
+
+	if d.EvidenceDetails != nil && d.EvidenceDetails.DueBy > 0 {
+		dueBy := time.Unix(d.EvidenceDetails.DueBy, 0)
+		mapped.EvidenceDueBy = &dueBy
+	}
+
+	return mapped
+}
+
+// stripeListParams requests a single page so callers control paging
+func stripeListParams(params *ListParams) stripe.ListParams {
+	listParams := stripe.ListParams{Single: true}
+	if params.Limit > 0 {
+		listParams.Limit = stripe.Int64(int64(params.Limit))
+	}
+	if params.StartingAfter != "" {
+		listParams.StartingAfter = stripe.String(params.StartingAfter)
+	}
+	return listParams
+}
+
+func stripeCreatedRange(params *ListParams) *stripe.RangeQueryParams {
+	return &stripe.RangeQueryParams{
+		GreaterThanOrEqual: params.CreatedFrom.Unix(),
+		LesserThan:         params.CreatedTo.Unix(),
+	}
+}
+
+func stripeListPage(it *stripe.Iter, object string, id func(interface{}) string) (*ListPage, error) {
+	page := &ListPage{}
+	for it.Next() {
+		page.IDs = append(page.IDs, id(it.Current()))
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("stripe: failed to list %s: %w", object, err)
+	}
+	if it.Meta().HasMore && len(page.IDs) > 0 {
+		page.NextCursor = page.IDs[len(page.IDs)-1]
+	}
+	return page, nil
+}
+
+// CreateCoupon creates a coupon in Stripe and returns its ID
+func (p *StripeProvider) CreateCoupon(ctx context.Context, req *CreateCouponRequest) (string, error) {
+	params := &stripe.CouponParams{
+		Duration: stripe.String(req.Duration),
+	}
+
+	if req.Name != "" {
+		params.Name = stripe.String(req.Name)
+	}
+	if req.PercentOff != nil {
+		params.PercentOff = stripe.Float64(*req.PercentOff)
+	}
+	if req.AmountOff != nil {
+		params.AmountOff = stripe.Int64(*req.AmountOff)
+		params.Currency = stripe.String(strings.ToLower(req.Currency))
+	}
+	if req.DurationInMonths != nil {
+		params.DurationInMonths = stripe.Int64(int64(*req.DurationInMonths))
+	}
+	if req.MaxRedemptions != nil {
+		params.MaxRedemptions = stripe.Int64(int64(*req.MaxRedemptions))
+	}
+	if req.RedeemBy != nil {
+		params.RedeemBy = stripe.Int64(req.RedeemBy.Unix())
+	}
+
+	for k, v := range req.Metadata {
+		params.AddMetadata(k, v)
+	}
+
+	c, err := coupon.New(params)
+	if err != nil {
+		return "", fmt.Errorf("stripe: failed to create coupon: %w", err)
+	}
+
+	return c.ID, nil
+}
+
+// DeleteCoupon deletes a coupon in Stripe. Existing discounts are kept.
+func (p *StripeProvider) DeleteCoupon(ctx context.Context, providerCouponID string) error {
+	if _, err := coupon.Del(providerCouponID, nil); err != nil {
+		return fmt.Errorf("stripe: failed to delete coupon: %w", err)
+	}
+
+	return nil
+}
+
 // mapStripeSubscription converts a Stripe Subscription to our Subscription model
 func mapStripeSubscription(sub *stripe.Subscription) *models.Subscription {
+	// Licensed items are billed per period; metered items bill usage. All
+	// items share the subscription's currency and interval.
+	first := sub.Items.Data[0]
+	var items []models.SubscriptionItem
+	var metered []models.MeteredPrice
+	for _, it := range sub.Items.Data {
+		if it.Price.Recurring.UsageType == stripe.PriceRecurringUsageTypeMetered {
+			itemID := it.ID
+			metered = append(metered, models.MeteredPrice{
+				Meter:          it.Price.Metadata[stripeMeterMetadataKey],
+				UnitAmount:     it.Price.UnitAmount,
+				Aggregation:    mapStripeAggregateUsage(it.Price.Recurring.AggregateUsage),
+				ProviderItemID: &itemID,
+			})
+		} else {
+			items = append(items, *mapStripeSubscriptionItem(it))
+		}
+	}
+
 	subscription := &models.Subscription{
 		Provider:               models.ProviderStripe,
 		ProviderSubscriptionID: sub.ID,
-		Amount:                 sub.Items.Data[0].Price.UnitAmount,
-		Currency:               models.Currency(strings.ToUpper(string(sub.Items.Data[0].Price.Currency))),
-		Interval:               string(sub.Items.Data[0].Price.Recurring.Interval),
-		IntervalCount:          int(sub.Items.Data[0].Price.Recurring.IntervalCount),
+		Amount:                 models.ItemsAmount(items),
+		Currency:               models.Currency(strings.ToUpper(string(first.Price.Currency))),
+		Interval:               string(first.Price.Recurring.Interval),
+		IntervalCount:          int(first.Price.Recurring.IntervalCount),
+		Items:                  items,
+		MeteredPrices:          metered,
 		Status:                 mapStripeSubscriptionStatus(string(sub.Status)),
 		CurrentPeriodStart:     time.Unix(sub.CurrentPeriodStart, 0),
 		CurrentPeriodEnd:       time.Unix(sub.CurrentPeriodEnd, 0),
@@ -345,14 +1353,61 @@ func mapStripeSubscription(sub *stripe.Subscription) *models.Subscription {
 		subscription.TrialEnd = &trialEnd
 	}
 
+	if sub.TrialSettings != nil && sub.TrialSettings.EndBehavior != nil {
+		switch behavior := models.TrialEndBehavior(sub.TrialSettings.EndBehavior.MissingPaymentMethod); behavior {
+		case models.TrialEndBehaviorCancel, models.TrialEndBehaviorPause:
+			subscription.TrialEndBehavior = &behavior
+		}
+	}
+
+	if sub.CancelAt != 0 {
+		cancelAt := time.Unix(sub.CancelAt, 0)
+		subscription.CancelAt = &cancelAt
+	}
+
 	if sub.CanceledAt != 0 {
 		canceledAt := time.Unix(sub.CanceledAt, 0)
 		subscription.CanceledAt = &canceledAt
 	}
 
+	// Stripe keeps the status unchanged while collection is paused
+	if sub.PauseCollection != nil && sub.PauseCollection.Behavior != "" {
+		behavior := models.PauseBehavior(sub.PauseCollection.Behavior)
+		subscription.PauseBehavior = &behavior
+		subscription.Status = models.SubscriptionStatusPaused
+
+		if sub.PauseCollection.ResumesAt != 0 {
+			resumesAt := time.Unix(sub.PauseCollection.ResumesAt, 0)
+			subscription.ResumesAt = &resumesAt
+		}
+	}
+
 	return subscription
 }
 
+// mapStripeSubscriptionItem converts a licensed Stripe subscription item to our model
+func mapStripeSubscriptionItem(item *stripe.SubscriptionItem) *models.SubscriptionItem {
+	itemID := item.ID
+	return &models.SubscriptionItem{
+		ProductName:    item.Price.Nickname,
+		UnitAmount:     item.Price.UnitAmount,
+		Quantity:       item.Quantity,
+		ProviderItemID: &itemID,
+	}
+}
+
+// mapStripeAggregateUsage maps Stripe's usage aggregation to ours
+func mapStripeAggregateUsage(aggregateUsage stripe.PriceRecurringAggregateUsage) models.UsageAggregation {
+	switch aggregateUsage {
+	case stripe.PriceRecurringAggregateUsageMax:
+		return models.UsageAggregationMax
+	case stripe.PriceRecurringAggregateUsageLastDuringPeriod, stripe.PriceRecurringAggregateUsageLastEver:
+		return models.UsageAggregationLast
+	default:
+		return models.UsageAggregationSum
+	}
+}
+
 // mapStripeSubscriptionStatus maps Stripe subscription status to our SubscriptionStatus
 func mapStripeSubscriptionStatus(stripeStatus string) models.SubscriptionStatus {
 	switch stripeStatus {
@@ -377,6 +1432,117 @@ func mapStripeSubscriptionStatus(stripeStatus string) models.SubscriptionStatus
 	}
 }
 
+// mapStripeInvoice converts a Stripe Invoice to our Invoice model
+func mapStripeInvoice(inv *stripe.Invoice) *models.Invoice {
+	invoice := &models.Invoice{
+		Provider:          models.ProviderStripe,
+		ProviderInvoiceID: inv.ID,
+		Status:            mapStripeInvoiceStatus(string(inv.Status)),
+		Currency:          models.Currency(strings.ToUpper(string(inv.Currency))),
+		Subtotal:          inv.Subtotal,
+		Tax:               inv.Tax,
+		Total:             inv.Total,
+		AmountDue:         inv.AmountDue,
+		AmountPaid:        inv.AmountPaid,
+		LineItems:         models.InvoiceLineItems{},
+		PeriodStart:       time.Unix(inv.PeriodStart, 0),
+		PeriodEnd:         time.Unix(inv.PeriodEnd, 0),
+	}
+
+	if inv.Number != "" {
+		number := inv.Number
+		invoice.Number = &number
+	}
+
+	if inv.BillingReason != "" {
+		billingReason := string(inv.BillingReason)
+		invoice.BillingReason = &billingReason
+	}
+
+	if inv.DueDate != 0 {
+		dueDate := time.Unix(inv.DueDate, 0)
+		invoice.DueDate = &dueDate
+	}
+
+	if inv.StatusTransitions != nil && inv.StatusTransitions.PaidAt != 0 {
+		paidAt := time.Unix(inv.StatusTransitions.PaidAt, 0)
+		invoice.PaidAt = &paidAt
+	}
+
+	if len(inv.TotalTaxAmounts) > 0 {
+		invoice.TaxBreakdown = mapStripeTaxAmounts(inv.TotalTaxAmounts)
+	}
+
+	if inv.Lines != nil {
+		for _, line := range inv.Lines.Data {
+			item := models.InvoiceLineItem{
+				Description: line.Description,
+				Quantity:    line.Quantity,
+				Amount:      line.Amount,
+			}
+			if line.Price != nil {
+				item.UnitAmount = line.Price.UnitAmount
+			}
+			if line.Period != nil {
+				periodStart := time.Unix(line.Period.Start, 0)
+				periodEnd := time.Unix(line.Period.End, 0)
+				item.PeriodStart = &periodStart
+				item.PeriodEnd = &periodEnd
+			}
+			invoice.LineItems = append(invoice.LineItems, item)
+		}
+	}
+
+	return invoice
+}
+
+// mapStripeTaxAmounts converts the tax amounts on a Stripe invoice to a tax breakdown
+func mapStripeTaxAmounts(amounts []*stripe.InvoiceTotalTaxAmount) *models.TaxBreakdown {
+	breakdown := &models.TaxBreakdown{
+		Behavior: models.TaxBehaviorExclusive,
+		Lines:    []models.TaxLine{},
+	}
+
+	for _, amount := range amounts {
+		if amount.Inclusive {
+			breakdown.Behavior = models.TaxBehaviorInclusive
+		}
+		if amount.TaxabilityReason == stripe.InvoiceTotalTaxAmountTaxabilityReasonReverseCharge {
+			breakdown.ReverseCharge = true
+		}
+
+		line := models.TaxLine{
+			Net: amount.TaxableAmount,
+			Tax: amount.Amount,
+		}
+		if amount.TaxRate != nil {
+			line.Rate = amount.TaxRate.Percentage
+			if breakdown.Country == "" {
+				breakdown.Country = amount.TaxRate.Country
+			}
+		}
+		breakdown.Lines = append(breakdown.Lines, line)
+	}
+
+	return breakdown
+}
+
+// mapStripeInvoiceStatus maps Stripe invoice status to our InvoiceStatus
+func mapStripeInvoiceStatus(stripeStatus string) models.InvoiceStatus {
+	switch stripeStatus {
+	case "open":
+		return models.InvoiceStatusOpen
+	case "paid":
+		return models.InvoiceStatusPaid
+	case "void":
+		return models.InvoiceStatusVoid
+	case "uncollectible":
+		return models.InvoiceStatusUncollectible
+	default:
+		return models.InvoiceStatusDraft
+	}
+}
+
 // mapStripeRefund converts a Stripe Refund to our Refund model
 func mapStripeRefund(ref *stripe.Refund) *models.Refund {
 	refund := &models.Refund{
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create appends an audit event
+func (r *AuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (
+			customer_id, actor_user_id, action,
+			resource_type, resource_id, details
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		) RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		event.CustomerID,
+		event.ActorUserID,
+		event.Action,
+		event.ResourceType,
+		event.ResourceID,
+		event.Details,
+	).Scan(&event.ID, &event.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+
+	return nil
+}
+
+// ListByCustomer retrieves audit events for a customer with pagination
+func (r *AuditRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.AuditEvent, int, error) {
+	// Get total count
+	var total int
+	countQuery := `SELECT COUNT(*) FROM audit_events WHERE customer_id = $1`
+	if err := r.db.QueryRowContext(ctx, countQuery, customerID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	// Get audit events
+	query := `
+		SELECT
+			id, customer_id, actor_user_id, action,
+			resource_type, resource_id, details, created_at
+		FROM audit_events
+		WHERE customer_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, customerID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.CustomerID,
+			&event.ActorUserID,
+			&event.Action,
+			&event.ResourceType,
+			&event.ResourceID,
+			&event.Details,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating audit events: %w", err)
+	}
+
+	return events, total, nil
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type CouponRepository struct {
+	db *sql.DB
+}
+
+func NewCouponRepository(db *sql.DB) *CouponRepository {
+	return &CouponRepository{db: db}
+}
+
+// Create creates a new coupon
+func (r *CouponRepository) Create(ctx context.Context, coupon *models.Coupon) error {
+	query := `
+		INSERT INTO coupons (
+			name, percent_off, amount_off, currency,
+			duration, duration_in_months, max_redemptions,
+			redeem_by, valid, stripe_coupon_id, metadata
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		) RETURNING id, times_redeemed, created_at, updated_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		coupon.Name,
+		coupon.PercentOff,
+		coupon.AmountOff,
+		coupon.Currency,
+		coupon.Duration,
+		coupon.DurationInMonths,
+		coupon.MaxRedemptions,
+		coupon.RedeemBy,
+		coupon.Valid,
+		coupon.StripeCouponID,
+		coupon.Metadata,
+	).Scan(&coupon.ID, &coupon.TimesRedeemed, &coupon.CreatedAt, &coupon.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create coupon: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a coupon by ID
+func (r *CouponRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Coupon, error) {
+	query := `
+		SELECT
+			id, name, percent_off, amount_off, currency,
+			duration, duration_in_months, max_redemptions,
+			times_redeemed, redeem_by, valid, stripe_coupon_id,
+			metadata, created_at, updated_at
+		FROM coupons
+		WHERE id = $1`
+
+	coupon := &models.Coupon{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&coupon.ID,
+		&coupon.Name,
+		&coupon.PercentOff,
+		&coupon.AmountOff,
+		&coupon.Currency,
+		&coupon.Duration,
+		&coupon.DurationInMonths,
+		&coupon.MaxRedemptions,
+		&coupon.TimesRedeemed,
+		&coupon.RedeemBy,
+		&coupon.Valid,
+		&coupon.StripeCouponID,
+		&coupon.Metadata,
+		&coupon.CreatedAt,
+		&coupon.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coupon: %w", err)
+	}
+
+	return coupon, nil
+}
+
+// List retrieves coupons with pagination, newest first
+func (r *CouponRepository) List(ctx context.Context, limit, offset int) ([]models.Coupon, int, error) {
+	// Get total count
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM coupons`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count coupons: %w", err)
+	}
+
+	// Get coupons
+	query := `
+		SELECT
+			id, name, percent_off, amount_off, currency,
+			duration, duration_in_months, max_redemptions,
+			times_redeemed, redeem_by, valid, stripe_coupon_id,
+			metadata, created_at, updated_at
+		FROM coupons
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list coupons: %w", err)
+	}
+	defer rows.Close()
+
+	coupons := []models.Coupon{}
+	for rows.Next() {
+		var coupon models.Coupon
+		err := rows.Scan(
+			&coupon.ID,
+			&coupon.Name,
+			&coupon.PercentOff,
+			&coupon.AmountOff,
+			&coupon.Currency,
+			&coupon.Duration,
+			&coupon.DurationInMonths,
+			&coupon.MaxRedemptions,
+			&coupon.TimesRedeemed,
+			&coupon.RedeemBy,
+			&coupon.Valid,
+			&coupon.StripeCouponID,
+			&coupon.Metadata,
+			&coupon.CreatedAt,
+			&coupon.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan coupon: %w", err)
+		}
+		coupons = append(coupons, coupon)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating coupons: %w", err)
+	}
+
+	return coupons, total, nil
+}
+
+// Update updates a coupon's validity and provider IDs
+func (r *CouponRepository) Update(ctx context.Context, coupon *models.Coupon) error {
+	query := `
+		UPDATE coupons SET
+			valid = $1,
+			stripe_coupon_id = $2,
+			metadata = $3
+		WHERE id = $4
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		coupon.Valid,
+		coupon.StripeCouponID,
+		coupon.Metadata,
+		coupon.ID,
+	).Scan(&coupon.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("coupon not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update coupon: %w", err)
+	}
+
+	return nil
+}
+
+// Reserve atomically counts a redemption against the coupon. It returns false
+// if the coupon is no longer valid, has expired or has reached its limit.
+func (r *CouponRepository) Reserve(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE coupons SET times_redeemed = times_redeemed + 1
+		WHERE id = $1
+		  AND valid
+		  AND (max_redemptions IS NULL OR times_redeemed < max_redemptions)
+		  AND (redeem_by IS NULL OR redeem_by > NOW())`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve coupon redemption: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// Release gives back a redemption reserved with Reserve
+func (r *CouponRepository) Release(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE coupons SET times_redeemed = GREATEST(times_redeemed - 1, 0) WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to release coupon redemption: %w", err)
+	}
+
+	return nil
+}
+
+// CreateRedemption records a completed redemption
+func (r *CouponRepository) CreateRedemption(ctx context.Context, redemption *models.DiscountRedemption) error {
+	query := `
+		INSERT INTO discount_redemptions (
+			coupon_id, promotion_code_id, customer_id,
+			payment_id, subscription_id, amount_off
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		) RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		redemption.CouponID,
+		redemption.PromotionCodeID,
+		redemption.CustomerID,
+		redemption.PaymentID,
+		redemption.SubscriptionID,
+		redemption.AmountOff,
+	).Scan(&redemption.ID, &redemption.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create discount redemption: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type CustomerBalanceRepository struct {
+	db *sql.DB
+}
+
+func NewCustomerBalanceRepository(db *sql.DB) *CustomerBalanceRepository {
+	return &CustomerBalanceRepository{db: db}
+}
+
+// Credit adds txn.Amount to the customer's balance and records the
+// transaction. Returns false if a transaction of the same type already exists
+// for the reference.
+func (r *CustomerBalanceRepository) Credit(ctx context.Context, txn *models.CustomerBalanceTransaction) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	balanceQuery := `
+		INSERT INTO customer_balances (customer_id, currency, balance)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (customer_id, currency)
+		DO UPDATE SET balance = customer_balances.balance + EXCLUDED.balance
+		RETURNING balance`
+
+	err = tx.QueryRowContext(ctx, balanceQuery, txn.CustomerID, txn.Currency, txn.Amount).Scan(&txn.BalanceAfter)
+	if err != nil {
+		return false, fmt.Errorf("failed to credit customer balance: %w", err)
+	}
+
+	inserted, err := insertBalanceTransaction(ctx, tx, txn)
+	if err != nil || !inserted {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit customer balance credit: %w", err)
+	}
+
+	return true, nil
+}
+
+// Debit takes up to maxAmount from the customer's balance in txn's currency
+// and records the transaction, setting txn.Amount to the negated amount taken.
+// The balance row is locked so concurrent debits never spend the same credit
+// twice. Takes nothing if the balance is empty or the reference was already
+// debited.
+func (r *CustomerBalanceRepository) Debit(ctx context.Context, txn *models.CustomerBalanceTransaction, maxAmount models.Money) error {
+	txn.Amount = 0
+	if maxAmount.Currency != txn.Currency {
+		return fmt.Errorf("failed to debit customer balance: %w: %s debit from %s balance", models.ErrCurrencyMismatch, maxAmount.Currency, txn.Currency)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var balance int64
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT balance FROM customer_balances WHERE customer_id = $1 AND currency = $2 FOR UPDATE`,
+		txn.CustomerID,
+		txn.Currency,
+	).Scan(&balance)
+
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get customer balance: %w", err)
+	}
+
+	applied := min(balance, maxAmount.Amount)
+	if applied <= 0 {
+		return nil
+	}
+
+	txn.Amount = -applied
+	txn.BalanceAfter = balance - applied
+
+	inserted, err := insertBalanceTransaction(ctx, tx, txn)
+	if err != nil || !inserted {
+		txn.Amount = 0
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE customer_balances SET balance = $3 WHERE customer_id = $1 AND currency = $2`,
+		txn.CustomerID,
+		txn.Currency,
+		txn.BalanceAfter,
+	)
+	if err != nil {
+		txn.Amount = 0
+		return fmt.Errorf("failed to debit customer balance: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		txn.Amount = 0
+		return fmt.Errorf("failed to commit customer balance debit: %w", err)
+	}
+
+	return nil
+}
+
+// SetReference points a transaction at what it paid for, once that exists
+func (r *CustomerBalanceRepository) SetReference(ctx context.Context, id uuid.UUID, referenceType string, referenceID uuid.UUID) error {
+	query := `
+		UPDATE customer_balance_transactions
+		SET reference_type = $2, reference_id = $3
+		WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, id, referenceType, referenceID)
+	if err != nil {
+		return fmt.Errorf("failed to set customer balance transaction reference: %w", err)
+	}
+
+	return nil
+}
+
+// ListBalances returns the customer's balance in every currency they have held
+// credit in
+func (r *CustomerBalanceRepository) ListBalances(ctx context.Context, customerID uuid.UUID) ([]models.CustomerBalance, error) {
+	query := `
+		SELECT currency, balance
+		FROM customer_balances
+		WHERE customer_id = $1
+		ORDER BY currency`
+
+	rows, err := r.db.QueryContext(ctx, query, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customer balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := []models.CustomerBalance{}
+	for rows.Next() {
+		var balance models.CustomerBalance
+		if err := rows.Scan(&balance.Currency, &balance.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan customer balance: %w", err)
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, rows.Err()
+}
+
+// ListTransactions returns the customer's balance history, newest first
+func (r *CustomerBalanceRepository) ListTransactions(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.CustomerBalanceTransaction, int, error) {
+	var total int
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM customer_balance_transactions WHERE customer_id = $1`,
+		customerID,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count customer balance transactions: %w", err)
+	}
+
+	query := `
+		SELECT id, customer_id, currency, amount, balance_after, type,
+			description, reference_type, reference_id, created_at
+		FROM customer_balance_transactions
+		WHERE customer_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, customerID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list customer balance transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.CustomerBalanceTransaction{}
+	for rows.Next() {
+		var txn models.CustomerBalanceTransaction
+		err := rows.Scan(
+			&txn.ID,
+			&txn.CustomerID,
+			&txn.Currency,
+			&txn.Amount,
+			&txn.BalanceAfter,
+			&txn.Type,
+			&txn.Description,
+			&txn.ReferenceType,
+			&txn.ReferenceID,
+			&txn.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan customer balance transaction: %w", err)
+		}
+		transactions = append(transactions, txn)
+	}
+
+	return transactions, total, rows.Err()
+}
+
+// insertBalanceTransaction records a balance change inside tx. Returns false
+// if a transaction of the same type already exists for the reference.
+func insertBalanceTransaction(ctx context.Context, tx *sql.Tx, txn *models.CustomerBalanceTransaction) (bool, error) {
+	query := `
+		INSERT INTO customer_balance_transactions (
+			customer_id, currency, amount, balance_after, type,
+			description, reference_type, reference_id
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at`
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		txn.CustomerID,
+		txn.Currency,
+		txn.Amount,
+		txn.BalanceAfter,
+		txn.Type,
+		txn.Description,
+		txn.ReferenceType,
+		txn.ReferenceID,
+	).Scan(&txn.ID, &txn.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create customer balance transaction: %w", err)
+	}
+
+	return true, nil
+}
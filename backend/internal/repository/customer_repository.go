@@ -20,8 +20,13 @@ func NewCustomerRepository(db *sql.DB) *CustomerRepository {
 // Create inserts a new customer
 func (r *CustomerRepository) Create(ctx context.Context, customer *models.Customer) error {
 	query := `
-		INSERT INTO customers (user_id, email, name, stripe_customer_id, swish_customer_id, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO customers (
+			user_id, email, name, stripe_customer_id, swish_customer_id,
+			address_line1, address_line2, postal_code, city, country,
+			company_name, org_number, vat_id, locale, invoice_email,
+			metadata
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -33,6 +38,16 @@ func (r *CustomerRepository) Create(ctx context.Context, customer *models.Custom
 		customer.Name,
 		customer.StripeCustomerID,
 		customer.SwishCustomerID,
+		customer.AddressLine1,
+		customer.AddressLine2,
+		customer.PostalCode,
+		customer.City,
+		customer.Country,
+		customer.CompanyName,
+		customer.OrgNumber,
+		customer.VATID,
+		customer.Locale,
+		customer.InvoiceEmail,
 		customer.Metadata,
 	).Scan(&customer.ID, &customer.CreatedAt, &customer.UpdatedAt)
 
@@ -47,6 +62,8 @@ func (r *CustomerRepository) Create(ctx context.Context, customer *models.Custom
 func (r *CustomerRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.Customer, error) {
 	query := `
 		SELECT id, user_id, email, name, stripe_customer_id, swish_customer_id,
+		       address_line1, address_line2, postal_code, city, country,
+		       company_name, org_number, vat_id, locale, invoice_email,
 		       metadata, created_at, updated_at, deleted_at
 		FROM customers
 		WHERE user_id = $1 AND deleted_at IS NULL
@@ -60,6 +77,16 @@ func (r *CustomerRepository) GetByUserID(ctx context.Context, userID uuid.UUID)
 		&customer.Name,
 		&customer.StripeCustomerID,
 		&customer.SwishCustomerID,
+		&customer.AddressLine1,
+		&customer.AddressLine2,
+		&customer.PostalCode,
+		&customer.City,
+		&customer.Country,
+		&customer.CompanyName,
+		&customer.OrgNumber,
+		&customer.VATID,
+		&customer.Locale,
+		&customer.InvoiceEmail,
 		&customer.Metadata,
 		&customer.CreatedAt,
 		&customer.UpdatedAt,
@@ -80,6 +107,8 @@ func (r *CustomerRepository) GetByUserID(ctx context.Context, userID uuid.UUID)
 func (r *CustomerRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Customer, error) {
 	query := `
 		SELECT id, user_id, email, name, stripe_customer_id, swish_customer_id,
+		       address_line1, address_line2, postal_code, city, country,
+		       company_name, org_number, vat_id, locale, invoice_email,
 		       metadata, created_at, updated_at, deleted_at
 		FROM customers
 		WHERE id = $1 AND deleted_at IS NULL
@@ -93,6 +122,16 @@ func (r *CustomerRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 		&customer.Name,
 		&customer.StripeCustomerID,
 		&customer.SwishCustomerID,
+		&customer.AddressLine1,
+		&customer.AddressLine2,
+		&customer.PostalCode,
+		&customer.City,
+		&customer.Country,
+		&customer.CompanyName,
+		&customer.OrgNumber,
+		&customer.VATID,
+		&customer.Locale,
+		&customer.InvoiceEmail,
 		&customer.Metadata,
 		&customer.CreatedAt,
 		&customer.UpdatedAt,
@@ -114,7 +153,10 @@ func (r *CustomerRepository) Update(ctx context.Context, customer *models.Custom
 	query := `
 		UPDATE customers
 		SET email = $2, name = $3, stripe_customer_id = $4,
-		    swish_customer_id = $5, metadata = $6
+		    swish_customer_id = $5, address_line1 = $6, address_line2 = $7,
+		    postal_code = $8, city = $9, country = $10, company_name = $11,
+		    org_number = $12, vat_id = $13, locale = $14, invoice_email = $15,
+		    metadata = $16
 		WHERE id = $1
 		RETURNING updated_at
 	`
@@ -127,6 +169,16 @@ func (r *CustomerRepository) Update(ctx context.Context, customer *models.Custom
 		customer.Name,
 		customer.StripeCustomerID,
 		customer.SwishCustomerID,
+		customer.AddressLine1,
+		customer.AddressLine2,
+		customer.PostalCode,
+		customer.City,
+		customer.Country,
+		customer.CompanyName,
+		customer.OrgNumber,
+		customer.VATID,
+		customer.Locale,
+		customer.InvoiceEmail,
 		customer.Metadata,
 	).Scan(&customer.UpdatedAt)
 
@@ -136,3 +188,41 @@ func (r *CustomerRepository) Update(ctx context.Context, customer *models.Custom
 
 	return nil
 }
+
+// Erase pseudonymizes a customer's personal data and soft-deletes the
+// customer. Payments, invoices and receipts keep referencing the row.
+func (r *CustomerRepository) Erase(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE customers
+		SET email = 'erased-' || id || '@invalid', name = '',
+		    stripe_customer_id = NULL, swish_customer_id = NULL,
+		    address_line1 = NULL, address_line2 = NULL, postal_code = NULL,
+		    city = NULL, country = NULL, company_name = NULL, org_number = NULL,
+		    vat_id = NULL, locale = NULL, invoice_email = NULL,
+		    metadata = '{}', deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to erase customer: %w", err)
+	}
+
+	return nil
+}
+
+// HasTransactions reports whether the customer has any succeeded payment or
+// any subscription
+func (r *CustomerRepository) HasTransactions(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM payments WHERE customer_id = $1 AND status = 'succeeded')
+			OR EXISTS (SELECT 1 FROM subscriptions WHERE customer_id = $1)
+	`
+
+	var hasTransactions bool
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&hasTransactions); err != nil {
+		return false, fmt.Errorf("failed to check customer transactions: %w", err)
+	}
+
+	return hasTransactions, nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type DisputeRepository struct {
+	db *sql.DB
+}
+
+func NewDisputeRepository(db *sql.DB) *DisputeRepository {
+	return &DisputeRepository{db: db}
+}
+
+// Create inserts a new dispute
+func (r *DisputeRepository) Create(ctx context.Context, dispute *models.Dispute) error {
+	query := `
+		INSERT INTO disputes (
+			payment_id, customer_id, provider, provider_dispute_id, amount, currency,
+			status, reason, evidence_due_by, evidence, evidence_submitted_at, closed_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		dispute.PaymentID,
+		dispute.CustomerID,
+		dispute.Provider,
+		dispute.ProviderDisputeID,
+		dispute.Amount,
+		dispute.Currency,
+		dispute.Status,
+		dispute.Reason,
+		dispute.EvidenceDueBy,
+		dispute.Evidence,
+		dispute.EvidenceSubmittedAt,
+		dispute.ClosedAt,
+	).Scan(&dispute.ID, &dispute.CreatedAt, &dispute.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create dispute: %w", err)
+	}
+
+	return nil
+}
+
+// Update updates a dispute
+func (r *DisputeRepository) Update(ctx context.Context, dispute *models.Dispute) error {
+	query := `
+		UPDATE disputes
+		SET amount = $2, currency = $3, status = $4, reason = $5, evidence_due_by = $6,
+		    evidence = $7, evidence_submitted_at = $8, closed_at = $9
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		dispute.ID,
+		dispute.Amount,
+		dispute.Currency,
+		dispute.Status,
+		dispute.Reason,
+		dispute.EvidenceDueBy,
+		dispute.Evidence,
+		dispute.EvidenceSubmittedAt,
+		dispute.ClosedAt,
+	).Scan(&dispute.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to update dispute: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a dispute by ID
+func (r *DisputeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	query := `
+		SELECT id, payment_id, customer_id, provider, provider_dispute_id, amount, currency,
+		       status, reason, evidence_due_by, evidence, evidence_submitted_at,
+		       created_at, updated_at, closed_at
+		FROM disputes
+		WHERE id = $1
+	`
+
+	return r.get(ctx, query, id)
+}
+
+// GetByProviderDisputeID retrieves a dispute by provider dispute ID
+func (r *DisputeRepository) GetByProviderDisputeID(ctx context.Context, provider models.Provider, providerDisputeID string) (*models.Dispute, error) {
+	query := `
+		SELECT id, payment_id, customer_id, provider, provider_dispute_id, amount, currency,
+		       status, reason, evidence_due_by, evidence, evidence_submitted_at,
+		       created_at, updated_at, closed_at
+		FROM disputes
+		WHERE provider = $1 AND provider_dispute_id = $2
+	`
+
+	return r.get(ctx, query, provider, providerDisputeID)
+}
+
+func (r *DisputeRepository) get(ctx context.Context, query string, args ...any) (*models.Dispute, error) {
+	dispute := &models.Dispute{}
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&dispute.ID,
+		&dispute.PaymentID,
+		&dispute.CustomerID,
+		&dispute.Provider,
+		&dispute.ProviderDisputeID,
+		&dispute.Amount,
+		&dispute.Currency,
+		&dispute.Status,
+		&dispute.Reason,
+		&dispute.EvidenceDueBy,
+		&dispute.Evidence,
+		&dispute.EvidenceSubmittedAt,
+		&dispute.CreatedAt,
+		&dispute.UpdatedAt,
+		&dispute.ClosedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+
+	return dispute, nil
+}
+
+// List returns disputes, newest first
+func (r *DisputeRepository) List(ctx context.Context, filter models.DisputeFilter) ([]models.Dispute, int, error) {
+	// Get total count
+	var total int
+	countQuery := `SELECT COUNT(*) FROM disputes WHERE ($1 = '' OR status = $1)`
+	if err := r.db.QueryRowContext(ctx, countQuery, string(filter.Status)).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count disputes: %w", err)
+	}
+
+	// Get disputes
+	query := `
+		SELECT id, payment_id, customer_id, provider, provider_dispute_id, amount, currency,
+		       status, reason, evidence_due_by, evidence, evidence_submitted_at,
+		       created_at, updated_at, closed_at
+		FROM disputes
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, string(filter.Status), filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list disputes: %w", err)
+	}
+	defer rows.Close()
+
+	disputes := []models.Dispute{}
+	for rows.Next() {
+		var dispute models.Dispute
+		err := rows.Scan(
+			&dispute.ID,
+			&dispute.PaymentID,
+			&dispute.CustomerID,
+			&dispute.Provider,
+			&dispute.ProviderDisputeID,
+			&dispute.Amount,
+			&dispute.Currency,
+			&dispute.Status,
+			&dispute.Reason,
+			&dispute.EvidenceDueBy,
+			&dispute.Evidence,
+			&dispute.EvidenceSubmittedAt,
+			&dispute.CreatedAt,
+			&dispute.UpdatedAt,
+			&dispute.ClosedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan dispute: %w", err)
+		}
+		disputes = append(disputes, dispute)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating disputes: %w", err)
+	}
+
+	return disputes, total, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type EventRepository struct {
+	db *sql.DB
+}
+
+func NewEventRepository(db *sql.DB) *EventRepository {
+	return &EventRepository{db: db}
+}
+
+// Create appends an event
+func (r *EventRepository) Create(ctx context.Context, event *models.Event) error {
+	query := `
+		INSERT INTO events (
+			customer_id, type, resource_type, resource_id, data
+		) VALUES (
+			$1, $2, $3, $4, $5
+		) RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		event.CustomerID,
+		event.Type,
+		event.ResourceType,
+		event.ResourceID,
+		event.Data,
+	).Scan(&event.ID, &event.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+
+	return nil
+}
+
+// ListByCustomer retrieves events for a customer with pagination, newest first
+func (r *EventRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Event, int, error) {
+	// Get total count
+	var total int
+	countQuery := `SELECT COUNT(*) FROM events WHERE customer_id = $1`
+	if err := r.db.QueryRowContext(ctx, countQuery, customerID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	// Get events
+	query := `
+		SELECT
+			id, customer_id, type, resource_type,
+			resource_id, data, created_at
+		FROM events
+		WHERE customer_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, customerID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.Event{}
+	for rows.Next() {
+		var event models.Event
+		err := rows.Scan(
+			&event.ID,
+			&event.CustomerID,
+			&event.Type,
+			&event.ResourceType,
+			&event.ResourceID,
+			&event.Data,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating events: %w", err)
+	}
+
+	return events, total, nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDB is a database/sql driver that records the statements it is sent and
+// answers them with canned rows, in order. It lets a test check the SQL and
+// arguments a repository sends and how it scans the results, without
+// Postgres.
+type fakeDB struct {
+	mu       sync.Mutex
+	results  []fakeResult
+	executed []fakeStatement
+}
+
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error
+}
+
+type fakeStatement struct {
+	query string
+	args  []driver.Value
+}
+
+var fakeDBs sync.Map
+var fakeDBCount atomic.Int64
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+}
+
+// newFakeDB returns a *sql.DB backed by a fakeDB that answers with results
+func newFakeDB(t *testing.T, results ...fakeResult) (*sql.DB, *fakeDB) {
+	t.Helper()
+
+	fake := &fakeDB{results: results}
+	name := fmt.Sprintf("%s-%d", t.Name(), fakeDBCount.Add(1))
+	fakeDBs.Store(name, fake)
+
+	db, err := sql.Open("fakedb", name)
+	if err != nil {
+		t.Fatalf("failed to open fake database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		fakeDBs.Delete(name)
+	})
+	return db, fake
+}
+
+// statements returns the statements sent so far
+func (f *fakeDB) statements() []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeStatement(nil), f.executed...)
+}
+
+func (f *fakeDB) next(query string, args []driver.NamedValue) (fakeResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.executed = append(f.executed, fakeStatement{query: query, args: values})
+
+	if len(f.results) == 0 {
+		return fakeResult{}, fmt.Errorf("fakedb: unexpected statement %q", query)
+	}
+	result := f.results[0]
+	f.results = f.results[1:]
+	return result, result.err
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fake, ok := fakeDBs.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("fakedb: unknown database %q", name)
+	}
+	return &fakeConn{db: fake.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fakedb: prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.db.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: result.columns, rows: result.rows}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.db.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(result.rows)), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"payment-service/internal/models"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -14,6 +15,11 @@ type PaymentRepositoryInterface interface {
 	GetByProviderPaymentID(ctx context.Context, provider models.Provider, providerPaymentID string) (*models.Payment, error)
 	ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Payment, int, error)
 	Update(ctx context.Context, payment *models.Payment) error
+	UpdateSettlement(ctx context.Context, payment *models.Payment) error
+	ListProductMargins(ctx context.Context, from, to time.Time) ([]models.ProductMargin, error)
+	ReserveRefund(ctx context.Context, payment *models.Payment, amount models.Money) (bool, error)
+	ReleaseRefund(ctx context.Context, payment *models.Payment, amount models.Money) error
+	MarkDisputed(ctx context.Context, payment *models.Payment, disputedAt time.Time) error
 }
 
 // CustomerRepositoryInterface defines the interface for customer repository operations
@@ -22,6 +28,8 @@ type CustomerRepositoryInterface interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Customer, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID) (*models.Customer, error)
 	Update(ctx context.Context, customer *models.Customer) error
+	HasTransactions(ctx context.Context, id uuid.UUID) (bool, error)
+	Erase(ctx context.Context, id uuid.UUID) error
 }
 
 // SubscriptionRepositoryInterface defines the interface for subscription repository operations
@@ -31,6 +39,13 @@ type SubscriptionRepositoryInterface interface {
 	GetByProviderSubscriptionID(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error)
 	ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Subscription, int, error)
 	Update(ctx context.Context, subscription *models.Subscription) error
+	SaveItems(ctx context.Context, subscription *models.Subscription) error
+	ListDunningDue(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error)
+	ClaimDunningRetry(ctx context.Context, id uuid.UUID, nextRetryAt time.Time) (bool, error)
+	ClaimDunningExhaustion(ctx context.Context, id uuid.UUID, now time.Time) (bool, error)
+	ListBillingDue(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error)
+	ClaimBilling(ctx context.Context, id uuid.UUID, nextBillingAt, leaseUntil time.Time) (bool, error)
+	SaveBillingRun(ctx context.Context, subscription *models.Subscription, status models.SubscriptionStatus, leaseUntil time.Time) (bool, error)
 }
 
 // RefundRepositoryInterface defines the interface for refund repository operations
@@ -40,7 +55,10 @@ type RefundRepositoryInterface interface {
 	GetByProviderRefundID(ctx context.Context, providerRefundID string) (*models.Refund, error)
 	ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]models.Refund, error)
 	ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Refund, int, error)
+	ListByStatus(ctx context.Context, status models.RefundStatus, limit, offset int) ([]models.Refund, int, error)
 	Update(ctx context.Context, refund *models.Refund) error
+	Review(ctx context.Context, refund *models.Refund) (bool, error)
+	UpdateSettlement(ctx context.Context, refund *models.Refund) error
 }
 
 // WebhookRepositoryInterface defines the interface for webhook repository operations
@@ -49,3 +67,136 @@ type WebhookRepositoryInterface interface {
 	GetByProviderEventID(ctx context.Context, provider models.Provider, providerEventID string) (*WebhookEvent, error)
 	MarkProcessed(ctx context.Context, id uuid.UUID, processingError *string) error
 }
+
+// AuditRepositoryInterface defines the interface for audit log operations
+type AuditRepositoryInterface interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.AuditEvent, int, error)
+}
+
+// CouponRepositoryInterface defines the interface for coupon repository operations
+type CouponRepositoryInterface interface {
+	Create(ctx context.Context, coupon *models.Coupon) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Coupon, error)
+	List(ctx context.Context, limit, offset int) ([]models.Coupon, int, error)
+	Update(ctx context.Context, coupon *models.Coupon) error
+	Reserve(ctx context.Context, id uuid.UUID) (bool, error)
+	Release(ctx context.Context, id uuid.UUID) error
+	CreateRedemption(ctx context.Context, redemption *models.DiscountRedemption) error
+}
+
+// PromotionCodeRepositoryInterface defines the interface for promotion code repository operations
+type PromotionCodeRepositoryInterface interface {
+	Create(ctx context.Context, code *models.PromotionCode) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.PromotionCode, error)
+	GetByCode(ctx context.Context, code string) (*models.PromotionCode, error)
+	ListByCoupon(ctx context.Context, couponID uuid.UUID) ([]models.PromotionCode, error)
+	Update(ctx context.Context, code *models.PromotionCode) error
+	Reserve(ctx context.Context, id uuid.UUID) (bool, error)
+	Release(ctx context.Context, id uuid.UUID) error
+}
+
+// EventRepositoryInterface defines the interface for event repository operations
+type EventRepositoryInterface interface {
+	Create(ctx context.Context, event *models.Event) error
+	ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Event, int, error)
+}
+
+// InvoiceRepositoryInterface defines the interface for invoice repository operations
+type InvoiceRepositoryInterface interface {
+	Create(ctx context.Context, invoice *models.Invoice) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Invoice, error)
+	GetByProviderInvoiceID(ctx context.Context, provider models.Provider, providerInvoiceID string) (*models.Invoice, error)
+	ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Invoice, int, error)
+	ListBySubscription(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]models.Invoice, int, error)
+	Update(ctx context.Context, invoice *models.Invoice) error
+}
+
+// ReceiptRepositoryInterface defines the interface for receipt repository operations
+type ReceiptRepositoryInterface interface {
+	Create(ctx context.Context, receipt *models.Receipt, numberPrefix string) (bool, error)
+	GetByPaymentID(ctx context.Context, paymentID uuid.UUID) (*models.Receipt, error)
+	UpdatePDF(ctx context.Context, id uuid.UUID, pdf []byte) error
+}
+
+// LedgerRepositoryInterface defines the interface for ledger repository operations
+type LedgerRepositoryInterface interface {
+	CreateEntry(ctx context.Context, entry *models.LedgerEntry) (bool, error)
+	ListBalances(ctx context.Context, filter models.LedgerBalanceFilter) ([]models.LedgerBalance, error)
+}
+
+// ReconciliationRepositoryInterface defines the interface for reconciliation repository operations
+type ReconciliationRepositoryInterface interface {
+	RecordDiscrepancy(ctx context.Context, discrepancy *models.ReconciliationDiscrepancy) error
+	ResolveObject(ctx context.Context, objectType models.ReconciliationObjectType, providerObjectID string, stillOpen []models.DiscrepancyKind) error
+	Resolve(ctx context.Context, id uuid.UUID) (bool, error)
+	List(ctx context.Context, filter models.DiscrepancyFilter) ([]models.ReconciliationDiscrepancy, int, error)
+	CountOpen(ctx context.Context) (map[models.ReconciliationObjectType]int, error)
+}
+
+// PayoutRepositoryInterface defines the interface for payout repository operations
+type PayoutRepositoryInterface interface {
+	Upsert(ctx context.Context, payout *models.Payout) error
+	ReplaceTransactions(ctx context.Context, payoutID uuid.UUID, transactions []models.PayoutTransaction) error
+	SetReconciled(ctx context.Context, payout *models.Payout, reconciled bool) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Payout, error)
+	List(ctx context.Context, limit, offset int) ([]models.Payout, int, error)
+	ListTransactions(ctx context.Context, payoutID uuid.UUID) ([]models.PayoutTransaction, error)
+}
+
+// DisputeRepositoryInterface defines the interface for dispute repository operations
+type DisputeRepositoryInterface interface {
+	Create(ctx context.Context, dispute *models.Dispute) error
+	Update(ctx context.Context, dispute *models.Dispute) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Dispute, error)
+	GetByProviderDisputeID(ctx context.Context, provider models.Provider, providerDisputeID string) (*models.Dispute, error)
+	List(ctx context.Context, filter models.DisputeFilter) ([]models.Dispute, int, error)
+}
+
+// CustomerBalanceRepositoryInterface defines the interface for customer balance repository operations
+type CustomerBalanceRepositoryInterface interface {
+	Credit(ctx context.Context, txn *models.CustomerBalanceTransaction) (bool, error)
+	Debit(ctx context.Context, txn *models.CustomerBalanceTransaction, maxAmount models.Money) error
+	SetReference(ctx context.Context, id uuid.UUID, referenceType string, referenceID uuid.UUID) error
+	ListBalances(ctx context.Context, customerID uuid.UUID) ([]models.CustomerBalance, error)
+	ListTransactions(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.CustomerBalanceTransaction, int, error)
+}
+
+// WalletRepositoryInterface defines the interface for wallet repository operations
+type WalletRepositoryInterface interface {
+	Get(ctx context.Context, customerID uuid.UUID, currency models.Currency) (*models.Wallet, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
+	ListByCustomer(ctx context.Context, customerID uuid.UUID) ([]models.Wallet, error)
+	Credit(ctx context.Context, wallet *models.Wallet, txn *models.WalletTransaction, grant *models.WalletGrant) (bool, error)
+	Debit(ctx context.Context, txn *models.WalletTransaction) (bool, error)
+	CreateHold(ctx context.Context, hold *models.WalletHold) (bool, error)
+	GetHold(ctx context.Context, id uuid.UUID) (*models.WalletHold, error)
+	CaptureHold(ctx context.Context, hold *models.WalletHold, amount int64, txn *models.WalletTransaction) (bool, error)
+	ReleaseHold(ctx context.Context, hold *models.WalletHold) (bool, error)
+	ListTransactions(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.WalletTransaction, int, error)
+	ListExpiredGrants(ctx context.Context, now time.Time, limit int) ([]models.WalletGrant, error)
+	ExpireGrant(ctx context.Context, grant *models.WalletGrant, txn *models.WalletTransaction) (bool, error)
+}
+
+// UsageRepositoryInterface defines the interface for usage repository operations
+type UsageRepositoryInterface interface {
+	CreateRecord(ctx context.Context, record *models.UsageRecord) (bool, error)
+	MarkReported(ctx context.Context, id uuid.UUID, reportedAt time.Time) error
+	ListUnreported(ctx context.Context, before time.Time, limit int) ([]models.UsageRecord, error)
+	Summarize(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time) ([]models.UsageSummary, error)
+	ListUninvoicedPeriods(ctx context.Context, now time.Time, limit int) ([]models.UsagePeriod, error)
+	ClaimUninvoiced(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time, invoiceKey string) error
+	ListPendingInvoiceKeys(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time) ([]string, error)
+	SummarizeClaim(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time, invoiceKey string) ([]models.UsageSummary, error)
+	MarkInvoiced(ctx context.Context, invoiceKey string, invoiceID uuid.UUID) error
+}
+
+// SubscriptionScheduleRepositoryInterface defines the interface for subscription schedule repository operations
+type SubscriptionScheduleRepositoryInterface interface {
+	Create(ctx context.Context, schedule *models.SubscriptionSchedule) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.SubscriptionSchedule, error)
+	ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.SubscriptionSchedule, int, error)
+	ListDue(ctx context.Context, now time.Time, limit int) ([]models.SubscriptionSchedule, error)
+	ClaimTransition(ctx context.Context, id uuid.UUID, transitionAt time.Time) (bool, error)
+	Update(ctx context.Context, schedule *models.SubscriptionSchedule) error
+}
@@ -0,0 +1,354 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type InvoiceRepository struct {
+	db *sql.DB
+}
+
+func NewInvoiceRepository(db *sql.DB) *InvoiceRepository {
+	return &InvoiceRepository{db: db}
+}
+
+// Create creates a new invoice
+func (r *InvoiceRepository) Create(ctx context.Context, invoice *models.Invoice) error {
+	query := `
+		INSERT INTO invoices (
+			customer_id, subscription_id, payment_id,
+			provider, provider_invoice_id, number, status, billing_reason,
+			currency, subtotal, tax, total, amount_due, amount_paid, tax_breakdown,
+			line_items, period_start, period_end, due_date, paid_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20
+		) RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		invoice.CustomerID,
+		invoice.SubscriptionID,
+		invoice.PaymentID,
+		invoice.Provider,
+		invoice.ProviderInvoiceID,
+		invoice.Number,
+		invoice.Status,
+		invoice.BillingReason,
+		invoice.Currency,
+		invoice.Subtotal,
+		invoice.Tax,
+		invoice.Total,
+		invoice.AmountDue,
+		invoice.AmountPaid,
+		invoice.TaxBreakdown,
+		invoice.LineItems,
+		invoice.PeriodStart,
+		invoice.PeriodEnd,
+		invoice.DueDate,
+		invoice.PaidAt,
+	).Scan(&invoice.ID, &invoice.CreatedAt, &invoice.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create invoice: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an invoice by ID
+func (r *InvoiceRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Invoice, error) {
+	query := `
+		SELECT
+			id, customer_id, subscription_id, payment_id,
+			provider, provider_invoice_id, number, status, billing_reason,
+			currency, subtotal, tax, total, amount_due, amount_paid, tax_breakdown,
+			line_items, period_start, period_end,
+			due_date, paid_at, created_at, updated_at
+		FROM invoices
+		WHERE id = $1`
+
+	invoice := &models.Invoice{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&invoice.ID,
+		&invoice.CustomerID,
+		&invoice.SubscriptionID,
+		&invoice.PaymentID,
+		&invoice.Provider,
+		&invoice.ProviderInvoiceID,
+		&invoice.Number,
+		&invoice.Status,
+		&invoice.BillingReason,
+		&invoice.Currency,
+		&invoice.Subtotal,
+		&invoice.Tax,
+		&invoice.Total,
+		&invoice.AmountDue,
+		&invoice.AmountPaid,
+		&invoice.TaxBreakdown,
+		&invoice.LineItems,
+		&invoice.PeriodStart,
+		&invoice.PeriodEnd,
+		&invoice.DueDate,
+		&invoice.PaidAt,
+		&invoice.CreatedAt,
+		&invoice.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+
+	return invoice, nil
+}
+
+// GetByProviderInvoiceID retrieves an invoice by provider invoice ID
+func (r *InvoiceRepository) GetByProviderInvoiceID(ctx context.Context, provider models.Provider, providerInvoiceID string) (*models.Invoice, error) {
+	query := `
+		SELECT
+			id, customer_id, subscription_id, payment_id,
+			provider, provider_invoice_id, number, status, billing_reason,
+			currency, subtotal, tax, total, amount_due, amount_paid, tax_breakdown,
+			line_items, period_start, period_end,
+			due_date, paid_at, created_at, updated_at
+		FROM invoices
+		WHERE provider = $1 AND provider_invoice_id = $2`
+
+	invoice := &models.Invoice{}
+	err := r.db.QueryRowContext(ctx, query, provider, providerInvoiceID).Scan(
+		&invoice.ID,
+		&invoice.CustomerID,
+		&invoice.SubscriptionID,
+		&invoice.PaymentID,
+		&invoice.Provider,
+		&invoice.ProviderInvoiceID,
+		&invoice.Number,
+		&invoice.Status,
+		&invoice.BillingReason,
+		&invoice.Currency,
+		&invoice.Subtotal,
+		&invoice.Tax,
+		&invoice.Total,
+		&invoice.AmountDue,
+		&invoice.AmountPaid,
+		&invoice.TaxBreakdown,
+		&invoice.LineItems,
+		&invoice.PeriodStart,
+		&invoice.PeriodEnd,
+		&invoice.DueDate,
+		&invoice.PaidAt,
+		&invoice.CreatedAt,
+		&invoice.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice by provider ID: %w", err)
+	}
+
+	return invoice, nil
+}
+
+// ListByCustomer retrieves invoices for a customer with pagination, newest period first
+func (r *InvoiceRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Invoice, int, error) {
+	// Get total count
+	var total int
+	countQuery := `SELECT COUNT(*) FROM invoices WHERE customer_id = $1`
+	if err := r.db.QueryRowContext(ctx, countQuery, customerID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count invoices: %w", err)
+	}
+
+	// Get invoices
+	query := `
+		SELECT
+			id, customer_id, subscription_id, payment_id,
+			provider, provider_invoice_id, number, status, billing_reason,
+			currency, subtotal, tax, total, amount_due, amount_paid, tax_breakdown,
+			line_items, period_start, period_end,
+			due_date, paid_at, created_at, updated_at
+		FROM invoices
+		WHERE customer_id = $1
+		ORDER BY period_start DESC, created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, customerID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list invoices: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []models.Invoice{}
+	for rows.Next() {
+		var invoice models.Invoice
+		err := rows.Scan(
+			&invoice.ID,
+			&invoice.CustomerID,
+			&invoice.SubscriptionID,
+			&invoice.PaymentID,
+			&invoice.Provider,
+			&invoice.ProviderInvoiceID,
+			&invoice.Number,
+			&invoice.Status,
+			&invoice.BillingReason,
+			&invoice.Currency,
+			&invoice.Subtotal,
+			&invoice.Tax,
+			&invoice.Total,
+			&invoice.AmountDue,
+			&invoice.AmountPaid,
+			&invoice.TaxBreakdown,
+			&invoice.LineItems,
+			&invoice.PeriodStart,
+			&invoice.PeriodEnd,
+			&invoice.DueDate,
+			&invoice.PaidAt,
+			&invoice.CreatedAt,
+			&invoice.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		invoices = append(invoices, invoice)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating invoices: %w", err)
+	}
+
+	return invoices, total, nil
+}
+
+// ListBySubscription retrieves invoices for a subscription with pagination, newest period first
+func (r *InvoiceRepository) ListBySubscription(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]models.Invoice, int, error) {
+	// Get total count
+	var total int
+	countQuery := `SELECT COUNT(*) FROM invoices WHERE subscription_id = $1`
+	if err := r.db.QueryRowContext(ctx, countQuery, subscriptionID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count invoices: %w", err)
+	}
+
+	// Get invoices
+	query := `
+		SELECT
+			id, customer_id, subscription_id, payment_id,
+			provider, provider_invoice_id, number, status, billing_reason,
+			currency, subtotal, tax, total, amount_due, amount_paid, tax_breakdown,
+			line_items, period_start, period_end,
+			due_date, paid_at, created_at, updated_at
+		FROM invoices
+		WHERE subscription_id = $1
+		ORDER BY period_start DESC, created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list invoices by subscription: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []models.Invoice{}
+	for rows.Next() {
+		var invoice models.Invoice
+		err := rows.Scan(
+			&invoice.ID,
+			&invoice.CustomerID,
+			&invoice.SubscriptionID,
+			&invoice.PaymentID,
+			&invoice.Provider,
+			&invoice.ProviderInvoiceID,
+			&invoice.Number,
+			&invoice.Status,
+			&invoice.BillingReason,
+			&invoice.Currency,
+			&invoice.Subtotal,
+			&invoice.Tax,
+			&invoice.Total,
+			&invoice.AmountDue,
+			&invoice.AmountPaid,
+			&invoice.TaxBreakdown,
+			&invoice.LineItems,
+			&invoice.PeriodStart,
+			&invoice.PeriodEnd,
+			&invoice.DueDate,
+			&invoice.PaidAt,
+			&invoice.CreatedAt,
+			&invoice.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		invoices = append(invoices, invoice)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating invoices: %w", err)
+	}
+
+	return invoices, total, nil
+}
+
+// Update updates an invoice from its provider state
+func (r *InvoiceRepository) Update(ctx context.Context, invoice *models.Invoice) error {
+	query := `
+		UPDATE invoices SET
+			subscription_id = $1,
+			payment_id = $2,
+			number = $3,
+			status = $4,
+			billing_reason = $5,
+			subtotal = $6,
+			tax = $7,
+			total = $8,
+			amount_due = $9,
+			amount_paid = $10,
+			tax_breakdown = $11,
+			line_items = $12,
+			period_start = $13,
+			period_end = $14,
+			due_date = $15,
+			paid_at = $16,
+			updated_at = NOW()
+		WHERE id = $17
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		invoice.SubscriptionID,
+		invoice.PaymentID,
+		invoice.Number,
+		invoice.Status,
+		invoice.BillingReason,
+		invoice.Subtotal,
+		invoice.Tax,
+		invoice.Total,
+		invoice.AmountDue,
+		invoice.AmountPaid,
+		invoice.TaxBreakdown,
+		invoice.LineItems,
+		invoice.PeriodStart,
+		invoice.PeriodEnd,
+		invoice.DueDate,
+		invoice.PaidAt,
+		invoice.ID,
+	).Scan(&invoice.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("invoice not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update invoice: %w", err)
+	}
+
+	return nil
+}
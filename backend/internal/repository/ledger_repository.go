@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+)
+
+type LedgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// CreateEntry inserts a journal entry and its postings in one transaction,
+// creating accounts on first use. Returns false if an entry of the same type
+// already exists for the reference.
+func (r *LedgerRepository) CreateEntry(ctx context.Context, entry *models.LedgerEntry) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO ledger_entries (
+			tenant, type, currency, customer_id,
+			reference_type, reference_id, description
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)
+		ON CONFLICT (tenant, type, reference_type, reference_id) DO NOTHING
+		RETURNING id, created_at`
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		entry.Tenant,
+		entry.Type,
+		entry.Currency,
+		entry.CustomerID,
+		entry.ReferenceType,
+		entry.ReferenceID,
+		entry.Description,
+	).Scan(&entry.ID, &entry.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create ledger entry: %w", err)
+	}
+
+	accountQuery := `
+		INSERT INTO ledger_accounts (tenant, code, type, currency)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant, code, currency) DO UPDATE SET type = EXCLUDED.type
+		RETURNING id`
+
+	postingQuery := `
+		INSERT INTO ledger_postings (entry_id, account_id, amount)
+		VALUES ($1, $2, $3)
+		RETURNING id`
+
+	for i := range entry.Postings {
+		posting := &entry.Postings[i]
+
+		var accountID string
+		err := tx.QueryRowContext(
+			ctx,
+			accountQuery,
+			entry.Tenant,
+			posting.AccountCode,
+			posting.AccountCode.Type(),
+			entry.Currency,
+		).Scan(&accountID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get ledger account %s: %w", posting.AccountCode, err)
+		}
+
+		posting.EntryID = entry.ID
+		if err := tx.QueryRowContext(ctx, postingQuery, entry.ID, accountID, posting.Amount).Scan(&posting.ID); err != nil {
+			return false, fmt.Errorf("failed to create ledger posting: %w", err)
+		}
+	}
+
+	// The balance check runs at commit
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit ledger entry: %w", err)
+	}
+
+	return true, nil
+}
+
+// ListBalances sums postings per account and currency
+func (r *LedgerRepository) ListBalances(ctx context.Context, filter models.LedgerBalanceFilter) ([]models.LedgerBalance, error) {
+	query := `
+		SELECT a.code, a.type, a.currency, SUM(p.amount)
+		FROM ledger_postings p
+		JOIN ledger_accounts a ON a.id = p.account_id
+		JOIN ledger_entries e ON e.id = p.entry_id
+		WHERE a.tenant = $1
+		  AND ($2::uuid IS NULL OR e.customer_id = $2)
+		  AND ($3 = '' OR a.code = $3)
+		  AND ($4 = '' OR a.currency::text = $4)
+		GROUP BY a.code, a.type, a.currency
+		ORDER BY a.code, a.currency
+	`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		filter.Tenant,
+		filter.CustomerID,
+		string(filter.AccountCode),
+		string(filter.Currency),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ledger balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := []models.LedgerBalance{}
+	for rows.Next() {
+		var balance models.LedgerBalance
+		if err := rows.Scan(
+			&balance.AccountCode,
+			&balance.AccountType,
+			&balance.Currency,
+			&balance.Balance,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger balance: %w", err)
+		}
+		balances = append(balances, balance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ledger balances: %w", err)
+	}
+
+	return balances, nil
+}
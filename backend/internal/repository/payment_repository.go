@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"payment-service/internal/models"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -24,9 +25,10 @@ func (r *PaymentRepository) Create(ctx context.Context, payment *models.Payment)
 			customer_id, provider, provider_payment_id, amount, currency, status,
 			payment_method_type, payment_method_details, description, statement_descriptor,
 			subscription_id, invoice_id, client_secret, failure_code, failure_message,
-			metadata, idempotency_key
+			discount_amount, coupon_id, promotion_code_id, tax_amount, tax_breakdown,
+			credit_applied, metadata, idempotency_key
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -48,6 +50,12 @@ func (r *PaymentRepository) Create(ctx context.Context, payment *models.Payment)
 		payment.ClientSecret,
 		payment.FailureCode,
 		payment.FailureMessage,
+		payment.DiscountAmount,
+		payment.CouponID,
+		payment.PromotionCodeID,
+		payment.TaxAmount,
+		payment.TaxBreakdown,
+		payment.CreditApplied,
 		payment.Metadata,
 		payment.IdempotencyKey,
 	).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
@@ -62,10 +70,13 @@ func (r *PaymentRepository) Create(ctx context.Context, payment *models.Payment)
 // GetByID retrieves a payment by ID
 func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Payment, error) {
 	query := `
-		SELECT id, customer_id, provider, provider_payment_id, amount, currency, status,
+		SELECT id, customer_id, provider, COALESCE(provider_payment_id, ''), amount, currency, status,
 		       payment_method_type, payment_method_details, description, statement_descriptor,
 		       subscription_id, invoice_id, client_secret, failure_code, failure_message,
-		       metadata, idempotency_key, created_at, updated_at, completed_at
+		       discount_amount, coupon_id, promotion_code_id, tax_amount, tax_breakdown, credit_applied,
+		       provider_fee, net_amount, settlement_currency, exchange_rate, available_on,
+		       balance_transaction_id, amount_refunded, amount_refund_pending, refund_status,
+		       disputed_at, metadata, idempotency_key, created_at, updated_at, completed_at
 		FROM payments
 		WHERE id = $1
 	`
@@ -88,6 +99,22 @@ func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 		&payment.ClientSecret,
 		&payment.FailureCode,
 		&payment.FailureMessage,
+		&payment.DiscountAmount,
+		&payment.CouponID,
+		&payment.PromotionCodeID,
+		&payment.TaxAmount,
+		&payment.TaxBreakdown,
+		&payment.CreditApplied,
+		&payment.ProviderFee,
+		&payment.NetAmount,
+		&payment.SettlementCurrency,
+		&payment.ExchangeRate,
+		&payment.AvailableOn,
+		&payment.BalanceTransactionID,
+		&payment.AmountRefunded,
+		&payment.AmountRefundPending,
+		&payment.RefundStatus,
+		&payment.DisputedAt,
 		&payment.Metadata,
 		&payment.IdempotencyKey,
 		&payment.CreatedAt,
@@ -108,10 +135,13 @@ func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 // GetByProviderPaymentID retrieves a payment by provider payment ID
 func (r *PaymentRepository) GetByProviderPaymentID(ctx context.Context, provider models.Provider, providerPaymentID string) (*models.Payment, error) {
 	query := `
-		SELECT id, customer_id, provider, provider_payment_id, amount, currency, status,
+		SELECT id, customer_id, provider, COALESCE(provider_payment_id, ''), amount, currency, status,
 		       payment_method_type, payment_method_details, description, statement_descriptor,
 		       subscription_id, invoice_id, client_secret, failure_code, failure_message,
-		       metadata, idempotency_key, created_at, updated_at, completed_at
+		       discount_amount, coupon_id, promotion_code_id, tax_amount, tax_breakdown, credit_applied,
+		       provider_fee, net_amount, settlement_currency, exchange_rate, available_on,
+		       balance_transaction_id, amount_refunded, amount_refund_pending, refund_status,
+		       disputed_at, metadata, idempotency_key, created_at, updated_at, completed_at
 		FROM payments
 		WHERE provider = $1 AND provider_payment_id = $2
 	`
@@ -134,6 +164,22 @@ func (r *PaymentRepository) GetByProviderPaymentID(ctx context.Context, provider
 		&payment.ClientSecret,
 		&payment.FailureCode,
 		&payment.FailureMessage,
+		&payment.DiscountAmount,
+		&payment.CouponID,
+		&payment.PromotionCodeID,
+		&payment.TaxAmount,
+		&payment.TaxBreakdown,
+		&payment.CreditApplied,
+		&payment.ProviderFee,
+		&payment.NetAmount,
+		&payment.SettlementCurrency,
+		&payment.ExchangeRate,
+		&payment.AvailableOn,
+		&payment.BalanceTransactionID,
+		&payment.AmountRefunded,
+		&payment.AmountRefundPending,
+		&payment.RefundStatus,
+		&payment.DisputedAt,
 		&payment.Metadata,
 		&payment.IdempotencyKey,
 		&payment.CreatedAt,
@@ -162,10 +208,13 @@ func (r *PaymentRepository) ListByCustomer(ctx context.Context, customerID uuid.
 
 	// Get payments
 	query := `
-		SELECT id, customer_id, provider, provider_payment_id, amount, currency, status,
+		SELECT id, customer_id, provider, COALESCE(provider_payment_id, ''), amount, currency, status,
 		       payment_method_type, payment_method_details, description, statement_descriptor,
 		       subscription_id, invoice_id, client_secret, failure_code, failure_message,
-		       metadata, idempotency_key, created_at, updated_at, completed_at
+		       discount_amount, coupon_id, promotion_code_id, tax_amount, tax_breakdown, credit_applied,
+		       provider_fee, net_amount, settlement_currency, exchange_rate, available_on,
+		       balance_transaction_id, amount_refunded, amount_refund_pending, refund_status,
+		       disputed_at, metadata, idempotency_key, created_at, updated_at, completed_at
 		FROM payments
 		WHERE customer_id = $1
 		ORDER BY created_at DESC
@@ -198,6 +247,22 @@ func (r *PaymentRepository) ListByCustomer(ctx context.Context, customerID uuid.
 			&payment.ClientSecret,
 			&payment.FailureCode,
 			&payment.FailureMessage,
+			&payment.DiscountAmount,
+			&payment.CouponID,
+			&payment.PromotionCodeID,
+			&payment.TaxAmount,
+			&payment.TaxBreakdown,
+			&payment.CreditApplied,
+			&payment.ProviderFee,
+			&payment.NetAmount,
+			&payment.SettlementCurrency,
+			&payment.ExchangeRate,
+			&payment.AvailableOn,
+			&payment.BalanceTransactionID,
+			&payment.AmountRefunded,
+			&payment.AmountRefundPending,
+			&payment.RefundStatus,
+			&payment.DisputedAt,
 			&payment.Metadata,
 			&payment.IdempotencyKey,
 			&payment.CreatedAt,
@@ -245,3 +310,169 @@ func (r *PaymentRepository) Update(ctx context.Context, payment *models.Payment)
 
 	return nil
 }
+
+// UpdateSettlement stores the settlement details of a payment
+func (r *PaymentRepository) UpdateSettlement(ctx context.Context, payment *models.Payment) error {
+	query := `
+		UPDATE payments
+		SET provider_fee = $2, net_amount = $3, settlement_currency = $4,
+		    exchange_rate = $5, available_on = $6, balance_transaction_id = $7
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		payment.ID,
+		payment.ProviderFee,
+		payment.NetAmount,
+		payment.SettlementCurrency,
+		payment.ExchangeRate,
+		payment.AvailableOn,
+		payment.BalanceTransactionID,
+	).Scan(&payment.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to update payment settlement: %w", err)
+	}
+
+	return nil
+}
+
+// ReserveRefund reserves an amount for a new refund. The check and the
+// reservation are a single statement, so concurrent refunds cannot reserve
+// more than the charged amount. Returns false if the amount is not refundable,
+// with the payment's refunded and pending amounts read again so they include
+// reservations made since it was loaded.
+func (r *PaymentRepository) ReserveRefund(ctx context.Context, payment *models.Payment, amount models.Money) (bool, error) {
+	if amount.Currency != payment.Currency {
+		return false, fmt.Errorf("failed to reserve refund: %w: %s refund on %s payment", models.ErrCurrencyMismatch, amount.Currency, payment.Currency)
+	}
+
+	query := `
+		UPDATE payments
+		SET amount_refund_pending = amount_refund_pending + $2
+		WHERE id = $1 AND amount_refunded + amount_refund_pending + $2 <= amount - credit_applied
+		RETURNING amount_refunded, amount_refund_pending, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, payment.ID, amount.Amount).Scan(
+		&payment.AmountRefunded,
+		&payment.AmountRefundPending,
+		&payment.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		err = r.db.QueryRowContext(
+			ctx,
+			`SELECT amount_refunded, amount_refund_pending, updated_at FROM payments WHERE id = $1`,
+			payment.ID,
+		).Scan(&payment.AmountRefunded, &payment.AmountRefundPending, &payment.UpdatedAt)
+		if err != nil {
+			return false, fmt.Errorf("failed to reserve refund: %w", err)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve refund: %w", err)
+	}
+
+	return true, nil
+}
+
+// ReleaseRefund releases a reservation for a refund that was never created
+func (r *PaymentRepository) ReleaseRefund(ctx context.Context, payment *models.Payment, amount models.Money) error {
+	if amount.Currency != payment.Currency {
+		return fmt.Errorf("failed to release refund: %w: %s refund on %s payment", models.ErrCurrencyMismatch, amount.Currency, payment.Currency)
+	}
+
+	query := `
+		UPDATE payments
+		SET amount_refund_pending = amount_refund_pending - $2
+		WHERE id = $1
+		RETURNING amount_refunded, amount_refund_pending, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, payment.ID, amount.Amount).Scan(
+		&payment.AmountRefunded,
+		&payment.AmountRefundPending,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to release refund: %w", err)
+	}
+
+	return nil
+}
+
+// MarkDisputed flags a payment as disputed, keeping the time of the first dispute
+func (r *PaymentRepository) MarkDisputed(ctx context.Context, payment *models.Payment, disputedAt time.Time) error {
+	query := `
+		UPDATE payments
+		SET disputed_at = COALESCE(disputed_at, $2)
+		WHERE id = $1
+		RETURNING disputed_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, payment.ID, disputedAt).Scan(&payment.DisputedAt, &payment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark payment disputed: %w", err)
+	}
+
+	return nil
+}
+
+// ListProductMargins aggregates succeeded payments created in [from, to) per
+// product, currency and settlement currency. The product is the payment's
+// "product" metadata, falling back to its description.
+func (r *PaymentRepository) ListProductMargins(ctx context.Context, from, to time.Time) ([]models.ProductMargin, error) {
+	query := `
+		SELECT COALESCE(p.metadata->>'product', p.description, '') AS product,
+		       p.currency, p.settlement_currency, COUNT(*),
+		       COUNT(*) FILTER (WHERE p.balance_transaction_id IS NULL),
+		       SUM(p.amount), SUM(p.tax_amount), COALESCE(SUM(r.amount), 0),
+		       COALESCE(SUM(p.provider_fee), 0) + COALESCE(SUM(r.provider_fee), 0),
+		       COALESCE(SUM(p.net_amount), 0) + COALESCE(SUM(r.net_amount), 0)
+		FROM payments p
+		LEFT JOIN (
+			SELECT payment_id, SUM(amount) AS amount,
+			       SUM(COALESCE(provider_fee, 0)) AS provider_fee,
+			       SUM(COALESCE(net_amount, 0)) AS net_amount
+			FROM refunds
+			WHERE status = 'succeeded'
+			GROUP BY payment_id
+		) r ON r.payment_id = p.id
+		WHERE p.status = 'succeeded' AND p.created_at >= $1 AND p.created_at < $2
+		GROUP BY 1, p.currency, p.settlement_currency
+		ORDER BY 1, p.currency, p.settlement_currency
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list product margins: %w", err)
+	}
+	defer rows.Close()
+
+	var margins []models.ProductMargin
+	for rows.Next() {
+		var margin models.ProductMargin
+		err := rows.Scan(
+			&margin.Product,
+			&margin.Currency,
+			&margin.SettlementCurrency,
+			&margin.Payments,
+			&margin.Unsettled,
+			&margin.GrossAmount,
+			&margin.TaxAmount,
+			&margin.RefundedAmount,
+			&margin.ProviderFees,
+			&margin.NetAmount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product margin: %w", err)
+		}
+		margins = append(margins, margin)
+	}
+
+	return margins, rows.Err()
+}
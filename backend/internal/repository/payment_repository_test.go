@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"payment-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentRepository_ListProductMargins(t *testing.T) {
+	// Setup
+	columns := []string{"product", "currency", "settlement_currency", "count", "count", "sum", "sum", "coalesce", "?column?", "?column?"}
+	db, fake := newFakeDB(t, fakeResult{
+		columns: columns,
+		rows: [][]driver.Value{
+			{"Pro plan", "SEK", "SEK", int64(3), int64(0), int64(30000), int64(6000), int64(5000), int64(900), int64(23100)},
+			{"Pro plan", "EUR", nil, int64(1), int64(1), int64(1000), int64(0), int64(0), int64(0), int64(0)},
+		},
+	})
+	repo := NewPaymentRepository(db)
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	// Execute
+	margins, err := repo.ListProductMargins(context.Background(), from, to)
+
+	// Assert
+	assert.NoError(t, err)
+	sek := models.CurrencySEK
+	assert.Equal(t, []models.ProductMargin{
+		{
+			Product:            "Pro plan",
+			Currency:           models.CurrencySEK,
+			SettlementCurrency: &sek,
+			Payments:           3,
+			GrossAmount:        30000,
+			TaxAmount:          6000,
+			RefundedAmount:     5000,
+			ProviderFees:       900,
+			NetAmount:          23100,
+		},
+		{
+			Product:     "Pro plan",
+			Currency:    models.CurrencyEUR,
+			Payments:    1,
+			Unsettled:   1,
+			GrossAmount: 1000,
+		},
+	}, margins)
+
+	statements := fake.statements()
+	assert.Len(t, statements, 1)
+	assert.Equal(t, []driver.Value{from, to}, statements[0].args)
+	// refunds has no deleted_at column, so filtering on it fails in Postgres
+	assert.NotContains(t, statements[0].query, "deleted_at")
+}
+
+func TestPaymentRepository_ReserveRefund_RereadsAmountsWhenRefused(t *testing.T) {
+	// Setup
+	updatedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	db, fake := newFakeDB(t,
+		fakeResult{columns: []string{"amount_refunded", "amount_refund_pending", "updated_at"}},
+		fakeResult{
+			columns: []string{"amount_refunded", "amount_refund_pending", "updated_at"},
+			rows:    [][]driver.Value{{int64(4000), int64(5000), updatedAt}},
+		},
+	)
+	repo := NewPaymentRepository(db)
+	payment := &models.Payment{
+		ID:                  uuid.New(),
+		Amount:              10000,
+		Currency:            models.CurrencySEK,
+		AmountRefunded:      4000,
+		AmountRefundPending: 4000,
+	}
+
+	// Execute
+	reserved, err := repo.ReserveRefund(context.Background(), payment, models.NewMoney(5000, models.CurrencySEK))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Len(t, fake.statements(), 2)
+	assert.Equal(t, int64(5000), payment.AmountRefundPending)
+	assert.Equal(t, models.NewMoney(1000, models.CurrencySEK), payment.RefundableAmount())
+}
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type PayoutRepository struct {
+	db *sql.DB
+}
+
+func NewPayoutRepository(db *sql.DB) *PayoutRepository {
+	return &PayoutRepository{db: db}
+}
+
+// Upsert inserts a payout or refreshes the one already imported
+func (r *PayoutRepository) Upsert(ctx context.Context, payout *models.Payout) error {
+	query := `
+		INSERT INTO payouts (
+			provider, provider_payout_id, amount, currency, status, arrival_date,
+			description, failure_code, failure_message, provider_created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (provider, provider_payout_id) DO UPDATE SET
+			amount = EXCLUDED.amount,
+			currency = EXCLUDED.currency,
+			status = EXCLUDED.status,
+			arrival_date = EXCLUDED.arrival_date,
+			description = EXCLUDED.description,
+			failure_code = EXCLUDED.failure_code,
+			failure_message = EXCLUDED.failure_message,
+			updated_at = NOW()
+		RETURNING id, reconciled_at, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		payout.Provider,
+		payout.ProviderPayoutID,
+		payout.Amount,
+		payout.Currency,
+		payout.Status,
+		payout.ArrivalDate,
+		payout.Description,
+		payout.FailureCode,
+		payout.FailureMessage,
+		payout.ProviderCreatedAt,
+	).Scan(&payout.ID, &payout.ReconciledAt, &payout.CreatedAt, &payout.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to upsert payout: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceTransactions replaces a payout's transactions and links each charge
+// and refund to our payment or refund with the same balance transaction
+func (r *PayoutRepository) ReplaceTransactions(ctx context.Context, payoutID uuid.UUID, transactions []models.PayoutTransaction) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM payout_transactions WHERE payout_id = $1`, payoutID); err != nil {
+		return fmt.Errorf("failed to delete payout transactions: %w", err)
+	}
+
+	insertQuery := `
+		INSERT INTO payout_transactions (
+			payout_id, balance_transaction_id, type, source_id, amount, fee, net,
+			currency, description
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	for _, transaction := range transactions {
+		_, err := tx.ExecContext(
+			ctx,
+			insertQuery,
+			payoutID,
+			transaction.BalanceTransactionID,
+			transaction.Type,
+			transaction.SourceID,
+			transaction.Amount,
+			transaction.Fee,
+			transaction.Net,
+			transaction.Currency,
+			transaction.Description,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert payout transaction: %w", err)
+		}
+	}
+
+	matchPayments := `
+		UPDATE payout_transactions pt
+		SET payment_id = p.id
+		FROM payments p
+		WHERE pt.payout_id = $1 AND pt.type = 'charge'
+		  AND p.balance_transaction_id = pt.balance_transaction_id
+	`
+	if _, err := tx.ExecContext(ctx, matchPayments, payoutID); err != nil {
+		return fmt.Errorf("failed to match payout payments: %w", err)
+	}
+
+	matchRefunds := `
+		UPDATE payout_transactions pt
+		SET refund_id = rf.id
+		FROM refunds rf
+		WHERE pt.payout_id = $1 AND pt.type = 'refund'
+		  AND rf.balance_transaction_id = pt.balance_transaction_id
+	`
+	if _, err := tx.ExecContext(ctx, matchRefunds, payoutID); err != nil {
+		return fmt.Errorf("failed to match payout refunds: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payout transactions: %w", err)
+	}
+
+	return nil
+}
+
+// SetReconciled records whether a payout is fully reconciled, keeping the
+// original reconciliation time
+func (r *PayoutRepository) SetReconciled(ctx context.Context, payout *models.Payout, reconciled bool) error {
+	query := `
+		UPDATE payouts
+		SET reconciled_at = CASE WHEN $2 THEN COALESCE(reconciled_at, NOW()) END,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING reconciled_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, payout.ID, reconciled).Scan(&payout.ReconciledAt, &payout.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update payout reconciliation: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a payout by ID
+func (r *PayoutRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Payout, error) {
+	query := `
+		SELECT
+			id, provider, provider_payout_id, amount, currency, status, arrival_date,
+			description, failure_code, failure_message, reconciled_at,
+			provider_created_at, created_at, updated_at
+		FROM payouts
+		WHERE id = $1
+	`
+
+	var payout models.Payout
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&payout.ID,
+		&payout.Provider,
+		&payout.ProviderPayoutID,
+		&payout.Amount,
+		&payout.Currency,
+		&payout.Status,
+		&payout.ArrivalDate,
+		&payout.Description,
+		&payout.FailureCode,
+		&payout.FailureMessage,
+		&payout.ReconciledAt,
+		&payout.ProviderCreatedAt,
+		&payout.CreatedAt,
+		&payout.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout: %w", err)
+	}
+
+	return &payout, nil
+}
+
+// List returns payouts, latest arrival first
+func (r *PayoutRepository) List(ctx context.Context, limit, offset int) ([]models.Payout, int, error) {
+	// Get total count
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM payouts`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count payouts: %w", err)
+	}
+
+	// Get payouts
+	query := `
+		SELECT
+			id, provider, provider_payout_id, amount, currency, status, arrival_date,
+			description, failure_code, failure_message, reconciled_at,
+			provider_created_at, created_at, updated_at
+		FROM payouts
+		ORDER BY arrival_date DESC, provider_created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list payouts: %w", err)
+	}
+	defer rows.Close()
+
+	payouts := []models.Payout{}
+	for rows.Next() {
+		var payout models.Payout
+		err := rows.Scan(
+			&payout.ID,
+			&payout.Provider,
+			&payout.ProviderPayoutID,
+			&payout.Amount,
+			&payout.Currency,
+			&payout.Status,
+			&payout.ArrivalDate,
+			&payout.Description,
+			&payout.FailureCode,
+			&payout.FailureMessage,
+			&payout.ReconciledAt,
+			&payout.ProviderCreatedAt,
+			&payout.CreatedAt,
+			&payout.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan payout: %w", err)
+		}
+		payouts = append(payouts, payout)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating payouts: %w", err)
+	}
+
+	return payouts, total, nil
+}
+
+// ListTransactions returns a payout's transactions grouped by type
+func (r *PayoutRepository) ListTransactions(ctx context.Context, payoutID uuid.UUID) ([]models.PayoutTransaction, error) {
+	query := `
+		SELECT
+			id, payout_id, balance_transaction_id, type, source_id, amount, fee, net,
+			currency, description, payment_id, refund_id, created_at
+		FROM payout_transactions
+		WHERE payout_id = $1
+		ORDER BY type, balance_transaction_id`
+
+	rows, err := r.db.QueryContext(ctx, query, payoutID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payout transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.PayoutTransaction{}
+	for rows.Next() {
+		var transaction models.PayoutTransaction
+		err := rows.Scan(
+			&transaction.ID,
+			&transaction.PayoutID,
+			&transaction.BalanceTransactionID,
+			&transaction.Type,
+			&transaction.SourceID,
+			&transaction.Amount,
+			&transaction.Fee,
+			&transaction.Net,
+			&transaction.Currency,
+			&transaction.Description,
+			&transaction.PaymentID,
+			&transaction.RefundID,
+			&transaction.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payout transaction: %w", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating payout transactions: %w", err)
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type PromotionCodeRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionCodeRepository(db *sql.DB) *PromotionCodeRepository {
+	return &PromotionCodeRepository{db: db}
+}
+
+// Create creates a new promotion code
+func (r *PromotionCodeRepository) Create(ctx context.Context, code *models.PromotionCode) error {
+	query := `
+		INSERT INTO promotion_codes (
+			coupon_id, code, active, max_redemptions,
+			expires_at, first_time_transaction, metadata
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		) RETURNING id, times_redeemed, created_at, updated_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		code.CouponID,
+		code.Code,
+		code.Active,
+		code.MaxRedemptions,
+		code.ExpiresAt,
+		code.FirstTimeTransaction,
+		code.Metadata,
+	).Scan(&code.ID, &code.TimesRedeemed, &code.CreatedAt, &code.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create promotion code: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a promotion code by ID
+func (r *PromotionCodeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.PromotionCode, error) {
+	query := `
+		SELECT
+			id, coupon_id, code, active, max_redemptions,
+			times_redeemed, expires_at, first_time_transaction,
+			metadata, created_at, updated_at
+		FROM promotion_codes
+		WHERE id = $1`
+
+	code := &models.PromotionCode{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&code.ID,
+		&code.CouponID,
+		&code.Code,
+		&code.Active,
+		&code.MaxRedemptions,
+		&code.TimesRedeemed,
+		&code.ExpiresAt,
+		&code.FirstTimeTransaction,
+		&code.Metadata,
+		&code.CreatedAt,
+		&code.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promotion code: %w", err)
+	}
+
+	return code, nil
+}
+
+// GetByCode retrieves a promotion code by its (upper case) code
+func (r *PromotionCodeRepository) GetByCode(ctx context.Context, code string) (*models.PromotionCode, error) {
+	query := `
+		SELECT
+			id, coupon_id, code, active, max_redemptions,
+			times_redeemed, expires_at, first_time_transaction,
+			metadata, created_at, updated_at
+		FROM promotion_codes
+		WHERE code = $1`
+
+	promotionCode := &models.PromotionCode{}
+	err := r.db.QueryRowContext(ctx, query, code).Scan(
+		&promotionCode.ID,
+		&promotionCode.CouponID,
+		&promotionCode.Code,
+		&promotionCode.Active,
+		&promotionCode.MaxRedemptions,
+		&promotionCode.TimesRedeemed,
+		&promotionCode.ExpiresAt,
+		&promotionCode.FirstTimeTransaction,
+		&promotionCode.Metadata,
+		&promotionCode.CreatedAt,
+		&promotionCode.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promotion code by code: %w", err)
+	}
+
+	return promotionCode, nil
+}
+
+// ListByCoupon retrieves all promotion codes for a coupon
+func (r *PromotionCodeRepository) ListByCoupon(ctx context.Context, couponID uuid.UUID) ([]models.PromotionCode, error) {
+	query := `
+		SELECT
+			id, coupon_id, code, active, max_redemptions,
+			times_redeemed, expires_at, first_time_transaction,
+			metadata, created_at, updated_at
+		FROM promotion_codes
+		WHERE coupon_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, couponID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list promotion codes: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []models.PromotionCode{}
+	for rows.Next() {
+		var code models.PromotionCode
+		err := rows.Scan(
+			&code.ID,
+			&code.CouponID,
+			&code.Code,
+			&code.Active,
+			&code.MaxRedemptions,
+			&code.TimesRedeemed,
+			&code.ExpiresAt,
+			&code.FirstTimeTransaction,
+			&code.Metadata,
+			&code.CreatedAt,
+			&code.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotion code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating promotion codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// Update updates a promotion code's active flag
+func (r *PromotionCodeRepository) Update(ctx context.Context, code *models.PromotionCode) error {
+	query := `
+		UPDATE promotion_codes SET
+			active = $1,
+			metadata = $2
+		WHERE id = $3
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(ctx, query, code.Active, code.Metadata, code.ID).Scan(&code.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("promotion code not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update promotion code: %w", err)
+	}
+
+	return nil
+}
+
+// Reserve atomically counts a redemption against the code. It returns false if
+// the code is inactive, has expired or has reached its limit.
+func (r *PromotionCodeRepository) Reserve(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE promotion_codes SET times_redeemed = times_redeemed + 1
+		WHERE id = $1
+		  AND active
+		  AND (max_redemptions IS NULL OR times_redeemed < max_redemptions)
+		  AND (expires_at IS NULL OR expires_at > NOW())`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve promotion code redemption: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// Release gives back a redemption reserved with Reserve
+func (r *PromotionCodeRepository) Release(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE promotion_codes SET times_redeemed = GREATEST(times_redeemed - 1, 0) WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to release promotion code redemption: %w", err)
+	}
+
+	return nil
+}
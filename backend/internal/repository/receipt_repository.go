@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type ReceiptRepository struct {
+	db *sql.DB
+}
+
+func NewReceiptRepository(db *sql.DB) *ReceiptRepository {
+	return &ReceiptRepository{db: db}
+}
+
+// Create assigns the tenant's next receipt number and inserts the receipt in
+// one transaction, so numbers are never skipped. Returns false without
+// consuming a number if the payment already has a receipt.
+func (r *ReceiptRepository) Create(ctx context.Context, receipt *models.Receipt, numberPrefix string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locks the tenant's sequence row until commit
+	sequenceQuery := `
+		INSERT INTO receipt_sequences (tenant, last_number)
+		VALUES ($1, 1)
+		ON CONFLICT (tenant) DO UPDATE SET last_number = receipt_sequences.last_number + 1
+		RETURNING last_number`
+
+	if err := tx.QueryRowContext(ctx, sequenceQuery, receipt.Tenant).Scan(&receipt.Number); err != nil {
+		return false, fmt.Errorf("failed to allocate receipt number: %w", err)
+	}
+	receipt.ReceiptNumber = fmt.Sprintf("%s-%06d", numberPrefix, receipt.Number)
+
+	query := `
+		INSERT INTO receipts (
+			tenant, number, receipt_number, payment_id, invoice_id,
+			customer_id, amount, currency, pdf
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+		ON CONFLICT (payment_id) DO NOTHING
+		RETURNING id, created_at`
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		receipt.Tenant,
+		receipt.Number,
+		receipt.ReceiptNumber,
+		receipt.PaymentID,
+		receipt.InvoiceID,
+		receipt.CustomerID,
+		receipt.Amount,
+		receipt.Currency,
+		receipt.PDF,
+	).Scan(&receipt.ID, &receipt.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		// Another request created it first; rolling back returns the number
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create receipt: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit receipt: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetByPaymentID retrieves the receipt for a payment
+func (r *ReceiptRepository) GetByPaymentID(ctx context.Context, paymentID uuid.UUID) (*models.Receipt, error) {
+	query := `
+		SELECT
+			id, tenant, number, receipt_number, payment_id, invoice_id,
+			customer_id, amount, currency, pdf, created_at
+		FROM receipts
+		WHERE payment_id = $1`
+
+	receipt := &models.Receipt{}
+	err := r.db.QueryRowContext(ctx, query, paymentID).Scan(
+		&receipt.ID,
+		&receipt.Tenant,
+		&receipt.Number,
+		&receipt.ReceiptNumber,
+		&receipt.PaymentID,
+		&receipt.InvoiceID,
+		&receipt.CustomerID,
+		&receipt.Amount,
+		&receipt.Currency,
+		&receipt.PDF,
+		&receipt.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt: %w", err)
+	}
+
+	return receipt, nil
+}
+
+// UpdatePDF stores the rendered document for a receipt
+func (r *ReceiptRepository) UpdatePDF(ctx context.Context, id uuid.UUID, pdf []byte) error {
+	query := `UPDATE receipts SET pdf = $1 WHERE id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, pdf, id)
+	if err != nil {
+		return fmt.Errorf("failed to update receipt: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update receipt: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("receipt not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type ReconciliationRepository struct {
+	db *sql.DB
+}
+
+func NewReconciliationRepository(db *sql.DB) *ReconciliationRepository {
+	return &ReconciliationRepository{db: db}
+}
+
+// RecordDiscrepancy stores a discrepancy. An open discrepancy that is already
+// on file for the same object and kind is refreshed instead of duplicated.
+func (r *ReconciliationRepository) RecordDiscrepancy(ctx context.Context, discrepancy *models.ReconciliationDiscrepancy) error {
+	query := `
+		INSERT INTO reconciliation_discrepancies (
+			provider, object_type, provider_object_id, local_id, kind,
+			local_value, provider_value, status, resolved_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CASE WHEN $8 = 'open' THEN NULL ELSE NOW() END)
+		ON CONFLICT (object_type, provider_object_id, kind) WHERE status = 'open'
+		DO UPDATE SET
+			local_id = EXCLUDED.local_id,
+			local_value = EXCLUDED.local_value,
+			provider_value = EXCLUDED.provider_value,
+			last_seen_at = NOW()
+		RETURNING id, first_seen_at, last_seen_at, resolved_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		discrepancy.Provider,
+		discrepancy.ObjectType,
+		discrepancy.ProviderObjectID,
+		discrepancy.LocalID,
+		discrepancy.Kind,
+		discrepancy.LocalValue,
+		discrepancy.ProviderValue,
+		discrepancy.Status,
+	).Scan(&discrepancy.ID, &discrepancy.FirstSeenAt, &discrepancy.LastSeenAt, &discrepancy.ResolvedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to record discrepancy: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveObject resolves an object's open discrepancies except those of the
+// given kinds, which are still present
+func (r *ReconciliationRepository) ResolveObject(
+	ctx context.Context,
+	objectType models.ReconciliationObjectType,
+	providerObjectID string,
+	stillOpen []models.DiscrepancyKind,
+) error {
+	kinds := make([]string, len(stillOpen))
+	for i, kind := range stillOpen {
+		kinds[i] = string(kind)
+	}
+
+	query := `
+		UPDATE reconciliation_discrepancies
+		SET status = 'resolved', resolved_at = NOW()
+		WHERE object_type = $1 AND provider_object_id = $2 AND status = 'open'
+		  AND kind <> ALL(string_to_array($3, ','))
+	`
+
+	_, err := r.db.ExecContext(ctx, query, objectType, providerObjectID, strings.Join(kinds, ","))
+	if err != nil {
+		return fmt.Errorf("failed to resolve discrepancies: %w", err)
+	}
+
+	return nil
+}
+
+// Resolve closes an open discrepancy. Returns false if it is not open.
+func (r *ReconciliationRepository) Resolve(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE reconciliation_discrepancies
+		SET status = 'resolved', resolved_at = NOW()
+		WHERE id = $1 AND status = 'open'
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve discrepancy: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve discrepancy: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// List returns discrepancies, most recently seen first
+func (r *ReconciliationRepository) List(ctx context.Context, filter models.DiscrepancyFilter) ([]models.ReconciliationDiscrepancy, int, error) {
+	// Get total count
+	var total int
+	countQuery := `
+		SELECT COUNT(*) FROM reconciliation_discrepancies
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR object_type = $2)`
+	err := r.db.QueryRowContext(ctx, countQuery, string(filter.Status), string(filter.ObjectType)).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count discrepancies: %w", err)
+	}
+
+	// Get discrepancies
+	query := `
+		SELECT
+			id, provider, object_type, provider_object_id, local_id, kind,
+			local_value, provider_value, status, first_seen_at, last_seen_at, resolved_at
+		FROM reconciliation_discrepancies
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR object_type = $2)
+		ORDER BY last_seen_at DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		string(filter.Status),
+		string(filter.ObjectType),
+		filter.Limit,
+		filter.Offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list discrepancies: %w", err)
+	}
+	defer rows.Close()
+
+	discrepancies := []models.ReconciliationDiscrepancy{}
+	for rows.Next() {
+		var discrepancy models.ReconciliationDiscrepancy
+		err := rows.Scan(
+			&discrepancy.ID,
+			&discrepancy.Provider,
+			&discrepancy.ObjectType,
+			&discrepancy.ProviderObjectID,
+			&discrepancy.LocalID,
+			&discrepancy.Kind,
+			&discrepancy.LocalValue,
+			&discrepancy.ProviderValue,
+			&discrepancy.Status,
+			&discrepancy.FirstSeenAt,
+			&discrepancy.LastSeenAt,
+			&discrepancy.ResolvedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan discrepancy: %w", err)
+		}
+		discrepancies = append(discrepancies, discrepancy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating discrepancies: %w", err)
+	}
+
+	return discrepancies, total, nil
+}
+
+// CountOpen returns the number of open discrepancies per object type
+func (r *ReconciliationRepository) CountOpen(ctx context.Context) (map[models.ReconciliationObjectType]int, error) {
+	query := `
+		SELECT object_type, COUNT(*)
+		FROM reconciliation_discrepancies
+		WHERE status = 'open'
+		GROUP BY object_type
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count open discrepancies: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[models.ReconciliationObjectType]int{}
+	for rows.Next() {
+		var objectType models.ReconciliationObjectType
+		var count int
+		if err := rows.Scan(&objectType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan open discrepancy count: %w", err)
+		}
+		counts[objectType] = count
+	}
+
+	return counts, rows.Err()
+}
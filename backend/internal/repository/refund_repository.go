@@ -17,17 +17,26 @@ func NewRefundRepository(db *sql.DB) *RefundRepository {
 	return &RefundRepository{db: db}
 }
 
-// Create creates a new refund
+// Create inserts a new refund. Its amount must already be reserved on the
+// payment with PaymentRepository.ReserveRefund; a refund created in a final
+// status settles the reservation right away.
 func (r *RefundRepository) Create(ctx context.Context, refund *models.Refund) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO refunds (
 			payment_id, provider, provider_refund_id,
-			amount, currency, status, reason, metadata
+			amount, currency, status, destination, reason, metadata,
+			requested_by, approval_reason
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8
+			$1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11
 		) RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		refund.PaymentID,
@@ -36,14 +45,27 @@ func (r *RefundRepository) Create(ctx context.Context, refund *models.Refund) er
 		refund.Amount,
 		refund.Currency,
 		refund.Status,
+		refund.Destination,
 		refund.Reason,
 		refund.Metadata,
+		refund.RequestedBy,
+		refund.ApprovalReason,
 	).Scan(&refund.ID, &refund.CreatedAt, &refund.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create refund: %w", err)
 	}
 
+	if !refund.Status.Open() {
+		if err := settleRefund(ctx, tx, refund); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit refund: %w", err)
+	}
+
 	return nil
 }
 
@@ -51,11 +73,14 @@ func (r *RefundRepository) Create(ctx context.Context, refund *models.Refund) er
 func (r *RefundRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Refund, error) {
 	query := `
 		SELECT
-			id, payment_id, provider, provider_refund_id,
-			amount, currency, status, reason, metadata,
+			id, payment_id, provider, COALESCE(provider_refund_id, ''),
+			amount, currency, status, destination, reason, metadata,
+			provider_fee, net_amount, settlement_currency, exchange_rate,
+			available_on, balance_transaction_id,
+			requested_by, approval_reason, reviewed_by, reviewed_at, review_note,
 			created_at, updated_at
 		FROM refunds
-		WHERE id = $1 AND deleted_at IS NULL`
+		WHERE id = $1`
 
 	refund := &models.Refund{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
@@ -66,8 +91,20 @@ func (r *RefundRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.R
 		&refund.Amount,
 		&refund.Currency,
 		&refund.Status,
+		&refund.Destination,
 		&refund.Reason,
 		&refund.Metadata,
+		&refund.ProviderFee,
+		&refund.NetAmount,
+		&refund.SettlementCurrency,
+		&refund.ExchangeRate,
+		&refund.AvailableOn,
+		&refund.BalanceTransactionID,
+		&refund.RequestedBy,
+		&refund.ApprovalReason,
+		&refund.ReviewedBy,
+		&refund.ReviewedAt,
+		&refund.ReviewNote,
 		&refund.CreatedAt,
 		&refund.UpdatedAt,
 	)
@@ -86,11 +123,14 @@ func (r *RefundRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.R
 func (r *RefundRepository) GetByProviderRefundID(ctx context.Context, providerRefundID string) (*models.Refund, error) {
 	query := `
 		SELECT
-			id, payment_id, provider, provider_refund_id,
-			amount, currency, status, reason, metadata,
+			id, payment_id, provider, COALESCE(provider_refund_id, ''),
+			amount, currency, status, destination, reason, metadata,
+			provider_fee, net_amount, settlement_currency, exchange_rate,
+			available_on, balance_transaction_id,
+			requested_by, approval_reason, reviewed_by, reviewed_at, review_note,
 			created_at, updated_at
 		FROM refunds
-		WHERE provider_refund_id = $1 AND deleted_at IS NULL`
+		WHERE provider_refund_id = $1`
 
 	refund := &models.Refund{}
 	err := r.db.QueryRowContext(ctx, query, providerRefundID).Scan(
@@ -101,8 +141,20 @@ func (r *RefundRepository) GetByProviderRefundID(ctx context.Context, providerRe
 		&refund.Amount,
 		&refund.Currency,
 		&refund.Status,
+		&refund.Destination,
 		&refund.Reason,
 		&refund.Metadata,
+		&refund.ProviderFee,
+		&refund.NetAmount,
+		&refund.SettlementCurrency,
+		&refund.ExchangeRate,
+		&refund.AvailableOn,
+		&refund.BalanceTransactionID,
+		&refund.RequestedBy,
+		&refund.ApprovalReason,
+		&refund.ReviewedBy,
+		&refund.ReviewedAt,
+		&refund.ReviewNote,
 		&refund.CreatedAt,
 		&refund.UpdatedAt,
 	)
@@ -121,11 +173,14 @@ func (r *RefundRepository) GetByProviderRefundID(ctx context.Context, providerRe
 func (r *RefundRepository) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]models.Refund, error) {
 	query := `
 		SELECT
-			id, payment_id, provider, provider_refund_id,
-			amount, currency, status, reason, metadata,
+			id, payment_id, provider, COALESCE(provider_refund_id, ''),
+			amount, currency, status, destination, reason, metadata,
+			provider_fee, net_amount, settlement_currency, exchange_rate,
+			available_on, balance_transaction_id,
+			requested_by, approval_reason, reviewed_by, reviewed_at, review_note,
 			created_at, updated_at
 		FROM refunds
-		WHERE payment_id = $1 AND deleted_at IS NULL
+		WHERE payment_id = $1
 		ORDER BY created_at DESC`
 
 	rows, err := r.db.QueryContext(ctx, query, paymentID)
@@ -145,8 +200,20 @@ func (r *RefundRepository) ListByPayment(ctx context.Context, paymentID uuid.UUI
 			&refund.Amount,
 			&refund.Currency,
 			&refund.Status,
+			&refund.Destination,
 			&refund.Reason,
 			&refund.Metadata,
+			&refund.ProviderFee,
+			&refund.NetAmount,
+			&refund.SettlementCurrency,
+			&refund.ExchangeRate,
+			&refund.AvailableOn,
+			&refund.BalanceTransactionID,
+			&refund.RequestedBy,
+			&refund.ApprovalReason,
+			&refund.ReviewedBy,
+			&refund.ReviewedAt,
+			&refund.ReviewNote,
 			&refund.CreatedAt,
 			&refund.UpdatedAt,
 		)
@@ -171,7 +238,7 @@ func (r *RefundRepository) ListByCustomer(ctx context.Context, customerID uuid.U
 		SELECT COUNT(*)
 		FROM refunds rf
 		JOIN payments p ON rf.payment_id = p.id
-		WHERE p.customer_id = $1 AND rf.deleted_at IS NULL`
+		WHERE p.customer_id = $1`
 	err := r.db.QueryRowContext(ctx, countQuery, customerID).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count refunds: %w", err)
@@ -180,12 +247,15 @@ func (r *RefundRepository) ListByCustomer(ctx context.Context, customerID uuid.U
 	// Get refunds
 	query := `
 		SELECT
-			rf.id, rf.payment_id, rf.provider, rf.provider_refund_id,
-			rf.amount, rf.currency, rf.status, rf.reason, rf.metadata,
+			rf.id, rf.payment_id, rf.provider, COALESCE(rf.provider_refund_id, ''),
+			rf.amount, rf.currency, rf.status, rf.destination, rf.reason, rf.metadata,
+			rf.provider_fee, rf.net_amount, rf.settlement_currency, rf.exchange_rate,
+			rf.available_on, rf.balance_transaction_id,
+			rf.requested_by, rf.approval_reason, rf.reviewed_by, rf.reviewed_at, rf.review_note,
 			rf.created_at, rf.updated_at
 		FROM refunds rf
 		JOIN payments p ON rf.payment_id = p.id
-		WHERE p.customer_id = $1 AND rf.deleted_at IS NULL
+		WHERE p.customer_id = $1
 		ORDER BY rf.created_at DESC
 		LIMIT $2 OFFSET $3`
 
@@ -206,8 +276,20 @@ func (r *RefundRepository) ListByCustomer(ctx context.Context, customerID uuid.U
 			&refund.Amount,
 			&refund.Currency,
 			&refund.Status,
+			&refund.Destination,
 			&refund.Reason,
 			&refund.Metadata,
+			&refund.ProviderFee,
+			&refund.NetAmount,
+			&refund.SettlementCurrency,
+			&refund.ExchangeRate,
+			&refund.AvailableOn,
+			&refund.BalanceTransactionID,
+			&refund.RequestedBy,
+			&refund.ApprovalReason,
+			&refund.ReviewedBy,
+			&refund.ReviewedAt,
+			&refund.ReviewNote,
 			&refund.CreatedAt,
 			&refund.UpdatedAt,
 		)
@@ -224,29 +306,244 @@ func (r *RefundRepository) ListByCustomer(ctx context.Context, customerID uuid.U
 	return refunds, total, nil
 }
 
-// Update updates a refund status
+// ListByStatus retrieves refunds with the given status, oldest first
+func (r *RefundRepository) ListByStatus(ctx context.Context, status models.RefundStatus, limit, offset int) ([]models.Refund, int, error) {
+	// Get total count
+	var total int
+	countQuery := `SELECT COUNT(*) FROM refunds WHERE status = $1`
+	err := r.db.QueryRowContext(ctx, countQuery, status).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count refunds: %w", err)
+	}
+
+	// Get refunds
+	query := `
+		SELECT
+			id, payment_id, provider, COALESCE(provider_refund_id, ''),
+			amount, currency, status, destination, reason, metadata,
+			provider_fee, net_amount, settlement_currency, exchange_rate,
+			available_on, balance_transaction_id,
+			requested_by, approval_reason, reviewed_by, reviewed_at, review_note,
+			created_at, updated_at
+		FROM refunds
+		WHERE status = $1
+		ORDER BY created_at ASC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list refunds: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := []models.Refund{}
+	for rows.Next() {
+		var refund models.Refund
+		err := rows.Scan(
+			&refund.ID,
+			&refund.PaymentID,
+			&refund.Provider,
+			&refund.ProviderRefundID,
+			&refund.Amount,
+			&refund.Currency,
+			&refund.Status,
+			&refund.Destination,
+			&refund.Reason,
+			&refund.Metadata,
+			&refund.ProviderFee,
+			&refund.NetAmount,
+			&refund.SettlementCurrency,
+			&refund.ExchangeRate,
+			&refund.AvailableOn,
+			&refund.BalanceTransactionID,
+			&refund.RequestedBy,
+			&refund.ApprovalReason,
+			&refund.ReviewedBy,
+			&refund.ReviewedAt,
+			&refund.ReviewNote,
+			&refund.CreatedAt,
+			&refund.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		refunds = append(refunds, refund)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating refunds: %w", err)
+	}
+
+	return refunds, total, nil
+}
+
+// Update updates a refund. The first move from an open to a final status
+// settles the refund's reservation on the payment, in the same transaction.
 func (r *RefundRepository) Update(ctx context.Context, refund *models.Refund) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous models.RefundStatus
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT status FROM refunds WHERE id = $1 FOR UPDATE`,
+		refund.ID,
+	).Scan(&previous)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("refund not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock refund: %w", err)
+	}
+
 	query := `
 		UPDATE refunds SET
-			status = $1,
-			metadata = $2,
+			provider_refund_id = COALESCE(NULLIF($1, ''), provider_refund_id),
+			status = $2,
+			metadata = $3,
 			updated_at = NOW()
-		WHERE id = $3 AND deleted_at IS NULL
+		WHERE id = $4
 		RETURNING updated_at`
 
-	err := r.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
+		refund.ProviderRefundID,
 		refund.Status,
 		refund.Metadata,
 		refund.ID,
 	).Scan(&refund.UpdatedAt)
 
+	if err != nil {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+
+	if previous.Open() && !refund.Status.Open() {
+		if err := settleRefund(ctx, tx, refund); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit refund: %w", err)
+	}
+
+	return nil
+}
+
+// Review records the approval or rejection of a refund waiting for approval.
+// It returns false if the refund has already been reviewed. A rejection
+// settles the refund's reservation on the payment.
+func (r *RefundRepository) Review(ctx context.Context, refund *models.Refund) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE refunds SET
+			status = $2,
+			reviewed_by = $3,
+			reviewed_at = $4,
+			review_note = $5,
+			updated_at = NOW()
+		WHERE id = $1 AND status = 'pending_approval'
+		RETURNING updated_at`
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		refund.ID,
+		refund.Status,
+		refund.ReviewedBy,
+		refund.ReviewedAt,
+		refund.ReviewNote,
+	).Scan(&refund.UpdatedAt)
+
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("refund not found")
+		return false, nil
 	}
 	if err != nil {
-		return fmt.Errorf("failed to update refund: %w", err)
+		return false, fmt.Errorf("failed to review refund: %w", err)
+	}
+
+	if !refund.Status.Open() {
+		if err := settleRefund(ctx, tx, refund); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit refund review: %w", err)
+	}
+
+	return true, nil
+}
+
+// settleRefund releases a finished refund's reservation on its payment and,
+// if it succeeded, adds it to the refunded amount. A refund in another
+// currency than its payment is refused rather than added to its totals.
+func settleRefund(ctx context.Context, tx *sql.Tx, refund *models.Refund) error {
+	query := `
+		UPDATE payments SET
+			amount_refund_pending = amount_refund_pending - $2,
+			amount_refunded = amount_refunded + CASE WHEN $3::boolean THEN $2 ELSE 0 END,
+			refund_status = CASE
+				WHEN NOT $3::boolean THEN refund_status
+				WHEN amount_refunded + $2 >= amount - credit_applied THEN 'refunded'
+				ELSE 'partially_refunded'
+			END
+		WHERE id = $1 AND currency = $4`
+
+	succeeded := refund.Status == models.RefundStatusSucceeded
+	result, err := tx.ExecContext(ctx, query, refund.PaymentID, refund.Amount, succeeded, refund.Currency)
+	if err != nil {
+		return fmt.Errorf("failed to settle refund on payment: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to settle refund on payment: %w", err)
+	}
+	if rows != 1 {
+		return fmt.Errorf("failed to settle refund on payment: %w: refund in %s", models.ErrCurrencyMismatch, refund.Currency)
+	}
+
+	return nil
+}
+
+// UpdateSettlement stores the settlement details of a refund
+func (r *RefundRepository) UpdateSettlement(ctx context.Context, refund *models.Refund) error {
+	query := `
+		UPDATE refunds SET
+			provider_fee = $2,
+			net_amount = $3,
+			settlement_currency = $4,
+			exchange_rate = $5,
+			available_on = $6,
+			balance_transaction_id = $7
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		refund.ID,
+		refund.ProviderFee,
+		refund.NetAmount,
+		refund.SettlementCurrency,
+		refund.ExchangeRate,
+		refund.AvailableOn,
+		refund.BalanceTransactionID,
+	).Scan(&refund.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to update refund settlement: %w", err)
 	}
 
 	return nil
@@ -5,8 +5,10 @@ import (
 	"database/sql"
 	"fmt"
 	"payment-service/internal/models"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type SubscriptionRepository struct {
@@ -17,20 +19,29 @@ func NewSubscriptionRepository(db *sql.DB) *SubscriptionRepository {
 	return &SubscriptionRepository{db: db}
 }
 
-// Create creates a new subscription
+// Create creates a new subscription with its items and metered prices
 func (r *SubscriptionRepository) Create(ctx context.Context, subscription *models.Subscription) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO subscriptions (
 			customer_id, provider, provider_subscription_id,
 			amount, currency, interval, interval_count,
 			status, current_period_start, current_period_end,
-			trial_start, trial_end, cancel_at_period_end,
-			canceled_at, metadata
+			trial_start, trial_end, trial_end_behavior, cancel_at_period_end,
+			canceled_at, pause_behavior, paused_at, resumes_at,
+			discount_amount, coupon_id, promotion_code_id, discount_ends_at,
+			tax_amount, tax_breakdown, next_billing_at, metadata
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
+			$19, $20, $21, $22, $23, $24, $25, $26
 		) RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		subscription.CustomerID,
@@ -45,8 +56,19 @@ func (r *SubscriptionRepository) Create(ctx context.Context, subscription *model
 		subscription.CurrentPeriodEnd,
 		subscription.TrialStart,
 		subscription.TrialEnd,
+		subscription.TrialEndBehavior,
 		subscription.CancelAtPeriodEnd,
 		subscription.CanceledAt,
+		subscription.PauseBehavior,
+		subscription.PausedAt,
+		subscription.ResumesAt,
+		subscription.DiscountAmount,
+		subscription.CouponID,
+		subscription.PromotionCodeID,
+		subscription.DiscountEndsAt,
+		subscription.TaxAmount,
+		subscription.TaxBreakdown,
+		subscription.NextBillingAt,
 		subscription.Metadata,
 	).Scan(&subscription.ID, &subscription.CreatedAt, &subscription.UpdatedAt)
 
@@ -54,6 +76,36 @@ func (r *SubscriptionRepository) Create(ctx context.Context, subscription *model
 		return fmt.Errorf("failed to create subscription: %w", err)
 	}
 
+	for i := range subscription.Items {
+		if err := upsertItem(ctx, tx, subscription.ID, &subscription.Items[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range subscription.MeteredPrices {
+		price := &subscription.MeteredPrices[i]
+		price.SubscriptionID = subscription.ID
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO subscription_metered_prices (
+				subscription_id, meter, unit_amount, aggregation, provider_item_id
+			) VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at`,
+			price.SubscriptionID,
+			price.Meter,
+			price.UnitAmount,
+			price.Aggregation,
+			price.ProviderItemID,
+		).Scan(&price.ID, &price.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create metered price: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit subscription: %w", err)
+	}
+
 	return nil
 }
 
@@ -64,10 +116,16 @@ func (r *SubscriptionRepository) GetByID(ctx context.Context, id uuid.UUID) (*mo
 			id, customer_id, provider, provider_subscription_id,
 			amount, currency, interval, interval_count,
 			status, current_period_start, current_period_end,
-			trial_start, trial_end, cancel_at_period_end,
-			canceled_at, metadata, created_at, updated_at
+			trial_start, trial_end, trial_end_behavior, cancel_at, cancel_at_period_end,
+			canceled_at, cancellation_reason, cancellation_feedback,
+			pause_behavior, paused_at, resumes_at,
+			discount_amount, coupon_id, promotion_code_id, discount_ends_at,
+			tax_amount, tax_breakdown,
+			dunning_status, dunning_invoice_id, dunning_attempts, dunning_started_at,
+			next_retry_at, grace_period_ends_at, latest_payment_id, next_billing_at,
+			metadata, created_at, updated_at
 		FROM subscriptions
-		WHERE id = $1 AND deleted_at IS NULL`
+		WHERE id = $1`
 
 	subscription := &models.Subscription{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
@@ -84,8 +142,29 @@ func (r *SubscriptionRepository) GetByID(ctx context.Context, id uuid.UUID) (*mo
 		&subscription.CurrentPeriodEnd,
 		&subscription.TrialStart,
 		&subscription.TrialEnd,
+		&subscription.TrialEndBehavior,
+		&subscription.CancelAt,
 		&subscription.CancelAtPeriodEnd,
 		&subscription.CanceledAt,
+		&subscription.CancellationReason,
+		&subscription.CancellationFeedback,
+		&subscription.PauseBehavior,
+		&subscription.PausedAt,
+		&subscription.ResumesAt,
+		&subscription.DiscountAmount,
+		&subscription.CouponID,
+		&subscription.PromotionCodeID,
+		&subscription.DiscountEndsAt,
+		&subscription.TaxAmount,
+		&subscription.TaxBreakdown,
+		&subscription.DunningStatus,
+		&subscription.DunningInvoiceID,
+		&subscription.DunningAttempts,
+		&subscription.DunningStartedAt,
+		&subscription.NextRetryAt,
+		&subscription.GracePeriodEndsAt,
+		&subscription.LatestPaymentID,
+		&subscription.NextBillingAt,
 		&subscription.Metadata,
 		&subscription.CreatedAt,
 		&subscription.UpdatedAt,
@@ -98,6 +177,10 @@ func (r *SubscriptionRepository) GetByID(ctx context.Context, id uuid.UUID) (*mo
 		return nil, fmt.Errorf("failed to get subscription: %w", err)
 	}
 
+	if err := r.loadDetails(ctx, subscription); err != nil {
+		return nil, err
+	}
+
 	return subscription, nil
 }
 
@@ -108,10 +191,16 @@ func (r *SubscriptionRepository) GetByProviderSubscriptionID(ctx context.Context
 			id, customer_id, provider, provider_subscription_id,
 			amount, currency, interval, interval_count,
 			status, current_period_start, current_period_end,
-			trial_start, trial_end, cancel_at_period_end,
-			canceled_at, metadata, created_at, updated_at
+			trial_start, trial_end, trial_end_behavior, cancel_at, cancel_at_period_end,
+			canceled_at, cancellation_reason, cancellation_feedback,
+			pause_behavior, paused_at, resumes_at,
+			discount_amount, coupon_id, promotion_code_id, discount_ends_at,
+			tax_amount, tax_breakdown,
+			dunning_status, dunning_invoice_id, dunning_attempts, dunning_started_at,
+			next_retry_at, grace_period_ends_at, latest_payment_id, next_billing_at,
+			metadata, created_at, updated_at
 		FROM subscriptions
-		WHERE provider_subscription_id = $1 AND deleted_at IS NULL`
+		WHERE provider_subscription_id = $1`
 
 	subscription := &models.Subscription{}
 	err := r.db.QueryRowContext(ctx, query, providerSubscriptionID).Scan(
@@ -128,8 +217,29 @@ func (r *SubscriptionRepository) GetByProviderSubscriptionID(ctx context.Context
 		&subscription.CurrentPeriodEnd,
 		&subscription.TrialStart,
 		&subscription.TrialEnd,
+		&subscription.TrialEndBehavior,
+		&subscription.CancelAt,
 		&subscription.CancelAtPeriodEnd,
 		&subscription.CanceledAt,
+		&subscription.CancellationReason,
+		&subscription.CancellationFeedback,
+		&subscription.PauseBehavior,
+		&subscription.PausedAt,
+		&subscription.ResumesAt,
+		&subscription.DiscountAmount,
+		&subscription.CouponID,
+		&subscription.PromotionCodeID,
+		&subscription.DiscountEndsAt,
+		&subscription.TaxAmount,
+		&subscription.TaxBreakdown,
+		&subscription.DunningStatus,
+		&subscription.DunningInvoiceID,
+		&subscription.DunningAttempts,
+		&subscription.DunningStartedAt,
+		&subscription.NextRetryAt,
+		&subscription.GracePeriodEndsAt,
+		&subscription.LatestPaymentID,
+		&subscription.NextBillingAt,
 		&subscription.Metadata,
 		&subscription.CreatedAt,
 		&subscription.UpdatedAt,
@@ -142,6 +252,10 @@ func (r *SubscriptionRepository) GetByProviderSubscriptionID(ctx context.Context
 		return nil, fmt.Errorf("failed to get subscription by provider ID: %w", err)
 	}
 
+	if err := r.loadDetails(ctx, subscription); err != nil {
+		return nil, err
+	}
+
 	return subscription, nil
 }
 
@@ -149,7 +263,7 @@ func (r *SubscriptionRepository) GetByProviderSubscriptionID(ctx context.Context
 func (r *SubscriptionRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Subscription, int, error) {
 	// Get total count
 	var total int
-	countQuery := `SELECT COUNT(*) FROM subscriptions WHERE customer_id = $1 AND deleted_at IS NULL`
+	countQuery := `SELECT COUNT(*) FROM subscriptions WHERE customer_id = $1`
 	err := r.db.QueryRowContext(ctx, countQuery, customerID).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count subscriptions: %w", err)
@@ -161,10 +275,16 @@ func (r *SubscriptionRepository) ListByCustomer(ctx context.Context, customerID
 			id, customer_id, provider, provider_subscription_id,
 			amount, currency, interval, interval_count,
 			status, current_period_start, current_period_end,
-			trial_start, trial_end, cancel_at_period_end,
-			canceled_at, metadata, created_at, updated_at
+			trial_start, trial_end, trial_end_behavior, cancel_at, cancel_at_period_end,
+			canceled_at, cancellation_reason, cancellation_feedback,
+			pause_behavior, paused_at, resumes_at,
+			discount_amount, coupon_id, promotion_code_id, discount_ends_at,
+			tax_amount, tax_breakdown,
+			dunning_status, dunning_invoice_id, dunning_attempts, dunning_started_at,
+			next_retry_at, grace_period_ends_at, latest_payment_id, next_billing_at,
+			metadata, created_at, updated_at
 		FROM subscriptions
-		WHERE customer_id = $1 AND deleted_at IS NULL
+		WHERE customer_id = $1
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3`
 
@@ -191,8 +311,29 @@ func (r *SubscriptionRepository) ListByCustomer(ctx context.Context, customerID
 			&subscription.CurrentPeriodEnd,
 			&subscription.TrialStart,
 			&subscription.TrialEnd,
+			&subscription.TrialEndBehavior,
+			&subscription.CancelAt,
 			&subscription.CancelAtPeriodEnd,
 			&subscription.CanceledAt,
+			&subscription.CancellationReason,
+			&subscription.CancellationFeedback,
+			&subscription.PauseBehavior,
+			&subscription.PausedAt,
+			&subscription.ResumesAt,
+			&subscription.DiscountAmount,
+			&subscription.CouponID,
+			&subscription.PromotionCodeID,
+			&subscription.DiscountEndsAt,
+			&subscription.TaxAmount,
+			&subscription.TaxBreakdown,
+			&subscription.DunningStatus,
+			&subscription.DunningInvoiceID,
+			&subscription.DunningAttempts,
+			&subscription.DunningStartedAt,
+			&subscription.NextRetryAt,
+			&subscription.GracePeriodEndsAt,
+			&subscription.LatestPaymentID,
+			&subscription.NextBillingAt,
 			&subscription.Metadata,
 			&subscription.CreatedAt,
 			&subscription.UpdatedAt,
@@ -207,9 +348,254 @@ func (r *SubscriptionRepository) ListByCustomer(ctx context.Context, customerID
 		return nil, 0, fmt.Errorf("error iterating subscriptions: %w", err)
 	}
 
+	page := make([]*models.Subscription, len(subscriptions))
+	for i := range subscriptions {
+		page[i] = &subscriptions[i]
+	}
+	if err := r.loadDetails(ctx, page...); err != nil {
+		return nil, 0, err
+	}
+
 	return subscriptions, total, nil
 }
 
+// loadDetails fills in the items, metered prices and pending schedule of subscriptions
+func (r *SubscriptionRepository) loadDetails(ctx context.Context, subscriptions ...*models.Subscription) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(subscriptions))
+	byID := make(map[uuid.UUID]*models.Subscription, len(subscriptions))
+	for i, subscription := range subscriptions {
+		ids[i] = subscription.ID.String()
+		byID[subscription.ID] = subscription
+	}
+
+	if err := r.loadItems(ctx, ids, byID); err != nil {
+		return err
+	}
+	if err := r.loadSchedules(ctx, ids, byID); err != nil {
+		return err
+	}
+
+	query := `
+		SELECT id, subscription_id, meter, unit_amount, aggregation, provider_item_id, created_at
+		FROM subscription_metered_prices
+		WHERE subscription_id = ANY($1::uuid[])
+		ORDER BY created_at, meter`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to list metered prices: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var price models.MeteredPrice
+		err := rows.Scan(
+			&price.ID,
+			&price.SubscriptionID,
+			&price.Meter,
+			&price.UnitAmount,
+			&price.Aggregation,
+			&price.ProviderItemID,
+			&price.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan metered price: %w", err)
+		}
+		subscription := byID[price.SubscriptionID]
+		subscription.MeteredPrices = append(subscription.MeteredPrices, price)
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating metered prices: %w", err)
+	}
+
+	return nil
+}
+
+// loadItems fills in the items of subscriptions, keyed by ID
+func (r *SubscriptionRepository) loadItems(ctx context.Context, ids []string, byID map[uuid.UUID]*models.Subscription) error {
+	query := `
+		SELECT id, subscription_id, product_name, unit_amount, quantity, provider_item_id, created_at, updated_at
+		FROM subscription_items
+		WHERE subscription_id = ANY($1::uuid[])
+		ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to list subscription items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.SubscriptionItem
+		err := rows.Scan(
+			&item.ID,
+			&item.SubscriptionID,
+			&item.ProductName,
+			&item.UnitAmount,
+			&item.Quantity,
+			&item.ProviderItemID,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan subscription item: %w", err)
+		}
+		subscription := byID[item.SubscriptionID]
+		subscription.Items = append(subscription.Items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating subscription items: %w", err)
+	}
+
+	return nil
+}
+
+// loadSchedules fills in the schedules of subscriptions that have not ended, keyed by ID
+func (r *SubscriptionRepository) loadSchedules(ctx context.Context, ids []string, byID map[uuid.UUID]*models.Subscription) error {
+	query := `SELECT ` + subscriptionScheduleColumns + `
+		FROM subscription_schedules
+		WHERE subscription_id = ANY($1::uuid[]) AND status IN ('not_started', 'active')`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to list subscription schedules: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		schedule, err := scanSubscriptionSchedule(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan subscription schedule: %w", err)
+		}
+		byID[*schedule.SubscriptionID].Schedule = schedule
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating subscription schedules: %w", err)
+	}
+
+	return nil
+}
+
+// SaveItems replaces a subscription's items with subscription.Items and
+// stores its amount, billing interval and tax. Items are matched by ID, or by
+// provider item ID for items synced from the provider.
+func (r *SubscriptionRepository) SaveItems(ctx context.Context, subscription *models.Subscription) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE subscriptions SET
+			amount = $1,
+			interval = $2,
+			interval_count = $3,
+			tax_amount = $4,
+			tax_breakdown = $5,
+			updated_at = NOW()
+		WHERE id = $6
+		RETURNING updated_at`
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		subscription.Amount,
+		subscription.Interval,
+		subscription.IntervalCount,
+		subscription.TaxAmount,
+		subscription.TaxBreakdown,
+		subscription.ID,
+	).Scan(&subscription.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("subscription not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update subscription amount: %w", err)
+	}
+
+	kept := make([]string, len(subscription.Items))
+	for i := range subscription.Items {
+		if err := upsertItem(ctx, tx, subscription.ID, &subscription.Items[i]); err != nil {
+			return err
+		}
+		kept[i] = subscription.Items[i].ID.String()
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM subscription_items WHERE subscription_id = $1 AND NOT (id = ANY($2::uuid[]))`,
+		subscription.ID,
+		pq.Array(kept),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription items: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit subscription items: %w", err)
+	}
+
+	return nil
+}
+
+// upsertItem stores a subscription item, updating it if it has an ID or its
+// provider item is already stored
+func upsertItem(ctx context.Context, tx *sql.Tx, subscriptionID uuid.UUID, item *models.SubscriptionItem) error {
+	item.SubscriptionID = subscriptionID
+
+	if item.ID != uuid.Nil {
+		err := tx.QueryRowContext(ctx, `
+			UPDATE subscription_items SET
+				product_name = $1,
+				unit_amount = $2,
+				quantity = $3,
+				provider_item_id = $4,
+				updated_at = NOW()
+			WHERE id = $5 AND subscription_id = $6
+			RETURNING updated_at`,
+			item.ProductName,
+			item.UnitAmount,
+			item.Quantity,
+			item.ProviderItemID,
+			item.ID,
+			subscriptionID,
+		).Scan(&item.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update subscription item: %w", err)
+		}
+		return nil
+	}
+
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO subscription_items (
+			subscription_id, product_name, unit_amount, quantity, provider_item_id
+		) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (subscription_id, provider_item_id) DO UPDATE SET
+			product_name = EXCLUDED.product_name,
+			unit_amount = EXCLUDED.unit_amount,
+			quantity = EXCLUDED.quantity,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at`,
+		subscriptionID,
+		item.ProductName,
+		item.UnitAmount,
+		item.Quantity,
+		item.ProviderItemID,
+	).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create subscription item: %w", err)
+	}
+
+	return nil
+}
+
 // Update updates a subscription
 func (r *SubscriptionRepository) Update(ctx context.Context, subscription *models.Subscription) error {
 	query := `
@@ -217,11 +603,27 @@ func (r *SubscriptionRepository) Update(ctx context.Context, subscription *model
 			status = $1,
 			current_period_start = $2,
 			current_period_end = $3,
-			cancel_at_period_end = $4,
-			canceled_at = $5,
-			metadata = $6,
+			trial_start = $4,
+			trial_end = $5,
+			cancel_at = $6,
+			cancel_at_period_end = $7,
+			canceled_at = $8,
+			cancellation_reason = $9,
+			cancellation_feedback = $10,
+			pause_behavior = $11,
+			paused_at = $12,
+			resumes_at = $13,
+			dunning_status = $14,
+			dunning_invoice_id = $15,
+			dunning_attempts = $16,
+			dunning_started_at = $17,
+			next_retry_at = $18,
+			grace_period_ends_at = $19,
+			latest_payment_id = $20,
+			next_billing_at = $21,
+			metadata = $22,
 			updated_at = NOW()
-		WHERE id = $7 AND deleted_at IS NULL
+		WHERE id = $23
 		RETURNING updated_at`
 
 	err := r.db.QueryRowContext(
@@ -230,8 +632,24 @@ func (r *SubscriptionRepository) Update(ctx context.Context, subscription *model
 		subscription.Status,
 		subscription.CurrentPeriodStart,
 		subscription.CurrentPeriodEnd,
+		subscription.TrialStart,
+		subscription.TrialEnd,
+		subscription.CancelAt,
 		subscription.CancelAtPeriodEnd,
 		subscription.CanceledAt,
+		subscription.CancellationReason,
+		subscription.CancellationFeedback,
+		subscription.PauseBehavior,
+		subscription.PausedAt,
+		subscription.ResumesAt,
+		subscription.DunningStatus,
+		subscription.DunningInvoiceID,
+		subscription.DunningAttempts,
+		subscription.DunningStartedAt,
+		subscription.NextRetryAt,
+		subscription.GracePeriodEndsAt,
+		subscription.LatestPaymentID,
+		subscription.NextBillingAt,
 		subscription.Metadata,
 		subscription.ID,
 	).Scan(&subscription.UpdatedAt)
@@ -245,3 +663,287 @@ func (r *SubscriptionRepository) Update(ctx context.Context, subscription *model
 
 	return nil
 }
+
+// ListDunningDue retrieves subscriptions in dunning whose next retry or grace
+// period end has passed
+func (r *SubscriptionRepository) ListDunningDue(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error) {
+	query := `
+		SELECT
+			id, customer_id, provider, provider_subscription_id,
+			amount, currency, interval, interval_count,
+			status, current_period_start, current_period_end,
+			trial_start, trial_end, trial_end_behavior, cancel_at, cancel_at_period_end,
+			canceled_at, cancellation_reason, cancellation_feedback,
+			pause_behavior, paused_at, resumes_at,
+			discount_amount, coupon_id, promotion_code_id, discount_ends_at,
+			tax_amount, tax_breakdown,
+			dunning_status, dunning_invoice_id, dunning_attempts, dunning_started_at,
+			next_retry_at, grace_period_ends_at, latest_payment_id, next_billing_at,
+			metadata, created_at, updated_at
+		FROM subscriptions
+		WHERE dunning_status = 'retrying'
+			AND (next_retry_at <= $1 OR grace_period_ends_at <= $1)
+		ORDER BY COALESCE(next_retry_at, grace_period_ends_at) ASC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions due for dunning: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []models.Subscription
+	for rows.Next() {
+		var subscription models.Subscription
+		err := rows.Scan(
+			&subscription.ID,
+			&subscription.CustomerID,
+			&subscription.Provider,
+			&subscription.ProviderSubscriptionID,
+			&subscription.Amount,
+			&subscription.Currency,
+			&subscription.Interval,
+			&subscription.IntervalCount,
+			&subscription.Status,
+			&subscription.CurrentPeriodStart,
+			&subscription.CurrentPeriodEnd,
+			&subscription.TrialStart,
+			&subscription.TrialEnd,
+			&subscription.TrialEndBehavior,
+			&subscription.CancelAt,
+			&subscription.CancelAtPeriodEnd,
+			&subscription.CanceledAt,
+			&subscription.CancellationReason,
+			&subscription.CancellationFeedback,
+			&subscription.PauseBehavior,
+			&subscription.PausedAt,
+			&subscription.ResumesAt,
+			&subscription.DiscountAmount,
+			&subscription.CouponID,
+			&subscription.PromotionCodeID,
+			&subscription.DiscountEndsAt,
+			&subscription.TaxAmount,
+			&subscription.TaxBreakdown,
+			&subscription.DunningStatus,
+			&subscription.DunningInvoiceID,
+			&subscription.DunningAttempts,
+			&subscription.DunningStartedAt,
+			&subscription.NextRetryAt,
+			&subscription.GracePeriodEndsAt,
+			&subscription.LatestPaymentID,
+			&subscription.NextBillingAt,
+			&subscription.Metadata,
+			&subscription.CreatedAt,
+			&subscription.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// ClaimDunningRetry atomically takes ownership of a scheduled retry by clearing
+// next_retry_at, so only one instance attempts the payment. Returns false if
+// another worker or a webhook got there first.
+func (r *SubscriptionRepository) ClaimDunningRetry(ctx context.Context, id uuid.UUID, nextRetryAt time.Time) (bool, error) {
+	query := `
+		UPDATE subscriptions SET
+			next_retry_at = NULL,
+			updated_at = NOW()
+		WHERE id = $1 AND dunning_status = 'retrying' AND next_retry_at = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, nextRetryAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim dunning retry: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim dunning retry: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+// ClaimDunningExhaustion atomically moves a subscription whose grace period
+// has ended out of the retrying state. Returns false if it was already
+// handled or recovered.
+func (r *SubscriptionRepository) ClaimDunningExhaustion(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	query := `
+		UPDATE subscriptions SET
+			dunning_status = 'exhausted',
+			next_retry_at = NULL,
+			updated_at = NOW()
+		WHERE id = $1 AND dunning_status = 'retrying' AND grace_period_ends_at <= $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim dunning exhaustion: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim dunning exhaustion: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+// ListBillingDue retrieves natively billed subscriptions whose next billing
+// run is due
+func (r *SubscriptionRepository) ListBillingDue(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error) {
+	query := `
+		SELECT
+			id, customer_id, provider, provider_subscription_id,
+			amount, currency, interval, interval_count,
+			status, current_period_start, current_period_end,
+			trial_start, trial_end, trial_end_behavior, cancel_at, cancel_at_period_end,
+			canceled_at, cancellation_reason, cancellation_feedback,
+			pause_behavior, paused_at, resumes_at,
+			discount_amount, coupon_id, promotion_code_id, discount_ends_at,
+			tax_amount, tax_breakdown,
+			dunning_status, dunning_invoice_id, dunning_attempts, dunning_started_at,
+			next_retry_at, grace_period_ends_at, latest_payment_id, next_billing_at,
+			metadata, created_at, updated_at
+		FROM subscriptions
+		WHERE next_billing_at <= $1
+		ORDER BY next_billing_at ASC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions due for billing: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []models.Subscription
+	for rows.Next() {
+		var subscription models.Subscription
+		err := rows.Scan(
+			&subscription.ID,
+			&subscription.CustomerID,
+			&subscription.Provider,
+			&subscription.ProviderSubscriptionID,
+			&subscription.Amount,
+			&subscription.Currency,
+			&subscription.Interval,
+			&subscription.IntervalCount,
+			&subscription.Status,
+			&subscription.CurrentPeriodStart,
+			&subscription.CurrentPeriodEnd,
+			&subscription.TrialStart,
+			&subscription.TrialEnd,
+			&subscription.TrialEndBehavior,
+			&subscription.CancelAt,
+			&subscription.CancelAtPeriodEnd,
+			&subscription.CanceledAt,
+			&subscription.CancellationReason,
+			&subscription.CancellationFeedback,
+			&subscription.PauseBehavior,
+			&subscription.PausedAt,
+			&subscription.ResumesAt,
+			&subscription.DiscountAmount,
+			&subscription.CouponID,
+			&subscription.PromotionCodeID,
+			&subscription.DiscountEndsAt,
+			&subscription.TaxAmount,
+			&subscription.TaxBreakdown,
+			&subscription.DunningStatus,
+			&subscription.DunningInvoiceID,
+			&subscription.DunningAttempts,
+			&subscription.DunningStartedAt,
+			&subscription.NextRetryAt,
+			&subscription.GracePeriodEndsAt,
+			&subscription.LatestPaymentID,
+			&subscription.NextBillingAt,
+			&subscription.Metadata,
+			&subscription.CreatedAt,
+			&subscription.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// ClaimBilling atomically takes ownership of a due billing run by moving
+// next_billing_at to leaseUntil, so only one instance renews or charges the
+// subscription. A run that dies part way is picked up again once the lease
+// has passed. Returns false if another worker got there first.
+func (r *SubscriptionRepository) ClaimBilling(ctx context.Context, id uuid.UUID, nextBillingAt, leaseUntil time.Time) (bool, error) {
+	query := `
+		UPDATE subscriptions SET
+			next_billing_at = $3,
+			updated_at = NOW()
+		WHERE id = $1 AND next_billing_at = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, nextBillingAt, leaseUntil)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim billing run: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim billing run: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+// SaveBillingRun stores what a billing run changed: the status, the billing
+// period, the cancellation time, the latest payment and the next billing run.
+// Only these columns are written, and only while the run still holds its
+// claim (next_billing_at is still leaseUntil) and the status is still status,
+// the one the run started from or last saved, so a cancellation or other
+// change made while the run was in progress is kept. Returns false if the
+// subscription changed under the run.
+func (r *SubscriptionRepository) SaveBillingRun(ctx context.Context, subscription *models.Subscription, status models.SubscriptionStatus, leaseUntil time.Time) (bool, error) {
+	query := `
+		UPDATE subscriptions SET
+			status = $2,
+			current_period_start = $3,
+			current_period_end = $4,
+			canceled_at = $5,
+			latest_payment_id = $6,
+			next_billing_at = $7,
+			updated_at = NOW()
+		WHERE id = $1 AND next_billing_at = $8 AND status = $9
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		subscription.ID,
+		subscription.Status,
+		subscription.CurrentPeriodStart,
+		subscription.CurrentPeriodEnd,
+		subscription.CanceledAt,
+		subscription.LatestPaymentID,
+		subscription.NextBillingAt,
+		leaseUntil,
+		status,
+	).Scan(&subscription.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to save billing run: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionRepository_ListDunningDue(t *testing.T) {
+	// Setup
+	db, fake := newFakeDB(t, fakeResult{})
+	repo := NewSubscriptionRepository(db)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	// Execute
+	subscriptions, err := repo.ListDunningDue(context.Background(), now, 50)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, subscriptions)
+
+	statements := fake.statements()
+	assert.Len(t, statements, 1)
+	assert.Equal(t, []driver.Value{now, int64(50)}, statements[0].args)
+	// subscriptions has no deleted_at column, so filtering on it fails in Postgres
+	assert.NotContains(t, statements[0].query, "deleted_at")
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const subscriptionScheduleColumns = `
+	id, customer_id, subscription_id, provider, provider_schedule_id,
+	status, end_behavior, phases, current_phase, next_transition_at,
+	currency, product_name, tax_category, tax_behavior, billing_country, vat_id,
+	metadata, ended_at, created_at, updated_at`
+
+type SubscriptionScheduleRepository struct {
+	db *sql.DB
+}
+
+func NewSubscriptionScheduleRepository(db *sql.DB) *SubscriptionScheduleRepository {
+	return &SubscriptionScheduleRepository{db: db}
+}
+
+// Create stores a new subscription schedule
+func (r *SubscriptionScheduleRepository) Create(ctx context.Context, schedule *models.SubscriptionSchedule) error {
+	query := `
+		INSERT INTO subscription_schedules (
+			customer_id, subscription_id, provider, provider_schedule_id,
+			status, end_behavior, phases, current_phase, next_transition_at,
+			currency, product_name, tax_category, tax_behavior, billing_country, vat_id,
+			metadata
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		schedule.CustomerID,
+		schedule.SubscriptionID,
+		schedule.Provider,
+		schedule.ProviderScheduleID,
+		schedule.Status,
+		schedule.EndBehavior,
+		schedule.Phases,
+		schedule.CurrentPhase,
+		schedule.NextTransitionAt,
+		schedule.Currency,
+		schedule.ProductName,
+		schedule.TaxCategory,
+		schedule.TaxBehavior,
+		schedule.BillingCountry,
+		schedule.VATID,
+		schedule.Metadata,
+	).Scan(&schedule.ID, &schedule.CreatedAt, &schedule.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create subscription schedule: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a subscription schedule by ID
+func (r *SubscriptionScheduleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.SubscriptionSchedule, error) {
+	query := `SELECT ` + subscriptionScheduleColumns + ` FROM subscription_schedules WHERE id = $1`
+
+	schedule, err := scanSubscriptionSchedule(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+// ListByCustomer retrieves a customer's subscription schedules, newest first
+func (r *SubscriptionScheduleRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.SubscriptionSchedule, int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM subscription_schedules WHERE customer_id = $1`,
+		customerID,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count subscription schedules: %w", err)
+	}
+
+	query := `SELECT ` + subscriptionScheduleColumns + `
+		FROM subscription_schedules
+		WHERE customer_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	schedules, err := r.list(ctx, query, customerID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return schedules, total, nil
+}
+
+// ListDue retrieves schedules whose next phase or end is due, oldest first
+func (r *SubscriptionScheduleRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.SubscriptionSchedule, error) {
+	query := `SELECT ` + subscriptionScheduleColumns + `
+		FROM subscription_schedules
+		WHERE next_transition_at <= $1
+		ORDER BY next_transition_at ASC
+		LIMIT $2`
+
+	return r.list(ctx, query, now, limit)
+}
+
+func (r *SubscriptionScheduleRepository) list(ctx context.Context, query string, args ...any) ([]models.SubscriptionSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscription schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []models.SubscriptionSchedule{}
+	for rows.Next() {
+		schedule, err := scanSubscriptionSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription schedule: %w", err)
+		}
+		schedules = append(schedules, *schedule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscription schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// ClaimTransition atomically takes ownership of a schedule's next transition
+// by clearing next_transition_at, so only one instance applies it. Returns
+// false if another worker or a cancellation got there first.
+func (r *SubscriptionScheduleRepository) ClaimTransition(ctx context.Context, id uuid.UUID, transitionAt time.Time) (bool, error) {
+	query := `
+		UPDATE subscription_schedules SET
+			next_transition_at = NULL,
+			updated_at = NOW()
+		WHERE id = $1 AND next_transition_at = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, transitionAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim schedule transition: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim schedule transition: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+// Update stores a schedule's progress
+func (r *SubscriptionScheduleRepository) Update(ctx context.Context, schedule *models.SubscriptionSchedule) error {
+	query := `
+		UPDATE subscription_schedules SET
+			subscription_id = $1,
+			provider_schedule_id = $2,
+			status = $3,
+			current_phase = $4,
+			next_transition_at = $5,
+			ended_at = $6,
+			updated_at = NOW()
+		WHERE id = $7
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		schedule.SubscriptionID,
+		schedule.ProviderScheduleID,
+		schedule.Status,
+		schedule.CurrentPhase,
+		schedule.NextTransitionAt,
+		schedule.EndedAt,
+		schedule.ID,
+	).Scan(&schedule.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("subscription schedule not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update subscription schedule: %w", err)
+	}
+
+	return nil
+}
+
+func scanSubscriptionSchedule(row rowScanner) (*models.SubscriptionSchedule, error) {
+	schedule := &models.SubscriptionSchedule{}
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.CustomerID,
+		&schedule.SubscriptionID,
+		&schedule.Provider,
+		&schedule.ProviderScheduleID,
+		&schedule.Status,
+		&schedule.EndBehavior,
+		&schedule.Phases,
+		&schedule.CurrentPhase,
+		&schedule.NextTransitionAt,
+		&schedule.Currency,
+		&schedule.ProductName,
+		&schedule.TaxCategory,
+		&schedule.TaxBehavior,
+		&schedule.BillingCountry,
+		&schedule.VATID,
+		&schedule.Metadata,
+		&schedule.EndedAt,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
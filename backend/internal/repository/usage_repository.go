@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const usageRecordColumns = `
+	id, subscription_id, metered_price_id, record_id, quantity, timestamp,
+	period_start, period_end, reported_at, invoice_id, created_at`
+
+type UsageRepository struct {
+	db *sql.DB
+}
+
+func NewUsageRepository(db *sql.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// CreateRecord stores a usage record. Returns false and fills in record with
+// the stored one if the subscription already has a record with its RecordID.
+func (r *UsageRepository) CreateRecord(ctx context.Context, record *models.UsageRecord) (bool, error) {
+	query := `
+		INSERT INTO usage_records (
+			subscription_id, metered_price_id, record_id, quantity, timestamp,
+			period_start, period_end
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (subscription_id, record_id) DO NOTHING
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		record.SubscriptionID,
+		record.MeteredPriceID,
+		record.RecordID,
+		record.Quantity,
+		record.Timestamp,
+		record.PeriodStart,
+		record.PeriodEnd,
+	).Scan(&record.ID, &record.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		existing, err := scanUsageRecord(r.db.QueryRowContext(
+			ctx,
+			`SELECT `+usageRecordColumns+` FROM usage_records WHERE subscription_id = $1 AND record_id = $2`,
+			record.SubscriptionID,
+			record.RecordID,
+		))
+		if err != nil {
+			return false, fmt.Errorf("failed to get usage record: %w", err)
+		}
+		*record = *existing
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create usage record: %w", err)
+	}
+
+	return true, nil
+}
+
+// MarkReported records that a usage record was sent to the provider
+func (r *UsageRepository) MarkReported(ctx context.Context, id uuid.UUID, reportedAt time.Time) error {
+	query := `UPDATE usage_records SET reported_at = $2 WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id, reportedAt); err != nil {
+		return fmt.Errorf("failed to mark usage record reported: %w", err)
+	}
+
+	return nil
+}
+
+// ListUnreported lists usage records created before the given time that
+// still have to be sent to the provider, oldest first
+func (r *UsageRepository) ListUnreported(ctx context.Context, before time.Time, limit int) ([]models.UsageRecord, error) {
+	query := `
+		SELECT r.id, r.subscription_id, r.metered_price_id, r.record_id, r.quantity, r.timestamp,
+			r.period_start, r.period_end, r.reported_at, r.invoice_id, r.created_at
+		FROM usage_records r
+		JOIN subscription_metered_prices p ON p.id = r.metered_price_id
+		WHERE r.reported_at IS NULL AND p.provider_item_id IS NOT NULL AND r.created_at < $1
+		ORDER BY r.created_at
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unreported usage: %w", err)
+	}
+	defer rows.Close()
+
+	records := []models.UsageRecord{}
+	for rows.Next() {
+		record, err := scanUsageRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage record: %w", err)
+		}
+		records = append(records, *record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage records: %w", err)
+	}
+
+	return records, nil
+}
+
+// Summarize aggregates a subscription's usage in the billing period starting
+// at periodStart, with one summary per metered price
+func (r *UsageRepository) Summarize(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time) ([]models.UsageSummary, error) {
+	query := `
+		SELECT p.id, p.meter, p.aggregation, p.unit_amount,
+			COALESCE(CASE p.aggregation
+				WHEN 'sum' THEN SUM(r.quantity)
+				WHEN 'max' THEN MAX(r.quantity)
+				ELSE (ARRAY_AGG(r.quantity ORDER BY r.timestamp DESC, r.created_at DESC))[1]
+			END, 0)
+		FROM subscription_metered_prices p
+		LEFT JOIN usage_records r ON r.metered_price_id = p.id AND r.period_start = $2
+		WHERE p.subscription_id = $1
+		GROUP BY p.id
+		ORDER BY p.created_at, p.meter`
+
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, periodStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize usage: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []models.UsageSummary{}
+	for rows.Next() {
+		var summary models.UsageSummary
+		err := rows.Scan(
+			&summary.MeteredPriceID,
+			&summary.Meter,
+			&summary.Aggregation,
+			&summary.UnitAmount,
+			&summary.Quantity,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage summary: %w", err)
+		}
+		summary.Amount = summary.Quantity * summary.UnitAmount
+		summaries = append(summaries, summary)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage summaries: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// ListUninvoicedPeriods lists billing periods ended by now with usage of
+// locally invoiced prices that has not been invoiced yet
+func (r *UsageRepository) ListUninvoicedPeriods(ctx context.Context, now time.Time, limit int) ([]models.UsagePeriod, error) {
+	query := `
+		SELECT DISTINCT r.subscription_id, r.period_start, r.period_end
+		FROM usage_records r
+		JOIN subscription_metered_prices p ON p.id = r.metered_price_id
+		WHERE r.reported_at IS NULL AND r.invoice_id IS NULL
+			AND p.provider_item_id IS NULL AND r.period_end <= $1
+		ORDER BY r.period_end, r.subscription_id
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uninvoiced usage periods: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []models.UsagePeriod{}
+	for rows.Next() {
+		var period models.UsagePeriod
+		if err := rows.Scan(&period.SubscriptionID, &period.PeriodStart, &period.PeriodEnd); err != nil {
+			return nil, fmt.Errorf("failed to scan usage period: %w", err)
+		}
+		periods = append(periods, period)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage periods: %w", err)
+	}
+
+	return periods, nil
+}
+
+// ClaimUninvoiced claims the locally invoiced usage of a billing period that
+// no invoice has claimed yet for the invoice with the given key. Usage
+// recorded after the claim is left for a later invoice.
+func (r *UsageRepository) ClaimUninvoiced(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time, invoiceKey string) error {
+	query := `
+		UPDATE usage_records r
+		SET invoice_key = $3
+		FROM subscription_metered_prices p
+		WHERE p.id = r.metered_price_id AND p.provider_item_id IS NULL
+			AND r.subscription_id = $1 AND r.period_start = $2
+			AND r.invoice_id IS NULL AND r.invoice_key IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, subscriptionID, periodStart, invoiceKey); err != nil {
+		return fmt.Errorf("failed to claim usage for invoice: %w", err)
+	}
+
+	return nil
+}
+
+// ListPendingInvoiceKeys lists the keys of invoices that have claimed usage of
+// a billing period without being marked as billing it yet, oldest claim first
+func (r *UsageRepository) ListPendingInvoiceKeys(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time) ([]string, error) {
+	query := `
+		SELECT invoice_key
+		FROM usage_records
+		WHERE subscription_id = $1 AND period_start = $2
+			AND invoice_key IS NOT NULL AND invoice_id IS NULL
+		GROUP BY invoice_key
+		ORDER BY MIN(created_at), invoice_key`
+
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, periodStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending usage invoices: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan usage invoice key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage invoice keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// SummarizeClaim aggregates the usage a billing period's invoice with the
+// given key has to bill, with one summary per metered price. That is the
+// period's usage including the claim less the usage already invoiced, so a
+// follow-up invoice for late usage bills the extra sum, or how much the max or
+// last value went up; a value that went down is not credited.
+func (r *UsageRepository) SummarizeClaim(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time, invoiceKey string) ([]models.UsageSummary, error) {
+	query := `
+		SELECT p.id, p.meter, p.aggregation, p.unit_amount,
+			GREATEST(` + usageAggregate("r.invoice_id IS NOT NULL OR r.invoice_key = $3") + `
+				- ` + usageAggregate("r.invoice_id IS NOT NULL") + `, 0)
+		FROM subscription_metered_prices p
+		LEFT JOIN usage_records r ON r.metered_price_id = p.id AND r.period_start = $2
+		WHERE p.subscription_id = $1
+		GROUP BY p.id
+		ORDER BY p.created_at, p.meter`
+
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, periodStart, invoiceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize claimed usage: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []models.UsageSummary{}
+	for rows.Next() {
+		var summary models.UsageSummary
+		err := rows.Scan(
+			&summary.MeteredPriceID,
+			&summary.Meter,
+			&summary.Aggregation,
+			&summary.UnitAmount,
+			&summary.Quantity,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan usage summary: %w", err)
+		}
+		summary.Amount = summary.Quantity * summary.UnitAmount
+		summaries = append(summaries, summary)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage summaries: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// usageAggregate aggregates the usage records matching filter the way their
+// metered price does
+func usageAggregate(filter string) string {
+	return `COALESCE(CASE p.aggregation
+				WHEN 'sum' THEN SUM(r.quantity) FILTER (WHERE ` + filter + `)
+				WHEN 'max' THEN MAX(r.quantity) FILTER (WHERE ` + filter + `)
+				ELSE (ARRAY_AGG(r.quantity ORDER BY r.timestamp DESC, r.created_at DESC) FILTER (WHERE ` + filter + `))[1]
+			END, 0)`
+}
+
+// MarkInvoiced links the usage claimed for the invoice with the given key to
+// that invoice
+func (r *UsageRepository) MarkInvoiced(ctx context.Context, invoiceKey string, invoiceID uuid.UUID) error {
+	query := `
+		UPDATE usage_records
+		SET invoice_id = $2, reported_at = NOW()
+		WHERE invoice_key = $1 AND invoice_id IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, invoiceKey, invoiceID); err != nil {
+		return fmt.Errorf("failed to mark usage invoiced: %w", err)
+	}
+
+	return nil
+}
+
+func scanUsageRecord(row rowScanner) (*models.UsageRecord, error) {
+	record := &models.UsageRecord{}
+	err := row.Scan(
+		&record.ID,
+		&record.SubscriptionID,
+		&record.MeteredPriceID,
+		&record.RecordID,
+		&record.Quantity,
+		&record.Timestamp,
+		&record.PeriodStart,
+		&record.PeriodEnd,
+		&record.ReportedAt,
+		&record.InvoiceID,
+		&record.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
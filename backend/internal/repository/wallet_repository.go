@@ -0,0 +1,689 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"payment-service/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// walletColumns selects a wallet with its unspent promotional credit
+const walletColumns = `
+	w.id, w.customer_id, w.currency, w.balance, w.held,
+	COALESCE((SELECT SUM(g.remaining) FROM wallet_grants g WHERE g.wallet_id = w.id), 0),
+	w.created_at, w.updated_at`
+
+const walletHoldColumns = `
+	id, wallet_id, amount, captured_amount, status, idempotency_key, description,
+	created_at, updated_at`
+
+const walletTransactionColumns = `
+	id, wallet_id, type, amount, balance_after, idempotency_key, description,
+	payment_id, hold_id, grant_id, created_at`
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+type WalletRepository struct {
+	db *sql.DB
+}
+
+func NewWalletRepository(db *sql.DB) *WalletRepository {
+	return &WalletRepository{db: db}
+}
+
+// Get retrieves a customer's wallet in a currency
+func (r *WalletRepository) Get(ctx context.Context, customerID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
+	query := `SELECT ` + walletColumns + `
+		FROM wallets w
+		WHERE w.customer_id = $1 AND w.currency = $2`
+
+	wallet, err := scanWallet(r.db.QueryRowContext(ctx, query, customerID, currency))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// GetByID retrieves a wallet by ID
+func (r *WalletRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+	query := `SELECT ` + walletColumns + `
+		FROM wallets w
+		WHERE w.id = $1`
+
+	wallet, err := scanWallet(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// ListByCustomer lists a customer's wallets
+func (r *WalletRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID) ([]models.Wallet, error) {
+	query := `SELECT ` + walletColumns + `
+		FROM wallets w
+		WHERE w.customer_id = $1
+		ORDER BY w.currency`
+
+	rows, err := r.db.QueryContext(ctx, query, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %w", err)
+	}
+	defer rows.Close()
+
+	wallets := []models.Wallet{}
+	for rows.Next() {
+		wallet, err := scanWallet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		wallets = append(wallets, *wallet)
+	}
+
+	return wallets, rows.Err()
+}
+
+// Credit adds funds to the customer's wallet in wallet.Currency, creating the
+// wallet on first use, and records the transaction. A non-nil grant records
+// the funds as promotional credit. Returns false if a transaction with the
+// same idempotency key exists; txn is then the original.
+func (r *WalletRepository) Credit(ctx context.Context, wallet *models.Wallet, txn *models.WalletTransaction, grant *models.WalletGrant) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO wallets (customer_id, currency)
+		VALUES ($1, $2)
+		ON CONFLICT (customer_id, currency) DO UPDATE SET customer_id = EXCLUDED.customer_id
+		RETURNING id`,
+		wallet.CustomerID,
+		wallet.Currency,
+	).Scan(&wallet.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to create wallet: %w", err)
+	}
+
+	if _, err := lockWallet(ctx, tx, wallet.ID); err != nil {
+		return false, err
+	}
+
+	txn.WalletID = wallet.ID
+	found, err := getWalletTransactionByKey(ctx, tx, txn)
+	if err != nil || found {
+		return false, err
+	}
+
+	if grant != nil {
+		grant.WalletID = wallet.ID
+		grant.Remaining = grant.Amount
+		err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO wallet_grants (wallet_id, amount, remaining, expires_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at`,
+			grant.WalletID,
+			grant.Amount,
+			grant.Remaining,
+			grant.ExpiresAt,
+		).Scan(&grant.ID, &grant.CreatedAt)
+		if err != nil {
+			return false, fmt.Errorf("failed to create wallet grant: %w", err)
+		}
+		txn.GrantID = &grant.ID
+	}
+
+	if err := applyWalletTransaction(ctx, tx, txn); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit wallet credit: %w", err)
+	}
+
+	return true, r.reload(ctx, wallet)
+}
+
+// Debit spends -txn.Amount from the wallet, promotional credit first.
+// Returns false if the wallet does not have enough available funds. A
+// transaction with the same idempotency key is returned in txn instead of
+// debiting again.
+func (r *WalletRepository) Debit(ctx context.Context, txn *models.WalletTransaction) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := lockWallet(ctx, tx, txn.WalletID)
+	if err != nil {
+		return false, err
+	}
+
+	found, err := getWalletTransactionByKey(ctx, tx, txn)
+	if err != nil {
+		return false, err
+	}
+	if found {
+		return true, nil
+	}
+
+	if wallet.Available() < -txn.Amount {
+		return false, nil
+	}
+
+	if err := spendWalletGrants(ctx, tx, txn.WalletID, -txn.Amount); err != nil {
+		return false, err
+	}
+	if err := applyWalletTransaction(ctx, tx, txn); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit wallet debit: %w", err)
+	}
+
+	return true, nil
+}
+
+// CreateHold reserves hold.Amount of the wallet's available funds. Returns
+// false if the wallet does not have enough. A hold with the same idempotency
+// key is returned in hold instead of holding again.
+func (r *WalletRepository) CreateHold(ctx context.Context, hold *models.WalletHold) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := lockWallet(ctx, tx, hold.WalletID)
+	if err != nil {
+		return false, err
+	}
+
+	existing, err := scanWalletHold(tx.QueryRowContext(
+		ctx,
+		`SELECT `+walletHoldColumns+` FROM wallet_holds WHERE wallet_id = $1 AND idempotency_key = $2`,
+		hold.WalletID,
+		hold.IdempotencyKey,
+	))
+	if err == nil {
+		*hold = *existing
+		return true, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to get wallet hold: %w", err)
+	}
+
+	if wallet.Available() < hold.Amount {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE wallets SET held = held + $2 WHERE id = $1`, hold.WalletID, hold.Amount)
+	if err != nil {
+		return false, fmt.Errorf("failed to hold wallet funds: %w", err)
+	}
+
+	hold.Status = models.WalletHoldStatusActive
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO wallet_holds (wallet_id, amount, status, idempotency_key, description)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`,
+		hold.WalletID,
+		hold.Amount,
+		hold.Status,
+		hold.IdempotencyKey,
+		hold.Description,
+	).Scan(&hold.ID, &hold.CreatedAt, &hold.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to create wallet hold: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit wallet hold: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetHold retrieves a wallet hold by ID
+func (r *WalletRepository) GetHold(ctx context.Context, id uuid.UUID) (*models.WalletHold, error) {
+	hold, err := scanWalletHold(r.db.QueryRowContext(
+		ctx,
+		`SELECT `+walletHoldColumns+` FROM wallet_holds WHERE id = $1`,
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+// CaptureHold spends amount of an active hold and releases the rest. A zero
+// amount records no transaction. Returns false if the hold is no longer
+// active.
+func (r *WalletRepository) CaptureHold(ctx context.Context, hold *models.WalletHold, amount int64, txn *models.WalletTransaction) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockWallet(ctx, tx, hold.WalletID); err != nil {
+		return false, err
+	}
+
+	closed, err := closeWalletHold(ctx, tx, hold, models.WalletHoldStatusCaptured, amount)
+	if err != nil || !closed {
+		return false, err
+	}
+
+	if amount > 0 {
+		if err := spendWalletGrants(ctx, tx, hold.WalletID, amount); err != nil {
+			return false, err
+		}
+		txn.WalletID = hold.WalletID
+		txn.Amount = -amount
+		txn.HoldID = &hold.ID
+		if err := applyWalletTransaction(ctx, tx, txn); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit wallet hold capture: %w", err)
+	}
+
+	return true, nil
+}
+
+// ReleaseHold gives an active hold's funds back to the wallet. Returns false
+// if the hold is no longer active.
+func (r *WalletRepository) ReleaseHold(ctx context.Context, hold *models.WalletHold) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockWallet(ctx, tx, hold.WalletID); err != nil {
+		return false, err
+	}
+
+	released, err := closeWalletHold(ctx, tx, hold, models.WalletHoldStatusReleased, 0)
+	if err != nil || !released {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit wallet hold release: %w", err)
+	}
+
+	return true, nil
+}
+
+// ListTransactions lists the transactions of all of a customer's wallets,
+// newest first
+func (r *WalletRepository) ListTransactions(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.WalletTransaction, int, error) {
+	var total int
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*)
+		FROM wallet_transactions t
+		JOIN wallets w ON w.id = t.wallet_id
+		WHERE w.customer_id = $1`,
+		customerID,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count wallet transactions: %w", err)
+	}
+
+	query := `
+		SELECT
+			t.id, t.wallet_id, t.type, t.amount, t.balance_after, t.idempotency_key, t.description,
+			t.payment_id, t.hold_id, t.grant_id, t.created_at
+		FROM wallet_transactions t
+		JOIN wallets w ON w.id = t.wallet_id
+		WHERE w.customer_id = $1
+		ORDER BY t.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, customerID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list wallet transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.WalletTransaction{}
+	for rows.Next() {
+		var txn models.WalletTransaction
+		if err := scanWalletTransaction(rows, &txn); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan wallet transaction: %w", err)
+		}
+		transactions = append(transactions, txn)
+	}
+
+	return transactions, total, rows.Err()
+}
+
+// ListExpiredGrants lists promotional grants past their expiry that still
+// have credit left, oldest first
+func (r *WalletRepository) ListExpiredGrants(ctx context.Context, now time.Time, limit int) ([]models.WalletGrant, error) {
+	query := `
+		SELECT id, wallet_id, amount, remaining, expires_at, created_at
+		FROM wallet_grants
+		WHERE remaining > 0 AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired wallet grants: %w", err)
+	}
+	defer rows.Close()
+
+	grants := []models.WalletGrant{}
+	for rows.Next() {
+		var grant models.WalletGrant
+		err := rows.Scan(
+			&grant.ID,
+			&grant.WalletID,
+			&grant.Amount,
+			&grant.Remaining,
+			&grant.ExpiresAt,
+			&grant.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wallet grant: %w", err)
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, rows.Err()
+}
+
+// ExpireGrant removes what is left of an expired grant from its wallet and
+// records it in txn. Credit that is held stays in the wallet. Returns false
+// if there was nothing left to expire, e.g. because another instance got
+// there first.
+func (r *WalletRepository) ExpireGrant(ctx context.Context, grant *models.WalletGrant, txn *models.WalletTransaction) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wallet, err := lockWallet(ctx, tx, grant.WalletID)
+	if err != nil {
+		return false, err
+	}
+
+	var remaining int64
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT remaining FROM wallet_grants WHERE id = $1 AND remaining > 0 FOR UPDATE`,
+		grant.ID,
+	).Scan(&remaining)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock wallet grant: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE wallet_grants SET remaining = 0 WHERE id = $1`, grant.ID); err != nil {
+		return false, fmt.Errorf("failed to expire wallet grant: %w", err)
+	}
+
+	amount := min(remaining, wallet.Available())
+	if amount > 0 {
+		txn.WalletID = grant.WalletID
+		txn.Amount = -amount
+		txn.GrantID = &grant.ID
+		if err := applyWalletTransaction(ctx, tx, txn); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit wallet grant expiry: %w", err)
+	}
+
+	return amount > 0, nil
+}
+
+// reload refreshes a wallet after a change
+func (r *WalletRepository) reload(ctx context.Context, wallet *models.Wallet) error {
+	query := `SELECT ` + walletColumns + ` FROM wallets w WHERE w.id = $1`
+
+	reloaded, err := scanWallet(r.db.QueryRowContext(ctx, query, wallet.ID))
+	if err != nil {
+		return fmt.Errorf("failed to get wallet: %w", err)
+	}
+	*wallet = *reloaded
+
+	return nil
+}
+
+// lockWallet locks a wallet row for the rest of tx
+func lockWallet(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.Wallet, error) {
+	query := `SELECT ` + walletColumns + ` FROM wallets w WHERE w.id = $1 FOR UPDATE OF w`
+
+	wallet, err := scanWallet(tx.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("wallet not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+// getWalletTransactionByKey loads the transaction with txn's wallet and
+// idempotency key into txn, reporting whether there was one
+func getWalletTransactionByKey(ctx context.Context, tx *sql.Tx, txn *models.WalletTransaction) (bool, error) {
+	row := tx.QueryRowContext(
+		ctx,
+		`SELECT `+walletTransactionColumns+` FROM wallet_transactions WHERE wallet_id = $1 AND idempotency_key = $2`,
+		txn.WalletID,
+		txn.IdempotencyKey,
+	)
+
+	err := scanWalletTransaction(row, txn)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get wallet transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// applyWalletTransaction changes the locked wallet's balance by txn.Amount and
+// records txn
+func applyWalletTransaction(ctx context.Context, tx *sql.Tx, txn *models.WalletTransaction) error {
+	err := tx.QueryRowContext(
+		ctx,
+		`UPDATE wallets SET balance = balance + $2 WHERE id = $1 RETURNING balance`,
+		txn.WalletID,
+		txn.Amount,
+	).Scan(&txn.BalanceAfter)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+
+	query := `
+		INSERT INTO wallet_transactions (
+			wallet_id, type, amount, balance_after, idempotency_key, description,
+			payment_id, hold_id, grant_id
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+		RETURNING id, created_at`
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		txn.WalletID,
+		txn.Type,
+		txn.Amount,
+		txn.BalanceAfter,
+		txn.IdempotencyKey,
+		txn.Description,
+		txn.PaymentID,
+		txn.HoldID,
+		txn.GrantID,
+	).Scan(&txn.ID, &txn.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create wallet transaction: %w", err)
+	}
+
+	return nil
+}
+
+// spendWalletGrants takes amount from the wallet's promotional grants, the
+// one expiring first first, until the amount or the grants run out
+func spendWalletGrants(ctx context.Context, tx *sql.Tx, walletID uuid.UUID, amount int64) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT id, remaining FROM wallet_grants
+		WHERE wallet_id = $1 AND remaining > 0
+		ORDER BY expires_at
+		FOR UPDATE`,
+		walletID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to lock wallet grants: %w", err)
+	}
+
+	spent := map[uuid.UUID]int64{}
+	for rows.Next() && amount > 0 {
+		var id uuid.UUID
+		var remaining int64
+		if err := rows.Scan(&id, &remaining); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan wallet grant: %w", err)
+		}
+		spent[id] = min(remaining, amount)
+		amount -= spent[id]
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read wallet grants: %w", err)
+	}
+
+	for id, amount := range spent {
+		_, err := tx.ExecContext(ctx, `UPDATE wallet_grants SET remaining = remaining - $2 WHERE id = $1`, id, amount)
+		if err != nil {
+			return fmt.Errorf("failed to spend wallet grant: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// closeWalletHold moves an active hold to status and releases its funds from
+// the locked wallet. Returns false if the hold is no longer active.
+func closeWalletHold(ctx context.Context, tx *sql.Tx, hold *models.WalletHold, status models.WalletHoldStatus, capturedAmount int64) (bool, error) {
+	query := `
+		UPDATE wallet_holds SET
+			status = $2,
+			captured_amount = $3,
+			updated_at = NOW()
+		WHERE id = $1 AND status = 'active'
+		RETURNING ` + walletHoldColumns
+
+	closed, err := scanWalletHold(tx.QueryRowContext(ctx, query, hold.ID, status, capturedAmount))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to close wallet hold: %w", err)
+	}
+	*hold = *closed
+
+	_, err = tx.ExecContext(ctx, `UPDATE wallets SET held = held - $2 WHERE id = $1`, hold.WalletID, hold.Amount)
+	if err != nil {
+		return false, fmt.Errorf("failed to release wallet funds: %w", err)
+	}
+
+	return true, nil
+}
+
+func scanWallet(row rowScanner) (*models.Wallet, error) {
+	wallet := &models.Wallet{}
+	err := row.Scan(
+		&wallet.ID,
+		&wallet.CustomerID,
+		&wallet.Currency,
+		&wallet.Balance,
+		&wallet.Held,
+		&wallet.Promotional,
+		&wallet.CreatedAt,
+		&wallet.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+func scanWalletHold(row rowScanner) (*models.WalletHold, error) {
+	hold := &models.WalletHold{}
+	err := row.Scan(
+		&hold.ID,
+		&hold.WalletID,
+		&hold.Amount,
+		&hold.CapturedAmount,
+		&hold.Status,
+		&hold.IdempotencyKey,
+		&hold.Description,
+		&hold.CreatedAt,
+		&hold.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+func scanWalletTransaction(row rowScanner, txn *models.WalletTransaction) error {
+	return row.Scan(
+		&txn.ID,
+		&txn.WalletID,
+		&txn.Type,
+		&txn.Amount,
+		&txn.BalanceAfter,
+		&txn.IdempotencyKey,
+		&txn.Description,
+		&txn.PaymentID,
+		&txn.HoldID,
+		&txn.GrantID,
+		&txn.CreatedAt,
+	)
+}
@@ -98,11 +98,12 @@ func (r *WebhookRepository) GetByProviderEventID(ctx context.Context, provider m
 	return event, nil
 }
 
-// MarkProcessed marks a webhook event as processed
+// MarkProcessed records a processing attempt. An event that failed keeps
+// processed = false so the provider's retry processes it again.
 func (r *WebhookRepository) MarkProcessed(ctx context.Context, id uuid.UUID, processingError *string) error {
 	query := `
 		UPDATE webhook_events SET
-			processed = true,
+			processed = ($1::text IS NULL),
 			processed_at = NOW(),
 			processing_error = $1
 		WHERE id = $2`
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"context"
+	"log"
+	"payment-service/internal/models"
+	"payment-service/internal/repository"
+)
+
+// recordAudit appends an audit event. Failures are logged rather than returned
+// because the audited change has already been applied at the provider.
+func recordAudit(ctx context.Context, auditRepo repository.AuditRepositoryInterface, event *models.AuditEvent) {
+	if err := auditRepo.Create(ctx, event); err != nil {
+		log.Printf("Failed to record audit event %s for %s %s: %v", event.Action, event.ResourceType, event.ResourceID, err)
+	}
+}
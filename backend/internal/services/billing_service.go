@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"time"
+)
+
+// billingBatchSize caps how many subscriptions one billing pass handles
+const billingBatchSize = 100
+
+// billingClaimLease is how long a claimed subscription is left alone by other
+// instances; a run that fails or dies part way is retried once it has passed
+const billingClaimLease = 5 * time.Minute
+
+// billingPendingPollInterval is how often a payment waiting on the customer
+// is checked again
+const billingPendingPollInterval = time.Minute
+
+// errBillingRunSuperseded is returned when a subscription was changed, e.g.
+// canceled, while its billing run was in progress. The change is kept and the
+// run's own changes are dropped.
+var errBillingRunSuperseded = errors.New("subscription changed during billing run")
+
+// BillingService renews natively billed subscriptions: at the end of each
+// period it starts the next one, invoices it and charges the invoice through
+// the provider. Declined renewals are handed to dunning.
+type BillingService struct {
+	subscriptionRepo repository.SubscriptionRepositoryInterface
+	invoiceRepo      repository.InvoiceRepositoryInterface
+	nativeBilling    *NativeBilling
+	dunningService   *DunningService
+}
+
+func NewBillingService(
+	subscriptionRepo repository.SubscriptionRepositoryInterface,
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	nativeBilling *NativeBilling,
+	dunningService *DunningService,
+) *BillingService {
+	return &BillingService{
+		subscriptionRepo: subscriptionRepo,
+		invoiceRepo:      invoiceRepo,
+		nativeBilling:    nativeBilling,
+		dunningService:   dunningService,
+	}
+}
+
+// ProcessRenewals bills natively billed subscriptions whose next billing run
+// is due. Each subscription is claimed atomically first, so several instances
+// can run this concurrently, and every step can be repeated, so a run that
+// dies part way is finished by a later one.
+func (s *BillingService) ProcessRenewals(ctx context.Context) (*models.BillingRunResult, error) {
+	now := time.Now()
+
+	subscriptions, err := s.subscriptionRepo.ListBillingDue(ctx, now, billingBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.BillingRunResult{}
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+
+		lease := now.Add(billingClaimLease)
+		claimed, err := s.subscriptionRepo.ClaimBilling(ctx, subscription.ID, *subscription.NextBillingAt, lease)
+		if err != nil {
+			log.Printf("Failed to claim billing run for subscription %s: %v", subscription.ID, err)
+			result.Failed++
+			continue
+		}
+		if !claimed {
+			// Another instance got there first
+			continue
+		}
+
+		err = s.bill(ctx, subscription, now, lease, result)
+		switch {
+		case errors.Is(err, errBillingRunSuperseded):
+			log.Printf("Subscription %s changed during its billing run; keeping the change", subscription.ID)
+		case err != nil:
+			log.Printf("Failed to bill subscription %s: %v", subscription.ID, err)
+			result.Failed++
+		}
+	}
+
+	return result, nil
+}
+
+// bill moves a subscription on to its next period if the current one has
+// ended and charges the current period's invoice. The period is saved before
+// it is charged, so a change made since the claim, e.g. a cancellation, stops
+// the run before the customer is charged. Every step finds its own work on a
+// second run, so a failed run leaves the claim to expire and is retried as a
+// whole.
+func (s *BillingService) bill(ctx context.Context, subscription *models.Subscription, now, lease time.Time, result *models.BillingRunResult) error {
+	// Only the columns the run owns are saved, and only if nothing else
+	// changed the subscription since it was claimed or last saved by the run
+	status := subscription.Status
+	save := func() error {
+		saved, err := s.subscriptionRepo.SaveBillingRun(ctx, subscription, status, lease)
+		if err != nil {
+			return err
+		}
+		if !saved {
+			return errBillingRunSuperseded
+		}
+		status = subscription.Status
+		return nil
+	}
+
+	if subscription.Status == models.SubscriptionStatusCanceled ||
+		subscription.Status == models.SubscriptionStatusIncompleteExpired {
+		subscription.NextBillingAt = nil
+		return save()
+	}
+
+	renewed := false
+	if !now.Before(subscription.CurrentPeriodEnd) {
+		if subscription.CancelAtPeriodEnd || (subscription.CancelAt != nil && !now.Before(*subscription.CancelAt)) {
+			canceledAt := subscription.CurrentPeriodEnd
+			subscription.Status = models.SubscriptionStatusCanceled
+			subscription.CanceledAt = &canceledAt
+			subscription.NextBillingAt = nil
+			if err := save(); err != nil {
+				return err
+			}
+			result.Canceled++
+			return nil
+		}
+
+		if subscription.Status == models.SubscriptionStatusTrialing {
+			subscription.Status = models.SubscriptionStatusActive
+		}
+		subscription.CurrentPeriodStart = subscription.CurrentPeriodEnd
+		subscription.CurrentPeriodEnd = models.AddInterval(subscription.CurrentPeriodStart, subscription.Interval, subscription.IntervalCount)
+		renewed = true
+	}
+
+	// A trial period is started without a charge; any other period is started
+	// while still holding the claim, before it is invoiced and charged
+	if subscription.Status == models.SubscriptionStatusTrialing {
+		next := subscription.CurrentPeriodEnd
+		subscription.NextBillingAt = &next
+	} else {
+		claim := lease
+		subscription.NextBillingAt = &claim
+	}
+	if err := save(); err != nil {
+		return err
+	}
+	if renewed {
+		result.Renewed++
+	}
+	if subscription.Status == models.SubscriptionStatusTrialing {
+		return nil
+	}
+
+	invoice, err := s.periodInvoice(ctx, subscription)
+	if err != nil {
+		return err
+	}
+
+	next := subscription.CurrentPeriodEnd
+	subscription.NextBillingAt = &next
+
+	// Dunning retries the invoices it has taken over
+	if invoice.Status != models.InvoiceStatusOpen || ownedByDunning(subscription, invoice.ProviderInvoiceID) {
+		if invoice.Status == models.InvoiceStatusPaid && subscription.Status == models.SubscriptionStatusIncomplete {
+			subscription.Status = models.SubscriptionStatusActive
+		}
+		return save()
+	}
+
+	payErr := s.nativeBilling.collect(ctx, subscription, invoice)
+	switch {
+	case payErr == nil:
+		if subscription.Status == models.SubscriptionStatusIncomplete {
+			subscription.Status = models.SubscriptionStatusActive
+		}
+		result.Paid++
+	case errors.Is(payErr, providers.ErrPaymentPending):
+		poll := now.Add(billingPendingPollInterval)
+		if poll.Before(next) {
+			subscription.NextBillingAt = &poll
+		}
+		result.Pending++
+	case errors.Is(payErr, providers.ErrPaymentDeclined):
+		result.Declined++
+		if subscription.Status == models.SubscriptionStatusIncomplete {
+			// The first payment never went through; the subscription never started
+			subscription.Status = models.SubscriptionStatusIncompleteExpired
+			subscription.NextBillingAt = nil
+			invoice.Status = models.InvoiceStatusVoid
+			if err := s.invoiceRepo.Update(ctx, invoice); err != nil {
+				return err
+			}
+		}
+	default:
+		return payErr
+	}
+
+	if err := save(); err != nil {
+		return err
+	}
+
+	if errors.Is(payErr, providers.ErrPaymentDeclined) && subscription.Status != models.SubscriptionStatusIncompleteExpired {
+		return s.dunningService.HandlePaymentFailed(ctx, subscription, invoice.ProviderInvoiceID, 1)
+	}
+
+	return nil
+}
+
+// periodInvoice returns the invoice for a subscription's current period,
+// creating it on the first run. The provider invoice ID is derived from the
+// period, so a repeated run finds the invoice instead of billing twice.
+func (s *BillingService) periodInvoice(ctx context.Context, subscription *models.Subscription) (*models.Invoice, error) {
+	providerInvoiceID := fmt.Sprintf("native_%s_%d", subscription.ID, subscription.CurrentPeriodStart.Unix())
+
+	invoice, err := s.invoiceRepo.GetByProviderInvoiceID(ctx, subscription.Provider, providerInvoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice != nil {
+		return invoice, nil
+	}
+
+	invoice = buildPeriodInvoice(subscription)
+	invoice.ProviderInvoiceID = providerInvoiceID
+	if err := s.invoiceRepo.Create(ctx, invoice); err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// buildPeriodInvoice bills a subscription's items for its current period,
+// taxed like the subscription
+func buildPeriodInvoice(subscription *models.Subscription) *models.Invoice {
+	billingReason := "subscription_cycle"
+	if subscription.Status == models.SubscriptionStatusIncomplete {
+		billingReason = "subscription_create"
+	}
+
+	invoice := &models.Invoice{
+		CustomerID:     subscription.CustomerID,
+		SubscriptionID: &subscription.ID,
+		Provider:       subscription.Provider,
+		Status:         models.InvoiceStatusOpen,
+		BillingReason:  &billingReason,
+		Currency:       subscription.Currency,
+		LineItems:      models.InvoiceLineItems{},
+		PeriodStart:    subscription.CurrentPeriodStart,
+		PeriodEnd:      subscription.CurrentPeriodEnd,
+	}
+
+	for _, item := range subscription.Items {
+		invoice.LineItems = append(invoice.LineItems, models.InvoiceLineItem{
+			Description: item.ProductName,
+			Quantity:    item.Quantity,
+			UnitAmount:  item.UnitAmount,
+			Amount:      item.UnitAmount * item.Quantity,
+			PeriodStart: &invoice.PeriodStart,
+			PeriodEnd:   &invoice.PeriodEnd,
+		})
+	}
+
+	tax := taxAtRate(models.ItemsAmount(subscription.Items), subscription.TaxBreakdown)
+	invoice.Subtotal = tax.Net
+	invoice.Tax = tax.Tax
+	invoice.Total = tax.Total
+	invoice.AmountDue = tax.Total
+	invoice.TaxBreakdown = tax.Breakdown
+
+	if invoice.AmountDue == 0 {
+		paidAt := time.Now()
+		invoice.Status = models.InvoiceStatusPaid
+		invoice.PaidAt = &paidAt
+	}
+
+	return invoice
+}
+
+// ownedByDunning reports whether dunning is retrying or gave up on invoiceID
+func ownedByDunning(subscription *models.Subscription, invoiceID string) bool {
+	return subscription.DunningStatus != nil &&
+		*subscription.DunningStatus != models.DunningStatusRecovered &&
+		subscription.DunningInvoiceID != nil && *subscription.DunningInvoiceID == invoiceID
+}
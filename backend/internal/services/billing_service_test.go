@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testNativeBillingConfig = NativeBillingConfig{
+	Providers:      []models.Provider{models.ProviderSwish},
+	PaymentTimeout: 24 * time.Hour,
+}
+
+// newRenewingSubscription returns a natively billed subscription whose period
+// has just ended
+func newRenewingSubscription() models.Subscription {
+	periodEnd := time.Now().Add(-time.Minute)
+	periodStart := models.AddInterval(periodEnd, "month", -1)
+	providerItemID := "native_si_test"
+
+	return models.Subscription{
+		ID:                     uuid.New(),
+		CustomerID:             uuid.New(),
+		Provider:               models.ProviderSwish,
+		ProviderSubscriptionID: "native_sub_test",
+		Amount:                 9900,
+		Currency:               models.CurrencySEK,
+		Interval:               "month",
+		IntervalCount:          1,
+		Status:                 models.SubscriptionStatusActive,
+		CurrentPeriodStart:     periodStart,
+		CurrentPeriodEnd:       periodEnd,
+		NextBillingAt:          &periodEnd,
+		ProductName:            "Pro",
+		Items: []models.SubscriptionItem{
+			{ID: uuid.New(), ProductName: "Pro", UnitAmount: 9900, Quantity: 1, ProviderItemID: &providerItemID},
+		},
+	}
+}
+
+func TestBillingService_ProcessRenewals_ChargesNextPeriod(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	swishCustomerID := "swish_cus_test"
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	nativeBilling := NewNativeBilling(mockSubscriptionRepo, mockInvoiceRepo, mockPaymentRepo, mockCustomerRepo, nil, nil, mockFactory, testNativeBillingConfig)
+	service := NewBillingService(mockSubscriptionRepo, mockInvoiceRepo, nativeBilling, nil)
+
+	subscription := newRenewingSubscription()
+	periodStart := subscription.CurrentPeriodEnd
+	providerInvoiceID := fmt.Sprintf("native_%s_%d", subscription.ID, periodStart.Unix())
+
+	// Mock expectations
+	mockSubscriptionRepo.On("ListBillingDue", ctx, mock.Anything, billingBatchSize).Return([]models.Subscription{subscription}, nil)
+	var lease time.Time
+	mockSubscriptionRepo.On("ClaimBilling", ctx, subscription.ID, *subscription.NextBillingAt, mock.Anything).Run(func(args mock.Arguments) {
+		lease = args.Get(3).(time.Time)
+	}).Return(true, nil)
+	mockInvoiceRepo.On("GetByProviderInvoiceID", ctx, models.ProviderSwish, providerInvoiceID).Return(nil, nil)
+	mockInvoiceRepo.On("Create", ctx, mock.MatchedBy(func(invoice *models.Invoice) bool {
+		return invoice.AmountDue == 9900 && invoice.Status == models.InvoiceStatusOpen &&
+			invoice.PeriodStart.Equal(periodStart) && len(invoice.LineItems) == 1
+	})).Return(nil)
+	mockFactory.On("GetProvider", models.ProviderSwish).Return(mockProvider, nil)
+	mockCustomerRepo.On("GetByID", ctx, subscription.CustomerID).Return(&models.Customer{
+		ID:              subscription.CustomerID,
+		SwishCustomerID: &swishCustomerID,
+	}, nil)
+	mockProvider.On("CreatePayment", ctx, mock.MatchedBy(func(req *providers.CreatePaymentRequest) bool {
+		return req.CustomerID == swishCustomerID && req.Amount == 9900 && strings.HasSuffix(req.IdempotencyKey, "_first")
+	})).Return(&models.Payment{
+		Provider:          models.ProviderSwish,
+		ProviderPaymentID: "swish_pay_test",
+		Amount:            9900,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusPending,
+	}, nil)
+	mockPaymentRepo.On("GetByProviderPaymentID", ctx, models.ProviderSwish, "swish_pay_test").Return(nil, nil)
+	mockPaymentRepo.On("Create", ctx, mock.MatchedBy(func(payment *models.Payment) bool {
+		return *payment.SubscriptionID == subscription.ID && *payment.InvoiceID == providerInvoiceID
+	})).Return(nil)
+	mockInvoiceRepo.On("Update", ctx, mock.Anything).Return(nil)
+
+	var saved *models.Subscription
+	var savedNextBilling []time.Time
+	mockSubscriptionRepo.On("SaveBillingRun", ctx, mock.Anything, models.SubscriptionStatusActive, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*models.Subscription)
+		savedNextBilling = append(savedNextBilling, *saved.NextBillingAt)
+	}).Return(true, nil)
+
+	// Execute
+	result, err := service.ProcessRenewals(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &models.BillingRunResult{Renewed: 1, Pending: 1}, result)
+	// The new period is saved under the claim before it is charged
+	assert.Len(t, savedNextBilling, 2)
+	assert.True(t, savedNextBilling[0].Equal(lease))
+	assert.True(t, saved.CurrentPeriodStart.Equal(periodStart))
+	assert.True(t, saved.CurrentPeriodEnd.After(time.Now()))
+	assert.Equal(t, models.SubscriptionStatusActive, saved.Status)
+	assert.WithinDuration(t, time.Now().Add(billingPendingPollInterval), *saved.NextBillingAt, 5*time.Second)
+	assert.NotNil(t, saved.LatestPaymentID)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockInvoiceRepo.AssertExpectations(t)
+	mockPaymentRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+}
+
+func TestBillingService_ProcessRenewals_DeclinedStartsDunning(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	swishCustomerID := "swish_cus_test"
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockEventRepo := new(MockEventRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	nativeBilling := NewNativeBilling(mockSubscriptionRepo, mockInvoiceRepo, mockPaymentRepo, mockCustomerRepo, nil, nil, mockFactory, testNativeBillingConfig)
+	dunningService := NewDunningService(mockSubscriptionRepo, mockEventRepo, nativeBilling, testDunningConfig)
+	service := NewBillingService(mockSubscriptionRepo, mockInvoiceRepo, nativeBilling, dunningService)
+
+	subscription := newRenewingSubscription()
+	providerInvoiceID := fmt.Sprintf("native_%s_%d", subscription.ID, subscription.CurrentPeriodEnd.Unix())
+
+	// Mock expectations
+	mockSubscriptionRepo.On("ListBillingDue", ctx, mock.Anything, billingBatchSize).Return([]models.Subscription{subscription}, nil)
+	mockSubscriptionRepo.On("ClaimBilling", ctx, subscription.ID, *subscription.NextBillingAt, mock.Anything).Return(true, nil)
+	mockInvoiceRepo.On("GetByProviderInvoiceID", ctx, models.ProviderSwish, providerInvoiceID).Return(nil, nil)
+	mockInvoiceRepo.On("Create", ctx, mock.Anything).Return(nil)
+	mockFactory.On("GetProvider", models.ProviderSwish).Return(mockProvider, nil)
+	mockCustomerRepo.On("GetByID", ctx, subscription.CustomerID).Return(&models.Customer{
+		ID:              subscription.CustomerID,
+		SwishCustomerID: &swishCustomerID,
+	}, nil)
+	mockProvider.On("CreatePayment", ctx, mock.Anything).Return(&models.Payment{
+		Provider:          models.ProviderSwish,
+		ProviderPaymentID: "swish_pay_test",
+		Amount:            9900,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusFailed,
+	}, nil)
+	mockPaymentRepo.On("GetByProviderPaymentID", ctx, models.ProviderSwish, "swish_pay_test").Return(nil, nil)
+	mockPaymentRepo.On("Create", ctx, mock.Anything).Return(nil)
+	mockInvoiceRepo.On("Update", ctx, mock.Anything).Return(nil)
+	mockSubscriptionRepo.On("SaveBillingRun", ctx, mock.Anything, models.SubscriptionStatusActive, mock.Anything).Return(true, nil)
+
+	var saved *models.Subscription
+	mockSubscriptionRepo.On("Update", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*models.Subscription)
+	}).Return(nil)
+	mockEventRepo.On("Create", ctx, mock.MatchedBy(func(event *models.Event) bool {
+		return event.Type == models.EventTypeSubscriptionPaymentFailed && event.Data["invoice_id"] == providerInvoiceID
+	})).Return(nil)
+
+	// Execute
+	result, err := service.ProcessRenewals(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &models.BillingRunResult{Renewed: 1, Declined: 1}, result)
+	assert.Equal(t, models.SubscriptionStatusPastDue, saved.Status)
+	assert.Equal(t, models.DunningStatusRetrying, *saved.DunningStatus)
+	assert.Equal(t, providerInvoiceID, *saved.DunningInvoiceID)
+	assert.True(t, saved.NextBillingAt.Equal(saved.CurrentPeriodEnd))
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestBillingService_ProcessRenewals_ClaimedElsewhere(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+
+	nativeBilling := NewNativeBilling(mockSubscriptionRepo, mockInvoiceRepo, nil, nil, nil, nil, new(MockProviderFactory), testNativeBillingConfig)
+	service := NewBillingService(mockSubscriptionRepo, mockInvoiceRepo, nativeBilling, nil)
+
+	subscription := newRenewingSubscription()
+
+	// Mock expectations
+	mockSubscriptionRepo.On("ListBillingDue", ctx, mock.Anything, billingBatchSize).Return([]models.Subscription{subscription}, nil)
+	mockSubscriptionRepo.On("ClaimBilling", ctx, subscription.ID, *subscription.NextBillingAt, mock.Anything).Return(false, nil)
+
+	// Execute
+	result, err := service.ProcessRenewals(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &models.BillingRunResult{}, result)
+
+	mockSubscriptionRepo.AssertNotCalled(t, "SaveBillingRun", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockInvoiceRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestBillingService_ProcessRenewals_KeepsCancellationMadeDuringRun(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+
+	nativeBilling := NewNativeBilling(mockSubscriptionRepo, mockInvoiceRepo, nil, nil, nil, nil, new(MockProviderFactory), testNativeBillingConfig)
+	service := NewBillingService(mockSubscriptionRepo, mockInvoiceRepo, nativeBilling, nil)
+
+	subscription := newRenewingSubscription()
+	subscription.CancelAtPeriodEnd = true
+	claimedAt := *subscription.NextBillingAt
+
+	// Mock expectations: the subscription is changed after the claim, so the
+	// guarded save finds nothing to update
+	mockSubscriptionRepo.On("ListBillingDue", ctx, mock.Anything, billingBatchSize).Return([]models.Subscription{subscription}, nil)
+	mockSubscriptionRepo.On("ClaimBilling", ctx, subscription.ID, claimedAt, mock.Anything).Return(true, nil)
+	mockSubscriptionRepo.On("SaveBillingRun", ctx, mock.MatchedBy(func(s *models.Subscription) bool {
+		return s.Status == models.SubscriptionStatusCanceled
+	}), models.SubscriptionStatusActive, mock.MatchedBy(func(lease time.Time) bool {
+		return lease.After(claimedAt)
+	})).Return(false, nil)
+
+	// Execute
+	result, err := service.ProcessRenewals(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &models.BillingRunResult{}, result)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockSubscriptionRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestBillingService_ProcessRenewals_DoesNotChargeWhenCanceledDuringRun(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	nativeBilling := NewNativeBilling(mockSubscriptionRepo, mockInvoiceRepo, nil, nil, nil, nil, mockFactory, testNativeBillingConfig)
+	service := NewBillingService(mockSubscriptionRepo, mockInvoiceRepo, nativeBilling, nil)
+
+	subscription := newRenewingSubscription()
+	claimedAt := *subscription.NextBillingAt
+
+	// Mock expectations: the subscription is canceled after the claim, so
+	// starting the new period finds nothing to update
+	var lease time.Time
+	mockSubscriptionRepo.On("ListBillingDue", ctx, mock.Anything, billingBatchSize).Return([]models.Subscription{subscription}, nil)
+	mockSubscriptionRepo.On("ClaimBilling", ctx, subscription.ID, claimedAt, mock.Anything).Run(func(args mock.Arguments) {
+		lease = args.Get(3).(time.Time)
+	}).Return(true, nil)
+	mockSubscriptionRepo.On("SaveBillingRun", ctx, mock.MatchedBy(func(s *models.Subscription) bool {
+		return s.CurrentPeriodStart.Equal(claimedAt) && s.NextBillingAt.Equal(lease)
+	}), models.SubscriptionStatusActive, mock.Anything).Return(false, nil)
+
+	// Execute
+	result, err := service.ProcessRenewals(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &models.BillingRunResult{}, result)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockSubscriptionRepo.AssertNumberOfCalls(t, "SaveBillingRun", 1)
+	mockInvoiceRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+	mockProvider.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+}
+
+func TestNativeBilling_CreateSubscription_StartsTrialLocally(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	nativeBilling := NewNativeBilling(nil, nil, nil, nil, nil, nil, mockFactory, testNativeBillingConfig)
+
+	// Mock expectations
+	mockFactory.On("GetProvider", models.ProviderSwish).Return(mockProvider, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+
+	// Execute
+	stripeProvider, err := nativeBilling.GetProvider(models.ProviderStripe)
+	assert.NoError(t, err)
+	swishProvider, err := nativeBilling.GetProvider(models.ProviderSwish)
+	assert.NoError(t, err)
+
+	subscription, err := swishProvider.CreateSubscription(ctx, &providers.CreateSubscriptionRequest{
+		CustomerID:      "swish_cus_test",
+		Currency:        "SEK",
+		Interval:        "month",
+		IntervalCount:   1,
+		TrialPeriodDays: 14,
+		Items: []providers.SubscriptionItem{
+			{ProductName: "Seat", UnitAmount: 4900, Quantity: 3},
+		},
+	})
+
+	// Assert
+	assert.Same(t, mockProvider, stripeProvider)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusTrialing, subscription.Status)
+	assert.Equal(t, int64(14700), subscription.Amount)
+	assert.True(t, strings.HasPrefix(subscription.ProviderSubscriptionID, "native_sub_"))
+	assert.NotNil(t, subscription.Items[0].ProviderItemID)
+	assert.Equal(t, *subscription.TrialEnd, subscription.CurrentPeriodEnd)
+	assert.Equal(t, *subscription.TrialEnd, *subscription.NextBillingAt)
+
+	mockProvider.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+}
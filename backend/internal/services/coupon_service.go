@@ -0,0 +1,485 @@
+package services
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CouponService struct {
+	couponRepo        repository.CouponRepositoryInterface
+	promotionCodeRepo repository.PromotionCodeRepositoryInterface
+	customerRepo      repository.CustomerRepositoryInterface
+	providerFactory   ProviderFactoryInterface
+}
+
+func NewCouponService(
+	couponRepo repository.CouponRepositoryInterface,
+	promotionCodeRepo repository.PromotionCodeRepositoryInterface,
+	customerRepo repository.CustomerRepositoryInterface,
+	providerFactory ProviderFactoryInterface,
+) *CouponService {
+	return &CouponService{
+		couponRepo:        couponRepo,
+		promotionCodeRepo: promotionCodeRepo,
+		customerRepo:      customerRepo,
+		providerFactory:   providerFactory,
+	}
+}
+
+// CreateCoupon creates a coupon locally and in Stripe so it can be attached to subscriptions
+func (s *CouponService) CreateCoupon(ctx context.Context, req *models.CreateCouponRequest) (*models.Coupon, error) {
+	if err := validateCouponRequest(req); err != nil {
+		return nil, err
+	}
+
+	coupon := &models.Coupon{
+		Name:             req.Name,
+		PercentOff:       req.PercentOff,
+		AmountOff:        req.AmountOff,
+		Currency:         req.Currency,
+		Duration:         req.Duration,
+		DurationInMonths: req.DurationInMonths,
+		MaxRedemptions:   req.MaxRedemptions,
+		RedeemBy:         req.RedeemBy,
+		Valid:            true,
+		Metadata:         req.Metadata,
+	}
+
+	provider, err := s.providerFactory.GetProvider(models.ProviderStripe)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Provider stripe not available",
+			http.StatusBadRequest,
+		)
+	}
+
+	providerReq := &providers.CreateCouponRequest{
+		Name:             req.Name,
+		PercentOff:       req.PercentOff,
+		AmountOff:        req.AmountOff,
+		Duration:         string(req.Duration),
+		DurationInMonths: req.DurationInMonths,
+		MaxRedemptions:   req.MaxRedemptions,
+		RedeemBy:         req.RedeemBy,
+		Metadata:         convertMetadataToStrings(req.Metadata),
+	}
+	if req.Currency != nil {
+		providerReq.Currency = string(*req.Currency)
+	}
+
+	stripeCouponID, err := provider.CreateCoupon(ctx, providerReq)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to create coupon with provider",
+			http.StatusBadGateway,
+		)
+	}
+	coupon.StripeCouponID = &stripeCouponID
+
+	if err := s.couponRepo.Create(ctx, coupon); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to save coupon to database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return coupon, nil
+}
+
+// GetCoupon retrieves a coupon by ID
+func (s *CouponService) GetCoupon(ctx context.Context, couponID uuid.UUID) (*models.Coupon, error) {
+	coupon, err := s.couponRepo.GetByID(ctx, couponID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve coupon",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if coupon == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Coupon not found",
+			http.StatusNotFound,
+		)
+	}
+
+	return coupon, nil
+}
+
+// ListCoupons lists all coupons
+func (s *CouponService) ListCoupons(ctx context.Context, limit, offset int) (*models.CouponListResponse, error) {
+	coupons, total, err := s.couponRepo.List(ctx, limit, offset)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list coupons",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.CouponListResponse{
+		Data:   coupons,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// DeleteCoupon invalidates a coupon so it can no longer be redeemed. Discounts
+// already applied are kept.
+func (s *CouponService) DeleteCoupon(ctx context.Context, couponID uuid.UUID) (*models.Coupon, error) {
+	coupon, err := s.GetCoupon(ctx, couponID)
+	if err != nil {
+		return nil, err
+	}
+
+	if coupon.StripeCouponID != nil {
+		provider, err := s.providerFactory.GetProvider(models.ProviderStripe)
+		if err != nil {
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Provider stripe not available",
+				http.StatusBadRequest,
+			)
+		}
+
+		if err := provider.DeleteCoupon(ctx, *coupon.StripeCouponID); err != nil {
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to delete coupon with provider",
+				http.StatusBadGateway,
+			)
+		}
+	}
+
+	coupon.Valid = false
+	if err := s.couponRepo.Update(ctx, coupon); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update coupon in database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return coupon, nil
+}
+
+// CreatePromotionCode creates a customer-facing code for a coupon
+func (s *CouponService) CreatePromotionCode(ctx context.Context, couponID uuid.UUID, req *models.CreatePromotionCodeRequest) (*models.PromotionCode, error) {
+	coupon, err := s.GetCoupon(ctx, couponID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !coupon.Valid {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Coupon is no longer valid",
+			http.StatusConflict,
+		)
+	}
+
+	code := normalizePromotionCode(req.Code)
+	if code == "" {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"code is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	existing, err := s.promotionCodeRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to check promotion code",
+			http.StatusInternalServerError,
+		)
+	}
+	if existing != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeDuplicate,
+			"Promotion code already exists",
+			http.StatusConflict,
+		)
+	}
+
+	promotionCode := &models.PromotionCode{
+		CouponID:             coupon.ID,
+		Code:                 code,
+		Active:               true,
+		MaxRedemptions:       req.MaxRedemptions,
+		ExpiresAt:            req.ExpiresAt,
+		FirstTimeTransaction: req.FirstTimeTransaction,
+		Metadata:             req.Metadata,
+	}
+
+	if err := s.promotionCodeRepo.Create(ctx, promotionCode); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to save promotion code to database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return promotionCode, nil
+}
+
+// ListPromotionCodes lists the promotion codes of a coupon
+func (s *CouponService) ListPromotionCodes(ctx context.Context, couponID uuid.UUID) ([]models.PromotionCode, error) {
+	if _, err := s.GetCoupon(ctx, couponID); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.promotionCodeRepo.ListByCoupon(ctx, couponID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list promotion codes",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return codes, nil
+}
+
+// UpdatePromotionCode activates or deactivates a promotion code
+func (s *CouponService) UpdatePromotionCode(ctx context.Context, promotionCodeID uuid.UUID, req *models.UpdatePromotionCodeRequest) (*models.PromotionCode, error) {
+	promotionCode, err := s.promotionCodeRepo.GetByID(ctx, promotionCodeID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve promotion code",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if promotionCode == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Promotion code not found",
+			http.StatusNotFound,
+		)
+	}
+
+	if req.Active != nil {
+		promotionCode.Active = *req.Active
+	}
+
+	if err := s.promotionCodeRepo.Update(ctx, promotionCode); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update promotion code in database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return promotionCode, nil
+}
+
+// ReserveDiscount validates a promotion code for a customer and reserves one
+// redemption of it. The reservation must be given back with ReleaseDiscount if
+// the payment or subscription is not created.
+func (s *CouponService) ReserveDiscount(
+	ctx context.Context,
+	code string,
+	customer *models.Customer,
+	amount int64,
+	currency models.Currency,
+) (*models.AppliedDiscount, error) {
+	invalidCode := models.NewAPIError(
+		models.ErrCodeInvalidRequest,
+		"Promotion code is not valid",
+		http.StatusBadRequest,
+	)
+
+	promotionCode, err := s.promotionCodeRepo.GetByCode(ctx, normalizePromotionCode(code))
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve promotion code",
+			http.StatusInternalServerError,
+		)
+	}
+	if promotionCode == nil || !promotionCode.Active {
+		return nil, invalidCode
+	}
+	if promotionCode.ExpiresAt != nil && !time.Now().Before(*promotionCode.ExpiresAt) {
+		return nil, invalidCode
+	}
+
+	coupon, err := s.couponRepo.GetByID(ctx, promotionCode.CouponID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve coupon",
+			http.StatusInternalServerError,
+		)
+	}
+	if coupon == nil || !coupon.Valid {
+		return nil, invalidCode
+	}
+	if coupon.RedeemBy != nil && !time.Now().Before(*coupon.RedeemBy) {
+		return nil, invalidCode
+	}
+
+	if coupon.AmountOff != nil && (coupon.Currency == nil || *coupon.Currency != currency) {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Promotion code is not valid for this currency",
+			http.StatusBadRequest,
+		)
+	}
+
+	if promotionCode.FirstTimeTransaction {
+		hasTransactions, err := s.customerRepo.HasTransactions(ctx, customer.ID)
+		if err != nil {
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to check customer eligibility",
+				http.StatusInternalServerError,
+			)
+		}
+		if hasTransactions {
+			return nil, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Promotion code is only valid for first-time customers",
+				http.StatusBadRequest,
+			)
+		}
+	}
+
+	// Reserve against both limits; the counters are checked atomically so
+	// concurrent redemptions cannot exceed them
+	reserved, err := s.promotionCodeRepo.Reserve(ctx, promotionCode.ID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to redeem promotion code",
+			http.StatusInternalServerError,
+		)
+	}
+	if !reserved {
+		return nil, invalidCode
+	}
+
+	reserved, err = s.couponRepo.Reserve(ctx, coupon.ID)
+	if err != nil || !reserved {
+		if releaseErr := s.promotionCodeRepo.Release(ctx, promotionCode.ID); releaseErr != nil {
+			log.Printf("Failed to release promotion code %s: %v", promotionCode.ID, releaseErr)
+		}
+		if err != nil {
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to redeem coupon",
+				http.StatusInternalServerError,
+			)
+		}
+		return nil, invalidCode
+	}
+
+	return &models.AppliedDiscount{
+		Coupon:        coupon,
+		PromotionCode: promotionCode,
+		Amount:        coupon.DiscountFor(amount),
+		Currency:      currency,
+	}, nil
+}
+
+// ReleaseDiscount gives back a redemption reserved with ReserveDiscount
+func (s *CouponService) ReleaseDiscount(ctx context.Context, discount *models.AppliedDiscount) {
+	if err := s.promotionCodeRepo.Release(ctx, discount.PromotionCode.ID); err != nil {
+		log.Printf("Failed to release promotion code %s: %v", discount.PromotionCode.ID, err)
+	}
+	if err := s.couponRepo.Release(ctx, discount.Coupon.ID); err != nil {
+		log.Printf("Failed to release coupon %s: %v", discount.Coupon.ID, err)
+	}
+}
+
+// CompleteRedemption records a reserved discount against the payment or
+// subscription it was applied to. Failures are logged because the charge has
+// already been created at the provider.
+func (s *CouponService) CompleteRedemption(
+	ctx context.Context,
+	discount *models.AppliedDiscount,
+	customerID uuid.UUID,
+	paymentID, subscriptionID *uuid.UUID,
+) {
+	redemption := &models.DiscountRedemption{
+		CouponID:        discount.Coupon.ID,
+		PromotionCodeID: &discount.PromotionCode.ID,
+		CustomerID:      customerID,
+		PaymentID:       paymentID,
+		SubscriptionID:  subscriptionID,
+		AmountOff:       discount.Amount,
+	}
+
+	if err := s.couponRepo.CreateRedemption(ctx, redemption); err != nil {
+		log.Printf("Failed to record redemption of coupon %s: %v", discount.Coupon.ID, err)
+	}
+}
+
+// validateCouponRequest checks that a coupon request describes exactly one kind of discount
+func validateCouponRequest(req *models.CreateCouponRequest) error {
+	invalid := func(message string) error {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, message, http.StatusBadRequest)
+	}
+
+	if (req.PercentOff == nil) == (req.AmountOff == nil) {
+		return invalid("Exactly one of percent_off and amount_off is required")
+	}
+	if req.PercentOff != nil && (*req.PercentOff <= 0 || *req.PercentOff > 100) {
+		return invalid("percent_off must be greater than 0 and at most 100")
+	}
+	if req.AmountOff != nil {
+		if *req.AmountOff <= 0 {
+			return invalid("amount_off must be greater than 0")
+		}
+		if req.Currency == nil {
+			return invalid("currency is required with amount_off")
+		}
+		if !req.Currency.IsValid() {
+			return invalid("Unsupported currency: " + string(*req.Currency))
+		}
+	}
+
+	switch req.Duration {
+	case models.CouponDurationOnce, models.CouponDurationForever:
+		if req.DurationInMonths != nil {
+			return invalid("duration_in_months is only allowed with duration repeating")
+		}
+	case models.CouponDurationRepeating:
+		if req.DurationInMonths == nil || *req.DurationInMonths <= 0 {
+			return invalid("duration_in_months must be greater than 0 with duration repeating")
+		}
+	default:
+		return invalid("duration must be once, repeating or forever")
+	}
+
+	if req.MaxRedemptions != nil && *req.MaxRedemptions <= 0 {
+		return invalid("max_redemptions must be greater than 0")
+	}
+	if req.RedeemBy != nil && !req.RedeemBy.After(time.Now()) {
+		return invalid("redeem_by must be in the future")
+	}
+
+	return nil
+}
+
+// normalizePromotionCode returns the canonical (trimmed, upper case) form of a code
+func normalizePromotionCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCouponRepository is a mock for CouponRepository
+type MockCouponRepository struct {
+	mock.Mock
+}
+
+func (m *MockCouponRepository) Create(ctx context.Context, coupon *models.Coupon) error {
+	args := m.Called(ctx, coupon)
+	return args.Error(0)
+}
+
+func (m *MockCouponRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Coupon, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Coupon), args.Error(1)
+}
+
+func (m *MockCouponRepository) List(ctx context.Context, limit, offset int) ([]models.Coupon, int, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]models.Coupon), args.Int(1), args.Error(2)
+}
+
+func (m *MockCouponRepository) Update(ctx context.Context, coupon *models.Coupon) error {
+	args := m.Called(ctx, coupon)
+	return args.Error(0)
+}
+
+func (m *MockCouponRepository) Reserve(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCouponRepository) Release(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockCouponRepository) CreateRedemption(ctx context.Context, redemption *models.DiscountRedemption) error {
+	args := m.Called(ctx, redemption)
+	return args.Error(0)
+}
+
+// MockPromotionCodeRepository is a mock for PromotionCodeRepository
+type MockPromotionCodeRepository struct {
+	mock.Mock
+}
+
+func (m *MockPromotionCodeRepository) Create(ctx context.Context, code *models.PromotionCode) error {
+	args := m.Called(ctx, code)
+	return args.Error(0)
+}
+
+func (m *MockPromotionCodeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.PromotionCode, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PromotionCode), args.Error(1)
+}
+
+func (m *MockPromotionCodeRepository) GetByCode(ctx context.Context, code string) (*models.PromotionCode, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PromotionCode), args.Error(1)
+}
+
+func (m *MockPromotionCodeRepository) ListByCoupon(ctx context.Context, couponID uuid.UUID) ([]models.PromotionCode, error) {
+	args := m.Called(ctx, couponID)
+	return args.Get(0).([]models.PromotionCode), args.Error(1)
+}
+
+func (m *MockPromotionCodeRepository) Update(ctx context.Context, code *models.PromotionCode) error {
+	args := m.Called(ctx, code)
+	return args.Error(0)
+}
+
+func (m *MockPromotionCodeRepository) Reserve(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPromotionCodeRepository) Release(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestCoupon_DiscountFor(t *testing.T) {
+	percentOff := 12.5
+	amountOff := int64(5000)
+
+	assert.Equal(t, int64(1250), (&models.Coupon{PercentOff: &percentOff}).DiscountFor(10000))
+	assert.Equal(t, int64(12), (&models.Coupon{PercentOff: &percentOff}).DiscountFor(99))
+	assert.Equal(t, int64(5000), (&models.Coupon{AmountOff: &amountOff}).DiscountFor(10000))
+	assert.Equal(t, int64(3000), (&models.Coupon{AmountOff: &amountOff}).DiscountFor(3000))
+}
+
+func TestCouponService_ReserveDiscount_Success(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	customer := &models.Customer{ID: uuid.New()}
+	percentOff := 20.0
+
+	mockCouponRepo := new(MockCouponRepository)
+	mockPromotionCodeRepo := new(MockPromotionCodeRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+
+	service := NewCouponService(mockCouponRepo, mockPromotionCodeRepo, mockCustomerRepo, new(MockProviderFactory))
+
+	coupon := &models.Coupon{
+		ID:         uuid.New(),
+		PercentOff: &percentOff,
+		Duration:   models.CouponDurationOnce,
+		Valid:      true,
+	}
+	promotionCode := &models.PromotionCode{
+		ID:                   uuid.New(),
+		CouponID:             coupon.ID,
+		Code:                 "WELCOME20",
+		Active:               true,
+		FirstTimeTransaction: true,
+	}
+
+	// Mock expectations
+	mockPromotionCodeRepo.On("GetByCode", ctx, "WELCOME20").Return(promotionCode, nil)
+	mockCouponRepo.On("GetByID", ctx, coupon.ID).Return(coupon, nil)
+	mockCustomerRepo.On("HasTransactions", ctx, customer.ID).Return(false, nil)
+	mockPromotionCodeRepo.On("Reserve", ctx, promotionCode.ID).Return(true, nil)
+	mockCouponRepo.On("Reserve", ctx, coupon.ID).Return(true, nil)
+
+	// Execute
+	discount, err := service.ReserveDiscount(ctx, " welcome20 ", customer, 10000, models.CurrencySEK)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2000), discount.Amount)
+	assert.Equal(t, coupon.ID, discount.Coupon.ID)
+	assert.Equal(t, promotionCode.ID, discount.PromotionCode.ID)
+
+	mockCouponRepo.AssertExpectations(t)
+	mockPromotionCodeRepo.AssertExpectations(t)
+	mockCustomerRepo.AssertExpectations(t)
+}
+
+func TestCouponService_ReserveDiscount_NotFirstTime(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	customer := &models.Customer{ID: uuid.New()}
+	percentOff := 20.0
+
+	mockCouponRepo := new(MockCouponRepository)
+	mockPromotionCodeRepo := new(MockPromotionCodeRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+
+	service := NewCouponService(mockCouponRepo, mockPromotionCodeRepo, mockCustomerRepo, new(MockProviderFactory))
+
+	coupon := &models.Coupon{
+		ID:         uuid.New(),
+		PercentOff: &percentOff,
+		Duration:   models.CouponDurationOnce,
+		Valid:      true,
+	}
+	promotionCode := &models.PromotionCode{
+		ID:                   uuid.New(),
+		CouponID:             coupon.ID,
+		Code:                 "WELCOME20",
+		Active:               true,
+		FirstTimeTransaction: true,
+	}
+
+	// Mock expectations
+	mockPromotionCodeRepo.On("GetByCode", ctx, "WELCOME20").Return(promotionCode, nil)
+	mockCouponRepo.On("GetByID", ctx, coupon.ID).Return(coupon, nil)
+	mockCustomerRepo.On("HasTransactions", ctx, customer.ID).Return(true, nil)
+
+	// Execute
+	discount, err := service.ReserveDiscount(ctx, "WELCOME20", customer, 10000, models.CurrencySEK)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, discount)
+	mockPromotionCodeRepo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+	mockCouponRepo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+}
+
+func TestCouponService_ReserveDiscount_CouponExhausted(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	customer := &models.Customer{ID: uuid.New()}
+	amountOff := int64(5000)
+	currency := models.CurrencySEK
+
+	mockCouponRepo := new(MockCouponRepository)
+	mockPromotionCodeRepo := new(MockPromotionCodeRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+
+	service := NewCouponService(mockCouponRepo, mockPromotionCodeRepo, mockCustomerRepo, new(MockProviderFactory))
+
+	coupon := &models.Coupon{
+		ID:        uuid.New(),
+		AmountOff: &amountOff,
+		Currency:  &currency,
+		Duration:  models.CouponDurationOnce,
+		Valid:     true,
+	}
+	promotionCode := &models.PromotionCode{
+		ID:       uuid.New(),
+		CouponID: coupon.ID,
+		Code:     "SPRING",
+		Active:   true,
+	}
+
+	// Mock expectations: the code is reserved, the coupon has reached its
+	// limit, so the code reservation is given back
+	mockPromotionCodeRepo.On("GetByCode", ctx, "SPRING").Return(promotionCode, nil)
+	mockCouponRepo.On("GetByID", ctx, coupon.ID).Return(coupon, nil)
+	mockPromotionCodeRepo.On("Reserve", ctx, promotionCode.ID).Return(true, nil)
+	mockCouponRepo.On("Reserve", ctx, coupon.ID).Return(false, nil)
+	mockPromotionCodeRepo.On("Release", ctx, promotionCode.ID).Return(nil)
+
+	// Execute
+	discount, err := service.ReserveDiscount(ctx, "SPRING", customer, 10000, models.CurrencySEK)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, discount)
+	mockPromotionCodeRepo.AssertExpectations(t)
+	mockCouponRepo.AssertExpectations(t)
+}
+
+func TestPaymentService_CreatePayment_WithPromotionCode(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	stripeCustomerID := "cus_test123"
+	percentOff := 25.0
+
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockCouponRepo := new(MockCouponRepository)
+	mockPromotionCodeRepo := new(MockPromotionCodeRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	couponService := NewCouponService(mockCouponRepo, mockPromotionCodeRepo, mockCustomerRepo, mockFactory)
+	mockBalanceRepo := new(MockCustomerBalanceRepository)
+	balanceService := NewCustomerBalanceService(mockBalanceRepo, mockCustomerRepo, nil, mockFactory)
+	mockBalanceRepo.On("Debit", ctx, mock.AnythingOfType("*models.CustomerBalanceTransaction"), models.NewMoney(7500, models.CurrencySEK)).Return(nil)
+
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, couponService, NewTaxService(TaxConfig{}), nil, nil, balanceService, mockFactory)
+
+	customer := &models.Customer{
+		ID:               customerID,
+		UserID:           userID,
+		StripeCustomerID: &stripeCustomerID,
+	}
+	coupon := &models.Coupon{
+		ID:         uuid.New(),
+		PercentOff: &percentOff,
+		Duration:   models.CouponDurationOnce,
+		Valid:      true,
+		RedeemBy:   func() *time.Time { t := time.Now().Add(time.Hour); return &t }(),
+	}
+	promotionCode := &models.PromotionCode{
+		ID:       uuid.New(),
+		CouponID: coupon.ID,
+		Code:     "SAVE25",
+		Active:   true,
+	}
+
+	req := &models.CreatePaymentRequest{
+		Provider:      models.ProviderStripe,
+		Amount:        10000,
+		Currency:      models.CurrencySEK,
+		PromotionCode: "SAVE25",
+	}
+
+	providerPayment := &models.Payment{
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            7500,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusPending,
+	}
+
+	// Mock expectations
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(customer, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockPromotionCodeRepo.On("GetByCode", ctx, "SAVE25").Return(promotionCode, nil)
+	mockCouponRepo.On("GetByID", ctx, coupon.ID).Return(coupon, nil)
+	mockPromotionCodeRepo.On("Reserve", ctx, promotionCode.ID).Return(true, nil)
+	mockCouponRepo.On("Reserve", ctx, coupon.ID).Return(true, nil)
+	mockProvider.On("CreatePayment", ctx, mock.MatchedBy(func(r *providers.CreatePaymentRequest) bool {
+		return r.Amount == 7500
+	})).Return(providerPayment, nil)
+	mockPaymentRepo.On("Create", ctx, mock.AnythingOfType("*models.Payment")).Return(nil)
+	mockCouponRepo.On("CreateRedemption", ctx, mock.MatchedBy(func(r *models.DiscountRedemption) bool {
+		return r.AmountOff == 2500 && r.CustomerID == customerID
+	})).Return(nil)
+
+	// Execute
+	payment, err := service.CreatePayment(ctx, userID, "test@example.com", "Test User", req)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7500), payment.Amount)
+	assert.Equal(t, int64(2500), payment.DiscountAmount)
+	assert.Equal(t, &coupon.ID, payment.CouponID)
+	assert.Equal(t, &promotionCode.ID, payment.PromotionCodeID)
+
+	mockProvider.AssertExpectations(t)
+	mockCouponRepo.AssertExpectations(t)
+	mockPromotionCodeRepo.AssertExpectations(t)
+}
+
+func TestValidateCouponRequest_UnsupportedCurrency(t *testing.T) {
+	amountOff := int64(5000)
+	currency := models.Currency("ABC")
+
+	err := validateCouponRequest(&models.CreateCouponRequest{
+		Name:      "Spring sale",
+		AmountOff: &amountOff,
+		Currency:  &currency,
+		Duration:  models.CouponDurationOnce,
+	})
+
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, models.ErrCodeInvalidRequest, apiErr.Code)
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// CustomerBalanceService manages customers' store credit. Credit comes from
+// refunds paid out to the balance and is used up automatically by later
+// payments and subscription invoices in the same currency.
+type CustomerBalanceService struct {
+	balanceRepo     repository.CustomerBalanceRepositoryInterface
+	customerRepo    repository.CustomerRepositoryInterface
+	ledgerService   *LedgerService
+	providerFactory ProviderFactoryInterface
+}
+
+func NewCustomerBalanceService(
+	balanceRepo repository.CustomerBalanceRepositoryInterface,
+	customerRepo repository.CustomerRepositoryInterface,
+	ledgerService *LedgerService,
+	providerFactory ProviderFactoryInterface,
+) *CustomerBalanceService {
+	return &CustomerBalanceService{
+		balanceRepo:     balanceRepo,
+		customerRepo:    customerRepo,
+		ledgerService:   ledgerService,
+		providerFactory: providerFactory,
+	}
+}
+
+// CreditRefund pays a refund out to the customer's balance. Crediting the
+// same refund twice is a no-op.
+func (s *CustomerBalanceService) CreditRefund(ctx context.Context, refund *models.Refund, payment *models.Payment) error {
+	_, err := s.balanceRepo.Credit(ctx, &models.CustomerBalanceTransaction{
+		CustomerID:    payment.CustomerID,
+		Currency:      refund.Currency,
+		Amount:        refund.Amount,
+		Type:          models.CustomerBalanceTransactionRefundCredit,
+		Description:   refund.Reason,
+		ReferenceType: "refund",
+		ReferenceID:   &refund.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.ledgerService.RecordCreditIssued(ctx, refund, payment); err != nil {
+		log.Printf("Failed to record credit for refund %s in ledger: %v", refund.ID, err)
+	}
+
+	return nil
+}
+
+// ApplyToPayment takes up to amount from the customer's balance in the same
+// currency for a new payment. The returned transaction's Amount is the negated credit used, zero
+// if there was none; pass it to CompletePaymentCredit once the payment is
+// saved, or to ReleaseCredit if the payment is abandoned.
+func (s *CustomerBalanceService) ApplyToPayment(
+	ctx context.Context,
+	customerID uuid.UUID,
+	amount models.Money,
+) (*models.CustomerBalanceTransaction, error) {
+	txn := &models.CustomerBalanceTransaction{
+		CustomerID:    customerID,
+		Currency:      amount.Currency,
+		Type:          models.CustomerBalanceTransactionPaymentApplied,
+		ReferenceType: "payment",
+	}
+
+	if err := s.balanceRepo.Debit(ctx, txn, amount); err != nil {
+		return nil, err
+	}
+
+	return txn, nil
+}
+
+// CompletePaymentCredit links credit taken by ApplyToPayment to the saved payment
+func (s *CustomerBalanceService) CompletePaymentCredit(ctx context.Context, txn *models.CustomerBalanceTransaction, payment *models.Payment) {
+	if txn == nil || txn.Amount == 0 {
+		return
+	}
+
+	if err := s.balanceRepo.SetReference(ctx, txn.ID, "payment", payment.ID); err != nil {
+		log.Printf("Failed to link balance transaction %s to payment %s: %v", txn.ID, payment.ID, err)
+	}
+}
+
+// ReleaseCredit gives back credit taken for a payment or invoice that did not
+// go ahead
+func (s *CustomerBalanceService) ReleaseCredit(ctx context.Context, txn *models.CustomerBalanceTransaction) {
+	if txn == nil || txn.Amount == 0 {
+		return
+	}
+
+	_, err := s.balanceRepo.Credit(ctx, &models.CustomerBalanceTransaction{
+		CustomerID:    txn.CustomerID,
+		Currency:      txn.Currency,
+		Amount:        -txn.Amount,
+		Type:          models.CustomerBalanceTransactionReversal,
+		ReferenceType: "balance_transaction",
+		ReferenceID:   &txn.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to release balance transaction %s: %v", txn.ID, err)
+	}
+}
+
+// RestorePaymentCredit gives back the credit used by a payment that later
+// failed or was canceled. Restoring the same payment twice is a no-op.
+func (s *CustomerBalanceService) RestorePaymentCredit(ctx context.Context, payment *models.Payment) error {
+	if payment.CreditApplied == 0 {
+		return nil
+	}
+
+	_, err := s.balanceRepo.Credit(ctx, &models.CustomerBalanceTransaction{
+		CustomerID:    payment.CustomerID,
+		Currency:      payment.Currency,
+		Amount:        payment.CreditApplied,
+		Type:          models.CustomerBalanceTransactionReversal,
+		ReferenceType: "payment",
+		ReferenceID:   &payment.ID,
+	})
+
+	return err
+}
+
+// ApplyToInvoice takes credit off a new draft subscription invoice, up to the
+// amount due. Each invoice is credited at most once.
+func (s *CustomerBalanceService) ApplyToInvoice(ctx context.Context, subscription *models.Subscription, invoice *models.Invoice) error {
+	if invoice.Status != models.InvoiceStatusDraft || invoice.AmountDue <= 0 {
+		return nil
+	}
+
+	txn := &models.CustomerBalanceTransaction{
+		CustomerID:    invoice.CustomerID,
+		Currency:      invoice.Currency,
+		Type:          models.CustomerBalanceTransactionInvoiceApplied,
+		ReferenceType: "invoice",
+		ReferenceID:   &invoice.ID,
+	}
+	if err := s.balanceRepo.Debit(ctx, txn, models.NewMoney(invoice.AmountDue, invoice.Currency)); err != nil {
+		return err
+	}
+	if txn.Amount == 0 {
+		return nil
+	}
+	applied := -txn.Amount
+
+	if err := s.creditInvoice(ctx, subscription, invoice, applied); err != nil {
+		s.ReleaseCredit(ctx, txn)
+		return err
+	}
+
+	if err := s.ledgerService.RecordInvoiceCredit(ctx, invoice, applied); err != nil {
+		log.Printf("Failed to record credit for invoice %s in ledger: %v", invoice.ID, err)
+	}
+
+	return nil
+}
+
+// creditInvoice adds the credit to the invoice at the provider
+func (s *CustomerBalanceService) creditInvoice(
+	ctx context.Context,
+	subscription *models.Subscription,
+	invoice *models.Invoice,
+	amount int64,
+) error {
+	customer, err := s.customerRepo.GetByID(ctx, invoice.CustomerID)
+	if err != nil {
+		return fmt.Errorf("failed to get customer: %w", err)
+	}
+	if customer == nil {
+		return fmt.Errorf("customer %s not found", invoice.CustomerID)
+	}
+
+	var providerCustomerID string
+	if subscription.Provider == models.ProviderStripe && customer.StripeCustomerID != nil {
+		providerCustomerID = *customer.StripeCustomerID
+	} else if subscription.Provider == models.ProviderSwish && customer.SwishCustomerID != nil {
+		providerCustomerID = *customer.SwishCustomerID
+	} else {
+		return fmt.Errorf("customer %s not configured for provider %s", customer.ID, subscription.Provider)
+	}
+
+	provider, err := s.providerFactory.GetProvider(subscription.Provider)
+	if err != nil {
+		return err
+	}
+
+	err = provider.CreateInvoiceCredit(ctx, &providers.InvoiceCreditRequest{
+		CustomerID:  providerCustomerID,
+		InvoiceID:   invoice.ProviderInvoiceID,
+		Amount:      amount,
+		Currency:    string(invoice.Currency),
+		Description: "Account credit",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to credit invoice: %w", err)
+	}
+
+	return nil
+}
+
+// GetBalance returns a user's store credit balances and a page of their
+// balance history
+func (s *CustomerBalanceService) GetBalance(ctx context.Context, userID uuid.UUID, limit, offset int) (*models.CustomerBalanceResponse, error) {
+	customer, err := s.customerRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve customer",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if customer == nil {
+		// No customer means no credit
+		return &models.CustomerBalanceResponse{
+			Balances:     []models.CustomerBalance{},
+			Transactions: []models.CustomerBalanceTransaction{},
+			Total:        0,
+			Limit:        limit,
+			Offset:       offset,
+		}, nil
+	}
+
+	balances, err := s.balanceRepo.ListBalances(ctx, customer.ID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve balance",
+			http.StatusInternalServerError,
+		)
+	}
+
+	transactions, total, err := s.balanceRepo.ListTransactions(ctx, customer.ID, limit, offset)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list balance transactions",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.CustomerBalanceResponse{
+		Balances:     balances,
+		Transactions: transactions,
+		Total:        total,
+		Limit:        limit,
+		Offset:       offset,
+	}, nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCustomerBalanceRepository is a mock for CustomerBalanceRepository
+type MockCustomerBalanceRepository struct {
+	mock.Mock
+}
+
+func (m *MockCustomerBalanceRepository) Credit(ctx context.Context, txn *models.CustomerBalanceTransaction) (bool, error) {
+	args := m.Called(ctx, txn)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCustomerBalanceRepository) Debit(ctx context.Context, txn *models.CustomerBalanceTransaction, maxAmount models.Money) error {
+	args := m.Called(ctx, txn, maxAmount)
+	return args.Error(0)
+}
+
+func (m *MockCustomerBalanceRepository) SetReference(ctx context.Context, id uuid.UUID, referenceType string, referenceID uuid.UUID) error {
+	args := m.Called(ctx, id, referenceType, referenceID)
+	return args.Error(0)
+}
+
+func (m *MockCustomerBalanceRepository) ListBalances(ctx context.Context, customerID uuid.UUID) ([]models.CustomerBalance, error) {
+	args := m.Called(ctx, customerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.CustomerBalance), args.Error(1)
+}
+
+func (m *MockCustomerBalanceRepository) ListTransactions(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.CustomerBalanceTransaction, int, error) {
+	args := m.Called(ctx, customerID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]models.CustomerBalanceTransaction), args.Int(1), args.Error(2)
+}
+
+// debitBalance makes a mocked Debit take amount from the balance
+func debitBalance(amount int64) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		txn := args.Get(1).(*models.CustomerBalanceTransaction)
+		txn.ID = uuid.New()
+		txn.Amount = -amount
+	}
+}
+
+func TestCustomerBalanceService_ApplyToInvoice_CreditsProviderInvoice(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockBalanceRepo := new(MockCustomerBalanceRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewCustomerBalanceService(mockBalanceRepo, mockCustomerRepo, NewLedgerService(mockLedgerRepo, LedgerConfig{}), mockFactory)
+
+	stripeCustomerID := "cus_test123"
+	customer := &models.Customer{ID: uuid.New(), StripeCustomerID: &stripeCustomerID}
+	subscription := &models.Subscription{ID: uuid.New(), CustomerID: customer.ID, Provider: models.ProviderStripe}
+	invoice := &models.Invoice{
+		ID:                uuid.New(),
+		CustomerID:        customer.ID,
+		ProviderInvoiceID: "in_test123",
+		Status:            models.InvoiceStatusDraft,
+		Currency:          models.CurrencySEK,
+		AmountDue:         10000,
+	}
+
+	mockBalanceRepo.On("Debit", ctx, mock.MatchedBy(func(txn *models.CustomerBalanceTransaction) bool {
+		return txn.Type == models.CustomerBalanceTransactionInvoiceApplied && *txn.ReferenceID == invoice.ID
+	}), models.NewMoney(10000, models.CurrencySEK)).Run(debitBalance(3000)).Return(nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("CreateInvoiceCredit", ctx, mock.MatchedBy(func(req *providers.InvoiceCreditRequest) bool {
+		return req.CustomerID == stripeCustomerID && req.InvoiceID == "in_test123" && req.Amount == 3000
+	})).Return(nil)
+
+	var recorded *models.LedgerEntry
+	mockLedgerRepo.On("CreateEntry", ctx, mock.AnythingOfType("*models.LedgerEntry")).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(*models.LedgerEntry)
+	}).Return(true, nil)
+
+	// Execute
+	err := service.ApplyToInvoice(ctx, subscription, invoice)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.LedgerEntryCreditApplied, recorded.Type)
+	assert.Equal(t, []models.LedgerPosting{
+		{AccountCode: models.LedgerAccountCustomerCredit, Amount: 3000},
+		{AccountCode: models.LedgerAccountRevenue, Amount: -3000},
+	}, recorded.Postings)
+	mockBalanceRepo.AssertNotCalled(t, "Credit", mock.Anything, mock.Anything)
+	mockProvider.AssertExpectations(t)
+}
+
+func TestCustomerBalanceService_ApplyToInvoice_ReleasesCreditOnProviderError(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockBalanceRepo := new(MockCustomerBalanceRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewCustomerBalanceService(mockBalanceRepo, mockCustomerRepo, nil, mockFactory)
+
+	stripeCustomerID := "cus_test123"
+	customer := &models.Customer{ID: uuid.New(), StripeCustomerID: &stripeCustomerID}
+	subscription := &models.Subscription{ID: uuid.New(), CustomerID: customer.ID, Provider: models.ProviderStripe}
+	invoice := &models.Invoice{
+		ID:                uuid.New(),
+		CustomerID:        customer.ID,
+		ProviderInvoiceID: "in_test123",
+		Status:            models.InvoiceStatusDraft,
+		Currency:          models.CurrencySEK,
+		AmountDue:         10000,
+	}
+
+	mockBalanceRepo.On("Debit", ctx, mock.AnythingOfType("*models.CustomerBalanceTransaction"), models.NewMoney(10000, models.CurrencySEK)).Run(debitBalance(3000)).Return(nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("CreateInvoiceCredit", ctx, mock.AnythingOfType("*providers.InvoiceCreditRequest")).Return(errors.New("provider error"))
+	mockBalanceRepo.On("Credit", ctx, mock.MatchedBy(func(txn *models.CustomerBalanceTransaction) bool {
+		return txn.Type == models.CustomerBalanceTransactionReversal && txn.Amount == 3000
+	})).Return(true, nil)
+
+	// Execute
+	err := service.ApplyToInvoice(ctx, subscription, invoice)
+
+	// Assert
+	assert.Error(t, err)
+	mockBalanceRepo.AssertExpectations(t)
+}
+
+func TestCustomerBalanceService_GetBalance_NoCustomer(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	mockBalanceRepo := new(MockCustomerBalanceRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	service := NewCustomerBalanceService(mockBalanceRepo, mockCustomerRepo, nil, nil)
+
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(nil, nil)
+
+	// Execute
+	balance, err := service.GetBalance(ctx, userID, 20, 0)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, balance.Balances)
+	assert.Empty(t, balance.Transactions)
+	mockBalanceRepo.AssertNotCalled(t, "ListBalances", mock.Anything, mock.Anything)
+}
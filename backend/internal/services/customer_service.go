@@ -0,0 +1,417 @@
+package services
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/mail"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+	localePattern      = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+)
+
+// exportPageSize is how many rows are read at a time when exporting
+const exportPageSize = 100
+
+type CustomerService struct {
+	customerRepo     repository.CustomerRepositoryInterface
+	paymentRepo      repository.PaymentRepositoryInterface
+	subscriptionRepo repository.SubscriptionRepositoryInterface
+	refundRepo       repository.RefundRepositoryInterface
+	auditRepo        repository.AuditRepositoryInterface
+	providerFactory  ProviderFactoryInterface
+}
+
+func NewCustomerService(
+	customerRepo repository.CustomerRepositoryInterface,
+	paymentRepo repository.PaymentRepositoryInterface,
+	subscriptionRepo repository.SubscriptionRepositoryInterface,
+	refundRepo repository.RefundRepositoryInterface,
+	auditRepo repository.AuditRepositoryInterface,
+	providerFactory ProviderFactoryInterface,
+) *CustomerService {
+	return &CustomerService{
+		customerRepo:     customerRepo,
+		paymentRepo:      paymentRepo,
+		subscriptionRepo: subscriptionRepo,
+		refundRepo:       refundRepo,
+		auditRepo:        auditRepo,
+		providerFactory:  providerFactory,
+	}
+}
+
+// GetCustomer retrieves the customer record for a user
+func (s *CustomerService) GetCustomer(ctx context.Context, userID uuid.UUID) (*models.Customer, error) {
+	customer, err := s.customerRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve customer",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if customer == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Customer not found",
+			http.StatusNotFound,
+		)
+	}
+
+	return customer, nil
+}
+
+// UpdateCustomer updates a user's billing profile and syncs it to the
+// provider customer
+func (s *CustomerService) UpdateCustomer(ctx context.Context, userID uuid.UUID, req *models.UpdateCustomerRequest) (*models.Customer, error) {
+	customer, err := s.GetCustomer(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		customer.Name = strings.TrimSpace(*req.Name)
+	}
+	setProfileField(&customer.AddressLine1, req.AddressLine1)
+	setProfileField(&customer.AddressLine2, req.AddressLine2)
+	setProfileField(&customer.PostalCode, req.PostalCode)
+	setProfileField(&customer.City, req.City)
+	setProfileField(&customer.CompanyName, req.CompanyName)
+	setProfileField(&customer.OrgNumber, req.OrgNumber)
+	setProfileField(&customer.Locale, req.Locale)
+	setProfileField(&customer.InvoiceEmail, req.InvoiceEmail)
+	setProfileField(&customer.Country, req.Country)
+	setProfileField(&customer.VATID, req.VATID)
+
+	if err := normalizeBillingProfile(customer); err != nil {
+		return nil, err
+	}
+
+	// Sync to the provider first so the two never disagree
+	if customer.StripeCustomerID != nil {
+		provider, err := s.providerFactory.GetProvider(models.ProviderStripe)
+		if err != nil {
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Provider stripe not available",
+				http.StatusBadRequest,
+			)
+		}
+
+		if err := provider.UpdateCustomer(ctx, *customer.StripeCustomerID, providerCustomerRequest(customer)); err != nil {
+			log.Printf("Failed to update customer %s with provider: %v", customer.ID, err)
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to update customer with provider",
+				http.StatusBadGateway,
+			)
+		}
+	}
+
+	if err := s.customerRepo.Update(ctx, customer); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update customer",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return customer, nil
+}
+
+// ExportCustomer collects everything stored about a user's customer
+func (s *CustomerService) ExportCustomer(ctx context.Context, userID uuid.UUID) (*models.CustomerExport, error) {
+	customer, err := s.GetCustomer(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.CustomerExport{
+		ExportedAt: time.Now().UTC(),
+		Customer:   customer,
+	}
+
+	if export.Payments, err = listAll(func(limit, offset int) ([]models.Payment, int, error) {
+		return s.paymentRepo.ListByCustomer(ctx, customer.ID, limit, offset)
+	}); err != nil {
+		return nil, exportError("payments", err)
+	}
+	if export.Subscriptions, err = listAll(func(limit, offset int) ([]models.Subscription, int, error) {
+		return s.subscriptionRepo.ListByCustomer(ctx, customer.ID, limit, offset)
+	}); err != nil {
+		return nil, exportError("subscriptions", err)
+	}
+	if export.Refunds, err = listAll(func(limit, offset int) ([]models.Refund, int, error) {
+		return s.refundRepo.ListByCustomer(ctx, customer.ID, limit, offset)
+	}); err != nil {
+		return nil, exportError("refunds", err)
+	}
+	if export.AuditEvents, err = listAll(func(limit, offset int) ([]models.AuditEvent, int, error) {
+		return s.auditRepo.ListByCustomer(ctx, customer.ID, limit, offset)
+	}); err != nil {
+		return nil, exportError("audit events", err)
+	}
+
+	return export, nil
+}
+
+// EraseCustomer cancels a user's subscriptions, deletes the provider customer
+// and pseudonymizes the customer. Payments, refunds, invoices and receipts are
+// kept since bookkeeping law requires them, but no longer identify the person.
+func (s *CustomerService) EraseCustomer(ctx context.Context, userID uuid.UUID) error {
+	customer, err := s.GetCustomer(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	subscriptions, err := listAll(func(limit, offset int) ([]models.Subscription, int, error) {
+		return s.subscriptionRepo.ListByCustomer(ctx, customer.ID, limit, offset)
+	})
+	if err != nil {
+		return models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list subscriptions",
+			http.StatusInternalServerError,
+		)
+	}
+
+	for i := range subscriptions {
+		if err := s.cancelForErasure(ctx, &subscriptions[i], userID); err != nil {
+			return err
+		}
+	}
+
+	if customer.StripeCustomerID != nil {
+		provider, err := s.providerFactory.GetProvider(models.ProviderStripe)
+		if err != nil {
+			return models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Provider stripe not available",
+				http.StatusBadRequest,
+			)
+		}
+
+		if err := provider.DeleteCustomer(ctx, *customer.StripeCustomerID); err != nil {
+			log.Printf("Failed to delete provider customer for %s: %v", customer.ID, err)
+			return models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to delete customer with provider",
+				http.StatusBadGateway,
+			)
+		}
+	}
+
+	if err := s.customerRepo.Erase(ctx, customer.ID); err != nil {
+		return models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to erase customer",
+			http.StatusInternalServerError,
+		)
+	}
+
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		CustomerID:   &customer.ID,
+		ActorUserID:  &userID,
+		Action:       models.AuditActionCustomerErased,
+		ResourceType: "customer",
+		ResourceID:   customer.ID,
+		Details:      models.JSONBMap{"subscriptions": len(subscriptions)},
+	})
+
+	return nil
+}
+
+// cancelForErasure immediately cancels a subscription that is still running
+func (s *CustomerService) cancelForErasure(ctx context.Context, subscription *models.Subscription, userID uuid.UUID) error {
+	switch subscription.Status {
+	case models.SubscriptionStatusCanceled, models.SubscriptionStatusIncompleteExpired:
+		return nil
+	}
+
+	provider, err := s.providerFactory.GetProvider(subscription.Provider)
+	if err != nil {
+		return models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Provider not available",
+			http.StatusBadRequest,
+		)
+	}
+
+	canceled, err := provider.CancelSubscription(ctx, subscription.ProviderSubscriptionID, true)
+	if err != nil {
+		log.Printf("Failed to cancel subscription %s for erasure: %v", subscription.ID, err)
+		return models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to cancel subscription with provider",
+			http.StatusBadGateway,
+		)
+	}
+
+	subscription.Status = canceled.Status
+	subscription.CancelAt = canceled.CancelAt
+	subscription.CancelAtPeriodEnd = canceled.CancelAtPeriodEnd
+	subscription.CanceledAt = canceled.CanceledAt
+
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		return models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update subscription in database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		CustomerID:   &subscription.CustomerID,
+		ActorUserID:  &userID,
+		Action:       models.AuditActionSubscriptionCanceled,
+		ResourceType: "subscription",
+		ResourceID:   subscription.ID,
+		Details:      models.JSONBMap{"immediate": true, "erasure": true},
+	})
+
+	return nil
+}
+
+// listAll reads every page of a paginated listing
+func listAll[T any](list func(limit, offset int) ([]T, int, error)) ([]T, error) {
+	all := []T{}
+	for {
+		page, total, err := list(exportPageSize, len(all))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < exportPageSize || len(all) >= total {
+			return all, nil
+		}
+	}
+}
+
+// exportError logs a failed export query and hides it from the caller
+func exportError(what string, err error) error {
+	log.Printf("Failed to export %s: %v", what, err)
+	return models.NewAPIError(
+		models.ErrCodeProviderError,
+		"Failed to export "+what,
+		http.StatusInternalServerError,
+	)
+}
+
+// normalizeBillingProfile validates a customer's billing profile and brings
+// country codes and VAT IDs to canonical form
+func normalizeBillingProfile(customer *models.Customer) error {
+	if customer.Country != nil {
+		country := strings.ToUpper(*customer.Country)
+		if !countryCodePattern.MatchString(country) {
+			return models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Country must be a two-letter ISO 3166-1 code",
+				http.StatusBadRequest,
+			)
+		}
+		customer.Country = &country
+	}
+
+	if customer.VATID != nil {
+		vatID, vatCountry, ok := ParseVATID(*customer.VATID)
+		if !ok {
+			return models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Invalid VAT ID",
+				http.StatusBadRequest,
+			)
+		}
+		if customer.Country == nil {
+			customer.Country = &vatCountry
+		} else if *customer.Country != vatCountry {
+			return models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"VAT ID does not match the billing country",
+				http.StatusBadRequest,
+			)
+		}
+		customer.VATID = &vatID
+	}
+
+	if customer.Locale != nil && !localePattern.MatchString(*customer.Locale) {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Locale must look like sv or sv-SE",
+			http.StatusBadRequest,
+		)
+	}
+
+	if customer.InvoiceEmail != nil {
+		if _, err := mail.ParseAddress(*customer.InvoiceEmail); err != nil {
+			return models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Invalid invoice email",
+				http.StatusBadRequest,
+			)
+		}
+	}
+
+	return nil
+}
+
+// providerCustomerRequest builds the provider's view of a billing profile
+func providerCustomerRequest(customer *models.Customer) *providers.UpdateCustomerRequest {
+	req := &providers.UpdateCustomerRequest{
+		Name:   customer.Name,
+		Email:  customer.Email,
+		Locale: stringValue(customer.Locale),
+		VATID:  stringValue(customer.VATID),
+	}
+	if customer.CompanyName != nil {
+		req.Name = *customer.CompanyName
+	}
+	if customer.InvoiceEmail != nil {
+		req.Email = *customer.InvoiceEmail
+	}
+
+	if customer.AddressLine1 != nil || customer.City != nil || customer.Country != nil {
+		req.Address = &providers.Address{
+			Line1:      stringValue(customer.AddressLine1),
+			Line2:      stringValue(customer.AddressLine2),
+			PostalCode: stringValue(customer.PostalCode),
+			City:       stringValue(customer.City),
+			Country:    stringValue(customer.Country),
+		}
+	}
+
+	return req
+}
+
+// setProfileField applies an optional update to a profile field. Values are
+// trimmed and an empty value clears the field.
+func setProfileField(field **string, value *string) {
+	if value == nil {
+		return
+	}
+
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		*field = nil
+		return
+	}
+	*field = &trimmed
+}
+
+// stringValue returns the string s points to, or "" if s is nil
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
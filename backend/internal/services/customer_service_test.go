@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestCustomerService_UpdateCustomer_Success(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	stripeCustomerID := "cus_test123"
+
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewCustomerService(mockCustomerRepo, nil, nil, nil, nil, mockFactory)
+
+	existingCustomer := &models.Customer{
+		ID:               uuid.New(),
+		UserID:           userID,
+		Email:            "test@example.com",
+		Name:             "Test User",
+		StripeCustomerID: &stripeCustomerID,
+	}
+
+	req := &models.UpdateCustomerRequest{
+		AddressLine1: strPtr("Storgatan 1"),
+		PostalCode:   strPtr("111 22"),
+		City:         strPtr("Stockholm"),
+		CompanyName:  strPtr("Acme AB"),
+		VATID:        strPtr("se 5560000000 01"),
+		InvoiceEmail: strPtr("billing@acme.se"),
+	}
+
+	// Mock expectations
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(existingCustomer, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("UpdateCustomer", ctx, stripeCustomerID, mock.MatchedBy(func(r *providers.UpdateCustomerRequest) bool {
+		return r.Name == "Acme AB" &&
+			r.Email == "billing@acme.se" &&
+			r.VATID == "SE556000000001" &&
+			r.Address != nil && r.Address.Country == "SE"
+	})).Return(nil)
+	mockCustomerRepo.On("Update", ctx, existingCustomer).Return(nil)
+
+	// Execute
+	result, err := service.UpdateCustomer(ctx, userID, req)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "SE556000000001", *result.VATID)
+	assert.Equal(t, "SE", *result.Country)
+	assert.Equal(t, "Test User", result.Name)
+
+	mockCustomerRepo.AssertExpectations(t)
+	mockFactory.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+}
+
+func TestCustomerService_UpdateCustomer_VATIDCountryMismatch(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewCustomerService(mockCustomerRepo, nil, nil, nil, nil, mockFactory)
+
+	existingCustomer := &models.Customer{
+		ID:     uuid.New(),
+		UserID: userID,
+		Email:  "test@example.com",
+		Name:   "Test User",
+	}
+
+	req := &models.UpdateCustomerRequest{
+		Country: strPtr("de"),
+		VATID:   strPtr("SE556000000001"),
+	}
+
+	// Mock expectations
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(existingCustomer, nil)
+
+	// Execute
+	result, err := service.UpdateCustomer(ctx, userID, req)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+
+	mockCustomerRepo.AssertExpectations(t)
+	mockCustomerRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestCustomerService_EraseCustomer_Success(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	stripeCustomerID := "cus_test123"
+
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewCustomerService(mockCustomerRepo, nil, mockSubscriptionRepo, nil, mockAuditRepo, mockFactory)
+
+	existingCustomer := &models.Customer{
+		ID:               customerID,
+		UserID:           userID,
+		Email:            "test@example.com",
+		Name:             "Test User",
+		StripeCustomerID: &stripeCustomerID,
+	}
+
+	canceledAt := time.Now()
+	subscriptions := []models.Subscription{
+		{
+			ID:                     uuid.New(),
+			CustomerID:             customerID,
+			Provider:               models.ProviderStripe,
+			ProviderSubscriptionID: "sub_active",
+			Status:                 models.SubscriptionStatusActive,
+		},
+		{
+			ID:                     uuid.New(),
+			CustomerID:             customerID,
+			Provider:               models.ProviderStripe,
+			ProviderSubscriptionID: "sub_canceled",
+			Status:                 models.SubscriptionStatusCanceled,
+		},
+	}
+
+	// Mock expectations
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(existingCustomer, nil)
+	mockSubscriptionRepo.On("ListByCustomer", ctx, customerID, exportPageSize, 0).Return(subscriptions, 2, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("CancelSubscription", ctx, "sub_active", true).Return(&models.Subscription{
+		Status:     models.SubscriptionStatusCanceled,
+		CanceledAt: &canceledAt,
+	}, nil)
+	mockSubscriptionRepo.On("Update", ctx, mock.MatchedBy(func(s *models.Subscription) bool {
+		return s.ProviderSubscriptionID == "sub_active" && s.Status == models.SubscriptionStatusCanceled
+	})).Return(nil)
+	mockProvider.On("DeleteCustomer", ctx, stripeCustomerID).Return(nil)
+	mockCustomerRepo.On("Erase", ctx, customerID).Return(nil)
+	mockAuditRepo.On("Create", ctx, mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	// Execute
+	err := service.EraseCustomer(ctx, userID)
+
+	// Assert
+	assert.NoError(t, err)
+	mockProvider.AssertNotCalled(t, "CancelSubscription", ctx, "sub_canceled", true)
+	mockAuditRepo.AssertNumberOfCalls(t, "Create", 2)
+
+	mockCustomerRepo.AssertExpectations(t)
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+}
+
+func TestCustomerService_EraseCustomer_ProviderError(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	stripeCustomerID := "cus_test123"
+
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewCustomerService(mockCustomerRepo, nil, mockSubscriptionRepo, nil, nil, mockFactory)
+
+	existingCustomer := &models.Customer{
+		ID:               customerID,
+		UserID:           userID,
+		Email:            "test@example.com",
+		StripeCustomerID: &stripeCustomerID,
+	}
+
+	// Mock expectations
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(existingCustomer, nil)
+	mockSubscriptionRepo.On("ListByCustomer", ctx, customerID, exportPageSize, 0).Return([]models.Subscription{}, 0, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("DeleteCustomer", ctx, stripeCustomerID).Return(errors.New("stripe unavailable"))
+
+	// Execute
+	err := service.EraseCustomer(ctx, userID)
+
+	// Assert
+	assert.Error(t, err)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+
+	// Personal data is kept until the provider customer is gone
+	mockCustomerRepo.AssertNotCalled(t, "Erase", mock.Anything, mock.Anything)
+}
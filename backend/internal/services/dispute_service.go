@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"payment-service/internal/middleware"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// disputeEvidenceContentTypes are the file types the provider accepts as evidence
+var disputeEvidenceContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+type DisputeService struct {
+	disputeRepo     repository.DisputeRepositoryInterface
+	paymentRepo     repository.PaymentRepositoryInterface
+	eventRepo       repository.EventRepositoryInterface
+	ledgerService   *LedgerService
+	providerFactory ProviderFactoryInterface
+}
+
+func NewDisputeService(
+	disputeRepo repository.DisputeRepositoryInterface,
+	paymentRepo repository.PaymentRepositoryInterface,
+	eventRepo repository.EventRepositoryInterface,
+	ledgerService *LedgerService,
+	providerFactory ProviderFactoryInterface,
+) *DisputeService {
+	return &DisputeService{
+		disputeRepo:     disputeRepo,
+		paymentRepo:     paymentRepo,
+		eventRepo:       eventRepo,
+		ledgerService:   ledgerService,
+		providerFactory: providerFactory,
+	}
+}
+
+// HandleDisputeEvent creates or updates the local dispute from a provider
+// dispute event, flags the payment and books withdrawn or reinstated funds
+func (s *DisputeService) HandleDisputeEvent(ctx context.Context, eventType string, webhookDispute *providers.WebhookDispute) error {
+	providerDispute := webhookDispute.Dispute
+
+	dispute, err := s.disputeRepo.GetByProviderDisputeID(ctx, providerDispute.Provider, providerDispute.ProviderDisputeID)
+	if err != nil {
+		return fmt.Errorf("failed to get dispute: %w", err)
+	}
+
+	var payment *models.Payment
+	if dispute != nil {
+		payment, err = s.paymentRepo.GetByID(ctx, dispute.PaymentID)
+	} else if webhookDispute.ProviderPaymentID != "" {
+		payment, err = s.paymentRepo.GetByProviderPaymentID(ctx, providerDispute.Provider, webhookDispute.ProviderPaymentID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+	if payment == nil {
+		if dispute != nil {
+			return fmt.Errorf("payment %s for dispute %s not found", dispute.PaymentID, dispute.ID)
+		}
+		// Payment not found in our database, might be from external source
+		return nil
+	}
+
+	var previousStatus models.DisputeStatus
+	if dispute == nil {
+		dispute = providerDispute
+		dispute.PaymentID = payment.ID
+		dispute.CustomerID = payment.CustomerID
+		dispute.Evidence = models.JSONBMap{}
+	} else {
+		previousStatus = dispute.Status
+		// A closed dispute is final; ignore late events carrying older state
+		if !dispute.Status.Closed() {
+			dispute.Status = providerDispute.Status
+		}
+		dispute.Amount = providerDispute.Amount
+		dispute.Currency = providerDispute.Currency
+		dispute.Reason = providerDispute.Reason
+		dispute.EvidenceDueBy = providerDispute.EvidenceDueBy
+	}
+	if dispute.Status.Closed() && dispute.ClosedAt == nil {
+		now := time.Now()
+		dispute.ClosedAt = &now
+	}
+
+	if dispute.ID == uuid.Nil {
+		if err := s.disputeRepo.Create(ctx, dispute); err != nil {
+			return err
+		}
+	} else {
+		if err := s.disputeRepo.Update(ctx, dispute); err != nil {
+			return err
+		}
+	}
+
+	if payment.DisputedAt == nil {
+		if err := s.paymentRepo.MarkDisputed(ctx, payment, dispute.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	switch eventType {
+	case "charge.dispute.funds_withdrawn":
+		if err := s.ledgerService.RecordDispute(ctx, dispute); err != nil {
+			return fmt.Errorf("failed to record dispute in ledger: %w", err)
+		}
+	case "charge.dispute.funds_reinstated":
+		if err := s.ledgerService.RecordDisputeReversal(ctx, dispute); err != nil {
+			return fmt.Errorf("failed to record dispute reversal in ledger: %w", err)
+		}
+	}
+
+	if previousStatus == "" {
+		log.Printf("Payment %s disputed: %s %d %s, evidence due %v", payment.ID, dispute.Reason, dispute.Amount, dispute.Currency, dispute.EvidenceDueBy)
+		middleware.RecordDispute(string(dispute.Provider), string(dispute.Status), dispute.Reason)
+		s.publishDisputeEvent(ctx, dispute, models.EventTypePaymentDisputed)
+	}
+	if dispute.Status.Closed() && !previousStatus.Closed() {
+		log.Printf("Dispute %s on payment %s closed: %s", dispute.ID, payment.ID, dispute.Status)
+		middleware.RecordDispute(string(dispute.Provider), string(dispute.Status), dispute.Reason)
+		s.publishDisputeEvent(ctx, dispute, models.EventTypePaymentDisputeClosed)
+	}
+
+	return nil
+}
+
+// ListDisputes lists disputes, newest first
+func (s *DisputeService) ListDisputes(ctx context.Context, filter models.DisputeFilter) (*models.DisputeListResponse, error) {
+	disputes, total, err := s.disputeRepo.List(ctx, filter)
+	if err != nil {
+		log.Printf("Failed to list disputes: %v", err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve disputes",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.DisputeListResponse{
+		Data:   disputes,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+// GetDispute retrieves a dispute by ID
+func (s *DisputeService) GetDispute(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	dispute, err := s.disputeRepo.GetByID(ctx, id)
+	if err != nil {
+		log.Printf("Failed to get dispute %s: %v", id, err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve dispute",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if dispute == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Dispute not found",
+			http.StatusNotFound,
+		)
+	}
+
+	return dispute, nil
+}
+
+// SubmitEvidence uploads evidence for a dispute and, when requested, submits
+// it to the bank. Evidence can usually only be submitted once.
+func (s *DisputeService) SubmitEvidence(ctx context.Context, id uuid.UUID, req *models.SubmitDisputeEvidenceRequest) (*models.Dispute, error) {
+	dispute, err := s.GetDispute(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dispute.Status.NeedsResponse() {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Evidence cannot be submitted for a dispute that is %s", dispute.Status),
+			http.StatusConflict,
+		)
+	}
+	if dispute.EvidenceDueBy != nil && time.Now().After(*dispute.EvidenceDueBy) {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"The evidence deadline has passed",
+			http.StatusConflict,
+		)
+	}
+
+	if err := validateDisputeEvidence(req); err != nil {
+		return nil, err
+	}
+
+	provider, err := s.providerFactory.GetProvider(dispute.Provider)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Provider not available",
+			http.StatusBadRequest,
+		)
+	}
+
+	updated, err := provider.UpdateDisputeEvidence(ctx, dispute.ProviderDisputeID, &providers.DisputeEvidenceRequest{
+		Text:   req.Text,
+		Files:  req.Files,
+		Submit: req.Submit,
+	})
+	if err != nil {
+		log.Printf("Failed to update evidence for dispute %s: %v", dispute.ID, err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to submit evidence to provider",
+			http.StatusBadGateway,
+		)
+	}
+
+	if dispute.Evidence == nil {
+		dispute.Evidence = models.JSONBMap{}
+	}
+	for field, value := range updated.Evidence {
+		dispute.Evidence[field] = value
+	}
+	dispute.Status = updated.Status
+	if req.Submit {
+		now := time.Now()
+		dispute.EvidenceSubmittedAt = &now
+	}
+
+	if err := s.disputeRepo.Update(ctx, dispute); err != nil {
+		log.Printf("Failed to save evidence for dispute %s: %v", dispute.ID, err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to save dispute",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return dispute, nil
+}
+
+// validateDisputeEvidence checks evidence fields and files against what the
+// provider accepts
+func validateDisputeEvidence(req *models.SubmitDisputeEvidenceRequest) error {
+	if len(req.Text) == 0 && len(req.Files) == 0 && !req.Submit {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"No evidence provided",
+			http.StatusBadRequest,
+		)
+	}
+
+	for field := range req.Text {
+		if !models.DisputeEvidenceTextFields[field] {
+			return models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				fmt.Sprintf("Unknown evidence field: %s", field),
+				http.StatusBadRequest,
+			)
+		}
+	}
+
+	var size int
+	for _, f := range req.Files {
+		if !models.DisputeEvidenceFileFields[f.Field] {
+			return models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				fmt.Sprintf("Unknown evidence file field: %s", f.Field),
+				http.StatusBadRequest,
+			)
+		}
+		if !disputeEvidenceContentTypes[http.DetectContentType(f.Data)] {
+			return models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				fmt.Sprintf("Evidence file %s must be a PDF, JPEG or PNG", f.Filename),
+				http.StatusBadRequest,
+			)
+		}
+		size += len(f.Data)
+	}
+	if size > models.MaxDisputeEvidenceSize {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Evidence files exceed 4.5 MB in total",
+			http.StatusBadRequest,
+		)
+	}
+
+	return nil
+}
+
+// publishDisputeEvent publishes an event about a disputed payment to its customer
+func (s *DisputeService) publishDisputeEvent(ctx context.Context, dispute *models.Dispute, eventType models.EventType) {
+	data := models.JSONBMap{
+		"dispute_id": dispute.ID,
+		"status":     dispute.Status,
+		"reason":     dispute.Reason,
+		"amount":     dispute.Amount,
+		"currency":   dispute.Currency,
+	}
+	if dispute.EvidenceDueBy != nil {
+		data["evidence_due_by"] = dispute.EvidenceDueBy
+	}
+
+	publishEvent(ctx, s.eventRepo, &models.Event{
+		CustomerID:   dispute.CustomerID,
+		Type:         eventType,
+		ResourceType: "payment",
+		ResourceID:   dispute.PaymentID,
+		Data:         data,
+	})
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDisputeRepository is a mock for DisputeRepository
+type MockDisputeRepository struct {
+	mock.Mock
+}
+
+func (m *MockDisputeRepository) Create(ctx context.Context, dispute *models.Dispute) error {
+	args := m.Called(ctx, dispute)
+	return args.Error(0)
+}
+
+func (m *MockDisputeRepository) Update(ctx context.Context, dispute *models.Dispute) error {
+	args := m.Called(ctx, dispute)
+	return args.Error(0)
+}
+
+func (m *MockDisputeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeRepository) GetByProviderDisputeID(ctx context.Context, provider models.Provider, providerDisputeID string) (*models.Dispute, error) {
+	args := m.Called(ctx, provider, providerDisputeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Dispute), args.Error(1)
+}
+
+func (m *MockDisputeRepository) List(ctx context.Context, filter models.DisputeFilter) ([]models.Dispute, int, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.Dispute), args.Int(1), args.Error(2)
+}
+
+func TestDisputeService_HandleDisputeEvent_CreatesDisputeAndFlagsPayment(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockDisputeRepo := new(MockDisputeRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockEventRepo := new(MockEventRepository)
+	service := NewDisputeService(mockDisputeRepo, mockPaymentRepo, mockEventRepo, nil, nil)
+
+	payment := &models.Payment{
+		ID:                uuid.New(),
+		CustomerID:        uuid.New(),
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            10000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusSucceeded,
+	}
+	dueBy := time.Now().Add(7 * 24 * time.Hour)
+	webhookDispute := &providers.WebhookDispute{
+		ProviderPaymentID: "pi_test123",
+		Dispute: &models.Dispute{
+			Provider:          models.ProviderStripe,
+			ProviderDisputeID: "dp_test123",
+			Amount:            10000,
+			Currency:          models.CurrencySEK,
+			Status:            models.DisputeStatusNeedsResponse,
+			Reason:            "fraudulent",
+			EvidenceDueBy:     &dueBy,
+		},
+	}
+
+	mockDisputeRepo.On("GetByProviderDisputeID", ctx, models.ProviderStripe, "dp_test123").Return(nil, nil)
+	mockPaymentRepo.On("GetByProviderPaymentID", ctx, models.ProviderStripe, "pi_test123").Return(payment, nil)
+	mockDisputeRepo.On("Create", ctx, mock.MatchedBy(func(d *models.Dispute) bool {
+		return d.PaymentID == payment.ID && d.CustomerID == payment.CustomerID && d.Status == models.DisputeStatusNeedsResponse
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Dispute).ID = uuid.New()
+	}).Return(nil)
+	mockPaymentRepo.On("MarkDisputed", ctx, payment, mock.AnythingOfType("time.Time")).Return(nil)
+	mockEventRepo.On("Create", ctx, mock.MatchedBy(func(event *models.Event) bool {
+		return event.Type == models.EventTypePaymentDisputed && event.ResourceID == payment.ID &&
+			event.CustomerID == payment.CustomerID && event.Data["reason"] == "fraudulent"
+	})).Return(nil)
+
+	// Execute
+	err := service.HandleDisputeEvent(ctx, "charge.dispute.created", webhookDispute)
+
+	// Assert
+	assert.NoError(t, err)
+	mockDisputeRepo.AssertExpectations(t)
+	mockPaymentRepo.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestDisputeService_HandleDisputeEvent_ClosesDisputeAndKeepsFinalStatus(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockDisputeRepo := new(MockDisputeRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockEventRepo := new(MockEventRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	ledgerService := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+	service := NewDisputeService(mockDisputeRepo, mockPaymentRepo, mockEventRepo, ledgerService, nil)
+
+	disputedAt := time.Now().Add(-24 * time.Hour)
+	payment := &models.Payment{ID: uuid.New(), CustomerID: uuid.New(), DisputedAt: &disputedAt}
+	existing := &models.Dispute{
+		ID:                uuid.New(),
+		PaymentID:         payment.ID,
+		CustomerID:        payment.CustomerID,
+		Provider:          models.ProviderStripe,
+		ProviderDisputeID: "dp_test123",
+		Amount:            10000,
+		Currency:          models.CurrencySEK,
+		Status:            models.DisputeStatusUnderReview,
+		Reason:            "fraudulent",
+	}
+	webhookDispute := func(status models.DisputeStatus) *providers.WebhookDispute {
+		return &providers.WebhookDispute{
+			ProviderPaymentID: "pi_test123",
+			Dispute: &models.Dispute{
+				Provider:          models.ProviderStripe,
+				ProviderDisputeID: "dp_test123",
+				Amount:            10000,
+				Currency:          models.CurrencySEK,
+				Status:            status,
+				Reason:            "fraudulent",
+			},
+		}
+	}
+
+	mockDisputeRepo.On("GetByProviderDisputeID", ctx, models.ProviderStripe, "dp_test123").Return(existing, nil)
+	mockPaymentRepo.On("GetByID", ctx, payment.ID).Return(payment, nil)
+	mockDisputeRepo.On("Update", ctx, existing).Return(nil)
+	mockLedgerRepo.On("CreateEntry", ctx, mock.MatchedBy(func(entry *models.LedgerEntry) bool {
+		return entry.Type == models.LedgerEntryDisputeReversal && entry.ReferenceID == existing.ID &&
+			entry.Postings[0].AccountCode == models.LedgerAccountProviderBalance && entry.Postings[0].Amount == 10000
+	})).Return(true, nil)
+	mockEventRepo.On("Create", ctx, mock.MatchedBy(func(event *models.Event) bool {
+		return event.Type == models.EventTypePaymentDisputeClosed && event.Data["status"] == models.DisputeStatusWon
+	})).Return(nil).Once()
+
+	// Execute
+	err := service.HandleDisputeEvent(ctx, "charge.dispute.funds_reinstated", webhookDispute(models.DisputeStatusWon))
+	assert.NoError(t, err)
+
+	// A late update carrying the earlier status does not reopen the dispute
+	err = service.HandleDisputeEvent(ctx, "charge.dispute.updated", webhookDispute(models.DisputeStatusUnderReview))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.DisputeStatusWon, existing.Status)
+	assert.NotNil(t, existing.ClosedAt)
+	mockPaymentRepo.AssertNotCalled(t, "MarkDisputed", mock.Anything, mock.Anything, mock.Anything)
+	mockLedgerRepo.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestDisputeService_SubmitEvidence(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockDisputeRepo := new(MockDisputeRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewDisputeService(mockDisputeRepo, nil, nil, nil, mockFactory)
+
+	dueBy := time.Now().Add(24 * time.Hour)
+	dispute := &models.Dispute{
+		ID:                uuid.New(),
+		Provider:          models.ProviderStripe,
+		ProviderDisputeID: "dp_test123",
+		Status:            models.DisputeStatusNeedsResponse,
+		EvidenceDueBy:     &dueBy,
+		Evidence:          models.JSONBMap{},
+	}
+	receipt := []byte("%PDF-1.4\n%receipt\n")
+
+	mockDisputeRepo.On("GetByID", ctx, dispute.ID).Return(dispute, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("UpdateDisputeEvidence", ctx, "dp_test123", mock.MatchedBy(func(req *providers.DisputeEvidenceRequest) bool {
+		return req.Submit && req.Text["product_description"] == "Annual plan" && len(req.Files) == 1
+	})).Return(&models.Dispute{
+		Status:   models.DisputeStatusUnderReview,
+		Evidence: models.JSONBMap{"product_description": "Annual plan", "receipt": "file_test123"},
+	}, nil)
+	mockDisputeRepo.On("Update", ctx, dispute).Return(nil)
+
+	// Execute
+	result, err := service.SubmitEvidence(ctx, dispute.ID, &models.SubmitDisputeEvidenceRequest{
+		Text:   map[string]string{"product_description": "Annual plan"},
+		Files:  []models.DisputeEvidenceFile{{Field: "receipt", Filename: "receipt.pdf", Data: receipt}},
+		Submit: true,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.DisputeStatusUnderReview, result.Status)
+	assert.Equal(t, "file_test123", result.Evidence["receipt"])
+	assert.NotNil(t, result.EvidenceSubmittedAt)
+
+	// Evidence cannot be submitted again once the dispute is under review
+	_, err = service.SubmitEvidence(ctx, dispute.ID, &models.SubmitDisputeEvidenceRequest{Submit: true})
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+
+	mockProvider.AssertNumberOfCalls(t, "UpdateDisputeEvidence", 1)
+}
+
+func TestDisputeService_SubmitEvidence_RejectsUnsupportedFile(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockDisputeRepo := new(MockDisputeRepository)
+	mockFactory := new(MockProviderFactory)
+	service := NewDisputeService(mockDisputeRepo, nil, nil, nil, mockFactory)
+
+	dispute := &models.Dispute{ID: uuid.New(), Status: models.DisputeStatusNeedsResponse}
+	mockDisputeRepo.On("GetByID", ctx, dispute.ID).Return(dispute, nil)
+
+	// Execute
+	_, err := service.SubmitEvidence(ctx, dispute.ID, &models.SubmitDisputeEvidenceRequest{
+		Files: []models.DisputeEvidenceFile{{Field: "receipt", Filename: "receipt.txt", Data: []byte("plain text")}},
+	})
+
+	// Assert
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"time"
+)
+
+// dunningBatchSize caps how many subscriptions one dunning pass handles
+const dunningBatchSize = 100
+
+// DunningConfig controls how failed renewal payments are retried
+type DunningConfig struct {
+	// RetrySchedule holds retry offsets measured from the first failure
+	RetrySchedule []time.Duration
+	// GracePeriod is how long after the first failure the subscription stays
+	// usable before FinalAction is applied
+	GracePeriod time.Duration
+	FinalAction models.DunningFinalAction
+}
+
+type DunningService struct {
+	subscriptionRepo repository.SubscriptionRepositoryInterface
+	eventRepo        repository.EventRepositoryInterface
+	providerFactory  ProviderFactoryInterface
+	config           DunningConfig
+}
+
+func NewDunningService(
+	subscriptionRepo repository.SubscriptionRepositoryInterface,
+	eventRepo repository.EventRepositoryInterface,
+	providerFactory ProviderFactoryInterface,
+	config DunningConfig,
+) *DunningService {
+	return &DunningService{
+		subscriptionRepo: subscriptionRepo,
+		eventRepo:        eventRepo,
+		providerFactory:  providerFactory,
+		config:           config,
+	}
+}
+
+// HandlePaymentFailed starts dunning for a failed renewal invoice, or records
+// another failed attempt if dunning is already under way. attempt is the
+// provider's attempt count for the invoice; replays of the same attempt are
+// ignored.
+func (s *DunningService) HandlePaymentFailed(ctx context.Context, subscription *models.Subscription, invoiceID string, attempt int) error {
+	if subscription.Status == models.SubscriptionStatusCanceled {
+		return nil
+	}
+
+	if !s.recordFailure(subscription, invoiceID, attempt, time.Now()) {
+		return nil
+	}
+
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+
+	s.publishPaymentFailed(ctx, subscription)
+
+	return nil
+}
+
+// HandlePaymentSucceeded ends dunning once the invoice being retried is paid
+func (s *DunningService) HandlePaymentSucceeded(ctx context.Context, subscription *models.Subscription, invoiceID string) error {
+	if !isRecoverable(subscription, invoiceID) {
+		return nil
+	}
+
+	recoverSubscription(subscription)
+
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+
+	s.publishSubscriptionEvent(ctx, subscription, models.EventTypeSubscriptionPaymentRecovered, models.JSONBMap{
+		"invoice_id": invoiceID,
+		"attempts":   subscription.DunningAttempts,
+	})
+
+	return nil
+}
+
+// ProcessDueRetries retries payments whose next attempt is due and applies the
+// final action to subscriptions whose grace period has ended. Each subscription
+// is claimed atomically first, so several instances can run this concurrently.
+func (s *DunningService) ProcessDueRetries(ctx context.Context) (*models.DunningRunResult, error) {
+	now := time.Now()
+
+	subscriptions, err := s.subscriptionRepo.ListDunningDue(ctx, now, dunningBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.DunningRunResult{}
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+
+		if subscription.GracePeriodEndsAt != nil && !now.Before(*subscription.GracePeriodEndsAt) {
+			exhausted, err := s.exhaust(ctx, subscription, now)
+			if err != nil {
+				log.Printf("Failed to end dunning for subscription %s: %v", subscription.ID, err)
+				result.Failed++
+			} else if exhausted {
+				result.Exhausted++
+			}
+			continue
+		}
+
+		if subscription.NextRetryAt != nil && !now.Before(*subscription.NextRetryAt) {
+			retried, recovered, err := s.retry(ctx, subscription)
+			if err != nil {
+				log.Printf("Failed to retry payment for subscription %s: %v", subscription.ID, err)
+				result.Failed++
+				continue
+			}
+			if retried {
+				result.Retried++
+			}
+			if recovered {
+				result.Recovered++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// retry attempts to pay the invoice being retried for a subscription
+func (s *DunningService) retry(ctx context.Context, subscription *models.Subscription) (retried, recovered bool, err error) {
+	if subscription.DunningInvoiceID == nil {
+		return false, false, fmt.Errorf("subscription has no invoice to retry")
+	}
+	invoiceID := *subscription.DunningInvoiceID
+	scheduledAt := *subscription.NextRetryAt
+	attempt := subscription.DunningAttempts + 1
+
+	claimed, err := s.subscriptionRepo.ClaimDunningRetry(ctx, subscription.ID, scheduledAt)
+	if err != nil {
+		return false, false, err
+	}
+	if !claimed {
+		// Another instance or a webhook handled it
+		return false, false, nil
+	}
+
+	provider, err := s.providerFactory.GetProvider(subscription.Provider)
+	if err != nil {
+		return false, false, s.restoreRetry(ctx, subscription, scheduledAt, err)
+	}
+
+	payErr := provider.PayInvoice(ctx, invoiceID)
+	if errors.Is(payErr, providers.ErrPaymentPending) {
+		// The customer has yet to approve the payment; check again next pass
+		return false, false, s.restoreRetry(ctx, subscription, scheduledAt, nil)
+	}
+	if payErr != nil && !errors.Is(payErr, providers.ErrPaymentDeclined) {
+		return false, false, s.restoreRetry(ctx, subscription, scheduledAt, payErr)
+	}
+
+	// Reload, since a webhook for this attempt may already have been applied
+	current, err := s.subscriptionRepo.GetByID(ctx, subscription.ID)
+	if err != nil {
+		return true, false, err
+	}
+	if current == nil {
+		return true, false, fmt.Errorf("subscription not found")
+	}
+
+	if payErr == nil {
+		if err := s.HandlePaymentSucceeded(ctx, current, invoiceID); err != nil {
+			return true, false, err
+		}
+		return true, true, nil
+	}
+
+	return true, false, s.HandlePaymentFailed(ctx, current, invoiceID, attempt)
+}
+
+// restoreRetry puts back a claimed retry that could not be attempted, so the
+// next pass tries again
+func (s *DunningService) restoreRetry(ctx context.Context, subscription *models.Subscription, scheduledAt time.Time, cause error) error {
+	current, err := s.subscriptionRepo.GetByID(ctx, subscription.ID)
+	if err != nil || current == nil || current.DunningStatus == nil || *current.DunningStatus != models.DunningStatusRetrying {
+		return cause
+	}
+
+	current.NextRetryAt = &scheduledAt
+	if err := s.subscriptionRepo.Update(ctx, current); err != nil {
+		log.Printf("Failed to restore dunning retry for subscription %s: %v", subscription.ID, err)
+	}
+
+	return cause
+}
+
+// exhaust applies the configured final action once the grace period has ended
+func (s *DunningService) exhaust(ctx context.Context, subscription *models.Subscription, now time.Time) (bool, error) {
+	claimed, err := s.subscriptionRepo.ClaimDunningExhaustion(ctx, subscription.ID, now)
+	if err != nil {
+		return false, err
+	}
+	if !claimed {
+		return false, nil
+	}
+
+	current, err := s.subscriptionRepo.GetByID(ctx, subscription.ID)
+	if err != nil {
+		return false, err
+	}
+	if current == nil {
+		return false, fmt.Errorf("subscription not found")
+	}
+
+	switch s.config.FinalAction {
+	case models.DunningFinalActionMarkUnpaid:
+		current.Status = models.SubscriptionStatusUnpaid
+	default:
+		provider, err := s.providerFactory.GetProvider(current.Provider)
+		if err != nil {
+			return false, s.restoreExhaustion(ctx, current, err)
+		}
+
+		canceled, err := provider.CancelSubscription(ctx, current.ProviderSubscriptionID, true)
+		if err != nil {
+			return false, s.restoreExhaustion(ctx, current, err)
+		}
+
+		current.Status = models.SubscriptionStatusCanceled
+		current.CanceledAt = canceled.CanceledAt
+		if current.CanceledAt == nil {
+			current.CanceledAt = &now
+		}
+	}
+
+	current.NextRetryAt = nil
+	if err := s.subscriptionRepo.Update(ctx, current); err != nil {
+		return false, fmt.Errorf("failed to update subscription: %w", err)
+	}
+
+	data := models.JSONBMap{
+		"action":   s.config.FinalAction,
+		"attempts": current.DunningAttempts,
+		"status":   current.Status,
+	}
+	if current.DunningInvoiceID != nil {
+		data["invoice_id"] = *current.DunningInvoiceID
+	}
+	s.publishSubscriptionEvent(ctx, current, models.EventTypeSubscriptionDunningExhausted, data)
+
+	return true, nil
+}
+
+// restoreExhaustion puts a subscription back into retrying when the final
+// action could not be applied, so the next pass tries again
+func (s *DunningService) restoreExhaustion(ctx context.Context, subscription *models.Subscription, cause error) error {
+	status := models.DunningStatusRetrying
+	subscription.DunningStatus = &status
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		log.Printf("Failed to restore dunning for subscription %s: %v", subscription.ID, err)
+	}
+
+	return cause
+}
+
+// recordFailure applies a failed payment attempt to the subscription's dunning
+// state and reports whether anything changed
+func (s *DunningService) recordFailure(subscription *models.Subscription, invoiceID string, attempt int, now time.Time) bool {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	sameInvoice := subscription.DunningStatus != nil &&
+		subscription.DunningInvoiceID != nil && *subscription.DunningInvoiceID == invoiceID
+
+	if sameInvoice && *subscription.DunningStatus == models.DunningStatusExhausted {
+		// Dunning already gave up on this invoice
+		return false
+	}
+
+	if sameInvoice && *subscription.DunningStatus == models.DunningStatusRetrying {
+		if attempt <= subscription.DunningAttempts {
+			// Already recorded
+			return false
+		}
+	} else {
+		status := models.DunningStatusRetrying
+		gracePeriodEndsAt := now.Add(s.config.GracePeriod)
+		subscription.DunningStatus = &status
+		subscription.DunningInvoiceID = &invoiceID
+		subscription.DunningStartedAt = &now
+		subscription.GracePeriodEndsAt = &gracePeriodEndsAt
+	}
+
+	subscription.DunningAttempts = attempt
+	subscription.NextRetryAt = s.nextRetryAt(subscription)
+
+	if subscription.Status != models.SubscriptionStatusUnpaid {
+		subscription.Status = models.SubscriptionStatusPastDue
+	}
+
+	return true
+}
+
+// nextRetryAt returns when the next retry is due, or nil if the schedule is
+// used up and the subscription is waiting for its grace period to end
+func (s *DunningService) nextRetryAt(subscription *models.Subscription) *time.Time {
+	retries := subscription.DunningAttempts - 1
+	if retries >= len(s.config.RetrySchedule) {
+		return nil
+	}
+
+	next := subscription.DunningStartedAt.Add(s.config.RetrySchedule[retries])
+	if !next.Before(*subscription.GracePeriodEndsAt) {
+		return nil
+	}
+
+	return &next
+}
+
+// isRecoverable reports whether paying invoiceID ends the subscription's dunning
+func isRecoverable(subscription *models.Subscription, invoiceID string) bool {
+	if subscription.DunningStatus == nil || subscription.DunningInvoiceID == nil || *subscription.DunningInvoiceID != invoiceID {
+		return false
+	}
+
+	switch *subscription.DunningStatus {
+	case models.DunningStatusRetrying:
+		return true
+	case models.DunningStatusExhausted:
+		// A subscription marked unpaid can still be recovered by paying
+		return subscription.Status == models.SubscriptionStatusUnpaid
+	default:
+		return false
+	}
+}
+
+// recoverSubscription clears the retry state after a successful payment
+func recoverSubscription(subscription *models.Subscription) {
+	status := models.DunningStatusRecovered
+	subscription.DunningStatus = &status
+	subscription.NextRetryAt = nil
+	subscription.GracePeriodEndsAt = nil
+
+	if subscription.Status == models.SubscriptionStatusPastDue || subscription.Status == models.SubscriptionStatusUnpaid {
+		subscription.Status = models.SubscriptionStatusActive
+	}
+}
+
+// publishPaymentFailed notifies the customer about a failed attempt and what happens next
+func (s *DunningService) publishPaymentFailed(ctx context.Context, subscription *models.Subscription) {
+	data := models.JSONBMap{
+		"attempt":              subscription.DunningAttempts,
+		"grace_period_ends_at": subscription.GracePeriodEndsAt,
+		"final_action":         s.config.FinalAction,
+	}
+	if subscription.DunningInvoiceID != nil {
+		data["invoice_id"] = *subscription.DunningInvoiceID
+	}
+	if subscription.NextRetryAt != nil {
+		data["next_retry_at"] = subscription.NextRetryAt
+	}
+
+	s.publishSubscriptionEvent(ctx, subscription, models.EventTypeSubscriptionPaymentFailed, data)
+}
+
+// publishSubscriptionEvent publishes an event about a subscription to its customer
+func (s *DunningService) publishSubscriptionEvent(
+	ctx context.Context,
+	subscription *models.Subscription,
+	eventType models.EventType,
+	data models.JSONBMap,
+) {
+	publishEvent(ctx, s.eventRepo, &models.Event{
+		CustomerID:   subscription.CustomerID,
+		Type:         eventType,
+		ResourceType: "subscription",
+		ResourceID:   subscription.ID,
+		Data:         data,
+	})
+}
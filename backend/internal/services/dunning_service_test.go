@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockEventRepository is a mock for EventRepository
+type MockEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockEventRepository) Create(ctx context.Context, event *models.Event) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockEventRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Event, int, error) {
+	args := m.Called(ctx, customerID, limit, offset)
+	return args.Get(0).([]models.Event), args.Int(1), args.Error(2)
+}
+
+var testDunningConfig = DunningConfig{
+	RetrySchedule: []time.Duration{72 * time.Hour, 120 * time.Hour},
+	GracePeriod:   336 * time.Hour,
+	FinalAction:   models.DunningFinalActionCancel,
+}
+
+func TestDunningService_HandlePaymentFailed_StartsDunning(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	subscriptionID := uuid.New()
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockEventRepo := new(MockEventRepository)
+
+	service := NewDunningService(mockSubscriptionRepo, mockEventRepo, new(MockProviderFactory), testDunningConfig)
+
+	subscription := &models.Subscription{
+		ID:                     subscriptionID,
+		CustomerID:             uuid.New(),
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusActive,
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("Update", ctx, subscription).Return(nil)
+	mockEventRepo.On("Create", ctx, mock.MatchedBy(func(event *models.Event) bool {
+		return event.Type == models.EventTypeSubscriptionPaymentFailed &&
+			event.ResourceID == subscriptionID && event.Data["attempt"] == 1
+	})).Return(nil)
+
+	// Execute
+	err := service.HandlePaymentFailed(ctx, subscription, "in_test123", 1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusPastDue, subscription.Status)
+	assert.Equal(t, models.DunningStatusRetrying, *subscription.DunningStatus)
+	assert.Equal(t, "in_test123", *subscription.DunningInvoiceID)
+	assert.Equal(t, 1, subscription.DunningAttempts)
+	assert.Equal(t, subscription.DunningStartedAt.Add(72*time.Hour), *subscription.NextRetryAt)
+	assert.Equal(t, subscription.DunningStartedAt.Add(336*time.Hour), *subscription.GracePeriodEndsAt)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestDunningService_HandlePaymentFailed_ReplayIgnored(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	startedAt := time.Now().Add(-time.Hour)
+	nextRetryAt := startedAt.Add(72 * time.Hour)
+	gracePeriodEndsAt := startedAt.Add(336 * time.Hour)
+	status := models.DunningStatusRetrying
+	invoiceID := "in_test123"
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockEventRepo := new(MockEventRepository)
+
+	service := NewDunningService(mockSubscriptionRepo, mockEventRepo, new(MockProviderFactory), testDunningConfig)
+
+	subscription := &models.Subscription{
+		ID:                uuid.New(),
+		Status:            models.SubscriptionStatusPastDue,
+		DunningStatus:     &status,
+		DunningInvoiceID:  &invoiceID,
+		DunningAttempts:   1,
+		DunningStartedAt:  &startedAt,
+		NextRetryAt:       &nextRetryAt,
+		GracePeriodEndsAt: &gracePeriodEndsAt,
+	}
+
+	// Execute
+	err := service.HandlePaymentFailed(ctx, subscription, invoiceID, 1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, subscription.DunningAttempts)
+
+	mockSubscriptionRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	mockEventRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestDunningService_ProcessDueRetries_Recovered(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	subscriptionID := uuid.New()
+	startedAt := time.Now().Add(-73 * time.Hour)
+	nextRetryAt := startedAt.Add(72 * time.Hour)
+	gracePeriodEndsAt := startedAt.Add(336 * time.Hour)
+	status := models.DunningStatusRetrying
+	invoiceID := "in_test123"
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockEventRepo := new(MockEventRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewDunningService(mockSubscriptionRepo, mockEventRepo, mockFactory, testDunningConfig)
+
+	subscription := models.Subscription{
+		ID:                subscriptionID,
+		Provider:          models.ProviderStripe,
+		Status:            models.SubscriptionStatusPastDue,
+		DunningStatus:     &status,
+		DunningInvoiceID:  &invoiceID,
+		DunningAttempts:   1,
+		DunningStartedAt:  &startedAt,
+		NextRetryAt:       &nextRetryAt,
+		GracePeriodEndsAt: &gracePeriodEndsAt,
+	}
+	current := subscription
+	current.NextRetryAt = nil
+
+	// Mock expectations
+	mockSubscriptionRepo.On("ListDunningDue", ctx, mock.Anything, dunningBatchSize).Return([]models.Subscription{subscription}, nil)
+	mockSubscriptionRepo.On("ClaimDunningRetry", ctx, subscriptionID, nextRetryAt).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("PayInvoice", ctx, invoiceID).Return(nil)
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(&current, nil)
+	mockSubscriptionRepo.On("Update", ctx, &current).Return(nil)
+	mockEventRepo.On("Create", ctx, mock.MatchedBy(func(event *models.Event) bool {
+		return event.Type == models.EventTypeSubscriptionPaymentRecovered
+	})).Return(nil)
+
+	// Execute
+	result, err := service.ProcessDueRetries(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &models.DunningRunResult{Retried: 1, Recovered: 1}, result)
+	assert.Equal(t, models.SubscriptionStatusActive, current.Status)
+	assert.Equal(t, models.DunningStatusRecovered, *current.DunningStatus)
+	assert.Nil(t, current.NextRetryAt)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestDunningService_ProcessDueRetries_Declined(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	subscriptionID := uuid.New()
+	startedAt := time.Now().Add(-73 * time.Hour)
+	nextRetryAt := startedAt.Add(72 * time.Hour)
+	gracePeriodEndsAt := startedAt.Add(336 * time.Hour)
+	status := models.DunningStatusRetrying
+	invoiceID := "in_test123"
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockEventRepo := new(MockEventRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewDunningService(mockSubscriptionRepo, mockEventRepo, mockFactory, testDunningConfig)
+
+	subscription := models.Subscription{
+		ID:                subscriptionID,
+		Provider:          models.ProviderStripe,
+		Status:            models.SubscriptionStatusPastDue,
+		DunningStatus:     &status,
+		DunningInvoiceID:  &invoiceID,
+		DunningAttempts:   1,
+		DunningStartedAt:  &startedAt,
+		NextRetryAt:       &nextRetryAt,
+		GracePeriodEndsAt: &gracePeriodEndsAt,
+	}
+	current := subscription
+	current.NextRetryAt = nil
+
+	// Mock expectations
+	mockSubscriptionRepo.On("ListDunningDue", ctx, mock.Anything, dunningBatchSize).Return([]models.Subscription{subscription}, nil)
+	mockSubscriptionRepo.On("ClaimDunningRetry", ctx, subscriptionID, nextRetryAt).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("PayInvoice", ctx, invoiceID).Return(fmt.Errorf("stripe: %w: card declined", providers.ErrPaymentDeclined))
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(&current, nil)
+	mockSubscriptionRepo.On("Update", ctx, &current).Return(nil)
+	mockEventRepo.On("Create", ctx, mock.MatchedBy(func(event *models.Event) bool {
+		return event.Type == models.EventTypeSubscriptionPaymentFailed && event.Data["attempt"] == 2
+	})).Return(nil)
+
+	// Execute
+	result, err := service.ProcessDueRetries(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &models.DunningRunResult{Retried: 1}, result)
+	assert.Equal(t, 2, current.DunningAttempts)
+	assert.Equal(t, startedAt.Add(120*time.Hour), *current.NextRetryAt)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestDunningService_ProcessDueRetries_PaymentPending(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	subscriptionID := uuid.New()
+	startedAt := time.Now().Add(-73 * time.Hour)
+	nextRetryAt := startedAt.Add(72 * time.Hour)
+	gracePeriodEndsAt := startedAt.Add(336 * time.Hour)
+	status := models.DunningStatusRetrying
+	invoiceID := "native_test"
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockEventRepo := new(MockEventRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewDunningService(mockSubscriptionRepo, mockEventRepo, mockFactory, testDunningConfig)
+
+	subscription := models.Subscription{
+		ID:                subscriptionID,
+		Provider:          models.ProviderSwish,
+		Status:            models.SubscriptionStatusPastDue,
+		DunningStatus:     &status,
+		DunningInvoiceID:  &invoiceID,
+		DunningAttempts:   1,
+		DunningStartedAt:  &startedAt,
+		NextRetryAt:       &nextRetryAt,
+		GracePeriodEndsAt: &gracePeriodEndsAt,
+	}
+	current := subscription
+	current.NextRetryAt = nil
+
+	// Mock expectations
+	mockSubscriptionRepo.On("ListDunningDue", ctx, mock.Anything, dunningBatchSize).Return([]models.Subscription{subscription}, nil)
+	mockSubscriptionRepo.On("ClaimDunningRetry", ctx, subscriptionID, nextRetryAt).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderSwish).Return(mockProvider, nil)
+	mockProvider.On("PayInvoice", ctx, invoiceID).Return(providers.ErrPaymentPending)
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(&current, nil)
+	mockSubscriptionRepo.On("Update", ctx, &current).Return(nil)
+
+	// Execute
+	result, err := service.ProcessDueRetries(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &models.DunningRunResult{}, result)
+	assert.Equal(t, 1, current.DunningAttempts)
+	assert.Equal(t, nextRetryAt, *current.NextRetryAt)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockEventRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type EventService struct {
+	eventRepo    repository.EventRepositoryInterface
+	customerRepo repository.CustomerRepositoryInterface
+}
+
+func NewEventService(
+	eventRepo repository.EventRepositoryInterface,
+	customerRepo repository.CustomerRepositoryInterface,
+) *EventService {
+	return &EventService{
+		eventRepo:    eventRepo,
+		customerRepo: customerRepo,
+	}
+}
+
+// ListEvents lists events for a user
+func (s *EventService) ListEvents(ctx context.Context, userID uuid.UUID, limit, offset int) (*models.EventListResponse, error) {
+	// Get customer
+	customer, err := s.customerRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve customer",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if customer == nil {
+		// No customer means no events
+		return &models.EventListResponse{
+			Data:   []models.Event{},
+			Total:  0,
+			Limit:  limit,
+			Offset: offset,
+		}, nil
+	}
+
+	// Get events
+	events, total, err := s.eventRepo.ListByCustomer(ctx, customer.ID, limit, offset)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list events",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.EventListResponse{
+		Data:   events,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
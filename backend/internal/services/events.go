@@ -0,0 +1,16 @@
+package services
+
+import (
+	"context"
+	"log"
+	"payment-service/internal/models"
+	"payment-service/internal/repository"
+)
+
+// publishEvent records an event for client applications. Failures are logged
+// rather than returned so they never undo the change that triggered them.
+func publishEvent(ctx context.Context, eventRepo repository.EventRepositoryInterface, event *models.Event) {
+	if err := eventRepo.Create(ctx, event); err != nil {
+		log.Printf("Failed to publish event %s for %s %s: %v", event.Type, event.ResourceType, event.ResourceID, err)
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type InvoiceService struct {
+	invoiceRepo      repository.InvoiceRepositoryInterface
+	subscriptionRepo repository.SubscriptionRepositoryInterface
+	customerRepo     repository.CustomerRepositoryInterface
+}
+
+func NewInvoiceService(
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	subscriptionRepo repository.SubscriptionRepositoryInterface,
+	customerRepo repository.CustomerRepositoryInterface,
+) *InvoiceService {
+	return &InvoiceService{
+		invoiceRepo:      invoiceRepo,
+		subscriptionRepo: subscriptionRepo,
+		customerRepo:     customerRepo,
+	}
+}
+
+// ListInvoices lists invoices for a user
+func (s *InvoiceService) ListInvoices(ctx context.Context, userID uuid.UUID, limit, offset int) (*models.InvoiceListResponse, error) {
+	// Get customer
+	customer, err := s.customerRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve customer",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if customer == nil {
+		// No customer means no invoices
+		return &models.InvoiceListResponse{
+			Data:   []models.Invoice{},
+			Total:  0,
+			Limit:  limit,
+			Offset: offset,
+		}, nil
+	}
+
+	// Get invoices
+	invoices, total, err := s.invoiceRepo.ListByCustomer(ctx, customer.ID, limit, offset)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list invoices",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.InvoiceListResponse{
+		Data:   invoices,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// ListSubscriptionInvoices lists the invoices of a subscription owned by a user
+func (s *InvoiceService) ListSubscriptionInvoices(
+	ctx context.Context,
+	subscriptionID, userID uuid.UUID,
+	limit, offset int,
+) (*models.InvoiceListResponse, error) {
+	subscription, err := s.subscriptionRepo.GetByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve subscription",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if subscription == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Subscription not found",
+			http.StatusNotFound,
+		)
+	}
+
+	// Verify customer owns this subscription
+	customer, err := s.customerRepo.GetByID(ctx, subscription.CustomerID)
+	if err != nil || customer == nil || customer.UserID != userID {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Subscription not found",
+			http.StatusNotFound,
+		)
+	}
+
+	// Get invoices
+	invoices, total, err := s.invoiceRepo.ListBySubscription(ctx, subscription.ID, limit, offset)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list invoices",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.InvoiceListResponse{
+		Data:   invoices,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockInvoiceRepository is a mock for InvoiceRepository
+type MockInvoiceRepository struct {
+	mock.Mock
+}
+
+func (m *MockInvoiceRepository) Create(ctx context.Context, invoice *models.Invoice) error {
+	args := m.Called(ctx, invoice)
+	return args.Error(0)
+}
+
+func (m *MockInvoiceRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Invoice, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) GetByProviderInvoiceID(ctx context.Context, provider models.Provider, providerInvoiceID string) (*models.Invoice, error) {
+	args := m.Called(ctx, provider, providerInvoiceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Invoice, int, error) {
+	args := m.Called(ctx, customerID, limit, offset)
+	return args.Get(0).([]models.Invoice), args.Int(1), args.Error(2)
+}
+
+func (m *MockInvoiceRepository) ListBySubscription(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]models.Invoice, int, error) {
+	args := m.Called(ctx, subscriptionID, limit, offset)
+	return args.Get(0).([]models.Invoice), args.Int(1), args.Error(2)
+}
+
+func (m *MockInvoiceRepository) Update(ctx context.Context, invoice *models.Invoice) error {
+	args := m.Called(ctx, invoice)
+	return args.Error(0)
+}
+
+func TestInvoiceService_ListSubscriptionInvoices_Success(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+
+	service := NewInvoiceService(mockInvoiceRepo, mockSubscriptionRepo, mockCustomerRepo)
+
+	subscription := &models.Subscription{ID: subscriptionID, CustomerID: customerID}
+	customer := &models.Customer{ID: customerID, UserID: userID}
+	invoices := []models.Invoice{
+		{ID: uuid.New(), SubscriptionID: &subscriptionID, Status: models.InvoiceStatusPaid, Total: 9900},
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(customer, nil)
+	mockInvoiceRepo.On("ListBySubscription", ctx, subscriptionID, 20, 0).Return(invoices, 1, nil)
+
+	// Execute
+	result, err := service.ListSubscriptionInvoices(ctx, subscriptionID, userID, 20, 0)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Total)
+	assert.Equal(t, invoices, result.Data)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockCustomerRepo.AssertExpectations(t)
+	mockInvoiceRepo.AssertExpectations(t)
+}
+
+func TestInvoiceService_ListSubscriptionInvoices_UnauthorizedAccess(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+
+	service := NewInvoiceService(mockInvoiceRepo, mockSubscriptionRepo, mockCustomerRepo)
+
+	subscription := &models.Subscription{ID: subscriptionID, CustomerID: customerID}
+	customer := &models.Customer{ID: customerID, UserID: uuid.New()}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(customer, nil)
+
+	// Execute
+	result, err := service.ListSubscriptionInvoices(ctx, subscriptionID, uuid.New(), 20, 0)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, models.ErrCodeNotFound, apiErr.Code)
+
+	mockInvoiceRepo.AssertNotCalled(t, "ListBySubscription", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWebhookService_InvoicePaid_LinksPayment(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+	paymentID := uuid.New()
+	paidAt := time.Now().Truncate(time.Second)
+
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+
+	dunningService := NewDunningService(mockSubscriptionRepo, new(MockEventRepository), new(MockProviderFactory), testDunningConfig)
+	mockLedgerRepo := new(MockLedgerRepository)
+	ledgerService := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	settlementService := NewSettlementService(mockPaymentRepo, nil, ledgerService, mockFactory)
+	service := NewWebhookService(nil, mockPaymentRepo, mockSubscriptionRepo, nil, nil, mockInvoiceRepo, dunningService, nil, ledgerService, settlementService, nil, nil)
+
+	subscription := &models.Subscription{
+		ID:                     subscriptionID,
+		CustomerID:             customerID,
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusActive,
+		ProductName:            "Pro plan",
+	}
+
+	event := &providers.WebhookEvent{
+		Type:         "invoice.paid",
+		ResourceType: "invoice",
+		ResourceID:   "in_test123",
+		Invoice: &providers.WebhookInvoice{
+			ProviderInvoiceID:      "in_test123",
+			ProviderSubscriptionID: "sub_test123",
+			ProviderPaymentID:      "pi_test123",
+			BillingReason:          "subscription_cycle",
+			Invoice: &models.Invoice{
+				Provider:          models.ProviderStripe,
+				ProviderInvoiceID: "in_test123",
+				Status:            models.InvoiceStatusPaid,
+				Currency:          models.CurrencySEK,
+				Subtotal:          9900,
+				Total:             9900,
+				AmountPaid:        9900,
+				PaidAt:            &paidAt,
+			},
+		},
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByProviderSubscriptionID", ctx, "sub_test123").Return(subscription, nil)
+	mockInvoiceRepo.On("GetByProviderInvoiceID", ctx, models.ProviderStripe, "in_test123").Return(nil, nil)
+	mockPaymentRepo.On("GetByProviderPaymentID", ctx, models.ProviderStripe, "pi_test123").Return(nil, nil)
+	mockPaymentRepo.On("Create", ctx, mock.MatchedBy(func(payment *models.Payment) bool {
+		return payment.Amount == 9900 && payment.Status == models.PaymentStatusSucceeded &&
+			*payment.SubscriptionID == subscriptionID && *payment.InvoiceID == "in_test123"
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Payment).ID = paymentID
+	}).Return(nil)
+	mockInvoiceRepo.On("Create", ctx, mock.MatchedBy(func(invoice *models.Invoice) bool {
+		return invoice.CustomerID == customerID && *invoice.SubscriptionID == subscriptionID &&
+			*invoice.PaymentID == paymentID
+	})).Return(nil)
+	mockSubscriptionRepo.On("Update", ctx, subscription).Return(nil)
+	mockLedgerRepo.On("CreateEntry", ctx, mock.MatchedBy(func(entry *models.LedgerEntry) bool {
+		return entry.Type == models.LedgerEntryCharge && entry.ReferenceID == paymentID
+	})).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("GetPaymentBalanceTransaction", ctx, "pi_test123").Return(nil, nil)
+
+	// Execute
+	err := service.processInvoiceEvent(ctx, event)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, paymentID, *subscription.LatestPaymentID)
+
+	mockPaymentRepo.AssertExpectations(t)
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockInvoiceRepo.AssertExpectations(t)
+	mockLedgerRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// LedgerConfig controls which tenant's books entries are recorded in
+type LedgerConfig struct {
+	Tenant string
+}
+
+type LedgerService struct {
+	ledgerRepo repository.LedgerRepositoryInterface
+	config     LedgerConfig
+}
+
+func NewLedgerService(ledgerRepo repository.LedgerRepositoryInterface, config LedgerConfig) *LedgerService {
+	return &LedgerService{
+		ledgerRepo: ledgerRepo,
+		config:     config,
+	}
+}
+
+// Record appends a balanced entry to the ledger. Recording the same money
+// movement twice is a no-op, so callers may retry freely.
+func (s *LedgerService) Record(ctx context.Context, entry *models.LedgerEntry) error {
+	if entry.Tenant == "" {
+		entry.Tenant = s.config.Tenant
+	}
+
+	// Zero postings carry no information
+	postings := entry.Postings[:0]
+	var sum int64
+	for _, posting := range entry.Postings {
+		if posting.AccountCode.Type() == "" {
+			return fmt.Errorf("unknown ledger account %s", posting.AccountCode)
+		}
+		if posting.Amount != 0 {
+			postings = append(postings, posting)
+			sum += posting.Amount
+		}
+	}
+	entry.Postings = postings
+
+	if sum != 0 {
+		return fmt.Errorf("ledger entry %s for %s %s is not balanced: %d", entry.Type, entry.ReferenceType, entry.ReferenceID, sum)
+	}
+	if len(entry.Postings) == 0 {
+		return nil
+	}
+
+	if _, err := s.ledgerRepo.CreateEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RecordCharge records the funds from a succeeded payment and any store
+// credit used towards it, splitting out the tax we owe
+func (s *LedgerService) RecordCharge(ctx context.Context, payment *models.Payment) error {
+	return s.Record(ctx, &models.LedgerEntry{
+		Type:          models.LedgerEntryCharge,
+		Currency:      payment.Currency,
+		CustomerID:    &payment.CustomerID,
+		ReferenceType: "payment",
+		ReferenceID:   payment.ID,
+		Description:   payment.Description,
+		Postings: []models.LedgerPosting{
+			{AccountCode: models.LedgerAccountProviderBalance, Amount: payment.ChargedAmount().Amount},
+			{AccountCode: models.LedgerAccountCustomerCredit, Amount: payment.CreditApplied},
+			{AccountCode: models.LedgerAccountRevenue, Amount: -(payment.Amount - payment.TaxAmount)},
+			{AccountCode: models.LedgerAccountTaxPayable, Amount: -payment.TaxAmount},
+		},
+	})
+}
+
+// RecordRefund records the funds returned by a succeeded refund
+func (s *LedgerService) RecordRefund(ctx context.Context, refund *models.Refund, payment *models.Payment) error {
+	if err := checkRefundCurrency(refund, payment); err != nil {
+		return err
+	}
+
+	return s.Record(ctx, &models.LedgerEntry{
+		Type:          models.LedgerEntryRefund,
+		Currency:      refund.Currency,
+		CustomerID:    &payment.CustomerID,
+		ReferenceType: "refund",
+		ReferenceID:   refund.ID,
+		Description:   refund.Reason,
+		Postings: []models.LedgerPosting{
+			{AccountCode: models.LedgerAccountRefunds, Amount: refund.Amount},
+			{AccountCode: models.LedgerAccountProviderBalance, Amount: -refund.Amount},
+		},
+	})
+}
+
+// RecordCreditIssued records a refund paid out as store credit
+func (s *LedgerService) RecordCreditIssued(ctx context.Context, refund *models.Refund, payment *models.Payment) error {
+	if err := checkRefundCurrency(refund, payment); err != nil {
+		return err
+	}
+
+	return s.Record(ctx, &models.LedgerEntry{
+		Type:          models.LedgerEntryCreditIssued,
+		Currency:      refund.Currency,
+		CustomerID:    &payment.CustomerID,
+		ReferenceType: "refund",
+		ReferenceID:   refund.ID,
+		Description:   refund.Reason,
+		Postings: []models.LedgerPosting{
+			{AccountCode: models.LedgerAccountRefunds, Amount: refund.Amount},
+			{AccountCode: models.LedgerAccountCustomerCredit, Amount: -refund.Amount},
+		},
+	})
+}
+
+// RecordInvoiceCredit records store credit taken off a provider invoice. The
+// invoice's charge only covers what was left to pay.
+func (s *LedgerService) RecordInvoiceCredit(ctx context.Context, invoice *models.Invoice, amount int64) error {
+	return s.Record(ctx, &models.LedgerEntry{
+		Type:          models.LedgerEntryCreditApplied,
+		Currency:      invoice.Currency,
+		CustomerID:    &invoice.CustomerID,
+		ReferenceType: "invoice",
+		ReferenceID:   invoice.ID,
+		Postings: []models.LedgerPosting{
+			{AccountCode: models.LedgerAccountCustomerCredit, Amount: amount},
+			{AccountCode: models.LedgerAccountRevenue, Amount: -amount},
+		},
+	})
+}
+
+// RecordWalletTransaction records a change to a prepaid wallet. Top-ups were
+// already booked as revenue by their charge and are moved to the wallet until
+// spent; grants and expiries go through promotions.
+func (s *LedgerService) RecordWalletTransaction(ctx context.Context, wallet *models.Wallet, txn *models.WalletTransaction) error {
+	entryType := models.LedgerEntryWalletDebit
+	account := models.LedgerAccountRevenue
+	switch txn.Type {
+	case models.WalletTransactionTopUp:
+		entryType = models.LedgerEntryWalletTopUp
+	case models.WalletTransactionGrant:
+		entryType = models.LedgerEntryWalletGrant
+		account = models.LedgerAccountPromotions
+	case models.WalletTransactionExpiry:
+		entryType = models.LedgerEntryWalletExpiry
+		account = models.LedgerAccountPromotions
+	}
+
+	return s.Record(ctx, &models.LedgerEntry{
+		Type:          entryType,
+		Currency:      wallet.Currency,
+		CustomerID:    &wallet.CustomerID,
+		ReferenceType: "wallet_transaction",
+		ReferenceID:   txn.ID,
+		Description:   txn.Description,
+		Postings: []models.LedgerPosting{
+			{AccountCode: account, Amount: txn.Amount},
+			{AccountCode: models.LedgerAccountWallet, Amount: -txn.Amount},
+		},
+	})
+}
+
+// RecordFee records the provider's processing fee for a payment or refund in
+// the currency it was settled in
+func (s *LedgerService) RecordFee(
+	ctx context.Context,
+	customerID *uuid.UUID,
+	referenceType string,
+	referenceID uuid.UUID,
+	currency models.Currency,
+	fee int64,
+) error {
+	return s.Record(ctx, &models.LedgerEntry{
+		Type:          models.LedgerEntryFee,
+		Currency:      currency,
+		CustomerID:    customerID,
+		ReferenceType: referenceType,
+		ReferenceID:   referenceID,
+		Postings: []models.LedgerPosting{
+			{AccountCode: models.LedgerAccountProviderFees, Amount: fee},
+			{AccountCode: models.LedgerAccountProviderBalance, Amount: -fee},
+		},
+	})
+}
+
+// RecordPayout records funds leaving the provider balance for our bank
+func (s *LedgerService) RecordPayout(ctx context.Context, payout *models.Payout) error {
+	return s.Record(ctx, &models.LedgerEntry{
+		Type:          models.LedgerEntryPayout,
+		Currency:      payout.Currency,
+		ReferenceType: "payout",
+		ReferenceID:   payout.ID,
+		Description:   payout.Description,
+		Postings: []models.LedgerPosting{
+			{AccountCode: models.LedgerAccountBank, Amount: payout.Amount},
+			{AccountCode: models.LedgerAccountProviderBalance, Amount: -payout.Amount},
+		},
+	})
+}
+
+// RecordDispute records disputed funds withdrawn from the provider balance
+func (s *LedgerService) RecordDispute(ctx context.Context, dispute *models.Dispute) error {
+	return s.Record(ctx, &models.LedgerEntry{
+		Type:          models.LedgerEntryDispute,
+		Currency:      dispute.Currency,
+		CustomerID:    &dispute.CustomerID,
+		ReferenceType: "dispute",
+		ReferenceID:   dispute.ID,
+		Postings: []models.LedgerPosting{
+			{AccountCode: models.LedgerAccountDisputes, Amount: dispute.Amount},
+			{AccountCode: models.LedgerAccountProviderBalance, Amount: -dispute.Amount},
+		},
+	})
+}
+
+// RecordDisputeReversal records disputed funds returned after a won dispute
+func (s *LedgerService) RecordDisputeReversal(ctx context.Context, dispute *models.Dispute) error {
+	return s.Record(ctx, &models.LedgerEntry{
+		Type:          models.LedgerEntryDisputeReversal,
+		Currency:      dispute.Currency,
+		CustomerID:    &dispute.CustomerID,
+		ReferenceType: "dispute",
+		ReferenceID:   dispute.ID,
+		Postings: []models.LedgerPosting{
+			{AccountCode: models.LedgerAccountProviderBalance, Amount: dispute.Amount},
+			{AccountCode: models.LedgerAccountDisputes, Amount: -dispute.Amount},
+		},
+	})
+}
+
+// GetBalances returns account balances, defaulting to this tenant's books
+func (s *LedgerService) GetBalances(ctx context.Context, filter models.LedgerBalanceFilter) ([]models.LedgerBalance, error) {
+	if filter.Tenant == "" {
+		filter.Tenant = s.config.Tenant
+	}
+
+	balances, err := s.ledgerRepo.ListBalances(ctx, filter)
+	if err != nil {
+		log.Printf("Failed to list ledger balances: %v", err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve ledger balances",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return balances, nil
+}
+
+// checkRefundCurrency refuses to book a refund in another currency than the
+// payment it returns, which would leave both currencies' accounts wrong
+func checkRefundCurrency(refund *models.Refund, payment *models.Payment) error {
+	if _, err := payment.Money().Sub(refund.Money()); err != nil {
+		return fmt.Errorf("refund %s of payment %s: %w", refund.ID, payment.ID, err)
+	}
+	return nil
+}
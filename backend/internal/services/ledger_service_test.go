@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"payment-service/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLedgerRepository is a mock for LedgerRepository
+type MockLedgerRepository struct {
+	mock.Mock
+}
+
+func (m *MockLedgerRepository) CreateEntry(ctx context.Context, entry *models.LedgerEntry) (bool, error) {
+	args := m.Called(ctx, entry)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockLedgerRepository) ListBalances(ctx context.Context, filter models.LedgerBalanceFilter) ([]models.LedgerBalance, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.LedgerBalance), args.Error(1)
+}
+
+func TestLedgerService_RecordCharge_SplitsTax(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockLedgerRepo := new(MockLedgerRepository)
+	service := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+
+	payment := &models.Payment{
+		ID:         uuid.New(),
+		CustomerID: uuid.New(),
+		Amount:     12500,
+		TaxAmount:  2500,
+		Currency:   models.CurrencySEK,
+	}
+
+	var recorded *models.LedgerEntry
+	mockLedgerRepo.On("CreateEntry", ctx, mock.AnythingOfType("*models.LedgerEntry")).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(*models.LedgerEntry)
+	}).Return(true, nil)
+
+	// Execute
+	err := service.RecordCharge(ctx, payment)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "test", recorded.Tenant)
+	assert.Equal(t, models.LedgerEntryCharge, recorded.Type)
+	assert.Equal(t, []models.LedgerPosting{
+		{AccountCode: models.LedgerAccountProviderBalance, Amount: 12500},
+		{AccountCode: models.LedgerAccountRevenue, Amount: -10000},
+		{AccountCode: models.LedgerAccountTaxPayable, Amount: -2500},
+	}, recorded.Postings)
+
+	mockLedgerRepo.AssertExpectations(t)
+}
+
+func TestLedgerService_RecordCharge_DropsZeroPostings(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockLedgerRepo := new(MockLedgerRepository)
+	service := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+
+	payment := &models.Payment{
+		ID:         uuid.New(),
+		CustomerID: uuid.New(),
+		Amount:     10000,
+		Currency:   models.CurrencyUSD,
+	}
+
+	mockLedgerRepo.On("CreateEntry", ctx, mock.MatchedBy(func(entry *models.LedgerEntry) bool {
+		return len(entry.Postings) == 2
+	})).Return(true, nil)
+
+	// Execute
+	err := service.RecordCharge(ctx, payment)
+
+	// Assert
+	assert.NoError(t, err)
+	mockLedgerRepo.AssertExpectations(t)
+}
+
+func TestLedgerService_Record_Unbalanced(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockLedgerRepo := new(MockLedgerRepository)
+	service := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+
+	entry := &models.LedgerEntry{
+		Type:          models.LedgerEntryFee,
+		Currency:      models.CurrencySEK,
+		ReferenceType: "payment",
+		ReferenceID:   uuid.New(),
+		Postings: []models.LedgerPosting{
+			{AccountCode: models.LedgerAccountProviderFees, Amount: 300},
+			{AccountCode: models.LedgerAccountProviderBalance, Amount: -250},
+		},
+	}
+
+	// Execute
+	err := service.Record(ctx, entry)
+
+	// Assert
+	assert.Error(t, err)
+	mockLedgerRepo.AssertNotCalled(t, "CreateEntry", mock.Anything, mock.Anything)
+}
+
+func TestLedgerService_RecordRefund_CurrencyMismatch(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockLedgerRepo := new(MockLedgerRepository)
+	service := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+
+	payment := &models.Payment{ID: uuid.New(), CustomerID: uuid.New(), Amount: 10000, Currency: models.CurrencySEK}
+	refund := &models.Refund{ID: uuid.New(), PaymentID: payment.ID, Amount: 4000, Currency: models.CurrencyEUR}
+
+	// Execute
+	err := service.RecordRefund(ctx, refund, payment)
+
+	// Assert
+	assert.ErrorIs(t, err, models.ErrCurrencyMismatch)
+	mockLedgerRepo.AssertNotCalled(t, "CreateEntry", mock.Anything, mock.Anything)
+}
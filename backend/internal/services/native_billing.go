@@ -0,0 +1,587 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NativeBillingConfig controls subscriptions billed by us rather than by the
+// provider
+type NativeBillingConfig struct {
+	// Providers only take one-off payments; their subscriptions are renewed
+	// and charged by the billing job
+	Providers []models.Provider
+	// PaymentTimeout is how long a payment may wait on the customer, e.g. to
+	// approve a payment request, before it is canceled and counts as declined
+	PaymentTimeout time.Duration
+}
+
+// NativeBilling runs subscriptions for providers without a subscription API,
+// such as Swish. It wraps the provider factory: subscriptions on those
+// providers live only in our database, and each period's invoice is charged
+// through the provider's one-off payment flow. Everything else is passed
+// through to the provider.
+type NativeBilling struct {
+	subscriptionRepo  repository.SubscriptionRepositoryInterface
+	invoiceRepo       repository.InvoiceRepositoryInterface
+	paymentRepo       repository.PaymentRepositoryInterface
+	customerRepo      repository.CustomerRepositoryInterface
+	ledgerService     *LedgerService
+	settlementService *SettlementService
+	providerFactory   ProviderFactoryInterface
+	config            NativeBillingConfig
+}
+
+func NewNativeBilling(
+	subscriptionRepo repository.SubscriptionRepositoryInterface,
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	paymentRepo repository.PaymentRepositoryInterface,
+	customerRepo repository.CustomerRepositoryInterface,
+	ledgerService *LedgerService,
+	settlementService *SettlementService,
+	providerFactory ProviderFactoryInterface,
+	config NativeBillingConfig,
+) *NativeBilling {
+	return &NativeBilling{
+		subscriptionRepo:  subscriptionRepo,
+		invoiceRepo:       invoiceRepo,
+		paymentRepo:       paymentRepo,
+		customerRepo:      customerRepo,
+		ledgerService:     ledgerService,
+		settlementService: settlementService,
+		providerFactory:   providerFactory,
+		config:            config,
+	}
+}
+
+// GetProvider returns a provider by name. Subscriptions on natively billed
+// providers are handled by the billing engine.
+func (b *NativeBilling) GetProvider(provider models.Provider) (providers.PaymentProvider, error) {
+	paymentProvider, err := b.providerFactory.GetProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if !b.IsNative(provider) {
+		return paymentProvider, nil
+	}
+
+	return &nativeSubscriptionProvider{
+		PaymentProvider: paymentProvider,
+		billing:         b,
+		provider:        provider,
+	}, nil
+}
+
+// IsNative reports whether subscriptions on provider are billed by us
+func (b *NativeBilling) IsNative(provider models.Provider) bool {
+	return slices.Contains(b.config.Providers, provider)
+}
+
+// collect charges an open invoice of a natively billed subscription. It
+// returns nil once the invoice is paid, providers.ErrPaymentPending while a
+// payment waits on the customer and providers.ErrPaymentDeclined when the
+// latest attempt failed; the next call then starts a new attempt.
+func (b *NativeBilling) collect(ctx context.Context, subscription *models.Subscription, invoice *models.Invoice) error {
+	if invoice.Status == models.InvoiceStatusPaid {
+		return nil
+	}
+	if invoice.Status != models.InvoiceStatusOpen {
+		return fmt.Errorf("invoice %s is %s", invoice.ID, invoice.Status)
+	}
+
+	provider, err := b.providerFactory.GetProvider(subscription.Provider)
+	if err != nil {
+		return err
+	}
+
+	var previous *models.Payment
+	if invoice.PaymentID != nil {
+		payment, err := b.paymentRepo.GetByID(ctx, *invoice.PaymentID)
+		if err != nil {
+			return err
+		}
+
+		if payment != nil {
+			changed, err := b.refreshPayment(ctx, provider, payment)
+			if err != nil {
+				return err
+			}
+
+			switch payment.Status {
+			case models.PaymentStatusSucceeded:
+				return b.markPaid(ctx, subscription, invoice, payment)
+			case models.PaymentStatusFailed, models.PaymentStatusCanceled:
+				if changed {
+					return providers.ErrPaymentDeclined
+				}
+				previous = payment
+			default:
+				return providers.ErrPaymentPending
+			}
+		}
+	}
+
+	payment, err := b.charge(ctx, provider, subscription, invoice, previous)
+	if err != nil {
+		return err
+	}
+
+	switch payment.Status {
+	case models.PaymentStatusSucceeded:
+		return b.markPaid(ctx, subscription, invoice, payment)
+	case models.PaymentStatusFailed, models.PaymentStatusCanceled:
+		return providers.ErrPaymentDeclined
+	default:
+		return providers.ErrPaymentPending
+	}
+}
+
+// charge starts a new payment attempt for an invoice. The idempotency key
+// names the attempt it follows, so a retry after a crash finds the payment
+// the provider already created instead of charging twice.
+func (b *NativeBilling) charge(
+	ctx context.Context,
+	provider providers.PaymentProvider,
+	subscription *models.Subscription,
+	invoice *models.Invoice,
+	previous *models.Payment,
+) (*models.Payment, error) {
+	customer, err := b.customerRepo.GetByID(ctx, subscription.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	if customer == nil {
+		return nil, fmt.Errorf("customer %s not found", subscription.CustomerID)
+	}
+
+	var providerCustomerID string
+	if subscription.Provider == models.ProviderStripe && customer.StripeCustomerID != nil {
+		providerCustomerID = *customer.StripeCustomerID
+	} else if subscription.Provider == models.ProviderSwish && customer.SwishCustomerID != nil {
+		providerCustomerID = *customer.SwishCustomerID
+	} else {
+		return nil, fmt.Errorf("customer %s not configured for provider %s", customer.ID, subscription.Provider)
+	}
+
+	idempotencyKey := fmt.Sprintf("invoice_%s_first", invoice.ID)
+	if previous != nil {
+		idempotencyKey = fmt.Sprintf("invoice_%s_after_%s", invoice.ID, previous.ID)
+	}
+
+	description := fmt.Sprintf("%s (%s - %s)", subscription.ProductName,
+		invoice.PeriodStart.Format("2006-01-02"), invoice.PeriodEnd.Format("2006-01-02"))
+
+	providerPayment, err := provider.CreatePayment(ctx, &providers.CreatePaymentRequest{
+		CustomerID:     providerCustomerID,
+		Amount:         invoice.AmountDue,
+		Currency:       string(invoice.Currency),
+		Description:    description,
+		Metadata:       map[string]string{"invoice_id": invoice.ProviderInvoiceID},
+		IdempotencyKey: idempotencyKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment with provider: %w", err)
+	}
+
+	payment, err := b.paymentRepo.GetByProviderPaymentID(ctx, providerPayment.Provider, providerPayment.ProviderPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		payment = providerPayment
+		payment.CustomerID = subscription.CustomerID
+		payment.SubscriptionID = &subscription.ID
+		payment.InvoiceID = &invoice.ProviderInvoiceID
+		payment.Description = &description
+		payment.TaxAmount = invoice.Tax
+		payment.TaxBreakdown = invoice.TaxBreakdown
+		payment.IdempotencyKey = &idempotencyKey
+
+		if err := b.paymentRepo.Create(ctx, payment); err != nil {
+			return nil, err
+		}
+	}
+
+	invoice.PaymentID = &payment.ID
+	if err := b.invoiceRepo.Update(ctx, invoice); err != nil {
+		return nil, err
+	}
+	subscription.LatestPaymentID = &payment.ID
+
+	return payment, nil
+}
+
+// refreshPayment updates a payment from the provider, canceling it if it has
+// waited on the customer longer than the payment timeout. Reports whether
+// its status changed.
+func (b *NativeBilling) refreshPayment(ctx context.Context, provider providers.PaymentProvider, payment *models.Payment) (bool, error) {
+	if payment.Status == models.PaymentStatusSucceeded ||
+		payment.Status == models.PaymentStatusFailed ||
+		payment.Status == models.PaymentStatusCanceled {
+		return false, nil
+	}
+
+	remote, err := provider.GetPayment(ctx, payment.ProviderPaymentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get payment from provider: %w", err)
+	}
+
+	pending := remote.Status != models.PaymentStatusSucceeded &&
+		remote.Status != models.PaymentStatusFailed &&
+		remote.Status != models.PaymentStatusCanceled
+	if pending && b.config.PaymentTimeout > 0 && time.Since(payment.CreatedAt) > b.config.PaymentTimeout {
+		remote, err = provider.CancelPayment(ctx, payment.ProviderPaymentID)
+		if err != nil {
+			return false, fmt.Errorf("failed to cancel expired payment: %w", err)
+		}
+	}
+
+	if remote.Status == payment.Status {
+		return false, nil
+	}
+
+	payment.Status = remote.Status
+	payment.FailureCode = remote.FailureCode
+	payment.FailureMessage = remote.FailureMessage
+	payment.CompletedAt = remote.CompletedAt
+	if payment.Status == models.PaymentStatusSucceeded && payment.CompletedAt == nil {
+		now := time.Now()
+		payment.CompletedAt = &now
+	}
+
+	if err := b.paymentRepo.Update(ctx, payment); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// markPaid records a succeeded payment and closes its invoice
+func (b *NativeBilling) markPaid(
+	ctx context.Context,
+	subscription *models.Subscription,
+	invoice *models.Invoice,
+	payment *models.Payment,
+) error {
+	if err := b.ledgerService.RecordCharge(ctx, payment); err != nil {
+		return fmt.Errorf("failed to record charge in ledger: %w", err)
+	}
+	if err := b.settlementService.SyncPayment(ctx, payment); err != nil {
+		log.Printf("Failed to sync settlement for payment %s: %v", payment.ID, err)
+	}
+
+	paidAt := time.Now()
+	if payment.CompletedAt != nil {
+		paidAt = *payment.CompletedAt
+	}
+	invoice.Status = models.InvoiceStatusPaid
+	invoice.AmountPaid = invoice.AmountDue
+	invoice.PaidAt = &paidAt
+	if err := b.invoiceRepo.Update(ctx, invoice); err != nil {
+		return err
+	}
+
+	subscription.LatestPaymentID = &payment.ID
+	return nil
+}
+
+// getSubscription returns a natively billed subscription
+func (b *NativeBilling) getSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	subscription, err := b.subscriptionRepo.GetByProviderSubscriptionID(ctx, providerSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, fmt.Errorf("subscription %s not found", providerSubscriptionID)
+	}
+	return subscription, nil
+}
+
+// nativeSubscriptionProvider is a provider whose subscriptions are billed by
+// us. Subscription calls return the subscription as it should be stored; the
+// caller saves it as it would a provider's.
+type nativeSubscriptionProvider struct {
+	providers.PaymentProvider
+	billing  *NativeBilling
+	provider models.Provider
+}
+
+// CreateSubscription starts a subscription, incomplete until the billing job
+// has collected the first period's payment
+func (p *nativeSubscriptionProvider) CreateSubscription(ctx context.Context, req *providers.CreateSubscriptionRequest) (*models.Subscription, error) {
+	if req.CouponID != "" {
+		return nil, providers.ErrNotSupported
+	}
+
+	now := time.Now()
+	subscription := &models.Subscription{
+		Provider:               p.provider,
+		ProviderSubscriptionID: "native_sub_" + uuid.New().String(),
+		Currency:               models.Currency(req.Currency),
+		Interval:               req.Interval,
+		IntervalCount:          req.IntervalCount,
+		Status:                 models.SubscriptionStatusIncomplete,
+		CurrentPeriodStart:     now,
+		CurrentPeriodEnd:       models.AddInterval(now, req.Interval, req.IntervalCount),
+		NextBillingAt:          &now,
+	}
+	for _, item := range req.Items {
+		subscription.Items = append(subscription.Items, nativeItem(item.ProductName, item.UnitAmount, item.Quantity))
+	}
+	subscription.Amount = models.ItemsAmount(subscription.Items)
+
+	// The first charge waits for the trial to end
+	if req.TrialPeriodDays > 0 {
+		trialEnd := now.AddDate(0, 0, req.TrialPeriodDays)
+		subscription.Status = models.SubscriptionStatusTrialing
+		subscription.TrialStart = &now
+		subscription.TrialEnd = &trialEnd
+		subscription.CurrentPeriodEnd = trialEnd
+		subscription.NextBillingAt = &trialEnd
+	}
+
+	return subscription, nil
+}
+
+// GetSubscription returns the stored subscription
+func (p *nativeSubscriptionProvider) GetSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	return p.billing.getSubscription(ctx, providerSubscriptionID)
+}
+
+// UpdateSubscription schedules or clears a cancellation, or moves the end of
+// a trial
+func (p *nativeSubscriptionProvider) UpdateSubscription(
+	ctx context.Context,
+	providerSubscriptionID string,
+	req *providers.UpdateSubscriptionRequest,
+) (*models.Subscription, error) {
+	subscription, err := p.billing.getSubscription(ctx, providerSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.CancelAtPeriodEnd != nil {
+		subscription.CancelAtPeriodEnd = *req.CancelAtPeriodEnd
+	}
+	if req.TrialEnd != nil {
+		if subscription.Status != models.SubscriptionStatusTrialing {
+			return nil, providers.ErrNotSupported
+		}
+		trialEnd := *req.TrialEnd
+		subscription.TrialEnd = &trialEnd
+		subscription.CurrentPeriodEnd = trialEnd
+		subscription.NextBillingAt = &trialEnd
+	}
+
+	return subscription, nil
+}
+
+// CancelSubscription cancels a subscription now or at the end of its period
+func (p *nativeSubscriptionProvider) CancelSubscription(ctx context.Context, providerSubscriptionID string, immediate bool) (*models.Subscription, error) {
+	subscription, err := p.billing.getSubscription(ctx, providerSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if immediate {
+		now := time.Now()
+		subscription.Status = models.SubscriptionStatusCanceled
+		subscription.CanceledAt = &now
+		subscription.CancelAtPeriodEnd = false
+	} else {
+		subscription.CancelAtPeriodEnd = true
+	}
+
+	return subscription, nil
+}
+
+// ReactivateSubscription clears a cancellation scheduled for the period end
+func (p *nativeSubscriptionProvider) ReactivateSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	subscription, err := p.billing.getSubscription(ctx, providerSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.CancelAt = nil
+	subscription.CancelAtPeriodEnd = false
+	subscription.CanceledAt = nil
+
+	return subscription, nil
+}
+
+func (p *nativeSubscriptionProvider) PauseSubscription(ctx context.Context, providerSubscriptionID string, req *providers.PauseSubscriptionRequest) (*models.Subscription, error) {
+	return nil, providers.ErrNotSupported
+}
+
+func (p *nativeSubscriptionProvider) ResumeSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	return nil, providers.ErrNotSupported
+}
+
+// AddSubscriptionItem adds an item billed from the next period. Prorated
+// changes are not supported.
+func (p *nativeSubscriptionProvider) AddSubscriptionItem(
+	ctx context.Context,
+	providerSubscriptionID string,
+	req *providers.AddSubscriptionItemRequest,
+) (*models.SubscriptionItem, error) {
+	if req.ProrationBehavior != string(models.ProrationBehaviorNone) {
+		return nil, providers.ErrNotSupported
+	}
+
+	item := nativeItem(req.ProductName, req.UnitAmount, req.Quantity)
+	return &item, nil
+}
+
+// UpdateSubscriptionItem changes a quantity from the next period. Prorated
+// changes are not supported.
+func (p *nativeSubscriptionProvider) UpdateSubscriptionItem(
+	ctx context.Context,
+	providerSubscriptionID, providerItemID string,
+	req *providers.UpdateSubscriptionItemRequest,
+) (*models.SubscriptionItem, error) {
+	if req.ProrationBehavior != string(models.ProrationBehaviorNone) {
+		return nil, providers.ErrNotSupported
+	}
+
+	return &models.SubscriptionItem{
+		Quantity:       req.Quantity,
+		ProviderItemID: &providerItemID,
+	}, nil
+}
+
+// DeleteSubscriptionItem removes an item from the next period. Prorated
+// changes are not supported.
+func (p *nativeSubscriptionProvider) DeleteSubscriptionItem(ctx context.Context, providerSubscriptionID, providerItemID string, prorationBehavior string) error {
+	if prorationBehavior != string(models.ProrationBehaviorNone) {
+		return providers.ErrNotSupported
+	}
+	return nil
+}
+
+// CreateSubscriptionSchedule is not supported; schedules on natively billed
+// subscriptions are applied with ChangeSubscriptionPlan
+func (p *nativeSubscriptionProvider) CreateSubscriptionSchedule(ctx context.Context, req *providers.CreateSubscriptionScheduleRequest) (string, error) {
+	return "", providers.ErrNotSupported
+}
+
+func (p *nativeSubscriptionProvider) GetSubscriptionSchedule(ctx context.Context, providerScheduleID string) (*providers.SubscriptionSchedule, error) {
+	return nil, providers.ErrNotSupported
+}
+
+func (p *nativeSubscriptionProvider) CancelSubscriptionSchedule(ctx context.Context, providerScheduleID string) error {
+	return providers.ErrNotSupported
+}
+
+func (p *nativeSubscriptionProvider) ReleaseSubscriptionSchedule(ctx context.Context, providerScheduleID string) error {
+	return providers.ErrNotSupported
+}
+
+// ChangeSubscriptionPlan replaces the items. A new interval starts a new
+// period, billed on the next billing run.
+func (p *nativeSubscriptionProvider) ChangeSubscriptionPlan(
+	ctx context.Context,
+	providerSubscriptionID string,
+	req *providers.ChangeSubscriptionPlanRequest,
+) (*models.Subscription, error) {
+	subscription, err := p.billing.getSubscription(ctx, providerSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.SubscriptionItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, nativeItem(item.ProductName, item.UnitAmount, item.Quantity))
+	}
+	subscription.Items = items
+	subscription.Amount = models.ItemsAmount(items)
+
+	intervalChanged := req.Interval != subscription.Interval || req.IntervalCount != subscription.IntervalCount
+	subscription.Interval = req.Interval
+	subscription.IntervalCount = req.IntervalCount
+
+	if intervalChanged && subscription.Status != models.SubscriptionStatusTrialing &&
+		subscription.Status != models.SubscriptionStatusCanceled {
+		now := time.Now()
+		subscription.CurrentPeriodStart = now
+		subscription.CurrentPeriodEnd = models.AddInterval(now, req.Interval, req.IntervalCount)
+		subscription.NextBillingAt = &now
+	}
+
+	return subscription, nil
+}
+
+// ReportUsage is not supported; metered prices on natively billed
+// subscriptions are invoiced locally
+func (p *nativeSubscriptionProvider) ReportUsage(ctx context.Context, req *providers.ReportUsageRequest) error {
+	return providers.ErrNotSupported
+}
+
+// PayInvoice charges an invoice the billing job created, e.g. when dunning
+// retries it
+func (p *nativeSubscriptionProvider) PayInvoice(ctx context.Context, providerInvoiceID string) error {
+	invoice, err := p.billing.invoiceRepo.GetByProviderInvoiceID(ctx, p.provider, providerInvoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice == nil {
+		return fmt.Errorf("invoice %s not found", providerInvoiceID)
+	}
+	if invoice.SubscriptionID == nil {
+		return fmt.Errorf("invoice %s has no subscription", providerInvoiceID)
+	}
+
+	subscription, err := p.billing.subscriptionRepo.GetByID(ctx, *invoice.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	if subscription == nil {
+		return fmt.Errorf("subscription %s not found", *invoice.SubscriptionID)
+	}
+
+	latestPaymentID := subscription.LatestPaymentID
+	payErr := p.billing.collect(ctx, subscription, invoice)
+	if payErr != nil && !errors.Is(payErr, providers.ErrPaymentPending) && !errors.Is(payErr, providers.ErrPaymentDeclined) {
+		return payErr
+	}
+
+	if subscription.LatestPaymentID != latestPaymentID {
+		if err := p.billing.subscriptionRepo.Update(ctx, subscription); err != nil {
+			return fmt.Errorf("failed to update subscription: %w", err)
+		}
+	}
+
+	return payErr
+}
+
+// CreateInvoiceCredit is not supported; natively billed invoices are charged
+// in full
+func (p *nativeSubscriptionProvider) CreateInvoiceCredit(ctx context.Context, req *providers.InvoiceCreditRequest) error {
+	return providers.ErrNotSupported
+}
+
+// ListSubscriptions lists nothing, since we hold the only copy of natively
+// billed subscriptions and there is nothing to reconcile
+func (p *nativeSubscriptionProvider) ListSubscriptions(ctx context.Context, params *providers.ListParams) (*providers.ListPage, error) {
+	return &providers.ListPage{}, nil
+}
+
+// nativeItem returns a subscription item with a locally assigned item ID
+func nativeItem(productName string, unitAmount, quantity int64) models.SubscriptionItem {
+	providerItemID := "native_si_" + uuid.New().String()
+	return models.SubscriptionItem{
+		ProductName:    productName,
+		UnitAmount:     unitAmount,
+		Quantity:       quantity,
+		ProviderItemID: &providerItemID,
+	}
+}
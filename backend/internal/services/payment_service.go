@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"payment-service/internal/models"
 	"payment-service/internal/providers"
@@ -12,9 +13,14 @@ import (
 )
 
 type PaymentService struct {
-	paymentRepo  repository.PaymentRepositoryInterface
-	customerRepo repository.CustomerRepositoryInterface
-	providerFactory ProviderFactoryInterface
+	paymentRepo       repository.PaymentRepositoryInterface
+	customerRepo      repository.CustomerRepositoryInterface
+	couponService     *CouponService
+	taxService        *TaxService
+	ledgerService     *LedgerService
+	settlementService *SettlementService
+	balanceService    *CustomerBalanceService
+	providerFactory   ProviderFactoryInterface
 }
 
 // ProviderFactoryInterface defines the interface for provider factory
@@ -25,12 +31,22 @@ type ProviderFactoryInterface interface {
 func NewPaymentService(
 	paymentRepo repository.PaymentRepositoryInterface,
 	customerRepo repository.CustomerRepositoryInterface,
+	couponService *CouponService,
+	taxService *TaxService,
+	ledgerService *LedgerService,
+	settlementService *SettlementService,
+	balanceService *CustomerBalanceService,
 	providerFactory ProviderFactoryInterface,
 ) *PaymentService {
 	return &PaymentService{
-		paymentRepo:     paymentRepo,
-		customerRepo:    customerRepo,
-		providerFactory: providerFactory,
+		paymentRepo:       paymentRepo,
+		customerRepo:      customerRepo,
+		couponService:     couponService,
+		taxService:        taxService,
+		ledgerService:     ledgerService,
+		settlementService: settlementService,
+		balanceService:    balanceService,
+		providerFactory:   providerFactory,
 	}
 }
 
@@ -75,30 +91,131 @@ func (s *PaymentService) CreatePayment(
 		)
 	}
 
-	// Create payment with provider
-	providerReq := &providers.CreatePaymentRequest{
-		CustomerID:          providerCustomerID,
-		Amount:              req.Amount,
-		Currency:            string(req.Currency),
-		Description:         req.Description,
-		StatementDescriptor: req.StatementDescriptor,
-		Metadata:            convertMetadataToStrings(req.Metadata),
+	// Apply promotion code
+	amount := models.NewMoney(req.Amount, req.Currency)
+	var discount *models.AppliedDiscount
+	if req.PromotionCode != "" {
+		discount, err = s.couponService.ReserveDiscount(ctx, req.PromotionCode, customer, req.Amount, req.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		if discount.Amount >= req.Amount {
+			s.couponService.ReleaseDiscount(ctx, discount)
+			return nil, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Discount cannot cover the full payment amount",
+				http.StatusBadRequest,
+			)
+		}
+		amount, err = amount.Sub(discount.Money())
+		if err != nil {
+			s.couponService.ReleaseDiscount(ctx, discount)
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to apply promotion code",
+				http.StatusInternalServerError,
+			)
+		}
 	}
 
-	providerPayment, err := provider.CreatePayment(ctx, providerReq)
+	country, vatID := customerTaxLocation(customer, req.BillingCountry, req.VATID)
+
+	// Calculate tax on the discounted price
+	tax, err := s.taxService.Calculate(&TaxRequest{
+		Amount:   amount.Amount,
+		Category: req.TaxCategory,
+		Behavior: req.TaxBehavior,
+		Country:  country,
+		VATID:    vatID,
+	})
 	if err != nil {
+		if discount != nil {
+			s.couponService.ReleaseDiscount(ctx, discount)
+		}
+		return nil, err
+	}
+	total := models.NewMoney(tax.Total, req.Currency)
+
+	// Use the customer's store credit first and charge the rest
+	credit, err := s.balanceService.ApplyToPayment(ctx, customer.ID, total)
+	if err != nil {
+		if discount != nil {
+			s.couponService.ReleaseDiscount(ctx, discount)
+		}
 		return nil, models.NewAPIError(
-			models.ErrCodePaymentFailed,
-			"Failed to create payment with provider",
-			http.StatusBadGateway,
+			models.ErrCodeProviderError,
+			"Failed to apply customer balance",
+			http.StatusInternalServerError,
 		)
 	}
+	amount, err = total.Add(credit.Money())
+	if err != nil {
+		if discount != nil {
+			s.couponService.ReleaseDiscount(ctx, discount)
+		}
+		s.balanceService.ReleaseCredit(ctx, credit)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to apply customer balance",
+			http.StatusInternalServerError,
+		)
+	}
+
+	var providerPayment *models.Payment
+	if amount.IsZero() {
+		// Paid in full with credit; nothing to charge
+		providerPayment = &models.Payment{
+			Provider: req.Provider,
+			Currency: req.Currency,
+			Status:   models.PaymentStatusSucceeded,
+		}
+		if req.Description != "" {
+			providerPayment.Description = &req.Description
+		}
+	} else {
+		// Create payment with provider
+		providerReq := &providers.CreatePaymentRequest{
+			CustomerID:          providerCustomerID,
+			Amount:              amount.Amount,
+			Currency:            string(amount.Currency),
+			Description:         req.Description,
+			StatementDescriptor: req.StatementDescriptor,
+			Metadata:            convertMetadataToStrings(req.Metadata),
+		}
+
+		providerPayment, err = provider.CreatePayment(ctx, providerReq)
+		if err != nil {
+			if discount != nil {
+				s.couponService.ReleaseDiscount(ctx, discount)
+			}
+			s.balanceService.ReleaseCredit(ctx, credit)
+			return nil, models.NewAPIError(
+				models.ErrCodePaymentFailed,
+				"Failed to create payment with provider",
+				http.StatusBadGateway,
+			)
+		}
+	}
 
-	// Save payment to database
+	// Save payment to database. The payment's amount is the total; the
+	// provider only charged what credit did not cover.
+	providerPayment.Amount = total.Amount
 	providerPayment.CustomerID = customer.ID
 	providerPayment.Metadata = req.Metadata
+	providerPayment.TaxAmount = tax.Tax
+	providerPayment.TaxBreakdown = tax.Breakdown
+	providerPayment.CreditApplied = -credit.Amount
+	if discount != nil {
+		providerPayment.DiscountAmount = discount.Amount
+		providerPayment.CouponID = &discount.Coupon.ID
+		providerPayment.PromotionCodeID = &discount.PromotionCode.ID
+	}
 
 	if err := s.paymentRepo.Create(ctx, providerPayment); err != nil {
+		if providerPayment.ProviderPaymentID == "" {
+			s.balanceService.ReleaseCredit(ctx, credit)
+		}
 		return nil, models.NewAPIError(
 			models.ErrCodeProviderError,
 			"Failed to save payment to database",
@@ -106,6 +223,21 @@ func (s *PaymentService) CreatePayment(
 		)
 	}
 
+	if discount != nil {
+		s.couponService.CompleteRedemption(ctx, discount, customer.ID, &providerPayment.ID, nil)
+	}
+	s.balanceService.CompletePaymentCredit(ctx, credit, providerPayment)
+
+	// Some providers settle immediately instead of through a webhook
+	if providerPayment.Status == models.PaymentStatusSucceeded {
+		if err := s.ledgerService.RecordCharge(ctx, providerPayment); err != nil {
+			log.Printf("Failed to record charge for payment %s in ledger: %v", providerPayment.ID, err)
+		}
+		if err := s.settlementService.SyncPayment(ctx, providerPayment); err != nil {
+			log.Printf("Failed to sync settlement for payment %s: %v", providerPayment.ID, err)
+		}
+	}
+
 	return providerPayment, nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"payment-service/internal/models"
 	"payment-service/internal/providers"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -48,6 +49,31 @@ func (m *MockPaymentRepository) Update(ctx context.Context, payment *models.Paym
 	return args.Error(0)
 }
 
+func (m *MockPaymentRepository) UpdateSettlement(ctx context.Context, payment *models.Payment) error {
+	args := m.Called(ctx, payment)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) ListProductMargins(ctx context.Context, from, to time.Time) ([]models.ProductMargin, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]models.ProductMargin), args.Error(1)
+}
+
+func (m *MockPaymentRepository) MarkDisputed(ctx context.Context, payment *models.Payment, disputedAt time.Time) error {
+	args := m.Called(ctx, payment, disputedAt)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) ReserveRefund(ctx context.Context, payment *models.Payment, amount models.Money) (bool, error) {
+	args := m.Called(ctx, payment, amount)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) ReleaseRefund(ctx context.Context, payment *models.Payment, amount models.Money) error {
+	args := m.Called(ctx, payment, amount)
+	return args.Error(0)
+}
+
 // MockCustomerRepository is a mock for CustomerRepository
 type MockCustomerRepository struct {
 	mock.Mock
@@ -79,6 +105,16 @@ func (m *MockCustomerRepository) Update(ctx context.Context, customer *models.Cu
 	return args.Error(0)
 }
 
+func (m *MockCustomerRepository) HasTransactions(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCustomerRepository) Erase(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 // MockPaymentProvider is a mock for PaymentProvider
 type MockPaymentProvider struct {
 	mock.Mock
@@ -97,6 +133,16 @@ func (m *MockPaymentProvider) CreateCustomer(ctx context.Context, req *providers
 	return args.Get(0).(*models.Customer), args.Error(1)
 }
 
+func (m *MockPaymentProvider) UpdateCustomer(ctx context.Context, providerCustomerID string, req *providers.UpdateCustomerRequest) error {
+	args := m.Called(ctx, providerCustomerID, req)
+	return args.Error(0)
+}
+
+func (m *MockPaymentProvider) DeleteCustomer(ctx context.Context, providerCustomerID string) error {
+	args := m.Called(ctx, providerCustomerID)
+	return args.Error(0)
+}
+
 func (m *MockPaymentProvider) GetCustomer(ctx context.Context, providerCustomerID string) (*models.Customer, error) {
 	args := m.Called(ctx, providerCustomerID)
 	if args.Get(0) == nil {
@@ -161,6 +207,97 @@ func (m *MockPaymentProvider) CancelSubscription(ctx context.Context, providerSu
 	return args.Get(0).(*models.Subscription), args.Error(1)
 }
 
+func (m *MockPaymentProvider) ReactivateSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	args := m.Called(ctx, providerSubscriptionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Subscription), args.Error(1)
+}
+
+func (m *MockPaymentProvider) PauseSubscription(ctx context.Context, providerSubscriptionID string, req *providers.PauseSubscriptionRequest) (*models.Subscription, error) {
+	args := m.Called(ctx, providerSubscriptionID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Subscription), args.Error(1)
+}
+
+func (m *MockPaymentProvider) ResumeSubscription(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	args := m.Called(ctx, providerSubscriptionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Subscription), args.Error(1)
+}
+
+func (m *MockPaymentProvider) AddSubscriptionItem(ctx context.Context, providerSubscriptionID string, req *providers.AddSubscriptionItemRequest) (*models.SubscriptionItem, error) {
+	args := m.Called(ctx, providerSubscriptionID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SubscriptionItem), args.Error(1)
+}
+
+func (m *MockPaymentProvider) UpdateSubscriptionItem(ctx context.Context, providerSubscriptionID, providerItemID string, req *providers.UpdateSubscriptionItemRequest) (*models.SubscriptionItem, error) {
+	args := m.Called(ctx, providerSubscriptionID, providerItemID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SubscriptionItem), args.Error(1)
+}
+
+func (m *MockPaymentProvider) DeleteSubscriptionItem(ctx context.Context, providerSubscriptionID, providerItemID string, prorationBehavior string) error {
+	args := m.Called(ctx, providerSubscriptionID, providerItemID, prorationBehavior)
+	return args.Error(0)
+}
+
+func (m *MockPaymentProvider) CreateSubscriptionSchedule(ctx context.Context, req *providers.CreateSubscriptionScheduleRequest) (string, error) {
+	args := m.Called(ctx, req)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPaymentProvider) GetSubscriptionSchedule(ctx context.Context, providerScheduleID string) (*providers.SubscriptionSchedule, error) {
+	args := m.Called(ctx, providerScheduleID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*providers.SubscriptionSchedule), args.Error(1)
+}
+
+func (m *MockPaymentProvider) CancelSubscriptionSchedule(ctx context.Context, providerScheduleID string) error {
+	args := m.Called(ctx, providerScheduleID)
+	return args.Error(0)
+}
+
+func (m *MockPaymentProvider) ReleaseSubscriptionSchedule(ctx context.Context, providerScheduleID string) error {
+	args := m.Called(ctx, providerScheduleID)
+	return args.Error(0)
+}
+
+func (m *MockPaymentProvider) ChangeSubscriptionPlan(ctx context.Context, providerSubscriptionID string, req *providers.ChangeSubscriptionPlanRequest) (*models.Subscription, error) {
+	args := m.Called(ctx, providerSubscriptionID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Subscription), args.Error(1)
+}
+
+func (m *MockPaymentProvider) ReportUsage(ctx context.Context, req *providers.ReportUsageRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockPaymentProvider) PayInvoice(ctx context.Context, providerInvoiceID string) error {
+	args := m.Called(ctx, providerInvoiceID)
+	return args.Error(0)
+}
+
+func (m *MockPaymentProvider) CreateInvoiceCredit(ctx context.Context, req *providers.InvoiceCreditRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
 func (m *MockPaymentProvider) CreateRefund(ctx context.Context, req *providers.CreateRefundRequest) (*models.Refund, error) {
 	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
@@ -177,6 +314,88 @@ func (m *MockPaymentProvider) GetRefund(ctx context.Context, providerRefundID st
 	return args.Get(0).(*models.Refund), args.Error(1)
 }
 
+func (m *MockPaymentProvider) GetPaymentBalanceTransaction(ctx context.Context, providerPaymentID string) (*providers.BalanceTransaction, error) {
+	args := m.Called(ctx, providerPaymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*providers.BalanceTransaction), args.Error(1)
+}
+
+func (m *MockPaymentProvider) GetRefundBalanceTransaction(ctx context.Context, providerRefundID string) (*providers.BalanceTransaction, error) {
+	args := m.Called(ctx, providerRefundID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*providers.BalanceTransaction), args.Error(1)
+}
+
+func (m *MockPaymentProvider) ListPayments(ctx context.Context, params *providers.ListParams) (*providers.ListPage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*providers.ListPage), args.Error(1)
+}
+
+func (m *MockPaymentProvider) ListSubscriptions(ctx context.Context, params *providers.ListParams) (*providers.ListPage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*providers.ListPage), args.Error(1)
+}
+
+func (m *MockPaymentProvider) ListRefunds(ctx context.Context, params *providers.ListParams) (*providers.ListPage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*providers.ListPage), args.Error(1)
+}
+
+func (m *MockPaymentProvider) ListPayouts(ctx context.Context, params *providers.ListParams) (*providers.ListPage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*providers.ListPage), args.Error(1)
+}
+
+func (m *MockPaymentProvider) GetPayout(ctx context.Context, providerPayoutID string) (*models.Payout, error) {
+	args := m.Called(ctx, providerPayoutID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Payout), args.Error(1)
+}
+
+func (m *MockPaymentProvider) ListPayoutTransactions(ctx context.Context, providerPayoutID string) ([]providers.BalanceTransaction, error) {
+	args := m.Called(ctx, providerPayoutID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]providers.BalanceTransaction), args.Error(1)
+}
+
+func (m *MockPaymentProvider) UpdateDisputeEvidence(ctx context.Context, providerDisputeID string, req *providers.DisputeEvidenceRequest) (*models.Dispute, error) {
+	args := m.Called(ctx, providerDisputeID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Dispute), args.Error(1)
+}
+
+func (m *MockPaymentProvider) CreateCoupon(ctx context.Context, req *providers.CreateCouponRequest) (string, error) {
+	args := m.Called(ctx, req)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPaymentProvider) DeleteCoupon(ctx context.Context, providerCouponID string) error {
+	args := m.Called(ctx, providerCouponID)
+	return args.Error(0)
+}
+
 func (m *MockPaymentProvider) VerifyWebhookSignature(payload []byte, signature string) error {
 	args := m.Called(payload, signature)
 	return args.Error(0)
@@ -203,8 +422,9 @@ func (m *MockProviderFactory) GetProvider(provider models.Provider) (providers.P
 	return args.Get(0).(providers.PaymentProvider), args.Error(1)
 }
 
-// Ensure Factory implements the interface
+// Ensure the mocks implement their interfaces
 var _ ProviderFactoryInterface = (*MockProviderFactory)(nil)
+var _ providers.PaymentProvider = (*MockPaymentProvider)(nil)
 
 func TestPaymentService_CreatePayment_Success(t *testing.T) {
 	// Setup
@@ -218,7 +438,11 @@ func TestPaymentService_CreatePayment_Success(t *testing.T) {
 	mockProvider := new(MockPaymentProvider)
 	mockFactory := new(MockProviderFactory)
 
-	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, mockFactory)
+	mockBalanceRepo := new(MockCustomerBalanceRepository)
+	balanceService := NewCustomerBalanceService(mockBalanceRepo, mockCustomerRepo, nil, mockFactory)
+	mockBalanceRepo.On("Debit", ctx, mock.AnythingOfType("*models.CustomerBalanceTransaction"), models.NewMoney(10000, models.CurrencySEK)).Return(nil)
+
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, nil, NewTaxService(TaxConfig{}), nil, nil, balanceService, mockFactory)
 
 	// Test data
 	req := &models.CreatePaymentRequest{
@@ -277,7 +501,11 @@ func TestPaymentService_CreatePayment_NewCustomer(t *testing.T) {
 	mockProvider := new(MockPaymentProvider)
 	mockFactory := new(MockProviderFactory)
 
-	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, mockFactory)
+	mockBalanceRepo := new(MockCustomerBalanceRepository)
+	balanceService := NewCustomerBalanceService(mockBalanceRepo, mockCustomerRepo, nil, mockFactory)
+	mockBalanceRepo.On("Debit", ctx, mock.AnythingOfType("*models.CustomerBalanceTransaction"), models.NewMoney(10000, models.CurrencySEK)).Return(nil)
+
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, nil, NewTaxService(TaxConfig{}), nil, nil, balanceService, mockFactory)
 
 	req := &models.CreatePaymentRequest{
 		Provider:    models.ProviderStripe,
@@ -336,7 +564,11 @@ func TestPaymentService_CreatePayment_ProviderError(t *testing.T) {
 	mockProvider := new(MockPaymentProvider)
 	mockFactory := new(MockProviderFactory)
 
-	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, mockFactory)
+	mockBalanceRepo := new(MockCustomerBalanceRepository)
+	balanceService := NewCustomerBalanceService(mockBalanceRepo, mockCustomerRepo, nil, mockFactory)
+	mockBalanceRepo.On("Debit", ctx, mock.AnythingOfType("*models.CustomerBalanceTransaction"), models.NewMoney(10000, models.CurrencySEK)).Return(nil)
+
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, nil, NewTaxService(TaxConfig{}), nil, nil, balanceService, mockFactory)
 
 	req := &models.CreatePaymentRequest{
 		Provider:    models.ProviderStripe,
@@ -381,7 +613,7 @@ func TestPaymentService_GetPayment_Success(t *testing.T) {
 	mockCustomerRepo := new(MockCustomerRepository)
 	mockFactory := new(MockProviderFactory)
 
-	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, mockFactory)
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, nil, nil, nil, nil, nil, mockFactory)
 
 	payment := &models.Payment{
 		ID:                paymentID,
@@ -426,7 +658,7 @@ func TestPaymentService_GetPayment_NotFound(t *testing.T) {
 	mockCustomerRepo := new(MockCustomerRepository)
 	mockFactory := new(MockProviderFactory)
 
-	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, mockFactory)
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, nil, nil, nil, nil, nil, mockFactory)
 
 	// Mock expectations
 	mockPaymentRepo.On("GetByID", ctx, paymentID).Return(nil, nil)
@@ -455,7 +687,7 @@ func TestPaymentService_GetPayment_UnauthorizedAccess(t *testing.T) {
 	mockCustomerRepo := new(MockCustomerRepository)
 	mockFactory := new(MockProviderFactory)
 
-	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, mockFactory)
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, nil, nil, nil, nil, nil, mockFactory)
 
 	payment := &models.Payment{
 		ID:                paymentID,
@@ -500,7 +732,7 @@ func TestPaymentService_ListPayments_Success(t *testing.T) {
 	mockCustomerRepo := new(MockCustomerRepository)
 	mockFactory := new(MockProviderFactory)
 
-	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, mockFactory)
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, nil, nil, nil, nil, nil, mockFactory)
 
 	customer := &models.Customer{
 		ID:     customerID,
@@ -556,7 +788,7 @@ func TestPaymentService_ListPayments_NoCustomer(t *testing.T) {
 	mockCustomerRepo := new(MockCustomerRepository)
 	mockFactory := new(MockProviderFactory)
 
-	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, mockFactory)
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, nil, nil, nil, nil, nil, mockFactory)
 
 	// Mock expectations
 	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(nil, nil)
@@ -572,3 +804,122 @@ func TestPaymentService_ListPayments_NoCustomer(t *testing.T) {
 
 	mockCustomerRepo.AssertExpectations(t)
 }
+
+func TestPaymentService_CreatePayment_PaidWithCredit(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	stripeCustomerID := "cus_test123"
+
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockBalanceRepo := new(MockCustomerBalanceRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	ledgerService := NewLedgerService(mockLedgerRepo, LedgerConfig{})
+	balanceService := NewCustomerBalanceService(mockBalanceRepo, mockCustomerRepo, ledgerService, mockFactory)
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, nil, NewTaxService(TaxConfig{}), ledgerService, nil, balanceService, mockFactory)
+
+	req := &models.CreatePaymentRequest{
+		Provider: models.ProviderStripe,
+		Amount:   10000,
+		Currency: models.CurrencySEK,
+	}
+
+	existingCustomer := &models.Customer{
+		ID:               customerID,
+		UserID:           userID,
+		StripeCustomerID: &stripeCustomerID,
+	}
+
+	// Mock expectations
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(existingCustomer, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockBalanceRepo.On("Debit", ctx, mock.AnythingOfType("*models.CustomerBalanceTransaction"), models.NewMoney(10000, models.CurrencySEK)).Run(debitBalance(10000)).Return(nil)
+	mockPaymentRepo.On("Create", ctx, mock.MatchedBy(func(p *models.Payment) bool {
+		return p.Amount == 10000 && p.CreditApplied == 10000 && p.ProviderPaymentID == ""
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Payment).ID = uuid.New()
+	}).Return(nil)
+	mockBalanceRepo.On("SetReference", ctx, mock.Anything, "payment", mock.Anything).Return(nil)
+
+	var recorded *models.LedgerEntry
+	mockLedgerRepo.On("CreateEntry", ctx, mock.AnythingOfType("*models.LedgerEntry")).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(*models.LedgerEntry)
+	}).Return(true, nil)
+
+	// Execute
+	result, err := service.CreatePayment(ctx, userID, "test@example.com", "Test User", req)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusSucceeded, result.Status)
+	assert.True(t, result.ChargedAmount().IsZero())
+	assert.Equal(t, []models.LedgerPosting{
+		{AccountCode: models.LedgerAccountCustomerCredit, Amount: 10000},
+		{AccountCode: models.LedgerAccountRevenue, Amount: -8000},
+		{AccountCode: models.LedgerAccountTaxPayable, Amount: -2000},
+	}, recorded.Postings)
+	mockProvider.AssertNotCalled(t, "CreatePayment", mock.Anything, mock.Anything)
+	mockBalanceRepo.AssertExpectations(t)
+}
+
+func TestPaymentService_CreatePayment_PartlyPaidWithCredit(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	stripeCustomerID := "cus_test123"
+
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockBalanceRepo := new(MockCustomerBalanceRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	balanceService := NewCustomerBalanceService(mockBalanceRepo, mockCustomerRepo, nil, mockFactory)
+	service := NewPaymentService(mockPaymentRepo, mockCustomerRepo, nil, NewTaxService(TaxConfig{}), nil, nil, balanceService, mockFactory)
+
+	req := &models.CreatePaymentRequest{
+		Provider: models.ProviderStripe,
+		Amount:   10000,
+		Currency: models.CurrencySEK,
+	}
+
+	existingCustomer := &models.Customer{
+		ID:               customerID,
+		UserID:           userID,
+		StripeCustomerID: &stripeCustomerID,
+	}
+
+	// Mock expectations: the provider charges only what credit does not cover
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(existingCustomer, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockBalanceRepo.On("Debit", ctx, mock.AnythingOfType("*models.CustomerBalanceTransaction"), models.NewMoney(10000, models.CurrencySEK)).Run(debitBalance(3000)).Return(nil)
+	mockProvider.On("CreatePayment", ctx, mock.MatchedBy(func(req *providers.CreatePaymentRequest) bool {
+		return req.Amount == 7000
+	})).Return(&models.Payment{
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            7000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusPending,
+	}, nil)
+	mockPaymentRepo.On("Create", ctx, mock.AnythingOfType("*models.Payment")).Return(nil)
+	mockBalanceRepo.On("SetReference", ctx, mock.Anything, "payment", mock.Anything).Return(nil)
+
+	// Execute
+	result, err := service.CreatePayment(ctx, userID, "test@example.com", "Test User", req)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10000), result.Amount)
+	assert.Equal(t, int64(3000), result.CreditApplied)
+	assert.Equal(t, models.NewMoney(7000, models.CurrencySEK), result.ChargedAmount())
+	assert.Equal(t, models.NewMoney(7000, models.CurrencySEK), result.RefundableAmount())
+	mockProvider.AssertExpectations(t)
+	mockPaymentRepo.AssertExpectations(t)
+}
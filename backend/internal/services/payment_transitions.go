@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"payment-service/internal/models"
+)
+
+// paymentTransitions applies what follows from a payment changing status at
+// the provider. The webhook and reconciliation, which repairs missed
+// webhooks, both go through it so a payment ends up the same either way.
+// Every step is idempotent, so a payment can be applied again after a partial
+// failure.
+type paymentTransitions struct {
+	ledgerService     *LedgerService
+	settlementService *SettlementService
+	balanceService    *CustomerBalanceService
+	walletService     *WalletService
+}
+
+// apply books a succeeded payment and credits it to the wallet if it is a
+// top-up, and gives back the store credit held by a payment that failed or
+// was canceled
+func (t paymentTransitions) apply(ctx context.Context, payment *models.Payment) error {
+	switch payment.Status {
+	case models.PaymentStatusSucceeded:
+		if err := t.ledgerService.RecordCharge(ctx, payment); err != nil {
+			return fmt.Errorf("failed to record charge in ledger: %w", err)
+		}
+		if err := t.settlementService.SyncPayment(ctx, payment); err != nil {
+			return fmt.Errorf("failed to sync payment settlement: %w", err)
+		}
+		if err := t.walletService.CreditTopUp(ctx, payment); err != nil {
+			return fmt.Errorf("failed to credit wallet top-up: %w", err)
+		}
+	case models.PaymentStatusFailed, models.PaymentStatusCanceled:
+		if err := t.balanceService.RestorePaymentCredit(ctx, payment); err != nil {
+			return fmt.Errorf("failed to restore customer balance: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PayoutConfig controls the scheduled payout import
+type PayoutConfig struct {
+	// ImportWindow is how far back each scheduled import looks. It should
+	// cover the time a payout takes to arrive, so status changes are picked up.
+	ImportWindow time.Duration
+}
+
+type PayoutService struct {
+	payoutRepo      repository.PayoutRepositoryInterface
+	ledgerService   *LedgerService
+	providerFactory ProviderFactoryInterface
+	config          PayoutConfig
+}
+
+func NewPayoutService(
+	payoutRepo repository.PayoutRepositoryInterface,
+	ledgerService *LedgerService,
+	providerFactory ProviderFactoryInterface,
+	config PayoutConfig,
+) *PayoutService {
+	return &PayoutService{
+		payoutRepo:      payoutRepo,
+		ledgerService:   ledgerService,
+		providerFactory: providerFactory,
+		config:          config,
+	}
+}
+
+// ImportScheduled imports payouts created within the configured window
+func (s *PayoutService) ImportScheduled(ctx context.Context) (*models.PayoutImportResult, error) {
+	to := time.Now()
+	return s.Import(ctx, to.Add(-s.config.ImportWindow), to)
+}
+
+// ImportPayouts imports payouts for an admin-chosen window
+func (s *PayoutService) ImportPayouts(ctx context.Context, from, to time.Time) (*models.PayoutImportResult, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-s.config.ImportWindow)
+	}
+	if !from.Before(to) {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"from must be before to",
+			http.StatusBadRequest,
+		)
+	}
+
+	result, err := s.Import(ctx, from, to)
+	if err != nil {
+		log.Printf("Failed to import payouts: %v", err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to import payouts",
+			http.StatusBadGateway,
+		)
+	}
+
+	return result, nil
+}
+
+// Import pages through provider payouts created in [from, to), stores each
+// with its balance transactions and reconciles it. Re-importing a payout
+// refreshes it, so payments settled since the last import get matched.
+func (s *PayoutService) Import(ctx context.Context, from, to time.Time) (*models.PayoutImportResult, error) {
+	provider, err := s.providerFactory.GetProvider(models.ProviderStripe)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.PayoutImportResult{}
+	params := &providers.ListParams{CreatedFrom: from, CreatedTo: to, Limit: reconciliationPageSize}
+	for {
+		page, err := provider.ListPayouts(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list provider payouts: %w", err)
+		}
+
+		for _, id := range page.IDs {
+			reconciled, err := s.importPayout(ctx, provider, id)
+			if err != nil {
+				log.Printf("Failed to import payout %s: %v", id, err)
+				result.Failed++
+				continue
+			}
+			result.Imported++
+			if reconciled {
+				result.Reconciled++
+			}
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		params.StartingAfter = page.NextCursor
+	}
+
+	return result, nil
+}
+
+// importPayout stores one payout and its transactions and reports whether it
+// reconciles
+func (s *PayoutService) importPayout(ctx context.Context, provider providers.PaymentProvider, providerPayoutID string) (bool, error) {
+	payout, err := provider.GetPayout(ctx, providerPayoutID)
+	if err != nil {
+		return false, err
+	}
+	balanceTransactions, err := provider.ListPayoutTransactions(ctx, providerPayoutID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := s.payoutRepo.Upsert(ctx, payout); err != nil {
+		return false, err
+	}
+
+	transactions := make([]models.PayoutTransaction, len(balanceTransactions))
+	for i, bt := range balanceTransactions {
+		transactions[i] = models.PayoutTransaction{
+			PayoutID:             payout.ID,
+			BalanceTransactionID: bt.ID,
+			Type:                 models.PayoutTransactionType(bt.Type),
+			Amount:               bt.Amount,
+			Fee:                  bt.Fee,
+			Net:                  bt.Net,
+			Currency:             bt.Currency,
+		}
+		if bt.SourceID != "" {
+			sourceID := bt.SourceID
+			transactions[i].SourceID = &sourceID
+		}
+		if bt.Description != "" {
+			description := bt.Description
+			transactions[i].Description = &description
+		}
+	}
+	if err := s.payoutRepo.ReplaceTransactions(ctx, payout.ID, transactions); err != nil {
+		return false, err
+	}
+
+	stored, err := s.payoutRepo.ListTransactions(ctx, payout.ID)
+	if err != nil {
+		return false, err
+	}
+	breakdown := buildPayoutBreakdown(payout, stored)
+	if err := s.payoutRepo.SetReconciled(ctx, payout, breakdown.Reconciled); err != nil {
+		return false, err
+	}
+
+	if payout.Status == models.PayoutStatusPaid {
+		if err := s.ledgerService.RecordPayout(ctx, payout); err != nil {
+			return false, err
+		}
+	}
+
+	return breakdown.Reconciled, nil
+}
+
+// ListPayouts lists imported payouts, latest arrival first
+func (s *PayoutService) ListPayouts(ctx context.Context, limit, offset int) (*models.PayoutListResponse, error) {
+	payouts, total, err := s.payoutRepo.List(ctx, limit, offset)
+	if err != nil {
+		log.Printf("Failed to list payouts: %v", err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve payouts",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.PayoutListResponse{
+		Data:   payouts,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// GetPayoutBreakdown returns a payout with the charges, refunds and fees it
+// paid out
+func (s *PayoutService) GetPayoutBreakdown(ctx context.Context, id uuid.UUID) (*models.PayoutBreakdown, error) {
+	payout, err := s.payoutRepo.GetByID(ctx, id)
+	if err != nil {
+		log.Printf("Failed to get payout %s: %v", id, err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve payout",
+			http.StatusInternalServerError,
+		)
+	}
+	if payout == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Payout not found",
+			http.StatusNotFound,
+		)
+	}
+
+	transactions, err := s.payoutRepo.ListTransactions(ctx, id)
+	if err != nil {
+		log.Printf("Failed to list transactions for payout %s: %v", id, err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve payout",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return buildPayoutBreakdown(payout, transactions), nil
+}
+
+// ExportPayoutCSV renders a payout's transactions as CSV, one row per
+// balance transaction, for matching a bank line to individual orders
+func (s *PayoutService) ExportPayoutCSV(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	breakdown, err := s.GetPayoutBreakdown(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{
+		"payout_id", "arrival_date", "balance_transaction_id", "type", "source_id",
+		"payment_id", "refund_id", "amount", "fee", "net", "currency", "description",
+	})
+	for _, t := range breakdown.Transactions {
+		w.Write([]string{
+			breakdown.Payout.ProviderPayoutID,
+			breakdown.Payout.ArrivalDate.Format(time.DateOnly),
+			t.BalanceTransactionID,
+			string(t.Type),
+			stringValue(t.SourceID),
+			uuidValue(t.PaymentID),
+			uuidValue(t.RefundID),
+			strconv.FormatInt(t.Amount, 10),
+			strconv.FormatInt(t.Fee, 10),
+			strconv.FormatInt(t.Net, 10),
+			string(t.Currency),
+			stringValue(t.Description),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to export payout",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// buildPayoutBreakdown totals a payout's transactions. The payout reconciles
+// when they sum to its amount and every charge and refund is matched to our
+// payment or refund. A transaction in another currency than the payout can't
+// be added to its totals and counts as unmatched.
+func buildPayoutBreakdown(payout *models.Payout, transactions []models.PayoutTransaction) *models.PayoutBreakdown {
+	breakdown := &models.PayoutBreakdown{
+		Payout:       *payout,
+		Transactions: transactions,
+	}
+
+	net := models.NewMoney(0, payout.Currency)
+	for _, t := range transactions {
+		sum, err := net.Add(t.NetMoney())
+		if err != nil {
+			breakdown.Unmatched++
+			continue
+		}
+		net = sum
+
+		line := &breakdown.Other
+		switch t.Type {
+		case models.PayoutTransactionCharge:
+			line = &breakdown.Charges
+		case models.PayoutTransactionRefund:
+			line = &breakdown.Refunds
+		}
+		line.Count++
+		line.Amount += t.Amount
+		line.Fees += t.Fee
+		line.Net += t.Net
+
+		if !t.Matched() {
+			breakdown.Unmatched++
+		}
+	}
+
+	breakdown.Net = net.Amount
+	breakdown.Difference = payout.Amount - net.Amount
+	breakdown.Reconciled = breakdown.Difference == 0 && breakdown.Unmatched == 0
+	return breakdown
+}
+
+func uuidValue(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
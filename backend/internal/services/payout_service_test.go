@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPayoutRepository is a mock for PayoutRepository
+type MockPayoutRepository struct {
+	mock.Mock
+}
+
+func (m *MockPayoutRepository) Upsert(ctx context.Context, payout *models.Payout) error {
+	args := m.Called(ctx, payout)
+	return args.Error(0)
+}
+
+func (m *MockPayoutRepository) ReplaceTransactions(ctx context.Context, payoutID uuid.UUID, transactions []models.PayoutTransaction) error {
+	args := m.Called(ctx, payoutID, transactions)
+	return args.Error(0)
+}
+
+func (m *MockPayoutRepository) SetReconciled(ctx context.Context, payout *models.Payout, reconciled bool) error {
+	args := m.Called(ctx, payout, reconciled)
+	return args.Error(0)
+}
+
+func (m *MockPayoutRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Payout, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Payout), args.Error(1)
+}
+
+func (m *MockPayoutRepository) List(ctx context.Context, limit, offset int) ([]models.Payout, int, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]models.Payout), args.Int(1), args.Error(2)
+}
+
+func (m *MockPayoutRepository) ListTransactions(ctx context.Context, payoutID uuid.UUID) ([]models.PayoutTransaction, error) {
+	args := m.Called(ctx, payoutID)
+	return args.Get(0).([]models.PayoutTransaction), args.Error(1)
+}
+
+func TestPayoutService_Import_ReconcilesPaidPayout(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockPayoutRepo := new(MockPayoutRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	ledgerService := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+	service := NewPayoutService(mockPayoutRepo, ledgerService, mockFactory, PayoutConfig{ImportWindow: 14 * 24 * time.Hour})
+
+	payoutID := uuid.New()
+	paymentID := uuid.New()
+	refundID := uuid.New()
+	payout := &models.Payout{
+		Provider:         models.ProviderStripe,
+		ProviderPayoutID: "po_test123",
+		Amount:           8000,
+		Currency:         models.CurrencySEK,
+		Status:           models.PayoutStatusPaid,
+		ArrivalDate:      time.Now(),
+	}
+	balanceTransactions := []providers.BalanceTransaction{
+		{ID: "txn_charge", Type: "charge", SourceID: "ch_test123", Amount: 10500, Fee: 500, Net: 10000, Currency: models.CurrencySEK},
+		{ID: "txn_refund", Type: "refund", SourceID: "re_test123", Amount: -2000, Net: -2000, Currency: models.CurrencySEK},
+	}
+	stored := []models.PayoutTransaction{
+		{PayoutID: payoutID, BalanceTransactionID: "txn_charge", Type: models.PayoutTransactionCharge, Amount: 10500, Fee: 500, Net: 10000, Currency: models.CurrencySEK, PaymentID: &paymentID},
+		{PayoutID: payoutID, BalanceTransactionID: "txn_refund", Type: models.PayoutTransactionRefund, Amount: -2000, Net: -2000, Currency: models.CurrencySEK, RefundID: &refundID},
+	}
+
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("ListPayouts", ctx, mock.AnythingOfType("*providers.ListParams")).
+		Return(&providers.ListPage{IDs: []string{"po_test123"}}, nil)
+	mockProvider.On("GetPayout", ctx, "po_test123").Return(payout, nil)
+	mockProvider.On("ListPayoutTransactions", ctx, "po_test123").Return(balanceTransactions, nil)
+	mockPayoutRepo.On("Upsert", ctx, payout).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Payout).ID = payoutID
+	}).Return(nil)
+	mockPayoutRepo.On("ReplaceTransactions", ctx, payoutID, mock.MatchedBy(func(transactions []models.PayoutTransaction) bool {
+		return len(transactions) == 2 && *transactions[0].SourceID == "ch_test123" &&
+			transactions[1].Type == models.PayoutTransactionRefund
+	})).Return(nil)
+	mockPayoutRepo.On("ListTransactions", ctx, payoutID).Return(stored, nil)
+	mockPayoutRepo.On("SetReconciled", ctx, payout, true).Return(nil)
+	mockLedgerRepo.On("CreateEntry", ctx, mock.MatchedBy(func(entry *models.LedgerEntry) bool {
+		return entry.Type == models.LedgerEntryPayout && entry.ReferenceID == payoutID &&
+			entry.Postings[0].AccountCode == models.LedgerAccountBank && entry.Postings[0].Amount == 8000
+	})).Return(true, nil)
+
+	// Execute
+	result, err := service.Import(ctx, time.Now().Add(-24*time.Hour), time.Now())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, result.Reconciled)
+	assert.Equal(t, 0, result.Failed)
+
+	mockPayoutRepo.AssertExpectations(t)
+	mockLedgerRepo.AssertExpectations(t)
+}
+
+func TestPayoutService_GetPayoutBreakdown_UnmatchedCharge(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockPayoutRepo := new(MockPayoutRepository)
+	service := NewPayoutService(mockPayoutRepo, nil, nil, PayoutConfig{})
+
+	payoutID := uuid.New()
+	paymentID := uuid.New()
+	payout := &models.Payout{ID: payoutID, Amount: 20000, Currency: models.CurrencySEK, Status: models.PayoutStatusPaid}
+
+	mockPayoutRepo.On("GetByID", ctx, payoutID).Return(payout, nil)
+	mockPayoutRepo.On("ListTransactions", ctx, payoutID).Return([]models.PayoutTransaction{
+		{Type: models.PayoutTransactionCharge, Amount: 10500, Fee: 500, Net: 10000, Currency: models.CurrencySEK, PaymentID: &paymentID},
+		{Type: models.PayoutTransactionCharge, Amount: 10500, Fee: 500, Net: 10000, Currency: models.CurrencySEK},
+		{Type: "adjustment", Amount: -100, Net: -100, Currency: models.CurrencySEK},
+	}, nil)
+
+	// Execute
+	breakdown, err := service.GetPayoutBreakdown(ctx, payoutID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.PayoutSummaryLine{Count: 2, Amount: 21000, Fees: 1000, Net: 20000}, breakdown.Charges)
+	assert.Equal(t, models.PayoutSummaryLine{Count: 1, Amount: -100, Net: -100}, breakdown.Other)
+	assert.Equal(t, int64(19900), breakdown.Net)
+	assert.Equal(t, int64(100), breakdown.Difference)
+	assert.Equal(t, 1, breakdown.Unmatched)
+	assert.False(t, breakdown.Reconciled)
+}
+
+func TestBuildPayoutBreakdown_OtherCurrencyIsUnmatched(t *testing.T) {
+	// Setup
+	paymentID := uuid.New()
+	otherPaymentID := uuid.New()
+	payout := &models.Payout{Amount: 10000, Currency: models.CurrencySEK}
+	transactions := []models.PayoutTransaction{
+		{Type: models.PayoutTransactionCharge, Amount: 10000, Net: 10000, Currency: models.CurrencySEK, PaymentID: &paymentID},
+		{Type: models.PayoutTransactionCharge, Amount: 500, Net: 500, Currency: models.CurrencyEUR, PaymentID: &otherPaymentID},
+	}
+
+	// Execute
+	breakdown := buildPayoutBreakdown(payout, transactions)
+
+	// Assert
+	assert.Equal(t, int64(10000), breakdown.Net)
+	assert.Equal(t, int64(0), breakdown.Difference)
+	assert.Equal(t, 1, breakdown.Charges.Count)
+	assert.Equal(t, 1, breakdown.Unmatched)
+	assert.False(t, breakdown.Reconciled)
+}
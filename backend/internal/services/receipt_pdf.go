@@ -0,0 +1,192 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"payment-service/internal/models"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// receiptDocument holds everything printed on a receipt
+type receiptDocument struct {
+	Seller        SellerDetails
+	ReceiptNumber string
+	IssuedAt      time.Time
+	PaymentRef    string
+	InvoiceNumber string
+
+	CustomerName      string
+	CustomerAddress   []string
+	CustomerEmail     string
+	CustomerOrgNumber string
+	CustomerVATID     string
+
+	Currency  models.Currency
+	LineItems []models.InvoiceLineItem
+	Subtotal  int64
+	Discount  int64
+	VATLines  []receiptVATLine
+	Total     int64
+
+	// Store credit used towards Total; the rest was charged
+	CreditApplied int64
+
+	// Tax inclusive prices show VAT as included rather than added
+	TaxInclusive  bool
+	ReverseCharge bool
+}
+
+// receiptVATLine is one row of the VAT breakdown
+type receiptVATLine struct {
+	Label  string
+	Net    int64
+	Amount int64
+}
+
+// renderReceiptPDF renders a receipt as an A4 PDF
+func renderReceiptPDF(doc *receiptDocument) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetCreationDate(doc.IssuedAt)
+	pdf.SetCatalogSort(true)
+	pdf.SetTitle("Receipt "+doc.ReceiptNumber, true)
+	pdf.SetAuthor(doc.Seller.Name, true)
+	pdf.AddPage()
+
+	// Core fonts are cp1252; translate so å, ä and ö print correctly
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	// Seller
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 7, tr(doc.Seller.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range []string{
+		doc.Seller.Address,
+		labelled("Org. no.", doc.Seller.OrgNumber),
+		labelled("VAT no.", doc.Seller.VATNumber),
+		doc.Seller.Email,
+	} {
+		if line != "" {
+			pdf.CellFormat(0, 4.5, tr(line), "", 1, "L", false, 0, "")
+		}
+	}
+	pdf.Ln(8)
+
+	// Receipt details
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 9, "Receipt", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	details := [][2]string{
+		{"Receipt number", doc.ReceiptNumber},
+		{"Date", doc.IssuedAt.Format("2006-01-02")},
+		{"Payment reference", doc.PaymentRef},
+	}
+	if doc.InvoiceNumber != "" {
+		details = append(details, [2]string{"Invoice number", doc.InvoiceNumber})
+	}
+	for _, d := range details {
+		pdf.CellFormat(45, 5.5, d[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5.5, tr(d[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Customer
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(0, 5.5, "Billed to", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	customerLines := append([]string{doc.CustomerName}, doc.CustomerAddress...)
+	customerLines = append(customerLines,
+		doc.CustomerEmail,
+		labelled("Org. no.", doc.CustomerOrgNumber),
+		labelled("VAT no.", doc.CustomerVATID),
+	)
+	for _, line := range customerLines {
+		if line != "" {
+			pdf.CellFormat(0, 5, tr(line), "", 1, "L", false, 0, "")
+		}
+	}
+	pdf.Ln(8)
+
+	// Line items
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(95, 7, "Description", "B", 0, "L", false, 0, "")
+	pdf.CellFormat(15, 7, "Qty", "B", 0, "R", false, 0, "")
+	pdf.CellFormat(30, 7, "Unit price", "B", 0, "R", false, 0, "")
+	pdf.CellFormat(30, 7, "Amount", "B", 1, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, item := range doc.LineItems {
+		pdf.CellFormat(95, 7, tr(item.Description), "", 0, "L", false, 0, "")
+		pdf.CellFormat(15, 7, fmt.Sprintf("%d", item.Quantity), "", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 7, doc.Currency.FormatAmount(item.UnitAmount), "", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 7, doc.Currency.FormatAmount(item.Amount), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(2)
+
+	// Totals
+	total := func(label, value string, bold bool) {
+		style := ""
+		if bold {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, 10)
+		pdf.CellFormat(140, 6, label, "", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 6, value, "", 1, "R", false, 0, "")
+	}
+	total("Subtotal", doc.Currency.FormatAmount(doc.Subtotal), false)
+	if doc.Discount > 0 {
+		total("Discount", doc.Currency.FormatAmount(-doc.Discount), false)
+	}
+	for _, vat := range doc.VATLines {
+		label := vat.Label
+		if doc.TaxInclusive {
+			label = "Incl. " + label
+		}
+		total(label, doc.Currency.FormatAmount(vat.Amount), false)
+	}
+	if doc.CreditApplied > 0 {
+		total(fmt.Sprintf("Total (%s)", doc.Currency), doc.Currency.FormatAmount(doc.Total), false)
+		total("Paid with store credit", doc.Currency.FormatAmount(-doc.CreditApplied), false)
+		total(fmt.Sprintf("Total paid (%s)", doc.Currency), doc.Currency.FormatAmount(doc.Total-doc.CreditApplied), true)
+	} else {
+		total(fmt.Sprintf("Total paid (%s)", doc.Currency), doc.Currency.FormatAmount(doc.Total), true)
+	}
+
+	// VAT breakdown
+	if len(doc.VATLines) > 0 {
+		pdf.Ln(8)
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(60, 6, "VAT breakdown", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(40, 6, "Net", "B", 0, "R", false, 0, "")
+		pdf.CellFormat(40, 6, "VAT", "B", 1, "R", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		for _, vat := range doc.VATLines {
+			pdf.CellFormat(60, 6, vat.Label, "", 0, "L", false, 0, "")
+			pdf.CellFormat(40, 6, doc.Currency.FormatAmount(vat.Net), "", 0, "R", false, 0, "")
+			pdf.CellFormat(40, 6, doc.Currency.FormatAmount(vat.Amount), "", 1, "R", false, 0, "")
+		}
+	}
+
+	if doc.ReverseCharge {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, 4.5, "Reverse charge: VAT to be accounted for by the recipient "+
+			"(Article 196, Council Directive 2006/112/EC).", "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render receipt: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// labelled prefixes value with label, or returns "" if value is empty
+func labelled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + " " + value
+}
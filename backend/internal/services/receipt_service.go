@@ -0,0 +1,369 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/repository"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SellerDetails identifies the seller on receipts
+type SellerDetails struct {
+	Name      string
+	Address   string
+	OrgNumber string
+	VATNumber string
+	Email     string
+}
+
+// ReceiptConfig controls receipt numbering and the seller printed on receipts
+type ReceiptConfig struct {
+	Tenant       string
+	NumberPrefix string
+	Seller       SellerDetails
+}
+
+type ReceiptService struct {
+	receiptRepo  repository.ReceiptRepositoryInterface
+	paymentRepo  repository.PaymentRepositoryInterface
+	invoiceRepo  repository.InvoiceRepositoryInterface
+	customerRepo repository.CustomerRepositoryInterface
+	config       ReceiptConfig
+}
+
+func NewReceiptService(
+	receiptRepo repository.ReceiptRepositoryInterface,
+	paymentRepo repository.PaymentRepositoryInterface,
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	customerRepo repository.CustomerRepositoryInterface,
+	config ReceiptConfig,
+) *ReceiptService {
+	return &ReceiptService{
+		receiptRepo:  receiptRepo,
+		paymentRepo:  paymentRepo,
+		invoiceRepo:  invoiceRepo,
+		customerRepo: customerRepo,
+		config:       config,
+	}
+}
+
+// GetPaymentReceipt returns the receipt for a succeeded payment, issuing and
+// rendering it on first request
+func (s *ReceiptService) GetPaymentReceipt(ctx context.Context, paymentID, userID uuid.UUID) (*models.Receipt, error) {
+	payment, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve payment",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if payment == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Payment not found",
+			http.StatusNotFound,
+		)
+	}
+
+	// Verify customer owns this payment
+	customer, err := s.customerRepo.GetByID(ctx, payment.CustomerID)
+	if err != nil || customer == nil || customer.UserID != userID {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Payment not found",
+			http.StatusNotFound,
+		)
+	}
+
+	return s.getReceipt(ctx, payment, customer)
+}
+
+// GetInvoiceReceipt returns the receipt for the payment that settled an invoice
+func (s *ReceiptService) GetInvoiceReceipt(ctx context.Context, invoiceID, userID uuid.UUID) (*models.Receipt, error) {
+	invoice, err := s.invoiceRepo.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve invoice",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if invoice == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Invoice not found",
+			http.StatusNotFound,
+		)
+	}
+
+	// Verify customer owns this invoice
+	customer, err := s.customerRepo.GetByID(ctx, invoice.CustomerID)
+	if err != nil || customer == nil || customer.UserID != userID {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Invoice not found",
+			http.StatusNotFound,
+		)
+	}
+
+	if invoice.Status != models.InvoiceStatusPaid || invoice.PaymentID == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Receipts are only available for paid invoices",
+			http.StatusBadRequest,
+		)
+	}
+
+	payment, err := s.paymentRepo.GetByID(ctx, *invoice.PaymentID)
+	if err != nil || payment == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve payment",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return s.getReceipt(ctx, payment, customer)
+}
+
+// getReceipt loads or issues the receipt for a payment and makes sure its PDF
+// has been rendered
+func (s *ReceiptService) getReceipt(ctx context.Context, payment *models.Payment, customer *models.Customer) (*models.Receipt, error) {
+	if payment.Status != models.PaymentStatusSucceeded {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Receipts are only available for succeeded payments",
+			http.StatusBadRequest,
+		)
+	}
+
+	receipt, err := s.receiptRepo.GetByPaymentID(ctx, payment.ID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve receipt",
+			http.StatusInternalServerError,
+		)
+	}
+
+	invoice, err := s.paymentInvoice(ctx, payment)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve invoice",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if receipt == nil {
+		receipt, err = s.issueReceipt(ctx, payment, invoice)
+		if err != nil {
+			log.Printf("Failed to issue receipt for payment %s: %v", payment.ID, err)
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to issue receipt",
+				http.StatusInternalServerError,
+			)
+		}
+	}
+
+	if len(receipt.PDF) == 0 {
+		pdf, err := renderReceiptPDF(s.buildReceiptDocument(receipt, payment, invoice, customer))
+		if err != nil {
+			log.Printf("Failed to render receipt %s: %v", receipt.ReceiptNumber, err)
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to render receipt",
+				http.StatusInternalServerError,
+			)
+		}
+
+		// Store it so later downloads return the same document
+		if err := s.receiptRepo.UpdatePDF(ctx, receipt.ID, pdf); err != nil {
+			log.Printf("Failed to store receipt %s: %v", receipt.ReceiptNumber, err)
+		}
+		receipt.PDF = pdf
+	}
+
+	return receipt, nil
+}
+
+// issueReceipt assigns the next receipt number to a payment
+func (s *ReceiptService) issueReceipt(ctx context.Context, payment *models.Payment, invoice *models.Invoice) (*models.Receipt, error) {
+	receipt := &models.Receipt{
+		Tenant:     s.config.Tenant,
+		PaymentID:  payment.ID,
+		CustomerID: payment.CustomerID,
+		Amount:     payment.Amount,
+		Currency:   payment.Currency,
+	}
+	if invoice != nil {
+		receipt.InvoiceID = &invoice.ID
+	}
+
+	created, err := s.receiptRepo.Create(ctx, receipt, s.config.NumberPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return receipt, nil
+	}
+
+	// A concurrent request issued it first
+	receipt, err = s.receiptRepo.GetByPaymentID(ctx, payment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, fmt.Errorf("receipt for payment %s not found after conflict", payment.ID)
+	}
+
+	return receipt, nil
+}
+
+// paymentInvoice returns the local invoice a payment settled, if any
+func (s *ReceiptService) paymentInvoice(ctx context.Context, payment *models.Payment) (*models.Invoice, error) {
+	if payment.InvoiceID == nil {
+		return nil, nil
+	}
+	return s.invoiceRepo.GetByProviderInvoiceID(ctx, payment.Provider, *payment.InvoiceID)
+}
+
+// buildReceiptDocument collects what is printed on a receipt
+func (s *ReceiptService) buildReceiptDocument(
+	receipt *models.Receipt,
+	payment *models.Payment,
+	invoice *models.Invoice,
+	customer *models.Customer,
+) *receiptDocument {
+	issuedAt := receipt.CreatedAt
+	if payment.CompletedAt != nil {
+		issuedAt = *payment.CompletedAt
+	}
+
+	doc := &receiptDocument{
+		Seller:        s.config.Seller,
+		ReceiptNumber: receipt.ReceiptNumber,
+		IssuedAt:      issuedAt,
+		PaymentRef:    payment.ProviderPaymentID,
+		Currency:      payment.Currency,
+		Total:         payment.Amount,
+		CreditApplied: payment.CreditApplied,
+	}
+	applyBillingProfile(doc, customer)
+
+	if invoice != nil {
+		if invoice.Number != nil {
+			doc.InvoiceNumber = *invoice.Number
+		}
+		doc.LineItems = invoice.LineItems
+		doc.Subtotal = invoice.Subtotal
+		doc.Discount = invoice.Subtotal + invoice.Tax - invoice.Total
+		if invoice.TaxBreakdown != nil {
+			applyTaxBreakdown(doc, invoice.TaxBreakdown)
+			if doc.TaxInclusive {
+				doc.Discount = invoice.Subtotal - invoice.Total
+			}
+		} else {
+			doc.VATLines = []receiptVATLine{vatLine(invoice.Total-invoice.Tax, invoice.Tax)}
+		}
+		return doc
+	}
+
+	description := "Payment"
+	if payment.Description != nil && *payment.Description != "" {
+		description = *payment.Description
+	}
+	listPrice := payment.Amount + payment.DiscountAmount
+	if payment.TaxBreakdown != nil && payment.TaxBreakdown.Behavior == models.TaxBehaviorExclusive {
+		listPrice -= payment.TaxAmount
+	}
+	doc.LineItems = []models.InvoiceLineItem{{
+		Description: description,
+		Quantity:    1,
+		UnitAmount:  listPrice,
+		Amount:      listPrice,
+	}}
+	doc.Subtotal = listPrice
+	doc.Discount = payment.DiscountAmount
+	if payment.TaxBreakdown != nil {
+		applyTaxBreakdown(doc, payment.TaxBreakdown)
+	} else {
+		doc.VATLines = []receiptVATLine{vatLine(payment.Amount, 0)}
+	}
+
+	return doc
+}
+
+// applyBillingProfile fills in who a receipt is billed to from the customer's
+// billing profile
+func applyBillingProfile(doc *receiptDocument, customer *models.Customer) {
+	doc.CustomerName = customer.Name
+	if customer.CompanyName != nil {
+		doc.CustomerName = *customer.CompanyName
+	}
+	doc.CustomerEmail = customer.Email
+	if customer.InvoiceEmail != nil {
+		doc.CustomerEmail = *customer.InvoiceEmail
+	}
+	doc.CustomerOrgNumber = stringValue(customer.OrgNumber)
+	doc.CustomerVATID = stringValue(customer.VATID)
+
+	for _, line := range []string{
+		stringValue(customer.AddressLine1),
+		stringValue(customer.AddressLine2),
+		strings.TrimSpace(stringValue(customer.PostalCode) + " " + stringValue(customer.City)),
+		stringValue(customer.Country),
+	} {
+		if line != "" {
+			doc.CustomerAddress = append(doc.CustomerAddress, line)
+		}
+	}
+}
+
+// applyTaxBreakdown adds the VAT lines and reverse charge details of a tax
+// breakdown to a receipt
+func applyTaxBreakdown(doc *receiptDocument, breakdown *models.TaxBreakdown) {
+	doc.TaxInclusive = breakdown.Behavior == models.TaxBehaviorInclusive
+	doc.ReverseCharge = breakdown.ReverseCharge
+	if breakdown.CustomerVATID != "" {
+		doc.CustomerVATID = breakdown.CustomerVATID
+	}
+	for _, line := range breakdown.Lines {
+		doc.VATLines = append(doc.VATLines, receiptVATLine{
+			Label:  fmt.Sprintf("VAT %s%%", trimRate(line.Rate)),
+			Net:    line.Net,
+			Amount: line.Tax,
+		})
+	}
+}
+
+// vatLine builds a VAT breakdown row, deriving the rate from the amounts
+func vatLine(net, vat int64) receiptVATLine {
+	rate := 0.0
+	if net > 0 {
+		rate = float64(vat) * 100 / float64(net)
+	}
+	return receiptVATLine{
+		Label:  fmt.Sprintf("VAT %s%%", trimRate(rate)),
+		Net:    net,
+		Amount: vat,
+	}
+}
+
+// trimRate formats a percentage with at most one decimal, e.g. 25 -> "25", 5.5 -> "5.5"
+func trimRate(rate float64) string {
+	rounded := float64(int64(rate*10+0.5)) / 10
+	if rounded == float64(int64(rounded)) {
+		return fmt.Sprintf("%d", int64(rounded))
+	}
+	return fmt.Sprintf("%.1f", rounded)
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"payment-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReceiptRepository is a mock for ReceiptRepository
+type MockReceiptRepository struct {
+	mock.Mock
+}
+
+func (m *MockReceiptRepository) Create(ctx context.Context, receipt *models.Receipt, numberPrefix string) (bool, error) {
+	args := m.Called(ctx, receipt, numberPrefix)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockReceiptRepository) GetByPaymentID(ctx context.Context, paymentID uuid.UUID) (*models.Receipt, error) {
+	args := m.Called(ctx, paymentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Receipt), args.Error(1)
+}
+
+func (m *MockReceiptRepository) UpdatePDF(ctx context.Context, id uuid.UUID, pdf []byte) error {
+	args := m.Called(ctx, id, pdf)
+	return args.Error(0)
+}
+
+var testReceiptConfig = ReceiptConfig{
+	Tenant:       "default",
+	NumberPrefix: "R",
+	Seller: SellerDetails{
+		Name:      "Exempel AB",
+		Address:   "Storgatan 1, 111 22 Stockholm",
+		OrgNumber: "556677-8899",
+		VATNumber: "SE556677889901",
+	},
+}
+
+func TestReceiptService_GetPaymentReceipt_IssuesReceipt(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	paymentID := uuid.New()
+	receiptID := uuid.New()
+	completedAt := time.Now()
+
+	mockReceiptRepo := new(MockReceiptRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+
+	service := NewReceiptService(mockReceiptRepo, mockPaymentRepo, mockInvoiceRepo, mockCustomerRepo, testReceiptConfig)
+
+	payment := &models.Payment{
+		ID:                paymentID,
+		CustomerID:        customerID,
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            12500,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusSucceeded,
+		CompletedAt:       &completedAt,
+	}
+	customer := &models.Customer{ID: customerID, UserID: userID, Name: "Åsa Öberg", Email: "asa@example.com"}
+
+	// Mock expectations
+	mockPaymentRepo.On("GetByID", ctx, paymentID).Return(payment, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(customer, nil)
+	mockReceiptRepo.On("GetByPaymentID", ctx, paymentID).Return(nil, nil)
+	mockReceiptRepo.On("Create", ctx, mock.MatchedBy(func(receipt *models.Receipt) bool {
+		return receipt.Tenant == "default" && receipt.PaymentID == paymentID && receipt.Amount == 12500
+	}), "R").Run(func(args mock.Arguments) {
+		receipt := args.Get(1).(*models.Receipt)
+		receipt.ID = receiptID
+		receipt.Number = 1
+		receipt.ReceiptNumber = "R-000001"
+	}).Return(true, nil)
+	mockReceiptRepo.On("UpdatePDF", ctx, receiptID, mock.Anything).Return(nil)
+
+	// Execute
+	receipt, err := service.GetPaymentReceipt(ctx, paymentID, userID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "R-000001", receipt.ReceiptNumber)
+	assert.True(t, bytes.HasPrefix(receipt.PDF, []byte("%PDF")))
+
+	mockPaymentRepo.AssertExpectations(t)
+	mockCustomerRepo.AssertExpectations(t)
+	mockReceiptRepo.AssertExpectations(t)
+}
+
+func TestReceiptService_GetPaymentReceipt_StoredReceipt(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	paymentID := uuid.New()
+
+	mockReceiptRepo := new(MockReceiptRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+
+	service := NewReceiptService(mockReceiptRepo, mockPaymentRepo, mockInvoiceRepo, mockCustomerRepo, testReceiptConfig)
+
+	payment := &models.Payment{ID: paymentID, CustomerID: customerID, Status: models.PaymentStatusSucceeded}
+	customer := &models.Customer{ID: customerID, UserID: userID}
+	stored := &models.Receipt{ID: uuid.New(), PaymentID: paymentID, ReceiptNumber: "R-000007", PDF: []byte("%PDF-1.3 stored")}
+
+	// Mock expectations
+	mockPaymentRepo.On("GetByID", ctx, paymentID).Return(payment, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(customer, nil)
+	mockReceiptRepo.On("GetByPaymentID", ctx, paymentID).Return(stored, nil)
+
+	// Execute
+	receipt, err := service.GetPaymentReceipt(ctx, paymentID, userID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, stored, receipt)
+
+	mockReceiptRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	mockReceiptRepo.AssertNotCalled(t, "UpdatePDF", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReceiptService_GetPaymentReceipt_NotSucceeded(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	paymentID := uuid.New()
+
+	mockReceiptRepo := new(MockReceiptRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+
+	service := NewReceiptService(mockReceiptRepo, mockPaymentRepo, mockInvoiceRepo, mockCustomerRepo, testReceiptConfig)
+
+	payment := &models.Payment{ID: paymentID, CustomerID: customerID, Status: models.PaymentStatusPending}
+	customer := &models.Customer{ID: customerID, UserID: userID}
+
+	// Mock expectations
+	mockPaymentRepo.On("GetByID", ctx, paymentID).Return(payment, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(customer, nil)
+
+	// Execute
+	receipt, err := service.GetPaymentReceipt(ctx, paymentID, userID)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, receipt)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, models.ErrCodeInvalidRequest, apiErr.Code)
+
+	mockReceiptRepo.AssertNotCalled(t, "GetByPaymentID", mock.Anything, mock.Anything)
+}
+
+func TestReceiptService_BuildReceiptDocument_ShowsStoreCredit(t *testing.T) {
+	// Setup
+	service := NewReceiptService(nil, nil, nil, nil, testReceiptConfig)
+	description := "Annual plan"
+	payment := &models.Payment{
+		ID:            uuid.New(),
+		Amount:        12500,
+		Currency:      models.CurrencySEK,
+		Status:        models.PaymentStatusSucceeded,
+		CreditApplied: 12500,
+		Description:   &description,
+	}
+	receipt := &models.Receipt{ReceiptNumber: "R-000002", CreatedAt: time.Now()}
+
+	// Execute
+	doc := service.buildReceiptDocument(receipt, payment, nil, &models.Customer{Name: "Åsa Öberg"})
+
+	// Assert: a purchase paid with credit is receipted at its full price
+	assert.Equal(t, int64(12500), doc.Total)
+	assert.Equal(t, int64(12500), doc.CreditApplied)
+	assert.Equal(t, int64(12500), doc.LineItems[0].Amount)
+}
@@ -0,0 +1,494 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"payment-service/internal/middleware"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// reconciliationPageSize is how many provider objects are listed per request
+const reconciliationPageSize = 100
+
+// maxReconciliationRange caps a manual run so it can't page through years
+const maxReconciliationRange = 31 * 24 * time.Hour
+
+// ReconciliationConfig controls the scheduled reconciliation run
+type ReconciliationConfig struct {
+	// Window is how far back each scheduled run looks. Overlapping windows
+	// are fine; rechecking an object is idempotent.
+	Window time.Duration
+}
+
+type ReconciliationService struct {
+	reconciliationRepo repository.ReconciliationRepositoryInterface
+	paymentRepo        repository.PaymentRepositoryInterface
+	subscriptionRepo   repository.SubscriptionRepositoryInterface
+	refundRepo         repository.RefundRepositoryInterface
+	ledgerService      *LedgerService
+	settlementService  *SettlementService
+	transitions        paymentTransitions
+	providerFactory    ProviderFactoryInterface
+	config             ReconciliationConfig
+}
+
+func NewReconciliationService(
+	reconciliationRepo repository.ReconciliationRepositoryInterface,
+	paymentRepo repository.PaymentRepositoryInterface,
+	subscriptionRepo repository.SubscriptionRepositoryInterface,
+	refundRepo repository.RefundRepositoryInterface,
+	ledgerService *LedgerService,
+	settlementService *SettlementService,
+	balanceService *CustomerBalanceService,
+	walletService *WalletService,
+	providerFactory ProviderFactoryInterface,
+	config ReconciliationConfig,
+) *ReconciliationService {
+	return &ReconciliationService{
+		reconciliationRepo: reconciliationRepo,
+		paymentRepo:        paymentRepo,
+		subscriptionRepo:   subscriptionRepo,
+		refundRepo:         refundRepo,
+		ledgerService:      ledgerService,
+		settlementService:  settlementService,
+		transitions: paymentTransitions{
+			ledgerService:     ledgerService,
+			settlementService: settlementService,
+			balanceService:    balanceService,
+			walletService:     walletService,
+		},
+		providerFactory: providerFactory,
+		config:          config,
+	}
+}
+
+// RunScheduled reconciles objects created within the configured window
+func (s *ReconciliationService) RunScheduled(ctx context.Context) (*models.ReconciliationResult, error) {
+	to := time.Now()
+	return s.Run(ctx, to.Add(-s.config.Window), to)
+}
+
+// Reconcile runs reconciliation for an admin-chosen window
+func (s *ReconciliationService) Reconcile(ctx context.Context, req *models.ReconciliationRequest) (*models.ReconciliationResult, error) {
+	if req.To.IsZero() {
+		req.To = time.Now()
+	}
+	if req.From.IsZero() {
+		req.From = req.To.Add(-s.config.Window)
+	}
+	if !req.From.Before(req.To) {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"from must be before to",
+			http.StatusBadRequest,
+		)
+	}
+	if req.To.Sub(req.From) > maxReconciliationRange {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Reconciliation range cannot exceed 31 days",
+			http.StatusBadRequest,
+		)
+	}
+
+	result, err := s.Run(ctx, req.From, req.To)
+	if err != nil {
+		log.Printf("Failed to run reconciliation: %v", err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to run reconciliation",
+			http.StatusBadGateway,
+		)
+	}
+
+	return result, nil
+}
+
+// Run pages through provider payments, subscriptions and refunds created in
+// [from, to) and compares each with its local row. Safe drifts are repaired;
+// the rest are recorded as open discrepancies. A failure on one object is
+// counted and the run continues.
+func (s *ReconciliationService) Run(ctx context.Context, from, to time.Time) (*models.ReconciliationResult, error) {
+	provider, err := s.providerFactory.GetProvider(models.ProviderStripe)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ReconciliationResult{From: from, To: to}
+	checks := []struct {
+		objectType models.ReconciliationObjectType
+		list       func(context.Context, *providers.ListParams) (*providers.ListPage, error)
+		check      func(context.Context, providers.PaymentProvider, string) (*reconciliationCheck, error)
+	}{
+		{models.ReconciliationObjectPayment, provider.ListPayments, s.checkPayment},
+		{models.ReconciliationObjectSubscription, provider.ListSubscriptions, s.checkSubscription},
+		{models.ReconciliationObjectRefund, provider.ListRefunds, s.checkRefund},
+	}
+
+	for _, c := range checks {
+		params := &providers.ListParams{CreatedFrom: from, CreatedTo: to, Limit: reconciliationPageSize}
+		for {
+			page, err := c.list(ctx, params)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list provider %ss: %w", c.objectType, err)
+			}
+
+			for _, id := range page.IDs {
+				check, err := c.check(ctx, provider, id)
+				if err == nil {
+					err = s.recordCheck(ctx, check, result)
+				}
+				if err != nil {
+					log.Printf("Failed to reconcile %s %s: %v", c.objectType, id, err)
+					result.Failed++
+				}
+				result.Checked++
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+			params.StartingAfter = page.NextCursor
+		}
+	}
+
+	s.updateOpenGauge(ctx)
+	middleware.RecordReconciliationRun(time.Now())
+
+	return result, nil
+}
+
+// ListDiscrepancies lists discrepancies, open ones by default
+func (s *ReconciliationService) ListDiscrepancies(ctx context.Context, filter models.DiscrepancyFilter) (*models.DiscrepancyListResponse, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	discrepancies, total, err := s.reconciliationRepo.List(ctx, filter)
+	if err != nil {
+		log.Printf("Failed to list discrepancies: %v", err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve discrepancies",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.DiscrepancyListResponse{
+		Data:   discrepancies,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+// ResolveDiscrepancy closes an open discrepancy after it was handled by hand
+func (s *ReconciliationService) ResolveDiscrepancy(ctx context.Context, id uuid.UUID) error {
+	resolved, err := s.reconciliationRepo.Resolve(ctx, id)
+	if err != nil {
+		log.Printf("Failed to resolve discrepancy %s: %v", id, err)
+		return models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to resolve discrepancy",
+			http.StatusInternalServerError,
+		)
+	}
+	if !resolved {
+		return models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Open discrepancy not found",
+			http.StatusNotFound,
+		)
+	}
+
+	s.updateOpenGauge(ctx)
+	return nil
+}
+
+// reconciliationCheck collects what differs between one provider object and
+// its local row
+type reconciliationCheck struct {
+	objectType       models.ReconciliationObjectType
+	providerObjectID string
+	localID          *uuid.UUID
+	discrepancies    []models.ReconciliationDiscrepancy
+}
+
+func newReconciliationCheck(objectType models.ReconciliationObjectType, providerObjectID string) *reconciliationCheck {
+	return &reconciliationCheck{objectType: objectType, providerObjectID: providerObjectID}
+}
+
+// add notes a difference; repaired ones were already fixed locally
+func (c *reconciliationCheck) add(kind models.DiscrepancyKind, localValue, providerValue string, repaired bool) {
+	status := models.DiscrepancyStatusOpen
+	if repaired {
+		status = models.DiscrepancyStatusRepaired
+	}
+
+	discrepancy := models.ReconciliationDiscrepancy{
+		Provider:         models.ProviderStripe,
+		ObjectType:       c.objectType,
+		ProviderObjectID: c.providerObjectID,
+		LocalID:          c.localID,
+		Kind:             kind,
+		ProviderValue:    &providerValue,
+		Status:           status,
+	}
+	if localValue != "" {
+		discrepancy.LocalValue = &localValue
+	}
+	c.discrepancies = append(c.discrepancies, discrepancy)
+}
+
+// recordCheck stores a check's discrepancies and resolves the object's open
+// discrepancies that are gone
+func (s *ReconciliationService) recordCheck(ctx context.Context, check *reconciliationCheck, result *models.ReconciliationResult) error {
+	var stillOpen []models.DiscrepancyKind
+	for i := range check.discrepancies {
+		discrepancy := &check.discrepancies[i]
+		if err := s.reconciliationRepo.RecordDiscrepancy(ctx, discrepancy); err != nil {
+			return err
+		}
+		middleware.RecordDiscrepancy(string(discrepancy.ObjectType), string(discrepancy.Kind), string(discrepancy.Status))
+
+		if discrepancy.Status == models.DiscrepancyStatusRepaired {
+			result.Repaired++
+		} else {
+			stillOpen = append(stillOpen, discrepancy.Kind)
+			result.Discrepancies++
+		}
+	}
+
+	return s.reconciliationRepo.ResolveObject(ctx, check.objectType, check.providerObjectID, stillOpen)
+}
+
+// checkPayment compares a provider payment with its local row. A payment that
+// is still pending locally takes the provider's status; succeeded payments
+// missing settlement details get them.
+func (s *ReconciliationService) checkPayment(ctx context.Context, provider providers.PaymentProvider, providerPaymentID string) (*reconciliationCheck, error) {
+	remote, err := provider.GetPayment(ctx, providerPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	local, err := s.paymentRepo.GetByProviderPaymentID(ctx, models.ProviderStripe, providerPaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	check := newReconciliationCheck(models.ReconciliationObjectPayment, providerPaymentID)
+	if local == nil {
+		check.add(models.DiscrepancyMissingLocal, "", string(remote.Status), false)
+		return check, nil
+	}
+	check.localID = &local.ID
+
+	if local.Currency != remote.Currency {
+		check.add(models.DiscrepancyCurrencyMismatch, string(local.Currency), string(remote.Currency), false)
+	}
+	// The provider only charged what store credit did not cover
+	if charged := local.ChargedAmount().Amount; charged != remote.Amount {
+		check.add(models.DiscrepancyAmountMismatch, strconv.FormatInt(charged, 10), strconv.FormatInt(remote.Amount, 10), false)
+	}
+
+	if local.Status != remote.Status {
+		previous := local.Status
+		repaired := isPendingPaymentStatus(local.Status)
+		if repaired {
+			if err := s.repairPaymentStatus(ctx, local, remote); err != nil {
+				return nil, err
+			}
+		}
+		check.add(models.DiscrepancyStatusMismatch, string(previous), string(remote.Status), repaired)
+	}
+
+	if local.Status == models.PaymentStatusSucceeded && local.BalanceTransactionID == nil {
+		if err := s.settlementService.SyncPayment(ctx, local); err != nil {
+			return nil, err
+		}
+		if local.BalanceTransactionID != nil {
+			check.add(models.DiscrepancyMissingSettlement, "", *local.BalanceTransactionID, true)
+		}
+	}
+
+	return check, nil
+}
+
+// repairPaymentStatus applies the provider's status to a pending payment and
+// its side effects, as the missed webhook would have
+func (s *ReconciliationService) repairPaymentStatus(ctx context.Context, local, remote *models.Payment) error {
+	local.Status = remote.Status
+	if remote.FailureCode != nil {
+		local.FailureCode = remote.FailureCode
+		local.FailureMessage = remote.FailureMessage
+	}
+	if local.Status == models.PaymentStatusSucceeded && local.CompletedAt == nil {
+		now := time.Now()
+		local.CompletedAt = &now
+	}
+
+	if err := s.paymentRepo.Update(ctx, local); err != nil {
+		return err
+	}
+
+	return s.transitions.apply(ctx, local)
+}
+
+// checkSubscription compares a provider subscription with its local row.
+// Status and billing period follow the provider unless the subscription is
+// canceled locally or in dunning, where our own state takes precedence.
+func (s *ReconciliationService) checkSubscription(ctx context.Context, provider providers.PaymentProvider, providerSubscriptionID string) (*reconciliationCheck, error) {
+	remote, err := provider.GetSubscription(ctx, providerSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	local, err := s.subscriptionRepo.GetByProviderSubscriptionID(ctx, providerSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	check := newReconciliationCheck(models.ReconciliationObjectSubscription, providerSubscriptionID)
+	if local == nil {
+		check.add(models.DiscrepancyMissingLocal, "", string(remote.Status), false)
+		return check, nil
+	}
+	check.localID = &local.ID
+
+	if local.Currency != remote.Currency {
+		check.add(models.DiscrepancyCurrencyMismatch, string(local.Currency), string(remote.Currency), false)
+	}
+	if local.Amount != remote.Amount {
+		check.add(models.DiscrepancyAmountMismatch, strconv.FormatInt(local.Amount, 10), strconv.FormatInt(remote.Amount, 10), false)
+	}
+
+	repairable := local.Status != models.SubscriptionStatusCanceled &&
+		(local.DunningStatus == nil || *local.DunningStatus == models.DunningStatusRecovered)
+	changed := false
+
+	if local.Status != remote.Status {
+		check.add(models.DiscrepancyStatusMismatch, string(local.Status), string(remote.Status), repairable)
+		if repairable {
+			local.Status = remote.Status
+			local.CanceledAt = remote.CanceledAt
+			changed = true
+		}
+	}
+
+	if !local.CurrentPeriodEnd.Truncate(time.Second).Equal(remote.CurrentPeriodEnd.Truncate(time.Second)) {
+		check.add(models.DiscrepancyPeriodMismatch, local.CurrentPeriodEnd.Format(time.RFC3339),
+			remote.CurrentPeriodEnd.Format(time.RFC3339), repairable)
+		if repairable {
+			local.CurrentPeriodStart = remote.CurrentPeriodStart
+			local.CurrentPeriodEnd = remote.CurrentPeriodEnd
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := s.subscriptionRepo.Update(ctx, local); err != nil {
+			return nil, err
+		}
+	}
+
+	return check, nil
+}
+
+// checkRefund compares a provider refund with its local row. A refund still
+// pending locally takes the provider's status.
+func (s *ReconciliationService) checkRefund(ctx context.Context, provider providers.PaymentProvider, providerRefundID string) (*reconciliationCheck, error) {
+	remote, err := provider.GetRefund(ctx, providerRefundID)
+	if err != nil {
+		return nil, err
+	}
+	local, err := s.refundRepo.GetByProviderRefundID(ctx, providerRefundID)
+	if err != nil {
+		return nil, err
+	}
+
+	check := newReconciliationCheck(models.ReconciliationObjectRefund, providerRefundID)
+	if local == nil {
+		check.add(models.DiscrepancyMissingLocal, "", string(remote.Status), false)
+		return check, nil
+	}
+	check.localID = &local.ID
+
+	if local.Currency != remote.Currency {
+		check.add(models.DiscrepancyCurrencyMismatch, string(local.Currency), string(remote.Currency), false)
+	}
+	if local.Amount != remote.Amount {
+		check.add(models.DiscrepancyAmountMismatch, strconv.FormatInt(local.Amount, 10), strconv.FormatInt(remote.Amount, 10), false)
+	}
+
+	payment, err := s.paymentRepo.GetByID(ctx, local.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, fmt.Errorf("payment %s for refund %s not found", local.PaymentID, local.ID)
+	}
+
+	if local.Status != remote.Status {
+		previous := local.Status
+		repaired := local.Status == models.RefundStatusPending || local.Status == models.RefundStatusProcessing
+		if repaired {
+			local.Status = remote.Status
+			if err := s.refundRepo.Update(ctx, local); err != nil {
+				return nil, err
+			}
+			if local.Status == models.RefundStatusSucceeded {
+				if err := s.ledgerService.RecordRefund(ctx, local, payment); err != nil {
+					return nil, err
+				}
+			}
+		}
+		check.add(models.DiscrepancyStatusMismatch, string(previous), string(remote.Status), repaired)
+	}
+
+	if local.Status == models.RefundStatusSucceeded && local.BalanceTransactionID == nil {
+		if err := s.settlementService.SyncRefund(ctx, local, payment); err != nil {
+			return nil, err
+		}
+		if local.BalanceTransactionID != nil {
+			check.add(models.DiscrepancyMissingSettlement, "", *local.BalanceTransactionID, true)
+		}
+	}
+
+	return check, nil
+}
+
+// updateOpenGauge refreshes the open discrepancy gauge from the database
+func (s *ReconciliationService) updateOpenGauge(ctx context.Context) {
+	counts, err := s.reconciliationRepo.CountOpen(ctx)
+	if err != nil {
+		log.Printf("Failed to count open discrepancies: %v", err)
+		return
+	}
+
+	for _, objectType := range []models.ReconciliationObjectType{
+		models.ReconciliationObjectPayment,
+		models.ReconciliationObjectSubscription,
+		models.ReconciliationObjectRefund,
+	} {
+		middleware.UpdateOpenDiscrepancies(string(objectType), counts[objectType])
+	}
+}
+
+// isPendingPaymentStatus reports whether a payment has not reached a final
+// status yet
+func isPendingPaymentStatus(status models.PaymentStatus) bool {
+	switch status {
+	case models.PaymentStatusPending, models.PaymentStatusProcessing, models.PaymentStatusRequiresAction:
+		return true
+	}
+	return false
+}
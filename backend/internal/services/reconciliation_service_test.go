@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReconciliationRepository is a mock for ReconciliationRepository
+type MockReconciliationRepository struct {
+	mock.Mock
+}
+
+func (m *MockReconciliationRepository) RecordDiscrepancy(ctx context.Context, discrepancy *models.ReconciliationDiscrepancy) error {
+	args := m.Called(ctx, discrepancy)
+	return args.Error(0)
+}
+
+func (m *MockReconciliationRepository) ResolveObject(ctx context.Context, objectType models.ReconciliationObjectType, providerObjectID string, stillOpen []models.DiscrepancyKind) error {
+	args := m.Called(ctx, objectType, providerObjectID, stillOpen)
+	return args.Error(0)
+}
+
+func (m *MockReconciliationRepository) Resolve(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockReconciliationRepository) List(ctx context.Context, filter models.DiscrepancyFilter) ([]models.ReconciliationDiscrepancy, int, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.ReconciliationDiscrepancy), args.Int(1), args.Error(2)
+}
+
+func (m *MockReconciliationRepository) CountOpen(ctx context.Context) (map[models.ReconciliationObjectType]int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[models.ReconciliationObjectType]int), args.Error(1)
+}
+
+// setupPaymentReconciliation lists a single provider payment and nothing else
+func setupPaymentReconciliation(ctx context.Context, providerPaymentID string) (*MockPaymentProvider, *MockProviderFactory) {
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("ListPayments", ctx, mock.AnythingOfType("*providers.ListParams")).
+		Return(&providers.ListPage{IDs: []string{providerPaymentID}}, nil)
+	mockProvider.On("ListSubscriptions", ctx, mock.AnythingOfType("*providers.ListParams")).
+		Return(&providers.ListPage{}, nil)
+	mockProvider.On("ListRefunds", ctx, mock.AnythingOfType("*providers.ListParams")).
+		Return(&providers.ListPage{}, nil)
+
+	return mockProvider, mockFactory
+}
+
+func TestReconciliationService_Run_RepairsPendingPayment(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockReconciliationRepo := new(MockReconciliationRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	mockProvider, mockFactory := setupPaymentReconciliation(ctx, "pi_test123")
+
+	ledgerService := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+	settlementService := NewSettlementService(mockPaymentRepo, nil, ledgerService, mockFactory)
+	walletService := NewWalletService(new(MockWalletRepository), nil, nil, nil, ledgerService)
+	balanceService := NewCustomerBalanceService(new(MockCustomerBalanceRepository), nil, ledgerService, mockFactory)
+	service := NewReconciliationService(mockReconciliationRepo, mockPaymentRepo, nil, nil, ledgerService, settlementService, balanceService, walletService, mockFactory, ReconciliationConfig{Window: 48 * time.Hour})
+
+	local := &models.Payment{
+		ID:                uuid.New(),
+		CustomerID:        uuid.New(),
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            10000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusProcessing,
+	}
+	remote := &models.Payment{
+		ProviderPaymentID: "pi_test123",
+		Amount:            10000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusSucceeded,
+	}
+
+	mockProvider.On("GetPayment", ctx, "pi_test123").Return(remote, nil)
+	mockPaymentRepo.On("GetByProviderPaymentID", ctx, models.ProviderStripe, "pi_test123").Return(local, nil)
+	mockPaymentRepo.On("Update", ctx, mock.MatchedBy(func(payment *models.Payment) bool {
+		return payment.Status == models.PaymentStatusSucceeded && payment.CompletedAt != nil
+	})).Return(nil)
+	mockLedgerRepo.On("CreateEntry", ctx, mock.MatchedBy(func(entry *models.LedgerEntry) bool {
+		return entry.Type == models.LedgerEntryCharge && entry.ReferenceID == local.ID
+	})).Return(true, nil)
+	mockProvider.On("GetPaymentBalanceTransaction", ctx, "pi_test123").Return(nil, nil)
+	mockReconciliationRepo.On("RecordDiscrepancy", ctx, mock.MatchedBy(func(d *models.ReconciliationDiscrepancy) bool {
+		return d.Kind == models.DiscrepancyStatusMismatch && d.Status == models.DiscrepancyStatusRepaired &&
+			*d.LocalValue == "processing" && *d.ProviderValue == "succeeded" && *d.LocalID == local.ID
+	})).Return(nil)
+	mockReconciliationRepo.On("ResolveObject", ctx, models.ReconciliationObjectPayment, "pi_test123", []models.DiscrepancyKind(nil)).Return(nil)
+	mockReconciliationRepo.On("CountOpen", ctx).Return(map[models.ReconciliationObjectType]int{}, nil)
+
+	// Execute
+	result, err := service.Run(ctx, time.Now().Add(-48*time.Hour), time.Now())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Checked)
+	assert.Equal(t, 1, result.Repaired)
+	assert.Equal(t, 0, result.Discrepancies)
+	assert.Equal(t, 0, result.Failed)
+
+	mockPaymentRepo.AssertExpectations(t)
+	mockLedgerRepo.AssertExpectations(t)
+	mockReconciliationRepo.AssertExpectations(t)
+}
+
+func TestReconciliationService_Run_RepairsPendingTopUp(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockReconciliationRepo := new(MockReconciliationRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	mockWalletRepo := new(MockWalletRepository)
+	mockProvider, mockFactory := setupPaymentReconciliation(ctx, "pi_test123")
+
+	ledgerService := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+	settlementService := NewSettlementService(mockPaymentRepo, nil, ledgerService, mockFactory)
+	walletService := NewWalletService(mockWalletRepo, nil, nil, nil, ledgerService)
+	balanceService := NewCustomerBalanceService(new(MockCustomerBalanceRepository), nil, ledgerService, mockFactory)
+	service := NewReconciliationService(mockReconciliationRepo, mockPaymentRepo, nil, nil, ledgerService, settlementService, balanceService, walletService, mockFactory, ReconciliationConfig{Window: 48 * time.Hour})
+
+	local := &models.Payment{
+		ID:                uuid.New(),
+		CustomerID:        uuid.New(),
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            10000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusPending,
+		Metadata:          models.JSONBMap{walletTopUpMetadataKey: true},
+	}
+	remote := &models.Payment{
+		ProviderPaymentID: "pi_test123",
+		Amount:            10000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusSucceeded,
+	}
+
+	mockProvider.On("GetPayment", ctx, "pi_test123").Return(remote, nil)
+	mockPaymentRepo.On("GetByProviderPaymentID", ctx, models.ProviderStripe, "pi_test123").Return(local, nil)
+	mockPaymentRepo.On("Update", ctx, mock.MatchedBy(func(payment *models.Payment) bool {
+		return payment.Status == models.PaymentStatusSucceeded
+	})).Return(nil)
+	mockLedgerRepo.On("CreateEntry", ctx, mock.MatchedBy(func(entry *models.LedgerEntry) bool {
+		return entry.Type == models.LedgerEntryCharge && entry.ReferenceID == local.ID
+	})).Return(true, nil)
+	mockProvider.On("GetPaymentBalanceTransaction", ctx, "pi_test123").Return(nil, nil)
+	mockWalletRepo.On("Credit", ctx, mock.MatchedBy(func(wallet *models.Wallet) bool {
+		return wallet.CustomerID == local.CustomerID && wallet.Currency == models.CurrencySEK
+	}), mock.MatchedBy(func(txn *models.WalletTransaction) bool {
+		return txn.Type == models.WalletTransactionTopUp && txn.Amount == 10000 && *txn.PaymentID == local.ID
+	}), (*models.WalletGrant)(nil)).Return(true, nil)
+	mockLedgerRepo.On("CreateEntry", ctx, mock.MatchedBy(func(entry *models.LedgerEntry) bool {
+		return entry.ReferenceType == "wallet_transaction"
+	})).Return(true, nil)
+	mockReconciliationRepo.On("RecordDiscrepancy", ctx, mock.MatchedBy(func(d *models.ReconciliationDiscrepancy) bool {
+		return d.Kind == models.DiscrepancyStatusMismatch && d.Status == models.DiscrepancyStatusRepaired
+	})).Return(nil)
+	mockReconciliationRepo.On("ResolveObject", ctx, models.ReconciliationObjectPayment, "pi_test123", []models.DiscrepancyKind(nil)).Return(nil)
+	mockReconciliationRepo.On("CountOpen", ctx).Return(map[models.ReconciliationObjectType]int{}, nil)
+
+	// Execute
+	result, err := service.Run(ctx, time.Now().Add(-48*time.Hour), time.Now())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Repaired)
+	assert.Equal(t, 0, result.Failed)
+
+	mockPaymentRepo.AssertExpectations(t)
+	mockWalletRepo.AssertExpectations(t)
+	mockReconciliationRepo.AssertExpectations(t)
+}
+
+func TestReconciliationService_Run_ReportsAmountMismatch(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockReconciliationRepo := new(MockReconciliationRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockProvider, mockFactory := setupPaymentReconciliation(ctx, "pi_test123")
+	service := NewReconciliationService(mockReconciliationRepo, mockPaymentRepo, nil, nil, nil, nil, nil, nil, mockFactory, ReconciliationConfig{})
+
+	transactionID := "txn_test123"
+	local := &models.Payment{
+		ID:                   uuid.New(),
+		Provider:             models.ProviderStripe,
+		ProviderPaymentID:    "pi_test123",
+		Amount:               10000,
+		Currency:             models.CurrencySEK,
+		Status:               models.PaymentStatusSucceeded,
+		BalanceTransactionID: &transactionID,
+	}
+	remote := &models.Payment{
+		ProviderPaymentID: "pi_test123",
+		Amount:            12000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusSucceeded,
+	}
+
+	mockProvider.On("GetPayment", ctx, "pi_test123").Return(remote, nil)
+	mockPaymentRepo.On("GetByProviderPaymentID", ctx, models.ProviderStripe, "pi_test123").Return(local, nil)
+	mockReconciliationRepo.On("RecordDiscrepancy", ctx, mock.MatchedBy(func(d *models.ReconciliationDiscrepancy) bool {
+		return d.Kind == models.DiscrepancyAmountMismatch && d.Status == models.DiscrepancyStatusOpen &&
+			*d.LocalValue == "10000" && *d.ProviderValue == "12000"
+	})).Return(nil)
+	mockReconciliationRepo.On("ResolveObject", ctx, models.ReconciliationObjectPayment, "pi_test123",
+		[]models.DiscrepancyKind{models.DiscrepancyAmountMismatch}).Return(nil)
+	mockReconciliationRepo.On("CountOpen", ctx).Return(map[models.ReconciliationObjectType]int{models.ReconciliationObjectPayment: 1}, nil)
+
+	// Execute
+	result, err := service.Run(ctx, time.Now().Add(-48*time.Hour), time.Now())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Discrepancies)
+	assert.Equal(t, 0, result.Repaired)
+
+	mockPaymentRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	mockReconciliationRepo.AssertExpectations(t)
+}
+
+func TestReconciliationService_Run_ReportsMissingLocalPayment(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockReconciliationRepo := new(MockReconciliationRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockProvider, mockFactory := setupPaymentReconciliation(ctx, "pi_test123")
+	service := NewReconciliationService(mockReconciliationRepo, mockPaymentRepo, nil, nil, nil, nil, nil, nil, mockFactory, ReconciliationConfig{})
+
+	mockProvider.On("GetPayment", ctx, "pi_test123").Return(&models.Payment{Status: models.PaymentStatusSucceeded}, nil)
+	mockPaymentRepo.On("GetByProviderPaymentID", ctx, models.ProviderStripe, "pi_test123").Return(nil, nil)
+	mockReconciliationRepo.On("RecordDiscrepancy", ctx, mock.MatchedBy(func(d *models.ReconciliationDiscrepancy) bool {
+		return d.Kind == models.DiscrepancyMissingLocal && d.LocalID == nil && d.Status == models.DiscrepancyStatusOpen
+	})).Return(nil)
+	mockReconciliationRepo.On("ResolveObject", ctx, models.ReconciliationObjectPayment, "pi_test123",
+		[]models.DiscrepancyKind{models.DiscrepancyMissingLocal}).Return(nil)
+	mockReconciliationRepo.On("CountOpen", ctx).Return(map[models.ReconciliationObjectType]int{}, nil)
+
+	// Execute
+	result, err := service.Run(ctx, time.Now().Add(-48*time.Hour), time.Now())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Discrepancies)
+	mockReconciliationRepo.AssertExpectations(t)
+}
@@ -3,52 +3,98 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"payment-service/internal/models"
 	"payment-service/internal/providers"
 	"payment-service/internal/repository"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// RefundApprovalPolicy decides which refunds need a second person's approval
+// before they are sent to the provider. Zero values turn a rule off.
+type RefundApprovalPolicy struct {
+	// Refunds over this amount, in the payment currency's minor unit
+	AmountThreshold int64
+	// Refunds of payments older than this
+	PaymentAge time.Duration
+	// Refunds requested by users with one of these roles
+	Roles []string
+}
+
+// approvalReasons lists the rules a refund matches. A refund matching none
+// is sent to the provider right away.
+func (p RefundApprovalPolicy) approvalReasons(payment *models.Payment, amount int64, role string, now time.Time) []string {
+	var reasons []string
+	if p.AmountThreshold > 0 && amount > p.AmountThreshold {
+		reasons = append(reasons, fmt.Sprintf("amount %d is over the approval threshold of %d", amount, p.AmountThreshold))
+	}
+	if p.PaymentAge > 0 && now.Sub(payment.CreatedAt) > p.PaymentAge {
+		reasons = append(reasons, fmt.Sprintf("payment is older than %s", p.PaymentAge))
+	}
+	if role != "" && slices.Contains(p.Roles, role) {
+		reasons = append(reasons, fmt.Sprintf("requested by %s", role))
+	}
+	return reasons
+}
+
 type RefundService struct {
-	refundRepo       repository.RefundRepositoryInterface
-	paymentRepo      repository.PaymentRepositoryInterface
-	customerRepo     repository.CustomerRepositoryInterface
-	providerFactory  ProviderFactoryInterface
+	refundRepo        repository.RefundRepositoryInterface
+	paymentRepo       repository.PaymentRepositoryInterface
+	customerRepo      repository.CustomerRepositoryInterface
+	auditRepo         repository.AuditRepositoryInterface
+	ledgerService     *LedgerService
+	settlementService *SettlementService
+	balanceService    *CustomerBalanceService
+	providerFactory   ProviderFactoryInterface
+	approvalPolicy    RefundApprovalPolicy
 }
 
 func NewRefundService(
 	refundRepo repository.RefundRepositoryInterface,
 	paymentRepo repository.PaymentRepositoryInterface,
 	customerRepo repository.CustomerRepositoryInterface,
+	auditRepo repository.AuditRepositoryInterface,
+	ledgerService *LedgerService,
+	settlementService *SettlementService,
+	balanceService *CustomerBalanceService,
 	providerFactory ProviderFactoryInterface,
+	approvalPolicy RefundApprovalPolicy,
 ) *RefundService {
 	return &RefundService{
-		refundRepo:      refundRepo,
-		paymentRepo:     paymentRepo,
-		customerRepo:    customerRepo,
-		providerFactory: providerFactory,
+		refundRepo:        refundRepo,
+		paymentRepo:       paymentRepo,
+		customerRepo:      customerRepo,
+		auditRepo:         auditRepo,
+		ledgerService:     ledgerService,
+		settlementService: settlementService,
+		balanceService:    balanceService,
+		providerFactory:   providerFactory,
+		approvalPolicy:    approvalPolicy,
 	}
 }
 
-// CreateRefund creates a new refund
+// CreateRefund creates a new refund of the user's own payment. Refunds matching
+// the approval policy are returned in pending_approval instead.
 func (s *RefundService) CreateRefund(
 	ctx context.Context,
 	userID uuid.UUID,
+	role string,
 	req *models.CreateRefundRequest,
 ) (*models.Refund, error) {
 	// Get payment and verify ownership
-	payment, err := s.paymentRepo.GetByID(ctx, req.PaymentID)
+	payment, err := s.getPayment(ctx, req.PaymentID)
 	if err != nil {
-		return nil, models.NewAPIError(
-			models.ErrCodeProviderError,
-			"Failed to retrieve payment",
-			http.StatusInternalServerError,
-		)
+		return nil, err
 	}
 
-	if payment == nil {
+	// Verify customer owns this payment
+	customer, err := s.customerRepo.GetByID(ctx, payment.CustomerID)
+	if err != nil || customer == nil || customer.UserID != userID {
 		return nil, models.NewAPIError(
 			models.ErrCodeNotFound,
 			"Payment not found",
@@ -56,9 +102,35 @@ func (s *RefundService) CreateRefund(
 		)
 	}
 
-	// Verify customer owns this payment
-	customer, err := s.customerRepo.GetByID(ctx, payment.CustomerID)
-	if err != nil || customer == nil || customer.UserID != userID {
+	return s.createRefund(ctx, payment, userID, role, req)
+}
+
+// CreateSupportRefund creates a refund of any payment on behalf of its customer
+func (s *RefundService) CreateSupportRefund(
+	ctx context.Context,
+	userID uuid.UUID,
+	role string,
+	req *models.CreateRefundRequest,
+) (*models.Refund, error) {
+	payment, err := s.getPayment(ctx, req.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createRefund(ctx, payment, userID, role, req)
+}
+
+func (s *RefundService) getPayment(ctx context.Context, paymentID uuid.UUID) (*models.Payment, error) {
+	payment, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve payment",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if payment == nil {
 		return nil, models.NewAPIError(
 			models.ErrCodeNotFound,
 			"Payment not found",
@@ -66,6 +138,16 @@ func (s *RefundService) CreateRefund(
 		)
 	}
 
+	return payment, nil
+}
+
+func (s *RefundService) createRefund(
+	ctx context.Context,
+	payment *models.Payment,
+	userID uuid.UUID,
+	role string,
+	req *models.CreateRefundRequest,
+) (*models.Refund, error) {
 	// Check payment is refundable
 	if payment.Status != models.PaymentStatusSucceeded {
 		return nil, models.NewAPIError(
@@ -75,6 +157,16 @@ func (s *RefundService) CreateRefund(
 		)
 	}
 
+	// A disputed payment is settled through the dispute; refunding it too
+	// could return the money twice
+	if payment.DisputedAt != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Disputed payments cannot be refunded",
+			http.StatusConflict,
+		)
+	}
+
 	// Validate refund amount
 	if req.Amount <= 0 {
 		return nil, models.NewAPIError(
@@ -84,7 +176,7 @@ func (s *RefundService) CreateRefund(
 		)
 	}
 
-	if req.Amount > payment.Amount {
+	if req.Amount > payment.ChargedAmount().Amount {
 		return nil, models.NewAPIError(
 			models.ErrCodePaymentFailed,
 			"Refund amount cannot exceed payment amount",
@@ -92,8 +184,23 @@ func (s *RefundService) CreateRefund(
 		)
 	}
 
-	// Check if payment has already been fully refunded
-	existingRefunds, err := s.refundRepo.ListByPayment(ctx, payment.ID)
+	switch req.Destination {
+	case "":
+		req.Destination = models.RefundDestinationPaymentMethod
+	case models.RefundDestinationPaymentMethod, models.RefundDestinationCustomerBalance:
+	default:
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Refund destination must be payment_method or customer_balance",
+			http.StatusBadRequest,
+		)
+	}
+
+	// Reserve the amount first so concurrent or still pending refunds
+	// cannot take the payment over its amount. Refunds are always in the
+	// payment's currency.
+	amount := models.NewMoney(req.Amount, payment.Currency)
+	reserved, err := s.paymentRepo.ReserveRefund(ctx, payment, amount)
 	if err != nil {
 		return nil, models.NewAPIError(
 			models.ErrCodeProviderError,
@@ -102,22 +209,396 @@ func (s *RefundService) CreateRefund(
 		)
 	}
 
-	var totalRefunded int64
-	for _, refund := range existingRefunds {
-		if refund.Status == models.RefundStatusSucceeded {
-			totalRefunded += refund.Amount
+	if !reserved {
+		// ReserveRefund read the refunded and pending amounts again, so the
+		// refundable amount includes concurrent reservations
+		return nil, models.NewAPIError(
+			models.ErrCodePaymentFailed,
+			fmt.Sprintf("Cannot refund more than remaining amount. Refundable: %s, Attempting: %s, Total: %s",
+				payment.RefundableAmount(), amount, payment.ChargedAmount()),
+			http.StatusBadRequest,
+		)
+	}
+
+	if reasons := s.approvalPolicy.approvalReasons(payment, req.Amount, role, time.Now()); len(reasons) > 0 {
+		return s.requestApproval(ctx, payment, userID, req, strings.Join(reasons, "; "))
+	}
+
+	if req.Destination == models.RefundDestinationCustomerBalance {
+		return s.createCreditRefund(ctx, payment, userID, req)
+	}
+
+	providerRefund, err := s.sendRefund(ctx, payment, req.Amount, req.Reason, req.Metadata)
+	if err != nil {
+		s.releaseRefund(ctx, payment, amount)
+		return nil, err
+	}
+
+	// Save refund to database
+	providerRefund.PaymentID = payment.ID
+	providerRefund.Destination = models.RefundDestinationPaymentMethod
+	if req.Notes != "" {
+		providerRefund.Notes = &req.Notes
+	}
+	providerRefund.Metadata = req.Metadata
+	providerRefund.RequestedBy = &userID
+
+	// The reservation is kept if saving fails, since the provider has
+	// already accepted the refund
+	if err := s.refundRepo.Create(ctx, providerRefund); err != nil {
+		log.Printf("Failed to save refund %s for payment %s: %v", providerRefund.ProviderRefundID, payment.ID, err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to save refund to database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	s.bookRefund(ctx, providerRefund, payment)
+
+	return providerRefund, nil
+}
+
+// createCreditRefund saves a refund to the customer's balance and credits it
+func (s *RefundService) createCreditRefund(
+	ctx context.Context,
+	payment *models.Payment,
+	userID uuid.UUID,
+	req *models.CreateRefundRequest,
+) (*models.Refund, error) {
+	refund := &models.Refund{
+		PaymentID:   payment.ID,
+		Provider:    payment.Provider,
+		Amount:      req.Amount,
+		Currency:    payment.Currency,
+		Status:      models.RefundStatusPending,
+		Destination: models.RefundDestinationCustomerBalance,
+		RequestedBy: &userID,
+		Metadata:    req.Metadata,
+	}
+	if req.Reason != "" {
+		refund.Reason = &req.Reason
+	}
+	if req.Notes != "" {
+		refund.Notes = &req.Notes
+	}
+
+	if err := s.refundRepo.Create(ctx, refund); err != nil {
+		log.Printf("Failed to save credit refund for payment %s: %v", payment.ID, err)
+		s.releaseRefund(ctx, payment, refund.Money())
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to save refund to database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if err := s.issueCredit(ctx, refund, payment); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// issueCredit credits a saved, pending refund to the customer's balance and
+// marks it succeeded. If crediting fails, the refund fails and its amount is
+// released.
+func (s *RefundService) issueCredit(ctx context.Context, refund *models.Refund, payment *models.Payment) error {
+	if err := s.balanceService.CreditRefund(ctx, refund, payment); err != nil {
+		log.Printf("Failed to credit refund %s to customer balance: %v", refund.ID, err)
+		refund.Status = models.RefundStatusFailed
+		if updateErr := s.refundRepo.Update(ctx, refund); updateErr != nil {
+			log.Printf("Failed to mark credit refund %s as failed: %v", refund.ID, updateErr)
 		}
+		return models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to credit customer balance",
+			http.StatusInternalServerError,
+		)
+	}
+
+	refund.Status = models.RefundStatusSucceeded
+	if err := s.refundRepo.Update(ctx, refund); err != nil {
+		log.Printf("Failed to save credited refund %s: %v", refund.ID, err)
+		return models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to save refund to database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return nil
+}
+
+// requestApproval saves a refund that waits for approval. Its amount stays
+// reserved until it is approved and sent, or rejected.
+func (s *RefundService) requestApproval(
+	ctx context.Context,
+	payment *models.Payment,
+	userID uuid.UUID,
+	req *models.CreateRefundRequest,
+	approvalReason string,
+) (*models.Refund, error) {
+	refund := &models.Refund{
+		PaymentID:      payment.ID,
+		Provider:       payment.Provider,
+		Amount:         req.Amount,
+		Currency:       payment.Currency,
+		Status:         models.RefundStatusPendingApproval,
+		Destination:    req.Destination,
+		RequestedBy:    &userID,
+		ApprovalReason: &approvalReason,
+		Metadata:       req.Metadata,
+	}
+	if req.Reason != "" {
+		refund.Reason = &req.Reason
+	}
+	if req.Notes != "" {
+		refund.Notes = &req.Notes
 	}
 
-	if totalRefunded+req.Amount > payment.Amount {
+	if err := s.refundRepo.Create(ctx, refund); err != nil {
+		log.Printf("Failed to save refund awaiting approval for payment %s: %v", payment.ID, err)
+		s.releaseRefund(ctx, payment, refund.Money())
 		return nil, models.NewAPIError(
-			models.ErrCodePaymentFailed,
-			fmt.Sprintf("Cannot refund more than remaining amount. Already refunded: %d, Attempting: %d, Total: %d",
-				totalRefunded, req.Amount, payment.Amount),
-			http.StatusBadRequest,
+			models.ErrCodeProviderError,
+			"Failed to save refund to database",
+			http.StatusInternalServerError,
 		)
 	}
 
+	log.Printf("Refund %s of %d on payment %s needs approval: %s", refund.ID, refund.Amount, payment.ID, approvalReason)
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		CustomerID:   &payment.CustomerID,
+		ActorUserID:  &userID,
+		Action:       models.AuditActionRefundApprovalRequested,
+		ResourceType: "refund",
+		ResourceID:   refund.ID,
+		Details: models.JSONBMap{
+			"payment_id":      payment.ID,
+			"amount":          refund.Amount,
+			"approval_reason": approvalReason,
+		},
+	})
+
+	return refund, nil
+}
+
+// ListPendingApprovals lists refunds waiting for approval, oldest first
+func (s *RefundService) ListPendingApprovals(ctx context.Context, limit, offset int) (*models.RefundListResponse, error) {
+	refunds, total, err := s.refundRepo.ListByStatus(ctx, models.RefundStatusPendingApproval, limit, offset)
+	if err != nil {
+		log.Printf("Failed to list refunds pending approval: %v", err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list refunds",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.RefundListResponse{
+		Data:   refunds,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// ApproveRefund approves a refund waiting for approval and sends it to the
+// provider, or credits the customer's balance. If that fails, the refund fails and its amount is
+// released.
+func (s *RefundService) ApproveRefund(
+	ctx context.Context,
+	refundID uuid.UUID,
+	reviewerID uuid.UUID,
+	req *models.ReviewRefundRequest,
+) (*models.Refund, error) {
+	refund, err := s.getRefundForReview(ctx, refundID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := s.getPayment(ctx, refund.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The payment may have been disputed while the refund waited
+	if payment.DisputedAt != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Disputed payments cannot be refunded; reject the refund instead",
+			http.StatusConflict,
+		)
+	}
+
+	// Claim the refund before calling the provider so two approvals cannot
+	// both send it
+	refund.Status = models.RefundStatusPending
+	if err := s.review(ctx, refund, reviewerID, req); err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		CustomerID:   &payment.CustomerID,
+		ActorUserID:  &reviewerID,
+		Action:       models.AuditActionRefundApproved,
+		ResourceType: "refund",
+		ResourceID:   refund.ID,
+		Details: models.JSONBMap{
+			"payment_id": payment.ID,
+			"amount":     refund.Amount,
+			"note":       req.Note,
+		},
+	})
+
+	if refund.Destination == models.RefundDestinationCustomerBalance {
+		if err := s.issueCredit(ctx, refund, payment); err != nil {
+			return nil, err
+		}
+		return refund, nil
+	}
+
+	var reason string
+	if refund.Reason != nil {
+		reason = *refund.Reason
+	}
+	providerRefund, err := s.sendRefund(ctx, payment, refund.Amount, reason, refund.Metadata)
+	if err != nil {
+		refund.Status = models.RefundStatusFailed
+		if updateErr := s.refundRepo.Update(ctx, refund); updateErr != nil {
+			log.Printf("Failed to mark approved refund %s as failed: %v", refund.ID, updateErr)
+		}
+		return nil, err
+	}
+
+	refund.ProviderRefundID = providerRefund.ProviderRefundID
+	refund.Status = providerRefund.Status
+	if err := s.refundRepo.Update(ctx, refund); err != nil {
+		log.Printf("Failed to save approved refund %s (%s): %v", refund.ID, refund.ProviderRefundID, err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to save refund to database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	s.bookRefund(ctx, refund, payment)
+
+	return refund, nil
+}
+
+// RejectRefund rejects a refund waiting for approval and releases its amount
+func (s *RefundService) RejectRefund(
+	ctx context.Context,
+	refundID uuid.UUID,
+	reviewerID uuid.UUID,
+	req *models.ReviewRefundRequest,
+) (*models.Refund, error) {
+	refund, err := s.getRefundForReview(ctx, refundID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	refund.Status = models.RefundStatusRejected
+	if err := s.review(ctx, refund, reviewerID, req); err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		ActorUserID:  &reviewerID,
+		Action:       models.AuditActionRefundRejected,
+		ResourceType: "refund",
+		ResourceID:   refund.ID,
+		Details: models.JSONBMap{
+			"payment_id": refund.PaymentID,
+			"amount":     refund.Amount,
+			"note":       req.Note,
+		},
+	})
+
+	return refund, nil
+}
+
+// getRefundForReview loads a refund waiting for approval and checks the
+// reviewer did not request it
+func (s *RefundService) getRefundForReview(ctx context.Context, refundID, reviewerID uuid.UUID) (*models.Refund, error) {
+	refund, err := s.refundRepo.GetByID(ctx, refundID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve refund",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if refund == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Refund not found",
+			http.StatusNotFound,
+		)
+	}
+
+	if refund.Status != models.RefundStatusPendingApproval {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Refund is %s, not waiting for approval", refund.Status),
+			http.StatusConflict,
+		)
+	}
+
+	if refund.RequestedBy != nil && *refund.RequestedBy == reviewerID {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Refunds must be reviewed by someone other than the requester",
+			http.StatusForbidden,
+		)
+	}
+
+	return refund, nil
+}
+
+// review records the reviewer's decision, failing if someone else reviewed
+// the refund first
+func (s *RefundService) review(ctx context.Context, refund *models.Refund, reviewerID uuid.UUID, req *models.ReviewRefundRequest) error {
+	now := time.Now()
+	refund.ReviewedBy = &reviewerID
+	refund.ReviewedAt = &now
+	if req.Note != "" {
+		refund.ReviewNote = &req.Note
+	}
+
+	reviewed, err := s.refundRepo.Review(ctx, refund)
+	if err != nil {
+		log.Printf("Failed to review refund %s: %v", refund.ID, err)
+		return models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to review refund",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if !reviewed {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Refund has already been reviewed",
+			http.StatusConflict,
+		)
+	}
+
+	return nil
+}
+
+// sendRefund creates the refund with the provider. The caller releases the
+// reservation if it fails.
+func (s *RefundService) sendRefund(
+	ctx context.Context,
+	payment *models.Payment,
+	amount int64,
+	reason string,
+	metadata map[string]any,
+) (*models.Refund, error) {
 	// Get provider
 	provider, err := s.providerFactory.GetProvider(payment.Provider)
 	if err != nil {
@@ -131,9 +612,9 @@ func (s *RefundService) CreateRefund(
 	// Create refund with provider
 	providerReq := &providers.CreateRefundRequest{
 		PaymentID: payment.ProviderPaymentID,
-		Amount:    req.Amount,
-		Reason:    req.Reason,
-		Metadata:  convertMetadataToStrings(req.Metadata),
+		Amount:    amount,
+		Reason:    reason,
+		Metadata:  convertMetadataToStrings(metadata),
 	}
 
 	providerRefund, err := provider.CreateRefund(ctx, providerReq)
@@ -145,22 +626,27 @@ func (s *RefundService) CreateRefund(
 		)
 	}
 
-	// Save refund to database
-	providerRefund.PaymentID = payment.ID
-	if req.Notes != "" {
-		providerRefund.Notes = &req.Notes
-	}
-	providerRefund.Metadata = req.Metadata
+	return providerRefund, nil
+}
 
-	if err := s.refundRepo.Create(ctx, providerRefund); err != nil {
-		return nil, models.NewAPIError(
-			models.ErrCodeProviderError,
-			"Failed to save refund to database",
-			http.StatusInternalServerError,
-		)
+// bookRefund records a succeeded refund in the ledger and syncs its settlement
+func (s *RefundService) bookRefund(ctx context.Context, refund *models.Refund, payment *models.Payment) {
+	if refund.Status != models.RefundStatusSucceeded {
+		return
+	}
+	if err := s.ledgerService.RecordRefund(ctx, refund, payment); err != nil {
+		log.Printf("Failed to record refund %s in ledger: %v", refund.ID, err)
 	}
+	if err := s.settlementService.SyncRefund(ctx, refund, payment); err != nil {
+		log.Printf("Failed to sync settlement for refund %s: %v", refund.ID, err)
+	}
+}
 
-	return providerRefund, nil
+// releaseRefund releases a reservation for a refund the provider did not create
+func (s *RefundService) releaseRefund(ctx context.Context, payment *models.Payment, amount models.Money) {
+	if err := s.paymentRepo.ReleaseRefund(ctx, payment, amount); err != nil {
+		log.Printf("Failed to release refund reservation of %s on payment %s: %v", amount, payment.ID, err)
+	}
 }
 
 // GetRefund retrieves a refund by ID
@@ -0,0 +1,414 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRefundRepository is a mock for RefundRepository
+type MockRefundRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefundRepository) Create(ctx context.Context, refund *models.Refund) error {
+	args := m.Called(ctx, refund)
+	return args.Error(0)
+}
+
+func (m *MockRefundRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Refund, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Refund), args.Error(1)
+}
+
+func (m *MockRefundRepository) GetByProviderRefundID(ctx context.Context, providerRefundID string) (*models.Refund, error) {
+	args := m.Called(ctx, providerRefundID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Refund), args.Error(1)
+}
+
+func (m *MockRefundRepository) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]models.Refund, error) {
+	args := m.Called(ctx, paymentID)
+	return args.Get(0).([]models.Refund), args.Error(1)
+}
+
+func (m *MockRefundRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Refund, int, error) {
+	args := m.Called(ctx, customerID, limit, offset)
+	return args.Get(0).([]models.Refund), args.Int(1), args.Error(2)
+}
+
+func (m *MockRefundRepository) ListByStatus(ctx context.Context, status models.RefundStatus, limit, offset int) ([]models.Refund, int, error) {
+	args := m.Called(ctx, status, limit, offset)
+	return args.Get(0).([]models.Refund), args.Int(1), args.Error(2)
+}
+
+func (m *MockRefundRepository) Update(ctx context.Context, refund *models.Refund) error {
+	args := m.Called(ctx, refund)
+	return args.Error(0)
+}
+
+func (m *MockRefundRepository) Review(ctx context.Context, refund *models.Refund) (bool, error) {
+	args := m.Called(ctx, refund)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRefundRepository) UpdateSettlement(ctx context.Context, refund *models.Refund) error {
+	args := m.Called(ctx, refund)
+	return args.Error(0)
+}
+
+func TestRefundService_CreateRefund_RejectsDisputedPayment(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockFactory := new(MockProviderFactory)
+	service := NewRefundService(nil, mockPaymentRepo, mockCustomerRepo, nil, nil, nil, nil, mockFactory, RefundApprovalPolicy{})
+
+	userID := uuid.New()
+	disputedAt := time.Now()
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	payment := &models.Payment{
+		ID:         uuid.New(),
+		CustomerID: customer.ID,
+		Amount:     10000,
+		Currency:   models.CurrencySEK,
+		Status:     models.PaymentStatusSucceeded,
+		DisputedAt: &disputedAt,
+	}
+
+	mockPaymentRepo.On("GetByID", ctx, payment.ID).Return(payment, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+
+	// Execute
+	refund, err := service.CreateRefund(ctx, userID, "", &models.CreateRefundRequest{PaymentID: payment.ID, Amount: 5000})
+
+	// Assert
+	assert.Nil(t, refund)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+}
+
+func TestRefundService_CreateRefund_ReservesAmount(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockRefundRepo := new(MockRefundRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewRefundService(mockRefundRepo, mockPaymentRepo, mockCustomerRepo, nil, nil, nil, nil, mockFactory, RefundApprovalPolicy{})
+
+	userID := uuid.New()
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	payment := &models.Payment{
+		ID:                uuid.New(),
+		CustomerID:        customer.ID,
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            10000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusSucceeded,
+	}
+
+	mockPaymentRepo.On("GetByID", ctx, payment.ID).Return(payment, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	mockPaymentRepo.On("ReserveRefund", ctx, payment, models.NewMoney(4000, models.CurrencySEK)).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("CreateRefund", ctx, mock.MatchedBy(func(req *providers.CreateRefundRequest) bool {
+		return req.PaymentID == "pi_test123" && req.Amount == 4000
+	})).Return(&models.Refund{
+		ProviderRefundID: "re_test123",
+		Amount:           4000,
+		Status:           models.RefundStatusPending,
+	}, nil)
+	mockRefundRepo.On("Create", ctx, mock.MatchedBy(func(r *models.Refund) bool {
+		return r.PaymentID == payment.ID && r.ProviderRefundID == "re_test123"
+	})).Return(nil)
+
+	// Execute
+	refund, err := service.CreateRefund(ctx, userID, "", &models.CreateRefundRequest{PaymentID: payment.ID, Amount: 4000})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, payment.ID, refund.PaymentID)
+	mockPaymentRepo.AssertNotCalled(t, "ReleaseRefund", mock.Anything, mock.Anything, mock.Anything)
+	mockPaymentRepo.AssertExpectations(t)
+	mockRefundRepo.AssertExpectations(t)
+}
+
+func TestRefundService_CreateRefund_RejectsWhenReservationFails(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockFactory := new(MockProviderFactory)
+	service := NewRefundService(nil, mockPaymentRepo, mockCustomerRepo, nil, nil, nil, nil, mockFactory, RefundApprovalPolicy{})
+
+	userID := uuid.New()
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	payment := &models.Payment{
+		ID:                  uuid.New(),
+		CustomerID:          customer.ID,
+		Amount:              10000,
+		Currency:            models.CurrencySEK,
+		Status:              models.PaymentStatusSucceeded,
+		AmountRefunded:      4000,
+		AmountRefundPending: 4000,
+	}
+
+	mockPaymentRepo.On("GetByID", ctx, payment.ID).Return(payment, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	// A concurrent refund reserved another 1000 after the payment was loaded
+	mockPaymentRepo.On("ReserveRefund", ctx, payment, models.NewMoney(5000, models.CurrencySEK)).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Payment).AmountRefundPending = 5000
+	}).Return(false, nil)
+
+	// Execute
+	refund, err := service.CreateRefund(ctx, userID, "", &models.CreateRefundRequest{PaymentID: payment.ID, Amount: 5000})
+
+	// Assert
+	assert.Nil(t, refund)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Contains(t, apiErr.Message, "Refundable: 10.00 SEK")
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+}
+
+func TestRefundService_CreateRefund_ReleasesReservationOnProviderError(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewRefundService(nil, mockPaymentRepo, mockCustomerRepo, nil, nil, nil, nil, mockFactory, RefundApprovalPolicy{})
+
+	userID := uuid.New()
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	payment := &models.Payment{
+		ID:                uuid.New(),
+		CustomerID:        customer.ID,
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            10000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusSucceeded,
+	}
+
+	mockPaymentRepo.On("GetByID", ctx, payment.ID).Return(payment, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	mockPaymentRepo.On("ReserveRefund", ctx, payment, models.NewMoney(10000, models.CurrencySEK)).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("CreateRefund", ctx, mock.Anything).Return(nil, errors.New("card_declined"))
+	mockPaymentRepo.On("ReleaseRefund", ctx, payment, models.NewMoney(10000, models.CurrencySEK)).Return(nil)
+
+	// Execute
+	refund, err := service.CreateRefund(ctx, userID, "", &models.CreateRefundRequest{PaymentID: payment.ID, Amount: 10000})
+
+	// Assert
+	assert.Nil(t, refund)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	mockPaymentRepo.AssertExpectations(t)
+}
+
+func TestRefundService_CreateRefund_RequiresApprovalOverThreshold(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockRefundRepo := new(MockRefundRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockFactory := new(MockProviderFactory)
+	service := NewRefundService(mockRefundRepo, mockPaymentRepo, mockCustomerRepo, mockAuditRepo, nil, nil, nil, mockFactory, RefundApprovalPolicy{
+		AmountThreshold: 5000,
+	})
+
+	userID := uuid.New()
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	payment := &models.Payment{
+		ID:         uuid.New(),
+		CustomerID: customer.ID,
+		Provider:   models.ProviderStripe,
+		Amount:     10000,
+		Currency:   models.CurrencySEK,
+		Status:     models.PaymentStatusSucceeded,
+		CreatedAt:  time.Now(),
+	}
+
+	mockPaymentRepo.On("GetByID", ctx, payment.ID).Return(payment, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	mockPaymentRepo.On("ReserveRefund", ctx, payment, models.NewMoney(6000, models.CurrencySEK)).Return(true, nil)
+	mockRefundRepo.On("Create", ctx, mock.MatchedBy(func(r *models.Refund) bool {
+		return r.Status == models.RefundStatusPendingApproval && r.ProviderRefundID == "" &&
+			*r.RequestedBy == userID && r.ApprovalReason != nil
+	})).Return(nil)
+	mockAuditRepo.On("Create", ctx, mock.MatchedBy(func(event *models.AuditEvent) bool {
+		return event.Action == models.AuditActionRefundApprovalRequested && *event.ActorUserID == userID
+	})).Return(nil)
+
+	// Execute
+	refund, err := service.CreateRefund(ctx, userID, "", &models.CreateRefundRequest{PaymentID: payment.ID, Amount: 6000})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.RefundStatusPendingApproval, refund.Status)
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+	mockRefundRepo.AssertExpectations(t)
+	mockAuditRepo.AssertExpectations(t)
+}
+
+func TestRefundService_ApproveRefund_SendsRefundToProvider(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockRefundRepo := new(MockRefundRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewRefundService(mockRefundRepo, mockPaymentRepo, nil, mockAuditRepo, nil, nil, nil, mockFactory, RefundApprovalPolicy{})
+
+	requesterID := uuid.New()
+	reviewerID := uuid.New()
+	payment := &models.Payment{
+		ID:                uuid.New(),
+		CustomerID:        uuid.New(),
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            10000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusSucceeded,
+	}
+	refund := &models.Refund{
+		ID:          uuid.New(),
+		PaymentID:   payment.ID,
+		Provider:    models.ProviderStripe,
+		Amount:      6000,
+		Currency:    models.CurrencySEK,
+		Status:      models.RefundStatusPendingApproval,
+		RequestedBy: &requesterID,
+	}
+
+	mockRefundRepo.On("GetByID", ctx, refund.ID).Return(refund, nil)
+	mockPaymentRepo.On("GetByID", ctx, payment.ID).Return(payment, nil)
+	mockRefundRepo.On("Review", ctx, refund).Return(true, nil).Once()
+	mockAuditRepo.On("Create", ctx, mock.MatchedBy(func(event *models.AuditEvent) bool {
+		return event.Action == models.AuditActionRefundApproved && *event.ActorUserID == reviewerID
+	})).Return(nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("CreateRefund", ctx, mock.MatchedBy(func(req *providers.CreateRefundRequest) bool {
+		return req.PaymentID == "pi_test123" && req.Amount == 6000
+	})).Return(&models.Refund{ProviderRefundID: "re_test123", Status: models.RefundStatusPending}, nil)
+	mockRefundRepo.On("Update", ctx, refund).Return(nil)
+
+	// Execute
+	result, err := service.ApproveRefund(ctx, refund.ID, reviewerID, &models.ReviewRefundRequest{Note: "Checked with customer"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "re_test123", result.ProviderRefundID)
+	assert.Equal(t, models.RefundStatusPending, result.Status)
+	assert.Equal(t, reviewerID, *result.ReviewedBy)
+	mockRefundRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+	mockAuditRepo.AssertExpectations(t)
+}
+
+func TestRefundService_ApproveRefund_RejectsRequester(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockRefundRepo := new(MockRefundRepository)
+	service := NewRefundService(mockRefundRepo, nil, nil, nil, nil, nil, nil, nil, RefundApprovalPolicy{})
+
+	requesterID := uuid.New()
+	refund := &models.Refund{
+		ID:          uuid.New(),
+		Status:      models.RefundStatusPendingApproval,
+		RequestedBy: &requesterID,
+	}
+	mockRefundRepo.On("GetByID", ctx, refund.ID).Return(refund, nil)
+
+	// Execute
+	_, err := service.ApproveRefund(ctx, refund.ID, requesterID, &models.ReviewRefundRequest{})
+
+	// Assert
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	mockRefundRepo.AssertNotCalled(t, "Review", mock.Anything, mock.Anything)
+}
+
+func TestRefundService_CreateRefund_CreditsCustomerBalance(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockRefundRepo := new(MockRefundRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockBalanceRepo := new(MockCustomerBalanceRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	mockFactory := new(MockProviderFactory)
+	ledgerService := NewLedgerService(mockLedgerRepo, LedgerConfig{})
+	balanceService := NewCustomerBalanceService(mockBalanceRepo, mockCustomerRepo, ledgerService, mockFactory)
+	service := NewRefundService(mockRefundRepo, mockPaymentRepo, mockCustomerRepo, nil, ledgerService, nil, balanceService, mockFactory, RefundApprovalPolicy{})
+
+	userID := uuid.New()
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	payment := &models.Payment{
+		ID:                uuid.New(),
+		CustomerID:        customer.ID,
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            10000,
+		Currency:          models.CurrencySEK,
+		Status:            models.PaymentStatusSucceeded,
+	}
+
+	mockPaymentRepo.On("GetByID", ctx, payment.ID).Return(payment, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	mockPaymentRepo.On("ReserveRefund", ctx, payment, models.NewMoney(4000, models.CurrencySEK)).Return(true, nil)
+	mockRefundRepo.On("Create", ctx, mock.MatchedBy(func(r *models.Refund) bool {
+		return r.Destination == models.RefundDestinationCustomerBalance && r.Status == models.RefundStatusPending
+	})).Return(nil)
+	mockBalanceRepo.On("Credit", ctx, mock.MatchedBy(func(txn *models.CustomerBalanceTransaction) bool {
+		return txn.CustomerID == customer.ID && txn.Amount == 4000 && txn.Type == models.CustomerBalanceTransactionRefundCredit
+	})).Return(true, nil)
+	mockLedgerRepo.On("CreateEntry", ctx, mock.MatchedBy(func(entry *models.LedgerEntry) bool {
+		return entry.Type == models.LedgerEntryCreditIssued
+	})).Return(true, nil)
+	mockRefundRepo.On("Update", ctx, mock.MatchedBy(func(r *models.Refund) bool {
+		return r.Status == models.RefundStatusSucceeded
+	})).Return(nil)
+
+	// Execute
+	refund, err := service.CreateRefund(ctx, userID, "", &models.CreateRefundRequest{
+		PaymentID:   payment.ID,
+		Amount:      4000,
+		Destination: models.RefundDestinationCustomerBalance,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.RefundStatusSucceeded, refund.Status)
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+	mockBalanceRepo.AssertExpectations(t)
+	mockRefundRepo.AssertExpectations(t)
+	mockLedgerRepo.AssertExpectations(t)
+}
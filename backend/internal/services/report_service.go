@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/repository"
+	"time"
+)
+
+// defaultReportPeriod is used when a report is requested without a start date
+const defaultReportPeriod = 30 * 24 * time.Hour
+
+type ReportService struct {
+	paymentRepo repository.PaymentRepositoryInterface
+}
+
+func NewReportService(paymentRepo repository.PaymentRepositoryInterface) *ReportService {
+	return &ReportService{
+		paymentRepo: paymentRepo,
+	}
+}
+
+// GetProductMargins reports gross, fees, refunds and net per product for
+// payments created in [from, to). Zero times default to the last 30 days.
+func (s *ReportService) GetProductMargins(ctx context.Context, from, to time.Time) (*models.ProductMarginReport, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultReportPeriod)
+	}
+	if !from.Before(to) {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Report start must be before its end",
+			http.StatusBadRequest,
+		)
+	}
+
+	margins, err := s.paymentRepo.ListProductMargins(ctx, from, to)
+	if err != nil {
+		log.Printf("Failed to list product margins: %v", err)
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve product margins",
+			http.StatusInternalServerError,
+		)
+	}
+
+	for i := range margins {
+		margin := &margins[i]
+		// Fees in another currency can't be subtracted from the gross
+		if margin.SettlementCurrency != nil && *margin.SettlementCurrency != margin.Currency {
+			continue
+		}
+		value := margin.GrossAmount - margin.TaxAmount - margin.RefundedAmount - margin.ProviderFees
+		margin.Margin = &value
+	}
+	if margins == nil {
+		margins = []models.ProductMargin{}
+	}
+
+	return &models.ProductMarginReport{
+		From:     from,
+		To:       to,
+		Products: margins,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"time"
+)
+
+type SettlementService struct {
+	paymentRepo     repository.PaymentRepositoryInterface
+	refundRepo      repository.RefundRepositoryInterface
+	ledgerService   *LedgerService
+	providerFactory ProviderFactoryInterface
+}
+
+func NewSettlementService(
+	paymentRepo repository.PaymentRepositoryInterface,
+	refundRepo repository.RefundRepositoryInterface,
+	ledgerService *LedgerService,
+	providerFactory ProviderFactoryInterface,
+) *SettlementService {
+	return &SettlementService{
+		paymentRepo:     paymentRepo,
+		refundRepo:      refundRepo,
+		ledgerService:   ledgerService,
+		providerFactory: providerFactory,
+	}
+}
+
+// SyncPayment stores the provider fee and net amount of a succeeded payment
+// and records the fee in the ledger. Payments the provider has not settled
+// yet are left for a later sync, and payments paid entirely with store credit
+// never reach the provider.
+func (s *SettlementService) SyncPayment(ctx context.Context, payment *models.Payment) error {
+	if payment.Status != models.PaymentStatusSucceeded || payment.BalanceTransactionID != nil || payment.ProviderPaymentID == "" {
+		return nil
+	}
+
+	provider, err := s.providerFactory.GetProvider(payment.Provider)
+	if err != nil {
+		return err
+	}
+
+	transaction, err := provider.GetPaymentBalanceTransaction(ctx, payment.ProviderPaymentID)
+	if err != nil {
+		return fmt.Errorf("failed to get balance transaction: %w", err)
+	}
+	if transaction == nil {
+		return nil
+	}
+
+	applySettlement(transaction, &payment.ProviderFee, &payment.NetAmount, &payment.SettlementCurrency,
+		&payment.ExchangeRate, &payment.AvailableOn, &payment.BalanceTransactionID)
+	if err := s.paymentRepo.UpdateSettlement(ctx, payment); err != nil {
+		return err
+	}
+
+	return s.ledgerService.RecordFee(ctx, &payment.CustomerID, "payment", payment.ID, transaction.Currency, transaction.Fee)
+}
+
+// SyncRefund stores the settlement details of a succeeded refund
+func (s *SettlementService) SyncRefund(ctx context.Context, refund *models.Refund, payment *models.Payment) error {
+	if refund.Status != models.RefundStatusSucceeded || refund.BalanceTransactionID != nil {
+		return nil
+	}
+
+	provider, err := s.providerFactory.GetProvider(refund.Provider)
+	if err != nil {
+		return err
+	}
+
+	transaction, err := provider.GetRefundBalanceTransaction(ctx, refund.ProviderRefundID)
+	if err != nil {
+		return fmt.Errorf("failed to get balance transaction: %w", err)
+	}
+	if transaction == nil {
+		return nil
+	}
+
+	applySettlement(transaction, &refund.ProviderFee, &refund.NetAmount, &refund.SettlementCurrency,
+		&refund.ExchangeRate, &refund.AvailableOn, &refund.BalanceTransactionID)
+	if err := s.refundRepo.UpdateSettlement(ctx, refund); err != nil {
+		return err
+	}
+
+	return s.ledgerService.RecordFee(ctx, &payment.CustomerID, "refund", refund.ID, transaction.Currency, transaction.Fee)
+}
+
+// applySettlement copies a balance transaction onto a payment's or refund's
+// settlement fields
+func applySettlement(
+	transaction *providers.BalanceTransaction,
+	fee, net **int64,
+	currency **models.Currency,
+	exchangeRate **float64,
+	availableOn **time.Time,
+	balanceTransactionID **string,
+) {
+	*fee = &transaction.Fee
+	*net = &transaction.Net
+	*currency = &transaction.Currency
+	*exchangeRate = transaction.ExchangeRate
+	*availableOn = &transaction.AvailableOn
+	*balanceTransactionID = &transaction.ID
+}
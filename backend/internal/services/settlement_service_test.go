@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSettlementService_SyncPayment_StoresFeeAndRecordsLedger(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	ledgerService := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+	service := NewSettlementService(mockPaymentRepo, nil, ledgerService, mockFactory)
+
+	payment := &models.Payment{
+		ID:                uuid.New(),
+		CustomerID:        uuid.New(),
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Amount:            10000,
+		Currency:          models.CurrencyEUR,
+		Status:            models.PaymentStatusSucceeded,
+	}
+	rate := 11.5
+	transaction := &providers.BalanceTransaction{
+		ID:           "txn_test123",
+		Amount:       115000,
+		Fee:          2000,
+		Net:          113000,
+		Currency:     models.CurrencySEK,
+		ExchangeRate: &rate,
+		AvailableOn:  time.Now().Add(48 * time.Hour),
+	}
+
+	var recorded *models.LedgerEntry
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("GetPaymentBalanceTransaction", ctx, "pi_test123").Return(transaction, nil)
+	mockPaymentRepo.On("UpdateSettlement", ctx, payment).Return(nil)
+	mockLedgerRepo.On("CreateEntry", ctx, mock.AnythingOfType("*models.LedgerEntry")).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(*models.LedgerEntry)
+	}).Return(true, nil)
+
+	// Execute
+	err := service.SyncPayment(ctx, payment)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2000), *payment.ProviderFee)
+	assert.Equal(t, int64(113000), *payment.NetAmount)
+	assert.Equal(t, models.CurrencySEK, *payment.SettlementCurrency)
+	assert.Equal(t, "txn_test123", *payment.BalanceTransactionID)
+	assert.Equal(t, models.LedgerEntryFee, recorded.Type)
+	assert.Equal(t, models.CurrencySEK, recorded.Currency)
+	assert.Equal(t, []models.LedgerPosting{
+		{AccountCode: models.LedgerAccountProviderFees, Amount: 2000},
+		{AccountCode: models.LedgerAccountProviderBalance, Amount: -2000},
+	}, recorded.Postings)
+
+	mockPaymentRepo.AssertExpectations(t)
+	mockLedgerRepo.AssertExpectations(t)
+}
+
+func TestSettlementService_SyncPayment_NotSettledYet(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewSettlementService(mockPaymentRepo, nil, nil, mockFactory)
+
+	payment := &models.Payment{
+		ID:                uuid.New(),
+		Provider:          models.ProviderStripe,
+		ProviderPaymentID: "pi_test123",
+		Status:            models.PaymentStatusSucceeded,
+	}
+
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("GetPaymentBalanceTransaction", ctx, "pi_test123").Return(nil, nil)
+
+	// Execute
+	err := service.SyncPayment(ctx, payment)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Nil(t, payment.ProviderFee)
+	mockPaymentRepo.AssertNotCalled(t, "UpdateSettlement", mock.Anything, mock.Anything)
+}
+
+func TestReportService_GetProductMargins_SkipsForeignSettlement(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockPaymentRepo := new(MockPaymentRepository)
+	service := NewReportService(mockPaymentRepo)
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	sek := models.CurrencySEK
+	mockPaymentRepo.On("ListProductMargins", ctx, from, to).Return([]models.ProductMargin{
+		{Product: "Pro plan", Currency: models.CurrencySEK, SettlementCurrency: &sek,
+			GrossAmount: 12500, TaxAmount: 2500, RefundedAmount: 1000, ProviderFees: 400},
+		{Product: "Pro plan", Currency: models.CurrencyEUR, SettlementCurrency: &sek,
+			GrossAmount: 1000, ProviderFees: 300},
+	}, nil)
+
+	// Execute
+	report, err := service.GetProductMargins(ctx, from, to)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, report.Products, 2)
+	assert.Equal(t, int64(8600), *report.Products[0].Margin)
+	assert.Nil(t, report.Products[1].Margin)
+}
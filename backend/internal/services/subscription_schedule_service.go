@@ -0,0 +1,908 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// scheduleBatchSize caps how many schedules one scheduler pass handles
+const scheduleBatchSize = 100
+
+// SubscriptionScheduleService schedules subscription changes in phases.
+// Providers with subscription schedules run them; otherwise ProcessSchedules
+// applies each phase when it starts. Either way ProcessSchedules keeps the
+// stored subscription in step with the phase in effect.
+type SubscriptionScheduleService struct {
+	scheduleRepo        repository.SubscriptionScheduleRepositoryInterface
+	subscriptionRepo    repository.SubscriptionRepositoryInterface
+	customerRepo        repository.CustomerRepositoryInterface
+	auditRepo           repository.AuditRepositoryInterface
+	subscriptionService *SubscriptionService
+	taxService          *TaxService
+	providerFactory     ProviderFactoryInterface
+}
+
+func NewSubscriptionScheduleService(
+	scheduleRepo repository.SubscriptionScheduleRepositoryInterface,
+	subscriptionRepo repository.SubscriptionRepositoryInterface,
+	customerRepo repository.CustomerRepositoryInterface,
+	auditRepo repository.AuditRepositoryInterface,
+	subscriptionService *SubscriptionService,
+	taxService *TaxService,
+	providerFactory ProviderFactoryInterface,
+) *SubscriptionScheduleService {
+	return &SubscriptionScheduleService{
+		scheduleRepo:        scheduleRepo,
+		subscriptionRepo:    subscriptionRepo,
+		customerRepo:        customerRepo,
+		auditRepo:           auditRepo,
+		subscriptionService: subscriptionService,
+		taxService:          taxService,
+		providerFactory:     providerFactory,
+	}
+}
+
+// CreateSchedule schedules phases for an existing subscription, starting
+// when its current period ends, or for a new subscription starting on a
+// future date
+func (s *SubscriptionScheduleService) CreateSchedule(
+	ctx context.Context,
+	userID uuid.UUID,
+	email, name string,
+	req *models.CreateSubscriptionScheduleRequest,
+) (*models.SubscriptionSchedule, error) {
+	invalid := func(message string) error {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, message, http.StatusBadRequest)
+	}
+
+	endBehavior := req.EndBehavior
+	switch endBehavior {
+	case "":
+		endBehavior = models.ScheduleEndBehaviorRelease
+	case models.ScheduleEndBehaviorRelease, models.ScheduleEndBehaviorCancel:
+	default:
+		return nil, invalid("end_behavior must be release or cancel")
+	}
+
+	if len(req.Phases) == 0 {
+		return nil, invalid("At least one phase is required")
+	}
+
+	schedule := &models.SubscriptionSchedule{
+		Status:      models.SubscriptionScheduleStatusNotStarted,
+		EndBehavior: endBehavior,
+	}
+
+	var subscription *models.Subscription
+	var customer *models.Customer
+	var start time.Time
+	var defaults models.SchedulePhase
+	if req.SubscriptionID != nil {
+		if req.StartDate != nil {
+			return nil, invalid("start_date cannot be set for an existing subscription; its first phase starts at the next renewal")
+		}
+
+		var err error
+		subscription, err = s.subscriptionService.GetSubscription(ctx, *req.SubscriptionID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSchedulable(subscription); err != nil {
+			return nil, err
+		}
+
+		customer, err = s.customerRepo.GetByID(ctx, subscription.CustomerID)
+		if err != nil || customer == nil {
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to retrieve customer",
+				http.StatusInternalServerError,
+			)
+		}
+
+		start = subscription.CurrentPeriodEnd
+		defaults = models.SchedulePhase{
+			Items:         itemRequests(subscription.Items),
+			Interval:      subscription.Interval,
+			IntervalCount: subscription.IntervalCount,
+		}
+
+		schedule.SubscriptionID = &subscription.ID
+		schedule.Provider = subscription.Provider
+		schedule.Currency = subscription.Currency
+		schedule.ProductName = subscription.ProductName
+		schedule.Metadata = subscription.Metadata
+	} else {
+		if req.StartDate == nil || !req.StartDate.After(time.Now()) {
+			return nil, invalid("start_date must be in the future")
+		}
+		if req.Currency == "" {
+			return nil, invalid("Currency is required")
+		}
+		if req.ProductName == "" {
+			return nil, invalid("Product name is required")
+		}
+
+		start = *req.StartDate
+
+		schedule.Provider = req.Provider
+		schedule.Currency = req.Currency
+		schedule.ProductName = req.ProductName
+		schedule.TaxCategory = req.TaxCategory
+		schedule.TaxBehavior = req.TaxBehavior
+		schedule.BillingCountry = req.BillingCountry
+		schedule.VATID = req.VATID
+		schedule.Metadata = req.Metadata
+	}
+
+	phases, err := schedulePhases(start, defaults, schedule.ProductName, req.Phases)
+	if err != nil {
+		return nil, err
+	}
+	if subscription != nil && len(subscription.MeteredPrices) > 0 {
+		for _, phase := range phases {
+			if phase.Interval != subscription.Interval || phase.IntervalCount != subscription.IntervalCount {
+				return nil, invalid("Cannot change the interval of a subscription with metered prices")
+			}
+		}
+	}
+	schedule.Phases = phases
+	schedule.NextTransitionAt = &phases[0].StartDate
+
+	if customer == nil {
+		customer, err = s.subscriptionService.getOrCreateCustomer(ctx, userID, email, name, schedule.Provider)
+		if err != nil {
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to get or create customer",
+				http.StatusInternalServerError,
+			)
+		}
+	}
+	schedule.CustomerID = customer.ID
+
+	// Phases are taxed at the rate the subscription has or will start with
+	var taxRate *providers.TaxRate
+	if subscription != nil {
+		if subscription.TaxBreakdown != nil {
+			taxRate = providerTaxRate(subscription.TaxBreakdown)
+		}
+	} else {
+		tax, err := s.calculateTax(customer, schedule, models.ItemsAmount(subscriptionItems(phases[0].Items)))
+		if err != nil {
+			return nil, err
+		}
+		taxRate = providerTaxRate(tax.Breakdown)
+	}
+
+	// Get provider
+	provider, err := s.providerFactory.GetProvider(schedule.Provider)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			fmt.Sprintf("Provider %s not available", schedule.Provider),
+			http.StatusBadRequest,
+		)
+	}
+
+	// Get provider customer ID
+	var providerCustomerID string
+	if schedule.Provider == models.ProviderStripe && customer.StripeCustomerID != nil {
+		providerCustomerID = *customer.StripeCustomerID
+	} else if schedule.Provider == models.ProviderSwish && customer.SwishCustomerID != nil {
+		providerCustomerID = *customer.SwishCustomerID
+	} else {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Customer not configured for this provider",
+			http.StatusBadRequest,
+		)
+	}
+
+	providerReq := &providers.CreateSubscriptionScheduleRequest{
+		CustomerID:  providerCustomerID,
+		Currency:    string(schedule.Currency),
+		EndBehavior: string(schedule.EndBehavior),
+		TaxRate:     taxRate,
+		Metadata:    convertMetadataToStrings(schedule.Metadata),
+	}
+	if subscription != nil {
+		providerReq.SubscriptionID = subscription.ProviderSubscriptionID
+	}
+	for _, phase := range phases {
+		providerReq.Phases = append(providerReq.Phases, providers.SchedulePhase{
+			Items:         providerItems(phase.Items),
+			Interval:      phase.Interval,
+			IntervalCount: phase.IntervalCount,
+			Iterations:    phase.Iterations,
+			StartDate:     phase.StartDate,
+		})
+	}
+
+	// Providers without schedules leave the phases to ProcessSchedules
+	providerScheduleID, err := provider.CreateSubscriptionSchedule(ctx, providerReq)
+	if err == nil {
+		schedule.ProviderScheduleID = &providerScheduleID
+	} else if !errors.Is(err, providers.ErrNotSupported) {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to create subscription schedule with provider",
+			http.StatusBadGateway,
+		)
+	}
+
+	if err := s.scheduleRepo.Create(ctx, schedule); err != nil {
+		if schedule.ProviderScheduleID != nil {
+			if err := s.stopProviderSchedule(ctx, provider, schedule); err != nil {
+				log.Printf("Failed to stop unsaved provider schedule %s: %v", *schedule.ProviderScheduleID, err)
+			}
+		}
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to save subscription schedule to database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	s.recordScheduleChange(ctx, schedule, &userID, "created")
+
+	return schedule, nil
+}
+
+// GetSchedule retrieves a subscription schedule by ID
+func (s *SubscriptionScheduleService) GetSchedule(ctx context.Context, scheduleID, userID uuid.UUID) (*models.SubscriptionSchedule, error) {
+	schedule, err := s.scheduleRepo.GetByID(ctx, scheduleID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve subscription schedule",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if schedule == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Subscription schedule not found",
+			http.StatusNotFound,
+		)
+	}
+
+	// Verify customer owns this schedule
+	customer, err := s.customerRepo.GetByID(ctx, schedule.CustomerID)
+	if err != nil || customer == nil || customer.UserID != userID {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Subscription schedule not found",
+			http.StatusNotFound,
+		)
+	}
+
+	return schedule, nil
+}
+
+// ListSchedules lists a user's subscription schedules, newest first
+func (s *SubscriptionScheduleService) ListSchedules(ctx context.Context, userID uuid.UUID, limit, offset int) (*models.SubscriptionScheduleListResponse, error) {
+	customer, err := s.customerRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve customer",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if customer == nil {
+		return &models.SubscriptionScheduleListResponse{
+			Data:   []models.SubscriptionSchedule{},
+			Total:  0,
+			Limit:  limit,
+			Offset: offset,
+		}, nil
+	}
+
+	schedules, total, err := s.scheduleRepo.ListByCustomer(ctx, customer.ID, limit, offset)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list subscription schedules",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.SubscriptionScheduleListResponse{
+		Data:   schedules,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// CancelSchedule drops the phases that have not started yet. A subscription
+// the schedule already changed or started continues as it is.
+func (s *SubscriptionScheduleService) CancelSchedule(ctx context.Context, scheduleID, userID uuid.UUID) (*models.SubscriptionSchedule, error) {
+	schedule, err := s.GetSchedule(ctx, scheduleID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule.Status != models.SubscriptionScheduleStatusNotStarted &&
+		schedule.Status != models.SubscriptionScheduleStatusActive {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Cannot cancel a subscription schedule with status %s", schedule.Status),
+			http.StatusConflict,
+		)
+	}
+
+	// A due transition may already be under way at the provider
+	busy := models.NewAPIError(
+		models.ErrCodeInvalidRequest,
+		"Subscription schedule is changing phase; try again shortly",
+		http.StatusConflict,
+	)
+	if schedule.NextTransitionAt == nil || !time.Now().Before(*schedule.NextTransitionAt) {
+		return nil, busy
+	}
+
+	transitionAt := *schedule.NextTransitionAt
+	claimed, err := s.scheduleRepo.ClaimTransition(ctx, schedule.ID, transitionAt)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update subscription schedule in database",
+			http.StatusInternalServerError,
+		)
+	}
+	if !claimed {
+		return nil, busy
+	}
+
+	if schedule.ProviderScheduleID != nil {
+		provider, err := s.providerFactory.GetProvider(schedule.Provider)
+		if err == nil {
+			err = s.stopProviderSchedule(ctx, provider, schedule)
+		}
+		if err != nil {
+			// Give the transition back so the schedule keeps running
+			if err := s.scheduleRepo.Update(ctx, schedule); err != nil {
+				log.Printf("Failed to restore subscription schedule %s: %v", schedule.ID, err)
+			}
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to cancel subscription schedule with provider",
+				http.StatusBadGateway,
+			)
+		}
+	}
+
+	status := models.SubscriptionScheduleStatusReleased
+	if schedule.SubscriptionID == nil {
+		status = models.SubscriptionScheduleStatusCanceled
+	}
+	endSchedule(schedule, status, time.Now())
+
+	if err := s.scheduleRepo.Update(ctx, schedule); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update subscription schedule in database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	s.recordScheduleChange(ctx, schedule, &userID, string(status))
+
+	return schedule, nil
+}
+
+// ProcessSchedules applies the phase changes that are due. Each schedule is
+// claimed atomically first, so several instances can run this concurrently.
+// A failed transition stays due and is retried on the next pass.
+func (s *SubscriptionScheduleService) ProcessSchedules(ctx context.Context) (*models.ScheduleRunResult, error) {
+	now := time.Now()
+
+	schedules, err := s.scheduleRepo.ListDue(ctx, now, scheduleBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ScheduleRunResult{}
+	for i := range schedules {
+		schedule := &schedules[i]
+		transitionAt := *schedule.NextTransitionAt
+
+		claimed, err := s.scheduleRepo.ClaimTransition(ctx, schedule.ID, transitionAt)
+		if err != nil {
+			log.Printf("Failed to claim subscription schedule %s: %v", schedule.ID, err)
+			result.Failed++
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		status, currentPhase, endedAt := schedule.Status, schedule.CurrentPhase, schedule.EndedAt
+		if err := s.advance(ctx, schedule, now); err != nil {
+			log.Printf("Failed to apply subscription schedule %s: %v", schedule.ID, err)
+			result.Failed++
+
+			// Keep a subscription the transition started, but leave the
+			// transition itself due
+			schedule.Status, schedule.CurrentPhase, schedule.EndedAt = status, currentPhase, endedAt
+			schedule.NextTransitionAt = &transitionAt
+			if err := s.scheduleRepo.Update(ctx, schedule); err != nil {
+				log.Printf("Failed to restore subscription schedule %s: %v", schedule.ID, err)
+			}
+			continue
+		}
+
+		switch {
+		case schedule.EndedAt != nil:
+			result.Ended++
+		case *schedule.CurrentPhase == 0:
+			result.Started++
+		default:
+			result.Phased++
+		}
+	}
+
+	return result, nil
+}
+
+// advance moves a claimed schedule into its next phase, or ends it after the
+// last one. The schedule is only changed for steps that succeeded, so a
+// subscription it started is kept when a later step fails.
+func (s *SubscriptionScheduleService) advance(ctx context.Context, schedule *models.SubscriptionSchedule, now time.Time) error {
+	provider, err := s.providerFactory.GetProvider(schedule.Provider)
+	if err != nil {
+		return err
+	}
+
+	next := 0
+	if schedule.CurrentPhase != nil {
+		next = *schedule.CurrentPhase + 1
+	}
+
+	var change string
+	if schedule.ProviderScheduleID != nil {
+		change, err = s.syncProviderSchedule(ctx, provider, schedule, next, now)
+	} else {
+		change, err = s.applyPhase(ctx, provider, schedule, next, now)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.scheduleRepo.Update(ctx, schedule); err != nil {
+		return err
+	}
+
+	s.recordScheduleChange(ctx, schedule, nil, change)
+	return nil
+}
+
+// applyPhase starts or changes the subscription for phase next, or ends the
+// schedule after its last phase. Returns the change made.
+func (s *SubscriptionScheduleService) applyPhase(
+	ctx context.Context,
+	provider providers.PaymentProvider,
+	schedule *models.SubscriptionSchedule,
+	next int,
+	now time.Time,
+) (string, error) {
+	if schedule.SubscriptionID == nil {
+		subscription, err := s.startSubscription(ctx, schedule)
+		if err != nil {
+			return "", err
+		}
+		schedule.SubscriptionID = &subscription.ID
+		startPhase(schedule, next)
+		return "phase_started", nil
+	}
+
+	subscription, err := s.getSubscription(ctx, *schedule.SubscriptionID)
+	if err != nil {
+		return "", err
+	}
+
+	// Nothing is left to change on a subscription that has been canceled since
+	if subscription.Status == models.SubscriptionStatusCanceled ||
+		subscription.Status == models.SubscriptionStatusIncompleteExpired {
+		endSchedule(schedule, models.SubscriptionScheduleStatusReleased, now)
+		return string(models.SubscriptionScheduleStatusReleased), nil
+	}
+
+	if next == len(schedule.Phases) {
+		if schedule.EndBehavior == models.ScheduleEndBehaviorCancel {
+			providerSubscription, err := provider.CancelSubscription(ctx, subscription.ProviderSubscriptionID, true)
+			if err != nil {
+				return "", err
+			}
+			if err := s.refreshSubscription(ctx, subscription, providerSubscription); err != nil {
+				return "", err
+			}
+		}
+		endSchedule(schedule, models.SubscriptionScheduleStatusCompleted, now)
+		return string(models.SubscriptionScheduleStatusCompleted), nil
+	}
+
+	phase := schedule.Phases[next]
+	providerSubscription, err := provider.ChangeSubscriptionPlan(ctx, subscription.ProviderSubscriptionID, &providers.ChangeSubscriptionPlanRequest{
+		Items:         providerItems(phase.Items),
+		Currency:      string(subscription.Currency),
+		Interval:      phase.Interval,
+		IntervalCount: phase.IntervalCount,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.refreshSubscription(ctx, subscription, providerSubscription); err != nil {
+		return "", err
+	}
+
+	startPhase(schedule, next)
+	return "phase_started", nil
+}
+
+// syncProviderSchedule follows a schedule run by the provider into phase
+// next, or to its end, storing the subscription as the provider has it.
+// Returns the change made.
+func (s *SubscriptionScheduleService) syncProviderSchedule(
+	ctx context.Context,
+	provider providers.PaymentProvider,
+	schedule *models.SubscriptionSchedule,
+	next int,
+	now time.Time,
+) (string, error) {
+	providerSchedule, err := provider.GetSubscriptionSchedule(ctx, *schedule.ProviderScheduleID)
+	if err != nil {
+		return "", err
+	}
+
+	ended := providerSchedule.Status == string(models.SubscriptionScheduleStatusCompleted) ||
+		providerSchedule.Status == string(models.SubscriptionScheduleStatusReleased) ||
+		providerSchedule.Status == string(models.SubscriptionScheduleStatusCanceled)
+
+	if providerSchedule.SubscriptionID != "" {
+		subscription, err := s.syncSubscription(ctx, provider, schedule, providerSchedule.SubscriptionID)
+		if err != nil {
+			return "", err
+		}
+		schedule.SubscriptionID = &subscription.ID
+	}
+
+	if next == len(schedule.Phases) {
+		if !ended {
+			return "", fmt.Errorf("provider has not ended schedule yet")
+		}
+		endSchedule(schedule, models.SubscriptionScheduleStatusCompleted, now)
+		return string(models.SubscriptionScheduleStatusCompleted), nil
+	}
+
+	// Stopped at the provider before its last phase ended
+	if ended {
+		status := models.SubscriptionScheduleStatusReleased
+		if schedule.SubscriptionID == nil {
+			status = models.SubscriptionScheduleStatusCanceled
+		}
+		endSchedule(schedule, status, now)
+		return string(status), nil
+	}
+
+	if schedule.SubscriptionID == nil {
+		return "", fmt.Errorf("provider has not started the subscription yet")
+	}
+
+	startPhase(schedule, next)
+	return "phase_started", nil
+}
+
+// syncSubscription stores a subscription as the provider has it, creating it
+// if the provider's schedule just started it
+func (s *SubscriptionScheduleService) syncSubscription(
+	ctx context.Context,
+	provider providers.PaymentProvider,
+	schedule *models.SubscriptionSchedule,
+	providerSubscriptionID string,
+) (*models.Subscription, error) {
+	providerSubscription, err := provider.GetSubscription(ctx, providerSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := s.subscriptionRepo.GetByProviderSubscriptionID(ctx, providerSubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription != nil {
+		return subscription, s.refreshSubscription(ctx, subscription, providerSubscription)
+	}
+
+	customer, err := s.customerRepo.GetByID(ctx, schedule.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	if customer == nil {
+		return nil, fmt.Errorf("customer %s not found", schedule.CustomerID)
+	}
+
+	tax, err := s.calculateTax(customer, schedule, providerSubscription.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	providerSubscription.CustomerID = schedule.CustomerID
+	providerSubscription.ProductName = schedule.ProductName
+	providerSubscription.Metadata = schedule.Metadata
+	providerSubscription.TaxAmount = tax.Tax
+	providerSubscription.TaxBreakdown = tax.Breakdown
+
+	if err := s.subscriptionRepo.Create(ctx, providerSubscription); err != nil {
+		return nil, err
+	}
+
+	return providerSubscription, nil
+}
+
+// startSubscription creates the subscription of a schedule's first phase
+func (s *SubscriptionScheduleService) startSubscription(ctx context.Context, schedule *models.SubscriptionSchedule) (*models.Subscription, error) {
+	customer, err := s.customerRepo.GetByID(ctx, schedule.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	if customer == nil {
+		return nil, fmt.Errorf("customer %s not found", schedule.CustomerID)
+	}
+
+	phase := schedule.Phases[0]
+	return s.subscriptionService.createSubscription(ctx, customer, &models.CreateSubscriptionRequest{
+		Provider:       schedule.Provider,
+		Currency:       schedule.Currency,
+		Interval:       phase.Interval,
+		IntervalCount:  phase.IntervalCount,
+		ProductName:    schedule.ProductName,
+		TaxCategory:    schedule.TaxCategory,
+		TaxBehavior:    schedule.TaxBehavior,
+		BillingCountry: schedule.BillingCountry,
+		VATID:          schedule.VATID,
+		Metadata:       schedule.Metadata,
+	}, subscriptionItems(phase.Items), nil)
+}
+
+// refreshSubscription stores the items, interval, period and status the
+// provider has for a subscription
+func (s *SubscriptionScheduleService) refreshSubscription(ctx context.Context, subscription, providerSubscription *models.Subscription) error {
+	syncSubscriptionItems(subscription, providerSubscription.Items)
+	subscription.Interval = providerSubscription.Interval
+	subscription.IntervalCount = providerSubscription.IntervalCount
+	subscription.Status = providerSubscription.Status
+	subscription.CurrentPeriodStart = providerSubscription.CurrentPeriodStart
+	subscription.CurrentPeriodEnd = providerSubscription.CurrentPeriodEnd
+	subscription.CanceledAt = providerSubscription.CanceledAt
+	subscription.NextBillingAt = providerSubscription.NextBillingAt
+
+	if err := s.subscriptionRepo.SaveItems(ctx, subscription); err != nil {
+		return err
+	}
+	return s.subscriptionRepo.Update(ctx, subscription)
+}
+
+func (s *SubscriptionScheduleService) getSubscription(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	subscription, err := s.subscriptionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, fmt.Errorf("subscription %s not found", id)
+	}
+	return subscription, nil
+}
+
+// calculateTax calculates the tax per period on amount for the subscription a
+// schedule starts
+func (s *SubscriptionScheduleService) calculateTax(customer *models.Customer, schedule *models.SubscriptionSchedule, amount int64) (*TaxResult, error) {
+	country, vatID := customerTaxLocation(customer, schedule.BillingCountry, schedule.VATID)
+	return s.taxService.Calculate(&TaxRequest{
+		Amount:   amount,
+		Category: schedule.TaxCategory,
+		Behavior: schedule.TaxBehavior,
+		Country:  country,
+		VATID:    vatID,
+	})
+}
+
+// stopProviderSchedule cancels a provider schedule that has not started a
+// subscription, or releases the subscription of one that has
+func (s *SubscriptionScheduleService) stopProviderSchedule(ctx context.Context, provider providers.PaymentProvider, schedule *models.SubscriptionSchedule) error {
+	if schedule.SubscriptionID == nil {
+		return provider.CancelSubscriptionSchedule(ctx, *schedule.ProviderScheduleID)
+	}
+	return provider.ReleaseSubscriptionSchedule(ctx, *schedule.ProviderScheduleID)
+}
+
+// recordScheduleChange writes the audit entry for a schedule change; actor is
+// nil for changes made by the scheduler job
+func (s *SubscriptionScheduleService) recordScheduleChange(
+	ctx context.Context,
+	schedule *models.SubscriptionSchedule,
+	actor *uuid.UUID,
+	change string,
+) {
+	details := models.JSONBMap{
+		"change": change,
+		"status": schedule.Status,
+	}
+	if schedule.SubscriptionID != nil {
+		details["subscription_id"] = *schedule.SubscriptionID
+	}
+	if schedule.CurrentPhase != nil {
+		details["phase"] = *schedule.CurrentPhase
+	}
+
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		CustomerID:   &schedule.CustomerID,
+		ActorUserID:  actor,
+		Action:       models.AuditActionSubscriptionScheduleChanged,
+		ResourceType: "subscription_schedule",
+		ResourceID:   schedule.ID,
+		Details:      details,
+	})
+}
+
+// checkSchedulable reports whether changes can be scheduled for a subscription
+func checkSchedulable(subscription *models.Subscription) error {
+	conflict := func(message string) error {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, message, http.StatusConflict)
+	}
+
+	switch subscription.Status {
+	case models.SubscriptionStatusCanceled,
+		models.SubscriptionStatusIncomplete,
+		models.SubscriptionStatusIncompleteExpired:
+		return conflict(fmt.Sprintf("Cannot schedule changes to a subscription with status %s", subscription.Status))
+	}
+
+	if subscription.CancelAtPeriodEnd {
+		return conflict("Subscription is set to cancel at the end of the period; reactivate it first")
+	}
+
+	if subscription.Schedule != nil {
+		return conflict("Subscription already has a schedule; cancel it first")
+	}
+
+	return nil
+}
+
+// schedulePhases validates requested phases and dates them, the first one
+// starting at start. Items and interval default to the previous phase's, the
+// first phase's to defaults.
+func schedulePhases(
+	start time.Time,
+	defaults models.SchedulePhase,
+	productName string,
+	requested []models.SchedulePhaseRequest,
+) (models.SchedulePhases, error) {
+	invalid := func(i int, message string) error {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Phase %d: %s", i+1, message),
+			http.StatusBadRequest,
+		)
+	}
+
+	previous := defaults
+	phases := make(models.SchedulePhases, 0, len(requested))
+	for i, req := range requested {
+		phase := models.SchedulePhase{
+			Items:         previous.Items,
+			Interval:      previous.Interval,
+			IntervalCount: previous.IntervalCount,
+			Iterations:    req.Iterations,
+		}
+
+		if len(req.Items) > 0 {
+			items, err := validateItems(req.Items, productName)
+			if err != nil {
+				return nil, err
+			}
+			phase.Items = itemRequests(items)
+		}
+		if len(phase.Items) == 0 {
+			return nil, invalid(i, "items are required")
+		}
+
+		if req.Interval != "" {
+			phase.Interval = req.Interval
+			phase.IntervalCount = 1
+		}
+		if req.IntervalCount != 0 {
+			phase.IntervalCount = req.IntervalCount
+		}
+		switch phase.Interval {
+		case "day", "week", "month", "year":
+		default:
+			return nil, invalid(i, "interval must be day, week, month or year")
+		}
+		if phase.IntervalCount <= 0 {
+			return nil, invalid(i, "interval_count must be at least 1")
+		}
+
+		if phase.Iterations == 0 {
+			phase.Iterations = 1
+		}
+		if phase.Iterations < 0 {
+			return nil, invalid(i, "iterations must be at least 1")
+		}
+
+		phase.StartDate = start
+		phase.EndDate = models.AddInterval(start, phase.Interval, phase.IntervalCount*phase.Iterations)
+		start = phase.EndDate
+
+		phases = append(phases, phase)
+		previous = phase
+	}
+
+	return phases, nil
+}
+
+// startPhase records that a schedule's phase at index is in effect
+func startPhase(schedule *models.SubscriptionSchedule, index int) {
+	schedule.Status = models.SubscriptionScheduleStatusActive
+	schedule.CurrentPhase = &index
+	schedule.NextTransitionAt = &schedule.Phases[index].EndDate
+}
+
+// endSchedule records that a schedule has ended with status
+func endSchedule(schedule *models.SubscriptionSchedule, status models.SubscriptionScheduleStatus, now time.Time) {
+	schedule.Status = status
+	schedule.NextTransitionAt = nil
+	schedule.EndedAt = &now
+}
+
+// itemRequests converts subscription items to the items of a schedule phase
+func itemRequests(items []models.SubscriptionItem) []models.SubscriptionItemRequest {
+	requests := make([]models.SubscriptionItemRequest, len(items))
+	for i, item := range items {
+		requests[i] = models.SubscriptionItemRequest{
+			ProductName: item.ProductName,
+			UnitAmount:  item.UnitAmount,
+			Quantity:    item.Quantity,
+		}
+	}
+	return requests
+}
+
+// subscriptionItems converts the items of a schedule phase to subscription items
+func subscriptionItems(requests []models.SubscriptionItemRequest) []models.SubscriptionItem {
+	items := make([]models.SubscriptionItem, len(requests))
+	for i, req := range requests {
+		items[i] = models.SubscriptionItem{
+			ProductName: req.ProductName,
+			UnitAmount:  req.UnitAmount,
+			Quantity:    req.Quantity,
+		}
+	}
+	return items
+}
+
+// providerItems converts the items of a schedule phase for the provider
+func providerItems(requests []models.SubscriptionItemRequest) []providers.SubscriptionItem {
+	items := make([]providers.SubscriptionItem, len(requests))
+	for i, req := range requests {
+		items[i] = providers.SubscriptionItem{
+			ProductName: req.ProductName,
+			UnitAmount:  req.UnitAmount,
+			Quantity:    req.Quantity,
+		}
+	}
+	return items
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSubscriptionScheduleRepository is a mock for SubscriptionScheduleRepository
+type MockSubscriptionScheduleRepository struct {
+	mock.Mock
+}
+
+func (m *MockSubscriptionScheduleRepository) Create(ctx context.Context, schedule *models.SubscriptionSchedule) error {
+	args := m.Called(ctx, schedule)
+	return args.Error(0)
+}
+
+func (m *MockSubscriptionScheduleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.SubscriptionSchedule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SubscriptionSchedule), args.Error(1)
+}
+
+func (m *MockSubscriptionScheduleRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.SubscriptionSchedule, int, error) {
+	args := m.Called(ctx, customerID, limit, offset)
+	return args.Get(0).([]models.SubscriptionSchedule), args.Int(1), args.Error(2)
+}
+
+func (m *MockSubscriptionScheduleRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.SubscriptionSchedule, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SubscriptionSchedule), args.Error(1)
+}
+
+func (m *MockSubscriptionScheduleRepository) ClaimTransition(ctx context.Context, id uuid.UUID, transitionAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, transitionAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSubscriptionScheduleRepository) Update(ctx context.Context, schedule *models.SubscriptionSchedule) error {
+	args := m.Called(ctx, schedule)
+	return args.Error(0)
+}
+
+func newScheduledSubscription(customerID uuid.UUID) *models.Subscription {
+	now := time.Now()
+	subscriptionID := uuid.New()
+	return &models.Subscription{
+		ID:                     subscriptionID,
+		CustomerID:             customerID,
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusActive,
+		Amount:                 10000,
+		Currency:               models.CurrencySEK,
+		Interval:               "month",
+		IntervalCount:          1,
+		CurrentPeriodStart:     now.Add(-24 * time.Hour),
+		CurrentPeriodEnd:       now.Add(24 * time.Hour),
+		ProductName:            "Starter",
+		Items: []models.SubscriptionItem{{
+			ID:             uuid.New(),
+			SubscriptionID: subscriptionID,
+			ProductName:    "Starter",
+			UnitAmount:     10000,
+			Quantity:       1,
+		}},
+	}
+}
+
+func TestSubscriptionScheduleService_CreateSchedule_FallsBackToScheduler(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	stripeCustomerID := "cus_test123"
+	customer := &models.Customer{ID: uuid.New(), UserID: userID, StripeCustomerID: &stripeCustomerID}
+	subscription := newScheduledSubscription(customer.ID)
+
+	mockScheduleRepo := new(MockSubscriptionScheduleRepository)
+	mockSubRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	subscriptionService := NewSubscriptionService(mockSubRepo, mockCustomerRepo, mockAuditRepo, nil, nil, mockFactory)
+	service := NewSubscriptionScheduleService(mockScheduleRepo, mockSubRepo, mockCustomerRepo, mockAuditRepo, subscriptionService, nil, mockFactory)
+
+	mockSubRepo.On("GetByID", ctx, subscription.ID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("CreateSubscriptionSchedule", ctx, mock.MatchedBy(func(req *providers.CreateSubscriptionScheduleRequest) bool {
+		return req.SubscriptionID == "sub_test123" && len(req.Phases) == 2
+	})).Return("", providers.ErrNotSupported)
+	mockScheduleRepo.On("Create", ctx, mock.AnythingOfType("*models.SubscriptionSchedule")).Return(nil)
+	mockAuditRepo.On("Create", ctx, mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	// Execute: three months of Pro, then back to the current plan
+	schedule, err := service.CreateSchedule(ctx, userID, "test@example.com", "Test", &models.CreateSubscriptionScheduleRequest{
+		SubscriptionID: &subscription.ID,
+		Phases: []models.SchedulePhaseRequest{
+			{Items: []models.SubscriptionItemRequest{{ProductName: "Pro", UnitAmount: 20000}}, Iterations: 3},
+			{Items: []models.SubscriptionItemRequest{{ProductName: "Starter", UnitAmount: 10000}}},
+		},
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Nil(t, schedule.ProviderScheduleID)
+	assert.Equal(t, models.SubscriptionScheduleStatusNotStarted, schedule.Status)
+	assert.Equal(t, models.ScheduleEndBehaviorRelease, schedule.EndBehavior)
+	assert.Equal(t, subscription.CurrentPeriodEnd, *schedule.NextTransitionAt)
+	assert.Len(t, schedule.Phases, 2)
+	assert.Equal(t, "month", schedule.Phases[0].Interval)
+	assert.Equal(t, models.AddInterval(subscription.CurrentPeriodEnd, "month", 3), schedule.Phases[0].EndDate)
+	assert.Equal(t, schedule.Phases[0].EndDate, schedule.Phases[1].StartDate)
+	mockScheduleRepo.AssertExpectations(t)
+}
+
+func TestSubscriptionScheduleService_CreateSchedule_CancelAtPeriodEnd(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	subscription := newScheduledSubscription(customer.ID)
+	subscription.CancelAtPeriodEnd = true
+
+	mockScheduleRepo := new(MockSubscriptionScheduleRepository)
+	mockSubRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockFactory := new(MockProviderFactory)
+	subscriptionService := NewSubscriptionService(mockSubRepo, mockCustomerRepo, nil, nil, nil, mockFactory)
+	service := NewSubscriptionScheduleService(mockScheduleRepo, mockSubRepo, mockCustomerRepo, nil, subscriptionService, nil, mockFactory)
+
+	mockSubRepo.On("GetByID", ctx, subscription.ID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+
+	// Execute
+	schedule, err := service.CreateSchedule(ctx, userID, "", "", &models.CreateSubscriptionScheduleRequest{
+		SubscriptionID: &subscription.ID,
+		Phases:         []models.SchedulePhaseRequest{{Iterations: 2}},
+	})
+
+	// Assert
+	assert.Nil(t, schedule)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+	mockScheduleRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestSubscriptionScheduleService_ProcessSchedules_AppliesPhase(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	customerID := uuid.New()
+	subscription := newScheduledSubscription(customerID)
+	transitionAt := time.Now().Add(-time.Minute)
+
+	schedule := models.SubscriptionSchedule{
+		ID:             uuid.New(),
+		CustomerID:     customerID,
+		SubscriptionID: &subscription.ID,
+		Provider:       models.ProviderStripe,
+		Status:         models.SubscriptionScheduleStatusNotStarted,
+		EndBehavior:    models.ScheduleEndBehaviorRelease,
+		Phases: models.SchedulePhases{{
+			Items:         []models.SubscriptionItemRequest{{ProductName: "Pro", UnitAmount: 20000, Quantity: 1}},
+			Interval:      "year",
+			IntervalCount: 1,
+			Iterations:    1,
+			StartDate:     transitionAt,
+			EndDate:       models.AddInterval(transitionAt, "year", 1),
+		}},
+		NextTransitionAt: &transitionAt,
+	}
+
+	changed := *subscription
+	changed.Interval = "year"
+	providerItemID := "si_pro"
+	changed.Items = []models.SubscriptionItem{{ProductName: "Pro", UnitAmount: 20000, Quantity: 1, ProviderItemID: &providerItemID}}
+
+	mockScheduleRepo := new(MockSubscriptionScheduleRepository)
+	mockSubRepo := new(MockSubscriptionRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewSubscriptionScheduleService(mockScheduleRepo, mockSubRepo, nil, mockAuditRepo, nil, nil, mockFactory)
+
+	mockScheduleRepo.On("ListDue", ctx, mock.AnythingOfType("time.Time"), scheduleBatchSize).Return([]models.SubscriptionSchedule{schedule}, nil)
+	mockScheduleRepo.On("ClaimTransition", ctx, schedule.ID, transitionAt).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockSubRepo.On("GetByID", ctx, subscription.ID).Return(subscription, nil)
+	mockProvider.On("ChangeSubscriptionPlan", ctx, "sub_test123", mock.MatchedBy(func(req *providers.ChangeSubscriptionPlanRequest) bool {
+		return req.Interval == "year" && len(req.Items) == 1 && req.Items[0].UnitAmount == 20000
+	})).Return(&changed, nil)
+	mockSubRepo.On("SaveItems", ctx, subscription).Return(nil)
+	mockSubRepo.On("Update", ctx, subscription).Return(nil)
+	mockScheduleRepo.On("Update", ctx, mock.MatchedBy(func(s *models.SubscriptionSchedule) bool {
+		return s.Status == models.SubscriptionScheduleStatusActive &&
+			*s.CurrentPhase == 0 &&
+			s.NextTransitionAt.Equal(s.Phases[0].EndDate)
+	})).Return(nil)
+	mockAuditRepo.On("Create", ctx, mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	// Execute
+	result, err := service.ProcessSchedules(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Started)
+	assert.Equal(t, 0, result.Failed)
+	assert.Equal(t, "year", subscription.Interval)
+	assert.Equal(t, int64(20000), subscription.Amount)
+	mockProvider.AssertExpectations(t)
+	mockScheduleRepo.AssertExpectations(t)
+}
+
+func TestSubscriptionScheduleService_ProcessSchedules_RetriesFailedPhase(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	customerID := uuid.New()
+	subscription := newScheduledSubscription(customerID)
+	transitionAt := time.Now().Add(-time.Minute)
+
+	schedule := models.SubscriptionSchedule{
+		ID:             uuid.New(),
+		CustomerID:     customerID,
+		SubscriptionID: &subscription.ID,
+		Provider:       models.ProviderStripe,
+		Status:         models.SubscriptionScheduleStatusNotStarted,
+		Phases: models.SchedulePhases{{
+			Items:         []models.SubscriptionItemRequest{{ProductName: "Pro", UnitAmount: 20000, Quantity: 1}},
+			Interval:      "month",
+			IntervalCount: 1,
+			Iterations:    1,
+			StartDate:     transitionAt,
+			EndDate:       models.AddInterval(transitionAt, "month", 1),
+		}},
+		NextTransitionAt: &transitionAt,
+	}
+
+	mockScheduleRepo := new(MockSubscriptionScheduleRepository)
+	mockSubRepo := new(MockSubscriptionRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewSubscriptionScheduleService(mockScheduleRepo, mockSubRepo, nil, nil, nil, nil, mockFactory)
+
+	mockScheduleRepo.On("ListDue", ctx, mock.AnythingOfType("time.Time"), scheduleBatchSize).Return([]models.SubscriptionSchedule{schedule}, nil)
+	mockScheduleRepo.On("ClaimTransition", ctx, schedule.ID, transitionAt).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockSubRepo.On("GetByID", ctx, subscription.ID).Return(subscription, nil)
+	mockProvider.On("ChangeSubscriptionPlan", ctx, "sub_test123", mock.Anything).Return(nil, assert.AnError)
+	// The transition is handed back so the next pass retries it
+	mockScheduleRepo.On("Update", ctx, mock.MatchedBy(func(s *models.SubscriptionSchedule) bool {
+		return s.Status == models.SubscriptionScheduleStatusNotStarted &&
+			s.CurrentPhase == nil &&
+			s.NextTransitionAt.Equal(transitionAt)
+	})).Return(nil)
+
+	// Execute
+	result, err := service.ProcessSchedules(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 0, result.Started)
+	mockScheduleRepo.AssertExpectations(t)
+	mockSubRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestSubscriptionScheduleService_CancelSchedule_ReleasesProviderSchedule(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	subscriptionID := uuid.New()
+	providerScheduleID := "sub_sched_123"
+	transitionAt := time.Now().Add(24 * time.Hour)
+
+	schedule := &models.SubscriptionSchedule{
+		ID:                 uuid.New(),
+		CustomerID:         customer.ID,
+		SubscriptionID:     &subscriptionID,
+		Provider:           models.ProviderStripe,
+		ProviderScheduleID: &providerScheduleID,
+		Status:             models.SubscriptionScheduleStatusNotStarted,
+		NextTransitionAt:   &transitionAt,
+	}
+
+	mockScheduleRepo := new(MockSubscriptionScheduleRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewSubscriptionScheduleService(mockScheduleRepo, nil, mockCustomerRepo, mockAuditRepo, nil, nil, mockFactory)
+
+	mockScheduleRepo.On("GetByID", ctx, schedule.ID).Return(schedule, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	mockScheduleRepo.On("ClaimTransition", ctx, schedule.ID, transitionAt).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("ReleaseSubscriptionSchedule", ctx, providerScheduleID).Return(nil)
+	mockScheduleRepo.On("Update", ctx, schedule).Return(nil)
+	mockAuditRepo.On("Create", ctx, mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	// Execute
+	result, err := service.CancelSchedule(ctx, schedule.ID, userID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionScheduleStatusReleased, result.Status)
+	assert.Nil(t, result.NextTransitionAt)
+	assert.NotNil(t, result.EndedAt)
+	mockProvider.AssertExpectations(t)
+	mockProvider.AssertNotCalled(t, "CancelSubscriptionSchedule", mock.Anything, mock.Anything)
+}
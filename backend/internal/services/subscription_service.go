@@ -2,11 +2,14 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"payment-service/internal/models"
 	"payment-service/internal/providers"
 	"payment-service/internal/repository"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -14,17 +17,26 @@ import (
 type SubscriptionService struct {
 	subscriptionRepo repository.SubscriptionRepositoryInterface
 	customerRepo     repository.CustomerRepositoryInterface
+	auditRepo        repository.AuditRepositoryInterface
+	couponService    *CouponService
+	taxService       *TaxService
 	providerFactory  ProviderFactoryInterface
 }
 
 func NewSubscriptionService(
 	subscriptionRepo repository.SubscriptionRepositoryInterface,
 	customerRepo repository.CustomerRepositoryInterface,
+	auditRepo repository.AuditRepositoryInterface,
+	couponService *CouponService,
+	taxService *TaxService,
 	providerFactory ProviderFactoryInterface,
 ) *SubscriptionService {
 	return &SubscriptionService{
 		subscriptionRepo: subscriptionRepo,
 		customerRepo:     customerRepo,
+		auditRepo:        auditRepo,
+		couponService:    couponService,
+		taxService:       taxService,
 		providerFactory:  providerFactory,
 	}
 }
@@ -36,6 +48,16 @@ func (s *SubscriptionService) CreateSubscription(
 	email, name string,
 	req *models.CreateSubscriptionRequest,
 ) (*models.Subscription, error) {
+	items, err := itemsFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	meteredPrices, err := meteredPricesFromRequest(req.MeteredPrices)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get or create customer
 	customer, err := s.getOrCreateCustomer(ctx, userID, email, name, req.Provider)
 	if err != nil {
@@ -46,6 +68,20 @@ func (s *SubscriptionService) CreateSubscription(
 		)
 	}
 
+	return s.createSubscription(ctx, customer, req, items, meteredPrices)
+}
+
+// createSubscription creates a subscription for a customer with the validated
+// items and metered prices of req
+func (s *SubscriptionService) createSubscription(
+	ctx context.Context,
+	customer *models.Customer,
+	req *models.CreateSubscriptionRequest,
+	items []models.SubscriptionItem,
+	meteredPrices []models.MeteredPrice,
+) (*models.Subscription, error) {
+	amount := models.ItemsAmount(items)
+
 	// Get provider
 	provider, err := s.providerFactory.GetProvider(req.Provider)
 	if err != nil {
@@ -70,21 +106,82 @@ func (s *SubscriptionService) CreateSubscription(
 		)
 	}
 
+	country, vatID := customerTaxLocation(customer, req.BillingCountry, req.VATID)
+
+	// Calculate tax per period; the provider applies the same rate to each invoice
+	tax, err := s.taxService.Calculate(&TaxRequest{
+		Amount:   amount,
+		Category: req.TaxCategory,
+		Behavior: req.TaxBehavior,
+		Country:  country,
+		VATID:    vatID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply promotion code
+	var discount *models.AppliedDiscount
+	var providerCouponID string
+	if req.PromotionCode != "" {
+		discount, err = s.couponService.ReserveDiscount(ctx, req.PromotionCode, customer, amount, req.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.Provider == models.ProviderStripe && discount.Coupon.StripeCouponID != nil {
+			providerCouponID = *discount.Coupon.StripeCouponID
+		} else {
+			s.couponService.ReleaseDiscount(ctx, discount)
+			return nil, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Promotion code is not supported for this provider",
+				http.StatusBadRequest,
+			)
+		}
+	}
+
+	// Trials do not require a card up front; unless told otherwise, cancel
+	// the subscription if none was added by the end of the trial
+	trialEndBehavior := req.TrialEndBehavior
+	if req.TrialPeriodDays > 0 && trialEndBehavior == "" {
+		trialEndBehavior = models.TrialEndBehaviorCancel
+	}
+
 	// Create subscription with provider
 	providerReq := &providers.CreateSubscriptionRequest{
 		CustomerID:         providerCustomerID,
-		Amount:             req.Amount,
 		Currency:           string(req.Currency),
 		Interval:           req.Interval,
 		IntervalCount:      req.IntervalCount,
 		ProductName:        req.ProductName,
 		ProductDescription: req.ProductDescription,
 		TrialPeriodDays:    req.TrialPeriodDays,
+		TrialEndBehavior:   string(trialEndBehavior),
+		CouponID:           providerCouponID,
+		TaxRate:            providerTaxRate(tax.Breakdown),
 		Metadata:           convertMetadataToStrings(req.Metadata),
 	}
+	for _, item := range items {
+		providerReq.Items = append(providerReq.Items, providers.SubscriptionItem{
+			ProductName: item.ProductName,
+			UnitAmount:  item.UnitAmount,
+			Quantity:    item.Quantity,
+		})
+	}
+	for _, price := range meteredPrices {
+		providerReq.MeteredPrices = append(providerReq.MeteredPrices, providers.MeteredPrice{
+			Meter:       price.Meter,
+			UnitAmount:  price.UnitAmount,
+			Aggregation: price.Aggregation,
+		})
+	}
 
 	providerSubscription, err := provider.CreateSubscription(ctx, providerReq)
 	if err != nil {
+		if discount != nil {
+			s.couponService.ReleaseDiscount(ctx, discount)
+		}
 		return nil, models.NewAPIError(
 			models.ErrCodeProviderError,
 			"Failed to create subscription with provider",
@@ -99,6 +196,15 @@ func (s *SubscriptionService) CreateSubscription(
 		providerSubscription.ProductDescription = &req.ProductDescription
 	}
 	providerSubscription.Metadata = req.Metadata
+	providerSubscription.MeteredPrices = linkMeteredPrices(meteredPrices, providerSubscription.MeteredPrices)
+	providerSubscription.TaxAmount = tax.Tax
+	providerSubscription.TaxBreakdown = tax.Breakdown
+	if trialEndBehavior != "" {
+		providerSubscription.TrialEndBehavior = &trialEndBehavior
+	}
+	if discount != nil {
+		applyDiscount(providerSubscription, discount)
+	}
 
 	if err := s.subscriptionRepo.Create(ctx, providerSubscription); err != nil {
 		return nil, models.NewAPIError(
@@ -108,6 +214,10 @@ func (s *SubscriptionService) CreateSubscription(
 		)
 	}
 
+	if discount != nil {
+		s.couponService.CompleteRedemption(ctx, discount, customer.ID, nil, &providerSubscription.ID)
+	}
+
 	return providerSubscription, nil
 }
 
@@ -195,6 +305,14 @@ func (s *SubscriptionService) UpdateSubscription(
 		return nil, err
 	}
 
+	// Undoing a scheduled cancellation follows the same rules as reactivation
+	reactivating := req.CancelAtPeriodEnd != nil && !*req.CancelAtPeriodEnd && subscription.CancelAtPeriodEnd
+	if reactivating {
+		if err := checkReactivatable(subscription); err != nil {
+			return nil, err
+		}
+	}
+
 	// Get provider
 	provider, err := s.providerFactory.GetProvider(subscription.Provider)
 	if err != nil {
@@ -226,8 +344,13 @@ func (s *SubscriptionService) UpdateSubscription(
 
 	// Update in database
 	subscription.Status = updatedSubscription.Status
+	subscription.CancelAt = updatedSubscription.CancelAt
 	subscription.CancelAtPeriodEnd = updatedSubscription.CancelAtPeriodEnd
 	subscription.CanceledAt = updatedSubscription.CanceledAt
+	if reactivating {
+		subscription.CancellationReason = nil
+		subscription.CancellationFeedback = nil
+	}
 	if req.Metadata != nil {
 		subscription.Metadata = req.Metadata
 	}
@@ -240,14 +363,20 @@ func (s *SubscriptionService) UpdateSubscription(
 		)
 	}
 
+	if reactivating {
+		s.recordReactivation(ctx, subscription, userID)
+	}
+
 	return subscription, nil
 }
 
-// CancelSubscription cancels a subscription
+// CancelSubscription cancels a subscription. req carries optional churn
+// feedback and may be nil.
 func (s *SubscriptionService) CancelSubscription(
 	ctx context.Context,
 	subscriptionID, userID uuid.UUID,
 	immediate bool,
+	req *models.CancelSubscriptionRequest,
 ) (*models.Subscription, error) {
 	// Get and verify ownership
 	subscription, err := s.GetSubscription(ctx, subscriptionID, userID)
@@ -281,8 +410,17 @@ func (s *SubscriptionService) CancelSubscription(
 
 	// Update in database
 	subscription.Status = canceledSubscription.Status
+	subscription.CancelAt = canceledSubscription.CancelAt
 	subscription.CancelAtPeriodEnd = canceledSubscription.CancelAtPeriodEnd
 	subscription.CanceledAt = canceledSubscription.CanceledAt
+	if req != nil {
+		if req.Reason != "" {
+			subscription.CancellationReason = &req.Reason
+		}
+		if req.Feedback != "" {
+			subscription.CancellationFeedback = &req.Feedback
+		}
+	}
 
 	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
 		return nil, models.NewAPIError(
@@ -292,9 +430,481 @@ func (s *SubscriptionService) CancelSubscription(
 		)
 	}
 
+	details := models.JSONBMap{"immediate": immediate}
+	if subscription.CancellationReason != nil {
+		details["reason"] = *subscription.CancellationReason
+	}
+	if subscription.CancellationFeedback != nil {
+		details["feedback"] = *subscription.CancellationFeedback
+	}
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		CustomerID:   &subscription.CustomerID,
+		ActorUserID:  &userID,
+		Action:       models.AuditActionSubscriptionCanceled,
+		ResourceType: "subscription",
+		ResourceID:   subscription.ID,
+		Details:      details,
+	})
+
 	return subscription, nil
 }
 
+// ReactivateSubscription clears a cancellation scheduled for the end of the
+// current period
+func (s *SubscriptionService) ReactivateSubscription(
+	ctx context.Context,
+	subscriptionID, userID uuid.UUID,
+) (*models.Subscription, error) {
+	// Get and verify ownership
+	subscription, err := s.GetSubscription(ctx, subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkReactivatable(subscription); err != nil {
+		return nil, err
+	}
+
+	// Get provider
+	provider, err := s.providerFactory.GetProvider(subscription.Provider)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Provider not available",
+			http.StatusBadRequest,
+		)
+	}
+
+	// Clear the scheduled cancellation with provider
+	reactivatedSubscription, err := provider.ReactivateSubscription(ctx, subscription.ProviderSubscriptionID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to reactivate subscription with provider",
+			http.StatusBadGateway,
+		)
+	}
+
+	// Update in database
+	subscription.Status = reactivatedSubscription.Status
+	subscription.CancelAt = nil
+	subscription.CancelAtPeriodEnd = false
+	subscription.CanceledAt = nil
+	subscription.CancellationReason = nil
+	subscription.CancellationFeedback = nil
+
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update subscription in database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	s.recordReactivation(ctx, subscription, userID)
+
+	return subscription, nil
+}
+
+// ExtendTrial moves the end of a subscription's trial. It is used by admins and
+// does not check ownership.
+func (s *SubscriptionService) ExtendTrial(
+	ctx context.Context,
+	subscriptionID, actorUserID uuid.UUID,
+	req *models.ExtendTrialRequest,
+) (*models.Subscription, error) {
+	subscription, err := s.subscriptionRepo.GetByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve subscription",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if subscription == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Subscription not found",
+			http.StatusNotFound,
+		)
+	}
+
+	if subscription.Status != models.SubscriptionStatusTrialing || subscription.TrialEnd == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Only subscriptions in a trial can have their trial extended",
+			http.StatusConflict,
+		)
+	}
+
+	if !req.TrialEnd.After(*subscription.TrialEnd) {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"trial_end must be after the current trial end",
+			http.StatusBadRequest,
+		)
+	}
+
+	// Get provider
+	provider, err := s.providerFactory.GetProvider(subscription.Provider)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Provider not available",
+			http.StatusBadRequest,
+		)
+	}
+
+	// Move the trial end with provider
+	updatedSubscription, err := provider.UpdateSubscription(ctx, subscription.ProviderSubscriptionID, &providers.UpdateSubscriptionRequest{
+		TrialEnd: &req.TrialEnd,
+	})
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to extend trial with provider",
+			http.StatusBadGateway,
+		)
+	}
+
+	// Update in database
+	previousTrialEnd := *subscription.TrialEnd
+	subscription.Status = updatedSubscription.Status
+	subscription.TrialEnd = updatedSubscription.TrialEnd
+	subscription.CurrentPeriodStart = updatedSubscription.CurrentPeriodStart
+	subscription.CurrentPeriodEnd = updatedSubscription.CurrentPeriodEnd
+
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update subscription in database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		CustomerID:   &subscription.CustomerID,
+		ActorUserID:  &actorUserID,
+		Action:       models.AuditActionSubscriptionTrialExtended,
+		ResourceType: "subscription",
+		ResourceID:   subscription.ID,
+		Details: models.JSONBMap{
+			"previous_trial_end": previousTrialEnd,
+			"trial_end":          subscription.TrialEnd,
+		},
+	})
+
+	return subscription, nil
+}
+
+// checkReactivatable verifies that a subscription has a scheduled cancellation
+// that can still be undone
+func checkReactivatable(subscription *models.Subscription) error {
+	if subscription.Status == models.SubscriptionStatusCanceled ||
+		subscription.Status == models.SubscriptionStatusIncompleteExpired {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Subscription has already ended and cannot be reactivated",
+			http.StatusConflict,
+		)
+	}
+
+	if !subscription.CancelAtPeriodEnd && subscription.CancelAt == nil {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Subscription is not scheduled for cancellation",
+			http.StatusConflict,
+		)
+	}
+
+	now := time.Now()
+	if !now.Before(subscription.CurrentPeriodEnd) ||
+		(subscription.CancelAt != nil && !now.Before(*subscription.CancelAt)) {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Subscription period has ended and cannot be reactivated",
+			http.StatusConflict,
+		)
+	}
+
+	return nil
+}
+
+// recordReactivation writes the audit entry for a reactivated subscription
+func (s *SubscriptionService) recordReactivation(ctx context.Context, subscription *models.Subscription, userID uuid.UUID) {
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		CustomerID:   &subscription.CustomerID,
+		ActorUserID:  &userID,
+		Action:       models.AuditActionSubscriptionReactivated,
+		ResourceType: "subscription",
+		ResourceID:   subscription.ID,
+		Details: models.JSONBMap{
+			"current_period_end": subscription.CurrentPeriodEnd,
+		},
+	})
+}
+
+// PauseSubscription pauses payment collection on a subscription
+func (s *SubscriptionService) PauseSubscription(
+	ctx context.Context,
+	subscriptionID, userID uuid.UUID,
+	req *models.PauseSubscriptionRequest,
+) (*models.Subscription, error) {
+	// Get and verify ownership
+	subscription, err := s.GetSubscription(ctx, subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.Status != models.SubscriptionStatusActive && subscription.Status != models.SubscriptionStatusTrialing {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Cannot pause a subscription with status %s", subscription.Status),
+			http.StatusConflict,
+		)
+	}
+
+	// Get provider
+	provider, err := s.providerFactory.GetProvider(subscription.Provider)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Provider not available",
+			http.StatusBadRequest,
+		)
+	}
+
+	// Pause with provider
+	pausedSubscription, err := provider.PauseSubscription(
+		ctx,
+		subscription.ProviderSubscriptionID,
+		&providers.PauseSubscriptionRequest{
+			Behavior:  string(req.Behavior),
+			ResumesAt: req.ResumesAt,
+		},
+	)
+	if errors.Is(err, providers.ErrNotSupported) {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Pausing is not supported for this provider",
+			http.StatusBadRequest,
+		)
+	}
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to pause subscription with provider",
+			http.StatusBadGateway,
+		)
+	}
+
+	// Update in database
+	now := time.Now()
+	subscription.Status = models.SubscriptionStatusPaused
+	subscription.PauseBehavior = &req.Behavior
+	subscription.PausedAt = &now
+	subscription.ResumesAt = pausedSubscription.ResumesAt
+
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update subscription in database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return subscription, nil
+}
+
+// ResumeSubscription resumes payment collection on a paused subscription
+func (s *SubscriptionService) ResumeSubscription(
+	ctx context.Context,
+	subscriptionID, userID uuid.UUID,
+) (*models.Subscription, error) {
+	// Get and verify ownership
+	subscription, err := s.GetSubscription(ctx, subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.Status != models.SubscriptionStatusPaused {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Subscription is not paused",
+			http.StatusConflict,
+		)
+	}
+
+	// Get provider
+	provider, err := s.providerFactory.GetProvider(subscription.Provider)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Provider not available",
+			http.StatusBadRequest,
+		)
+	}
+
+	// Resume with provider
+	resumedSubscription, err := provider.ResumeSubscription(ctx, subscription.ProviderSubscriptionID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to resume subscription with provider",
+			http.StatusBadGateway,
+		)
+	}
+
+	// Update in database
+	subscription.Status = resumedSubscription.Status
+	subscription.CurrentPeriodStart = resumedSubscription.CurrentPeriodStart
+	subscription.CurrentPeriodEnd = resumedSubscription.CurrentPeriodEnd
+	subscription.PauseBehavior = nil
+	subscription.PausedAt = nil
+	subscription.ResumesAt = nil
+
+	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update subscription in database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return subscription, nil
+}
+
+// itemsFromRequest validates the items requested for a new subscription. A
+// subscription created with just an Amount has a single item.
+func itemsFromRequest(req *models.CreateSubscriptionRequest) ([]models.SubscriptionItem, error) {
+	invalid := func(message string) error {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, message, http.StatusBadRequest)
+	}
+
+	if len(req.Items) == 0 {
+		return []models.SubscriptionItem{{
+			ProductName: req.ProductName,
+			UnitAmount:  req.Amount,
+			Quantity:    1,
+		}}, nil
+	}
+
+	if req.Amount != 0 {
+		return nil, invalid("Set either amount or items, not both")
+	}
+
+	return validateItems(req.Items, req.ProductName)
+}
+
+// validateItems validates requested subscription items. Product names
+// default to productName and quantities to 1.
+func validateItems(requested []models.SubscriptionItemRequest, productName string) ([]models.SubscriptionItem, error) {
+	invalid := func(message string) error {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, message, http.StatusBadRequest)
+	}
+
+	items := make([]models.SubscriptionItem, 0, len(requested))
+	for _, item := range requested {
+		if item.UnitAmount < 0 {
+			return nil, invalid("unit_amount cannot be negative")
+		}
+		if item.Quantity < 0 {
+			return nil, invalid("quantity must be at least 1")
+		}
+
+		itemProductName := strings.TrimSpace(item.ProductName)
+		if itemProductName == "" {
+			itemProductName = productName
+		}
+		quantity := item.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+
+		items = append(items, models.SubscriptionItem{
+			ProductName: itemProductName,
+			UnitAmount:  item.UnitAmount,
+			Quantity:    quantity,
+		})
+	}
+
+	return items, nil
+}
+
+// meteredPricesFromRequest validates the metered prices requested for a new
+// subscription. Aggregation defaults to sum.
+func meteredPricesFromRequest(requested []models.MeteredPriceRequest) ([]models.MeteredPrice, error) {
+	invalid := func(message string) error {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, message, http.StatusBadRequest)
+	}
+
+	prices := make([]models.MeteredPrice, 0, len(requested))
+	seen := make(map[string]bool, len(requested))
+	for _, req := range requested {
+		meter := strings.TrimSpace(req.Meter)
+		if meter == "" {
+			return nil, invalid("meter is required for metered prices")
+		}
+		if seen[meter] {
+			return nil, invalid(fmt.Sprintf("Duplicate metered price for meter %s", meter))
+		}
+		seen[meter] = true
+
+		if req.UnitAmount < 0 {
+			return nil, invalid("unit_amount cannot be negative")
+		}
+
+		aggregation := req.Aggregation
+		switch aggregation {
+		case "":
+			aggregation = models.UsageAggregationSum
+		case models.UsageAggregationSum, models.UsageAggregationMax, models.UsageAggregationLast:
+		default:
+			return nil, invalid("aggregation must be sum, max or last")
+		}
+
+		prices = append(prices, models.MeteredPrice{
+			Meter:       meter,
+			UnitAmount:  req.UnitAmount,
+			Aggregation: aggregation,
+		})
+	}
+
+	return prices, nil
+}
+
+// linkMeteredPrices sets the provider item of each requested metered price.
+// Prices the provider created no item for are invoiced locally.
+func linkMeteredPrices(prices, providerPrices []models.MeteredPrice) []models.MeteredPrice {
+	for i := range prices {
+		for _, providerPrice := range providerPrices {
+			if providerPrice.Meter == prices[i].Meter {
+				prices[i].ProviderItemID = providerPrice.ProviderItemID
+			}
+		}
+	}
+	return prices
+}
+
+// applyDiscount records a coupon on a new subscription and when its discount stops applying
+func applyDiscount(subscription *models.Subscription, discount *models.AppliedDiscount) {
+	subscription.DiscountAmount = discount.Amount
+	subscription.CouponID = &discount.Coupon.ID
+	subscription.PromotionCodeID = &discount.PromotionCode.ID
+
+	switch discount.Coupon.Duration {
+	case models.CouponDurationOnce:
+		endsAt := subscription.CurrentPeriodEnd
+		subscription.DiscountEndsAt = &endsAt
+	case models.CouponDurationRepeating:
+		if discount.Coupon.DurationInMonths != nil {
+			endsAt := subscription.CurrentPeriodStart.AddDate(0, *discount.Coupon.DurationInMonths, 0)
+			subscription.DiscountEndsAt = &endsAt
+		}
+	}
+}
+
 // getOrCreateCustomer gets an existing customer or creates a new one
 func (s *SubscriptionService) getOrCreateCustomer(
 	ctx context.Context,
@@ -383,3 +993,383 @@ func (s *SubscriptionService) addProviderToCustomer(
 
 	return customer, nil
 }
+
+// AddItem adds an item to a subscription, e.g. an add-on, prorated as requested
+func (s *SubscriptionService) AddItem(
+	ctx context.Context,
+	subscriptionID, userID uuid.UUID,
+	req *models.AddSubscriptionItemRequest,
+) (*models.Subscription, error) {
+	invalid := func(message string) error {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, message, http.StatusBadRequest)
+	}
+
+	productName := strings.TrimSpace(req.ProductName)
+	if productName == "" {
+		return nil, invalid("product_name is required")
+	}
+	if req.UnitAmount < 0 {
+		return nil, invalid("unit_amount cannot be negative")
+	}
+	quantity := req.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 0 {
+		return nil, invalid("quantity must be at least 1")
+	}
+	proration, err := prorationBehavior(req.ProrationBehavior)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, provider, err := s.getSubscriptionForItemChange(ctx, subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := provider.AddSubscriptionItem(ctx, subscription.ProviderSubscriptionID, &providers.AddSubscriptionItemRequest{
+		ProductName:       productName,
+		UnitAmount:        req.UnitAmount,
+		Quantity:          quantity,
+		Currency:          string(subscription.Currency),
+		Interval:          subscription.Interval,
+		IntervalCount:     subscription.IntervalCount,
+		ProrationBehavior: string(proration),
+	})
+	if errors.Is(err, providers.ErrNotSupported) {
+		return nil, unsupportedProration()
+	}
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to add subscription item with provider",
+			http.StatusBadGateway,
+		)
+	}
+
+	setSubscriptionItems(subscription, append(subscription.Items, *item))
+	if err := s.saveItemChange(ctx, subscription, userID, "added", len(subscription.Items)-1, proration); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// UpdateItem changes the quantity of a subscription item, e.g. the number of
+// seats, prorated as requested
+func (s *SubscriptionService) UpdateItem(
+	ctx context.Context,
+	subscriptionID, itemID, userID uuid.UUID,
+	req *models.UpdateSubscriptionItemRequest,
+) (*models.Subscription, error) {
+	if req.Quantity < 1 {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"quantity must be at least 1",
+			http.StatusBadRequest,
+		)
+	}
+	proration, err := prorationBehavior(req.ProrationBehavior)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, provider, err := s.getSubscriptionForItemChange(ctx, subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := findSubscriptionItem(subscription, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := provider.UpdateSubscriptionItem(
+		ctx,
+		subscription.ProviderSubscriptionID,
+		*subscription.Items[index].ProviderItemID,
+		&providers.UpdateSubscriptionItemRequest{
+			Quantity:          req.Quantity,
+			ProrationBehavior: string(proration),
+		},
+	)
+	if errors.Is(err, providers.ErrNotSupported) {
+		return nil, unsupportedProration()
+	}
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update subscription item with provider",
+			http.StatusBadGateway,
+		)
+	}
+
+	items := append([]models.SubscriptionItem(nil), subscription.Items...)
+	items[index].Quantity = item.Quantity
+	setSubscriptionItems(subscription, items)
+	if err := s.saveItemChange(ctx, subscription, userID, "updated", index, proration); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// RemoveItem removes an item from a subscription, prorated as requested. The
+// last item cannot be removed; the subscription is canceled instead.
+func (s *SubscriptionService) RemoveItem(
+	ctx context.Context,
+	subscriptionID, itemID, userID uuid.UUID,
+	requestedProration models.ProrationBehavior,
+) (*models.Subscription, error) {
+	proration, err := prorationBehavior(requestedProration)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, provider, err := s.getSubscriptionForItemChange(ctx, subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := findSubscriptionItem(subscription, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(subscription.Items) == 1 {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"Cannot remove the last item of a subscription; cancel the subscription instead",
+			http.StatusConflict,
+		)
+	}
+
+	removed := subscription.Items[index]
+	err = provider.DeleteSubscriptionItem(ctx, subscription.ProviderSubscriptionID, *removed.ProviderItemID, string(proration))
+	if errors.Is(err, providers.ErrNotSupported) {
+		return nil, unsupportedProration()
+	}
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to remove subscription item with provider",
+			http.StatusBadGateway,
+		)
+	}
+
+	items := make([]models.SubscriptionItem, 0, len(subscription.Items)-1)
+	items = append(items, subscription.Items[:index]...)
+	items = append(items, subscription.Items[index+1:]...)
+	setSubscriptionItems(subscription, items)
+
+	if err := s.subscriptionRepo.SaveItems(ctx, subscription); err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update subscription in database",
+			http.StatusInternalServerError,
+		)
+	}
+	s.recordItemChange(ctx, subscription, userID, "removed", &removed, proration)
+
+	return subscription, nil
+}
+
+// getSubscriptionForItemChange returns a subscription whose items can be
+// changed and its provider. Items created before items were tracked are
+// linked to their provider items first.
+func (s *SubscriptionService) getSubscriptionForItemChange(
+	ctx context.Context,
+	subscriptionID, userID uuid.UUID,
+) (*models.Subscription, providers.PaymentProvider, error) {
+	// Get and verify ownership
+	subscription, err := s.GetSubscription(ctx, subscriptionID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if subscription.Status == models.SubscriptionStatusCanceled ||
+		subscription.Status == models.SubscriptionStatusIncompleteExpired {
+		return nil, nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Cannot change items of a subscription with status %s", subscription.Status),
+			http.StatusConflict,
+		)
+	}
+
+	// Get provider
+	provider, err := s.providerFactory.GetProvider(subscription.Provider)
+	if err != nil {
+		return nil, nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Provider not available",
+			http.StatusBadRequest,
+		)
+	}
+
+	linked := len(subscription.Items) > 0
+	for _, item := range subscription.Items {
+		if item.ProviderItemID == nil {
+			linked = false
+		}
+	}
+
+	if !linked {
+		providerSubscription, err := provider.GetSubscription(ctx, subscription.ProviderSubscriptionID)
+		if err != nil {
+			return nil, nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to retrieve subscription from provider",
+				http.StatusBadGateway,
+			)
+		}
+		syncSubscriptionItems(subscription, providerSubscription.Items)
+	}
+
+	return subscription, provider, nil
+}
+
+// saveItemChange stores a subscription's items and audits the change to the
+// item at index
+func (s *SubscriptionService) saveItemChange(
+	ctx context.Context,
+	subscription *models.Subscription,
+	userID uuid.UUID,
+	change string,
+	index int,
+	proration models.ProrationBehavior,
+) error {
+	if err := s.subscriptionRepo.SaveItems(ctx, subscription); err != nil {
+		return models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to update subscription in database",
+			http.StatusInternalServerError,
+		)
+	}
+
+	s.recordItemChange(ctx, subscription, userID, change, &subscription.Items[index], proration)
+	return nil
+}
+
+// recordItemChange writes the audit entry for a changed subscription item
+func (s *SubscriptionService) recordItemChange(
+	ctx context.Context,
+	subscription *models.Subscription,
+	userID uuid.UUID,
+	change string,
+	item *models.SubscriptionItem,
+	proration models.ProrationBehavior,
+) {
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		CustomerID:   &subscription.CustomerID,
+		ActorUserID:  &userID,
+		Action:       models.AuditActionSubscriptionItemsChanged,
+		ResourceType: "subscription",
+		ResourceID:   subscription.ID,
+		Details: models.JSONBMap{
+			"change":             change,
+			"item_id":            item.ID,
+			"product_name":       item.ProductName,
+			"quantity":           item.Quantity,
+			"amount":             subscription.Amount,
+			"proration_behavior": proration,
+		},
+	})
+}
+
+// prorationBehavior validates a requested proration behavior, defaulting to
+// create_prorations
+func prorationBehavior(behavior models.ProrationBehavior) (models.ProrationBehavior, error) {
+	switch behavior {
+	case "":
+		return models.ProrationBehaviorCreateProrations, nil
+	case models.ProrationBehaviorCreateProrations, models.ProrationBehaviorAlwaysInvoice, models.ProrationBehaviorNone:
+		return behavior, nil
+	default:
+		return "", models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"proration_behavior must be create_prorations, always_invoice or none",
+			http.StatusBadRequest,
+		)
+	}
+}
+
+// findSubscriptionItem returns the index of an item of a subscription
+func findSubscriptionItem(subscription *models.Subscription, itemID uuid.UUID) (int, error) {
+	for i, item := range subscription.Items {
+		if item.ID == itemID && item.ProviderItemID != nil {
+			return i, nil
+		}
+	}
+	return 0, models.NewAPIError(
+		models.ErrCodeNotFound,
+		"Subscription item not found",
+		http.StatusNotFound,
+	)
+}
+
+// syncSubscriptionItems replaces a subscription's items with the provider's,
+// keeping the IDs of items already stored. Items stored before items were
+// tracked take the provider items not matched otherwise, in order. Returns
+// whether anything changed.
+func syncSubscriptionItems(subscription *models.Subscription, providerItems []models.SubscriptionItem) bool {
+	byProviderID := make(map[string]models.SubscriptionItem, len(subscription.Items))
+	var unlinked []models.SubscriptionItem
+	for _, item := range subscription.Items {
+		if item.ProviderItemID != nil {
+			byProviderID[*item.ProviderItemID] = item
+		} else {
+			unlinked = append(unlinked, item)
+		}
+	}
+
+	changed := len(providerItems) != len(subscription.Items)
+	items := make([]models.SubscriptionItem, 0, len(providerItems))
+	for _, providerItem := range providerItems {
+		local, ok := byProviderID[*providerItem.ProviderItemID]
+		if !ok && len(unlinked) > 0 {
+			local, unlinked = unlinked[0], unlinked[1:]
+		}
+		if !ok {
+			changed = true
+		} else if local.ProductName != providerItem.ProductName ||
+			local.UnitAmount != providerItem.UnitAmount ||
+			local.Quantity != providerItem.Quantity {
+			changed = true
+		}
+
+		providerItem.ID = local.ID
+		providerItem.SubscriptionID = subscription.ID
+		providerItem.CreatedAt = local.CreatedAt
+		providerItem.UpdatedAt = local.UpdatedAt
+		items = append(items, providerItem)
+	}
+
+	if changed {
+		setSubscriptionItems(subscription, items)
+	} else {
+		subscription.Items = items
+	}
+	return changed
+}
+
+// setSubscriptionItems sets a subscription's items and the amount and tax per
+// period they add up to. Tax stays at the subscription's rate.
+func setSubscriptionItems(subscription *models.Subscription, items []models.SubscriptionItem) {
+	subscription.Items = items
+	subscription.Amount = models.ItemsAmount(items)
+
+	tax := taxAtRate(subscription.Amount, subscription.TaxBreakdown)
+	subscription.TaxAmount = tax.Tax
+	subscription.TaxBreakdown = tax.Breakdown
+}
+
+// unsupportedProration is returned when a provider only takes item changes
+// billed from the next period, e.g. for natively billed subscriptions
+func unsupportedProration() error {
+	return models.NewAPIError(
+		models.ErrCodeInvalidRequest,
+		"This provider only supports item changes with proration_behavior none",
+		http.StatusBadRequest,
+	)
+}
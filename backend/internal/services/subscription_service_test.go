@@ -0,0 +1,606 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSubscriptionRepository is a mock for SubscriptionRepository
+type MockSubscriptionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSubscriptionRepository) Create(ctx context.Context, subscription *models.Subscription) error {
+	args := m.Called(ctx, subscription)
+	return args.Error(0)
+}
+
+func (m *MockSubscriptionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) GetByProviderSubscriptionID(ctx context.Context, providerSubscriptionID string) (*models.Subscription, error) {
+	args := m.Called(ctx, providerSubscriptionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.Subscription, int, error) {
+	args := m.Called(ctx, customerID, limit, offset)
+	return args.Get(0).([]models.Subscription), args.Int(1), args.Error(2)
+}
+
+func (m *MockSubscriptionRepository) Update(ctx context.Context, subscription *models.Subscription) error {
+	args := m.Called(ctx, subscription)
+	return args.Error(0)
+}
+
+func (m *MockSubscriptionRepository) SaveItems(ctx context.Context, subscription *models.Subscription) error {
+	args := m.Called(ctx, subscription)
+	return args.Error(0)
+}
+
+func (m *MockSubscriptionRepository) ListDunningDue(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]models.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ClaimDunningRetry(ctx context.Context, id uuid.UUID, nextRetryAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, nextRetryAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ClaimDunningExhaustion(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	args := m.Called(ctx, id, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ListBillingDue(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]models.Subscription), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) ClaimBilling(ctx context.Context, id uuid.UUID, nextBillingAt, leaseUntil time.Time) (bool, error) {
+	args := m.Called(ctx, id, nextBillingAt, leaseUntil)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSubscriptionRepository) SaveBillingRun(ctx context.Context, subscription *models.Subscription, status models.SubscriptionStatus, leaseUntil time.Time) (bool, error) {
+	args := m.Called(ctx, subscription, status, leaseUntil)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockAuditRepository is a mock for AuditRepository
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.AuditEvent, int, error) {
+	args := m.Called(ctx, customerID, limit, offset)
+	return args.Get(0).([]models.AuditEvent), args.Int(1), args.Error(2)
+}
+
+func TestSubscriptionService_PauseSubscription_Success(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+	resumesAt := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewSubscriptionService(mockSubscriptionRepo, mockCustomerRepo, new(MockAuditRepository), nil, nil, mockFactory)
+
+	subscription := &models.Subscription{
+		ID:                     subscriptionID,
+		CustomerID:             customerID,
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusActive,
+	}
+
+	behavior := models.PauseBehaviorKeepAsDraft
+	providerSubscription := &models.Subscription{
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusPaused,
+		PauseBehavior:          &behavior,
+		ResumesAt:              &resumesAt,
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(&models.Customer{ID: customerID, UserID: userID}, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("PauseSubscription", ctx, "sub_test123", &providers.PauseSubscriptionRequest{
+		Behavior:  "keep_as_draft",
+		ResumesAt: &resumesAt,
+	}).Return(providerSubscription, nil)
+	mockSubscriptionRepo.On("Update", ctx, subscription).Return(nil)
+
+	// Execute
+	result, err := service.PauseSubscription(ctx, subscriptionID, userID, &models.PauseSubscriptionRequest{
+		Behavior:  models.PauseBehaviorKeepAsDraft,
+		ResumesAt: &resumesAt,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusPaused, result.Status)
+	assert.Equal(t, models.PauseBehaviorKeepAsDraft, *result.PauseBehavior)
+	assert.NotNil(t, result.PausedAt)
+	assert.Equal(t, resumesAt, *result.ResumesAt)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+}
+
+func TestSubscriptionService_PauseSubscription_Canceled(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewSubscriptionService(mockSubscriptionRepo, mockCustomerRepo, new(MockAuditRepository), nil, nil, mockFactory)
+
+	subscription := &models.Subscription{
+		ID:         subscriptionID,
+		CustomerID: customerID,
+		Provider:   models.ProviderStripe,
+		Status:     models.SubscriptionStatusCanceled,
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(&models.Customer{ID: customerID, UserID: userID}, nil)
+
+	// Execute
+	result, err := service.PauseSubscription(ctx, subscriptionID, userID, &models.PauseSubscriptionRequest{
+		Behavior: models.PauseBehaviorVoid,
+	})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, models.ErrCodeInvalidRequest, apiErr.Code)
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+}
+
+func TestSubscriptionService_ResumeSubscription_Success(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+	pausedAt := time.Now().Add(-24 * time.Hour)
+	behavior := models.PauseBehaviorVoid
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewSubscriptionService(mockSubscriptionRepo, mockCustomerRepo, new(MockAuditRepository), nil, nil, mockFactory)
+
+	subscription := &models.Subscription{
+		ID:                     subscriptionID,
+		CustomerID:             customerID,
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusPaused,
+		PauseBehavior:          &behavior,
+		PausedAt:               &pausedAt,
+	}
+
+	providerSubscription := &models.Subscription{
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusActive,
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(&models.Customer{ID: customerID, UserID: userID}, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("ResumeSubscription", ctx, "sub_test123").Return(providerSubscription, nil)
+	mockSubscriptionRepo.On("Update", ctx, subscription).Return(nil)
+
+	// Execute
+	result, err := service.ResumeSubscription(ctx, subscriptionID, userID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusActive, result.Status)
+	assert.Nil(t, result.PauseBehavior)
+	assert.Nil(t, result.PausedAt)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+}
+
+func TestSubscriptionService_ReactivateSubscription_Success(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+	periodEnd := time.Now().Add(10 * 24 * time.Hour)
+	reason := models.CancellationReasonTooExpensive
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewSubscriptionService(mockSubscriptionRepo, mockCustomerRepo, mockAuditRepo, nil, nil, mockFactory)
+
+	subscription := &models.Subscription{
+		ID:                     subscriptionID,
+		CustomerID:             customerID,
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusActive,
+		CurrentPeriodEnd:       periodEnd,
+		CancelAt:               &periodEnd,
+		CancelAtPeriodEnd:      true,
+		CancellationReason:     &reason,
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(&models.Customer{ID: customerID, UserID: userID}, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("ReactivateSubscription", ctx, "sub_test123").Return(&models.Subscription{
+		Status: models.SubscriptionStatusActive,
+	}, nil)
+	mockSubscriptionRepo.On("Update", ctx, subscription).Return(nil)
+	mockAuditRepo.On("Create", ctx, mock.MatchedBy(func(event *models.AuditEvent) bool {
+		return event.Action == models.AuditActionSubscriptionReactivated &&
+			event.ResourceID == subscriptionID && *event.ActorUserID == userID
+	})).Return(nil)
+
+	// Execute
+	result, err := service.ReactivateSubscription(ctx, subscriptionID, userID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, result.CancelAtPeriodEnd)
+	assert.Nil(t, result.CancelAt)
+	assert.Nil(t, result.CancellationReason)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+	mockAuditRepo.AssertExpectations(t)
+}
+
+func TestSubscriptionService_ReactivateSubscription_PeriodEnded(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+	periodEnd := time.Now().Add(-time.Hour)
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewSubscriptionService(mockSubscriptionRepo, mockCustomerRepo, new(MockAuditRepository), nil, nil, mockFactory)
+
+	subscription := &models.Subscription{
+		ID:                subscriptionID,
+		CustomerID:        customerID,
+		Provider:          models.ProviderStripe,
+		Status:            models.SubscriptionStatusActive,
+		CurrentPeriodEnd:  periodEnd,
+		CancelAtPeriodEnd: true,
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(&models.Customer{ID: customerID, UserID: userID}, nil)
+
+	// Execute
+	result, err := service.ReactivateSubscription(ctx, subscriptionID, userID)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, models.ErrCodeInvalidRequest, apiErr.Code)
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+}
+
+func TestSubscriptionService_ExtendTrial_Success(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	adminUserID := uuid.New()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+	trialEnd := time.Now().Add(2 * 24 * time.Hour).Truncate(time.Second)
+	newTrialEnd := trialEnd.Add(14 * 24 * time.Hour)
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewSubscriptionService(mockSubscriptionRepo, new(MockCustomerRepository), mockAuditRepo, nil, nil, mockFactory)
+
+	subscription := &models.Subscription{
+		ID:                     subscriptionID,
+		CustomerID:             customerID,
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusTrialing,
+		TrialEnd:               &trialEnd,
+		CurrentPeriodEnd:       trialEnd,
+	}
+
+	providerSubscription := &models.Subscription{
+		Status:           models.SubscriptionStatusTrialing,
+		TrialEnd:         &newTrialEnd,
+		CurrentPeriodEnd: newTrialEnd,
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("UpdateSubscription", ctx, "sub_test123", mock.MatchedBy(func(req *providers.UpdateSubscriptionRequest) bool {
+		return req.TrialEnd != nil && req.TrialEnd.Equal(newTrialEnd)
+	})).Return(providerSubscription, nil)
+	mockSubscriptionRepo.On("Update", ctx, subscription).Return(nil)
+	mockAuditRepo.On("Create", ctx, mock.MatchedBy(func(event *models.AuditEvent) bool {
+		return event.Action == models.AuditActionSubscriptionTrialExtended &&
+			event.ResourceID == subscriptionID && *event.ActorUserID == adminUserID
+	})).Return(nil)
+
+	// Execute
+	result, err := service.ExtendTrial(ctx, subscriptionID, adminUserID, &models.ExtendTrialRequest{TrialEnd: newTrialEnd})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, newTrialEnd, *result.TrialEnd)
+	assert.Equal(t, newTrialEnd, result.CurrentPeriodEnd)
+
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+	mockAuditRepo.AssertExpectations(t)
+}
+
+func TestSubscriptionService_ExtendTrial_NotTrialing(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	subscriptionID := uuid.New()
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewSubscriptionService(mockSubscriptionRepo, new(MockCustomerRepository), new(MockAuditRepository), nil, nil, mockFactory)
+
+	subscription := &models.Subscription{
+		ID:       subscriptionID,
+		Provider: models.ProviderStripe,
+		Status:   models.SubscriptionStatusActive,
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+
+	// Execute
+	result, err := service.ExtendTrial(ctx, subscriptionID, uuid.New(), &models.ExtendTrialRequest{
+		TrialEnd: time.Now().Add(7 * 24 * time.Hour),
+	})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, models.ErrCodeInvalidRequest, apiErr.Code)
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+}
+
+func TestSubscriptionService_UpdateItem_ChangesSeatsAndTax(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+	seatsID := uuid.New()
+	seatsItemID := "si_seats"
+	addonItemID := "si_addon"
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewSubscriptionService(mockSubscriptionRepo, mockCustomerRepo, mockAuditRepo, nil, nil, mockFactory)
+
+	subscription := &models.Subscription{
+		ID:                     subscriptionID,
+		CustomerID:             customerID,
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusActive,
+		Amount:                 35000,
+		TaxAmount:              8750,
+		TaxBreakdown: &models.TaxBreakdown{
+			Country:  "SE",
+			Behavior: models.TaxBehaviorExclusive,
+			Lines:    []models.TaxLine{{Rate: 25, Net: 35000, Tax: 8750}},
+		},
+		Items: []models.SubscriptionItem{
+			{ID: seatsID, ProductName: "Seat", UnitAmount: 10000, Quantity: 3, ProviderItemID: &seatsItemID},
+			{ID: uuid.New(), ProductName: "Support", UnitAmount: 5000, Quantity: 1, ProviderItemID: &addonItemID},
+		},
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(&models.Customer{ID: customerID, UserID: userID}, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("UpdateSubscriptionItem", ctx, "sub_test123", seatsItemID, &providers.UpdateSubscriptionItemRequest{
+		Quantity:          5,
+		ProrationBehavior: "create_prorations",
+	}).Return(&models.SubscriptionItem{ProductName: "Seat", UnitAmount: 10000, Quantity: 5, ProviderItemID: &seatsItemID}, nil)
+	mockSubscriptionRepo.On("SaveItems", ctx, subscription).Return(nil)
+	mockAuditRepo.On("Create", ctx, mock.MatchedBy(func(event *models.AuditEvent) bool {
+		return event.Action == models.AuditActionSubscriptionItemsChanged && event.Details["change"] == "updated"
+	})).Return(nil)
+
+	// Execute
+	result, err := service.UpdateItem(ctx, subscriptionID, seatsID, userID, &models.UpdateSubscriptionItemRequest{Quantity: 5})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), result.Items[0].Quantity)
+	assert.Equal(t, seatsID, result.Items[0].ID)
+	assert.Equal(t, int64(55000), result.Amount)
+	assert.Equal(t, int64(13750), result.TaxAmount)
+	assert.Equal(t, int64(13750), result.TaxBreakdown.Lines[0].Tax)
+
+	mockProvider.AssertExpectations(t)
+	mockSubscriptionRepo.AssertExpectations(t)
+	mockAuditRepo.AssertExpectations(t)
+}
+
+func TestSubscriptionService_UpdateItem_LinksUntrackedItem(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+	itemID := uuid.New()
+	providerItemID := "si_legacy"
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewSubscriptionService(mockSubscriptionRepo, mockCustomerRepo, mockAuditRepo, nil, nil, mockFactory)
+
+	// Stored before items were tracked, so the provider item is unknown
+	subscription := &models.Subscription{
+		ID:                     subscriptionID,
+		CustomerID:             customerID,
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusActive,
+		Amount:                 9900,
+		Items: []models.SubscriptionItem{
+			{ID: itemID, ProductName: "Team", UnitAmount: 9900, Quantity: 1},
+		},
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(&models.Customer{ID: customerID, UserID: userID}, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("GetSubscription", ctx, "sub_test123").Return(&models.Subscription{
+		Items: []models.SubscriptionItem{
+			{ProductName: "Team", UnitAmount: 9900, Quantity: 1, ProviderItemID: &providerItemID},
+		},
+	}, nil)
+	mockProvider.On("UpdateSubscriptionItem", ctx, "sub_test123", providerItemID, &providers.UpdateSubscriptionItemRequest{
+		Quantity:          2,
+		ProrationBehavior: "none",
+	}).Return(&models.SubscriptionItem{ProductName: "Team", UnitAmount: 9900, Quantity: 2, ProviderItemID: &providerItemID}, nil)
+	mockSubscriptionRepo.On("SaveItems", ctx, subscription).Return(nil)
+	mockAuditRepo.On("Create", ctx, mock.Anything).Return(nil)
+
+	// Execute
+	result, err := service.UpdateItem(ctx, subscriptionID, itemID, userID, &models.UpdateSubscriptionItemRequest{
+		Quantity:          2,
+		ProrationBehavior: models.ProrationBehaviorNone,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, itemID, result.Items[0].ID)
+	assert.Equal(t, providerItemID, *result.Items[0].ProviderItemID)
+	assert.Equal(t, int64(19800), result.Amount)
+	mockProvider.AssertExpectations(t)
+}
+
+func TestSubscriptionService_RemoveItem_LastItem(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	customerID := uuid.New()
+	subscriptionID := uuid.New()
+	itemID := uuid.New()
+	providerItemID := "si_only"
+
+	mockSubscriptionRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+
+	service := NewSubscriptionService(mockSubscriptionRepo, mockCustomerRepo, new(MockAuditRepository), nil, nil, mockFactory)
+
+	subscription := &models.Subscription{
+		ID:                     subscriptionID,
+		CustomerID:             customerID,
+		Provider:               models.ProviderStripe,
+		ProviderSubscriptionID: "sub_test123",
+		Status:                 models.SubscriptionStatusActive,
+		Items: []models.SubscriptionItem{
+			{ID: itemID, ProductName: "Team", UnitAmount: 9900, Quantity: 1, ProviderItemID: &providerItemID},
+		},
+	}
+
+	// Mock expectations
+	mockSubscriptionRepo.On("GetByID", ctx, subscriptionID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customerID).Return(&models.Customer{ID: customerID, UserID: userID}, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+
+	// Execute
+	result, err := service.RemoveItem(ctx, subscriptionID, itemID, userID, "")
+
+	// Assert
+	assert.Nil(t, result)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	mockProvider.AssertNotCalled(t, "DeleteSubscriptionItem", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"payment-service/internal/models"
+	"regexp"
+)
+
+// defaultTaxRates are the VAT rates in percent applied unless overridden by
+// configuration. Reduced rates are only listed for Sweden; add others through
+// TAX_RATES when selling reduced-rate products to consumers in those countries.
+var defaultTaxRates = map[string]map[models.TaxCategory]float64{
+	"SE": {
+		models.TaxCategoryStandard:     25,
+		models.TaxCategoryReduced:      12,
+		models.TaxCategorySuperReduced: 6,
+	},
+	"AT": {models.TaxCategoryStandard: 20},
+	"BE": {models.TaxCategoryStandard: 21},
+	"BG": {models.TaxCategoryStandard: 20},
+	"CY": {models.TaxCategoryStandard: 19},
+	"CZ": {models.TaxCategoryStandard: 21},
+	"DE": {models.TaxCategoryStandard: 19},
+	"DK": {models.TaxCategoryStandard: 25},
+	"EE": {models.TaxCategoryStandard: 24},
+	"ES": {models.TaxCategoryStandard: 21},
+	"FI": {models.TaxCategoryStandard: 25.5},
+	"FR": {models.TaxCategoryStandard: 20},
+	"GR": {models.TaxCategoryStandard: 24},
+	"HR": {models.TaxCategoryStandard: 25},
+	"HU": {models.TaxCategoryStandard: 27},
+	"IE": {models.TaxCategoryStandard: 23},
+	"IT": {models.TaxCategoryStandard: 22},
+	"LT": {models.TaxCategoryStandard: 21},
+	"LU": {models.TaxCategoryStandard: 17},
+	"LV": {models.TaxCategoryStandard: 21},
+	"MT": {models.TaxCategoryStandard: 18},
+	"NL": {models.TaxCategoryStandard: 21},
+	"PL": {models.TaxCategoryStandard: 23},
+	"PT": {models.TaxCategoryStandard: 23},
+	"RO": {models.TaxCategoryStandard: 21},
+	"SI": {models.TaxCategoryStandard: 22},
+	"SK": {models.TaxCategoryStandard: 23},
+}
+
+// euCountries lists the EU member states by ISO 3166-1 alpha-2 code
+var euCountries = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true,
+	"DK": true, "EE": true, "ES": true, "FI": true, "FR": true, "GR": true,
+	"HR": true, "HU": true, "IE": true, "IT": true, "LT": true, "LU": true,
+	"LV": true, "MT": true, "NL": true, "PL": true, "PT": true, "RO": true,
+	"SE": true, "SI": true, "SK": true,
+}
+
+// vatIDFormats holds the format of each member state's VAT ID, after the
+// two-letter prefix. Greece uses the prefix EL rather than its country code.
+var vatIDFormats = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^U\d{8}$`),
+	"BE": regexp.MustCompile(`^[01]\d{9}$`),
+	"BG": regexp.MustCompile(`^\d{9,10}$`),
+	"CY": regexp.MustCompile(`^\d{8}[A-Z]$`),
+	"CZ": regexp.MustCompile(`^\d{8,10}$`),
+	"DE": regexp.MustCompile(`^\d{9}$`),
+	"DK": regexp.MustCompile(`^\d{8}$`),
+	"EE": regexp.MustCompile(`^\d{9}$`),
+	"EL": regexp.MustCompile(`^\d{9}$`),
+	"ES": regexp.MustCompile(`^[A-Z0-9]\d{7}[A-Z0-9]$`),
+	"FI": regexp.MustCompile(`^\d{8}$`),
+	"FR": regexp.MustCompile(`^[A-HJ-NP-Z0-9]{2}\d{9}$`),
+	"HR": regexp.MustCompile(`^\d{11}$`),
+	"HU": regexp.MustCompile(`^\d{8}$`),
+	"IE": regexp.MustCompile(`^(\d{7}[A-W][A-IW]?|\d[A-Z+*]\d{5}[A-W])$`),
+	"IT": regexp.MustCompile(`^\d{11}$`),
+	"LT": regexp.MustCompile(`^(\d{9}|\d{12})$`),
+	"LU": regexp.MustCompile(`^\d{8}$`),
+	"LV": regexp.MustCompile(`^\d{11}$`),
+	"MT": regexp.MustCompile(`^\d{8}$`),
+	"NL": regexp.MustCompile(`^\d{9}B\d{2}$`),
+	"PL": regexp.MustCompile(`^\d{10}$`),
+	"PT": regexp.MustCompile(`^\d{9}$`),
+	"RO": regexp.MustCompile(`^\d{2,10}$`),
+	"SE": regexp.MustCompile(`^\d{10}01$`),
+	"SI": regexp.MustCompile(`^\d{8}$`),
+	"SK": regexp.MustCompile(`^\d{10}$`),
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"strings"
+)
+
+// TaxConfig controls how tax is calculated
+type TaxConfig struct {
+	// Country the seller is established in; domestic sales use its rates
+	HomeCountry string
+
+	// Behavior used when a request does not say whether its price includes tax
+	DefaultBehavior models.TaxBehavior
+
+	// Rate overrides in percent, country -> category -> rate
+	Rates map[string]map[string]float64
+}
+
+// TaxRequest describes an amount to calculate tax on
+type TaxRequest struct {
+	Amount   int64 // Price after discounts, with or without tax depending on Behavior
+	Category models.TaxCategory
+	Behavior models.TaxBehavior
+	Country  string // Buyer's country; defaults to the VAT ID prefix, then the home country
+	VATID    string // Buyer's VAT ID for B2B sales
+}
+
+// TaxResult is the outcome of a tax calculation
+type TaxResult struct {
+	Net       int64 // Amount excluding tax
+	Tax       int64
+	Total     int64 // Amount to charge
+	Breakdown *models.TaxBreakdown
+}
+
+type TaxService struct {
+	config TaxConfig
+	rates  map[string]map[models.TaxCategory]float64
+}
+
+func NewTaxService(config TaxConfig) *TaxService {
+	rates := make(map[string]map[models.TaxCategory]float64, len(defaultTaxRates))
+	for country, categories := range defaultTaxRates {
+		rates[country] = make(map[models.TaxCategory]float64, len(categories))
+		for category, rate := range categories {
+			rates[country][category] = rate
+		}
+	}
+	for country, categories := range config.Rates {
+		if rates[country] == nil {
+			rates[country] = map[models.TaxCategory]float64{}
+		}
+		for category, rate := range categories {
+			rates[country][models.TaxCategory(category)] = rate
+		}
+	}
+
+	if config.HomeCountry == "" {
+		config.HomeCountry = "SE"
+	}
+	if config.DefaultBehavior == "" {
+		config.DefaultBehavior = models.TaxBehaviorInclusive
+	}
+
+	return &TaxService{
+		config: config,
+		rates:  rates,
+	}
+}
+
+// Calculate works out the tax on an amount. Domestic sales and sales to EU
+// consumers are taxed at the buyer country's rate, EU businesses with a valid
+// VAT ID are reverse charged and sales outside the EU are not taxed.
+func (s *TaxService) Calculate(req *TaxRequest) (*TaxResult, error) {
+	category := req.Category
+	if category == "" {
+		category = models.TaxCategoryStandard
+	}
+	if !validTaxCategory(category) {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Invalid tax category: %s", category),
+			http.StatusBadRequest,
+		)
+	}
+
+	behavior := req.Behavior
+	if behavior == "" {
+		behavior = s.config.DefaultBehavior
+	}
+	if behavior != models.TaxBehaviorInclusive && behavior != models.TaxBehaviorExclusive {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Invalid tax behavior: %s", behavior),
+			http.StatusBadRequest,
+		)
+	}
+
+	country := strings.ToUpper(strings.TrimSpace(req.Country))
+
+	var vatID string
+	if req.VATID != "" {
+		var vatCountry string
+		var ok bool
+		vatID, vatCountry, ok = ParseVATID(req.VATID)
+		if !ok {
+			return nil, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"Invalid VAT ID",
+				http.StatusBadRequest,
+			)
+		}
+		if country == "" {
+			country = vatCountry
+		} else if country != vatCountry {
+			return nil, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				"VAT ID does not match the billing country",
+				http.StatusBadRequest,
+			)
+		}
+	}
+	if country == "" {
+		country = s.config.HomeCountry
+	}
+
+	breakdown := &models.TaxBreakdown{
+		Country:       country,
+		Behavior:      behavior,
+		CustomerVATID: vatID,
+	}
+
+	var rate float64
+	switch {
+	case category == models.TaxCategoryExempt:
+	case country != s.config.HomeCountry && euCountries[country] && vatID != "":
+		breakdown.ReverseCharge = true
+	case country != s.config.HomeCountry && !euCountries[country]:
+		// Exports outside the EU are not subject to VAT
+	default:
+		var ok bool
+		rate, ok = s.rates[country][category]
+		if !ok {
+			return nil, models.NewAPIError(
+				models.ErrCodeInvalidRequest,
+				fmt.Sprintf("No tax rate configured for %s in %s", category, country),
+				http.StatusBadRequest,
+			)
+		}
+	}
+
+	result := &TaxResult{Breakdown: breakdown}
+	if behavior == models.TaxBehaviorInclusive {
+		result.Total = req.Amount
+		result.Net = netOfTax(req.Amount, rate)
+		result.Tax = result.Total - result.Net
+	} else {
+		result.Net = req.Amount
+		result.Tax = int64(math.Round(float64(req.Amount) * rate / 100))
+		result.Total = result.Net + result.Tax
+	}
+
+	breakdown.Lines = []models.TaxLine{{
+		Category: category,
+		Rate:     rate,
+		Net:      result.Net,
+		Tax:      result.Tax,
+	}}
+
+	return result, nil
+}
+
+// taxAtRate works out the tax on another amount charged under an earlier
+// calculation, e.g. usage billed on a subscription, reusing its rate and
+// behavior
+func taxAtRate(amount int64, breakdown *models.TaxBreakdown) *TaxResult {
+	if breakdown == nil {
+		return &TaxResult{Net: amount, Total: amount}
+	}
+
+	applied := *breakdown
+	applied.Lines = nil
+
+	var rate float64
+	var category models.TaxCategory
+	if len(breakdown.Lines) > 0 {
+		rate = breakdown.Lines[0].Rate
+		category = breakdown.Lines[0].Category
+	}
+
+	result := &TaxResult{Breakdown: &applied}
+	if breakdown.Behavior == models.TaxBehaviorInclusive {
+		result.Total = amount
+		result.Net = netOfTax(amount, rate)
+		result.Tax = result.Total - result.Net
+	} else {
+		result.Net = amount
+		result.Tax = int64(math.Round(float64(amount) * rate / 100))
+		result.Total = result.Net + result.Tax
+	}
+
+	applied.Lines = []models.TaxLine{{
+		Category: category,
+		Rate:     rate,
+		Net:      result.Net,
+		Tax:      result.Tax,
+	}}
+
+	return result
+}
+
+// netOfTax returns the part of a tax-inclusive amount that is not tax
+func netOfTax(gross int64, rate float64) int64 {
+	return int64(math.Round(float64(gross) * 100 / (100 + rate)))
+}
+
+// providerTaxRate returns the rate a provider should apply for a breakdown,
+// or nil if no tax is charged
+func providerTaxRate(breakdown *models.TaxBreakdown) *providers.TaxRate {
+	if len(breakdown.Lines) == 0 || breakdown.Lines[0].Rate == 0 {
+		return nil
+	}
+
+	rate := breakdown.Lines[0].Rate
+	return &providers.TaxRate{
+		DisplayName: fmt.Sprintf("VAT %s%%", trimRate(rate)),
+		Country:     breakdown.Country,
+		Percentage:  rate,
+		Inclusive:   breakdown.Behavior == models.TaxBehaviorInclusive,
+	}
+}
+
+// customerTaxLocation returns the country and VAT ID to tax a sale with. Values
+// given on the request win; otherwise the customer's billing profile is used.
+func customerTaxLocation(customer *models.Customer, country, vatID string) (string, string) {
+	if country != "" || vatID != "" {
+		return country, vatID
+	}
+	return stringValue(customer.Country), stringValue(customer.VATID)
+}
+
+// ParseVATID normalizes an EU VAT ID and checks its format. It returns the
+// normalized ID and the ISO country code it belongs to.
+func ParseVATID(vatID string) (string, string, bool) {
+	normalized := strings.ToUpper(strings.NewReplacer(" ", "", "-", "", ".", "").Replace(vatID))
+	if len(normalized) < 4 {
+		return "", "", false
+	}
+
+	prefix := normalized[:2]
+	format, ok := vatIDFormats[prefix]
+	if !ok || !format.MatchString(normalized[2:]) {
+		return "", "", false
+	}
+
+	country := prefix
+	if prefix == "EL" {
+		country = "GR"
+	}
+
+	return normalized, country, true
+}
+
+// validTaxCategory reports whether category is a known tax category
+func validTaxCategory(category models.TaxCategory) bool {
+	switch category {
+	case models.TaxCategoryStandard, models.TaxCategoryReduced,
+		models.TaxCategorySuperReduced, models.TaxCategoryExempt:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"payment-service/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaxService_Calculate_DomesticInclusive(t *testing.T) {
+	// Setup
+	service := NewTaxService(TaxConfig{HomeCountry: "SE", DefaultBehavior: models.TaxBehaviorInclusive})
+
+	// Execute
+	result, err := service.Calculate(&TaxRequest{Amount: 12500})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12500), result.Total)
+	assert.Equal(t, int64(10000), result.Net)
+	assert.Equal(t, int64(2500), result.Tax)
+	assert.Equal(t, "SE", result.Breakdown.Country)
+	assert.Equal(t, []models.TaxLine{{Category: models.TaxCategoryStandard, Rate: 25, Net: 10000, Tax: 2500}}, result.Breakdown.Lines)
+}
+
+func TestTaxService_Calculate_ReducedExclusive(t *testing.T) {
+	// Setup
+	service := NewTaxService(TaxConfig{HomeCountry: "SE", DefaultBehavior: models.TaxBehaviorInclusive})
+
+	// Execute
+	food, err := service.Calculate(&TaxRequest{Amount: 10000, Category: models.TaxCategoryReduced, Behavior: models.TaxBehaviorExclusive})
+	assert.NoError(t, err)
+	books, err := service.Calculate(&TaxRequest{Amount: 10000, Category: models.TaxCategorySuperReduced, Behavior: models.TaxBehaviorExclusive})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, int64(11200), food.Total)
+	assert.Equal(t, int64(1200), food.Tax)
+	assert.Equal(t, int64(10600), books.Total)
+	assert.Equal(t, int64(600), books.Tax)
+}
+
+func TestTaxService_Calculate_EUConsumer(t *testing.T) {
+	// Setup
+	service := NewTaxService(TaxConfig{HomeCountry: "SE", DefaultBehavior: models.TaxBehaviorExclusive})
+
+	// Execute
+	result, err := service.Calculate(&TaxRequest{Amount: 10000, Country: "de"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "DE", result.Breakdown.Country)
+	assert.False(t, result.Breakdown.ReverseCharge)
+	assert.Equal(t, int64(1900), result.Tax)
+	assert.Equal(t, int64(11900), result.Total)
+}
+
+func TestTaxService_Calculate_ReverseCharge(t *testing.T) {
+	// Setup
+	service := NewTaxService(TaxConfig{HomeCountry: "SE", DefaultBehavior: models.TaxBehaviorExclusive})
+
+	// Execute
+	result, err := service.Calculate(&TaxRequest{Amount: 10000, VATID: "de 123 456 789"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "DE", result.Breakdown.Country)
+	assert.True(t, result.Breakdown.ReverseCharge)
+	assert.Equal(t, "DE123456789", result.Breakdown.CustomerVATID)
+	assert.Equal(t, int64(0), result.Tax)
+	assert.Equal(t, int64(10000), result.Total)
+}
+
+func TestTaxService_Calculate_DomesticBusinessIsTaxed(t *testing.T) {
+	// Setup
+	service := NewTaxService(TaxConfig{HomeCountry: "SE", DefaultBehavior: models.TaxBehaviorExclusive})
+
+	// Execute
+	result, err := service.Calculate(&TaxRequest{Amount: 10000, VATID: "SE556677889901"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, result.Breakdown.ReverseCharge)
+	assert.Equal(t, int64(2500), result.Tax)
+}
+
+func TestTaxService_Calculate_Export(t *testing.T) {
+	// Setup
+	service := NewTaxService(TaxConfig{HomeCountry: "SE", DefaultBehavior: models.TaxBehaviorInclusive})
+
+	// Execute
+	result, err := service.Calculate(&TaxRequest{Amount: 10000, Country: "US"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), result.Tax)
+	assert.Equal(t, int64(10000), result.Net)
+}
+
+func TestTaxService_Calculate_RateOverride(t *testing.T) {
+	// Setup
+	service := NewTaxService(TaxConfig{
+		HomeCountry:     "SE",
+		DefaultBehavior: models.TaxBehaviorExclusive,
+		Rates:           map[string]map[string]float64{"DE": {"reduced": 7}},
+	})
+
+	// Execute
+	result, err := service.Calculate(&TaxRequest{Amount: 10000, Country: "DE", Category: models.TaxCategoryReduced})
+	assert.NoError(t, err)
+	_, missingErr := service.Calculate(&TaxRequest{Amount: 10000, Country: "FR", Category: models.TaxCategoryReduced})
+
+	// Assert
+	assert.Equal(t, int64(700), result.Tax)
+	assert.Error(t, missingErr)
+}
+
+func TestTaxService_Calculate_InvalidVATID(t *testing.T) {
+	// Setup
+	service := NewTaxService(TaxConfig{HomeCountry: "SE"})
+
+	// Execute
+	_, invalidErr := service.Calculate(&TaxRequest{Amount: 10000, VATID: "DE12345"})
+	_, mismatchErr := service.Calculate(&TaxRequest{Amount: 10000, Country: "FR", VATID: "DE123456789"})
+
+	// Assert
+	apiErr, ok := invalidErr.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, models.ErrCodeInvalidRequest, apiErr.Code)
+	assert.Error(t, mismatchErr)
+}
+
+func TestParseVATID(t *testing.T) {
+	vatID, country, ok := ParseVATID("EL 123456789")
+	assert.True(t, ok)
+	assert.Equal(t, "EL123456789", vatID)
+	assert.Equal(t, "GR", country)
+
+	_, _, ok = ParseVATID("SE5566778899")
+	assert.False(t, ok)
+}
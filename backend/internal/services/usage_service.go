@@ -0,0 +1,468 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// usageBatchSize is how many records or periods the usage job handles per query
+const usageBatchSize = 100
+
+// usageReportGracePeriod keeps the usage job away from records that are
+// still being reported by the request that created them
+const usageReportGracePeriod = time.Minute
+
+// UsageService records usage for metered subscription prices. Usage of prices
+// with a provider item is reported to the provider, which bills it on its
+// next invoice; other usage is invoiced locally once the period has ended.
+type UsageService struct {
+	usageRepo        repository.UsageRepositoryInterface
+	subscriptionRepo repository.SubscriptionRepositoryInterface
+	customerRepo     repository.CustomerRepositoryInterface
+	invoiceRepo      repository.InvoiceRepositoryInterface
+	providerFactory  ProviderFactoryInterface
+}
+
+func NewUsageService(
+	usageRepo repository.UsageRepositoryInterface,
+	subscriptionRepo repository.SubscriptionRepositoryInterface,
+	customerRepo repository.CustomerRepositoryInterface,
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	providerFactory ProviderFactoryInterface,
+) *UsageService {
+	return &UsageService{
+		usageRepo:        usageRepo,
+		subscriptionRepo: subscriptionRepo,
+		customerRepo:     customerRepo,
+		invoiceRepo:      invoiceRepo,
+		providerFactory:  providerFactory,
+	}
+}
+
+// ReportUsage records usage for one of the user's subscriptions. Reporting
+// the same RecordID again returns the original record without counting it
+// twice.
+func (s *UsageService) ReportUsage(
+	ctx context.Context,
+	subscriptionID, userID uuid.UUID,
+	req *models.ReportUsageRequest,
+) (*models.UsageRecord, error) {
+	if req.RecordID == "" {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"record_id is required",
+			http.StatusBadRequest,
+		)
+	}
+	if req.Quantity < 0 {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"quantity cannot be negative",
+			http.StatusBadRequest,
+		)
+	}
+
+	subscription, err := s.getSubscription(ctx, subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	price := findMeteredPrice(subscription, req.Meter)
+	if price == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Subscription has no metered price for meter %s", req.Meter),
+			http.StatusBadRequest,
+		)
+	}
+
+	switch subscription.Status {
+	case models.SubscriptionStatusActive, models.SubscriptionStatusTrialing, models.SubscriptionStatusPastDue:
+	default:
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Cannot report usage for a %s subscription", subscription.Status),
+			http.StatusConflict,
+		)
+	}
+
+	timestamp := time.Now()
+	if req.Timestamp != nil {
+		timestamp = *req.Timestamp
+	}
+	if timestamp.Before(subscription.CurrentPeriodStart) || !timestamp.Before(subscription.CurrentPeriodEnd) {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"timestamp must be within the current billing period",
+			http.StatusBadRequest,
+		)
+	}
+
+	record := &models.UsageRecord{
+		SubscriptionID: subscription.ID,
+		MeteredPriceID: price.ID,
+		RecordID:       req.RecordID,
+		Quantity:       req.Quantity,
+		Timestamp:      timestamp,
+		PeriodStart:    subscription.CurrentPeriodStart,
+		PeriodEnd:      subscription.CurrentPeriodEnd,
+	}
+
+	created, err := s.usageRepo.CreateRecord(ctx, record)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to record usage",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if !created && (record.MeteredPriceID != price.ID || record.Quantity != req.Quantity) {
+		return nil, models.NewAPIError(
+			models.ErrCodeDuplicate,
+			"record_id was already used for different usage",
+			http.StatusConflict,
+		)
+	}
+
+	// A retry also retries a report that failed the first time
+	if price.ProviderItemID != nil && record.ReportedAt == nil {
+		if err := s.report(ctx, subscription, price, record); err != nil {
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to report usage to provider",
+				http.StatusBadGateway,
+			)
+		}
+	}
+
+	return record, nil
+}
+
+// GetUsage returns the usage of the current billing period of one of the
+// user's subscriptions so far
+func (s *UsageService) GetUsage(ctx context.Context, subscriptionID, userID uuid.UUID) (*models.SubscriptionUsageResponse, error) {
+	subscription, err := s.getSubscription(ctx, subscriptionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := s.usageRepo.Summarize(ctx, subscription.ID, subscription.CurrentPeriodStart)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to summarize usage",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.SubscriptionUsageResponse{
+		SubscriptionID: subscription.ID,
+		PeriodStart:    subscription.CurrentPeriodStart,
+		PeriodEnd:      subscription.CurrentPeriodEnd,
+		Data:           summaries,
+	}, nil
+}
+
+// ProcessUsage reports usage the provider has not received yet and invoices
+// locally billed usage of ended periods. It is safe to run on several
+// instances at once.
+func (s *UsageService) ProcessUsage(ctx context.Context) (*models.UsageRunResult, error) {
+	result := &models.UsageRunResult{}
+
+	if err := s.reportPending(ctx, result); err != nil {
+		return result, err
+	}
+	if err := s.invoiceEndedPeriods(ctx, result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// reportPending retries usage reports that failed when the usage was recorded
+func (s *UsageService) reportPending(ctx context.Context, result *models.UsageRunResult) error {
+	records, err := s.usageRepo.ListUnreported(ctx, time.Now().Add(-usageReportGracePeriod), usageBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range records {
+		record := &records[i]
+
+		subscription, err := s.subscriptionRepo.GetByID(ctx, record.SubscriptionID)
+		if err != nil || subscription == nil {
+			log.Printf("Failed to get subscription %s for usage record %s: %v", record.SubscriptionID, record.ID, err)
+			result.Failed++
+			continue
+		}
+
+		price := findMeteredPriceByID(subscription, record.MeteredPriceID)
+		if price == nil || price.ProviderItemID == nil {
+			result.Failed++
+			continue
+		}
+
+		if err := s.report(ctx, subscription, price, record); err != nil {
+			log.Printf("Failed to report usage record %s: %v", record.ID, err)
+			result.Failed++
+			continue
+		}
+		result.Reported++
+	}
+
+	return nil
+}
+
+// invoiceEndedPeriods creates an invoice for the locally billed usage of each
+// billing period that has ended
+func (s *UsageService) invoiceEndedPeriods(ctx context.Context, result *models.UsageRunResult) error {
+	periods, err := s.usageRepo.ListUninvoicedPeriods(ctx, time.Now(), usageBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, period := range periods {
+		if err := s.invoicePeriod(ctx, period); err != nil {
+			log.Printf("Failed to invoice usage of subscription %s for period starting %s: %v",
+				period.SubscriptionID, period.PeriodStart.Format(time.RFC3339), err)
+			result.Failed++
+			continue
+		}
+		result.Invoiced++
+	}
+
+	return nil
+}
+
+// invoicePeriod invoices a subscription's locally billed usage for one period.
+// The usage is claimed for an invoice before it is summarized, and only the
+// claimed usage is marked invoiced: a second run finishes the invoice of a
+// claim made by the first instead of billing the usage twice, and usage
+// recorded after the claim is billed on a follow-up invoice.
+func (s *UsageService) invoicePeriod(ctx context.Context, period models.UsagePeriod) error {
+	subscription, err := s.subscriptionRepo.GetByID(ctx, period.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	if subscription == nil {
+		return fmt.Errorf("subscription %s not found", period.SubscriptionID)
+	}
+
+	keys, err := s.usageRepo.ListPendingInvoiceKeys(ctx, subscription.ID, period.PeriodStart)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		key, err := s.nextUsageInvoiceKey(ctx, subscription, period)
+		if err != nil {
+			return err
+		}
+		if err := s.usageRepo.ClaimUninvoiced(ctx, subscription.ID, period.PeriodStart, key); err != nil {
+			return err
+		}
+		// Another instance may have claimed the usage first
+		keys, err = s.usageRepo.ListPendingInvoiceKeys(ctx, subscription.ID, period.PeriodStart)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		if err := s.invoiceClaim(ctx, subscription, period, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nextUsageInvoiceKey returns the provider invoice ID for the next invoice of
+// a period's usage. The first invoice's is derived from the period; follow-up
+// invoices for usage recorded after it get one of their own.
+func (s *UsageService) nextUsageInvoiceKey(ctx context.Context, subscription *models.Subscription, period models.UsagePeriod) (string, error) {
+	key := fmt.Sprintf("usage_%s_%d", subscription.ID, period.PeriodStart.Unix())
+	invoice, err := s.invoiceRepo.GetByProviderInvoiceID(ctx, subscription.Provider, key)
+	if err != nil {
+		return "", err
+	}
+	if invoice == nil {
+		return key, nil
+	}
+	return fmt.Sprintf("%s_%d", key, time.Now().UnixNano()), nil
+}
+
+// invoiceClaim creates the invoice for the usage claimed with key, unless an
+// earlier run already did, and marks the claimed usage invoiced
+func (s *UsageService) invoiceClaim(ctx context.Context, subscription *models.Subscription, period models.UsagePeriod, key string) error {
+	invoice, err := s.invoiceRepo.GetByProviderInvoiceID(ctx, subscription.Provider, key)
+	if err != nil {
+		return err
+	}
+
+	if invoice == nil {
+		summaries, err := s.usageRepo.SummarizeClaim(ctx, subscription.ID, period.PeriodStart, key)
+		if err != nil {
+			return err
+		}
+
+		invoice, err = buildUsageInvoice(subscription, period, summaries)
+		if err != nil {
+			return err
+		}
+		invoice.ProviderInvoiceID = key
+		if err := s.invoiceRepo.Create(ctx, invoice); err != nil {
+			return err
+		}
+	}
+
+	return s.usageRepo.MarkInvoiced(ctx, key, invoice.ID)
+}
+
+// buildUsageInvoice bills the usage of a subscription's locally invoiced
+// metered prices for a period, taxed like the subscription. Metered prices are
+// in the subscription's currency.
+func buildUsageInvoice(subscription *models.Subscription, period models.UsagePeriod, summaries []models.UsageSummary) (*models.Invoice, error) {
+	billingReason := "usage"
+	invoice := &models.Invoice{
+		CustomerID:     subscription.CustomerID,
+		SubscriptionID: &subscription.ID,
+		Provider:       subscription.Provider,
+		Status:         models.InvoiceStatusOpen,
+		BillingReason:  &billingReason,
+		Currency:       subscription.Currency,
+		LineItems:      models.InvoiceLineItems{},
+		PeriodStart:    period.PeriodStart,
+		PeriodEnd:      period.PeriodEnd,
+	}
+
+	subtotal := models.NewMoney(0, subscription.Currency)
+	for _, summary := range summaries {
+		price := findMeteredPriceByID(subscription, summary.MeteredPriceID)
+		if price == nil || price.ProviderItemID != nil {
+			continue
+		}
+
+		invoice.LineItems = append(invoice.LineItems, models.InvoiceLineItem{
+			Description: summary.Meter,
+			Quantity:    summary.Quantity,
+			UnitAmount:  summary.UnitAmount,
+			Amount:      summary.Amount,
+			PeriodStart: &invoice.PeriodStart,
+			PeriodEnd:   &invoice.PeriodEnd,
+		})
+		var err error
+		subtotal, err = subtotal.Add(models.NewMoney(summary.Amount, subscription.Currency))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tax := taxAtRate(subtotal.Amount, subscription.TaxBreakdown)
+	invoice.Subtotal = tax.Net
+	invoice.Tax = tax.Tax
+	invoice.Total = tax.Total
+	invoice.AmountDue = tax.Total
+	invoice.TaxBreakdown = tax.Breakdown
+
+	if invoice.AmountDue == 0 {
+		paidAt := time.Now()
+		invoice.Status = models.InvoiceStatusPaid
+		invoice.PaidAt = &paidAt
+	}
+
+	return invoice, nil
+}
+
+// report sends a usage record to the provider item of its metered price
+func (s *UsageService) report(
+	ctx context.Context,
+	subscription *models.Subscription,
+	price *models.MeteredPrice,
+	record *models.UsageRecord,
+) error {
+	provider, err := s.providerFactory.GetProvider(subscription.Provider)
+	if err != nil {
+		return err
+	}
+
+	// Sums add up increments; max and last look at each reported value
+	action := providers.UsageActionSet
+	if price.Aggregation == models.UsageAggregationSum {
+		action = providers.UsageActionIncrement
+	}
+
+	err = provider.ReportUsage(ctx, &providers.ReportUsageRequest{
+		SubscriptionItemID: *price.ProviderItemID,
+		Quantity:           record.Quantity,
+		Timestamp:          record.Timestamp,
+		Action:             action,
+		IdempotencyKey:     "usage:" + record.ID.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	reportedAt := time.Now()
+	if err := s.usageRepo.MarkReported(ctx, record.ID, reportedAt); err != nil {
+		log.Printf("Failed to mark usage record %s reported: %v", record.ID, err)
+	}
+	record.ReportedAt = &reportedAt
+
+	return nil
+}
+
+// getSubscription returns a subscription if it belongs to the user
+func (s *UsageService) getSubscription(ctx context.Context, subscriptionID, userID uuid.UUID) (*models.Subscription, error) {
+	subscription, err := s.subscriptionRepo.GetByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve subscription",
+			http.StatusInternalServerError,
+		)
+	}
+
+	notFound := models.NewAPIError(
+		models.ErrCodeNotFound,
+		"Subscription not found",
+		http.StatusNotFound,
+	)
+	if subscription == nil {
+		return nil, notFound
+	}
+
+	customer, err := s.customerRepo.GetByID(ctx, subscription.CustomerID)
+	if err != nil || customer == nil || customer.UserID != userID {
+		return nil, notFound
+	}
+
+	return subscription, nil
+}
+
+func findMeteredPrice(subscription *models.Subscription, meter string) *models.MeteredPrice {
+	for i := range subscription.MeteredPrices {
+		if subscription.MeteredPrices[i].Meter == meter {
+			return &subscription.MeteredPrices[i]
+		}
+	}
+	return nil
+}
+
+func findMeteredPriceByID(subscription *models.Subscription, id uuid.UUID) *models.MeteredPrice {
+	for i := range subscription.MeteredPrices {
+		if subscription.MeteredPrices[i].ID == id {
+			return &subscription.MeteredPrices[i]
+		}
+	}
+	return nil
+}
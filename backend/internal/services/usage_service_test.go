@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockUsageRepository is a mock for UsageRepository
+type MockUsageRepository struct {
+	mock.Mock
+}
+
+func (m *MockUsageRepository) CreateRecord(ctx context.Context, record *models.UsageRecord) (bool, error) {
+	args := m.Called(ctx, record)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUsageRepository) MarkReported(ctx context.Context, id uuid.UUID, reportedAt time.Time) error {
+	args := m.Called(ctx, id, reportedAt)
+	return args.Error(0)
+}
+
+func (m *MockUsageRepository) ListUnreported(ctx context.Context, before time.Time, limit int) ([]models.UsageRecord, error) {
+	args := m.Called(ctx, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UsageRecord), args.Error(1)
+}
+
+func (m *MockUsageRepository) Summarize(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time) ([]models.UsageSummary, error) {
+	args := m.Called(ctx, subscriptionID, periodStart)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UsageSummary), args.Error(1)
+}
+
+func (m *MockUsageRepository) ListUninvoicedPeriods(ctx context.Context, now time.Time, limit int) ([]models.UsagePeriod, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UsagePeriod), args.Error(1)
+}
+
+func (m *MockUsageRepository) ClaimUninvoiced(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time, invoiceKey string) error {
+	args := m.Called(ctx, subscriptionID, periodStart, invoiceKey)
+	return args.Error(0)
+}
+
+func (m *MockUsageRepository) ListPendingInvoiceKeys(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time) ([]string, error) {
+	args := m.Called(ctx, subscriptionID, periodStart)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockUsageRepository) SummarizeClaim(ctx context.Context, subscriptionID uuid.UUID, periodStart time.Time, invoiceKey string) ([]models.UsageSummary, error) {
+	args := m.Called(ctx, subscriptionID, periodStart, invoiceKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UsageSummary), args.Error(1)
+}
+
+func (m *MockUsageRepository) MarkInvoiced(ctx context.Context, invoiceKey string, invoiceID uuid.UUID) error {
+	args := m.Called(ctx, invoiceKey, invoiceID)
+	return args.Error(0)
+}
+
+func newMeteredSubscription(customerID uuid.UUID, providerItemID *string) *models.Subscription {
+	now := time.Now()
+	subscriptionID := uuid.New()
+	return &models.Subscription{
+		ID:                 subscriptionID,
+		CustomerID:         customerID,
+		Provider:           models.ProviderStripe,
+		Status:             models.SubscriptionStatusActive,
+		Currency:           models.CurrencySEK,
+		CurrentPeriodStart: now.Add(-24 * time.Hour),
+		CurrentPeriodEnd:   now.Add(24 * time.Hour),
+		MeteredPrices: []models.MeteredPrice{{
+			ID:             uuid.New(),
+			SubscriptionID: subscriptionID,
+			Meter:          "api_calls",
+			UnitAmount:     5,
+			Aggregation:    models.UsageAggregationSum,
+			ProviderItemID: providerItemID,
+		}},
+	}
+}
+
+func TestUsageService_ReportUsage_ReportsToProvider(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	mockUsageRepo := new(MockUsageRepository)
+	mockSubRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	service := NewUsageService(mockUsageRepo, mockSubRepo, mockCustomerRepo, nil, mockFactory)
+
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	itemID := "si_123"
+	subscription := newMeteredSubscription(customer.ID, &itemID)
+	recordID := uuid.New()
+
+	mockSubRepo.On("GetByID", ctx, subscription.ID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	mockUsageRepo.On("CreateRecord", ctx, mock.AnythingOfType("*models.UsageRecord")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.UsageRecord).ID = recordID
+	}).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("ReportUsage", ctx, mock.MatchedBy(func(req *providers.ReportUsageRequest) bool {
+		return req.SubscriptionItemID == itemID &&
+			req.Quantity == 40 &&
+			req.Action == providers.UsageActionIncrement &&
+			req.IdempotencyKey == "usage:"+recordID.String()
+	})).Return(nil)
+	mockUsageRepo.On("MarkReported", ctx, recordID, mock.AnythingOfType("time.Time")).Return(nil)
+
+	// Execute
+	record, err := service.ReportUsage(ctx, subscription.ID, userID, &models.ReportUsageRequest{
+		Meter:    "api_calls",
+		Quantity: 40,
+		RecordID: "batch-1",
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NotNil(t, record.ReportedAt)
+	assert.Equal(t, subscription.CurrentPeriodStart, record.PeriodStart)
+	mockProvider.AssertExpectations(t)
+	mockUsageRepo.AssertExpectations(t)
+}
+
+func TestUsageService_ReportUsage_ReplayWithDifferentQuantity(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	mockUsageRepo := new(MockUsageRepository)
+	mockSubRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockFactory := new(MockProviderFactory)
+	service := NewUsageService(mockUsageRepo, mockSubRepo, mockCustomerRepo, nil, mockFactory)
+
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	subscription := newMeteredSubscription(customer.ID, nil)
+
+	mockSubRepo.On("GetByID", ctx, subscription.ID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+	// The record ID was first used for a quantity of 10
+	mockUsageRepo.On("CreateRecord", ctx, mock.AnythingOfType("*models.UsageRecord")).Run(func(args mock.Arguments) {
+		record := args.Get(1).(*models.UsageRecord)
+		record.ID = uuid.New()
+		record.Quantity = 10
+	}).Return(false, nil)
+
+	// Execute
+	record, err := service.ReportUsage(ctx, subscription.ID, userID, &models.ReportUsageRequest{
+		Meter:    "api_calls",
+		Quantity: 40,
+		RecordID: "batch-1",
+	})
+
+	// Assert
+	assert.Nil(t, record)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	mockFactory.AssertNotCalled(t, "GetProvider", mock.Anything)
+}
+
+func TestUsageService_ReportUsage_OutsidePeriod(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	mockUsageRepo := new(MockUsageRepository)
+	mockSubRepo := new(MockSubscriptionRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	service := NewUsageService(mockUsageRepo, mockSubRepo, mockCustomerRepo, nil, nil)
+
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	subscription := newMeteredSubscription(customer.ID, nil)
+	timestamp := subscription.CurrentPeriodStart.Add(-time.Hour)
+
+	mockSubRepo.On("GetByID", ctx, subscription.ID).Return(subscription, nil)
+	mockCustomerRepo.On("GetByID", ctx, customer.ID).Return(customer, nil)
+
+	// Execute
+	_, err := service.ReportUsage(ctx, subscription.ID, userID, &models.ReportUsageRequest{
+		Meter:     "api_calls",
+		Quantity:  40,
+		RecordID:  "batch-1",
+		Timestamp: &timestamp,
+	})
+
+	// Assert
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	mockUsageRepo.AssertNotCalled(t, "CreateRecord", mock.Anything, mock.Anything)
+}
+
+func TestUsageService_ProcessUsage_InvoicesEndedPeriod(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockUsageRepo := new(MockUsageRepository)
+	mockSubRepo := new(MockSubscriptionRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	service := NewUsageService(mockUsageRepo, mockSubRepo, nil, mockInvoiceRepo, nil)
+
+	subscription := newMeteredSubscription(uuid.New(), nil)
+	subscription.TaxBreakdown = &models.TaxBreakdown{
+		Country:  "SE",
+		Behavior: models.TaxBehaviorExclusive,
+		Lines:    []models.TaxLine{{Rate: 25, Net: 9900, Tax: 2475}},
+	}
+	period := models.UsagePeriod{
+		SubscriptionID: subscription.ID,
+		PeriodStart:    subscription.CurrentPeriodStart.Add(-30 * 24 * time.Hour),
+		PeriodEnd:      subscription.CurrentPeriodStart,
+	}
+	price := subscription.MeteredPrices[0]
+	invoiceID := uuid.New()
+
+	mockUsageRepo.On("ListUnreported", ctx, mock.AnythingOfType("time.Time"), usageBatchSize).Return([]models.UsageRecord{}, nil)
+	mockUsageRepo.On("ListUninvoicedPeriods", ctx, mock.AnythingOfType("time.Time"), usageBatchSize).Return([]models.UsagePeriod{period}, nil)
+	mockSubRepo.On("GetByID", ctx, subscription.ID).Return(subscription, nil)
+	key := fmt.Sprintf("usage_%s_%d", subscription.ID, period.PeriodStart.Unix())
+
+	mockUsageRepo.On("ListPendingInvoiceKeys", ctx, subscription.ID, period.PeriodStart).Return([]string{}, nil).Once()
+	mockInvoiceRepo.On("GetByProviderInvoiceID", ctx, models.ProviderStripe, key).Return(nil, nil)
+	mockUsageRepo.On("ClaimUninvoiced", ctx, subscription.ID, period.PeriodStart, key).Return(nil)
+	mockUsageRepo.On("ListPendingInvoiceKeys", ctx, subscription.ID, period.PeriodStart).Return([]string{key}, nil).Once()
+	mockUsageRepo.On("SummarizeClaim", ctx, subscription.ID, period.PeriodStart, key).Return([]models.UsageSummary{{
+		MeteredPriceID: price.ID,
+		Meter:          price.Meter,
+		Aggregation:    price.Aggregation,
+		Quantity:       200,
+		UnitAmount:     5,
+		Amount:         1000,
+	}}, nil)
+
+	var created *models.Invoice
+	mockInvoiceRepo.On("Create", ctx, mock.AnythingOfType("*models.Invoice")).Run(func(args mock.Arguments) {
+		created = args.Get(1).(*models.Invoice)
+		created.ID = invoiceID
+	}).Return(nil)
+	mockUsageRepo.On("MarkInvoiced", ctx, key, invoiceID).Return(nil)
+
+	// Execute
+	result, err := service.ProcessUsage(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Invoiced)
+	assert.Equal(t, models.InvoiceStatusOpen, created.Status)
+	assert.Equal(t, int64(1000), created.Subtotal)
+	assert.Equal(t, int64(250), created.Tax)
+	assert.Equal(t, int64(1250), created.AmountDue)
+	assert.Len(t, created.LineItems, 1)
+	assert.Equal(t, int64(200), created.LineItems[0].Quantity)
+	mockUsageRepo.AssertExpectations(t)
+}
+
+func TestUsageService_ProcessUsage_InvoicesUsageRecordedAfterInvoice(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockUsageRepo := new(MockUsageRepository)
+	mockSubRepo := new(MockSubscriptionRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	service := NewUsageService(mockUsageRepo, mockSubRepo, nil, mockInvoiceRepo, nil)
+
+	subscription := newMeteredSubscription(uuid.New(), nil)
+	period := models.UsagePeriod{
+		SubscriptionID: subscription.ID,
+		PeriodStart:    subscription.CurrentPeriodStart.Add(-30 * 24 * time.Hour),
+		PeriodEnd:      subscription.CurrentPeriodStart,
+	}
+	price := subscription.MeteredPrices[0]
+	firstKey := fmt.Sprintf("usage_%s_%d", subscription.ID, period.PeriodStart.Unix())
+	first := &models.Invoice{ID: uuid.New(), ProviderInvoiceID: firstKey, Status: models.InvoiceStatusOpen}
+	followUpID := uuid.New()
+
+	// The period was invoiced, then a record arrived for it
+	pending := make([]string, 1)
+	isFollowUpKey := mock.MatchedBy(func(key string) bool {
+		return strings.HasPrefix(key, firstKey+"_")
+	})
+	mockUsageRepo.On("ListUnreported", ctx, mock.AnythingOfType("time.Time"), usageBatchSize).Return([]models.UsageRecord{}, nil)
+	mockUsageRepo.On("ListUninvoicedPeriods", ctx, mock.AnythingOfType("time.Time"), usageBatchSize).Return([]models.UsagePeriod{period}, nil)
+	mockSubRepo.On("GetByID", ctx, subscription.ID).Return(subscription, nil)
+	mockUsageRepo.On("ListPendingInvoiceKeys", ctx, subscription.ID, period.PeriodStart).Return([]string{}, nil).Once()
+	mockInvoiceRepo.On("GetByProviderInvoiceID", ctx, models.ProviderStripe, firstKey).Return(first, nil)
+	mockUsageRepo.On("ClaimUninvoiced", ctx, subscription.ID, period.PeriodStart, isFollowUpKey).Run(func(args mock.Arguments) {
+		pending[0] = args.String(3)
+	}).Return(nil)
+	mockUsageRepo.On("ListPendingInvoiceKeys", ctx, subscription.ID, period.PeriodStart).Return(pending, nil).Once()
+	mockInvoiceRepo.On("GetByProviderInvoiceID", ctx, models.ProviderStripe, isFollowUpKey).Return(nil, nil)
+	mockUsageRepo.On("SummarizeClaim", ctx, subscription.ID, period.PeriodStart, isFollowUpKey).Return([]models.UsageSummary{{
+		MeteredPriceID: price.ID,
+		Meter:          price.Meter,
+		Aggregation:    price.Aggregation,
+		Quantity:       30,
+		UnitAmount:     5,
+		Amount:         150,
+	}}, nil)
+
+	var created *models.Invoice
+	mockInvoiceRepo.On("Create", ctx, mock.AnythingOfType("*models.Invoice")).Run(func(args mock.Arguments) {
+		created = args.Get(1).(*models.Invoice)
+		created.ID = followUpID
+	}).Return(nil)
+	mockUsageRepo.On("MarkInvoiced", ctx, isFollowUpKey, followUpID).Return(nil)
+
+	// Execute
+	result, err := service.ProcessUsage(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Invoiced)
+	assert.Equal(t, pending[0], created.ProviderInvoiceID)
+	assert.Equal(t, int64(150), created.Subtotal)
+	assert.Equal(t, int64(30), created.LineItems[0].Quantity)
+	mockUsageRepo.AssertNotCalled(t, "MarkInvoiced", ctx, firstKey, first.ID)
+	mockUsageRepo.AssertExpectations(t)
+}
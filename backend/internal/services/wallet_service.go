@@ -0,0 +1,600 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"payment-service/internal/models"
+	"payment-service/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// walletTopUpMetadataKey marks payments that top up a wallet
+const walletTopUpMetadataKey = "wallet_top_up"
+
+// walletExpiryBatchSize is how many expired grants are handled per query
+const walletExpiryBatchSize = 100
+
+// WalletService manages prepaid wallets. Funds come from top-up payments and
+// promotional grants and are spent by apps through debits and holds.
+type WalletService struct {
+	walletRepo     repository.WalletRepositoryInterface
+	customerRepo   repository.CustomerRepositoryInterface
+	auditRepo      repository.AuditRepositoryInterface
+	paymentService *PaymentService
+	ledgerService  *LedgerService
+}
+
+func NewWalletService(
+	walletRepo repository.WalletRepositoryInterface,
+	customerRepo repository.CustomerRepositoryInterface,
+	auditRepo repository.AuditRepositoryInterface,
+	paymentService *PaymentService,
+	ledgerService *LedgerService,
+) *WalletService {
+	return &WalletService{
+		walletRepo:     walletRepo,
+		customerRepo:   customerRepo,
+		auditRepo:      auditRepo,
+		paymentService: paymentService,
+		ledgerService:  ledgerService,
+	}
+}
+
+// ListWallets lists a user's wallets
+func (s *WalletService) ListWallets(ctx context.Context, userID uuid.UUID) (*models.WalletListResponse, error) {
+	customer, err := s.getCustomer(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer == nil {
+		// No customer means no wallets
+		return &models.WalletListResponse{Data: []models.Wallet{}}, nil
+	}
+
+	wallets, err := s.walletRepo.ListByCustomer(ctx, customer.ID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list wallets",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.WalletListResponse{Data: wallets}, nil
+}
+
+// ListTransactions lists the transactions of a user's wallets, newest first
+func (s *WalletService) ListTransactions(ctx context.Context, userID uuid.UUID, limit, offset int) (*models.WalletTransactionListResponse, error) {
+	customer, err := s.getCustomer(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer == nil {
+		return &models.WalletTransactionListResponse{
+			Data:   []models.WalletTransaction{},
+			Total:  0,
+			Limit:  limit,
+			Offset: offset,
+		}, nil
+	}
+
+	transactions, total, err := s.walletRepo.ListTransactions(ctx, customer.ID, limit, offset)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to list wallet transactions",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.WalletTransactionListResponse{
+		Data:   transactions,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// TopUp charges the user and adds the payment to their wallet once it
+// succeeds. Top-ups are VAT exempt; tax is due when the funds are spent.
+func (s *WalletService) TopUp(
+	ctx context.Context,
+	userID uuid.UUID,
+	email, name string,
+	req *models.WalletTopUpRequest,
+) (*models.WalletTopUpResponse, error) {
+	metadata := map[string]any{}
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+	metadata[walletTopUpMetadataKey] = true
+
+	payment, err := s.paymentService.CreatePayment(ctx, userID, email, name, &models.CreatePaymentRequest{
+		Provider:       req.Provider,
+		Amount:         req.Amount,
+		Currency:       req.Currency,
+		Description:    "Wallet top-up",
+		TaxCategory:    models.TaxCategoryExempt,
+		BillingCountry: req.BillingCountry,
+		VATID:          req.VATID,
+		Metadata:       metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Some providers settle immediately instead of through a webhook
+	if err := s.CreditTopUp(ctx, payment); err != nil {
+		log.Printf("Failed to credit wallet top-up for payment %s: %v", payment.ID, err)
+	}
+
+	wallet, err := s.walletRepo.Get(ctx, payment.CustomerID, payment.Currency)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve wallet",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return &models.WalletTopUpResponse{
+		Payment: payment,
+		Wallet:  wallet,
+	}, nil
+}
+
+// CreditTopUp adds a succeeded top-up payment to the customer's wallet. Other
+// payments are ignored, and crediting the same payment twice is a no-op.
+func (s *WalletService) CreditTopUp(ctx context.Context, payment *models.Payment) error {
+	if payment.Status != models.PaymentStatusSucceeded || payment.Metadata[walletTopUpMetadataKey] != true {
+		return nil
+	}
+
+	wallet := &models.Wallet{
+		CustomerID: payment.CustomerID,
+		Currency:   payment.Currency,
+	}
+	txn := &models.WalletTransaction{
+		Type:           models.WalletTransactionTopUp,
+		Amount:         payment.Amount,
+		IdempotencyKey: "payment:" + payment.ID.String(),
+		Description:    payment.Description,
+		PaymentID:      &payment.ID,
+	}
+
+	credited, err := s.walletRepo.Credit(ctx, wallet, txn, nil)
+	if err != nil || !credited {
+		return err
+	}
+
+	s.recordTransaction(ctx, wallet, txn)
+
+	return nil
+}
+
+// Debit spends funds from the user's wallet
+func (s *WalletService) Debit(ctx context.Context, userID uuid.UUID, req *models.WalletDebitRequest) (*models.WalletTransaction, error) {
+	if err := validateWalletRequest(req.Amount, req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.getWallet(ctx, userID, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	txn := &models.WalletTransaction{
+		WalletID:       wallet.ID,
+		Type:           models.WalletTransactionDebit,
+		Amount:         -req.Amount,
+		IdempotencyKey: req.IdempotencyKey,
+	}
+	if req.Description != "" {
+		txn.Description = &req.Description
+	}
+
+	debited, err := s.walletRepo.Debit(ctx, txn)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to debit wallet",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if !debited {
+		return nil, insufficientWalletFunds(wallet, req.Amount)
+	}
+
+	// A retry must ask for the same debit as the original
+	if txn.Type != models.WalletTransactionDebit || txn.Amount != -req.Amount {
+		return nil, models.NewAPIError(
+			models.ErrCodeDuplicate,
+			"Idempotency key was already used for a different request",
+			http.StatusConflict,
+		)
+	}
+
+	s.recordTransaction(ctx, wallet, txn)
+
+	return txn, nil
+}
+
+// CreateHold reserves funds in the user's wallet
+func (s *WalletService) CreateHold(ctx context.Context, userID uuid.UUID, req *models.WalletHoldRequest) (*models.WalletHold, error) {
+	if err := validateWalletRequest(req.Amount, req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.getWallet(ctx, userID, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	hold := &models.WalletHold{
+		WalletID:       wallet.ID,
+		Amount:         req.Amount,
+		IdempotencyKey: req.IdempotencyKey,
+	}
+	if req.Description != "" {
+		hold.Description = &req.Description
+	}
+
+	held, err := s.walletRepo.CreateHold(ctx, hold)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to hold wallet funds",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if !held {
+		return nil, insufficientWalletFunds(wallet, req.Amount)
+	}
+
+	if hold.Amount != req.Amount {
+		return nil, models.NewAPIError(
+			models.ErrCodeDuplicate,
+			"Idempotency key was already used for a different request",
+			http.StatusConflict,
+		)
+	}
+
+	return hold, nil
+}
+
+// CaptureHold spends some or all of a hold and releases the rest
+func (s *WalletService) CaptureHold(
+	ctx context.Context,
+	userID uuid.UUID,
+	holdID uuid.UUID,
+	req *models.CaptureWalletHoldRequest,
+) (*models.WalletHold, error) {
+	hold, wallet, err := s.getHold(ctx, userID, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := hold.Amount
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	if amount < 0 || amount > hold.Amount {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Capture amount must be between 0 and the held %d", hold.Amount),
+			http.StatusBadRequest,
+		)
+	}
+
+	txn := &models.WalletTransaction{
+		Type:           models.WalletTransactionCapture,
+		IdempotencyKey: "capture:" + hold.ID.String(),
+		Description:    hold.Description,
+	}
+
+	captured, err := s.walletRepo.CaptureHold(ctx, hold, amount, txn)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to capture wallet hold",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if !captured {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Hold is %s, not active", hold.Status),
+			http.StatusConflict,
+		)
+	}
+
+	if amount > 0 {
+		s.recordTransaction(ctx, wallet, txn)
+	}
+
+	return hold, nil
+}
+
+// ReleaseHold gives a hold's funds back to the wallet
+func (s *WalletService) ReleaseHold(ctx context.Context, userID, holdID uuid.UUID) (*models.WalletHold, error) {
+	hold, _, err := s.getHold(ctx, userID, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := s.walletRepo.ReleaseHold(ctx, hold)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to release wallet hold",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if !released {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			fmt.Sprintf("Hold is %s, not active", hold.Status),
+			http.StatusConflict,
+		)
+	}
+
+	return hold, nil
+}
+
+// GrantCredit adds promotional credit to a customer's wallet that expires at
+// req.ExpiresAt if not spent
+func (s *WalletService) GrantCredit(ctx context.Context, adminID uuid.UUID, req *models.WalletGrantRequest) (*models.WalletTransaction, error) {
+	if err := validateWalletRequest(req.Amount, req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+
+	if !req.ExpiresAt.After(time.Now()) {
+		return nil, models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"expires_at must be in the future",
+			http.StatusBadRequest,
+		)
+	}
+
+	customer, err := s.customerRepo.GetByID(ctx, req.CustomerID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve customer",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if customer == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeNotFound,
+			"Customer not found",
+			http.StatusNotFound,
+		)
+	}
+
+	wallet := &models.Wallet{
+		CustomerID: customer.ID,
+		Currency:   req.Currency,
+	}
+	txn := &models.WalletTransaction{
+		Type:           models.WalletTransactionGrant,
+		Amount:         req.Amount,
+		IdempotencyKey: req.IdempotencyKey,
+	}
+	if req.Description != "" {
+		txn.Description = &req.Description
+	}
+
+	credited, err := s.walletRepo.Credit(ctx, wallet, txn, &models.WalletGrant{
+		Amount:    req.Amount,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to grant wallet credit",
+			http.StatusInternalServerError,
+		)
+	}
+
+	if !credited {
+		if txn.Type != models.WalletTransactionGrant || txn.Amount != req.Amount {
+			return nil, models.NewAPIError(
+				models.ErrCodeDuplicate,
+				"Idempotency key was already used for a different request",
+				http.StatusConflict,
+			)
+		}
+		return txn, nil
+	}
+
+	s.recordTransaction(ctx, wallet, txn)
+	recordAudit(ctx, s.auditRepo, &models.AuditEvent{
+		CustomerID:   &customer.ID,
+		ActorUserID:  &adminID,
+		Action:       models.AuditActionWalletCreditGranted,
+		ResourceType: "wallet",
+		ResourceID:   wallet.ID,
+		Details: models.JSONBMap{
+			"amount":     req.Amount,
+			"currency":   req.Currency,
+			"expires_at": req.ExpiresAt,
+		},
+	})
+
+	return txn, nil
+}
+
+// ExpireGrants removes unspent promotional credit past its expiry. It is
+// safe to run on several instances at once. Returns the number of grants
+// expired.
+func (s *WalletService) ExpireGrants(ctx context.Context) (int, error) {
+	expired := 0
+	for {
+		grants, err := s.walletRepo.ListExpiredGrants(ctx, time.Now(), walletExpiryBatchSize)
+		if err != nil {
+			return expired, err
+		}
+
+		for i := range grants {
+			grant := &grants[i]
+			txn := &models.WalletTransaction{
+				Type:           models.WalletTransactionExpiry,
+				IdempotencyKey: "expiry:" + grant.ID.String(),
+			}
+
+			ok, err := s.walletRepo.ExpireGrant(ctx, grant, txn)
+			if err != nil {
+				return expired, err
+			}
+			if !ok {
+				continue
+			}
+
+			expired++
+			wallet, err := s.walletRepo.GetByID(ctx, grant.WalletID)
+			if err != nil || wallet == nil {
+				log.Printf("Failed to record expiry of wallet grant %s in ledger: %v", grant.ID, err)
+				continue
+			}
+			s.recordTransaction(ctx, wallet, txn)
+		}
+
+		if len(grants) < walletExpiryBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// recordTransaction books a wallet transaction in the ledger
+func (s *WalletService) recordTransaction(ctx context.Context, wallet *models.Wallet, txn *models.WalletTransaction) {
+	if err := s.ledgerService.RecordWalletTransaction(ctx, wallet, txn); err != nil {
+		log.Printf("Failed to record wallet transaction %s in ledger: %v", txn.ID, err)
+	}
+}
+
+// getCustomer returns the user's customer, or nil if they have none
+func (s *WalletService) getCustomer(ctx context.Context, userID uuid.UUID) (*models.Customer, error) {
+	customer, err := s.customerRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve customer",
+			http.StatusInternalServerError,
+		)
+	}
+
+	return customer, nil
+}
+
+// getWallet returns the user's wallet in a currency. A user without one has
+// no funds.
+func (s *WalletService) getWallet(ctx context.Context, userID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
+	customer, err := s.getCustomer(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var wallet *models.Wallet
+	if customer != nil {
+		wallet, err = s.walletRepo.Get(ctx, customer.ID, currency)
+		if err != nil {
+			return nil, models.NewAPIError(
+				models.ErrCodeProviderError,
+				"Failed to retrieve wallet",
+				http.StatusInternalServerError,
+			)
+		}
+	}
+
+	if wallet == nil {
+		return nil, models.NewAPIError(
+			models.ErrCodeInsufficientFunds,
+			fmt.Sprintf("No %s wallet funds", currency),
+			http.StatusPaymentRequired,
+		)
+	}
+
+	return wallet, nil
+}
+
+// getHold returns a hold on one of the user's wallets and that wallet
+func (s *WalletService) getHold(ctx context.Context, userID, holdID uuid.UUID) (*models.WalletHold, *models.Wallet, error) {
+	hold, err := s.walletRepo.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve wallet hold",
+			http.StatusInternalServerError,
+		)
+	}
+
+	notFound := models.NewAPIError(
+		models.ErrCodeNotFound,
+		"Hold not found",
+		http.StatusNotFound,
+	)
+	if hold == nil {
+		return nil, nil, notFound
+	}
+
+	wallet, err := s.walletRepo.GetByID(ctx, hold.WalletID)
+	if err != nil {
+		return nil, nil, models.NewAPIError(
+			models.ErrCodeProviderError,
+			"Failed to retrieve wallet",
+			http.StatusInternalServerError,
+		)
+	}
+
+	// Verify the hold is on one of the user's wallets
+	customer, err := s.getCustomer(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if wallet == nil || customer == nil || wallet.CustomerID != customer.ID {
+		return nil, nil, notFound
+	}
+
+	return hold, wallet, nil
+}
+
+// validateWalletRequest checks the fields shared by debit, hold and grant requests
+func validateWalletRequest(amount int64, idempotencyKey string) error {
+	if amount <= 0 {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"amount must be greater than 0",
+			http.StatusBadRequest,
+		)
+	}
+	if idempotencyKey == "" {
+		return models.NewAPIError(
+			models.ErrCodeInvalidRequest,
+			"idempotency_key is required",
+			http.StatusBadRequest,
+		)
+	}
+
+	return nil
+}
+
+// insufficientWalletFunds is the error for spending more than a wallet holds
+func insufficientWalletFunds(wallet *models.Wallet, amount int64) error {
+	return models.NewAPIError(
+		models.ErrCodeInsufficientFunds,
+		fmt.Sprintf("Insufficient wallet funds. Available: %d, Requested: %d", wallet.Available(), amount),
+		http.StatusPaymentRequired,
+	)
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"payment-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWalletRepository is a mock for WalletRepository
+type MockWalletRepository struct {
+	mock.Mock
+}
+
+func (m *MockWalletRepository) Get(ctx context.Context, customerID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
+	args := m.Called(ctx, customerID, currency)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Wallet), args.Error(1)
+}
+
+func (m *MockWalletRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Wallet), args.Error(1)
+}
+
+func (m *MockWalletRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID) ([]models.Wallet, error) {
+	args := m.Called(ctx, customerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Wallet), args.Error(1)
+}
+
+func (m *MockWalletRepository) Credit(ctx context.Context, wallet *models.Wallet, txn *models.WalletTransaction, grant *models.WalletGrant) (bool, error) {
+	args := m.Called(ctx, wallet, txn, grant)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWalletRepository) Debit(ctx context.Context, txn *models.WalletTransaction) (bool, error) {
+	args := m.Called(ctx, txn)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWalletRepository) CreateHold(ctx context.Context, hold *models.WalletHold) (bool, error) {
+	args := m.Called(ctx, hold)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWalletRepository) GetHold(ctx context.Context, id uuid.UUID) (*models.WalletHold, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WalletHold), args.Error(1)
+}
+
+func (m *MockWalletRepository) CaptureHold(ctx context.Context, hold *models.WalletHold, amount int64, txn *models.WalletTransaction) (bool, error) {
+	args := m.Called(ctx, hold, amount, txn)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWalletRepository) ReleaseHold(ctx context.Context, hold *models.WalletHold) (bool, error) {
+	args := m.Called(ctx, hold)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWalletRepository) ListTransactions(ctx context.Context, customerID uuid.UUID, limit, offset int) ([]models.WalletTransaction, int, error) {
+	args := m.Called(ctx, customerID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]models.WalletTransaction), args.Int(1), args.Error(2)
+}
+
+func (m *MockWalletRepository) ListExpiredGrants(ctx context.Context, now time.Time, limit int) ([]models.WalletGrant, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.WalletGrant), args.Error(1)
+}
+
+func (m *MockWalletRepository) ExpireGrant(ctx context.Context, grant *models.WalletGrant, txn *models.WalletTransaction) (bool, error) {
+	args := m.Called(ctx, grant, txn)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestWalletService_Debit_InsufficientFunds(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	mockWalletRepo := new(MockWalletRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	service := NewWalletService(mockWalletRepo, mockCustomerRepo, nil, nil, NewLedgerService(mockLedgerRepo, LedgerConfig{}))
+
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	wallet := &models.Wallet{ID: uuid.New(), CustomerID: customer.ID, Currency: models.CurrencySEK, Balance: 1000, Held: 600}
+
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(customer, nil)
+	mockWalletRepo.On("Get", ctx, customer.ID, models.CurrencySEK).Return(wallet, nil)
+	mockWalletRepo.On("Debit", ctx, mock.AnythingOfType("*models.WalletTransaction")).Return(false, nil)
+
+	// Execute
+	txn, err := service.Debit(ctx, userID, &models.WalletDebitRequest{
+		Amount:         500,
+		Currency:       models.CurrencySEK,
+		IdempotencyKey: "order-1",
+	})
+
+	// Assert
+	assert.Nil(t, txn)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, models.ErrCodeInsufficientFunds, apiErr.Code)
+	assert.Equal(t, http.StatusPaymentRequired, apiErr.StatusCode)
+	mockLedgerRepo.AssertNotCalled(t, "CreateEntry", mock.Anything, mock.Anything)
+}
+
+func TestWalletService_Debit_ReplayWithDifferentAmount(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	mockWalletRepo := new(MockWalletRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	service := NewWalletService(mockWalletRepo, mockCustomerRepo, nil, nil, NewLedgerService(mockLedgerRepo, LedgerConfig{}))
+
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	wallet := &models.Wallet{ID: uuid.New(), CustomerID: customer.ID, Currency: models.CurrencySEK, Balance: 5000}
+
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(customer, nil)
+	mockWalletRepo.On("Get", ctx, customer.ID, models.CurrencySEK).Return(wallet, nil)
+	// The key was first used for a debit of 300
+	mockWalletRepo.On("Debit", ctx, mock.AnythingOfType("*models.WalletTransaction")).Run(func(args mock.Arguments) {
+		txn := args.Get(1).(*models.WalletTransaction)
+		txn.ID = uuid.New()
+		txn.Amount = -300
+	}).Return(true, nil)
+
+	// Execute
+	txn, err := service.Debit(ctx, userID, &models.WalletDebitRequest{
+		Amount:         500,
+		Currency:       models.CurrencySEK,
+		IdempotencyKey: "order-1",
+	})
+
+	// Assert
+	assert.Nil(t, txn)
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	mockLedgerRepo.AssertNotCalled(t, "CreateEntry", mock.Anything, mock.Anything)
+}
+
+func TestWalletService_CaptureHold_PartialCaptureBooksRevenue(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	mockWalletRepo := new(MockWalletRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	service := NewWalletService(mockWalletRepo, mockCustomerRepo, nil, nil, NewLedgerService(mockLedgerRepo, LedgerConfig{}))
+
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	wallet := &models.Wallet{ID: uuid.New(), CustomerID: customer.ID, Currency: models.CurrencySEK, Balance: 5000, Held: 1000}
+	hold := &models.WalletHold{ID: uuid.New(), WalletID: wallet.ID, Amount: 1000, Status: models.WalletHoldStatusActive}
+
+	mockWalletRepo.On("GetHold", ctx, hold.ID).Return(hold, nil)
+	mockWalletRepo.On("GetByID", ctx, wallet.ID).Return(wallet, nil)
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(customer, nil)
+	mockWalletRepo.On("CaptureHold", ctx, hold, int64(600), mock.MatchedBy(func(txn *models.WalletTransaction) bool {
+		return txn.Type == models.WalletTransactionCapture && txn.IdempotencyKey == "capture:"+hold.ID.String()
+	})).Run(func(args mock.Arguments) {
+		txn := args.Get(3).(*models.WalletTransaction)
+		txn.ID = uuid.New()
+		txn.WalletID = wallet.ID
+		txn.Amount = -600
+		hold.Status = models.WalletHoldStatusCaptured
+		hold.CapturedAmount = 600
+	}).Return(true, nil)
+
+	var recorded *models.LedgerEntry
+	mockLedgerRepo.On("CreateEntry", ctx, mock.AnythingOfType("*models.LedgerEntry")).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(*models.LedgerEntry)
+	}).Return(true, nil)
+
+	// Execute
+	amount := int64(600)
+	captured, err := service.CaptureHold(ctx, userID, hold.ID, &models.CaptureWalletHoldRequest{Amount: &amount})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.WalletHoldStatusCaptured, captured.Status)
+	assert.Equal(t, models.LedgerEntryWalletDebit, recorded.Type)
+	assert.Equal(t, []models.LedgerPosting{
+		{AccountCode: models.LedgerAccountRevenue, Amount: -600},
+		{AccountCode: models.LedgerAccountWallet, Amount: 600},
+	}, recorded.Postings)
+}
+
+func TestWalletService_CaptureHold_OtherCustomersHold(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	userID := uuid.New()
+	mockWalletRepo := new(MockWalletRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	service := NewWalletService(mockWalletRepo, mockCustomerRepo, nil, nil, nil)
+
+	customer := &models.Customer{ID: uuid.New(), UserID: userID}
+	wallet := &models.Wallet{ID: uuid.New(), CustomerID: uuid.New(), Currency: models.CurrencySEK}
+	hold := &models.WalletHold{ID: uuid.New(), WalletID: wallet.ID, Amount: 1000, Status: models.WalletHoldStatusActive}
+
+	mockWalletRepo.On("GetHold", ctx, hold.ID).Return(hold, nil)
+	mockWalletRepo.On("GetByID", ctx, wallet.ID).Return(wallet, nil)
+	mockCustomerRepo.On("GetByUserID", ctx, userID).Return(customer, nil)
+
+	// Execute
+	_, err := service.CaptureHold(ctx, userID, hold.ID, &models.CaptureWalletHoldRequest{})
+
+	// Assert
+	apiErr, ok := err.(*models.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	mockWalletRepo.AssertNotCalled(t, "CaptureHold", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWalletService_CreditTopUp_IgnoresOtherPayments(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	mockWalletRepo := new(MockWalletRepository)
+	service := NewWalletService(mockWalletRepo, nil, nil, nil, nil)
+
+	payment := &models.Payment{
+		ID:       uuid.New(),
+		Amount:   10000,
+		Currency: models.CurrencySEK,
+		Status:   models.PaymentStatusSucceeded,
+	}
+
+	// Execute
+	err := service.CreditTopUp(ctx, payment)
+
+	// Assert
+	assert.NoError(t, err)
+	mockWalletRepo.AssertNotCalled(t, "Credit", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -4,16 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"payment-service/internal/models"
 	"payment-service/internal/providers"
 	"payment-service/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type WebhookService struct {
-	webhookRepo      repository.WebhookRepositoryInterface
-	paymentRepo      repository.PaymentRepositoryInterface
-	subscriptionRepo repository.SubscriptionRepositoryInterface
-	refundRepo       repository.RefundRepositoryInterface
+	webhookRepo       repository.WebhookRepositoryInterface
+	paymentRepo       repository.PaymentRepositoryInterface
+	subscriptionRepo  repository.SubscriptionRepositoryInterface
+	refundRepo        repository.RefundRepositoryInterface
+	eventRepo         repository.EventRepositoryInterface
+	invoiceRepo       repository.InvoiceRepositoryInterface
+	dunningService    *DunningService
+	disputeService    *DisputeService
+	ledgerService     *LedgerService
+	settlementService *SettlementService
+	balanceService    *CustomerBalanceService
+	walletService     *WalletService
+	transitions       paymentTransitions
 }
 
 func NewWebhookService(
@@ -21,12 +34,34 @@ func NewWebhookService(
 	paymentRepo repository.PaymentRepositoryInterface,
 	subscriptionRepo repository.SubscriptionRepositoryInterface,
 	refundRepo repository.RefundRepositoryInterface,
+	eventRepo repository.EventRepositoryInterface,
+	invoiceRepo repository.InvoiceRepositoryInterface,
+	dunningService *DunningService,
+	disputeService *DisputeService,
+	ledgerService *LedgerService,
+	settlementService *SettlementService,
+	balanceService *CustomerBalanceService,
+	walletService *WalletService,
 ) *WebhookService {
 	return &WebhookService{
-		webhookRepo:      webhookRepo,
-		paymentRepo:      paymentRepo,
-		subscriptionRepo: subscriptionRepo,
-		refundRepo:       refundRepo,
+		webhookRepo:       webhookRepo,
+		paymentRepo:       paymentRepo,
+		subscriptionRepo:  subscriptionRepo,
+		refundRepo:        refundRepo,
+		eventRepo:         eventRepo,
+		invoiceRepo:       invoiceRepo,
+		dunningService:    dunningService,
+		disputeService:    disputeService,
+		ledgerService:     ledgerService,
+		settlementService: settlementService,
+		balanceService:    balanceService,
+		walletService:     walletService,
+		transitions: paymentTransitions{
+			ledgerService:     ledgerService,
+			settlementService: settlementService,
+			balanceService:    balanceService,
+			walletService:     walletService,
+		},
 	}
 }
 
@@ -49,8 +84,9 @@ func (s *WebhookService) ProcessWebhookEvent(ctx context.Context, event *provide
 		return fmt.Errorf("failed to check for duplicate event: %w", err)
 	}
 
-	if existing != nil && existing.Processed {
-		// Event already processed, skip
+	if existing != nil && existing.Processed && existing.ProcessingError == nil {
+		// Event already processed, skip. Events that failed are processed
+		// again when the provider retries them.
 		return nil
 	}
 
@@ -72,7 +108,7 @@ func (s *WebhookService) ProcessWebhookEvent(ctx context.Context, event *provide
 	}
 
 	if existing != nil {
-		// Event exists but not processed, use existing ID
+		// Event exists but not processed or failed, use existing ID
 		webhookEvent.ID = existing.ID
 	} else {
 		// Create new event record
@@ -92,6 +128,10 @@ func (s *WebhookService) ProcessWebhookEvent(ctx context.Context, event *provide
 		processErr = s.processSubscriptionEvent(ctx, event)
 	case "refund":
 		processErr = s.processRefundEvent(ctx, event)
+	case "invoice":
+		processErr = s.processInvoiceEvent(ctx, event)
+	case "dispute":
+		processErr = s.processDisputeEvent(ctx, event)
 	default:
 		// Unknown resource type, just mark as processed
 		processErr = nil
@@ -102,7 +142,7 @@ func (s *WebhookService) ProcessWebhookEvent(ctx context.Context, event *provide
 		processingErr = &errMsg
 	}
 
-	// Mark event as processed
+	// Mark event as processed, or record why it failed
 	if err := s.webhookRepo.MarkProcessed(ctx, webhookEvent.ID, processingErr); err != nil {
 		return fmt.Errorf("failed to mark webhook event as processed: %w", err)
 	}
@@ -148,7 +188,7 @@ func (s *WebhookService) processPaymentEvent(ctx context.Context, event *provide
 		return fmt.Errorf("failed to update payment: %w", err)
 	}
 
-	return nil
+	return s.transitions.apply(ctx, payment)
 }
 
 // processSubscriptionEvent handles subscription-related webhook events
@@ -168,20 +208,40 @@ func (s *WebhookService) processSubscriptionEvent(ctx context.Context, event *pr
 		return nil
 	}
 
+	previousStatus := subscription.Status
+	itemsChanged := false
+
 	// Update subscription status based on event type
 	switch event.Type {
-	case "customer.subscription.created":
-		subscription.Status = models.SubscriptionStatusActive
-	case "customer.subscription.updated":
-		// Parse status from event - for now just keep existing status
-		// In production, you'd want to parse the full subscription object from the payload
-		// and map the status properly
-		subscription.Status = models.SubscriptionStatusActive
+	case "customer.subscription.created", "customer.subscription.updated",
+		"customer.subscription.paused", "customer.subscription.resumed":
+		if event.Subscription == nil {
+			return fmt.Errorf("subscription event missing subscription object")
+		}
+		syncSubscription(subscription, event.Subscription)
+
+		// Items may also be changed at the provider, e.g. in its dashboard
+		if event.Subscription.Items != nil {
+			itemsChanged = syncSubscriptionItems(subscription, event.Subscription.Items)
+		}
 	case "customer.subscription.deleted":
 		subscription.Status = models.SubscriptionStatusCanceled
+		if event.Subscription != nil {
+			subscription.CanceledAt = event.Subscription.CanceledAt
+		}
 	case "customer.subscription.trial_will_end":
-		// Notification event, no status change needed
-		return nil
+		// Sent three days before the trial ends
+		if event.Subscription != nil {
+			syncSubscription(subscription, event.Subscription)
+		}
+		data := models.JSONBMap{}
+		if subscription.TrialEnd != nil {
+			data["trial_end"] = subscription.TrialEnd
+		}
+		if subscription.TrialEndBehavior != nil {
+			data["trial_end_behavior"] = *subscription.TrialEndBehavior
+		}
+		s.publishSubscriptionEvent(ctx, subscription, models.EventTypeSubscriptionTrialWillEnd, data)
 	default:
 		// Unknown event type, skip
 		return nil
@@ -191,15 +251,312 @@ func (s *WebhookService) processSubscriptionEvent(ctx context.Context, event *pr
 	if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
 		return fmt.Errorf("failed to update subscription: %w", err)
 	}
+	if itemsChanged {
+		if err := s.subscriptionRepo.SaveItems(ctx, subscription); err != nil {
+			return fmt.Errorf("failed to update subscription items: %w", err)
+		}
+	}
+
+	// Notify applications when a trial ends
+	if previousStatus == models.SubscriptionStatusTrialing && subscription.Status != models.SubscriptionStatusTrialing {
+		switch subscription.Status {
+		case models.SubscriptionStatusActive:
+			s.publishSubscriptionEvent(ctx, subscription, models.EventTypeSubscriptionTrialConverted, models.JSONBMap{})
+		case models.SubscriptionStatusCanceled, models.SubscriptionStatusPaused,
+			models.SubscriptionStatusIncompleteExpired:
+			s.publishSubscriptionEvent(ctx, subscription, models.EventTypeSubscriptionTrialExpired, models.JSONBMap{
+				"status": subscription.Status,
+			})
+		}
+	}
 
 	return nil
 }
 
+// processInvoiceEvent mirrors subscription invoices locally and drives
+// dunning from renewal invoice events
+func (s *WebhookService) processInvoiceEvent(ctx context.Context, event *providers.WebhookEvent) error {
+	if event.Invoice == nil || event.Invoice.Invoice == nil {
+		return fmt.Errorf("invoice event missing invoice object")
+	}
+
+	// Only subscription invoices are tracked
+	if event.Invoice.ProviderSubscriptionID == "" {
+		return nil
+	}
+
+	subscription, err := s.subscriptionRepo.GetByProviderSubscriptionID(ctx, event.Invoice.ProviderSubscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	if subscription == nil {
+		// Subscription not found in our database
+		return nil
+	}
+
+	if err := s.syncInvoice(ctx, subscription, event); err != nil {
+		return err
+	}
+
+	// The first invoice of a new subscription is handled by the incomplete
+	// status instead of dunning
+	if event.Invoice.BillingReason == "subscription_create" {
+		return nil
+	}
+
+	switch event.Type {
+	case "invoice.payment_failed":
+		return s.dunningService.HandlePaymentFailed(ctx, subscription, event.Invoice.ProviderInvoiceID, event.Invoice.AttemptCount)
+	case "invoice.paid":
+		return s.dunningService.HandlePaymentSucceeded(ctx, subscription, event.Invoice.ProviderInvoiceID)
+	default:
+		// Unknown event type, skip
+		return nil
+	}
+}
+
+// processDisputeEvent handles dispute (chargeback) webhook events
+func (s *WebhookService) processDisputeEvent(ctx context.Context, event *providers.WebhookEvent) error {
+	if event.Dispute == nil || event.Dispute.Dispute == nil {
+		return fmt.Errorf("dispute event missing dispute object")
+	}
+
+	return s.disputeService.HandleDisputeEvent(ctx, event.Type, event.Dispute)
+}
+
+// syncInvoice creates or updates the local invoice and, once a payment has
+// been attempted, links it to a payment record and the subscription
+func (s *WebhookService) syncInvoice(ctx context.Context, subscription *models.Subscription, event *providers.WebhookEvent) error {
+	providerInvoice := event.Invoice.Invoice
+	if providerInvoice.TaxBreakdown == nil {
+		applySubscriptionTax(providerInvoice, subscription)
+	}
+
+	invoice, err := s.invoiceRepo.GetByProviderInvoiceID(ctx, subscription.Provider, event.Invoice.ProviderInvoiceID)
+	if err != nil {
+		return fmt.Errorf("failed to get invoice: %w", err)
+	}
+
+	if invoice == nil {
+		invoice = providerInvoice
+		invoice.CustomerID = subscription.CustomerID
+	} else if invoice.Status != models.InvoiceStatusPaid && invoice.Status != models.InvoiceStatusVoid {
+		// Paid and void are final; ignore late events carrying older state
+		copyInvoice(invoice, providerInvoice)
+	}
+	invoice.SubscriptionID = &subscription.ID
+
+	// Record the payment behind the invoice
+	var payment *models.Payment
+	if event.Invoice.ProviderPaymentID != "" {
+		switch event.Type {
+		case "invoice.paid":
+			payment, err = s.recordInvoicePayment(ctx, subscription, event.Invoice, models.PaymentStatusSucceeded)
+		case "invoice.payment_failed":
+			payment, err = s.recordInvoicePayment(ctx, subscription, event.Invoice, models.PaymentStatusFailed)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if payment != nil {
+		invoice.PaymentID = &payment.ID
+	}
+	if payment != nil && payment.Status == models.PaymentStatusSucceeded {
+		if err := s.ledgerService.RecordCharge(ctx, payment); err != nil {
+			return fmt.Errorf("failed to record charge in ledger: %w", err)
+		}
+		if err := s.settlementService.SyncPayment(ctx, payment); err != nil {
+			return fmt.Errorf("failed to sync payment settlement: %w", err)
+		}
+	}
+
+	if invoice.ID == uuid.Nil {
+		if err := s.invoiceRepo.Create(ctx, invoice); err != nil {
+			return fmt.Errorf("failed to create invoice: %w", err)
+		}
+	} else {
+		if err := s.invoiceRepo.Update(ctx, invoice); err != nil {
+			return fmt.Errorf("failed to update invoice: %w", err)
+		}
+	}
+
+	// Take store credit off new invoices while they are still drafts. Credit
+	// that cannot be applied stays on the balance for a later invoice.
+	if event.Type == "invoice.created" {
+		if err := s.balanceService.ApplyToInvoice(ctx, subscription, invoice); err != nil {
+			log.Printf("Failed to apply customer balance to invoice %s: %v", invoice.ID, err)
+		}
+	}
+
+	if payment != nil && (subscription.LatestPaymentID == nil || *subscription.LatestPaymentID != payment.ID) {
+		subscription.LatestPaymentID = &payment.ID
+		if err := s.subscriptionRepo.Update(ctx, subscription); err != nil {
+			return fmt.Errorf("failed to update subscription: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// recordInvoicePayment creates or updates the local payment for an invoice's
+// payment attempt
+func (s *WebhookService) recordInvoicePayment(
+	ctx context.Context,
+	subscription *models.Subscription,
+	webhookInvoice *providers.WebhookInvoice,
+	status models.PaymentStatus,
+) (*models.Payment, error) {
+	payment, err := s.paymentRepo.GetByProviderPaymentID(ctx, subscription.Provider, webhookInvoice.ProviderPaymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	var completedAt *time.Time
+	if status == models.PaymentStatusSucceeded {
+		completedAt = webhookInvoice.Invoice.PaidAt
+		if completedAt == nil {
+			now := time.Now()
+			completedAt = &now
+		}
+	}
+
+	if payment != nil {
+		if payment.Status == status || payment.Status == models.PaymentStatusSucceeded {
+			return payment, nil
+		}
+		payment.Status = status
+		payment.CompletedAt = completedAt
+		if err := s.paymentRepo.Update(ctx, payment); err != nil {
+			return nil, fmt.Errorf("failed to update payment: %w", err)
+		}
+		return payment, nil
+	}
+
+	amount := webhookInvoice.Invoice.AmountDue
+	if status == models.PaymentStatusSucceeded {
+		amount = webhookInvoice.Invoice.AmountPaid
+	}
+
+	invoiceID := webhookInvoice.ProviderInvoiceID
+	description := subscription.ProductName
+	payment = &models.Payment{
+		CustomerID:        subscription.CustomerID,
+		Provider:          subscription.Provider,
+		ProviderPaymentID: webhookInvoice.ProviderPaymentID,
+		Amount:            amount,
+		Currency:          webhookInvoice.Invoice.Currency,
+		Status:            status,
+		Description:       &description,
+		SubscriptionID:    &subscription.ID,
+		InvoiceID:         &invoiceID,
+		Metadata:          models.JSONBMap{},
+		CompletedAt:       completedAt,
+	}
+	if status == models.PaymentStatusSucceeded {
+		payment.TaxAmount = webhookInvoice.Invoice.Tax
+		payment.TaxBreakdown = webhookInvoice.Invoice.TaxBreakdown
+	}
+
+	if err := s.paymentRepo.Create(ctx, payment); err != nil {
+		return nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	return payment, nil
+}
+
+// applySubscriptionTax fills in an invoice's tax breakdown from the
+// subscription's tax settings when the provider did not report one. Tax
+// inclusive prices are split at the subscription's rate.
+func applySubscriptionTax(invoice *models.Invoice, subscription *models.Subscription) {
+	if subscription.TaxBreakdown == nil || len(subscription.TaxBreakdown.Lines) == 0 {
+		return
+	}
+
+	breakdown := *subscription.TaxBreakdown
+	line := breakdown.Lines[0]
+	if invoice.Tax == 0 && line.Rate > 0 && breakdown.Behavior == models.TaxBehaviorInclusive {
+		invoice.Tax = invoice.Total - netOfTax(invoice.Total, line.Rate)
+	}
+	line.Net = invoice.Total - invoice.Tax
+	line.Tax = invoice.Tax
+	breakdown.Lines = []models.TaxLine{line}
+
+	invoice.TaxBreakdown = &breakdown
+}
+
+// copyInvoice copies provider-owned state onto the local invoice
+func copyInvoice(invoice, providerInvoice *models.Invoice) {
+	invoice.Number = providerInvoice.Number
+	invoice.Status = providerInvoice.Status
+	invoice.BillingReason = providerInvoice.BillingReason
+	invoice.Subtotal = providerInvoice.Subtotal
+	invoice.Tax = providerInvoice.Tax
+	invoice.TaxBreakdown = providerInvoice.TaxBreakdown
+	invoice.Total = providerInvoice.Total
+	invoice.AmountDue = providerInvoice.AmountDue
+	invoice.AmountPaid = providerInvoice.AmountPaid
+	invoice.LineItems = providerInvoice.LineItems
+	invoice.PeriodStart = providerInvoice.PeriodStart
+	invoice.PeriodEnd = providerInvoice.PeriodEnd
+	invoice.DueDate = providerInvoice.DueDate
+	invoice.PaidAt = providerInvoice.PaidAt
+}
+
+// publishSubscriptionEvent publishes an event about a subscription to its customer
+func (s *WebhookService) publishSubscriptionEvent(
+	ctx context.Context,
+	subscription *models.Subscription,
+	eventType models.EventType,
+	data models.JSONBMap,
+) {
+	publishEvent(ctx, s.eventRepo, &models.Event{
+		CustomerID:   subscription.CustomerID,
+		Type:         eventType,
+		ResourceType: "subscription",
+		ResourceID:   subscription.ID,
+		Data:         data,
+	})
+}
+
+// syncSubscription copies provider-owned state onto the local subscription
+func syncSubscription(subscription, providerSubscription *models.Subscription) {
+	// A subscription marked unpaid by dunning stays unpaid while the provider
+	// still reports it past due
+	if subscription.Status != models.SubscriptionStatusUnpaid || providerSubscription.Status != models.SubscriptionStatusPastDue {
+		subscription.Status = providerSubscription.Status
+	}
+	subscription.CurrentPeriodStart = providerSubscription.CurrentPeriodStart
+	subscription.CurrentPeriodEnd = providerSubscription.CurrentPeriodEnd
+	subscription.TrialStart = providerSubscription.TrialStart
+	subscription.TrialEnd = providerSubscription.TrialEnd
+	subscription.CancelAt = providerSubscription.CancelAt
+	subscription.CancelAtPeriodEnd = providerSubscription.CancelAtPeriodEnd
+	subscription.CanceledAt = providerSubscription.CanceledAt
+
+	// Pause state; keep our own paused_at if the pause started through the API.
+	// A trial ending without a payment method pauses without a pause behavior.
+	if providerSubscription.Status == models.SubscriptionStatusPaused {
+		if subscription.PausedAt == nil {
+			now := time.Now()
+			subscription.PausedAt = &now
+		}
+	} else {
+		subscription.PausedAt = nil
+	}
+	subscription.PauseBehavior = providerSubscription.PauseBehavior
+	subscription.ResumesAt = providerSubscription.ResumesAt
+}
+
 // processRefundEvent handles refund-related webhook events
 func (s *WebhookService) processRefundEvent(ctx context.Context, event *providers.WebhookEvent) error {
 	if event.ResourceID == "" {
 		return fmt.Errorf("refund event missing resource ID")
 	}
+	if event.Refund == nil {
+		return nil
+	}
 
 	// Get refund from database by provider refund ID
 	refund, err := s.refundRepo.GetByProviderRefundID(ctx, event.ResourceID)
@@ -212,20 +569,35 @@ func (s *WebhookService) processRefundEvent(ctx context.Context, event *provider
 		return nil
 	}
 
-	// Update refund status based on event type
-	switch event.Type {
-	case "charge.refund.updated":
-		// For now, just mark as succeeded
-		// In production, parse the actual status from the payload
-		refund.Status = models.RefundStatusSucceeded
-	default:
-		// Unknown event type, skip
+	// Only open refunds move; a final status is never reopened. Updating to
+	// a final status settles the refund's reservation on the payment: a
+	// failed or canceled refund releases it, a succeeded one is added to the
+	// refunded amount.
+	if refund.Status.Open() && refund.Status != event.Refund.Status {
+		refund.Status = event.Refund.Status
+		if err := s.refundRepo.Update(ctx, refund); err != nil {
+			return fmt.Errorf("failed to update refund: %w", err)
+		}
+	}
+
+	// Booking is idempotent, so a retried event books what an earlier
+	// attempt missed
+	if refund.Status != models.RefundStatusSucceeded {
 		return nil
 	}
 
-	// Update refund in database
-	if err := s.refundRepo.Update(ctx, refund); err != nil {
-		return fmt.Errorf("failed to update refund: %w", err)
+	payment, err := s.paymentRepo.GetByID(ctx, refund.PaymentID)
+	if err != nil {
+		return fmt.Errorf("failed to get payment: %w", err)
+	}
+	if payment == nil {
+		return fmt.Errorf("payment %s for refund %s not found", refund.PaymentID, refund.ID)
+	}
+	if err := s.ledgerService.RecordRefund(ctx, refund, payment); err != nil {
+		return fmt.Errorf("failed to record refund in ledger: %w", err)
+	}
+	if err := s.settlementService.SyncRefund(ctx, refund, payment); err != nil {
+		return fmt.Errorf("failed to sync refund settlement: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"payment-service/internal/models"
+	"payment-service/internal/providers"
+	"payment-service/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWebhookRepository is a mock implementation of WebhookRepositoryInterface
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) Create(ctx context.Context, event *repository.WebhookEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) GetByProviderEventID(ctx context.Context, provider models.Provider, providerEventID string) (*repository.WebhookEvent, error) {
+	args := m.Called(ctx, provider, providerEventID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.WebhookEvent), args.Error(1)
+}
+
+func (m *MockWebhookRepository) MarkProcessed(ctx context.Context, id uuid.UUID, processingError *string) error {
+	args := m.Called(ctx, id, processingError)
+	return args.Error(0)
+}
+
+func TestWebhookService_ProcessWebhookEvent_RetriesFailedEvent(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	eventID := uuid.New()
+
+	mockWebhookRepo := new(MockWebhookRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	service := NewWebhookService(mockWebhookRepo, mockPaymentRepo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	event := &providers.WebhookEvent{
+		ID:           "evt_test123",
+		Type:         "payment_intent.succeeded",
+		ResourceType: "payment",
+		ResourceID:   "pi_test123",
+	}
+	failure := "failed to get payment: connection refused"
+	failed := &repository.WebhookEvent{
+		ID:              eventID,
+		Provider:        models.ProviderStripe,
+		ProviderEventID: "evt_test123",
+		Processed:       true,
+		ProcessingError: &failure,
+	}
+
+	// Mock expectations: the first delivery fails, the retry succeeds
+	mockWebhookRepo.On("GetByProviderEventID", ctx, models.ProviderStripe, "evt_test123").Return(nil, nil).Once()
+	mockWebhookRepo.On("Create", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*repository.WebhookEvent).ID = eventID
+	}).Return(nil).Once()
+	mockPaymentRepo.On("GetByProviderPaymentID", ctx, models.ProviderStripe, "pi_test123").Return(nil, errors.New("connection refused")).Once()
+	mockWebhookRepo.On("MarkProcessed", ctx, eventID, mock.MatchedBy(func(processingError *string) bool {
+		return processingError != nil
+	})).Return(nil).Once()
+
+	mockWebhookRepo.On("GetByProviderEventID", ctx, models.ProviderStripe, "evt_test123").Return(failed, nil).Once()
+	mockPaymentRepo.On("GetByProviderPaymentID", ctx, models.ProviderStripe, "pi_test123").Return(nil, nil).Once()
+	mockWebhookRepo.On("MarkProcessed", ctx, eventID, (*string)(nil)).Return(nil).Once()
+
+	// Execute
+	firstErr := service.ProcessWebhookEvent(ctx, event, "stripe")
+	retryErr := service.ProcessWebhookEvent(ctx, event, "stripe")
+
+	// Assert
+	assert.Error(t, firstErr)
+	assert.NoError(t, retryErr)
+	mockWebhookRepo.AssertExpectations(t)
+	mockPaymentRepo.AssertExpectations(t)
+	mockWebhookRepo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestWebhookService_ProcessWebhookEvent_SkipsProcessedEvent(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+
+	mockWebhookRepo := new(MockWebhookRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	service := NewWebhookService(mockWebhookRepo, mockPaymentRepo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	event := &providers.WebhookEvent{
+		ID:           "evt_test123",
+		Type:         "payment_intent.succeeded",
+		ResourceType: "payment",
+		ResourceID:   "pi_test123",
+	}
+	processed := &repository.WebhookEvent{
+		ID:              uuid.New(),
+		Provider:        models.ProviderStripe,
+		ProviderEventID: "evt_test123",
+		Processed:       true,
+	}
+
+	// Mock expectations
+	mockWebhookRepo.On("GetByProviderEventID", ctx, models.ProviderStripe, "evt_test123").Return(processed, nil)
+
+	// Execute
+	err := service.ProcessWebhookEvent(ctx, event, "stripe")
+
+	// Assert
+	assert.NoError(t, err)
+	mockPaymentRepo.AssertNotCalled(t, "GetByProviderPaymentID", mock.Anything, mock.Anything, mock.Anything)
+	mockWebhookRepo.AssertNotCalled(t, "MarkProcessed", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWebhookService_RefundUpdated_Succeeded(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+	refundID := uuid.New()
+	paymentID := uuid.New()
+
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockRefundRepo := new(MockRefundRepository)
+	mockLedgerRepo := new(MockLedgerRepository)
+	mockProvider := new(MockPaymentProvider)
+	mockFactory := new(MockProviderFactory)
+	ledgerService := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+	settlementService := NewSettlementService(mockPaymentRepo, mockRefundRepo, ledgerService, mockFactory)
+	service := NewWebhookService(nil, mockPaymentRepo, nil, mockRefundRepo, nil, nil, nil, nil, ledgerService, settlementService, nil, nil)
+
+	refund := &models.Refund{
+		ID:               refundID,
+		PaymentID:        paymentID,
+		Provider:         models.ProviderStripe,
+		ProviderRefundID: "re_test123",
+		Amount:           5000,
+		Currency:         models.CurrencySEK,
+		Status:           models.RefundStatusPending,
+	}
+	payment := &models.Payment{
+		ID:         paymentID,
+		CustomerID: uuid.New(),
+		Amount:     10000,
+		Currency:   models.CurrencySEK,
+		Status:     models.PaymentStatusSucceeded,
+	}
+	event := &providers.WebhookEvent{
+		Type:         "refund.updated",
+		ResourceType: "refund",
+		ResourceID:   "re_test123",
+		Status:       "succeeded",
+		Refund:       &models.Refund{ProviderRefundID: "re_test123", Status: models.RefundStatusSucceeded},
+	}
+
+	// Mock expectations
+	mockRefundRepo.On("GetByProviderRefundID", ctx, "re_test123").Return(refund, nil)
+	mockRefundRepo.On("Update", ctx, mock.MatchedBy(func(r *models.Refund) bool {
+		return r.ID == refundID && r.Status == models.RefundStatusSucceeded
+	})).Return(nil)
+	mockPaymentRepo.On("GetByID", ctx, paymentID).Return(payment, nil)
+	mockLedgerRepo.On("CreateEntry", ctx, mock.MatchedBy(func(entry *models.LedgerEntry) bool {
+		return entry.Type == models.LedgerEntryRefund && entry.ReferenceID == refundID
+	})).Return(true, nil)
+	mockFactory.On("GetProvider", models.ProviderStripe).Return(mockProvider, nil)
+	mockProvider.On("GetRefundBalanceTransaction", ctx, "re_test123").Return(nil, nil)
+
+	// Execute
+	err := service.processRefundEvent(ctx, event)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.RefundStatusSucceeded, refund.Status)
+	mockRefundRepo.AssertExpectations(t)
+	mockLedgerRepo.AssertExpectations(t)
+}
+
+func TestWebhookService_RefundUpdated_FailedReleasesReservation(t *testing.T) {
+	for _, status := range []models.RefundStatus{models.RefundStatusFailed, models.RefundStatusCanceled} {
+		t.Run(string(status), func(t *testing.T) {
+			// Setup
+			ctx := context.Background()
+			refundID := uuid.New()
+
+			mockPaymentRepo := new(MockPaymentRepository)
+			mockRefundRepo := new(MockRefundRepository)
+			mockLedgerRepo := new(MockLedgerRepository)
+			ledgerService := NewLedgerService(mockLedgerRepo, LedgerConfig{Tenant: "test"})
+			service := NewWebhookService(nil, mockPaymentRepo, nil, mockRefundRepo, nil, nil, nil, nil, ledgerService, nil, nil, nil)
+
+			refund := &models.Refund{
+				ID:               refundID,
+				PaymentID:        uuid.New(),
+				Provider:         models.ProviderStripe,
+				ProviderRefundID: "re_test123",
+				Amount:           5000,
+				Currency:         models.CurrencySEK,
+				Status:           models.RefundStatusProcessing,
+			}
+			event := &providers.WebhookEvent{
+				Type:         "refund.updated",
+				ResourceType: "refund",
+				ResourceID:   "re_test123",
+				Status:       string(status),
+				Refund:       &models.Refund{ProviderRefundID: "re_test123", Status: status},
+			}
+
+			// Mock expectations: the repository's Update releases the reservation
+			mockRefundRepo.On("GetByProviderRefundID", ctx, "re_test123").Return(refund, nil)
+			mockRefundRepo.On("Update", ctx, mock.MatchedBy(func(r *models.Refund) bool {
+				return r.ID == refundID && r.Status == status
+			})).Return(nil)
+
+			// Execute
+			err := service.processRefundEvent(ctx, event)
+
+			// Assert
+			assert.NoError(t, err)
+			mockRefundRepo.AssertExpectations(t)
+			mockLedgerRepo.AssertNotCalled(t, "CreateEntry", mock.Anything, mock.Anything)
+			mockPaymentRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestWebhookService_RefundUpdated_FinalStatusNotReopened(t *testing.T) {
+	// Setup
+	ctx := context.Background()
+
+	mockRefundRepo := new(MockRefundRepository)
+	service := NewWebhookService(nil, nil, nil, mockRefundRepo, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	refund := &models.Refund{
+		ID:               uuid.New(),
+		ProviderRefundID: "re_test123",
+		Status:           models.RefundStatusFailed,
+	}
+	event := &providers.WebhookEvent{
+		Type:         "charge.refund.updated",
+		ResourceType: "refund",
+		ResourceID:   "re_test123",
+		Refund:       &models.Refund{ProviderRefundID: "re_test123", Status: models.RefundStatusPending},
+	}
+
+	// Mock expectations
+	mockRefundRepo.On("GetByProviderRefundID", ctx, "re_test123").Return(refund, nil)
+
+	// Execute
+	err := service.processRefundEvent(ctx, event)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.RefundStatusFailed, refund.Status)
+	mockRefundRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,7 @@
+-- Pause collection state (mirrors Stripe pause_collection)
+ALTER TABLE subscriptions
+    ADD COLUMN pause_behavior VARCHAR(30),           -- void, keep_as_draft, mark_uncollectible
+    ADD COLUMN paused_at TIMESTAMP,
+    ADD COLUMN resumes_at TIMESTAMP;                 -- NULL means paused until resumed manually
+
+CREATE INDEX idx_subscriptions_resumes_at ON subscriptions(resumes_at) WHERE status = 'paused';
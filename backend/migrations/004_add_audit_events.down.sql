@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_subscriptions_cancellation_reason;
+DROP INDEX IF EXISTS idx_audit_events_resource;
+DROP INDEX IF EXISTS idx_audit_events_customer_id;
+
+DROP TABLE IF EXISTS audit_events;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS cancellation_feedback,
+    DROP COLUMN IF EXISTS cancellation_reason;
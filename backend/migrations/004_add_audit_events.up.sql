@@ -0,0 +1,26 @@
+-- Churn feedback captured when a subscription is canceled
+ALTER TABLE subscriptions
+    ADD COLUMN cancellation_reason VARCHAR(50),      -- too_expensive, missing_features, unused, etc
+    ADD COLUMN cancellation_feedback TEXT;
+
+-- Audit log of changes made to payment resources
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID REFERENCES customers(id),
+
+    -- Actor (NULL for system and webhook driven changes)
+    actor_user_id UUID,
+
+    -- Change
+    action VARCHAR(100) NOT NULL,                    -- subscription.canceled, subscription.reactivated, etc
+    resource_type VARCHAR(50) NOT NULL,              -- payment, subscription, refund
+    resource_id UUID NOT NULL,
+    details JSONB DEFAULT '{}',
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_events_customer_id ON audit_events(customer_id, created_at DESC);
+CREATE INDEX idx_audit_events_resource ON audit_events(resource_type, resource_id);
+CREATE INDEX idx_subscriptions_cancellation_reason ON subscriptions(cancellation_reason) WHERE cancellation_reason IS NOT NULL;
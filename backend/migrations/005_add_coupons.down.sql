@@ -0,0 +1,19 @@
+DROP TRIGGER IF EXISTS update_promotion_codes_updated_at ON promotion_codes;
+DROP TRIGGER IF EXISTS update_coupons_updated_at ON coupons;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS discount_ends_at,
+    DROP COLUMN IF EXISTS promotion_code_id,
+    DROP COLUMN IF EXISTS coupon_id,
+    DROP COLUMN IF EXISTS discount_amount;
+
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS promotion_code_id,
+    DROP COLUMN IF EXISTS coupon_id,
+    DROP COLUMN IF EXISTS discount_amount;
+
+DROP TABLE IF EXISTS discount_redemptions;
+DROP TABLE IF EXISTS promotion_codes;
+DROP TABLE IF EXISTS coupons;
+
+DROP TYPE IF EXISTS coupon_duration;
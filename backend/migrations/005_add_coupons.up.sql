@@ -0,0 +1,105 @@
+-- Coupon duration enum
+CREATE TYPE coupon_duration AS ENUM ('once', 'repeating', 'forever');
+
+-- Coupons table - reusable discounts, mirrored to provider coupons
+CREATE TABLE coupons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+
+    -- Discount (exactly one of percent_off and amount_off)
+    percent_off NUMERIC(5, 2),                       -- 0.01 - 100.00
+    amount_off BIGINT,                               -- Smallest currency unit
+    currency currency_code,                          -- Required with amount_off
+
+    -- Duration for subscriptions
+    duration coupon_duration NOT NULL DEFAULT 'once',
+    duration_in_months INTEGER,                      -- Only for repeating
+
+    -- Redemption limits
+    max_redemptions INTEGER,
+    times_redeemed INTEGER NOT NULL DEFAULT 0,
+    redeem_by TIMESTAMP,
+    valid BOOLEAN NOT NULL DEFAULT true,
+
+    -- Provider-specific coupon IDs
+    stripe_coupon_id VARCHAR(255) UNIQUE,
+
+    -- Metadata
+    metadata JSONB DEFAULT '{}',
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    CONSTRAINT coupon_single_discount CHECK ((percent_off IS NULL) <> (amount_off IS NULL)),
+    CONSTRAINT coupon_percent_range CHECK (percent_off IS NULL OR (percent_off > 0 AND percent_off <= 100)),
+    CONSTRAINT coupon_amount_currency CHECK (amount_off IS NULL OR (amount_off > 0 AND currency IS NOT NULL)),
+    CONSTRAINT coupon_repeating_months CHECK (duration <> 'repeating' OR duration_in_months > 0)
+);
+
+-- Promotion codes table - customer-facing codes for a coupon
+CREATE TABLE promotion_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    coupon_id UUID NOT NULL REFERENCES coupons(id),
+    code VARCHAR(100) NOT NULL,                      -- Stored upper case
+    active BOOLEAN NOT NULL DEFAULT true,
+
+    -- Restrictions
+    max_redemptions INTEGER,
+    times_redeemed INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    first_time_transaction BOOLEAN NOT NULL DEFAULT false,
+
+    -- Metadata
+    metadata JSONB DEFAULT '{}',
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    CONSTRAINT unique_promotion_code UNIQUE (code)
+);
+
+-- Discount redemptions table - which customer redeemed what, and where
+CREATE TABLE discount_redemptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    coupon_id UUID NOT NULL REFERENCES coupons(id),
+    promotion_code_id UUID REFERENCES promotion_codes(id),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+
+    -- What the discount was applied to
+    payment_id UUID REFERENCES payments(id),
+    subscription_id UUID REFERENCES subscriptions(id),
+    amount_off BIGINT NOT NULL,
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Applied discounts
+ALTER TABLE payments
+    ADD COLUMN discount_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN coupon_id UUID REFERENCES coupons(id),
+    ADD COLUMN promotion_code_id UUID REFERENCES promotion_codes(id);
+
+ALTER TABLE subscriptions
+    ADD COLUMN discount_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN coupon_id UUID REFERENCES coupons(id),
+    ADD COLUMN promotion_code_id UUID REFERENCES promotion_codes(id),
+    ADD COLUMN discount_ends_at TIMESTAMP;           -- NULL for forever
+
+-- Indexes
+CREATE INDEX idx_promotion_codes_coupon_id ON promotion_codes(coupon_id);
+CREATE INDEX idx_discount_redemptions_coupon_id ON discount_redemptions(coupon_id);
+CREATE INDEX idx_discount_redemptions_customer_id ON discount_redemptions(customer_id);
+
+-- Update triggers for updated_at
+CREATE TRIGGER update_coupons_updated_at
+    BEFORE UPDATE ON coupons
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_promotion_codes_updated_at
+    BEFORE UPDATE ON promotion_codes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_events_type;
+DROP INDEX IF EXISTS idx_events_customer_id;
+
+DROP TABLE IF EXISTS events;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS trial_end_behavior;
@@ -0,0 +1,21 @@
+-- What happens when a trial ends without a payment method
+ALTER TABLE subscriptions
+    ADD COLUMN trial_end_behavior VARCHAR(20);       -- cancel, pause
+
+-- Events exposed to client applications
+CREATE TABLE events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+
+    -- Event
+    type VARCHAR(100) NOT NULL,                      -- subscription.trial_will_end, subscription.trial_converted, etc
+    resource_type VARCHAR(50) NOT NULL,              -- payment, subscription, refund
+    resource_id UUID NOT NULL,
+    data JSONB DEFAULT '{}',
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_events_customer_id ON events(customer_id, created_at DESC);
+CREATE INDEX idx_events_type ON events(type);
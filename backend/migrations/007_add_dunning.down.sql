@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_subscriptions_dunning;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS grace_period_ends_at,
+    DROP COLUMN IF EXISTS next_retry_at,
+    DROP COLUMN IF EXISTS dunning_started_at,
+    DROP COLUMN IF EXISTS dunning_attempts,
+    DROP COLUMN IF EXISTS dunning_invoice_id,
+    DROP COLUMN IF EXISTS dunning_status;
@@ -0,0 +1,10 @@
+-- Dunning state for subscriptions whose renewal payment failed
+ALTER TABLE subscriptions
+    ADD COLUMN dunning_status VARCHAR(20),           -- retrying, recovered, exhausted
+    ADD COLUMN dunning_invoice_id VARCHAR(255),      -- provider invoice being retried
+    ADD COLUMN dunning_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN dunning_started_at TIMESTAMP,
+    ADD COLUMN next_retry_at TIMESTAMP,
+    ADD COLUMN grace_period_ends_at TIMESTAMP;
+
+CREATE INDEX idx_subscriptions_dunning ON subscriptions(next_retry_at, grace_period_ends_at) WHERE dunning_status = 'retrying';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_payments_invoice_id;
+
+DROP INDEX IF EXISTS idx_invoices_subscription_id;
+DROP INDEX IF EXISTS idx_invoices_customer_id;
+
+DROP TABLE IF EXISTS invoices;
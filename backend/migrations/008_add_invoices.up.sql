@@ -0,0 +1,42 @@
+-- Local mirror of provider invoices
+CREATE TABLE invoices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    subscription_id UUID REFERENCES subscriptions(id) ON DELETE SET NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+
+    -- Invoice details
+    provider payment_provider NOT NULL,
+    provider_invoice_id VARCHAR(255) NOT NULL,
+    number VARCHAR(100),
+    status VARCHAR(20) NOT NULL,                     -- draft, open, paid, void, uncollectible
+    billing_reason VARCHAR(50),                      -- subscription_create, subscription_cycle, etc
+
+    -- Amounts in smallest currency unit
+    currency currency_code NOT NULL,
+    subtotal BIGINT NOT NULL DEFAULT 0,
+    tax BIGINT NOT NULL DEFAULT 0,
+    total BIGINT NOT NULL DEFAULT 0,
+    amount_due BIGINT NOT NULL DEFAULT 0,
+    amount_paid BIGINT NOT NULL DEFAULT 0,
+
+    -- Line items
+    line_items JSONB NOT NULL DEFAULT '[]',
+
+    -- Billing period
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+
+    -- Timestamps
+    due_date TIMESTAMP,
+    paid_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    CONSTRAINT unique_provider_invoice UNIQUE (provider, provider_invoice_id)
+);
+
+CREATE INDEX idx_invoices_customer_id ON invoices(customer_id, created_at DESC);
+CREATE INDEX idx_invoices_subscription_id ON invoices(subscription_id, created_at DESC);
+
+CREATE INDEX idx_payments_invoice_id ON payments(invoice_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_receipts_customer_id;
+
+DROP TABLE IF EXISTS receipts;
+DROP TABLE IF EXISTS receipt_sequences;
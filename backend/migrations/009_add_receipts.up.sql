@@ -0,0 +1,31 @@
+-- Last receipt number issued per tenant
+CREATE TABLE receipt_sequences (
+    tenant VARCHAR(100) PRIMARY KEY,
+    last_number BIGINT NOT NULL DEFAULT 0
+);
+
+-- Rendered PDF receipts for succeeded payments
+CREATE TABLE receipts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant VARCHAR(100) NOT NULL,
+    number BIGINT NOT NULL,
+    receipt_number VARCHAR(120) NOT NULL,            -- Formatted, e.g. R-000042
+    payment_id UUID NOT NULL REFERENCES payments(id),
+    invoice_id UUID REFERENCES invoices(id),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+
+    -- Amount covered by the receipt
+    amount BIGINT NOT NULL,
+    currency currency_code NOT NULL,
+
+    -- Rendered document
+    pdf BYTEA,
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    CONSTRAINT unique_receipt_number UNIQUE (tenant, number),
+    CONSTRAINT unique_receipt_payment UNIQUE (payment_id)
+);
+
+CREATE INDEX idx_receipts_customer_id ON receipts(customer_id);
@@ -0,0 +1,10 @@
+ALTER TABLE invoices
+    DROP COLUMN IF EXISTS tax_breakdown;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS tax_breakdown,
+    DROP COLUMN IF EXISTS tax_amount;
+
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS tax_breakdown,
+    DROP COLUMN IF EXISTS tax_amount;
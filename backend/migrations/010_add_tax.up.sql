@@ -0,0 +1,13 @@
+-- Tax charged on payments; amount includes tax_amount
+ALTER TABLE payments
+    ADD COLUMN tax_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax_breakdown JSONB;
+
+-- Tax per period on the undiscounted subscription price
+ALTER TABLE subscriptions
+    ADD COLUMN tax_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax_breakdown JSONB;
+
+-- Tax per rate on invoices, summing to tax
+ALTER TABLE invoices
+    ADD COLUMN tax_breakdown JSONB;
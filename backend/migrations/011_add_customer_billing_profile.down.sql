@@ -0,0 +1,11 @@
+ALTER TABLE customers
+    DROP COLUMN IF EXISTS invoice_email,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS vat_id,
+    DROP COLUMN IF EXISTS org_number,
+    DROP COLUMN IF EXISTS company_name,
+    DROP COLUMN IF EXISTS country,
+    DROP COLUMN IF EXISTS city,
+    DROP COLUMN IF EXISTS postal_code,
+    DROP COLUMN IF EXISTS address_line2,
+    DROP COLUMN IF EXISTS address_line1;
@@ -0,0 +1,12 @@
+-- Billing profile used for tax calculation, receipts and provider customers
+ALTER TABLE customers
+    ADD COLUMN address_line1 VARCHAR(255),
+    ADD COLUMN address_line2 VARCHAR(255),
+    ADD COLUMN postal_code VARCHAR(20),
+    ADD COLUMN city VARCHAR(100),
+    ADD COLUMN country CHAR(2),                  -- ISO 3166-1 alpha-2
+    ADD COLUMN company_name VARCHAR(255),
+    ADD COLUMN org_number VARCHAR(50),
+    ADD COLUMN vat_id VARCHAR(20),
+    ADD COLUMN locale VARCHAR(10),               -- e.g. sv-SE
+    ADD COLUMN invoice_email VARCHAR(255);
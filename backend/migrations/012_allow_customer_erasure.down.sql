@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS unique_active_user_id;
+ALTER TABLE customers ADD CONSTRAINT unique_user_id UNIQUE (user_id);
@@ -0,0 +1,3 @@
+-- Erased customers are soft-deleted, so a user may get a new customer record
+ALTER TABLE customers DROP CONSTRAINT unique_user_id;
+CREATE UNIQUE INDEX unique_active_user_id ON customers(user_id) WHERE deleted_at IS NULL;
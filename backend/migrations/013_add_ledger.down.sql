@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS ledger_postings_balanced ON ledger_postings;
+DROP TRIGGER IF EXISTS ledger_postings_append_only ON ledger_postings;
+DROP TRIGGER IF EXISTS ledger_entries_append_only ON ledger_entries;
+DROP FUNCTION IF EXISTS check_ledger_entry_balanced();
+DROP FUNCTION IF EXISTS reject_ledger_change();
+
+DROP TABLE IF EXISTS ledger_postings;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_accounts;
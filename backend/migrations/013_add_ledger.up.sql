@@ -0,0 +1,84 @@
+-- Chart of accounts, one account per tenant, code and currency
+CREATE TABLE ledger_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant VARCHAR(100) NOT NULL,
+    code VARCHAR(50) NOT NULL,                       -- e.g. provider_balance, revenue
+    type VARCHAR(20) NOT NULL,                       -- asset, liability, revenue, expense
+    currency currency_code NOT NULL,
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    CONSTRAINT unique_ledger_account UNIQUE (tenant, code, currency)
+);
+
+-- Journal entries; each records one money movement
+CREATE TABLE ledger_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant VARCHAR(100) NOT NULL,
+    type VARCHAR(30) NOT NULL,                       -- charge, refund, fee, dispute, dispute_reversal, payout
+    currency currency_code NOT NULL,
+    customer_id UUID REFERENCES customers(id),
+
+    -- What the entry records, e.g. a payment or refund
+    reference_type VARCHAR(50) NOT NULL,
+    reference_id UUID NOT NULL,
+    description TEXT,
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    -- A money movement is recorded once
+    CONSTRAINT unique_ledger_entry UNIQUE (tenant, type, reference_type, reference_id)
+);
+
+-- Postings; amounts are in minor units, debits positive and credits negative
+CREATE TABLE ledger_postings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entry_id UUID NOT NULL REFERENCES ledger_entries(id),
+    account_id UUID NOT NULL REFERENCES ledger_accounts(id),
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_ledger_entries_customer_id ON ledger_entries(customer_id);
+CREATE INDEX idx_ledger_entries_reference ON ledger_entries(reference_type, reference_id);
+CREATE INDEX idx_ledger_postings_entry_id ON ledger_postings(entry_id);
+CREATE INDEX idx_ledger_postings_account_id ON ledger_postings(account_id);
+
+-- The ledger is append-only; corrections are new entries
+CREATE OR REPLACE FUNCTION reject_ledger_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_entries_append_only
+    BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_ledger_change();
+
+CREATE TRIGGER ledger_postings_append_only
+    BEFORE UPDATE OR DELETE ON ledger_postings
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_ledger_change();
+
+-- Every entry's postings must sum to zero when its transaction commits
+CREATE OR REPLACE FUNCTION check_ledger_entry_balanced()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM ledger_postings WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'ledger entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER ledger_postings_balanced
+    AFTER INSERT ON ledger_postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION check_ledger_entry_balanced();
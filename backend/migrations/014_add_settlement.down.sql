@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS idx_refunds_balance_transaction_id;
+DROP INDEX IF EXISTS idx_payments_balance_transaction_id;
+
+ALTER TABLE refunds
+    DROP COLUMN IF EXISTS balance_transaction_id,
+    DROP COLUMN IF EXISTS available_on,
+    DROP COLUMN IF EXISTS exchange_rate,
+    DROP COLUMN IF EXISTS settlement_currency,
+    DROP COLUMN IF EXISTS net_amount,
+    DROP COLUMN IF EXISTS provider_fee;
+
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS balance_transaction_id,
+    DROP COLUMN IF EXISTS available_on,
+    DROP COLUMN IF EXISTS exchange_rate,
+    DROP COLUMN IF EXISTS settlement_currency,
+    DROP COLUMN IF EXISTS net_amount,
+    DROP COLUMN IF EXISTS provider_fee;
@@ -0,0 +1,20 @@
+-- Settlement details from the provider's balance transaction, in the
+-- settlement currency. Refund amounts are negative.
+ALTER TABLE payments
+    ADD COLUMN provider_fee BIGINT,
+    ADD COLUMN net_amount BIGINT,
+    ADD COLUMN settlement_currency VARCHAR(3),
+    ADD COLUMN exchange_rate NUMERIC(18, 8),
+    ADD COLUMN available_on TIMESTAMP,
+    ADD COLUMN balance_transaction_id VARCHAR(255);
+
+ALTER TABLE refunds
+    ADD COLUMN provider_fee BIGINT,
+    ADD COLUMN net_amount BIGINT,
+    ADD COLUMN settlement_currency VARCHAR(3),
+    ADD COLUMN exchange_rate NUMERIC(18, 8),
+    ADD COLUMN available_on TIMESTAMP,
+    ADD COLUMN balance_transaction_id VARCHAR(255);
+
+CREATE INDEX idx_payments_balance_transaction_id ON payments(balance_transaction_id) WHERE balance_transaction_id IS NOT NULL;
+CREATE INDEX idx_refunds_balance_transaction_id ON refunds(balance_transaction_id) WHERE balance_transaction_id IS NOT NULL;
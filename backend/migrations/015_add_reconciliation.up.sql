@@ -0,0 +1,23 @@
+-- Drift found by reconciling local rows against the provider
+CREATE TABLE reconciliation_discrepancies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider payment_provider NOT NULL,
+    object_type VARCHAR(20) NOT NULL,                -- payment, subscription, refund
+    provider_object_id VARCHAR(255) NOT NULL,
+    local_id UUID,                                   -- NULL when the object is missing locally
+    kind VARCHAR(50) NOT NULL,                       -- missing_local, status_mismatch, amount_mismatch, ...
+    local_value TEXT,
+    provider_value TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',      -- open, repaired, resolved
+
+    -- Timestamps
+    first_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP
+);
+
+-- One open discrepancy per object and kind; later runs bump last_seen_at
+CREATE UNIQUE INDEX unique_open_discrepancy
+    ON reconciliation_discrepancies(object_type, provider_object_id, kind)
+    WHERE status = 'open';
+CREATE INDEX idx_discrepancies_status ON reconciliation_discrepancies(status, last_seen_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payout_transactions;
+DROP TABLE IF EXISTS payouts;
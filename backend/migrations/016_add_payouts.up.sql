@@ -0,0 +1,52 @@
+-- Payouts from the provider balance to our bank account
+CREATE TABLE payouts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider payment_provider NOT NULL,
+    provider_payout_id VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL,                     -- pending, in_transit, paid, failed, canceled
+    arrival_date TIMESTAMP NOT NULL,
+    description TEXT,
+
+    -- Error handling
+    failure_code VARCHAR(100),
+    failure_message TEXT,
+
+    -- Set once every transaction is matched and they sum to the amount
+    reconciled_at TIMESTAMP,
+
+    -- Timestamps
+    provider_created_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    CONSTRAINT unique_provider_payout UNIQUE (provider, provider_payout_id)
+);
+
+-- Balance transactions included in a payout
+CREATE TABLE payout_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payout_id UUID NOT NULL REFERENCES payouts(id) ON DELETE CASCADE,
+    balance_transaction_id VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL,                       -- charge, refund, adjustment, ...
+    source_id VARCHAR(255),
+    amount BIGINT NOT NULL,
+    fee BIGINT NOT NULL,
+    net BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    description TEXT,
+
+    -- Our record of the money movement, matched on balance_transaction_id
+    payment_id UUID REFERENCES payments(id),
+    refund_id UUID REFERENCES refunds(id),
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    CONSTRAINT unique_payout_transaction UNIQUE (payout_id, balance_transaction_id)
+);
+
+CREATE INDEX idx_payouts_arrival_date ON payouts(arrival_date DESC);
+CREATE INDEX idx_payout_transactions_payment_id ON payout_transactions(payment_id) WHERE payment_id IS NOT NULL;
+CREATE INDEX idx_payout_transactions_refund_id ON payout_transactions(refund_id) WHERE refund_id IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS update_disputes_updated_at ON disputes;
+
+ALTER TABLE payments DROP COLUMN IF EXISTS disputed_at;
+
+DROP TABLE IF EXISTS disputes;
@@ -0,0 +1,35 @@
+-- Disputes (chargebacks and inquiries) raised against payments
+CREATE TABLE disputes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_id UUID NOT NULL REFERENCES payments(id),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    provider payment_provider NOT NULL,
+    provider_dispute_id VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(30) NOT NULL,                     -- needs_response, under_review, won, lost, warning_*
+    reason VARCHAR(50) NOT NULL,
+
+    -- Evidence; file fields hold the provider's file ID
+    evidence_due_by TIMESTAMP,
+    evidence JSONB NOT NULL DEFAULT '{}',
+    evidence_submitted_at TIMESTAMP,
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    closed_at TIMESTAMP,
+
+    CONSTRAINT unique_provider_dispute UNIQUE (provider, provider_dispute_id)
+);
+
+CREATE INDEX idx_disputes_payment_id ON disputes(payment_id);
+CREATE INDEX idx_disputes_status ON disputes(status, created_at DESC);
+
+CREATE TRIGGER update_disputes_updated_at
+    BEFORE UPDATE ON disputes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Set when a payment is first disputed; disputed payments cannot be refunded
+ALTER TABLE payments ADD COLUMN disputed_at TIMESTAMP;
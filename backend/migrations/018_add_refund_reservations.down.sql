@@ -0,0 +1,5 @@
+ALTER TABLE payments
+    DROP CONSTRAINT IF EXISTS refunds_within_amount,
+    DROP COLUMN IF EXISTS refund_status,
+    DROP COLUMN IF EXISTS amount_refund_pending,
+    DROP COLUMN IF EXISTS amount_refunded;
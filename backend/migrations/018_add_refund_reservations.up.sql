@@ -0,0 +1,28 @@
+-- Refund accounting on payments. Open (pending or processing) refunds reserve
+-- their amount in amount_refund_pending until they succeed or fail.
+ALTER TABLE payments
+    ADD COLUMN amount_refunded BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN amount_refund_pending BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN refund_status VARCHAR(20);             -- partially_refunded, refunded
+
+UPDATE payments p
+SET amount_refunded = r.succeeded,
+    amount_refund_pending = r.open,
+    refund_status = CASE
+        WHEN r.succeeded = 0 THEN NULL
+        WHEN r.succeeded >= p.amount THEN 'refunded'
+        ELSE 'partially_refunded'
+    END
+FROM (
+    SELECT payment_id,
+           COALESCE(SUM(amount) FILTER (WHERE status = 'succeeded'), 0) AS succeeded,
+           COALESCE(SUM(amount) FILTER (WHERE status IN ('pending', 'processing')), 0) AS open
+    FROM refunds
+    GROUP BY payment_id
+) r
+WHERE r.payment_id = p.id;
+
+-- Existing over-refunds are left as they are; new reservations are checked
+ALTER TABLE payments
+    ADD CONSTRAINT refunds_within_amount
+    CHECK (amount_refunded + amount_refund_pending <= amount) NOT VALID;
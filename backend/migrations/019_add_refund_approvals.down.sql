@@ -0,0 +1,12 @@
+-- Postgres cannot drop enum values; refunds that never reached the provider
+-- are marked canceled instead
+UPDATE refunds SET status = 'canceled' WHERE status IN ('pending_approval', 'rejected');
+UPDATE refunds SET provider_refund_id = 'unsent_' || id WHERE provider_refund_id IS NULL;
+
+ALTER TABLE refunds
+    DROP COLUMN IF EXISTS review_note,
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS approval_reason,
+    DROP COLUMN IF EXISTS requested_by,
+    ALTER COLUMN provider_refund_id SET NOT NULL;
@@ -0,0 +1,13 @@
+-- Refunds matching an approval policy wait in pending_approval until a second
+-- person approves (sent to the provider) or rejects them
+ALTER TYPE refund_status ADD VALUE IF NOT EXISTS 'pending_approval';
+ALTER TYPE refund_status ADD VALUE IF NOT EXISTS 'rejected';
+
+-- Refunds waiting for approval have not reached the provider yet
+ALTER TABLE refunds
+    ALTER COLUMN provider_refund_id DROP NOT NULL,
+    ADD COLUMN requested_by UUID,                    -- auth-service user ID
+    ADD COLUMN approval_reason TEXT,                 -- policies the refund matched
+    ADD COLUMN reviewed_by UUID,
+    ADD COLUMN reviewed_at TIMESTAMP,
+    ADD COLUMN review_note TEXT;
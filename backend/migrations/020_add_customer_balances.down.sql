@@ -0,0 +1,10 @@
+UPDATE payments SET provider_payment_id = 'credit_' || id WHERE provider_payment_id IS NULL;
+
+ALTER TABLE payments
+    ALTER COLUMN provider_payment_id SET NOT NULL,
+    DROP COLUMN IF EXISTS credit_applied;
+
+ALTER TABLE refunds DROP COLUMN IF EXISTS destination;
+
+DROP TABLE IF EXISTS customer_balance_transactions;
+DROP TABLE IF EXISTS customer_balances;
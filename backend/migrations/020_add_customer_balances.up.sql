@@ -0,0 +1,53 @@
+-- Store credit per customer and currency. The balance row is locked while it
+-- changes and can never go negative.
+CREATE TABLE customer_balances (
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    currency currency_code NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    PRIMARY KEY (customer_id, currency)
+);
+
+CREATE TRIGGER update_customer_balances_updated_at
+    BEFORE UPDATE ON customer_balances
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Append-only history of balance changes; positive amounts credit the customer
+CREATE TABLE customer_balance_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    currency currency_code NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    balance_after BIGINT NOT NULL,
+    type VARCHAR(30) NOT NULL,                       -- refund_credit, payment_applied, invoice_applied, reversal
+    description TEXT,
+
+    -- What caused the change, e.g. a refund or an invoice
+    reference_type VARCHAR(50) NOT NULL,
+    reference_id UUID,
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Each refund, payment or invoice moves the balance once
+CREATE UNIQUE INDEX idx_customer_balance_transactions_reference
+    ON customer_balance_transactions(type, reference_type, reference_id)
+    WHERE reference_id IS NOT NULL;
+CREATE INDEX idx_customer_balance_transactions_customer
+    ON customer_balance_transactions(customer_id, created_at DESC);
+
+-- Refunds can go to the customer balance instead of the payment method
+ALTER TABLE refunds
+    ADD COLUMN destination VARCHAR(20) NOT NULL DEFAULT 'payment_method';  -- payment_method, customer_balance
+
+-- Payments record the credit used; fully credited payments never reach the
+-- provider and have no provider payment ID
+ALTER TABLE payments
+    ADD COLUMN credit_applied BIGINT NOT NULL DEFAULT 0,
+    ALTER COLUMN provider_payment_id DROP NOT NULL;
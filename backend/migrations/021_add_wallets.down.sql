@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS wallet_transactions;
+DROP TABLE IF EXISTS wallet_holds;
+DROP TABLE IF EXISTS wallet_grants;
+DROP TABLE IF EXISTS wallets;
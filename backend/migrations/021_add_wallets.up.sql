@@ -0,0 +1,79 @@
+-- Prepaid wallets, one per customer and currency. Every change locks the
+-- wallet row, and held funds can never exceed the balance.
+CREATE TABLE wallets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    currency currency_code NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    held BIGINT NOT NULL DEFAULT 0 CHECK (held >= 0 AND held <= balance),
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE (customer_id, currency)
+);
+
+CREATE TRIGGER update_wallets_updated_at
+    BEFORE UPDATE ON wallets
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Promotional credit; debits spend the grant expiring first
+CREATE TABLE wallet_grants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    remaining BIGINT NOT NULL CHECK (remaining >= 0),
+    expires_at TIMESTAMP NOT NULL,
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_wallet_grants_wallet ON wallet_grants(wallet_id, expires_at) WHERE remaining > 0;
+CREATE INDEX idx_wallet_grants_expires_at ON wallet_grants(expires_at) WHERE remaining > 0;
+
+CREATE TABLE wallet_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    captured_amount BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',  -- active, captured, released
+    idempotency_key VARCHAR(255) NOT NULL,
+    description TEXT,
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE (wallet_id, idempotency_key)
+);
+
+CREATE TRIGGER update_wallet_holds_updated_at
+    BEFORE UPDATE ON wallet_holds
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Append-only history of balance changes; positive amounts add funds
+CREATE TABLE wallet_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    type VARCHAR(20) NOT NULL,                       -- top_up, grant, debit, capture, expiry
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    balance_after BIGINT NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    description TEXT,
+
+    -- What caused the change
+    payment_id UUID REFERENCES payments(id),
+    hold_id UUID REFERENCES wallet_holds(id),
+    grant_id UUID REFERENCES wallet_grants(id),
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE (wallet_id, idempotency_key)
+);
+
+CREATE INDEX idx_wallet_transactions_wallet ON wallet_transactions(wallet_id, created_at DESC);
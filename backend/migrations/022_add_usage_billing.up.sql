@@ -0,0 +1,43 @@
+-- Per-unit prices on a subscription billed on reported usage
+CREATE TABLE subscription_metered_prices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id),
+    meter VARCHAR(100) NOT NULL,                     -- e.g. api_calls, storage_gb
+    unit_amount BIGINT NOT NULL CHECK (unit_amount >= 0),
+    aggregation VARCHAR(10) NOT NULL,                -- sum, max, last
+
+    -- Provider subscription item usage is reported to; NULL when invoiced locally
+    provider_item_id VARCHAR(255),
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE (subscription_id, meter)
+);
+
+-- Reported usage; record_id is chosen by the reporter so retries are not counted twice
+CREATE TABLE usage_records (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id),
+    metered_price_id UUID NOT NULL REFERENCES subscription_metered_prices(id),
+    record_id VARCHAR(255) NOT NULL,
+    quantity BIGINT NOT NULL CHECK (quantity >= 0),
+    timestamp TIMESTAMP NOT NULL,
+
+    -- Billing period the usage belongs to
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+
+    -- Set once sent to the provider or invoiced locally
+    reported_at TIMESTAMP,
+    invoice_id UUID REFERENCES invoices(id),
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE (subscription_id, record_id)
+);
+
+CREATE INDEX idx_usage_records_period ON usage_records(metered_price_id, period_start);
+CREATE INDEX idx_usage_records_unreported ON usage_records(created_at) WHERE reported_at IS NULL;
+CREATE INDEX idx_usage_records_uninvoiced ON usage_records(period_end) WHERE reported_at IS NULL AND invoice_id IS NULL;
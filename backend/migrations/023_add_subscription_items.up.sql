@@ -0,0 +1,22 @@
+-- Licensed items of a subscription, each billed unit_amount x quantity per period
+CREATE TABLE subscription_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id),
+    product_name VARCHAR(255) NOT NULL,
+    unit_amount BIGINT NOT NULL CHECK (unit_amount >= 0),
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+
+    -- Provider subscription item; NULL for items created before items were tracked
+    provider_item_id VARCHAR(255),
+
+    -- Timestamps
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE (subscription_id, provider_item_id)
+);
+
+-- Existing subscriptions have a single item for their whole amount
+INSERT INTO subscription_items (subscription_id, product_name, unit_amount, quantity, created_at, updated_at)
+SELECT id, COALESCE(product_name, ''), amount, 1, created_at, updated_at
+FROM subscriptions;
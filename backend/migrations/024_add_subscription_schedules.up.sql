@@ -0,0 +1,40 @@
+-- Changes to a subscription applied in phases, e.g. a future start, a plan
+-- switch at the next renewal or an end after a number of billing cycles
+CREATE TABLE subscription_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    subscription_id UUID REFERENCES subscriptions(id),  -- NULL until a new subscription starts
+
+    -- Provider schedule; NULL when the scheduler job applies the phases
+    provider payment_provider NOT NULL,
+    provider_schedule_id VARCHAR(255) UNIQUE,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'not_started', -- not_started, active, completed, released, canceled
+    end_behavior VARCHAR(20) NOT NULL,                -- release, cancel
+
+    -- Phases with their items, interval and dates; current_phase indexes them
+    phases JSONB NOT NULL,
+    current_phase INTEGER,
+    next_transition_at TIMESTAMP,
+
+    -- Settings of a subscription the schedule starts
+    currency currency_code NOT NULL,
+    product_name VARCHAR(255) NOT NULL,
+    tax_category VARCHAR(20) NOT NULL DEFAULT '',
+    tax_behavior VARCHAR(20) NOT NULL DEFAULT '',
+    billing_country VARCHAR(2) NOT NULL DEFAULT '',
+    vat_id VARCHAR(20) NOT NULL DEFAULT '',
+    metadata JSONB,
+
+    -- Timestamps
+    ended_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- A subscription has at most one schedule that has not ended
+CREATE UNIQUE INDEX idx_subscription_schedules_pending ON subscription_schedules(subscription_id)
+    WHERE status IN ('not_started', 'active');
+CREATE INDEX idx_subscription_schedules_customer ON subscription_schedules(customer_id, created_at DESC);
+CREATE INDEX idx_subscription_schedules_due ON subscription_schedules(next_transition_at)
+    WHERE next_transition_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscriptions_next_billing;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS next_billing_at;
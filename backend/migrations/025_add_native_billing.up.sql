@@ -0,0 +1,7 @@
+-- Subscriptions on providers without a subscription API (e.g. Swish) are
+-- renewed and charged by the billing job; next_billing_at is when it next
+-- renews the subscription or checks on a pending payment. NULL when the
+-- provider bills the subscription.
+ALTER TABLE subscriptions ADD COLUMN next_billing_at TIMESTAMP;
+
+CREATE INDEX idx_subscriptions_next_billing ON subscriptions(next_billing_at) WHERE next_billing_at IS NOT NULL;
@@ -0,0 +1,23 @@
+-- Fails if any row uses a currency outside the original four
+CREATE TYPE currency_code AS ENUM ('SEK', 'USD', 'EUR', 'GBP');
+
+ALTER TABLE subscription_schedules ALTER COLUMN currency TYPE currency_code USING currency::currency_code;
+ALTER TABLE wallets ALTER COLUMN currency TYPE currency_code USING currency::currency_code;
+ALTER TABLE customer_balance_transactions ALTER COLUMN currency TYPE currency_code USING currency::currency_code;
+ALTER TABLE customer_balances ALTER COLUMN currency TYPE currency_code USING currency::currency_code;
+ALTER TABLE ledger_entries ALTER COLUMN currency TYPE currency_code USING currency::currency_code;
+ALTER TABLE ledger_accounts ALTER COLUMN currency TYPE currency_code USING currency::currency_code;
+ALTER TABLE receipts ALTER COLUMN currency TYPE currency_code USING currency::currency_code;
+ALTER TABLE invoices ALTER COLUMN currency TYPE currency_code USING currency::currency_code;
+ALTER TABLE coupons ALTER COLUMN currency TYPE currency_code USING currency::currency_code;
+ALTER TABLE refunds ALTER COLUMN currency TYPE currency_code USING currency::currency_code;
+
+ALTER TABLE subscriptions
+    ALTER COLUMN currency DROP DEFAULT,
+    ALTER COLUMN currency TYPE currency_code USING currency::currency_code,
+    ALTER COLUMN currency SET DEFAULT 'SEK';
+
+ALTER TABLE payments
+    ALTER COLUMN currency DROP DEFAULT,
+    ALTER COLUMN currency TYPE currency_code USING currency::currency_code,
+    ALTER COLUMN currency SET DEFAULT 'SEK';
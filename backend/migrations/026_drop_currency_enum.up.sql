@@ -0,0 +1,25 @@
+-- Currencies are checked against the application's ISO 4217 registry rather
+-- than a fixed enum, so adding one no longer takes a migration. Columns become
+-- plain three-letter codes like the ones added since (payouts, disputes).
+ALTER TABLE payments
+    ALTER COLUMN currency DROP DEFAULT,
+    ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text,
+    ALTER COLUMN currency SET DEFAULT 'SEK';
+
+ALTER TABLE subscriptions
+    ALTER COLUMN currency DROP DEFAULT,
+    ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text,
+    ALTER COLUMN currency SET DEFAULT 'SEK';
+
+ALTER TABLE refunds ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text;
+ALTER TABLE coupons ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text;
+ALTER TABLE invoices ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text;
+ALTER TABLE receipts ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text;
+ALTER TABLE ledger_accounts ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text;
+ALTER TABLE ledger_entries ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text;
+ALTER TABLE customer_balances ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text;
+ALTER TABLE customer_balance_transactions ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text;
+ALTER TABLE wallets ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text;
+ALTER TABLE subscription_schedules ALTER COLUMN currency TYPE VARCHAR(3) USING currency::text;
+
+DROP TYPE currency_code;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_usage_records_invoice_key;
+
+ALTER TABLE usage_records DROP COLUMN IF EXISTS invoice_key;
@@ -0,0 +1,6 @@
+-- Locally invoiced usage is claimed for an invoice before it is summarized, so
+-- usage recorded while the invoice is being created is left for a follow-up
+-- invoice instead of being marked invoiced without being billed
+ALTER TABLE usage_records ADD COLUMN invoice_key VARCHAR(255);
+
+CREATE INDEX idx_usage_records_invoice_key ON usage_records(invoice_key) WHERE invoice_key IS NOT NULL;
@@ -0,0 +1 @@
+UPDATE payments SET amount = amount - credit_applied WHERE credit_applied <> 0;
@@ -0,0 +1,3 @@
+-- Payment amounts become the full price including the store credit used
+-- towards it; the provider charged amount - credit_applied
+UPDATE payments SET amount = amount + credit_applied WHERE credit_applied <> 0;
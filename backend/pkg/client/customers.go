@@ -18,3 +18,53 @@ func (c *Client) GetCurrentCustomer(ctx context.Context) (*Customer, error) {
 	}
 	return &customer, nil
 }
+
+// UpdateCurrentCustomer updates the billing profile of the authenticated user.
+// The profile is synced to the payment provider and used for tax and receipts.
+func (c *Client) UpdateCurrentCustomer(ctx context.Context, req *UpdateCustomerRequest) (*Customer, error) {
+	data, err := c.do(ctx, "PATCH", "/api/customers/me", req)
+	if err != nil {
+		return nil, err
+	}
+	var customer Customer
+	if err := json.Unmarshal(data, &customer); err != nil {
+		return nil, fmt.Errorf("decode customer: %w", err)
+	}
+	return &customer, nil
+}
+
+// ExportCurrentCustomer returns all payments, subscriptions, refunds and audit
+// events stored for the authenticated user.
+func (c *Client) ExportCurrentCustomer(ctx context.Context) (*CustomerExport, error) {
+	data, err := c.do(ctx, "GET", "/api/customers/me/export", nil)
+	if err != nil {
+		return nil, err
+	}
+	var export CustomerExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("decode customer export: %w", err)
+	}
+	return &export, nil
+}
+
+// GetCurrentCustomerBalance returns the authenticated user's store credit in
+// each currency with a page of the balance history.
+func (c *Client) GetCurrentCustomerBalance(ctx context.Context, limit, offset int) (*CustomerBalanceResponse, error) {
+	path := fmt.Sprintf("/api/customers/me/balance?limit=%d&offset=%d", limit, offset)
+	data, err := c.do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var balance CustomerBalanceResponse
+	if err := json.Unmarshal(data, &balance); err != nil {
+		return nil, fmt.Errorf("decode customer balance: %w", err)
+	}
+	return &balance, nil
+}
+
+// EraseCurrentCustomer cancels the authenticated user's subscriptions and
+// erases their personal data. Financial records are kept in pseudonymized form.
+func (c *Client) EraseCurrentCustomer(ctx context.Context) error {
+	_, err := c.do(ctx, "DELETE", "/api/customers/me", nil)
+	return err
+}
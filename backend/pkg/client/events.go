@@ -0,0 +1,21 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ListEvents lists events for the current customer, newest first.
+func (c *Client) ListEvents(ctx context.Context, limit, offset int) (*EventListResponse, error) {
+	path := fmt.Sprintf("/api/events?limit=%d&offset=%d", limit, offset)
+	data, err := c.do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp EventListResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode event list: %w", err)
+	}
+	return &resp, nil
+}
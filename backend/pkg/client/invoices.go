@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ListInvoices lists invoices for the current customer, newest period first.
+func (c *Client) ListInvoices(ctx context.Context, limit, offset int) (*InvoiceListResponse, error) {
+	path := fmt.Sprintf("/api/invoices?limit=%d&offset=%d", limit, offset)
+	data, err := c.do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp InvoiceListResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode invoice list: %w", err)
+	}
+	return &resp, nil
+}
+
+// ListSubscriptionInvoices lists the invoices of a subscription, newest period first.
+func (c *Client) ListSubscriptionInvoices(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) (*InvoiceListResponse, error) {
+	path := fmt.Sprintf("/api/subscriptions/%s/invoices?limit=%d&offset=%d", subscriptionID, limit, offset)
+	data, err := c.do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp InvoiceListResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode invoice list: %w", err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,39 @@
+package client
+
+import "payment-service/pkg/currency"
+
+// Money is an amount in a currency's minor unit, e.g. 12345 SEK is 123.45 kr.
+// It is the same implementation the service uses; arithmetic refuses to mix
+// currencies.
+type Money = currency.Money[Currency]
+
+// ErrCurrencyMismatch is returned when amounts in different currencies are
+// combined.
+var ErrCurrencyMismatch = currency.ErrCurrencyMismatch
+
+// NewMoney returns amount minor units of currency.
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Money returns the payment's amount in its currency, including any store
+// credit used towards it.
+func (p *Payment) Money() Money {
+	return NewMoney(p.Amount, p.Currency)
+}
+
+// ChargedMoney returns the part of the payment's amount charged through the
+// provider, i.e. not covered by store credit.
+func (p *Payment) ChargedMoney() Money {
+	return NewMoney(p.Amount-p.CreditApplied, p.Currency)
+}
+
+// Money returns the refund's amount in its currency.
+func (r *Refund) Money() Money {
+	return NewMoney(r.Amount, r.Currency)
+}
+
+// AmountDueMoney returns what is left to pay on the invoice, in its currency.
+func (i *Invoice) AmountDueMoney() Money {
+	return NewMoney(i.AmountDue, i.Currency)
+}
@@ -0,0 +1,18 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// GetPaymentReceipt downloads the PDF receipt for a succeeded payment.
+func (c *Client) GetPaymentReceipt(ctx context.Context, paymentID uuid.UUID) ([]byte, error) {
+	return c.do(ctx, "GET", fmt.Sprintf("/api/payments/%s/receipt.pdf", paymentID), nil)
+}
+
+// GetInvoiceReceipt downloads the PDF receipt for a paid invoice.
+func (c *Client) GetInvoiceReceipt(ctx context.Context, invoiceID uuid.UUID) ([]byte, error) {
+	return c.do(ctx, "GET", fmt.Sprintf("/api/invoices/%s/receipt.pdf", invoiceID), nil)
+}
@@ -8,7 +8,8 @@ import (
 	"github.com/google/uuid"
 )
 
-// CreateRefund creates a new refund for a payment.
+// CreateRefund creates a new refund for a payment. Refunds that need
+// approval come back with status RefundStatusPendingApproval.
 func (c *Client) CreateRefund(ctx context.Context, req *CreateRefundRequest) (*Refund, error) {
 	data, err := c.do(ctx, "POST", "/api/refunds", req)
 	if err != nil {
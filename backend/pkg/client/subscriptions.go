@@ -75,3 +75,184 @@ func (c *Client) CancelSubscription(ctx context.Context, id uuid.UUID, immediate
 	}
 	return &sub, nil
 }
+
+// CancelSubscriptionWithFeedback cancels a subscription like CancelSubscription
+// and records the customer's reason and free-text feedback.
+func (c *Client) CancelSubscriptionWithFeedback(ctx context.Context, id uuid.UUID, immediate bool, req *CancelSubscriptionRequest) (*Subscription, error) {
+	path := fmt.Sprintf("/api/subscriptions/%s?immediate=%t", id.String(), immediate)
+	data, err := c.do(ctx, "DELETE", path, req)
+	if err != nil {
+		return nil, err
+	}
+	var sub Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("decode subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// ReactivateSubscription undoes a cancellation scheduled for the end of the
+// current billing period.
+func (c *Client) ReactivateSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	data, err := c.do(ctx, "POST", "/api/subscriptions/"+id.String()+"/reactivate", nil)
+	if err != nil {
+		return nil, err
+	}
+	var sub Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("decode subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// PauseSubscription pauses payment collection on a subscription.
+func (c *Client) PauseSubscription(ctx context.Context, id uuid.UUID, req *PauseSubscriptionRequest) (*Subscription, error) {
+	data, err := c.do(ctx, "POST", "/api/subscriptions/"+id.String()+"/pause", req)
+	if err != nil {
+		return nil, err
+	}
+	var sub Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("decode subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// ResumeSubscription resumes payment collection on a paused subscription.
+func (c *Client) ResumeSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	data, err := c.do(ctx, "POST", "/api/subscriptions/"+id.String()+"/resume", nil)
+	if err != nil {
+		return nil, err
+	}
+	var sub Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("decode subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// AddSubscriptionItem adds an item, such as an add-on, to a subscription.
+func (c *Client) AddSubscriptionItem(ctx context.Context, id uuid.UUID, req *AddSubscriptionItemRequest) (*Subscription, error) {
+	data, err := c.do(ctx, "POST", "/api/subscriptions/"+id.String()+"/items", req)
+	if err != nil {
+		return nil, err
+	}
+	var sub Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("decode subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// UpdateSubscriptionItem changes the quantity of a subscription item, such as
+// the number of seats.
+func (c *Client) UpdateSubscriptionItem(ctx context.Context, id, itemID uuid.UUID, req *UpdateSubscriptionItemRequest) (*Subscription, error) {
+	data, err := c.do(ctx, "PATCH", "/api/subscriptions/"+id.String()+"/items/"+itemID.String(), req)
+	if err != nil {
+		return nil, err
+	}
+	var sub Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("decode subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// RemoveSubscriptionItem removes an item from a subscription. An empty
+// prorationBehavior prorates the removal.
+func (c *Client) RemoveSubscriptionItem(ctx context.Context, id, itemID uuid.UUID, prorationBehavior ProrationBehavior) (*Subscription, error) {
+	path := "/api/subscriptions/" + id.String() + "/items/" + itemID.String()
+	if prorationBehavior != "" {
+		path += "?proration_behavior=" + string(prorationBehavior)
+	}
+	data, err := c.do(ctx, "DELETE", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var sub Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("decode subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// ReportUsage records usage for a metered price of a subscription.
+func (c *Client) ReportUsage(ctx context.Context, id uuid.UUID, req *ReportUsageRequest) (*UsageRecord, error) {
+	data, err := c.do(ctx, "POST", "/api/subscriptions/"+id.String()+"/usage", req)
+	if err != nil {
+		return nil, err
+	}
+	var record UsageRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("decode usage record: %w", err)
+	}
+	return &record, nil
+}
+
+// GetSubscriptionUsage returns the usage of a subscription's current billing period.
+func (c *Client) GetSubscriptionUsage(ctx context.Context, id uuid.UUID) (*SubscriptionUsageResponse, error) {
+	data, err := c.do(ctx, "GET", "/api/subscriptions/"+id.String()+"/usage", nil)
+	if err != nil {
+		return nil, err
+	}
+	var usage SubscriptionUsageResponse
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("decode subscription usage: %w", err)
+	}
+	return &usage, nil
+}
+
+// CreateSubscriptionSchedule schedules phased changes to a subscription, or a
+// new subscription starting on a future date.
+func (c *Client) CreateSubscriptionSchedule(ctx context.Context, req *CreateSubscriptionScheduleRequest) (*SubscriptionSchedule, error) {
+	data, err := c.do(ctx, "POST", "/api/subscription-schedules", req)
+	if err != nil {
+		return nil, err
+	}
+	var schedule SubscriptionSchedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("decode subscription schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+// GetSubscriptionSchedule retrieves a subscription schedule by ID.
+func (c *Client) GetSubscriptionSchedule(ctx context.Context, id uuid.UUID) (*SubscriptionSchedule, error) {
+	data, err := c.do(ctx, "GET", "/api/subscription-schedules/"+id.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var schedule SubscriptionSchedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("decode subscription schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+// ListSubscriptionSchedules lists subscription schedules with pagination.
+func (c *Client) ListSubscriptionSchedules(ctx context.Context, limit, offset int) (*SubscriptionScheduleListResponse, error) {
+	path := fmt.Sprintf("/api/subscription-schedules?limit=%d&offset=%d", limit, offset)
+	data, err := c.do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp SubscriptionScheduleListResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode subscription schedule list: %w", err)
+	}
+	return &resp, nil
+}
+
+// CancelSubscriptionSchedule drops the phases of a schedule that have not
+// started yet. The subscription continues as it is.
+func (c *Client) CancelSubscriptionSchedule(ctx context.Context, id uuid.UUID) (*SubscriptionSchedule, error) {
+	data, err := c.do(ctx, "POST", "/api/subscription-schedules/"+id.String()+"/cancel", nil)
+	if err != nil {
+		return nil, err
+	}
+	var schedule SubscriptionSchedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("decode subscription schedule: %w", err)
+	}
+	return &schedule, nil
+}
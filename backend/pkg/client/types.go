@@ -3,6 +3,8 @@ package client
 import (
 	"time"
 
+	"payment-service/pkg/currency"
+
 	"github.com/google/uuid"
 )
 
@@ -14,16 +16,84 @@ const (
 	ProviderSwish  Provider = "swish"
 )
 
-// Currency represents a supported currency.
+// Currency represents an ISO 4217 currency code. The service accepts every
+// currency in the currency package's registry; amounts are in the currency's
+// minor unit, e.g. öre for SEK and whole yen for JPY.
 type Currency string
 
 const (
 	CurrencySEK Currency = "SEK"
-	CurrencyUSD Currency = "USD"
+	CurrencyNOK Currency = "NOK"
+	CurrencyDKK Currency = "DKK"
 	CurrencyEUR Currency = "EUR"
 	CurrencyGBP Currency = "GBP"
+	CurrencyCHF Currency = "CHF"
+	CurrencyPLN Currency = "PLN"
+	CurrencyUSD Currency = "USD"
+	CurrencyCAD Currency = "CAD"
+	CurrencyAUD Currency = "AUD"
+	CurrencyJPY Currency = "JPY"
+	CurrencyISK Currency = "ISK"
+)
+
+// IsValid reports whether the service accepts the currency.
+func (c Currency) IsValid() bool {
+	return currency.IsValid(string(c))
+}
+
+// Exponent returns the number of decimals of the currency's minor unit, e.g.
+// 2 for SEK and 0 for JPY. Unknown currencies are assumed to have 2.
+func (c Currency) Exponent() int {
+	return c.info().Exponent
+}
+
+// FormatAmount formats an amount in the currency's minor unit as a decimal
+// number, e.g. 12345 is "123.45" in SEK and "12345" in JPY.
+func (c Currency) FormatAmount(amount int64) string {
+	return c.info().FormatAmount(amount)
+}
+
+func (c Currency) info() currency.Currency {
+	return currency.ForCode(string(c))
+}
+
+// --- Tax types ---
+
+// TaxCategory is the VAT category a product is sold under.
+type TaxCategory string
+
+const (
+	TaxCategoryStandard     TaxCategory = "standard"
+	TaxCategoryReduced      TaxCategory = "reduced"
+	TaxCategorySuperReduced TaxCategory = "super_reduced"
+	TaxCategoryExempt       TaxCategory = "exempt"
 )
 
+// TaxBehavior says whether an amount includes tax.
+type TaxBehavior string
+
+const (
+	TaxBehaviorInclusive TaxBehavior = "inclusive"
+	TaxBehaviorExclusive TaxBehavior = "exclusive"
+)
+
+// TaxBreakdown records how tax was calculated.
+type TaxBreakdown struct {
+	Country       string      `json:"country,omitempty"`
+	Behavior      TaxBehavior `json:"behavior"`
+	ReverseCharge bool        `json:"reverse_charge"`
+	CustomerVATID string      `json:"customer_vat_id,omitempty"`
+	Lines         []TaxLine   `json:"lines"`
+}
+
+// TaxLine is the tax charged at a single rate.
+type TaxLine struct {
+	Category TaxCategory `json:"category,omitempty"`
+	Rate     float64     `json:"rate"`
+	Net      int64       `json:"net"`
+	Tax      int64       `json:"tax"`
+}
+
 // --- Payment types ---
 
 // PaymentStatus represents the status of a payment.
@@ -47,9 +117,26 @@ type Payment struct {
 	Amount               int64          `json:"amount"`
 	Currency             Currency       `json:"currency"`
 	Status               PaymentStatus  `json:"status"`
+	DiscountAmount       int64          `json:"discount_amount"`
+	CouponID             *uuid.UUID     `json:"coupon_id,omitempty"`
+	PromotionCodeID      *uuid.UUID     `json:"promotion_code_id,omitempty"`
+	TaxAmount            int64          `json:"tax_amount"`
+	TaxBreakdown         *TaxBreakdown  `json:"tax_breakdown,omitempty"`
+	CreditApplied        int64          `json:"credit_applied"`
+	ProviderFee          *int64         `json:"provider_fee,omitempty"`
+	NetAmount            *int64         `json:"net_amount,omitempty"`
+	SettlementCurrency   *Currency      `json:"settlement_currency,omitempty"`
+	ExchangeRate         *float64       `json:"exchange_rate,omitempty"`
+	AvailableOn          *time.Time     `json:"available_on,omitempty"`
+	AmountRefunded       int64          `json:"amount_refunded"`
+	AmountRefundPending  int64          `json:"amount_refund_pending"`
+	RefundStatus         *string        `json:"refund_status,omitempty"`
+	DisputedAt           *time.Time     `json:"disputed_at,omitempty"`
 	PaymentMethodType    *string        `json:"payment_method_type,omitempty"`
 	PaymentMethodDetails map[string]any `json:"payment_method_details,omitempty"`
 	Description          *string        `json:"description,omitempty"`
+	SubscriptionID       *uuid.UUID     `json:"subscription_id,omitempty"`
+	InvoiceID            *string        `json:"invoice_id,omitempty"`
 	ClientSecret         *string        `json:"client_secret,omitempty"`
 	FailureCode          *string        `json:"failure_code,omitempty"`
 	FailureMessage       *string        `json:"failure_message,omitempty"`
@@ -61,11 +148,15 @@ type Payment struct {
 
 // CreatePaymentRequest is the request body for creating a payment.
 type CreatePaymentRequest struct {
-	Provider    Provider       `json:"provider"`
-	Amount      int64          `json:"amount"`
-	Currency    Currency       `json:"currency"`
-	Description string         `json:"description,omitempty"`
-	Metadata    map[string]any `json:"metadata,omitempty"`
+	Provider Provider `json:"provider"`
+	Money
+	Description    string         `json:"description,omitempty"`
+	PromotionCode  string         `json:"promotion_code,omitempty"`
+	TaxCategory    TaxCategory    `json:"tax_category,omitempty"`
+	TaxBehavior    TaxBehavior    `json:"tax_behavior,omitempty"`
+	BillingCountry string         `json:"billing_country,omitempty"`
+	VATID          string         `json:"vat_id,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
 }
 
 // PaymentListResponse is the response for listing payments.
@@ -92,43 +183,113 @@ const (
 	SubscriptionStatusPaused            SubscriptionStatus = "paused"
 )
 
+// CancellationReason is the churn reason given when canceling a subscription.
+type CancellationReason string
+
+const (
+	CancellationReasonTooExpensive    CancellationReason = "too_expensive"
+	CancellationReasonMissingFeatures CancellationReason = "missing_features"
+	CancellationReasonSwitchedService CancellationReason = "switched_service"
+	CancellationReasonUnused          CancellationReason = "unused"
+	CancellationReasonCustomerService CancellationReason = "customer_service"
+	CancellationReasonTooComplex      CancellationReason = "too_complex"
+	CancellationReasonLowQuality      CancellationReason = "low_quality"
+	CancellationReasonOther           CancellationReason = "other"
+)
+
+// TrialEndBehavior controls what happens when a trial ends without a payment method.
+type TrialEndBehavior string
+
+const (
+	TrialEndBehaviorCancel TrialEndBehavior = "cancel"
+	TrialEndBehaviorPause  TrialEndBehavior = "pause"
+)
+
+// DunningStatus reports where a subscription is in the failed-payment retry cycle.
+type DunningStatus string
+
+const (
+	DunningStatusRetrying  DunningStatus = "retrying"
+	DunningStatusRecovered DunningStatus = "recovered"
+	DunningStatusExhausted DunningStatus = "exhausted"
+)
+
+// PauseBehavior controls how invoices are handled while a subscription is paused.
+type PauseBehavior string
+
+const (
+	PauseBehaviorVoid              PauseBehavior = "void"
+	PauseBehaviorKeepAsDraft       PauseBehavior = "keep_as_draft"
+	PauseBehaviorMarkUncollectible PauseBehavior = "mark_uncollectible"
+)
+
 // Subscription represents a subscription returned by the API.
 type Subscription struct {
-	ID                     uuid.UUID          `json:"id"`
-	CustomerID             uuid.UUID          `json:"customer_id"`
-	Provider               Provider           `json:"provider"`
-	ProviderSubscriptionID string             `json:"provider_subscription_id"`
-	Status                 SubscriptionStatus `json:"status"`
-	Amount                 int64              `json:"amount"`
-	Currency               Currency           `json:"currency"`
-	Interval               string             `json:"interval"`
-	IntervalCount          int                `json:"interval_count"`
-	CurrentPeriodStart     time.Time          `json:"current_period_start"`
-	CurrentPeriodEnd       time.Time          `json:"current_period_end"`
-	TrialStart             *time.Time         `json:"trial_start,omitempty"`
-	TrialEnd               *time.Time         `json:"trial_end,omitempty"`
-	CancelAt               *time.Time         `json:"cancel_at,omitempty"`
-	CanceledAt             *time.Time         `json:"canceled_at,omitempty"`
-	CancelAtPeriodEnd      bool               `json:"cancel_at_period_end"`
-	LatestPaymentID        *uuid.UUID         `json:"latest_payment_id,omitempty"`
-	ProductName            string             `json:"product_name"`
-	ProductDescription     *string            `json:"product_description,omitempty"`
-	Metadata               map[string]any     `json:"metadata,omitempty"`
-	CreatedAt              time.Time          `json:"created_at"`
-	UpdatedAt              time.Time          `json:"updated_at"`
+	ID                     uuid.UUID             `json:"id"`
+	CustomerID             uuid.UUID             `json:"customer_id"`
+	Provider               Provider              `json:"provider"`
+	ProviderSubscriptionID string                `json:"provider_subscription_id"`
+	Status                 SubscriptionStatus    `json:"status"`
+	Amount                 int64                 `json:"amount"`
+	Currency               Currency              `json:"currency"`
+	DiscountAmount         int64                 `json:"discount_amount"`
+	CouponID               *uuid.UUID            `json:"coupon_id,omitempty"`
+	PromotionCodeID        *uuid.UUID            `json:"promotion_code_id,omitempty"`
+	DiscountEndsAt         *time.Time            `json:"discount_ends_at,omitempty"`
+	TaxAmount              int64                 `json:"tax_amount"`
+	TaxBreakdown           *TaxBreakdown         `json:"tax_breakdown,omitempty"`
+	Interval               string                `json:"interval"`
+	IntervalCount          int                   `json:"interval_count"`
+	Items                  []SubscriptionItem    `json:"items,omitempty"`
+	MeteredPrices          []MeteredPrice        `json:"metered_prices,omitempty"`
+	Schedule               *SubscriptionSchedule `json:"schedule,omitempty"`
+	CurrentPeriodStart     time.Time             `json:"current_period_start"`
+	CurrentPeriodEnd       time.Time             `json:"current_period_end"`
+	TrialStart             *time.Time            `json:"trial_start,omitempty"`
+	TrialEnd               *time.Time            `json:"trial_end,omitempty"`
+	TrialEndBehavior       *TrialEndBehavior     `json:"trial_end_behavior,omitempty"`
+	CancelAt               *time.Time            `json:"cancel_at,omitempty"`
+	CanceledAt             *time.Time            `json:"canceled_at,omitempty"`
+	CancelAtPeriodEnd      bool                  `json:"cancel_at_period_end"`
+	CancellationReason     *CancellationReason   `json:"cancellation_reason,omitempty"`
+	CancellationFeedback   *string               `json:"cancellation_feedback,omitempty"`
+	PauseBehavior          *PauseBehavior        `json:"pause_behavior,omitempty"`
+	PausedAt               *time.Time            `json:"paused_at,omitempty"`
+	ResumesAt              *time.Time            `json:"resumes_at,omitempty"`
+	DunningStatus          *DunningStatus        `json:"dunning_status,omitempty"`
+	DunningInvoiceID       *string               `json:"dunning_invoice_id,omitempty"`
+	DunningAttempts        int                   `json:"dunning_attempts"`
+	DunningStartedAt       *time.Time            `json:"dunning_started_at,omitempty"`
+	NextRetryAt            *time.Time            `json:"next_retry_at,omitempty"`
+	GracePeriodEndsAt      *time.Time            `json:"grace_period_ends_at,omitempty"`
+	LatestPaymentID        *uuid.UUID            `json:"latest_payment_id,omitempty"`
+	NextBillingAt          *time.Time            `json:"next_billing_at,omitempty"`
+	ProductName            string                `json:"product_name"`
+	ProductDescription     *string               `json:"product_description,omitempty"`
+	Metadata               map[string]any        `json:"metadata,omitempty"`
+	CreatedAt              time.Time             `json:"created_at"`
+	UpdatedAt              time.Time             `json:"updated_at"`
 }
 
 // CreateSubscriptionRequest is the request body for creating a subscription.
 type CreateSubscriptionRequest struct {
-	Provider           Provider       `json:"provider"`
-	Amount             int64          `json:"amount"`
-	Currency           Currency       `json:"currency"`
-	Interval           string         `json:"interval"`
-	IntervalCount      int            `json:"interval_count"`
-	ProductName        string         `json:"product_name"`
-	ProductDescription string         `json:"product_description,omitempty"`
-	TrialPeriodDays    int            `json:"trial_period_days,omitempty"`
-	Metadata           map[string]any `json:"metadata,omitempty"`
+	Provider           Provider                  `json:"provider"`
+	Amount             int64                     `json:"amount"`
+	Currency           Currency                  `json:"currency"`
+	Interval           string                    `json:"interval"`
+	IntervalCount      int                       `json:"interval_count"`
+	Items              []SubscriptionItemRequest `json:"items,omitempty"`
+	MeteredPrices      []MeteredPriceRequest     `json:"metered_prices,omitempty"`
+	ProductName        string                    `json:"product_name"`
+	ProductDescription string                    `json:"product_description,omitempty"`
+	TrialPeriodDays    int                       `json:"trial_period_days,omitempty"`
+	TrialEndBehavior   TrialEndBehavior          `json:"trial_end_behavior,omitempty"`
+	PromotionCode      string                    `json:"promotion_code,omitempty"`
+	TaxCategory        TaxCategory               `json:"tax_category,omitempty"`
+	TaxBehavior        TaxBehavior               `json:"tax_behavior,omitempty"`
+	BillingCountry     string                    `json:"billing_country,omitempty"`
+	VATID              string                    `json:"vat_id,omitempty"`
+	Metadata           map[string]any            `json:"metadata,omitempty"`
 }
 
 // UpdateSubscriptionRequest is the request body for updating a subscription.
@@ -137,6 +298,19 @@ type UpdateSubscriptionRequest struct {
 	Metadata          map[string]any `json:"metadata,omitempty"`
 }
 
+// CancelSubscriptionRequest carries optional churn feedback when canceling.
+type CancelSubscriptionRequest struct {
+	Reason   CancellationReason `json:"reason,omitempty"`
+	Feedback string             `json:"feedback,omitempty"`
+}
+
+// PauseSubscriptionRequest is the request body for pausing a subscription.
+// A nil ResumesAt pauses until ResumeSubscription is called.
+type PauseSubscriptionRequest struct {
+	Behavior  PauseBehavior `json:"behavior"`
+	ResumesAt *time.Time    `json:"resumes_at,omitempty"`
+}
+
 // SubscriptionListResponse is the response for listing subscriptions.
 type SubscriptionListResponse struct {
 	Data   []Subscription `json:"data"`
@@ -145,45 +319,268 @@ type SubscriptionListResponse struct {
 	Offset int            `json:"offset"`
 }
 
+// ProrationBehavior controls how a mid-period change to subscription items is billed.
+type ProrationBehavior string
+
+const (
+	ProrationBehaviorCreateProrations ProrationBehavior = "create_prorations"
+	ProrationBehaviorAlwaysInvoice    ProrationBehavior = "always_invoice"
+	ProrationBehaviorNone             ProrationBehavior = "none"
+)
+
+// SubscriptionItem is a price billed every period of a subscription, once per
+// unit of Quantity (e.g. per seat).
+type SubscriptionItem struct {
+	ID             uuid.UUID `json:"id"`
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	ProductName    string    `json:"product_name"`
+	UnitAmount     int64     `json:"unit_amount"`
+	Quantity       int64     `json:"quantity"`
+	ProviderItemID *string   `json:"provider_item_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// SubscriptionItemRequest adds an item to a new subscription instead of a
+// single Amount. Quantity defaults to 1.
+type SubscriptionItemRequest struct {
+	ProductName string `json:"product_name,omitempty"`
+	UnitAmount  int64  `json:"unit_amount"`
+	Quantity    int64  `json:"quantity,omitempty"`
+}
+
+// AddSubscriptionItemRequest is the request body for adding an item to a subscription.
+type AddSubscriptionItemRequest struct {
+	ProductName       string            `json:"product_name"`
+	UnitAmount        int64             `json:"unit_amount"`
+	Quantity          int64             `json:"quantity,omitempty"`
+	ProrationBehavior ProrationBehavior `json:"proration_behavior,omitempty"`
+}
+
+// UpdateSubscriptionItemRequest is the request body for changing an item's quantity.
+type UpdateSubscriptionItemRequest struct {
+	Quantity          int64             `json:"quantity"`
+	ProrationBehavior ProrationBehavior `json:"proration_behavior,omitempty"`
+}
+
+// UsageAggregation decides how the usage reported in a billing period is billed.
+type UsageAggregation string
+
+const (
+	UsageAggregationSum  UsageAggregation = "sum"
+	UsageAggregationMax  UsageAggregation = "max"
+	UsageAggregationLast UsageAggregation = "last"
+)
+
+// MeteredPrice is a per-unit price on a subscription billed on reported usage.
+type MeteredPrice struct {
+	ID             uuid.UUID        `json:"id"`
+	SubscriptionID uuid.UUID        `json:"subscription_id"`
+	Meter          string           `json:"meter"`
+	UnitAmount     int64            `json:"unit_amount"`
+	Aggregation    UsageAggregation `json:"aggregation"`
+	ProviderItemID *string          `json:"provider_item_id,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// MeteredPriceRequest adds a metered price to a new subscription.
+// Aggregation defaults to sum.
+type MeteredPriceRequest struct {
+	Meter       string           `json:"meter"`
+	UnitAmount  int64            `json:"unit_amount"`
+	Aggregation UsageAggregation `json:"aggregation,omitempty"`
+}
+
+// ReportUsageRequest is the request body for reporting usage. Reporting the
+// same RecordID again does not count the usage twice.
+type ReportUsageRequest struct {
+	Meter     string     `json:"meter"`
+	Quantity  int64      `json:"quantity"`
+	RecordID  string     `json:"record_id"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// UsageRecord represents reported usage returned by the API.
+type UsageRecord struct {
+	ID             uuid.UUID  `json:"id"`
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	MeteredPriceID uuid.UUID  `json:"metered_price_id"`
+	RecordID       string     `json:"record_id"`
+	Quantity       int64      `json:"quantity"`
+	Timestamp      time.Time  `json:"timestamp"`
+	PeriodStart    time.Time  `json:"period_start"`
+	PeriodEnd      time.Time  `json:"period_end"`
+	ReportedAt     *time.Time `json:"reported_at,omitempty"`
+	InvoiceID      *uuid.UUID `json:"invoice_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// UsageSummary is the usage of one metered price in a billing period.
+type UsageSummary struct {
+	MeteredPriceID uuid.UUID        `json:"metered_price_id"`
+	Meter          string           `json:"meter"`
+	Aggregation    UsageAggregation `json:"aggregation"`
+	Quantity       int64            `json:"quantity"`
+	UnitAmount     int64            `json:"unit_amount"`
+	Amount         int64            `json:"amount"`
+}
+
+// SubscriptionUsageResponse is the usage of a subscription's current billing period.
+type SubscriptionUsageResponse struct {
+	SubscriptionID uuid.UUID      `json:"subscription_id"`
+	PeriodStart    time.Time      `json:"period_start"`
+	PeriodEnd      time.Time      `json:"period_end"`
+	Data           []UsageSummary `json:"data"`
+}
+
+// SubscriptionScheduleStatus represents the status of a subscription schedule.
+type SubscriptionScheduleStatus string
+
+const (
+	SubscriptionScheduleStatusNotStarted SubscriptionScheduleStatus = "not_started"
+	SubscriptionScheduleStatusActive     SubscriptionScheduleStatus = "active"
+	SubscriptionScheduleStatusCompleted  SubscriptionScheduleStatus = "completed"
+	SubscriptionScheduleStatusReleased   SubscriptionScheduleStatus = "released"
+	SubscriptionScheduleStatusCanceled   SubscriptionScheduleStatus = "canceled"
+)
+
+// ScheduleEndBehavior decides what happens to a subscription when its schedule's last phase ends.
+type ScheduleEndBehavior string
+
+const (
+	ScheduleEndBehaviorRelease ScheduleEndBehavior = "release"
+	ScheduleEndBehaviorCancel  ScheduleEndBehavior = "cancel"
+)
+
+// SubscriptionSchedule represents scheduled phases of a subscription returned by the API.
+type SubscriptionSchedule struct {
+	ID                 uuid.UUID                  `json:"id"`
+	CustomerID         uuid.UUID                  `json:"customer_id"`
+	SubscriptionID     *uuid.UUID                 `json:"subscription_id,omitempty"`
+	Provider           Provider                   `json:"provider"`
+	ProviderScheduleID *string                    `json:"provider_schedule_id,omitempty"`
+	Status             SubscriptionScheduleStatus `json:"status"`
+	EndBehavior        ScheduleEndBehavior        `json:"end_behavior"`
+	Phases             []SchedulePhase            `json:"phases"`
+	CurrentPhase       *int                       `json:"current_phase,omitempty"`
+	NextTransitionAt   *time.Time                 `json:"next_transition_at,omitempty"`
+	Currency           Currency                   `json:"currency"`
+	ProductName        string                     `json:"product_name"`
+	TaxCategory        TaxCategory                `json:"tax_category,omitempty"`
+	TaxBehavior        TaxBehavior                `json:"tax_behavior,omitempty"`
+	BillingCountry     string                     `json:"billing_country,omitempty"`
+	VATID              string                     `json:"vat_id,omitempty"`
+	Metadata           map[string]any             `json:"metadata,omitempty"`
+	EndedAt            *time.Time                 `json:"ended_at,omitempty"`
+	CreatedAt          time.Time                  `json:"created_at"`
+	UpdatedAt          time.Time                  `json:"updated_at"`
+}
+
+// SchedulePhase bills Items every interval for Iterations billing cycles.
+type SchedulePhase struct {
+	Items         []SubscriptionItemRequest `json:"items"`
+	Interval      string                    `json:"interval"`
+	IntervalCount int                       `json:"interval_count"`
+	Iterations    int                       `json:"iterations"`
+	StartDate     time.Time                 `json:"start_date"`
+	EndDate       time.Time                 `json:"end_date"`
+}
+
+// CreateSubscriptionScheduleRequest is the request body for scheduling
+// subscription changes. With a SubscriptionID the first phase starts at the
+// subscription's next renewal; otherwise a new subscription starts on StartDate.
+type CreateSubscriptionScheduleRequest struct {
+	SubscriptionID *uuid.UUID             `json:"subscription_id,omitempty"`
+	StartDate      *time.Time             `json:"start_date,omitempty"`
+	Provider       Provider               `json:"provider,omitempty"`
+	Currency       Currency               `json:"currency,omitempty"`
+	ProductName    string                 `json:"product_name,omitempty"`
+	TaxCategory    TaxCategory            `json:"tax_category,omitempty"`
+	TaxBehavior    TaxBehavior            `json:"tax_behavior,omitempty"`
+	BillingCountry string                 `json:"billing_country,omitempty"`
+	VATID          string                 `json:"vat_id,omitempty"`
+	Metadata       map[string]any         `json:"metadata,omitempty"`
+	EndBehavior    ScheduleEndBehavior    `json:"end_behavior,omitempty"`
+	Phases         []SchedulePhaseRequest `json:"phases"`
+}
+
+// SchedulePhaseRequest is a phase of a new schedule. Items and the interval
+// default to the previous phase's; Iterations defaults to 1.
+type SchedulePhaseRequest struct {
+	Items         []SubscriptionItemRequest `json:"items,omitempty"`
+	Interval      string                    `json:"interval,omitempty"`
+	IntervalCount int                       `json:"interval_count,omitempty"`
+	Iterations    int                       `json:"iterations,omitempty"`
+}
+
+// SubscriptionScheduleListResponse is the response for listing subscription schedules.
+type SubscriptionScheduleListResponse struct {
+	Data   []SubscriptionSchedule `json:"data"`
+	Total  int                    `json:"total"`
+	Limit  int                    `json:"limit"`
+	Offset int                    `json:"offset"`
+}
+
 // --- Refund types ---
 
 // RefundStatus represents the status of a refund.
 type RefundStatus string
 
 const (
-	RefundStatusPending    RefundStatus = "pending"
-	RefundStatusProcessing RefundStatus = "processing"
-	RefundStatusSucceeded  RefundStatus = "succeeded"
-	RefundStatusFailed     RefundStatus = "failed"
-	RefundStatusCanceled   RefundStatus = "canceled"
+	RefundStatusPendingApproval RefundStatus = "pending_approval"
+	RefundStatusPending         RefundStatus = "pending"
+	RefundStatusProcessing      RefundStatus = "processing"
+	RefundStatusSucceeded       RefundStatus = "succeeded"
+	RefundStatusFailed          RefundStatus = "failed"
+	RefundStatusCanceled        RefundStatus = "canceled"
+	RefundStatusRejected        RefundStatus = "rejected"
 )
 
 // Refund represents a refund returned by the API.
 type Refund struct {
-	ID               uuid.UUID      `json:"id"`
-	PaymentID        uuid.UUID      `json:"payment_id"`
-	Provider         Provider       `json:"provider"`
-	ProviderRefundID string         `json:"provider_refund_id"`
-	Amount           int64          `json:"amount"`
-	Currency         Currency       `json:"currency"`
-	Status           RefundStatus   `json:"status"`
-	Reason           *string        `json:"reason,omitempty"`
-	Notes            *string        `json:"notes,omitempty"`
-	FailureCode      *string        `json:"failure_code,omitempty"`
-	FailureMessage   *string        `json:"failure_message,omitempty"`
-	Metadata         map[string]any `json:"metadata,omitempty"`
-	CreatedAt        time.Time      `json:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at"`
-	CompletedAt      *time.Time     `json:"completed_at,omitempty"`
+	ID                 uuid.UUID      `json:"id"`
+	PaymentID          uuid.UUID      `json:"payment_id"`
+	Provider           Provider       `json:"provider"`
+	ProviderRefundID   string         `json:"provider_refund_id"`
+	Amount             int64          `json:"amount"`
+	Currency           Currency       `json:"currency"`
+	Status             RefundStatus   `json:"status"`
+	Destination        string         `json:"destination"`
+	ProviderFee        *int64         `json:"provider_fee,omitempty"`
+	NetAmount          *int64         `json:"net_amount,omitempty"`
+	SettlementCurrency *Currency      `json:"settlement_currency,omitempty"`
+	ExchangeRate       *float64       `json:"exchange_rate,omitempty"`
+	AvailableOn        *time.Time     `json:"available_on,omitempty"`
+	Reason             *string        `json:"reason,omitempty"`
+	Notes              *string        `json:"notes,omitempty"`
+	RequestedBy        *uuid.UUID     `json:"requested_by,omitempty"`
+	ApprovalReason     *string        `json:"approval_reason,omitempty"`
+	ReviewedBy         *uuid.UUID     `json:"reviewed_by,omitempty"`
+	ReviewedAt         *time.Time     `json:"reviewed_at,omitempty"`
+	ReviewNote         *string        `json:"review_note,omitempty"`
+	FailureCode        *string        `json:"failure_code,omitempty"`
+	FailureMessage     *string        `json:"failure_message,omitempty"`
+	Metadata           map[string]any `json:"metadata,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	CompletedAt        *time.Time     `json:"completed_at,omitempty"`
 }
 
+// Refund destinations. Refunds to the customer balance become store credit
+// instead of going back to the payment method.
+const (
+	RefundDestinationPaymentMethod   = "payment_method"
+	RefundDestinationCustomerBalance = "customer_balance"
+)
+
 // CreateRefundRequest is the request body for creating a refund.
 type CreateRefundRequest struct {
-	PaymentID uuid.UUID      `json:"payment_id"`
-	Amount    int64          `json:"amount"`
-	Reason    string         `json:"reason,omitempty"`
-	Notes     string         `json:"notes,omitempty"`
-	Metadata  map[string]any `json:"metadata,omitempty"`
+	PaymentID   uuid.UUID      `json:"payment_id"`
+	Amount      int64          `json:"amount"`
+	Destination string         `json:"destination,omitempty"`
+	Reason      string         `json:"reason,omitempty"`
+	Notes       string         `json:"notes,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
 }
 
 // RefundListResponse is the response for listing refunds.
@@ -194,6 +591,64 @@ type RefundListResponse struct {
 	Offset int      `json:"offset"`
 }
 
+// --- Invoice types ---
+
+// InvoiceStatus represents the status of an invoice.
+type InvoiceStatus string
+
+const (
+	InvoiceStatusDraft         InvoiceStatus = "draft"
+	InvoiceStatusOpen          InvoiceStatus = "open"
+	InvoiceStatusPaid          InvoiceStatus = "paid"
+	InvoiceStatusVoid          InvoiceStatus = "void"
+	InvoiceStatusUncollectible InvoiceStatus = "uncollectible"
+)
+
+// InvoiceLineItem is a single charge on an invoice.
+type InvoiceLineItem struct {
+	Description string     `json:"description"`
+	Quantity    int64      `json:"quantity"`
+	UnitAmount  int64      `json:"unit_amount"`
+	Amount      int64      `json:"amount"`
+	PeriodStart *time.Time `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time `json:"period_end,omitempty"`
+}
+
+// Invoice represents an invoice returned by the API.
+type Invoice struct {
+	ID                uuid.UUID         `json:"id"`
+	CustomerID        uuid.UUID         `json:"customer_id"`
+	SubscriptionID    *uuid.UUID        `json:"subscription_id,omitempty"`
+	PaymentID         *uuid.UUID        `json:"payment_id,omitempty"`
+	Provider          Provider          `json:"provider"`
+	ProviderInvoiceID string            `json:"provider_invoice_id"`
+	Number            *string           `json:"number,omitempty"`
+	Status            InvoiceStatus     `json:"status"`
+	BillingReason     *string           `json:"billing_reason,omitempty"`
+	Currency          Currency          `json:"currency"`
+	Subtotal          int64             `json:"subtotal"`
+	Tax               int64             `json:"tax"`
+	Total             int64             `json:"total"`
+	AmountDue         int64             `json:"amount_due"`
+	AmountPaid        int64             `json:"amount_paid"`
+	TaxBreakdown      *TaxBreakdown     `json:"tax_breakdown,omitempty"`
+	LineItems         []InvoiceLineItem `json:"line_items"`
+	PeriodStart       time.Time         `json:"period_start"`
+	PeriodEnd         time.Time         `json:"period_end"`
+	DueDate           *time.Time        `json:"due_date,omitempty"`
+	PaidAt            *time.Time        `json:"paid_at,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
+
+// InvoiceListResponse is the response for listing invoices.
+type InvoiceListResponse struct {
+	Data   []Invoice `json:"data"`
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
+
 // --- Customer types ---
 
 // Customer represents a customer returned by the API.
@@ -202,6 +657,16 @@ type Customer struct {
 	UserID           uuid.UUID      `json:"user_id"`
 	Email            string         `json:"email"`
 	Name             string         `json:"name"`
+	AddressLine1     *string        `json:"address_line1,omitempty"`
+	AddressLine2     *string        `json:"address_line2,omitempty"`
+	PostalCode       *string        `json:"postal_code,omitempty"`
+	City             *string        `json:"city,omitempty"`
+	Country          *string        `json:"country,omitempty"`
+	CompanyName      *string        `json:"company_name,omitempty"`
+	OrgNumber        *string        `json:"org_number,omitempty"`
+	VATID            *string        `json:"vat_id,omitempty"`
+	Locale           *string        `json:"locale,omitempty"`
+	InvoiceEmail     *string        `json:"invoice_email,omitempty"`
 	StripeCustomerID *string        `json:"stripe_customer_id,omitempty"`
 	SwishCustomerID  *string        `json:"swish_customer_id,omitempty"`
 	Metadata         map[string]any `json:"metadata,omitempty"`
@@ -210,6 +675,229 @@ type Customer struct {
 	DeletedAt        *time.Time     `json:"deleted_at,omitempty"`
 }
 
+// UpdateCustomerRequest updates the billing profile of the authenticated
+// user. Omitted fields are left unchanged and empty strings clear a field.
+type UpdateCustomerRequest struct {
+	Name         *string `json:"name,omitempty"`
+	AddressLine1 *string `json:"address_line1,omitempty"`
+	AddressLine2 *string `json:"address_line2,omitempty"`
+	PostalCode   *string `json:"postal_code,omitempty"`
+	City         *string `json:"city,omitempty"`
+	Country      *string `json:"country,omitempty"`
+	CompanyName  *string `json:"company_name,omitempty"`
+	OrgNumber    *string `json:"org_number,omitempty"`
+	VATID        *string `json:"vat_id,omitempty"`
+	Locale       *string `json:"locale,omitempty"`
+	InvoiceEmail *string `json:"invoice_email,omitempty"`
+}
+
+// AuditEvent records a change made to one of the customer's resources.
+type AuditEvent struct {
+	ID           uuid.UUID      `json:"id"`
+	CustomerID   *uuid.UUID     `json:"customer_id,omitempty"`
+	ActorUserID  *uuid.UUID     `json:"actor_user_id,omitempty"`
+	Action       string         `json:"action"`
+	ResourceType string         `json:"resource_type"`
+	ResourceID   uuid.UUID      `json:"resource_id"`
+	Details      map[string]any `json:"details,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// CustomerExport is a copy of everything stored about the authenticated user.
+type CustomerExport struct {
+	ExportedAt    time.Time      `json:"exported_at"`
+	Customer      *Customer      `json:"customer"`
+	Payments      []Payment      `json:"payments"`
+	Subscriptions []Subscription `json:"subscriptions"`
+	Refunds       []Refund       `json:"refunds"`
+	AuditEvents   []AuditEvent   `json:"audit_events"`
+}
+
+// CustomerBalance is the authenticated user's store credit in one currency.
+type CustomerBalance struct {
+	Currency Currency `json:"currency"`
+	Balance  int64    `json:"balance"`
+}
+
+// CustomerBalanceTransaction is one change to a customer balance. Positive
+// amounts are credit added, negative amounts credit used.
+type CustomerBalanceTransaction struct {
+	ID            uuid.UUID  `json:"id"`
+	CustomerID    uuid.UUID  `json:"customer_id"`
+	Currency      Currency   `json:"currency"`
+	Amount        int64      `json:"amount"`
+	BalanceAfter  int64      `json:"balance_after"`
+	Type          string     `json:"type"`
+	Description   *string    `json:"description,omitempty"`
+	ReferenceType string     `json:"reference_type"`
+	ReferenceID   *uuid.UUID `json:"reference_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// CustomerBalanceResponse is the response for the customer balance, with a
+// page of its history, newest first.
+type CustomerBalanceResponse struct {
+	Balances     []CustomerBalance            `json:"balances"`
+	Transactions []CustomerBalanceTransaction `json:"transactions"`
+	Total        int                          `json:"total"`
+	Limit        int                          `json:"limit"`
+	Offset       int                          `json:"offset"`
+}
+
+// --- Wallet types ---
+
+// WalletTransactionType identifies what changed a wallet balance.
+type WalletTransactionType string
+
+const (
+	WalletTransactionTopUp   WalletTransactionType = "top_up"
+	WalletTransactionGrant   WalletTransactionType = "grant"
+	WalletTransactionDebit   WalletTransactionType = "debit"
+	WalletTransactionCapture WalletTransactionType = "capture"
+	WalletTransactionExpiry  WalletTransactionType = "expiry"
+)
+
+// WalletHoldStatus represents the status of a hold on wallet funds.
+type WalletHoldStatus string
+
+const (
+	WalletHoldStatusActive   WalletHoldStatus = "active"
+	WalletHoldStatusCaptured WalletHoldStatus = "captured"
+	WalletHoldStatusReleased WalletHoldStatus = "released"
+)
+
+// Wallet is the authenticated user's prepaid balance in one currency. Held
+// funds are part of Balance but cannot be spent; Promotional is unexpired
+// granted credit, also part of Balance.
+type Wallet struct {
+	ID          uuid.UUID `json:"id"`
+	CustomerID  uuid.UUID `json:"customer_id"`
+	Currency    Currency  `json:"currency"`
+	Balance     int64     `json:"balance"`
+	Held        int64     `json:"held"`
+	Promotional int64     `json:"promotional"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WalletTransaction is one change to a wallet balance. Positive amounts add
+// funds, negative amounts spend them.
+type WalletTransaction struct {
+	ID             uuid.UUID             `json:"id"`
+	WalletID       uuid.UUID             `json:"wallet_id"`
+	Type           WalletTransactionType `json:"type"`
+	Amount         int64                 `json:"amount"`
+	BalanceAfter   int64                 `json:"balance_after"`
+	IdempotencyKey string                `json:"idempotency_key"`
+	Description    *string               `json:"description,omitempty"`
+	PaymentID      *uuid.UUID            `json:"payment_id,omitempty"`
+	HoldID         *uuid.UUID            `json:"hold_id,omitempty"`
+	GrantID        *uuid.UUID            `json:"grant_id,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+// WalletHold reserves wallet funds until it is captured or released.
+type WalletHold struct {
+	ID             uuid.UUID        `json:"id"`
+	WalletID       uuid.UUID        `json:"wallet_id"`
+	Amount         int64            `json:"amount"`
+	CapturedAmount int64            `json:"captured_amount"`
+	Status         WalletHoldStatus `json:"status"`
+	IdempotencyKey string           `json:"idempotency_key"`
+	Description    *string          `json:"description,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+// WalletTopUpRequest adds funds to a wallet through a payment.
+type WalletTopUpRequest struct {
+	Provider Provider `json:"provider"`
+	Money
+	BillingCountry string         `json:"billing_country,omitempty"`
+	VATID          string         `json:"vat_id,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+}
+
+// WalletTopUpResponse is the payment behind a top-up and the wallet after it.
+// Wallet is nil until the first top-up in the currency succeeds.
+type WalletTopUpResponse struct {
+	Payment *Payment `json:"payment"`
+	Wallet  *Wallet  `json:"wallet"`
+}
+
+// WalletDebitRequest spends wallet funds. Retrying with the same
+// IdempotencyKey returns the original transaction.
+type WalletDebitRequest struct {
+	Money
+	IdempotencyKey string `json:"idempotency_key"`
+	Description    string `json:"description,omitempty"`
+}
+
+// WalletHoldRequest reserves wallet funds. Retrying with the same
+// IdempotencyKey returns the original hold.
+type WalletHoldRequest struct {
+	Money
+	IdempotencyKey string `json:"idempotency_key"`
+	Description    string `json:"description,omitempty"`
+}
+
+// CaptureWalletHoldRequest spends held funds. A nil Amount captures the
+// whole hold; the rest of the hold is released.
+type CaptureWalletHoldRequest struct {
+	Amount *int64 `json:"amount,omitempty"`
+}
+
+// WalletListResponse is the response for listing wallets.
+type WalletListResponse struct {
+	Data []Wallet `json:"data"`
+}
+
+// WalletTransactionListResponse is the response for listing wallet
+// transactions, newest first.
+type WalletTransactionListResponse struct {
+	Data   []WalletTransaction `json:"data"`
+	Total  int                 `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+// --- Event types ---
+
+// EventType identifies the kind of event.
+type EventType string
+
+const (
+	EventTypeSubscriptionTrialWillEnd   EventType = "subscription.trial_will_end"
+	EventTypeSubscriptionTrialConverted EventType = "subscription.trial_converted"
+	EventTypeSubscriptionTrialExpired   EventType = "subscription.trial_expired"
+
+	EventTypeSubscriptionPaymentFailed    EventType = "subscription.payment_failed"
+	EventTypeSubscriptionPaymentRecovered EventType = "subscription.payment_recovered"
+	EventTypeSubscriptionDunningExhausted EventType = "subscription.dunning_exhausted"
+
+	EventTypePaymentDisputed      EventType = "payment.disputed"
+	EventTypePaymentDisputeClosed EventType = "payment.dispute_closed"
+)
+
+// Event is a notification about one of the customer's resources.
+type Event struct {
+	ID           uuid.UUID      `json:"id"`
+	CustomerID   uuid.UUID      `json:"customer_id"`
+	Type         EventType      `json:"type"`
+	ResourceType string         `json:"resource_type"`
+	ResourceID   uuid.UUID      `json:"resource_id"`
+	Data         map[string]any `json:"data,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// EventListResponse is the response for listing events.
+type EventListResponse struct {
+	Data   []Event `json:"data"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
 // --- Error types ---
 
 // APIError represents an error response from the payment API.
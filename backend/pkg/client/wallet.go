@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ListWallets lists the authenticated user's wallets, one per currency.
+func (c *Client) ListWallets(ctx context.Context) (*WalletListResponse, error) {
+	data, err := c.do(ctx, "GET", "/api/wallet", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp WalletListResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode wallet list: %w", err)
+	}
+	return &resp, nil
+}
+
+// ListWalletTransactions lists wallet transactions with pagination, newest first.
+func (c *Client) ListWalletTransactions(ctx context.Context, limit, offset int) (*WalletTransactionListResponse, error) {
+	path := fmt.Sprintf("/api/wallet/transactions?limit=%d&offset=%d", limit, offset)
+	data, err := c.do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp WalletTransactionListResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode wallet transaction list: %w", err)
+	}
+	return &resp, nil
+}
+
+// TopUpWallet adds funds to a wallet through a payment. The wallet is
+// credited once the payment succeeds.
+func (c *Client) TopUpWallet(ctx context.Context, req *WalletTopUpRequest) (*WalletTopUpResponse, error) {
+	data, err := c.do(ctx, "POST", "/api/wallet/top-ups", req)
+	if err != nil {
+		return nil, err
+	}
+	var resp WalletTopUpResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode wallet top-up: %w", err)
+	}
+	return &resp, nil
+}
+
+// DebitWallet spends wallet funds. It fails with a 402 APIError when the
+// available balance is too low.
+func (c *Client) DebitWallet(ctx context.Context, req *WalletDebitRequest) (*WalletTransaction, error) {
+	data, err := c.do(ctx, "POST", "/api/wallet/debits", req)
+	if err != nil {
+		return nil, err
+	}
+	var txn WalletTransaction
+	if err := json.Unmarshal(data, &txn); err != nil {
+		return nil, fmt.Errorf("decode wallet transaction: %w", err)
+	}
+	return &txn, nil
+}
+
+// CreateWalletHold reserves wallet funds for a later capture.
+func (c *Client) CreateWalletHold(ctx context.Context, req *WalletHoldRequest) (*WalletHold, error) {
+	data, err := c.do(ctx, "POST", "/api/wallet/holds", req)
+	if err != nil {
+		return nil, err
+	}
+	var hold WalletHold
+	if err := json.Unmarshal(data, &hold); err != nil {
+		return nil, fmt.Errorf("decode wallet hold: %w", err)
+	}
+	return &hold, nil
+}
+
+// CaptureWalletHold spends some or all of a hold and releases the rest.
+func (c *Client) CaptureWalletHold(ctx context.Context, id uuid.UUID, req *CaptureWalletHoldRequest) (*WalletHold, error) {
+	data, err := c.do(ctx, "POST", "/api/wallet/holds/"+id.String()+"/capture", req)
+	if err != nil {
+		return nil, err
+	}
+	var hold WalletHold
+	if err := json.Unmarshal(data, &hold); err != nil {
+		return nil, fmt.Errorf("decode wallet hold: %w", err)
+	}
+	return &hold, nil
+}
+
+// ReleaseWalletHold releases a hold without spending it.
+func (c *Client) ReleaseWalletHold(ctx context.Context, id uuid.UUID) (*WalletHold, error) {
+	data, err := c.do(ctx, "POST", "/api/wallet/holds/"+id.String()+"/release", nil)
+	if err != nil {
+		return nil, err
+	}
+	var hold WalletHold
+	if err := json.Unmarshal(data, &hold); err != nil {
+		return nil, fmt.Errorf("decode wallet hold: %w", err)
+	}
+	return &hold, nil
+}
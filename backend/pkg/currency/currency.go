@@ -0,0 +1,365 @@
+// Package currency is a registry of the ISO 4217 currencies the payment
+// service accepts, with the number of decimal places (the minor-unit
+// exponent) each one is written with. Amounts are always whole minor units:
+// öre for SEK, cents for USD, yen for JPY, which has no minor unit.
+//
+// The registry is shared by the service and the client SDK, so both agree on
+// which codes are valid and how amounts are formatted.
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// Currency describes an ISO 4217 currency
+type Currency struct {
+	// Code is the three-letter alphabetic code, e.g. "SEK"
+	Code string `json:"code"`
+	// Name is the English name of the currency
+	Name string `json:"name"`
+	// Exponent is the number of decimal places of the minor unit: 2 for SEK,
+	// 0 for JPY, 3 for KWD
+	Exponent int `json:"exponent"`
+}
+
+// ErrInvalidAmount is returned for amounts that are not positive or are too
+// large for the currency
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// maxMajorDigits caps amounts at 10 digits in the major unit (e.g. just under
+// 10 billion kronor), which keeps every amount and sum well inside an int64
+const maxMajorDigits = 10
+
+// registry holds the circulating ISO 4217 currencies by code. Fund codes,
+// precious metals and testing codes are left out.
+var registry = map[string]Currency{}
+
+func init() {
+	for _, c := range []Currency{
+		{"AED", "UAE Dirham", 2},
+		{"AFN", "Afghani", 2},
+		{"ALL", "Lek", 2},
+		{"AMD", "Armenian Dram", 2},
+		{"AOA", "Kwanza", 2},
+		{"ARS", "Argentine Peso", 2},
+		{"AUD", "Australian Dollar", 2},
+		{"AWG", "Aruban Florin", 2},
+		{"AZN", "Azerbaijan Manat", 2},
+		{"BAM", "Convertible Mark", 2},
+		{"BBD", "Barbados Dollar", 2},
+		{"BDT", "Taka", 2},
+		{"BGN", "Bulgarian Lev", 2},
+		{"BHD", "Bahraini Dinar", 3},
+		{"BIF", "Burundi Franc", 0},
+		{"BMD", "Bermudian Dollar", 2},
+		{"BND", "Brunei Dollar", 2},
+		{"BOB", "Boliviano", 2},
+		{"BRL", "Brazilian Real", 2},
+		{"BSD", "Bahamian Dollar", 2},
+		{"BTN", "Ngultrum", 2},
+		{"BWP", "Pula", 2},
+		{"BYN", "Belarusian Ruble", 2},
+		{"BZD", "Belize Dollar", 2},
+		{"CAD", "Canadian Dollar", 2},
+		{"CDF", "Congolese Franc", 2},
+		{"CHF", "Swiss Franc", 2},
+		{"CLP", "Chilean Peso", 0},
+		{"CNY", "Yuan Renminbi", 2},
+		{"COP", "Colombian Peso", 2},
+		{"CRC", "Costa Rican Colon", 2},
+		{"CUP", "Cuban Peso", 2},
+		{"CVE", "Cabo Verde Escudo", 2},
+		{"CZK", "Czech Koruna", 2},
+		{"DJF", "Djibouti Franc", 0},
+		{"DKK", "Danish Krone", 2},
+		{"DOP", "Dominican Peso", 2},
+		{"DZD", "Algerian Dinar", 2},
+		{"EGP", "Egyptian Pound", 2},
+		{"ERN", "Nakfa", 2},
+		{"ETB", "Ethiopian Birr", 2},
+		{"EUR", "Euro", 2},
+		{"FJD", "Fiji Dollar", 2},
+		{"FKP", "Falkland Islands Pound", 2},
+		{"GBP", "Pound Sterling", 2},
+		{"GEL", "Lari", 2},
+		{"GHS", "Ghana Cedi", 2},
+		{"GIP", "Gibraltar Pound", 2},
+		{"GMD", "Dalasi", 2},
+		{"GNF", "Guinean Franc", 0},
+		{"GTQ", "Quetzal", 2},
+		{"GYD", "Guyana Dollar", 2},
+		{"HKD", "Hong Kong Dollar", 2},
+		{"HNL", "Lempira", 2},
+		{"HTG", "Gourde", 2},
+		{"HUF", "Forint", 2},
+		{"IDR", "Rupiah", 2},
+		{"ILS", "New Israeli Sheqel", 2},
+		{"INR", "Indian Rupee", 2},
+		{"IQD", "Iraqi Dinar", 3},
+		{"IRR", "Iranian Rial", 2},
+		{"ISK", "Iceland Krona", 0},
+		{"JMD", "Jamaican Dollar", 2},
+		{"JOD", "Jordanian Dinar", 3},
+		{"JPY", "Yen", 0},
+		{"KES", "Kenyan Shilling", 2},
+		{"KGS", "Som", 2},
+		{"KHR", "Riel", 2},
+		{"KMF", "Comorian Franc", 0},
+		{"KPW", "North Korean Won", 2},
+		{"KRW", "Won", 0},
+		{"KWD", "Kuwaiti Dinar", 3},
+		{"KYD", "Cayman Islands Dollar", 2},
+		{"KZT", "Tenge", 2},
+		{"LAK", "Lao Kip", 2},
+		{"LBP", "Lebanese Pound", 2},
+		{"LKR", "Sri Lanka Rupee", 2},
+		{"LRD", "Liberian Dollar", 2},
+		{"LSL", "Loti", 2},
+		{"LYD", "Libyan Dinar", 3},
+		{"MAD", "Moroccan Dirham", 2},
+		{"MDL", "Moldovan Leu", 2},
+		{"MGA", "Malagasy Ariary", 2},
+		{"MKD", "Denar", 2},
+		{"MMK", "Kyat", 2},
+		{"MNT", "Tugrik", 2},
+		{"MOP", "Pataca", 2},
+		{"MRU", "Ouguiya", 2},
+		{"MUR", "Mauritius Rupee", 2},
+		{"MVR", "Rufiyaa", 2},
+		{"MWK", "Malawi Kwacha", 2},
+		{"MXN", "Mexican Peso", 2},
+		{"MYR", "Malaysian Ringgit", 2},
+		{"MZN", "Mozambique Metical", 2},
+		{"NAD", "Namibia Dollar", 2},
+		{"NGN", "Naira", 2},
+		{"NIO", "Cordoba Oro", 2},
+		{"NOK", "Norwegian Krone", 2},
+		{"NPR", "Nepalese Rupee", 2},
+		{"NZD", "New Zealand Dollar", 2},
+		{"OMR", "Rial Omani", 3},
+		{"PAB", "Balboa", 2},
+		{"PEN", "Sol", 2},
+		{"PGK", "Kina", 2},
+		{"PHP", "Philippine Peso", 2},
+		{"PKR", "Pakistan Rupee", 2},
+		{"PLN", "Zloty", 2},
+		{"PYG", "Guarani", 0},
+		{"QAR", "Qatari Rial", 2},
+		{"RON", "Romanian Leu", 2},
+		{"RSD", "Serbian Dinar", 2},
+		{"RUB", "Russian Ruble", 2},
+		{"RWF", "Rwanda Franc", 0},
+		{"SAR", "Saudi Riyal", 2},
+		{"SBD", "Solomon Islands Dollar", 2},
+		{"SCR", "Seychelles Rupee", 2},
+		{"SDG", "Sudanese Pound", 2},
+		{"SEK", "Swedish Krona", 2},
+		{"SGD", "Singapore Dollar", 2},
+		{"SHP", "Saint Helena Pound", 2},
+		{"SLE", "Leone", 2},
+		{"SOS", "Somali Shilling", 2},
+		{"SRD", "Surinam Dollar", 2},
+		{"SSP", "South Sudanese Pound", 2},
+		{"STN", "Dobra", 2},
+		{"SVC", "El Salvador Colon", 2},
+		{"SYP", "Syrian Pound", 2},
+		{"SZL", "Lilangeni", 2},
+		{"THB", "Baht", 2},
+		{"TJS", "Somoni", 2},
+		{"TMT", "Turkmenistan New Manat", 2},
+		{"TND", "Tunisian Dinar", 3},
+		{"TOP", "Pa'anga", 2},
+		{"TRY", "Turkish Lira", 2},
+		{"TTD", "Trinidad and Tobago Dollar", 2},
+		{"TWD", "New Taiwan Dollar", 2},
+		{"TZS", "Tanzanian Shilling", 2},
+		{"UAH", "Hryvnia", 2},
+		{"UGX", "Uganda Shilling", 0},
+		{"USD", "US Dollar", 2},
+		{"UYU", "Peso Uruguayo", 2},
+		{"UZS", "Uzbekistan Sum", 2},
+		{"VES", "Bolívar Soberano", 2},
+		{"VND", "Dong", 0},
+		{"VUV", "Vatu", 0},
+		{"WST", "Tala", 2},
+		{"XAF", "CFA Franc BEAC", 0},
+		{"XCD", "East Caribbean Dollar", 2},
+		{"XCG", "Caribbean Guilder", 2},
+		{"XOF", "CFA Franc BCEAO", 0},
+		{"XPF", "CFP Franc", 0},
+		{"YER", "Yemeni Rial", 2},
+		{"ZAR", "Rand", 2},
+		{"ZMW", "Zambian Kwacha", 2},
+		{"ZWG", "Zimbabwe Gold", 2},
+	} {
+		registry[c.Code] = c
+	}
+}
+
+// Lookup returns the currency with the given code. Codes are upper case, as
+// in ISO 4217.
+func Lookup(code string) (Currency, bool) {
+	c, ok := registry[code]
+	return c, ok
+}
+
+// ForCode returns the currency with the given code. Codes missing from the
+// registry can still arrive from providers, e.g. on a dispute; they are
+// treated as having two decimals, the most common exponent.
+func ForCode(code string) Currency {
+	if c, ok := registry[code]; ok {
+		return c
+	}
+	return Currency{Code: code, Name: code, Exponent: 2}
+}
+
+// IsValid reports whether code is a currency in the registry
+func IsValid(code string) bool {
+	_, ok := registry[code]
+	return ok
+}
+
+// All returns every currency in the registry, ordered by code
+func All() []Currency {
+	all := make([]Currency, 0, len(registry))
+	for _, c := range registry {
+		all = append(all, c)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Code < all[j].Code })
+	return all
+}
+
+// IsZeroDecimal reports whether the currency has no minor unit, so amounts
+// are in whole units, e.g. JPY
+func (c Currency) IsZeroDecimal() bool {
+	return c.Exponent == 0
+}
+
+// MinorUnits returns how many minor units make up one major unit, e.g. 100
+// for SEK and 1 for JPY
+func (c Currency) MinorUnits() int64 {
+	units := int64(1)
+	for i := 0; i < c.Exponent; i++ {
+		units *= 10
+	}
+	return units
+}
+
+// MaxAmount returns the largest amount, in minor units, the service accepts
+// in the currency
+func (c Currency) MaxAmount() int64 {
+	limit := int64(1)
+	for i := 0; i < maxMajorDigits; i++ {
+		limit *= 10
+	}
+	return limit*c.MinorUnits() - 1
+}
+
+// ValidateAmount checks that amount, in minor units, is greater than zero and
+// no more than MaxAmount
+func (c Currency) ValidateAmount(amount int64) error {
+	if amount <= 0 {
+		return fmt.Errorf("%w: must be greater than 0", ErrInvalidAmount)
+	}
+	if amount > c.MaxAmount() {
+		return fmt.Errorf("%w: must be at most %s %s", ErrInvalidAmount, c.FormatAmount(c.MaxAmount()), c.Code)
+	}
+	return nil
+}
+
+// FormatAmount writes an amount in minor units as a plain decimal number with
+// the currency's number of decimals, e.g. 12345 is "123.45" in SEK, "12345"
+// in JPY and "12.345" in KWD
+func (c Currency) FormatAmount(amount int64) string {
+	sign := ""
+	// Work on the magnitude as uint64 so the most negative int64 doesn't overflow
+	magnitude := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		magnitude = uint64(-(amount + 1)) + 1
+	}
+
+	if c.Exponent == 0 {
+		return fmt.Sprintf("%s%d", sign, magnitude)
+	}
+
+	units := uint64(c.MinorUnits())
+	fraction := fmt.Sprintf("%0*d", c.Exponent, magnitude%units)
+	return fmt.Sprintf("%s%d.%s", sign, magnitude/units, fraction)
+}
+
+// String returns the currency code
+func (c Currency) String() string {
+	return c.Code
+}
+
+// Allocate splits an amount into parts proportional to ratios without losing
+// any minor units: each part is rounded towards zero and the units left over
+// go one each to the parts that lost the most, earlier parts first on ties.
+// Splitting 100 öre by 1:1:1 gives 34, 33 and 33.
+func Allocate(amount int64, ratios []int64) ([]int64, error) {
+	if len(ratios) == 0 {
+		return nil, errors.New("no ratios to allocate by")
+	}
+
+	// The total, like amount × ratio below, is computed in big.Int since it
+	// can overflow an int64
+	total := new(big.Int)
+	for _, ratio := range ratios {
+		if ratio < 0 {
+			return nil, fmt.Errorf("negative ratio %d", ratio)
+		}
+		total.Add(total, big.NewInt(ratio))
+	}
+	if total.Sign() <= 0 {
+		return nil, errors.New("ratios must add up to more than 0")
+	}
+
+	// Allocate the magnitude so negative amounts split the same way
+	magnitude := new(big.Int).Abs(big.NewInt(amount))
+
+	parts := make([]*big.Int, len(ratios))
+	remainders := make([]*big.Int, len(ratios))
+	left := new(big.Int).Set(magnitude)
+	for i, ratio := range ratios {
+		product := new(big.Int).Mul(magnitude, big.NewInt(ratio))
+		parts[i], remainders[i] = new(big.Int).QuoRem(product, total, new(big.Int))
+		left.Sub(left, parts[i])
+	}
+
+	// Fewer units are left over than there are parts
+	order := make([]int, len(ratios))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]].Cmp(remainders[order[b]]) > 0 })
+	for i := int64(0); i < left.Int64(); i++ {
+		parts[order[i]].Add(parts[order[i]], big.NewInt(1))
+	}
+
+	allocated := make([]int64, len(ratios))
+	for i, part := range parts {
+		if amount < 0 {
+			part.Neg(part)
+		}
+		allocated[i] = part.Int64()
+	}
+	return allocated, nil
+}
+
+// Split divides an amount into n parts that differ by at most one minor
+// unit and add up to the amount, e.g. 100 öre in 3 is 34, 33 and 33
+func Split(amount int64, n int) ([]int64, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("cannot split into %d parts", n)
+	}
+
+	ratios := make([]int64, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return Allocate(amount, ratios)
+}
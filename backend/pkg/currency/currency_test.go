@@ -0,0 +1,147 @@
+package currency
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustLookup(t *testing.T, code string) Currency {
+	t.Helper()
+	c, ok := Lookup(code)
+	if !ok {
+		t.Fatalf("currency %s is not in the registry", code)
+	}
+	return c
+}
+
+func TestLookup_Exponents(t *testing.T) {
+	tests := []struct {
+		code       string
+		exponent   int
+		minorUnits int64
+	}{
+		{"SEK", 2, 100},
+		{"EUR", 2, 100},
+		{"JPY", 0, 1},
+		{"ISK", 0, 1},
+		{"KWD", 3, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			c := mustLookup(t, tt.code)
+			assert.Equal(t, tt.exponent, c.Exponent)
+			assert.Equal(t, tt.minorUnits, c.MinorUnits())
+			assert.Equal(t, tt.exponent == 0, c.IsZeroDecimal())
+		})
+	}
+
+	assert.True(t, IsValid("NOK"))
+	assert.False(t, IsValid("XXX"))
+	assert.False(t, IsValid("sek"))
+	assert.Equal(t, 2, ForCode("XXX").Exponent)
+}
+
+func TestCurrency_ValidateAmount(t *testing.T) {
+	sek := mustLookup(t, "SEK")
+	jpy := mustLookup(t, "JPY")
+	kwd := mustLookup(t, "KWD")
+
+	assert.NoError(t, sek.ValidateAmount(1))
+	assert.NoError(t, sek.ValidateAmount(sek.MaxAmount()))
+	assert.Equal(t, int64(999_999_999_999), sek.MaxAmount())
+	assert.Equal(t, int64(9_999_999_999), jpy.MaxAmount())
+	assert.Equal(t, int64(9_999_999_999_999), kwd.MaxAmount())
+
+	for _, amount := range []int64{0, -100, sek.MaxAmount() + 1, math.MaxInt64} {
+		err := sek.ValidateAmount(amount)
+		assert.True(t, errors.Is(err, ErrInvalidAmount), "amount %d", amount)
+	}
+	assert.Error(t, jpy.ValidateAmount(jpy.MaxAmount()+1))
+}
+
+func TestCurrency_FormatAmount(t *testing.T) {
+	sek := mustLookup(t, "SEK")
+	assert.Equal(t, "123.45", sek.FormatAmount(12345))
+	assert.Equal(t, "0.05", sek.FormatAmount(5))
+	assert.Equal(t, "-0.05", mustLookup(t, "EUR").FormatAmount(-5))
+	assert.Equal(t, "12345", mustLookup(t, "JPY").FormatAmount(12345))
+	assert.Equal(t, "12.345", mustLookup(t, "KWD").FormatAmount(12345))
+	assert.Equal(t, "-92233720368547758.08", sek.FormatAmount(math.MinInt64))
+}
+
+func TestCurrency_Format(t *testing.T) {
+	tests := []struct {
+		code   string
+		amount int64
+		locale string
+		want   string
+	}{
+		{"SEK", 123456, "sv-SE", "1\u00a0234,56\u00a0kr"},
+		{"SEK", 123456, "en-US", "SEK\u00a01,234.56"},
+		{"EUR", -123456, "en", "-€1,234.56"},
+		{"EUR", 123456, "de_DE", "1.234,56\u00a0€"},
+		{"JPY", 123456, "ja-JP", "¥123,456"},
+		{"KWD", 1234567, "en-US", "KWD\u00a01,234.567"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code+"/"+tt.locale, func(t *testing.T) {
+			assert.Equal(t, tt.want, mustLookup(t, tt.code).Format(tt.amount, tt.locale))
+		})
+	}
+}
+
+func TestAllocate_KeepsEveryMinorUnit(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int64
+		ratios []int64
+		want   []int64
+	}{
+		{"even", 10000, []int64{1, 1}, []int64{5000, 5000}},
+		{"remainder to largest fraction", 10000, []int64{1, 2}, []int64{3333, 6667}},
+		{"remainder in order", 100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{"negative", -100, []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		{"zero ratio", 100, []int64{0, 1}, []int64{0, 100}},
+		{"large amount", math.MaxInt64, []int64{1, 1}, []int64{math.MaxInt64/2 + 1, math.MaxInt64 / 2}},
+		{"ratios overflowing int64", 100, []int64{math.MaxInt64, math.MaxInt64}, []int64{50, 50}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, err := Allocate(tt.amount, tt.ratios)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, parts)
+		})
+	}
+}
+
+func TestAllocate_RejectsInvalidRatios(t *testing.T) {
+	_, err := Allocate(100, nil)
+	assert.Error(t, err)
+	_, err = Allocate(100, []int64{0, 0})
+	assert.Error(t, err)
+	_, err = Allocate(100, []int64{2, -1})
+	assert.Error(t, err)
+	_, err = Allocate(100, []int64{math.MaxInt64, math.MinInt64})
+	assert.Error(t, err)
+}
+
+func TestSplit(t *testing.T) {
+	parts, err := Split(1000, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{334, 333, 333}, parts)
+
+	parts, err = Split(2, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 1, 0, 0}, parts)
+
+	_, err = Split(100, 0)
+	assert.Error(t, err)
+	_, err = Split(100, -1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package currency
+
+import "strings"
+
+// locale is how a locale writes amounts of money
+type locale struct {
+	decimal string
+	group   string
+	// symbolFirst puts the symbol before the number, e.g. "$1,234.56"
+	symbolFirst bool
+	// spaced separates a leading symbol from the number, e.g. "€ 1.234,56"
+	spaced bool
+	// home is the locale's own currency and homeSymbol how it is written
+	home       string
+	homeSymbol string
+}
+
+// nbsp keeps a number and its symbol, or the groups of a number, on one line
+const nbsp = " "
+
+var locales = map[string]locale{
+	"en-US": {decimal: ".", group: ",", symbolFirst: true, home: "USD", homeSymbol: "$"},
+	"en-GB": {decimal: ".", group: ",", symbolFirst: true, home: "GBP", homeSymbol: "£"},
+	"sv-SE": {decimal: ",", group: nbsp, home: "SEK", homeSymbol: "kr"},
+	"nb-NO": {decimal: ",", group: nbsp, home: "NOK", homeSymbol: "kr"},
+	"da-DK": {decimal: ",", group: ".", home: "DKK", homeSymbol: "kr."},
+	"is-IS": {decimal: ",", group: ".", home: "ISK", homeSymbol: "kr."},
+	"fi-FI": {decimal: ",", group: nbsp, home: "EUR", homeSymbol: "€"},
+	"de-DE": {decimal: ",", group: ".", home: "EUR", homeSymbol: "€"},
+	"fr-FR": {decimal: ",", group: nbsp, home: "EUR", homeSymbol: "€"},
+	"nl-NL": {decimal: ",", group: ".", symbolFirst: true, spaced: true, home: "EUR", homeSymbol: "€"},
+	"de-CH": {decimal: ".", group: "’", symbolFirst: true, spaced: true, home: "CHF", homeSymbol: "CHF"},
+	"pl-PL": {decimal: ",", group: nbsp, home: "PLN", homeSymbol: "zł"},
+	"ja-JP": {decimal: ".", group: ",", symbolFirst: true, home: "JPY", homeSymbol: "¥"},
+}
+
+// languageLocales picks a locale for a bare language tag
+var languageLocales = map[string]string{
+	"en": "en-US",
+	"sv": "sv-SE",
+	"nb": "nb-NO",
+	"no": "nb-NO",
+	"da": "da-DK",
+	"is": "is-IS",
+	"fi": "fi-FI",
+	"de": "de-DE",
+	"fr": "fr-FR",
+	"nl": "nl-NL",
+	"pl": "pl-PL",
+	"ja": "ja-JP",
+}
+
+// symbols are written the same way in every locale; other currencies are
+// written with their code outside their home locale
+var symbols = map[string]string{
+	"EUR": "€",
+	"GBP": "£",
+}
+
+// lookupLocale finds a locale by BCP 47 tag, e.g. "sv-SE" or "sv_SE", falling
+// back to the tag's language and then to en-US
+func lookupLocale(tag string) locale {
+	tag = strings.ReplaceAll(tag, "_", "-")
+	language, region, _ := strings.Cut(tag, "-")
+	language = strings.ToLower(language)
+
+	if l, ok := locales[language+"-"+strings.ToUpper(region)]; ok {
+		return l
+	}
+	if l, ok := locales[languageLocales[language]]; ok {
+		return l
+	}
+	return locales["en-US"]
+}
+
+// symbol returns how the locale writes a currency
+func (l locale) symbol(code string) string {
+	if code == l.home {
+		return l.homeSymbol
+	}
+	if symbol, ok := symbols[code]; ok {
+		return symbol
+	}
+	return code
+}
+
+// Format writes an amount in minor units the way a locale writes money, e.g.
+// 123456 SEK is "1 234,56 kr" in sv-SE and "SEK 1,234.56" in en-US. Unknown
+// locales are formatted as en-US.
+func (c Currency) Format(amount int64, localeTag string) string {
+	l := lookupLocale(localeTag)
+
+	number := c.FormatAmount(amount)
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign = "-"
+		number = number[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(number, ".")
+	number = groupDigits(whole, l.group)
+	if fraction != "" {
+		number += l.decimal + fraction
+	}
+
+	symbol := l.symbol(c.Code)
+	if !l.symbolFirst {
+		return sign + number + nbsp + symbol
+	}
+	if l.spaced || symbol == c.Code {
+		return sign + symbol + nbsp + number
+	}
+	return sign + symbol + number
+}
+
+// groupDigits separates the digits of a whole number into groups of three
+func groupDigits(digits, separator string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+
+	var b strings.Builder
+	first := len(digits) % 3
+	if first == 0 {
+		first = 3
+	}
+	b.WriteString(digits[:first])
+	for i := first; i < len(digits); i += 3 {
+		b.WriteString(separator)
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
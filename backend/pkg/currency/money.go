@@ -0,0 +1,170 @@
+package currency
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrCurrencyMismatch is returned when amounts in different currencies are
+// combined
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// Code is a currency code type, e.g. the service's or the client's Currency
+type Code interface {
+	~string
+}
+
+// Money is an amount in a currency's minor unit, e.g. 12345 SEK is 123.45 kr.
+// Arithmetic refuses to mix currencies, so amounts can't be added to or
+// compared with amounts in another currency by mistake.
+//
+// The zero Money has no currency and acts as zero in any currency, so a total
+// can start from Money{} and take the currency of the first amount added.
+//
+// The service and the client each use Money with their own Currency type.
+type Money[C Code] struct {
+	Amount   int64 `json:"amount"`
+	Currency C     `json:"currency"`
+}
+
+// Add returns m + other
+func (m Money[C]) Add(other Money[C]) (Money[C], error) {
+	c, err := m.sameCurrency(other)
+	if err != nil {
+		return Money[C]{}, err
+	}
+	return Money[C]{Amount: m.Amount + other.Amount, Currency: c}, nil
+}
+
+// Sub returns m - other
+func (m Money[C]) Sub(other Money[C]) (Money[C], error) {
+	c, err := m.sameCurrency(other)
+	if err != nil {
+		return Money[C]{}, err
+	}
+	return Money[C]{Amount: m.Amount - other.Amount, Currency: c}, nil
+}
+
+// Cmp compares m with other, returning -1, 0 or +1
+func (m Money[C]) Cmp(other Money[C]) (int, error) {
+	if _, err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Neg returns -m
+func (m Money[C]) Neg() Money[C] {
+	return Money[C]{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// IsZero reports whether the amount is zero
+func (m Money[C]) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (m Money[C]) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// IsNegative reports whether the amount is less than zero
+func (m Money[C]) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Allocate splits m into parts proportional to ratios that add up to exactly
+// m, e.g. 100.00 kr by 1:2 is 33.33 kr and 66.67 kr
+func (m Money[C]) Allocate(ratios ...int64) ([]Money[C], error) {
+	amounts, err := Allocate(m.Amount, ratios)
+	if err != nil {
+		return nil, err
+	}
+	return m.withAmounts(amounts), nil
+}
+
+// Split divides m into n parts that differ by at most one minor unit and add
+// up to exactly m, e.g. 1.00 kr in 3 is 0.34, 0.33 and 0.33 kr
+func (m Money[C]) Split(n int) ([]Money[C], error) {
+	amounts, err := Split(m.Amount, n)
+	if err != nil {
+		return nil, err
+	}
+	return m.withAmounts(amounts), nil
+}
+
+// Format writes m the way a locale writes money, e.g. "1 234,56 kr" in sv-SE
+// and "SEK 1,234.56" in en-US
+func (m Money[C]) Format(locale string) string {
+	return ForCode(string(m.Currency)).Format(m.Amount, locale)
+}
+
+// String writes m as a decimal number and currency code, e.g. "123.45 SEK"
+func (m Money[C]) String() string {
+	return ForCode(string(m.Currency)).FormatAmount(m.Amount) + " " + string(m.Currency)
+}
+
+// Scan implements sql.Scanner for reading Money stored as JSONB. A currency
+// missing from the registry is rejected rather than read with a guessed
+// exponent.
+func (m *Money[C]) Scan(value any) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*m = Money[C]{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("failed to scan Money: expected []byte, got %T", value)
+	}
+
+	var scanned Money[C]
+	if err := json.Unmarshal(b, &scanned); err != nil {
+		return fmt.Errorf("failed to scan Money: %w", err)
+	}
+	if scanned.Currency == "" && scanned.Amount != 0 {
+		return fmt.Errorf("failed to scan Money: amount %d has no currency", scanned.Amount)
+	}
+	if scanned.Currency != "" && !IsValid(string(scanned.Currency)) {
+		return fmt.Errorf("failed to scan Money: unknown currency %q", scanned.Currency)
+	}
+	*m = scanned
+	return nil
+}
+
+// Value implements driver.Valuer for writing Money as JSONB
+func (m Money[C]) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+// sameCurrency returns the currency of an operation on m and other
+func (m Money[C]) sameCurrency(other Money[C]) (C, error) {
+	switch {
+	case m.Currency == other.Currency:
+		return m.Currency, nil
+	case m.Currency == "" && m.Amount == 0:
+		return other.Currency, nil
+	case other.Currency == "" && other.Amount == 0:
+		return m.Currency, nil
+	}
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+}
+
+func (m Money[C]) withAmounts(amounts []int64) []Money[C] {
+	parts := make([]Money[C], len(amounts))
+	for i, amount := range amounts {
+		parts[i] = Money[C]{Amount: amount, Currency: m.Currency}
+	}
+	return parts
+}